@@ -17,7 +17,7 @@ func main() {
 
 	s := &http.Server{
 		Addr:           "127.0.0.1:8081",
-		Handler:        apiserver.New(storage, api.Codec, "/osapi/v1beta1"),
+		Handler:        apiserver.NewWithConfig(storage, api.Codec, "/osapi/v1beta1", apiserver.Config{}),
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
@@ -2,9 +2,12 @@ package client
 
 import (
 	"os"
+	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 const longDescription = `
@@ -22,28 +25,105 @@ func NewCommandKubecfg(name string) *cobra.Command {
 		Run: func(c *cobra.Command, args []string) {
 			if len(args) < 1 {
 				c.Help()
-				os.Exit(1)
+				exit(kubecfg.ExitUsage)
 			}
 			cfg.Args = args
+			if args[0] == "config" {
+				cfg.RunConfig()
+				return
+			}
+			if err := cfg.ApplyProfile(c.Flags()); err != nil {
+				fatalf("%v", err)
+			}
 			cfg.Run()
 		},
 	}
-	flag := cmd.Flags()
+	cfg.bindFlags(cmd.Flags())
+	cmd.AddCommand(newCommandCompletion(cmd, cfg))
+	return cmd
+}
+
+// bindFlags defines the kubecfg flags on flag, storing their values in cfg.
+func (cfg *KubeConfig) bindFlags(flag *pflag.FlagSet) {
 	flag.BoolVar(&cfg.ServerVersion, "server_version", false, "Print the server's version number.")
 	flag.BoolVar(&cfg.PreventSkew, "expect_version_match", false, "Fail if server's version doesn't match own version.")
+	flag.BoolVar(&cfg.ForceCompat, "force-compat", false, "If true, use every feature even if the server's version predates it, instead of refusing or working around it")
 	flag.StringVarP(&cfg.HttpServer, "host", "h", "", "The host to connect to.")
-	flag.StringVarP(&cfg.Config, "config", "c", "", "Path to the config file.")
+	flag.StringVarP(&cfg.Config, "config", "c", "", "Path to the config file, or - to read it from stdin.")
 	flag.StringVarP(&cfg.Selector, "label", "l", "", "Selector (label query) to use for listing")
+	flag.StringVarP(&cfg.Namespace, "namespace", "n", "", "The namespace of the objects to operate on, overriding the namespace of the profile. If empty, objects are looked up in the default namespace and listed across all namespaces")
+	flag.BoolVar(&cfg.AllNamespaces, "all-namespaces", false, "If true, 'list' lists the objects of every namespace, whatever the namespace of the profile, with a column for their namespace")
+	flag.StringVar(&cfg.Fields, "field-selector", "", "Selector (field query) to use for listing and deleting, e.g. status=failed for builds or reason=failedCreate for events. Objects must also match -l")
+	flag.StringVar(&cfg.Fields, "fields", "", "Same as --field-selector")
 	flag.DurationVarP(&cfg.UpdatePeriod, "update", "u", 60*time.Second, "Update interval period")
 	flag.StringVarP(&cfg.PortSpec, "port", "p", "", "The port spec, comma-separated list of <external>:<internal>,...")
 	flag.IntVarP(&cfg.ServicePort, "service", "s", -1, "If positive, create and run a corresponding service on this port, only used with 'run'")
 	flag.StringVar(&cfg.AuthConfig, "auth", os.Getenv("HOME")+"/.kubernetes_auth", "Path to the auth info file.  If missing, prompt the user.  Only used if doing https.")
-	flag.BoolVar(&cfg.JSON, "json", false, "If true, print raw JSON for responses")
+	flag.BoolVar(&cfg.JSON, "json", false, "If true, print responses as indented JSON, and the steps of stop, rollingupdate and of --wait as JSON events, one per line")
 	flag.BoolVar(&cfg.YAML, "yaml", false, "If true, print raw YAML for responses")
-	flag.BoolVar(&cfg.Verbose, "verbose", false, "If true, print extra information")
+	flag.BoolVar(&cfg.Verbose, "verbose", false, "If true, print extra information. Same as --verbosity=1")
+	flag.IntVar(&cfg.Verbosity, "verbosity", 0, "Level of extra information to print to stderr: 1 is --verbose, 2 adds each HTTP request and response with truncated bodies, 3 prints full bodies. Credentials are redacted")
 	flag.BoolVar(&cfg.Proxy, "proxy", false, "If true, run a proxy to the api server")
 	flag.StringVar(&cfg.WWW, "www", "", "If -proxy is true, use this directory to serve static files")
+	flag.StringVar(&cfg.Listen, "listen", "localhost:8001", "If -proxy is true, the address to listen on")
+	flag.StringVar(&cfg.UnixSocket, "unix-socket", "", "If -proxy is true and this is set, listen on this Unix socket instead of -listen")
+	flag.StringVar(&cfg.APIPrefix, "api-prefix", "/api/", "If -proxy is true, the path under which the server's /api/ is proxied")
+	flag.StringVar(&cfg.ProxyCertFile, "proxy-cert", "", "If -proxy is true, serve HTTPS using this certificate file")
+	flag.StringVar(&cfg.ProxyKeyFile, "proxy-key", "", "If -proxy is true, serve HTTPS using this private key file")
 	flag.StringVar(&cfg.TemplateFile, "template_file", "", "If present, load this file as a golang template and use it for output printing")
 	flag.StringVar(&cfg.TemplateStr, "template", "", "If present, parse this string as a golang template and use it for output printing")
-	return cmd
+	flag.BoolVar(&cfg.TemplateRaw, "template-raw", false, "If true, templates are given {{.Object}}, the object, and {{.Raw}}, its JSON as a map, to reach fields the object doesn't have")
+	flag.BoolVar(&cfg.Yes, "yes", false, "If true, do not ask for confirmation before deleting objects by label selector")
+	flag.BoolVar(&cfg.StopOnError, "stop-on-error", false, "If true, stop creating objects from a config file or directory after the first failure")
+	flag.DurationVar(&cfg.Timeout, "timeout", 0, "If positive, the maximum time to wait for an operation, such as a --wait request or a rollingupdate, to complete, or for the condition of 'wait' to hold")
+	flag.BoolVar(&cfg.Wait, "wait", false, "If true, wait for accepted operations to complete, and for a resized controller to reach the requested number of pods")
+	flag.DurationVar(&cfg.GracePeriod, "grace-period", 60*time.Second, "How long 'stop' waits for a controller's pods to terminate before giving up; zero waits forever")
+	flag.BoolVar(&cfg.AlsoServices, "also-services", false, "If true, 'stop' also deletes services labeled with the controller's selector")
+	flag.IntVar(&cfg.Retries, "retries", 3, "Number of times to retry a read request that fails to reach the server or gets a 429 or 5xx response. Writes are never retried")
+	flag.DurationVar(&cfg.RetryBackoff, "retry-backoff", 500*time.Millisecond, "Delay before the first retry of a failed read request, doubled on each subsequent retry. A Retry-After the server sends takes precedence")
+	flag.StringVar(&cfg.ProfileName, "profile", os.Getenv("KUBECFG_PROFILE"), "The profile in ~/.kubecfg to load the host, auth file, default labels and namespace from. Explicit flags override profile values")
+	flag.StringVar(&cfg.ClientCertificate, "client-certificate", "", "Path to a client certificate for TLS authentication, overriding the auth file. Only used if doing https.")
+	flag.StringVar(&cfg.ClientKey, "client-key", "", "Path to the key of the client certificate, overriding the auth file. Only used if doing https.")
+	flag.StringVar(&cfg.CertificateAuthority, "certificate-authority", "", "Path to a PEM certificate authority used to verify the server, overriding the auth file. Only used if doing https.")
+	flag.BoolVar(&cfg.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "If true, the server's certificate is not verified. This makes connections insecure.")
+	flag.BoolVar(&cfg.Wide, "wide", false, "If true, print additional columns in human readable output, and print every value in full")
+	flag.IntVar(&cfg.MaxColumnWidth, "max-column-width", kubecfg.DefaultMaxColumnWidth, "The number of characters longer values are truncated to in human readable output; negative for no limit. Use --wide or --json to see full values")
+	flag.BoolVar(&cfg.Follow, "follow", false, "If true, 'buildlogs' keeps printing the log until the build finishes")
+	flag.BoolVar(&cfg.Watch, "watch", false, "If true, 'list' prints the matching objects and then each change to them as it happens")
+	flag.BoolVar(&cfg.DryRun, "dry-run", false, "If true, 'create' and 'update' only have the server default and validate the objects, and print them as they would be stored, and 'run' prints the controller it would create")
+	flag.BoolVar(&cfg.Force, "force", false, "If true, 'create' and 'update' store replication controllers even if their replica selector overlaps that of another controller. Identical selectors are always refused")
+	flag.Var((*repeatedFlag)(&cfg.Env), "env", "An environment variable KEY=VALUE of the container 'run' creates. May be repeated")
+	flag.Var((*repeatedFlag)(&cfg.Volumes), "volume", "A host directory hostpath:containerpath mounted in the container 'run' creates. May be repeated")
+	flag.StringVar(&cfg.RestartPolicy, "restart-policy", "", "The restart policy of the pods 'run' creates: always, onFailure or never. Defaults to always")
+	flag.IntVar(&cfg.Memory, "memory", 0, "The memory limit, in bytes, of the container 'run' creates; zero for no limit")
+	flag.IntVar(&cfg.CPU, "cpu", 0, "The CPU limit, in millicores, of the container 'run' creates; zero for no limit")
+	flag.BoolVar(&cfg.Overwrite, "overwrite", false, "If true, 'label' may change the value of labels the object already has")
+	flag.BoolVar(&cfg.Summary, "summary", false, "If true, end human readable lists of pods, builds and replication controllers with a count of them by status")
+	flag.BoolVar(&cfg.NoHeaders, "no-headers", false, "If true, leave the column names and the summary out of human readable output")
+	flag.BoolVar(&cfg.SkipIDCheck, "skip-id-check", false, "If true, 'create' and 'apply' send objects without first checking that their IDs are valid, e.g. to recreate objects with legacy IDs the server still allows")
+	flag.BoolVar(&cfg.MergeLists, "merge-lists", false, "If true, 'update' merges the items of lists in the config into the lists of the object, by name or id, instead of replacing them")
+	flag.Var((*repeatedFlag)(&cfg.Patch), "patch", "A change <field path>=<value> 'update' makes to the object, e.g. desiredState.replicas=3. The value is read as JSON if it is valid JSON. May be repeated, and used with or without a config file")
+	flag.IntVar(&cfg.ConflictRetries, "conflict-retries", 3, "Number of times 'update' reads and merges the object again when writing it conflicts with a concurrent change")
+	flag.StringVar(&cfg.OutputFile, "output-file", "", "If present, also print responses to this file, as --output-file-format, e.g. to archive the JSON of the objects a table shows")
+	flag.StringVar(&cfg.OutputFileFormat, "output-file-format", "json", "The format of the --output-file: json or yaml")
+	flag.BoolVar(&cfg.OutputFileRequired, "output-file-required", false, "If true, fail when the --output-file can't be written to, rather than warning")
+	flag.StringVar(&cfg.ConvertTo, "to", "", "The API version 'convert' rewrites the objects of the config to, e.g. v1beta1")
+	flag.StringVar(&cfg.WaitFor, "for", "", "The condition 'wait' waits for: <field path>=<value> for a field of the object, e.g. currentState.status=Running, or delete for its deletion")
+}
+
+// repeatedFlag is a flag that may be given several times, collecting each value in order.
+// Unlike util.StringList, values are not split on commas.
+type repeatedFlag []string
+
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, " ")
+}
+
+func (f *repeatedFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func (f *repeatedFlag) Type() string {
+	return "string"
 }
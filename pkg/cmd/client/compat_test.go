@@ -0,0 +1,70 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
+)
+
+func TestRunServerVersionCompat(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "pod.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "Pod", "id": "foo"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var serverVersion version.Info
+	var versions int
+	var requests []string
+	handler := statusHandler(t, api.Status{Status: api.StatusSuccess, Code: http.StatusOK})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/version" {
+			versions++
+			data, _ := json.Marshal(serverVersion)
+			w.Write(data)
+			return
+		}
+		requests = append(requests, req.Method+" "+req.URL.RawQuery)
+		handler.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	oldServer := version.Info{Major: "0", Minor: "0"}
+	newServer := version.Info{Major: "0", Minor: "2"}
+	table := []struct {
+		server   version.Info
+		args     []string
+		code     int
+		requests []string
+	}{
+		{oldServer, []string{"create", "pods"}, kubecfg.ExitSuccess, []string{"POST "}},
+		{oldServer, []string{"--dry-run", "--force", "create", "pods"}, kubecfg.ExitError, nil},
+		{oldServer, []string{"apply", "pods"}, kubecfg.ExitError, nil},
+		{oldServer, []string{"--force-compat", "--dry-run", "create", "pods"}, kubecfg.ExitSuccess, []string{"POST dryRun=true"}},
+		{version.Get(), []string{"--dry-run", "create", "pods"}, kubecfg.ExitSuccess, []string{"POST dryRun=true"}},
+		{newServer, []string{"--dry-run", "--force", "create", "pods"}, kubecfg.ExitSuccess, []string{"POST dryRun=true&force=true"}},
+		{newServer, []string{"apply", "pods"}, kubecfg.ExitSuccess, []string{"PUT createIfMissing=true"}},
+	}
+	for _, item := range table {
+		serverVersion, versions, requests = item.server, 0, nil
+		args := append([]string{"--config=" + config}, item.args...)
+		if code := runKubecfg(t, server, args...); code != item.code {
+			t.Errorf("%s %v: expected exit code %d, got %d", item.server, item.args, item.code, code)
+		}
+		if !reflect.DeepEqual(requests, item.requests) {
+			t.Errorf("%s %v: expected requests %q, got %q", item.server, item.args, item.requests, requests)
+		}
+		if versions > 1 {
+			t.Errorf("%s %v: expected the version to be read once, got %d reads", item.server, item.args, versions)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// kubecfgActions are the actions accepted as the first argument of the kubecfg command.
+var kubecfgActions = []string{"apply", "buildlogs", "cancelbuild", "config", "convert", "create", "delete", "describe", "diff", "forward", "get", "label", "list", "rebuild", "resize", "rm", "rollingupdate", "run", "status", "stop", "update", "validate", "wait"}
+
+// resourceActions take a resource type, optionally followed by /<id>.
+var resourceActions = []string{"delete", "describe", "diff", "get", "label", "list", "update", "wait"}
+
+// configActions check or rewrite the objects of a config file, optionally taking their
+// resource type.
+var configActions = []string{"convert", "validate"}
+
+// controllerActions take the name of a replication controller.
+var controllerActions = []string{"resize", "rm", "rollingupdate", "stop"}
+
+// buildActions take the id of a build.
+var buildActions = []string{"buildlogs", "cancelbuild", "rebuild"}
+
+// podActions take the id of a pod.
+var podActions = []string{"forward"}
+
+// completionTimeout bounds how long completion waits for the server when listing names.
+const completionTimeout = 2 * time.Second
+
+// newCommandCompletion returns the 'completion' command of the kubecfg command 'kubeCmd'.
+func newCommandCompletion(kubeCmd *cobra.Command, cfg *KubeConfig) *cobra.Command {
+	return &cobra.Command{
+		Use:   "completion bash|zsh",
+		Short: "Print a shell completion script",
+		Long: `Print a shell completion script for bash or zsh. To enable completion, load the script from
+your shell profile, for example:
+
+    source <(openshift kube completion bash)`,
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) < 1 {
+				c.Help()
+				exit(kubecfg.ExitUsage)
+			}
+			switch args[0] {
+			case "bash":
+				writeBashCompletion(os.Stdout, kubeCmd)
+			case "zsh":
+				writeZshCompletion(os.Stdout, kubeCmd)
+			case "names":
+				// Used by the completion scripts; errors are deliberately silent.
+				if len(args) == 2 && cfg.ApplyProfile(kubeCmd.Flags()) == nil {
+					cfg.completeNames(args[1], os.Stdout)
+				}
+			default:
+				c.Help()
+				exit(kubecfg.ExitUsage)
+			}
+		},
+	}
+}
+
+// completeNames prints the ids of the objects in 'storage', one per line. Nothing is printed
+// if the server can't be reached within completionTimeout.
+func (c *KubeConfig) completeNames(storage string, w io.Writer) {
+	_, _, client, err := c.connect(false)
+	if err != nil {
+		return
+	}
+	client.RetryPolicy.MaxRetries = 0
+	result := make(chan []string, 1)
+	go func() {
+		obj, err := client.Get().Namespace(c.Namespace).Path(storage).Do().Get()
+		if err != nil {
+			result <- nil
+			return
+		}
+		ids, _ := kubecfg.ItemIDs(obj)
+		result <- ids
+	}()
+	select {
+	case ids := <-result:
+		sort.Strings(ids)
+		for _, id := range ids {
+			fmt.Fprintln(w, id)
+		}
+	case <-time.After(completionTimeout):
+	}
+}
+
+// writeZshCompletion writes a zsh completion script, which reuses the bash script through
+// zsh's bash completion emulation.
+func writeZshCompletion(w io.Writer, kubeCmd *cobra.Command) {
+	fmt.Fprintf(w, "#compdef %s\n\nautoload -U +X bashcompinit && bashcompinit\n\n", kubeCmd.Root().Name())
+	writeBashCompletion(w, kubeCmd)
+}
+
+// writeBashCompletion writes a bash completion script for the command tree containing
+// kubeCmd, completing subcommands and flags of every command, and the actions, resource
+// types and object names of kubeCmd. The output depends only on the command tree, so it
+// is stable across invocations.
+func writeBashCompletion(w io.Writer, kubeCmd *cobra.Command) {
+	root := kubeCmd.Root()
+	name := root.Name()
+	fn := "_" + completionIdentifier(name)
+	kubePath := commandPath(kubeCmd)
+	types := kubecfg.SupportedWireStorage()
+	sort.Strings(types)
+	actions := append(append([]string{}, kubecfgActions...), subcommandNames(kubeCmd)...)
+	sort.Strings(actions)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "# bash completion for %s\n", name)
+	fmt.Fprintf(buf, "# Generated by '%s completion bash', do not edit.\n\n", kubePath)
+
+	fmt.Fprintf(buf, "%s_names()\n{\n", fn)
+	fmt.Fprintf(buf, "    %s completion names \"$1\" 2>/dev/null\n}\n\n", kubePath)
+
+	fmt.Fprintf(buf, "%s()\n{\n", fn)
+	fmt.Fprintf(buf, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\"\n")
+	fmt.Fprintf(buf, "    local path=%q action=\"\" resource=\"\" skip=\"\" words=\"\" word i\n", name)
+	fmt.Fprintf(buf, "    for (( i=1; i < COMP_CWORD; i++ )); do\n")
+	fmt.Fprintf(buf, "        word=\"${COMP_WORDS[i]}\"\n")
+	fmt.Fprintf(buf, "        if [[ -n \"$skip\" ]]; then\n            skip=\"\"\n            continue\n        fi\n")
+	fmt.Fprintf(buf, "        case \"$path $word\" in\n")
+	walkCommands(root, func(cmd *cobra.Command) {
+		if flags := valueFlags(cmd); len(flags) > 0 {
+			patterns := []string{}
+			for _, flag := range flags {
+				patterns = append(patterns, fmt.Sprintf("%q", commandPath(cmd)+" "+flag))
+			}
+			fmt.Fprintf(buf, "            %s)\n                skip=1\n                continue\n                ;;\n", strings.Join(patterns, "|"))
+		}
+	})
+	fmt.Fprintf(buf, "            *\" -\"*)\n                continue\n                ;;\n")
+	walkCommands(root, func(cmd *cobra.Command) {
+		if cmd != root {
+			fmt.Fprintf(buf, "            %q)\n                path=%q\n                continue\n                ;;\n", commandPath(cmd), commandPath(cmd))
+		}
+	})
+	fmt.Fprintf(buf, "        esac\n")
+	fmt.Fprintf(buf, "        if [[ \"$path\" == %q ]]; then\n", kubePath)
+	fmt.Fprintf(buf, "            if [[ -z \"$action\" ]]; then\n                action=\"$word\"\n")
+	fmt.Fprintf(buf, "            elif [[ -z \"$resource\" ]]; then\n                resource=\"$word\"\n            fi\n")
+	fmt.Fprintf(buf, "        fi\n    done\n\n")
+
+	fmt.Fprintf(buf, "    if [[ \"$cur\" == -* ]]; then\n        case \"$path\" in\n")
+	walkCommands(root, func(cmd *cobra.Command) {
+		fmt.Fprintf(buf, "            %q)\n                words=%q\n                ;;\n", commandPath(cmd), strings.Join(flagNames(cmd), " "))
+	})
+	fmt.Fprintf(buf, "        esac\n")
+	fmt.Fprintf(buf, "        COMPREPLY=( $(compgen -W \"$words\" -- \"$cur\") )\n        return 0\n    fi\n\n")
+
+	fmt.Fprintf(buf, "    case \"$path\" in\n")
+	walkCommands(root, func(cmd *cobra.Command) {
+		if cmd == kubeCmd {
+			return
+		}
+		if subcommands := subcommandNames(cmd); len(subcommands) > 0 {
+			fmt.Fprintf(buf, "        %q)\n            words=%q\n            ;;\n", commandPath(cmd), strings.Join(subcommands, " "))
+		}
+	})
+	fmt.Fprintf(buf, "        %q)\n", kubePath)
+	fmt.Fprintf(buf, "            case \"$action\" in\n")
+	fmt.Fprintf(buf, "                \"\")\n                    words=%q\n                    ;;\n", strings.Join(actions, " "))
+	fmt.Fprintf(buf, "                %s)\n", strings.Join(resourceActions, "|"))
+	fmt.Fprintf(buf, "                    if [[ -n \"$resource\" ]]; then\n                        return 0\n                    fi\n")
+	fmt.Fprintf(buf, "                    words=%q\n", strings.Join(types, " "))
+	fmt.Fprintf(buf, "                    if [[ \"$action\" != list && \"$cur\" == */* ]]; then\n")
+	fmt.Fprintf(buf, "                        words=\"$(%s_names \"${cur%%%%/*}\" | sed \"s|^|${cur%%%%/*}/|\")\"\n", fn)
+	fmt.Fprintf(buf, "                    fi\n                    ;;\n")
+	fmt.Fprintf(buf, "                %s)\n", strings.Join(configActions, "|"))
+	fmt.Fprintf(buf, "                    if [[ -n \"$resource\" ]]; then\n                        return 0\n                    fi\n")
+	fmt.Fprintf(buf, "                    words=%q\n                    ;;\n", strings.Join(types, " "))
+	fmt.Fprintf(buf, "                %s)\n", strings.Join(controllerActions, "|"))
+	fmt.Fprintf(buf, "                    if [[ -n \"$resource\" ]]; then\n                        return 0\n                    fi\n")
+	fmt.Fprintf(buf, "                    words=\"$(%s_names replicationControllers)\"\n                    ;;\n", fn)
+	fmt.Fprintf(buf, "                %s)\n", strings.Join(buildActions, "|"))
+	fmt.Fprintf(buf, "                    if [[ -n \"$resource\" ]]; then\n                        return 0\n                    fi\n")
+	fmt.Fprintf(buf, "                    words=\"$(%s_names builds)\"\n                    ;;\n", fn)
+	fmt.Fprintf(buf, "                %s)\n", strings.Join(podActions, "|"))
+	fmt.Fprintf(buf, "                    if [[ -n \"$resource\" ]]; then\n                        return 0\n                    fi\n")
+	fmt.Fprintf(buf, "                    words=\"$(%s_names pods)\"\n                    ;;\n", fn)
+	fmt.Fprintf(buf, "                config)\n                    words=\"list use\"\n                    ;;\n")
+	fmt.Fprintf(buf, "                status)\n                    words=\"pods\"\n                    ;;\n")
+	fmt.Fprintf(buf, "            esac\n            ;;\n")
+	fmt.Fprintf(buf, "    esac\n")
+	fmt.Fprintf(buf, "    COMPREPLY=( $(compgen -W \"$words\" -- \"$cur\") )\n}\n\n")
+	fmt.Fprintf(buf, "complete -F %s %s\n", fn, name)
+	w.Write(buf.Bytes())
+}
+
+// walkCommands calls fn for cmd and each of its descendants, in name order.
+func walkCommands(cmd *cobra.Command, fn func(*cobra.Command)) {
+	fn(cmd)
+	commands := append([]*cobra.Command{}, cmd.Commands()...)
+	sort.Sort(byName(commands))
+	for _, child := range commands {
+		walkCommands(child, fn)
+	}
+}
+
+// commandPath returns the space separated names of cmd and its parents, starting at the root.
+func commandPath(cmd *cobra.Command) string {
+	if cmd.HasParent() {
+		return commandPath(cmd.Parent()) + " " + cmd.Name()
+	}
+	return cmd.Name()
+}
+
+// subcommandNames returns the sorted names of the children of cmd.
+func subcommandNames(cmd *cobra.Command) []string {
+	names := []string{}
+	for _, child := range cmd.Commands() {
+		names = append(names, child.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// flagNames returns the sorted long and short forms of the flags of cmd.
+func flagNames(cmd *cobra.Command) []string {
+	names := []string{}
+	visitFlags(cmd, func(flag *pflag.Flag) {
+		names = append(names, "--"+flag.Name)
+		if len(flag.Shorthand) > 0 {
+			names = append(names, "-"+flag.Shorthand)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+// valueFlags returns the sorted forms of the flags of cmd that consume the following word.
+func valueFlags(cmd *cobra.Command) []string {
+	names := []string{}
+	visitFlags(cmd, func(flag *pflag.Flag) {
+		if typed, ok := flag.Value.(interface {
+			Type() string
+		}); ok && typed.Type() == "bool" {
+			return
+		}
+		names = append(names, "--"+flag.Name)
+		if len(flag.Shorthand) > 0 {
+			names = append(names, "-"+flag.Shorthand)
+		}
+	})
+	sort.Strings(names)
+	return names
+}
+
+// visitFlags calls fn for the local and persistent flags of cmd, and the persistent flags
+// inherited from its parents.
+func visitFlags(cmd *cobra.Command, fn func(*pflag.Flag)) {
+	seen := map[string]bool{}
+	visit := func(flag *pflag.Flag) {
+		if !seen[flag.Name] {
+			seen[flag.Name] = true
+			fn(flag)
+		}
+	}
+	cmd.Flags().VisitAll(visit)
+	for c := cmd; c != nil; c = c.Parent() {
+		c.PersistentFlags().VisitAll(visit)
+	}
+}
+
+// completionIdentifier turns a command name into a valid shell function name.
+func completionIdentifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+type byName []*cobra.Command
+
+func (c byName) Len() int           { return len(c) }
+func (c byName) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c byName) Less(i, j int) bool { return c[i].Name() < c[j].Name() }
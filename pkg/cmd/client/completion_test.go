@@ -0,0 +1,56 @@
+package client
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+var updateGolden = flag.Bool("update", false, "If true, rewrite the golden completion scripts in testdata")
+
+// completionTree returns the kubecfg command as embedded in the openshift command.
+func completionTree() *cobra.Command {
+	root := &cobra.Command{Use: "openshift"}
+	kube := NewCommandKubecfg("kube")
+	root.AddCommand(kube)
+	return kube
+}
+
+func TestBashCompletionGolden(t *testing.T) {
+	out := &bytes.Buffer{}
+	writeBashCompletion(out, completionTree())
+
+	golden := filepath.Join("testdata", "completion.bash")
+	if *updateGolden {
+		if err := ioutil.WriteFile(golden, out.Bytes(), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(expected, out.Bytes()) {
+		t.Errorf("bash completion differs from %s, run the test with -update if the change is intended:\n%s", golden, out.String())
+	}
+
+	again := &bytes.Buffer{}
+	writeBashCompletion(again, completionTree())
+	if !bytes.Equal(out.Bytes(), again.Bytes()) {
+		t.Errorf("bash completion is not stable across invocations")
+	}
+}
+
+func TestZshCompletion(t *testing.T) {
+	bash, zsh := &bytes.Buffer{}, &bytes.Buffer{}
+	writeBashCompletion(bash, completionTree())
+	writeZshCompletion(zsh, completionTree())
+	if !strings.HasPrefix(zsh.String(), "#compdef openshift\n") || !strings.HasSuffix(zsh.String(), bash.String()) {
+		t.Errorf("unexpected zsh completion:\n%s", zsh.String())
+	}
+}
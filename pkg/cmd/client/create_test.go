@@ -0,0 +1,257 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunDryRun(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "pod.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "Pod", "id": "foo"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		var query string
+		handler := statusHandler(t, api.Status{Status: api.StatusSuccess, Code: http.StatusOK})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			query = req.URL.Query().Get("dryRun")
+			handler.ServeHTTP(w, req)
+		}))
+		args := []string{"--config=" + config, "create", "pods"}
+		if dryRun {
+			args = append([]string{"--dry-run"}, args...)
+		}
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitSuccess {
+			t.Errorf("dry run %t: unexpected exit code %d", dryRun, code)
+		}
+		if (query == "true") != dryRun {
+			t.Errorf("dry run %t: unexpected dryRun parameter %q", dryRun, query)
+		}
+		server.Close()
+	}
+}
+
+func TestRunApply(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "pod.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "Pod", "id": "foo"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	noID := filepath.Join(dir, "noid.json")
+	if err := ioutil.WriteFile(noID, []byte(`{"kind": "Pod"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var method, path, query string
+	handler := statusHandler(t, api.Status{Status: api.StatusSuccess, Code: http.StatusCreated})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method, path, query = req.Method, req.URL.Path, req.URL.Query().Get("createIfMissing")
+		handler.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	if code := runKubecfg(t, server, "--config="+config, "apply", "pods"); code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if method != "PUT" || path != "/api/v1beta1/pods/foo" || query != "true" {
+		t.Errorf("expected a PUT creating the object if missing, got %s %s createIfMissing=%q", method, path, query)
+	}
+
+	method = ""
+	if code := runKubecfg(t, server, "--config="+noID, "apply", "pods"); code != kubecfg.ExitError {
+		t.Errorf("expected an object without an id to fail, got exit code %d", code)
+	}
+	if len(method) != 0 {
+		t.Errorf("expected no request for an object without an id, got %s", method)
+	}
+}
+
+func TestRunCreateInvalidID(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "pod.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "Pod", "id": "Frontend"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	requests := 0
+	handler := statusHandler(t, api.Status{Status: api.StatusSuccess, Code: http.StatusCreated})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		handler.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	for _, action := range []string{"create", "apply"} {
+		if code := runKubecfg(t, server, "--config="+config, action, "pods"); code != kubecfg.ExitInvalid {
+			t.Errorf("%s: expected an invalid ID to fail, got exit code %d", action, code)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("expected an invalid ID to be rejected before it is sent, got %d requests", requests)
+	}
+
+	if code := runKubecfg(t, server, "--skip-id-check", "--config="+config, "create", "pods"); code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if requests != 1 {
+		t.Errorf("expected --skip-id-check to send the object, got %d requests", requests)
+	}
+}
+
+func TestRunCreateFromStdin(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	created := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var pod api.Pod
+		body, _ := ioutil.ReadAll(req.Body)
+		if err := api.DecodeInto(body, &pod); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		created = append(created, req.Method+" "+req.URL.Path+" "+pod.ID)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	restoreStdin := withStdin(t, "kind: Pod\nid: foo\n---\nkind: Pod\nid: bar\n")
+	defer restoreStdin()
+	if code := runKubecfg(t, server, "--config=-", "create"); code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	expected := []string{"POST /api/v1beta1/pods foo", "POST /api/v1beta1/pods bar"}
+	if !reflect.DeepEqual(created, expected) {
+		t.Errorf("expected %v, got %v", expected, created)
+	}
+}
+
+func TestReadConfigFromTerminal(t *testing.T) {
+	restoreStdin := withStdin(t, "kind: Pod\nid: foo\n")
+	defer restoreStdin()
+	stdinIsTerminal = func() bool { return true }
+	defer func() { stdinIsTerminal = isTerminal }()
+
+	stderr := os.Stderr
+	out, err := ioutil.TempFile("", "kubecfg-stderr")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(out.Name())
+	os.Stderr = out
+	c := &KubeConfig{Config: "-"}
+	data, err := c.readConfigData()
+	os.Stderr = stderr
+	out.Close()
+	if err != nil || string(data) != "kind: Pod\nid: foo\n" {
+		t.Errorf("unexpected config %q: %v", data, err)
+	}
+	hint, _ := ioutil.ReadFile(out.Name())
+	if !strings.Contains(string(hint), "stdin") {
+		t.Errorf("expected a hint that the config is read from stdin, got %q", hint)
+	}
+	// Stdin is read only once.
+	if again, err := c.readConfigData(); err != nil || string(again) != string(data) {
+		t.Errorf("expected the config read before, got %q: %v", again, err)
+	}
+}
+
+func TestRunForce(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "controller.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "ReplicationController", "id": "front"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, force := range []bool{false, true} {
+		var query string
+		handler := statusHandler(t, api.Status{Status: api.StatusSuccess, Code: http.StatusOK})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			query = req.URL.Query().Get("force")
+			handler.ServeHTTP(w, req)
+		}))
+		args := []string{"--config=" + config, "create", "replicationControllers"}
+		if force {
+			args = append([]string{"--force"}, args...)
+		}
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitSuccess {
+			t.Errorf("force %t: unexpected exit code %d", force, code)
+		}
+		if (query == "true") != force {
+			t.Errorf("force %t: unexpected force parameter %q", force, query)
+		}
+		server.Close()
+	}
+}
+
+func TestRunSelectorConflict(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "controller.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "ReplicationController", "id": "front"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	server := httptest.NewServer(statusHandler(t, api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusConflict,
+		Reason:  api.ReasonTypeConflict,
+		Message: `replicationController "front" conflicts with replicationController "web"`,
+		Details: &api.StatusDetails{
+			Kind: "replicationController",
+			ID:   "front",
+			Causes: []api.StatusCause{
+				{Type: api.CauseTypeFieldValueConflict, Field: "desiredState.replicaSelector", Message: `conflicts with replicationController "web"`},
+			},
+		},
+	}))
+	defer server.Close()
+	if code := runKubecfg(t, server, "--config="+config, "create", "replicationControllers"); code != kubecfg.ExitConflict {
+		t.Errorf("expected exit code %d, got %d", kubecfg.ExitConflict, code)
+	}
+}
+
+func TestRunServicePortConflict(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "service.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "Service", "id": "other", "port": 80, "selector": {"name": "other"}}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	conflict := statusHandler(t, api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusConflict,
+		Reason:  api.ReasonTypeConflict,
+		Details: &api.StatusDetails{Kind: "service", ID: "other", Causes: []api.StatusCause{{Type: api.CauseTypeFieldValueConflict, Field: "port"}}},
+	})
+	listed := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "GET" && req.URL.Query().Get("usedPorts") == "true" {
+			listed = true
+			w.Write([]byte(`{"kind": "UsedPorts", "ports": {"80/TCP": "web"}}`))
+			return
+		}
+		conflict.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+	if code := runKubecfg(t, server, "--config="+config, "create", "services"); code != kubecfg.ExitConflict {
+		t.Errorf("expected exit code %d, got %d", kubecfg.ExitConflict, code)
+	}
+	if !listed {
+		t.Errorf("expected the used ports to be listed to suggest a free one")
+	}
+}
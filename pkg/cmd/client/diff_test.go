@@ -0,0 +1,55 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunDiff(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "pod.yaml")
+	if err := ioutil.WriteFile(config, []byte("kind: Pod\nid: foo\nlabels:\n  name: foo\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	live := &api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo", ResourceVersion: 3},
+		Labels:       map[string]string{"name": "foo"},
+		CurrentState: api.PodState{Status: api.PodRunning},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1beta1/pods/foo" {
+			statusHandler(t, api.Status{Status: api.StatusFailure, Code: http.StatusNotFound}).ServeHTTP(w, req)
+			return
+		}
+		data, err := api.Encode(live)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	if code, output := runKubecfgOutput(t, server, "--config="+config, "diff", "pods/foo"); code != kubecfg.ExitSuccess || len(output) != 0 {
+		t.Errorf("expected no differences, got exit code %d:\n%s", code, output)
+	}
+	live.Labels["name"] = "bar"
+	code, output := runKubecfgOutput(t, server, "--config="+config, "diff", "pods/foo")
+	if code != kubecfg.ExitError || !strings.Contains(output, "-  name: foo\n+  name: bar\n") {
+		t.Errorf("expected the labels to differ, got exit code %d:\n%s", code, output)
+	}
+	if code := runKubecfg(t, server, "--config="+config, "diff", "pods/bar"); code != kubecfg.ExitNotFound {
+		t.Errorf("expected a missing object to exit with %d, got %d", kubecfg.ExitNotFound, code)
+	}
+	if code := runKubecfg(t, server, "diff", "pods"); code != kubecfg.ExitUsage {
+		t.Errorf("expected a usage error, got %d", code)
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunExitCodes(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	config := filepath.Join(dir, "pod.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "Pod", "id": "foo"}`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	table := []struct {
+		status api.Status
+		args   []string
+		code   int
+	}{
+		{api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, []string{"get", "pods/foo"}, kubecfg.ExitSuccess},
+		{api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound}, []string{"get", "pods/foo"}, kubecfg.ExitNotFound},
+		{api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeAlreadyExists}, []string{"--config=" + config, "create", "pods"}, kubecfg.ExitConflict},
+		{api.Status{Status: api.StatusFailure, Code: 422, Reason: api.ReasonTypeInvalid}, []string{"--config=" + config, "create", "pods"}, kubecfg.ExitInvalid},
+		{api.Status{Status: api.StatusFailure, Code: http.StatusInternalServerError}, []string{"delete", "pods/foo"}, kubecfg.ExitError},
+		{api.Status{Status: api.StatusWorking, Code: http.StatusAccepted, Details: &api.StatusDetails{ID: "1"}}, []string{"delete", "pods/foo"}, kubecfg.ExitSuccess},
+		{api.Status{Status: api.StatusFailure, Code: http.StatusInternalServerError}, []string{"--server_version"}, kubecfg.ExitError},
+		{api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, []string{"frobnicate", "pods"}, kubecfg.ExitUsage},
+		{api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, []string{"get", "pods"}, kubecfg.ExitSuccess},
+		{api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, []string{"get", "pods/foo", "bar"}, kubecfg.ExitUsage},
+		{api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, []string{"get", "frobs", "foo"}, kubecfg.ExitUsage},
+		{api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, []string{"describe", "pods/foo"}, kubecfg.ExitSuccess},
+		{api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound}, []string{"describe", "pods/foo"}, kubecfg.ExitNotFound},
+		{api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, []string{"describe", "pods"}, kubecfg.ExitUsage},
+	}
+	for _, item := range table {
+		server := httptest.NewServer(statusHandler(t, item.status))
+		if code := runKubecfg(t, server, item.args...); code != item.code {
+			t.Errorf("%v with %s (%d): expected exit code %d, got %d", item.args, item.status.Status, item.status.Code, item.code, code)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunForward(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		data, _ := api.Encode(&api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound})
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	if code := runKubecfg(t, server, "forward", "foo", "8080:80"); code != kubecfg.ExitNotFound {
+		t.Errorf("expected a missing pod to be reported, got exit code %d", code)
+	}
+	for _, args := range [][]string{
+		{"forward", "foo"},
+		{"forward", "foo", "8080:http"},
+		{"forward", "foo", "8080:80", "1:2:3"},
+	} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+}
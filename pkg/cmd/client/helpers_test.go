@@ -0,0 +1,98 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+// exitCode is the panic value of the exit function installed by runKubecfg.
+type exitCode int
+
+// runKubecfg runs kubecfg with args against server and returns its exit code. Output is
+// discarded.
+func runKubecfg(t *testing.T, server *httptest.Server, args ...string) int {
+	code, _ := runKubecfgOutput(t, server, args...)
+	return code
+}
+
+// runKubecfgOutput runs kubecfg with args against server and returns its exit code and what
+// it printed to stdout. Stderr is discarded.
+func runKubecfgOutput(t *testing.T, server *httptest.Server, args ...string) (code int, output string) {
+	cfg, flags := parseFlags(t, append([]string{"--host=" + server.URL, "--retries=0"}, args...)...)
+	cfg.Args = flags.Args()
+	if err := cfg.ApplyProfile(flags); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stdout, stderr := os.Stdout, os.Stderr
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out, err := ioutil.TempFile("", "kubecfg-stdout")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.Stdout, os.Stderr = out, devNull
+	exit = func(code int) { panic(exitCode(code)) }
+	defer func() {
+		os.Stdout, os.Stderr = stdout, stderr
+		devNull.Close()
+		out.Close()
+		data, err := ioutil.ReadFile(out.Name())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		os.Remove(out.Name())
+		output = string(data)
+		exit = os.Exit
+		if r := recover(); r != nil {
+			c, ok := r.(exitCode)
+			if !ok {
+				panic(r)
+			}
+			code = int(c)
+		}
+	}()
+	cfg.Run()
+	return kubecfg.ExitSuccess, ""
+}
+
+// statusHandler responds to every request with status, encoded with the code of status.
+func statusHandler(t *testing.T, status api.Status) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, err := api.Encode(&status)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.WriteHeader(status.Code)
+		w.Write(data)
+	})
+}
+
+// withStdin replaces stdin with a file holding data, and returns a function restoring it.
+func withStdin(t *testing.T, data string) func() {
+	file, err := ioutil.TempFile("", "kubecfg-stdin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stdin := os.Stdin
+	os.Stdin = file
+	return func() {
+		os.Stdin = stdin
+		file.Close()
+		os.Remove(file.Name())
+	}
+}
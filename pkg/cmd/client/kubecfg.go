@@ -17,42 +17,132 @@ limitations under the License.
 package client
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"reflect"
 	"sort"
-	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"text/template"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	kubeclient "github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 	"github.com/golang/glog"
+	"github.com/spf13/pflag"
 )
 
 type KubeConfig struct {
-	ServerVersion bool
-	PreventSkew   bool
-	HttpServer    string
-	Config        string
-	Selector      string
-	UpdatePeriod  time.Duration
-	PortSpec      string
-	ServicePort   int
-	AuthConfig    string
-	JSON          bool
-	YAML          bool
-	Verbose       bool
-	Proxy         bool
-	WWW           string
-	TemplateFile  string
-	TemplateStr   string
+	ServerVersion         bool
+	PreventSkew           bool
+	ForceCompat           bool
+	HttpServer            string
+	Config                string
+	Selector              string
+	Fields                string
+	Namespace             string
+	AllNamespaces         bool
+	UpdatePeriod          time.Duration
+	PortSpec              string
+	ServicePort           int
+	AuthConfig            string
+	JSON                  bool
+	YAML                  bool
+	Verbose               bool
+	Verbosity             int
+	Proxy                 bool
+	WWW                   string
+	Listen                string
+	UnixSocket            string
+	APIPrefix             string
+	ProxyCertFile         string
+	ProxyKeyFile          string
+	TemplateFile          string
+	TemplateStr           string
+	TemplateRaw           bool
+	StopOnError           bool
+	Yes                   bool
+	Timeout               time.Duration
+	Wait                  bool
+	GracePeriod           time.Duration
+	AlsoServices          bool
+	Retries               int
+	RetryBackoff          time.Duration
+	ProfileName           string
+	InsecureSkipTLSVerify bool
+	ClientCertificate     string
+	ClientKey             string
+	CertificateAuthority  string
+	Follow                bool
+	Wide                  bool
+	Watch                 bool
+	DryRun                bool
+	Force                 bool
+	SkipIDCheck           bool
+	MaxColumnWidth        int
+	Env                   []string
+	Volumes               []string
+	RestartPolicy         string
+	Memory                int
+	CPU                   int
+	Overwrite             bool
+	Summary               bool
+	NoHeaders             bool
+	WaitFor               string
+	MergeLists            bool
+	Patch                 []string
+	ConflictRetries       int
+	OutputFile            string
+	OutputFileFormat      string
+	OutputFileRequired    bool
+	ConvertTo             string
+
+	// insecureSet is true if --insecure-skip-tls-verify was given explicitly, in which case
+	// it overrides the profile and the auth file.
+	insecureSet bool
+
+	// stdinConfig holds the config once read from stdin, when Config is "-".
+	stdinConfig []byte
+
+	// serverConfig is read once, the first time objects are listed with a field selector
+	// or could be printed as they are decoded, to learn which resources the server filters
+	// by fields and whether it streams lists. It is nil if the server doesn't serve its
+	// config.
+	serverConfig     *apiserver.ServerConfig
+	serverConfigOnce sync.Once
+
+	// compat tells which features the server supports, from its version, read once before
+	// the first request that depends on it.
+	compat     *kubecfg.Compat
+	compatOnce sync.Once
+
+	// version is the version of the server, or versionErr why it couldn't be read, once
+	// read.
+	version     *version.Info
+	versionErr  error
+	versionOnce sync.Once
+
+	// outputFile is the --output-file every printer also prints to, opened the first time a
+	// printer is made. It is nil until then, or if it couldn't be opened.
+	outputFile     *os.File
+	outputFileOnce sync.Once
+
+	// progress is where long actions write their steps and result as JSON events, with
+	// --json, instead of printing prose. It is nil for other actions and without --json.
+	progress *kubecfg.ProgressWriter
 
 	Args []string
 }
@@ -68,33 +158,184 @@ func usage(name string) string {
 	return fmt.Sprintf(`
   Kubernetes REST API:
   %[1]s [OPTIONS] get|list|create|delete|update <%[2]s>[/<id>]
+  %[1]s [OPTIONS] get <%[2]s> <id> [...]
+  %[1]s [OPTIONS] [-l <selector>] [--field-selector <selector>] get all|<%[2]s>[,...]
+  %[1]s [OPTIONS] describe <%[2]s>/<id>
+  %[1]s [OPTIONS] -c <file>|- diff <%[2]s>/<id>
+  %[1]s [OPTIONS] [--overwrite] label <%[2]s>/<id> <key>=<value>|<key>- [...]
+  %[1]s [OPTIONS] --for=<field path>=<value>|delete [--timeout <duration>] wait <%[2]s>/<id>
+  %[1]s [OPTIONS] -c <file>|- [--merge-lists] [--patch <field path>=<value> ...] [--conflict-retries <n>] update <%[2]s>/<id>
+  %[1]s [OPTIONS] --patch <field path>=<value> [...] update <%[2]s>/<id>
+  %[1]s [OPTIONS] --wait [--timeout <duration>] create|delete|update <%[2]s>[/<id>]
+  %[1]s [OPTIONS] -c <file or directory>|- create|apply
+  %[1]s [OPTIONS] -c <file>|- --dry-run create|update|apply <%[2]s>[/<id>]
+  %[1]s [OPTIONS] -c <file>|- --force create|update|apply replicationControllers[/<id>]
+  %[1]s [OPTIONS] [-l <selector>] [--field-selector <selector>] [--yes] delete <%[2]s>
+  %[1]s [OPTIONS] [-l <selector>] [--field-selector <selector>] list <%[2]s>
+  %[1]s [OPTIONS] [-l <selector>] --watch list <%[2]s>
+  %[1]s [OPTIONS] [-l <selector>] [--field-selector <selector>] list all|<%[2]s>,<%[2]s>[,...]
+  %[1]s [OPTIONS] --all-namespaces list all|<%[2]s>[,...]
+  %[1]s [OPTIONS] [--summary] [--no-headers] list pods|replicationControllers|builds
+
+  Check or convert config files, without a server:
+  %[1]s [OPTIONS] -c <file or directory>|- validate [<%[2]s>]
+  %[1]s [OPTIONS] -c <file or directory>|- --to <API version> [--yaml] convert [<%[2]s>]
+
+  Shell completion:
+  %[1]s completion bash|zsh
+
+  Manage server profiles in ~/.kubecfg:
+  %[1]s config list
+  %[1]s config use <profile>
 
   Manage replication controllers:
   %[1]s [OPTIONS] stop|rm|rollingupdate <controller>
-  %[1]s [OPTIONS] run <image> <replicas> <controller>
-  %[1]s [OPTIONS] resize <controller> <replicas>
+  %[1]s [OPTIONS] [--grace-period <duration>] [--also-services] stop <controller>
+  %[1]s [OPTIONS] [-c <controller config>] [-u <period>] [--timeout <duration>] rollingupdate <controller>
+  %[1]s [OPTIONS] [--env KEY=VALUE ...] [--volume hostpath:containerpath ...] [--restart-policy always|onFailure|never] [--memory <bytes>] [--cpu <millicores>] [--dry-run] run <image> <replicas> <controller>
+  %[1]s [OPTIONS] [--wait] [--timeout <duration>] resize <controller> <replicas>
+
+  Forward local ports to a pod, until interrupted:
+  %[1]s [OPTIONS] forward <pod> <local port>:<pod port> [...]
+
+  Count pods by status and host:
+  %[1]s [OPTIONS] [-l <selector>] status pods
+
+  Manage builds:
+  %[1]s [OPTIONS] cancelbuild <build>
+  %[1]s [OPTIONS] [--follow] buildlogs <build>
+  %[1]s [OPTIONS] rebuild <build>
 `, name, prettyWireStorage())
 }
 
+// exit terminates kubecfg with code. It is replaced in tests to observe the exit code.
+var exit = os.Exit
+
+// fatalf reports an error and exits with kubecfg.ExitError.
+func fatalf(format string, args ...interface{}) {
+	glog.Errorf(format, args...)
+	util.FlushLogs()
+	exit(kubecfg.ExitError)
+}
+
+// usageErrorf reports an invalid command line and exits with kubecfg.ExitUsage.
+func usageErrorf(format string, args ...interface{}) {
+	glog.Errorf(format, args...)
+	util.FlushLogs()
+	exit(kubecfg.ExitUsage)
+}
+
+// fatalErrorf reports a failed request and exits with the code matching err.
+func fatalErrorf(err error, format string, args ...interface{}) {
+	glog.Errorf(format, args...)
+	util.FlushLogs()
+	exit(kubecfg.ExitCode(err))
+}
+
+// doRequest executes r and returns the object the server responded with. If the server
+// accepts the request without completing it, the operation is polled to completion when
+// --wait is set, and its working Status is returned otherwise.
+func (c *KubeConfig) doRequest(r *kubeclient.Request, client *kubeclient.Client) (interface{}, error) {
+	obj, err := r.PollPeriod(0).Do().Get()
+	statusErr, ok := err.(*kubeclient.StatusErr)
+	if !ok || statusErr.Status.Status != api.StatusWorking {
+		return obj, err
+	}
+	status := &statusErr.Status
+	if status.Details == nil || len(status.Details.ID) == 0 {
+		return status, nil
+	}
+	id := status.Details.ID
+	if c.Verbose {
+		glog.Infof("Operation %s accepted, see %s for its result", id, c.operationURL(id))
+	}
+	if !c.Wait {
+		return status, nil
+	}
+	if c.progress != nil {
+		c.progress.Progress(kubecfg.PhaseAccepted, "operations/"+id, "see "+c.operationURL(id)+" for its result")
+	}
+	obj, err = kubecfg.WaitForOperation(client, id, c.Timeout, c.reportProgress())
+	if _, ok := err.(*kubecfg.OperationTimeoutError); ok {
+		fmt.Fprintf(os.Stderr, "Operation %s is still running, see %s for its result\n", id, c.operationURL(id))
+	}
+	return obj, err
+}
+
+// operationURL returns the location of the operation 'id' on the server.
+func (c *KubeConfig) operationURL(id string) string {
+	return c.masterServer() + "/api/v1beta1/operations/" + id
+}
+
+// exitCodeForFailures returns the exit code shared by all failures, or kubecfg.ExitError if
+// they differ.
+func exitCodeForFailures(failures []error) int {
+	code := kubecfg.ExitCode(failures[0])
+	for _, err := range failures[1:] {
+		if kubecfg.ExitCode(err) != code {
+			return kubecfg.ExitError
+		}
+	}
+	return code
+}
+
 func prettyWireStorage() string {
 	types := kubecfg.SupportedWireStorage()
 	sort.Strings(types)
 	return strings.Join(types, "|")
 }
 
-// readConfig reads and parses pod, replicationController, and service
-// configuration files. If any errors log and exit non-zero.
-func (c *KubeConfig) readConfig(storage string) []byte {
+// stdinIsTerminal reports whether stdin is a terminal. It is replaced in tests.
+var stdinIsTerminal = isTerminal
+
+// isTerminal returns true if stdin is a terminal rather than a pipe or a file.
+func isTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// readConfigData returns the contents of the config file, or of stdin if Config is "-".
+// Stdin is read once, so that actions reading the config more than once see the same data.
+func (c *KubeConfig) readConfigData() ([]byte, error) {
+	if c.Config != "-" {
+		return ioutil.ReadFile(c.Config)
+	}
+	if c.stdinConfig == nil {
+		if stdinIsTerminal() {
+			fmt.Fprintln(os.Stderr, "Reading the config from stdin, end it with Ctrl-D")
+		}
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, err
+		}
+		c.stdinConfig = data
+	}
+	return c.stdinConfig, nil
+}
+
+// readConfigJSON reads the config file as JSON, converting it from YAML if needed. If any
+// errors log and exit non-zero.
+func (c *KubeConfig) readConfigJSON() []byte {
 	if len(c.Config) == 0 {
-		glog.Fatal("Need config file (-c)")
+		usageErrorf("Need config file (-c)")
 	}
-	data, err := ioutil.ReadFile(c.Config)
+	data, err := c.readConfigData()
 	if err != nil {
-		glog.Fatalf("Unable to read %v: %v\n", c.Config, err)
+		fatalf("Unable to read %v: %v\n", c.Config, err)
 	}
-	data, err = kubecfg.ToWireFormat(data, storage)
+	data, err = kubecfg.ToJSON(c.Config, data)
+	if err != nil {
+		fatalf("%v\n", err)
+	}
+	return data
+}
+
+// readConfig reads and parses pod, replicationController, and service
+// configuration files. If any errors log and exit non-zero.
+func (c *KubeConfig) readConfig(storage string) []byte {
+	data, err := kubecfg.ToWireFormat(c.readConfigJSON(), storage)
 	if err != nil {
-		glog.Fatalf("Error parsing %v as an object for %v: %v\n", c.Config, storage, err)
+		fatalf("Error parsing %v as an object for %v: %v\n", c.Config, storage, err)
 	}
 	if c.Verbose {
 		glog.Infof("Parsed config file successfully; sending:\n%v\n", string(data))
@@ -102,71 +343,296 @@ func (c *KubeConfig) readConfig(storage string) []byte {
 	return data
 }
 
-func (c *KubeConfig) Run() {
-	util.InitLogs()
-	defer util.FlushLogs()
+// profilePath returns the location of the profile file.
+func profilePath() string {
+	return os.Getenv("HOME") + "/.kubecfg"
+}
 
-	secure := true
-	var masterServer string
+// ApplyProfile loads the selected profile, or the default one if none was selected, and
+// uses its values for any of the host, auth, label, namespace and insecure-skip-tls-verify
+// flags that were not set explicitly. The namespace of the profile is ignored with
+// --all-namespaces. An error is returned if the profile file can't be loaded or the
+// selected profile doesn't exist.
+func (c *KubeConfig) ApplyProfile(flags *pflag.FlagSet) error {
+	c.insecureSet = flags.Lookup("insecure-skip-tls-verify").Changed
+	config, err := kubecfg.LoadProfileConfig(profilePath())
+	if err != nil {
+		return err
+	}
+	profile, ok, err := config.Profile(c.ProfileName)
+	if err != nil {
+		return fmt.Errorf("Error loading profile: %v", err)
+	}
+	if !ok {
+		return nil
+	}
+	if !flags.Lookup("host").Changed && len(profile.Host) > 0 {
+		c.HttpServer = profile.Host
+	}
+	if !flags.Lookup("auth").Changed && len(profile.Auth) > 0 {
+		c.AuthConfig = profile.Auth
+	}
+	if !flags.Lookup("label").Changed && len(profile.Labels) > 0 {
+		c.Selector = profile.Labels
+	}
+	if !flags.Lookup("namespace").Changed && !c.AllNamespaces && len(profile.Namespace) > 0 {
+		c.Namespace = profile.Namespace
+	}
+	if !c.insecureSet && profile.Insecure {
+		c.InsecureSkipTLSVerify = true
+	}
+	return nil
+}
+
+// RunConfig executes the 'config list' and 'config use <profile>' commands.
+func (c *KubeConfig) RunConfig() {
+	path := profilePath()
+	config, err := kubecfg.LoadProfileConfig(path)
+	if err != nil {
+		fatalf("%v", err)
+	}
+	switch c.Arg(1) {
+	case "list":
+		w := tabwriter.NewWriter(os.Stdout, 10, 4, 3, ' ', 0)
+		defer w.Flush()
+		fmt.Fprintln(w, "CURRENT\tNAME\tHOST\tAUTH\tLABELS\tNAMESPACE")
+		for _, name := range config.Names() {
+			profile := config.Profiles[name]
+			current := ""
+			if name == config.Default {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", current, name, profile.Host, profile.Auth, profile.Labels, profile.Namespace)
+		}
+	case "use":
+		if len(c.Args) != 3 {
+			usageErrorf("usage: kubecfg config use <profile>")
+		}
+		name := c.Arg(2)
+		if _, ok := config.Profiles[name]; !ok {
+			fatalf("No profile named %q in %s", name, path)
+		}
+		config.Default = name
+		if err := config.Save(path); err != nil {
+			fatalf("Error saving %s: %v", path, err)
+		}
+		fmt.Printf("Now using profile %s\n", name)
+	default:
+		usageErrorf("usage: kubecfg config list|use <profile>")
+	}
+}
+
+// masterServer returns the server given by the flags or the environment.
+func (c *KubeConfig) masterServer() string {
 	if len(c.HttpServer) > 0 {
-		masterServer = c.HttpServer
-	} else if len(os.Getenv("KUBERNETES_MASTER")) > 0 {
-		masterServer = os.Getenv("KUBERNETES_MASTER")
-	} else {
-		masterServer = "http://localhost:8080"
+		return c.HttpServer
+	}
+	if len(os.Getenv("KUBERNETES_MASTER")) > 0 {
+		return os.Getenv("KUBERNETES_MASTER")
 	}
+	return "http://localhost:8080"
+}
+
+// connect determines the server to talk to from the flags and environment, loads the auth
+// info for it and returns a client configured to use them. Unless interactive is true, an
+// error is returned instead of prompting for credentials.
+func (c *KubeConfig) connect(interactive bool) (string, *kubeclient.AuthInfo, *kubeclient.Client, error) {
+	secure := true
+	masterServer := c.masterServer()
 	parsedURL, err := url.Parse(masterServer)
 	if err != nil {
-		glog.Fatalf("Unable to parse %v as a URL\n", err)
+		return "", nil, nil, fmt.Errorf("Unable to parse %v as a URL: %v", masterServer, err)
 	}
 	if parsedURL.Scheme != "" && parsedURL.Scheme != "https" {
 		secure = false
 	}
 
 	var auth *kubeclient.AuthInfo
+	client := kubeclient.New(masterServer, nil)
 	if secure {
-		auth, err = kubecfg.LoadAuthInfo(c.AuthConfig, os.Stdin)
-		if err != nil {
-			glog.Fatalf("Error loading auth: %v", err)
+		// A client certificate given on the command line is enough to authenticate, so
+		// don't prompt for a username and password if there is no auth file.
+		_, err := os.Stat(c.AuthConfig)
+		if err != nil && !interactive && len(c.ClientCertificate) == 0 {
+			return "", nil, nil, fmt.Errorf("no auth info in %s", c.AuthConfig)
+		}
+		if len(c.ClientCertificate) == 0 || err == nil {
+			auth, err = kubecfg.LoadAuthInfo(c.AuthConfig, os.Stdin)
+			if err != nil {
+				return "", nil, nil, fmt.Errorf("Error loading auth: %v", err)
+			}
+		}
+		auth = kubecfg.MergeAuthInfo(auth, kubeclient.AuthInfo{
+			CertFile: c.ClientCertificate,
+			KeyFile:  c.ClientKey,
+			CAFile:   c.CertificateAuthority,
+			Insecure: c.InsecureSkipTLSVerify,
+		})
+		if c.insecureSet {
+			auth.Insecure = c.InsecureSkipTLSVerify
 		}
+		if auth.Insecure && interactive {
+			fmt.Fprintf(os.Stderr, "WARNING: the certificate of %s will not be verified, connections are subject to man-in-the-middle attacks\n", masterServer)
+		}
+		if client, err = kubeclient.NewTLS(masterServer, auth); err != nil {
+			return "", nil, nil, fmt.Errorf("Error configuring TLS: %v", err)
+		}
+	}
+	client.RetryPolicy.MaxRetries = c.Retries
+	client.RetryPolicy.Backoff = c.RetryBackoff
+	if c.Verbosity >= 2 {
+		client.Trace(os.Stderr, c.Verbosity >= 3)
+	}
+	return masterServer, auth, client, nil
+}
+
+func (c *KubeConfig) Run() {
+	util.InitLogs()
+	defer util.FlushLogs()
+
+	if c.Verbosity >= 1 {
+		c.Verbose = true
+	}
+	if c.OutputFileFormat != "json" && c.OutputFileFormat != "yaml" {
+		usageErrorf("--output-file-format must be json or yaml, not %q", c.OutputFileFormat)
+	}
+	if c.executeOfflineRequest(c.Arg(0)) {
+		return
 	}
 
-	client := kubeclient.New(masterServer, auth)
+	masterServer, auth, client, err := c.connect(true)
+	if err != nil {
+		fatalf("%v", err)
+	}
 
 	if c.ServerVersion {
-		got, err := client.ServerVersion()
+		got, err := c.serverVersion(client)
 		if err != nil {
-			fmt.Printf("Couldn't read version from server: %v\n", err)
-			os.Exit(1)
+			fatalErrorf(err, "Couldn't read version from server: %v", err)
 		}
 		fmt.Printf("Server Version: %#v\n", got)
-		os.Exit(0)
+		return
 	}
 
 	if c.PreventSkew {
-		got, err := client.ServerVersion()
+		got, err := c.serverVersion(client)
 		if err != nil {
-			fmt.Printf("Couldn't read version from server: %v\n", err)
-			os.Exit(1)
+			fatalErrorf(err, "Couldn't read version from server: %v", err)
 		}
 		if c, s := version.Get(), *got; !reflect.DeepEqual(c, s) {
-			fmt.Printf("Server version (%#v) differs from client version (%#v)!\n", s, c)
-			os.Exit(1)
+			fatalf("Server version (%#v) differs from client version (%#v)!", s, c)
 		}
 	}
 
 	if c.Proxy {
-		glog.Info("Starting to serve on localhost:8001")
-		server := kubecfg.NewProxyServer(c.WWW, masterServer, auth)
-		glog.Fatal(server.Serve())
+		server := kubecfg.NewPrefixedProxyServer(c.WWW, c.APIPrefix, masterServer, auth)
+		server.Client = client
+		server.CertFile = c.ProxyCertFile
+		server.KeyFile = c.ProxyKeyFile
+		if len(c.UnixSocket) > 0 {
+			glog.Infof("Starting to serve on %s", c.UnixSocket)
+			fatalf("%v", server.ListenAndServe("unix", c.UnixSocket))
+		}
+		glog.Infof("Starting to serve on %s", c.Listen)
+		fatalf("%v", server.ListenAndServe("tcp", c.Listen))
+	}
+
+	if c.Verbose {
+		kubecfg.LogServerConfig(client)
 	}
 
 	method := c.Arg(0)
+	if c.AllNamespaces {
+		// Objects are only listed across namespaces: creating or changing one needs a
+		// namespace to put it in.
+		if method != "list" {
+			usageErrorf("--all-namespaces can only be used with list, not %s", method)
+		}
+		if len(c.Namespace) > 0 {
+			usageErrorf("--all-namespaces and --namespace can't be used together")
+		}
+	}
+
+	if c.JSON && reportsProgress(method, c.Wait) {
+		c.progress = kubecfg.NewProgressWriter(os.Stdout)
+	}
 
-	matchFound := c.executeAPIRequest(method, client) || c.executeControllerRequest(method, client)
+	matchFound := c.executeAPIRequest(method, client) || c.executeControllerRequest(method, client) || c.executeBuildRequest(method, client) || c.executePodRequest(method, client)
 	if matchFound == false {
-		glog.Fatalf("Unknown command %s", method)
+		usageErrorf("Unknown command %s", method)
+	}
+}
+
+// serverVersion reads the version of the server the first time it is called, and returns
+// the same version or error after that.
+func (c *KubeConfig) serverVersion(client *kubeclient.Client) (*version.Info, error) {
+	c.versionOnce.Do(func() {
+		c.version, c.versionErr = client.ServerVersion()
+	})
+	return c.version, c.versionErr
+}
+
+// loadCompat sets c.compat from the version of the server, the first time it is called. It
+// warns once on stderr, unless --json was given, if the minor version of the server differs
+// from that of kubecfg. A server that doesn't tell its version is assumed to support every
+// feature.
+func (c *KubeConfig) loadCompat(client *kubeclient.Client) *kubecfg.Compat {
+	c.compatOnce.Do(func() {
+		server, err := c.serverVersion(client)
+		if err != nil {
+			if c.Verbose {
+				glog.Infof("Unable to read the server version: %v", err)
+			}
+			server = nil
+		}
+		c.compat = kubecfg.NewCompat(server, c.ForceCompat)
+		if warning := c.compat.Warning(); len(warning) > 0 && !c.JSON {
+			fmt.Fprintln(os.Stderr, warning)
+		}
+	})
+	return c.compat
+}
+
+// requireFeature exits before a request that needs feature is sent if the server is known
+// to predate it, unless --force-compat was given. Features with a fallback, such as field
+// selectors, are adapted to where they are used instead.
+func (c *KubeConfig) requireFeature(feature kubecfg.Feature, client *kubeclient.Client) {
+	if err := c.loadCompat(client).Check(feature); err != nil {
+		fatalf("Error: %v; use --force-compat to send the request anyway", err)
+	}
+}
+
+// reportsProgress returns true if method is a long action, whose steps kubecfg reports as
+// JSON events with --json: stopping or updating a controller, or waiting for a request to
+// complete with --wait.
+func reportsProgress(method string, wait bool) bool {
+	switch method {
+	case "stop", "rollingupdate":
+		return true
+	case "get", "create", "apply", "update", "delete", "resize":
+		return wait
+	}
+	return false
+}
+
+// reportProgress returns what long actions report their steps to: the JSON event stream
+// with --json, and nothing otherwise.
+func (c *KubeConfig) reportProgress() kubecfg.ProgressFunc {
+	if c.progress == nil {
+		return nil
+	}
+	return c.progress.Progress
+}
+
+// failAction exits with err, the failure of a long action, as the last JSON event with
+// --json and as fatalErrorf does otherwise.
+func (c *KubeConfig) failAction(err error, format string, args ...interface{}) {
+	if c.progress == nil {
+		fatalErrorf(err, format, args...)
+		return
 	}
+	c.progress.Result(err, nil)
+	exit(kubecfg.ExitCode(err))
 }
 
 // storagePathFromArg normalizes a path and breaks out the first segment if available
@@ -190,166 +656,1263 @@ func checkStorage(storage string) bool {
 	return false
 }
 
+// allStorage names the storages 'kubecfg list all' lists.
+var allStorage = []string{"pods", "replicationControllers", "services", "minions"}
+
+// parseResourceList returns the storages named by arg if it is "all" or a comma separated
+// list of storages, and false otherwise. An invalid storage in the list is a usage error of
+// method.
+func parseResourceList(method, arg string) ([]string, bool) {
+	if arg == "all" {
+		return allStorage, true
+	}
+	if !strings.Contains(arg, ",") {
+		return nil, false
+	}
+	resources := strings.Split(arg, ",")
+	for _, storage := range resources {
+		if !checkStorage(storage) {
+			usageErrorf("usage: kubecfg [OPTIONS] %s all|<%s>[,...]", method, prettyWireStorage())
+		}
+	}
+	return resources, true
+}
+
 func (c *KubeConfig) executeAPIRequest(method string, client *kubeclient.Client) bool {
 	storage, path, hasSuffix := storagePathFromArg(c.Arg(1))
 	validStorage := checkStorage(storage)
 	verb := ""
-	setBody := false
-	var version uint64
 	switch method {
 	case "get":
 		verb = "GET"
-		if !validStorage || !hasSuffix {
-			glog.Fatalf("usage: kubecfg [OPTIONS] %s <%s>[/<id>]", method, prettyWireStorage())
+		if resources, ok := parseResourceList(method, c.Arg(1)); ok && len(c.Args) == 2 {
+			c.validateSelectors()
+			return c.listResources(resources, client)
+		}
+		if !validStorage || (hasSuffix && len(c.Args) > 2) {
+			usageErrorf("usage: kubecfg [OPTIONS] %s <%s>[,...]|<%s>/<id>|<%s> <id> [...]", method, prettyWireStorage(), prettyWireStorage(), prettyWireStorage())
+		}
+		if len(c.Args) > 2 {
+			return c.getObjects(storage, c.Args[2:], client)
+		}
+		if !hasSuffix {
+			c.validateSelectors()
+			if c.streamObjects(storage, client) {
+				return true
+			}
+			obj, err := c.listObjects(storage, client, false)
+			c.printResponse(obj, err, client)
+			return true
 		}
 	case "list":
 		verb = "GET"
+		if resources, ok := parseResourceList(method, c.Arg(1)); ok {
+			if c.Watch {
+				usageErrorf("--watch can't be used to list several resources")
+			}
+			c.validateSelectors()
+			return c.listResources(resources, client)
+		}
 		if !validStorage || hasSuffix {
-			glog.Fatalf("usage: kubecfg [OPTIONS] %s <%s>", method, prettyWireStorage())
+			usageErrorf("usage: kubecfg [OPTIONS] %s <%s>", method, prettyWireStorage())
 		}
+		if c.Watch {
+			return c.watchObjects(storage, client)
+		}
+		c.validateSelectors()
+		if c.streamObjects(storage, client) {
+			return true
+		}
+		obj, err := c.listObjects(storage, client, false)
+		c.printResponse(obj, err, client)
+		return true
 	case "delete":
 		verb = "DELETE"
+		if !validStorage {
+			usageErrorf("usage: kubecfg [OPTIONS] %s <%s>/<id>", method, prettyWireStorage())
+		}
+		if !hasSuffix {
+			if len(c.Selector) == 0 && len(c.Fields) == 0 {
+				usageErrorf("delete requires an id or a selector (-l or --field-selector): kubecfg [OPTIONS] %s <%s>/<id>", method, prettyWireStorage())
+			}
+			c.validateSelectors()
+			return c.deleteBySelector(storage, client)
+		}
+	case "describe":
 		if !validStorage || !hasSuffix {
-			glog.Fatalf("usage: kubecfg [OPTIONS] %s <%s>/<id>", method, prettyWireStorage())
+			usageErrorf("usage: kubecfg [OPTIONS] %s <%s>/<id>", method, prettyWireStorage())
 		}
-	case "create":
-		verb = "POST"
-		setBody = true
-		if !validStorage || hasSuffix {
-			glog.Fatalf("usage: kubecfg [OPTIONS] %s <%s>", method, prettyWireStorage())
+		return c.describeObject(path, client)
+	case "diff":
+		if !validStorage || !hasSuffix {
+			usageErrorf("usage: kubecfg [OPTIONS] -c <file> %s <%s>/<id>", method, prettyWireStorage())
 		}
-	case "update":
-		obj, err := client.Verb("GET").Path(path).Do().Get()
-		if err != nil {
-			glog.Fatalf("error obtaining resource version for update: %v", err)
+		return c.diffObject(storage, path, client)
+	case "label":
+		if !validStorage || !hasSuffix || len(c.Args) < 3 {
+			usageErrorf("usage: kubecfg [OPTIONS] [--overwrite] %s <%s>/<id> <key>=<value>|<key>- [...]", method, prettyWireStorage())
 		}
-		jsonBase, err := api.FindJSONBase(obj)
-		if err != nil {
-			glog.Fatalf("error finding json base for update: %v", err)
+		return c.labelObject(path, client)
+	case "wait":
+		// The id may also be given as a separate argument, as in 'wait pods foo'.
+		id := strings.TrimPrefix(path, storage+"/")
+		if !hasSuffix {
+			id = c.Arg(2)
 		}
-		version = jsonBase.ResourceVersion()
-		verb = "PUT"
-		setBody = true
+		if !validStorage || len(id) == 0 || len(c.Args) > 3 || (hasSuffix && len(c.Args) > 2) || len(c.WaitFor) == 0 {
+			usageErrorf("usage: kubecfg [OPTIONS] --for=<field path>=<value>|delete [--timeout <duration>] %s <%s>/<id>", method, prettyWireStorage())
+		}
+		return c.waitForObject(storage, id, client)
+	case "create", "apply":
+		if (len(storage) > 0 && !validStorage) || hasSuffix {
+			usageErrorf("usage: kubecfg [OPTIONS] %s [<%s>]", method, prettyWireStorage())
+		}
+		return c.createObjects(storage, client, method == "apply")
+	case "update":
 		if !validStorage || !hasSuffix {
-			glog.Fatalf("usage: kubecfg [OPTIONS] %s <%s>/<id>", method, prettyWireStorage())
+			usageErrorf("usage: kubecfg [OPTIONS] %s <%s>/<id>", method, prettyWireStorage())
 		}
+		return c.updateObject(storage, path, client)
 	default:
 		return false
 	}
 
 	r := client.Verb(verb).
+		Namespace(c.Namespace).
 		Path(path).
-		ParseSelectorParam("labels", c.Selector)
-	if setBody {
-		if version != 0 {
-			data := c.readConfig(storage)
-			obj, err := api.Decode(data)
-			if err != nil {
-				glog.Fatalf("error setting resource version: %v", err)
+		ParseSelectorParam("labels", c.Selector).
+		ParseSelectorParam("fields", c.Fields)
+	obj, err := c.doRequest(r, client)
+	c.printResponse(obj, err, client)
+	return true
+}
+
+// printResponse prints obj, the response to a request, or exits with err if the request
+// failed, after printing the invalid fields the server named.
+func (c *KubeConfig) printResponse(obj interface{}, err error, client *kubeclient.Client) {
+	if c.progress != nil {
+		if err != nil {
+			c.failAction(err, "Got request error: %v\n", err)
+			return
+		}
+		c.progress.Result(nil, obj)
+		return
+	}
+	if err != nil {
+		if !c.JSON && !c.YAML {
+			for _, line := range kubecfg.InvalidFields(err) {
+				fmt.Fprintln(os.Stderr, line)
 			}
-			jsonBase, err := api.FindJSONBase(obj)
-			if err != nil {
-				glog.Fatalf("error setting resource version: %v", err)
+		}
+		fatalErrorf(err, "Got request error: %v\n", err)
+		return
+	}
+	c.printObject(obj, client)
+}
+
+// printObject prints obj with the selected printer.
+func (c *KubeConfig) printObject(obj interface{}, client *kubeclient.Client) {
+	printer := c.getPrinter()
+	c.addEndpoints(printer, obj, client)
+	c.addReplicas(printer, obj, client)
+	if err := printer.PrintObj(obj, os.Stdout); err != nil {
+		fatalf("Failed to print: %v\nRaw received object:\n%#v", err, obj)
+	}
+	fmt.Print("\n")
+}
+
+// updateObject merges the config file and the --patch changes over the object at path, read
+// from storage, and writes it back with the resource version it was read at. The object is
+// read and merged again up to --conflict-retries more times if the write conflicts with a
+// concurrent change.
+func (c *KubeConfig) updateObject(storage, path string, client *kubeclient.Client) bool {
+	changes, err := kubecfg.ParseFieldChanges(c.Patch)
+	if err != nil {
+		usageErrorf("Error parsing --patch: %v", err)
+	}
+	var config []byte
+	if len(c.Config) > 0 {
+		config = c.readConfigJSON()
+	} else if len(changes) == 0 {
+		usageErrorf("update needs a config file (-c) or changes (--patch)")
+	}
+	for attempt := 0; ; attempt++ {
+		live, err := client.Get().Namespace(c.Namespace).Path(path).Do().Raw()
+		if err != nil {
+			fatalErrorf(err, "Error reading %s to update it: %v", path, err)
+		}
+		merged, err := kubecfg.MergeObject(live, config, changes, c.MergeLists)
+		if err != nil {
+			fatalf("Error merging the changes to %s: %v", path, err)
+		}
+		data, err := kubecfg.ToWireFormat(merged, storage)
+		if err != nil {
+			fatalf("Error parsing the merged %s as an object for %s: %v", path, storage, err)
+		}
+		if c.Verbose {
+			indented := &bytes.Buffer{}
+			json.Indent(indented, merged, "", "  ")
+			glog.Infof("Merged %s; sending:\n%s\n", path, indented)
+		}
+		r := client.Put().Namespace(c.Namespace).Path(path).Body(data)
+		c.dryRunParam(r, client)
+		c.forceParam(r, client)
+		obj, err := c.doRequest(r, client)
+		if err != nil && kubeclient.IsConflict(err) && attempt < c.ConflictRetries {
+			glog.Infof("Update of %s conflicted, retrying", path)
+			continue
+		}
+		c.printResponse(obj, err, client)
+		return true
+	}
+}
+
+// addEndpoints gives printer the endpoints of services if it prints obj, a service or a
+// list of services, as a wide table. If they can't be read, the table shows the number of
+// endpoints of each service as unknown.
+func (c *KubeConfig) addEndpoints(printer kubecfg.ResourcePrinter, obj interface{}, client *kubeclient.Client) {
+	human, ok := humanPrinter(printer)
+	if !ok || !human.Wide {
+		return
+	}
+	switch obj.(type) {
+	case *api.Service, *api.ServiceList:
+	default:
+		return
+	}
+	list := api.EndpointsList{}
+	if err := client.Get().Namespace(c.Namespace).Path("endpoints").Do().Into(&list); err != nil {
+		glog.Warningf("Unable to read the endpoints of services: %v", err)
+		return
+	}
+	human.Endpoints = kubecfg.EndpointsByService(&list)
+}
+
+// addReplicas gives printer the current replicas of replication controllers if it prints a
+// summary of obj, a list of controllers. If the pods can't be read, the summary shows only
+// the desired replicas.
+func (c *KubeConfig) addReplicas(printer kubecfg.ResourcePrinter, obj interface{}, client *kubeclient.Client) {
+	human, ok := humanPrinter(printer)
+	if !ok || !human.Summary || human.NoHeaders {
+		return
+	}
+	controllers, ok := obj.(*api.ReplicationControllerList)
+	if !ok {
+		return
+	}
+	pods := api.PodList{}
+	if err := client.Get().Namespace(c.Namespace).Path("pods").Do().Into(&pods); err != nil {
+		glog.Warningf("Unable to read the pods of replication controllers: %v", err)
+		return
+	}
+	human.Replicas = kubecfg.ReplicasByController(controllers, &pods)
+}
+
+// humanPrinter returns the HumanReadablePrinter printer is, or prints with.
+func humanPrinter(printer kubecfg.ResourcePrinter) (*kubecfg.HumanReadablePrinter, bool) {
+	if tee, ok := printer.(*kubecfg.TeePrinter); ok {
+		for _, sink := range tee.Sinks {
+			if human, ok := sink.Printer.(*kubecfg.HumanReadablePrinter); ok {
+				return human, true
 			}
-			jsonBase.SetResourceVersion(version)
-			data, err = api.Encode(obj)
-			if err != nil {
-				glog.Fatalf("error setting resource version: %v", err)
+		}
+		return nil, false
+	}
+	human, ok := printer.(*kubecfg.HumanReadablePrinter)
+	return human, ok
+}
+
+// getPrinter returns the ResourcePrinter selected by the output flags: the printer of the
+// terminal, which also prints to the --output-file if one is given.
+func (c *KubeConfig) getPrinter() kubecfg.ResourcePrinter {
+	printer := c.terminalPrinter()
+	if len(c.OutputFile) == 0 {
+		return printer
+	}
+	var filePrinter kubecfg.ResourcePrinter = &kubecfg.JSONPrinter{}
+	if c.OutputFileFormat == "yaml" {
+		filePrinter = &kubecfg.YAMLPrinter{}
+	}
+	c.outputFileOnce.Do(func() {
+		file, err := os.Create(c.OutputFile)
+		if err != nil {
+			if c.OutputFileRequired {
+				fatalf("Unable to open the output file: %v", err)
 			}
-			r.Body(data)
-		} else {
-			r.Body(c.readConfig(storage))
+			fmt.Fprintf(os.Stderr, "Warning: unable to open the output file, output is only printed: %v\n", err)
+			return
 		}
+		c.outputFile = file
+	})
+	if c.outputFile == nil {
+		return printer
 	}
-	result := r.Do()
-	obj, err := result.Get()
-	if err != nil {
-		glog.Fatalf("Got request error: %v\n", err)
-		return false
+	return &kubecfg.TeePrinter{
+		Sinks: []kubecfg.TeeSink{
+			{Printer: printer},
+			{Printer: filePrinter, Out: c.outputFile, Optional: !c.OutputFileRequired},
+		},
+		OnError: func(sink kubecfg.TeeSink, err error) {
+			fmt.Fprintf(os.Stderr, "Warning: unable to write to the output file %s: %v\n", c.OutputFile, err)
+		},
 	}
+}
 
-	var printer kubecfg.ResourcePrinter
+// terminalPrinter returns the ResourcePrinter of the terminal selected by the output flags.
+func (c *KubeConfig) terminalPrinter() kubecfg.ResourcePrinter {
 	switch {
 	case c.JSON:
-		printer = &kubecfg.IdentityPrinter{}
+		return &kubecfg.JSONPrinter{}
 	case c.YAML:
-		printer = &kubecfg.YAMLPrinter{}
+		return &kubecfg.YAMLPrinter{}
 	case len(c.TemplateFile) > 0 || len(c.TemplateStr) > 0:
 		var data []byte
 		if len(c.TemplateFile) > 0 {
 			var err error
 			data, err = ioutil.ReadFile(c.TemplateFile)
 			if err != nil {
-				glog.Fatalf("Error reading template %s, %v\n", c.TemplateFile, err)
-				return false
+				fatalf("Error reading template %s, %v\n", c.TemplateFile, err)
 			}
 		} else {
 			data = []byte(c.TemplateStr)
 		}
 		tmpl, err := template.New("output").Parse(string(data))
 		if err != nil {
-			glog.Fatalf("Error parsing template %s, %v\n", string(data), err)
-			return false
+			fatalf("Error parsing template %s, %v\n", string(data), err)
 		}
-		printer = &kubecfg.TemplatePrinter{
+		return &kubecfg.TemplatePrinter{
 			Template: tmpl,
+			Raw:      c.TemplateRaw,
 		}
 	default:
-		printer = &kubecfg.HumanReadablePrinter{}
+		return &kubecfg.HumanReadablePrinter{Wide: c.Wide, MaxColumnWidth: c.MaxColumnWidth, NoHeaders: c.NoHeaders, Summary: c.Summary, Namespaces: c.AllNamespaces}
 	}
+}
 
-	if err = printer.PrintObj(obj, os.Stdout); err != nil {
-		body, _ := result.Raw()
-		glog.Fatalf("Failed to print: %v\nRaw received object:\n%#v\n\nBody received: %v", err, obj, string(body))
+// createObjects creates every object found in the config file, directory or stdin, in order,
+// printing one result per object. If apply is true, objects that already exist are
+// updated instead, so that the same config can be sent again safely. The target storage
+// of each object is inferred from its kind unless 'storage' is provided. Failures,
+// including files and documents that can't be parsed, are reported against the file and
+// document index the object was read from.
+func (c *KubeConfig) createObjects(storage string, client *kubeclient.Client, apply bool) bool {
+	objects := c.readConfigObjects()
+	printer := c.getPrinter()
+	failures := []error{}
+	for _, object := range objects {
+		if err := c.createObject(object, storage, client, printer, apply); err != nil {
+			failures = append(failures, err)
+			if c.progress != nil {
+				c.progress.Progress(kubecfg.PhaseFailed, object.String(), err.Error())
+				if c.StopOnError {
+					break
+				}
+				continue
+			}
+			action := "creating"
+			if apply {
+				action = "applying"
+			}
+			fmt.Fprintf(os.Stderr, "Error %s %v: %v\n", action, object, err)
+			for _, line := range kubecfg.InvalidFields(err) {
+				fmt.Fprintf(os.Stderr, "  %s\n", line)
+			}
+			if line := suggestServicePort(object, err, client); len(line) != 0 {
+				fmt.Fprintf(os.Stderr, "  %s\n", line)
+			}
+			if c.StopOnError {
+				break
+			}
+		}
 	}
-	fmt.Print("\n")
-
+	c.exitForFailures(failures, len(objects))
 	return true
 }
 
-func (c *KubeConfig) executeControllerRequest(method string, client *kubeclient.Client) bool {
-	parseController := func() string {
-		if len(c.Args) != 2 {
-			glog.Fatal("usage: kubecfg [OPTIONS] stop|rm|rollingupdate <controller>")
+// exitForFailures exits with the code shared by failures, the errors of an action on total
+// objects, if there are any. With --json, it ends the JSON events of the action with its
+// result, whether it failed or not.
+func (c *KubeConfig) exitForFailures(failures []error, total int) {
+	code := kubecfg.ExitSuccess
+	if len(failures) > 0 {
+		code = exitCodeForFailures(failures)
+	}
+	if c.progress != nil {
+		message := ""
+		if len(failures) > 0 {
+			message = fmt.Sprintf("%d of %d failed", len(failures), total)
 		}
-		return c.Arg(1)
+		c.progress.ResultCode(code, message, nil)
+	}
+	if code != kubecfg.ExitSuccess {
+		exit(code)
 	}
+}
 
-	var err error
-	switch method {
-	case "stop":
-		err = kubecfg.StopController(parseController(), client)
-	case "rm":
-		err = kubecfg.DeleteController(parseController(), client)
-	case "rollingupdate":
-		err = kubecfg.Update(parseController(), client, c.UpdatePeriod)
-	case "run":
-		if len(c.Args) != 4 {
-			glog.Fatal("usage: kubecfg [OPTIONS] run <image> <replicas> <controller>")
-		}
-		image := c.Arg(1)
-		replicas, err := strconv.Atoi(c.Arg(2))
-		name := c.Arg(3)
-		if err != nil {
-			glog.Fatalf("Error parsing replicas: %v", err)
-		}
-		err = kubecfg.RunController(image, name, replicas, client, c.PortSpec, c.ServicePort)
-	case "resize":
-		args := c.Args
+// suggestServicePort returns a line suggesting a free port for object, a service the server
+// refused with err because another service uses its port, or "" if it was refused otherwise.
+func suggestServicePort(object kubecfg.ConfigObject, refused error, client *kubeclient.Client) string {
+	if !kubecfg.IsPortConflict(refused) {
+		return ""
+	}
+	data, err := kubecfg.ToJSON(object.Source, object.Data)
+	if err != nil {
+		return ""
+	}
+	var service api.Service
+	if err := json.Unmarshal(data, &service); err != nil {
+		return ""
+	}
+	return kubecfg.SuggestServicePort(client, &service, refused)
+}
+
+// readConfigObjects returns every object in the config file, directory or stdin, as
+// kubecfg.LoadConfigObjects does. Objects that can't be read are returned with Err set.
+func (c *KubeConfig) readConfigObjects() []kubecfg.ConfigObject {
+	if len(c.Config) == 0 {
+		usageErrorf("Need config file (-c)")
+	}
+	if c.Config == "-" {
+		data, err := c.readConfigData()
+		if err != nil {
+			fatalf("Unable to read stdin: %v\n", err)
+		}
+		return kubecfg.ParseConfigObjects("stdin", data)
+	}
+	objects, err := kubecfg.LoadConfigObjects(c.Config)
+	if err != nil {
+		fatalf("Unable to read %v: %v\n", c.Config, err)
+	}
+	return objects
+}
+
+// createObject creates object, or creates or updates it by its ID if apply is true.
+func (c *KubeConfig) createObject(object kubecfg.ConfigObject, storage string, client *kubeclient.Client, printer kubecfg.ResourcePrinter, apply bool) error {
+	if object.Err != nil {
+		return object.Err
+	}
+	storage, err := object.Storage(storage)
+	if err != nil {
+		return err
+	}
+	data, err := kubecfg.ToJSON(object.Source, object.Data)
+	if err != nil {
+		return err
+	}
+	data, err = kubecfg.ToWireFormat(data, storage)
+	if err != nil {
+		return fmt.Errorf("error parsing as an object for %v: %v", storage, err)
+	}
+	if !c.SkipIDCheck {
+		if err := kubecfg.ValidateID(data); err != nil {
+			return err
+		}
+	}
+	if c.Verbose {
+		glog.Infof("Parsed %v successfully; sending to %v:\n%v\n", object, storage, string(data))
+	}
+	r := client.Verb("POST").Namespace(c.Namespace).Path(storage).Body(data)
+	if apply {
+		obj, err := api.Decode(data)
+		if err != nil {
+			return fmt.Errorf("error parsing as an object for %v: %v", storage, err)
+		}
+		jsonBase, err := api.FindJSONBase(obj)
+		if err != nil {
+			return err
+		}
+		if len(jsonBase.ID()) == 0 {
+			return fmt.Errorf("an object needs an id to be applied")
+		}
+		c.requireFeature(kubecfg.FeatureCreateIfMissing, client)
+		r = client.Verb("PUT").Namespace(c.Namespace).Path(storage).Path(jsonBase.ID()).Param("createIfMissing", "true").Body(data)
+	}
+	c.dryRunParam(r, client)
+	c.forceParam(r, client)
+	obj, err := c.doRequest(r, client)
+	if err != nil {
+		return err
+	}
+	if c.progress != nil {
+		c.progress.Progress(kubecfg.PhaseCreated, object.String(), "")
+		return nil
+	}
+	if err := printer.PrintObj(obj, os.Stdout); err != nil {
+		return err
+	}
+	fmt.Print("\n")
+	return nil
+}
+
+// describeObject prints a detailed description of the object at path, fetching the objects
+// related to it that the description mentions. Objects of kinds kubecfg can't describe are
+// printed as YAML.
+func (c *KubeConfig) describeObject(path string, client *kubeclient.Client) bool {
+	obj, err := client.Get().Namespace(c.Namespace).Path(path).Do().Get()
+	if err != nil {
+		fatalErrorf(err, "Got request error: %v\n", err)
+		return false
+	}
+	if err := kubecfg.DescriberFor(obj, client).Describe(obj, os.Stdout); err != nil {
+		fatalErrorf(err, "Failed to describe %s: %v\n", path, err)
+		return false
+	}
+	return true
+}
+
+// diffObject prints a unified diff of the YAML of the object in the config file and the
+// object at path, leaving out the fields the server manages. It exits with
+// kubecfg.ExitError if they differ, so that scripts can tell whether applying the config
+// would change anything.
+func (c *KubeConfig) diffObject(storage, path string, client *kubeclient.Client) bool {
+	local, err := api.Decode(c.readConfig(storage))
+	if err != nil {
+		fatalf("Error parsing %v as an object for %v: %v\n", c.Config, storage, err)
+	}
+	live, err := client.Get().Namespace(c.Namespace).Path(path).Do().Get()
+	if err != nil {
+		fatalErrorf(err, "Got request error: %v\n", err)
+		return false
+	}
+	diff, err := kubecfg.DiffObjects(local, live)
+	if err != nil {
+		fatalf("Failed to compare %v with %s: %v\n", c.Config, path, err)
+		return false
+	}
+	if len(diff) > 0 {
+		fmt.Print(diff)
+		exit(kubecfg.ExitError)
+	}
+	return true
+}
+
+// labelObject changes the labels of the object at path as the remaining arguments say, and
+// prints the updated object. The rest of the object is left as the server has it.
+func (c *KubeConfig) labelObject(path string, client *kubeclient.Client) bool {
+	changes, err := kubecfg.ParseLabelChanges(c.Args[2:])
+	if err != nil {
+		usageErrorf("%v", err)
+	}
+	obj, err := kubecfg.UpdateLabels(client, c.Namespace, path, changes, c.Overwrite, 3)
+	if err != nil {
+		fatalErrorf(err, "Error labeling %s: %v\n", path, err)
+		return false
+	}
+	if err := c.getPrinter().PrintObj(obj, os.Stdout); err != nil {
+		fatalf("Failed to print: %v", err)
+	}
+	fmt.Print("\n")
+	return true
+}
+
+// waitForObject waits until the object 'id' in 'storage' meets the --for condition, or until
+// --timeout expires, in which case the last value observed is reported.
+func (c *KubeConfig) waitForObject(storage, id string, client *kubeclient.Client) bool {
+	cond, err := kubecfg.ParseWaitCondition(c.WaitFor)
+	if err != nil {
+		usageErrorf("Error parsing --for: %v", err)
+	}
+	if err := kubecfg.Wait(client, c.Namespace, storage, id, cond, c.Timeout, time.Second); err != nil {
+		fatalErrorf(err, "Error waiting for %s/%s: %v", storage, id, err)
+	}
+	return true
+}
+
+// listResources lists the objects of each storage in resources that match the selectors,
+// with the requests made concurrently. With --json or --yaml the lists are printed as a
+// single object keyed by storage; otherwise each list is printed under a header naming its
+// storage. A list that fails is reported without stopping the others from being printed.
+func (c *KubeConfig) listResources(resources []string, client *kubeclient.Client) bool {
+	lists := make([]interface{}, len(resources))
+	errs := make([]error, len(resources))
+	var wg sync.WaitGroup
+	for i, storage := range resources {
+		wg.Add(1)
+		go func(i int, storage string) {
+			defer wg.Done()
+			lists[i], errs[i] = c.listObjects(storage, client, false)
+		}(i, storage)
+	}
+	wg.Wait()
+
+	failures := []error{}
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, err)
+			fmt.Fprintf(os.Stderr, "Error listing %s: %v\n", resources[i], err)
+		}
+	}
+	if c.JSON || c.YAML {
+		combined := map[string]json.RawMessage{}
+		for i, list := range lists {
+			if errs[i] != nil {
+				continue
+			}
+			data, err := api.Encode(list)
+			if err != nil {
+				fatalf("Failed to encode %s: %v", resources[i], err)
+			}
+			combined[resources[i]] = data
+		}
+		data, err := json.Marshal(combined)
+		if err != nil {
+			fatalf("Failed to encode the lists: %v", err)
+		}
+		if err := c.getPrinter().Print(data, os.Stdout); err != nil {
+			fatalf("Failed to print: %v", err)
+		}
+		fmt.Print("\n")
+	} else {
+		printer := c.getPrinter()
+		separator := ""
+		for i, list := range lists {
+			if errs[i] != nil {
+				continue
+			}
+			fmt.Printf("%s%s:\n", separator, resources[i])
+			c.addEndpoints(printer, list, client)
+			c.addReplicas(printer, list, client)
+			if err := printer.PrintObj(list, os.Stdout); err != nil {
+				fatalf("Failed to print: %v\nRaw received object:\n%#v", err, list)
+			}
+			separator = "\n"
+		}
+	}
+	if len(failures) > 0 {
+		exit(exitCodeForFailures(failures))
+	}
+	return true
+}
+
+// getObjects prints the objects of storage with the given ids, read concurrently, as one
+// list. The ids that can't be read are reported on stderr, after the objects that were, and
+// make kubecfg exit with the code shared by their errors.
+func (c *KubeConfig) getObjects(storage string, ids []string, client *kubeclient.Client) bool {
+	objects := make([]interface{}, len(ids))
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(i int, id string) {
+			defer wg.Done()
+			objects[i], errs[i] = client.Get().Namespace(c.Namespace).Path(storage).Path(id).Do().Get()
+		}(i, id)
+	}
+	wg.Wait()
+
+	found := []interface{}{}
+	failures := []error{}
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, err)
+			continue
+		}
+		found = append(found, objects[i])
+	}
+	list, err := kubecfg.ListOf(storage, found)
+	if err != nil {
+		fatalf("Failed to list the %s read: %v", storage, err)
+	}
+	if len(failures) == 0 {
+		c.printResponse(list, nil, client)
+		return true
+	}
+	if c.progress != nil {
+		code := exitCodeForFailures(failures)
+		c.progress.ResultCode(code, fmt.Sprintf("%d of %d not found", len(failures), len(ids)), list)
+		exit(code)
+		return true
+	}
+	if len(found) > 0 {
+		c.printObject(list, client)
+	}
+	for i, err := range errs {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error getting %s/%s: %v\n", storage, ids[i], err)
+		}
+	}
+	exit(exitCodeForFailures(failures))
+	return true
+}
+
+// dryRunParam asks the server to only check the object r sends, if --dry-run was given.
+// The server defaults and validates it as it would for a real request, and returns it
+// without storing it.
+func (c *KubeConfig) dryRunParam(r *kubeclient.Request, client *kubeclient.Client) {
+	if c.DryRun {
+		c.requireFeature(kubecfg.FeatureDryRun, client)
+		r.Param("dryRun", "true")
+	}
+}
+
+// forceParam asks the server to store the object r sends despite the checks that may be
+// overridden, such as replication controllers selecting the same pods, if --force was given.
+func (c *KubeConfig) forceParam(r *kubeclient.Request, client *kubeclient.Client) {
+	if c.Force {
+		c.requireFeature(kubecfg.FeatureForce, client)
+		r.Param("force", "true")
+	}
+}
+
+// watchObjects prints the objects in 'storage' matching the label selector, and then each
+// change to them, until the server ends the watch.
+func (c *KubeConfig) watchObjects(storage string, client *kubeclient.Client) bool {
+	selector, err := labels.ParseSelector(c.Selector)
+	if err != nil {
+		usageErrorf("Error parsing selector %q: %v", c.Selector, err)
+	}
+	watcher, err := client.Watch(storage, selector, 0)
+	if err != nil {
+		fatalErrorf(err, "Error watching %s: %v", storage, err)
+	}
+	printer := c.getPrinter()
+	for event := range watcher.ResultChan() {
+		fmt.Printf("%s\n", event.Type)
+		if err := printer.PrintObj(event.Object, os.Stdout); err != nil {
+			fatalf("Failed to print: %v\nRaw received object:\n%#v", err, event.Object)
+		}
+		fmt.Print("\n")
+	}
+	if err := watcher.Err(); err != nil {
+		fatalErrorf(err, "Watch of %s ended after resource version %d: %v", storage, watcher.ResourceVersion(), err)
+	}
+	return true
+}
+
+// validateSelectors exits with a usage error pointing at the offending character if the label
+// or field selector is malformed, before any request is made.
+func (c *KubeConfig) validateSelectors() {
+	if err := kubecfg.ValidateSelector(c.Selector); err != nil {
+		usageErrorf("Error parsing -l: %v", err)
+	}
+	if err := kubecfg.ValidateSelector(c.Fields); err != nil {
+		usageErrorf("Error parsing --field-selector: %v", err)
+	}
+}
+
+// loadServerConfig reads the config of the server into c.serverConfig, the first time it is
+// called.
+func (c *KubeConfig) loadServerConfig(client *kubeclient.Client) {
+	c.serverConfigOnce.Do(func() {
+		config, err := client.ServerConfig()
+		if err != nil {
+			if c.Verbose {
+				glog.Infof("Unable to read the server config: %v", err)
+			}
+			return
+		}
+		c.serverConfig = config
+	})
+}
+
+// streamObjects prints the objects of storage as they are decoded, so that a huge list isn't
+// held in memory, if the server streams lists and the printer can print them item by item.
+// It returns false, having printed nothing, if the list must be read whole to be printed:
+// for printers that aren't ListPrinters, lists filtered by fields, summaries of replication
+// controllers, which count the pods of all of them, and JSON progress events.
+func (c *KubeConfig) streamObjects(storage string, client *kubeclient.Client) bool {
+	if c.progress != nil || len(c.Fields) > 0 {
+		return false
+	}
+	printer := c.getPrinter()
+	listPrinter, ok := printer.(kubecfg.ListPrinter)
+	if !ok {
+		return false
+	}
+	if human, ok := humanPrinter(printer); ok && human.Summary && !human.NoHeaders && storage == "replicationControllers" {
+		return false
+	}
+	c.loadServerConfig(client)
+	if c.serverConfig == nil || !c.serverConfig.StreamedLists {
+		return false
+	}
+	if empty, err := kubecfg.ListOf(storage, nil); err == nil {
+		c.addEndpoints(printer, empty, client)
+	}
+	body, header, err := client.Get().
+		Namespace(c.Namespace).
+		Path(storage).
+		ParseSelectorParam("labels", c.Selector).
+		StreamResponse()
+	if err != nil {
+		c.printResponse(nil, err, client)
+		return true
+	}
+	defer body.Close()
+	err = kubecfg.DecodeListItems(body, func(kind string) error {
+		return listPrinter.PrintListStart(kind, os.Stdout)
+	}, func(obj interface{}) error {
+		return listPrinter.PrintItem(obj, os.Stdout)
+	})
+	if err == nil {
+		err = listPrinter.PrintListEnd(os.Stdout)
+	}
+	if err != nil {
+		fatalf("Failed to print the list of %s: %v", storage, err)
+	}
+	fmt.Print("\n")
+	if len(header.Get(apiserver.ListContinueHeader)) > 0 {
+		fmt.Fprintf(os.Stderr, "The server limits lists of %s, only the first ones are shown\n", storage)
+	}
+	return true
+}
+
+// listObjects lists the objects in 'storage' matching both the label and the field selector.
+// Servers that predate field selectors for 'storage', as their config or version says or as
+// they tell by refusing the request as bad, are asked for the objects matching the label
+// selector, and the field selector is applied to those here. Servers that limit lists answer
+// with the first objects only, which is warned about, unless 'all' is set, in which case the
+// rest are listed too.
+func (c *KubeConfig) listObjects(storage string, client *kubeclient.Client, all bool) (interface{}, error) {
+	list := func(fields string) (interface{}, error) {
+		var list interface{}
+		token := ""
+		for {
+			request := client.Get().
+				Namespace(c.Namespace).
+				Path(storage).
+				ParseSelectorParam("labels", c.Selector).
+				ParseSelectorParam("fields", fields)
+			if len(token) > 0 {
+				request.Param("continue", token)
+			}
+			result := request.Do()
+			obj, err := result.Get()
+			if err != nil {
+				return nil, err
+			}
+			if list == nil {
+				list = obj
+			} else if err := kubecfg.AppendItems(list, obj); err != nil {
+				return nil, err
+			}
+			token = result.Header().Get(apiserver.ListContinueHeader)
+			if len(token) == 0 {
+				return list, nil
+			}
+			if !all {
+				fmt.Fprintf(os.Stderr, "The server limits lists of %s, only the first ones are shown\n", storage)
+				return list, nil
+			}
+		}
+	}
+	if len(c.Fields) == 0 {
+		return list("")
+	}
+	c.loadServerConfig(client)
+	supported, known := kubecfg.SupportsFieldSelectors(c.serverConfig, storage)
+	if !c.loadCompat(client).Supports(kubecfg.FeatureFieldSelectors) {
+		supported, known = false, true
+	}
+	if supported || !known {
+		obj, err := list(c.Fields)
+		statusErr, ok := err.(*kubeclient.StatusErr)
+		if known || !ok || statusErr.Status.Code != http.StatusBadRequest {
+			return obj, err
+		}
+	}
+	if c.Verbose {
+		glog.Warningf("The server can't filter %s by fields, filtering them with %q here", storage, c.Fields)
+	}
+	obj, err := list("")
+	if err != nil {
+		return nil, err
+	}
+	field, err := labels.ParseSelector(c.Fields)
+	if err != nil {
+		return nil, err
+	}
+	return kubecfg.FilterFields(obj, field)
+}
+
+// deleteBySelector lists the objects in 'storage' matching the label and field selectors,
+// prints them, and after confirmation (unless --yes was given) deletes each, reporting
+// per-object results.
+func (c *KubeConfig) deleteBySelector(storage string, client *kubeclient.Client) bool {
+	list, err := c.listObjects(storage, client, true)
+	if err != nil {
+		fatalErrorf(err, "Got request error: %v\n", err)
+	}
+	ids, err := kubecfg.ItemIDs(list)
+	if err != nil {
+		fatalf("Unable to read the list of %s: %v\n", storage, err)
+	}
+	if len(ids) == 0 && c.progress == nil {
+		fmt.Printf("No %s match %q\n", storage, strings.Trim(c.Selector+","+c.Fields, ","))
+		return true
+	}
+	// With --json, stdout only has the JSON events of the deletions: the list isn't
+	// printed and the confirmation is asked on stderr.
+	prompt := os.Stdout
+	if c.progress == nil {
+		if err := c.getPrinter().PrintObj(list, os.Stdout); err != nil {
+			fatalf("Failed to print: %v\n", err)
+		}
+		fmt.Print("\n")
+	} else {
+		prompt = os.Stderr
+	}
+	if len(ids) > 0 && !c.Yes && !kubecfg.Confirm(fmt.Sprintf("Delete %d %s?", len(ids), storage), os.Stdin, prompt) {
+		fmt.Println("Aborted")
+		exit(kubecfg.ExitError)
+	}
+
+	failures := []error{}
+	for _, id := range ids {
+		obj, err := c.doRequest(client.Verb("DELETE").Namespace(c.Namespace).Path(storage).Path(id), client)
+		if status, ok := obj.(*api.Status); ok && status.Status == api.StatusWorking {
+			if c.progress != nil {
+				c.progress.Progress(kubecfg.PhaseAccepted, storage+"/"+id, "")
+				continue
+			}
+			fmt.Printf("Deleting %s/%s\n", storage, id)
+			continue
+		}
+		if err != nil {
+			failures = append(failures, err)
+			if c.progress != nil {
+				c.progress.Progress(kubecfg.PhaseFailed, storage+"/"+id, err.Error())
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Error deleting %s/%s: %v\n", storage, id, err)
+			continue
+		}
+		if c.progress != nil {
+			c.progress.Progress(kubecfg.PhaseDeleted, storage+"/"+id, "")
+			continue
+		}
+		fmt.Printf("Deleted %s/%s\n", storage, id)
+	}
+	c.exitForFailures(failures, len(ids))
+	return true
+}
+
+func (c *KubeConfig) executeControllerRequest(method string, client *kubeclient.Client) bool {
+	parseController := func() string {
+		if len(c.Args) != 2 {
+			usageErrorf("usage: kubecfg [OPTIONS] stop|rm|rollingupdate <controller>")
+		}
+		return c.Arg(1)
+	}
+
+	var err error
+	switch method {
+	case "stop":
+		c.stopController(parseController(), client)
+	case "rm":
+		err = kubecfg.DeleteController(parseController(), client)
+	case "rollingupdate":
+		options := kubecfg.RollingUpdateOptions{
+			UpdatePeriod: c.UpdatePeriod,
+			Timeout:      c.Timeout,
+			Progress:     c.reportProgress(),
+		}
+		if len(c.Config) > 0 {
+			controller := api.ReplicationController{}
+			if err := api.DecodeInto(c.readConfig("replicationControllers"), &controller); err != nil {
+				fatalf("Error parsing %v as a replication controller: %v", c.Config, err)
+			}
+			options.Template = &controller.DesiredState.PodTemplate
+		}
+		err = kubecfg.RollingUpdate(parseController(), client, options)
+	case "run":
+		if len(c.Args) != 4 {
+			usageErrorf("usage: kubecfg [OPTIONS] run <image> <replicas> <controller>")
+		}
+		image := c.Arg(1)
+		replicas, parseErr := kubecfg.ParseReplicas(c.Arg(2))
+		name := c.Arg(3)
+		if parseErr != nil {
+			usageErrorf("Error parsing replicas: %v", parseErr)
+		}
+		options := kubecfg.PodTemplateOptions{
+			Env:           c.Env,
+			RestartPolicy: c.RestartPolicy,
+			Memory:        c.Memory,
+			CPU:           c.CPU,
+			Volumes:       c.Volumes,
+		}
+		controller, makeErr := kubecfg.MakeController(image, name, replicas, c.PortSpec, options)
+		if makeErr != nil {
+			usageErrorf("Error: %v", makeErr)
+		}
+		if c.DryRun {
+			// The controller is printed encoded, with its kind and version, so that it can be
+			// saved as a config file.
+			data, err := api.Encode(&controller)
+			if err != nil {
+				fatalf("Failed to encode the controller: %v", err)
+			}
+			if err := c.getPrinter().Print(data, os.Stdout); err != nil {
+				fatalf("Failed to print: %v", err)
+			}
+			return true
+		}
+		err = kubecfg.CreateController(controller, client, c.ServicePort)
+	case "resize":
+		args := c.Args
 		if len(args) < 3 {
-			glog.Fatal("usage: kubecfg resize <controller> <replicas>")
+			usageErrorf("usage: kubecfg resize <controller> <replicas>")
 		}
 		name := args[1]
-		replicas, err := strconv.Atoi(args[2])
+		replicas, err := kubecfg.ParseReplicas(args[2])
 		if err != nil {
-			glog.Fatalf("Error parsing replicas: %v", err)
+			usageErrorf("Error parsing replicas: %v", err)
 		}
-		err = kubecfg.ResizeController(name, replicas, client)
+		c.resizeController(name, replicas, client)
 	default:
 		return false
 	}
 	if err != nil {
-		glog.Fatalf("Error: %v", err)
+		c.failAction(err, "Error: %v", err)
+	}
+	if c.progress != nil && method == "rollingupdate" {
+		c.progress.Result(nil, nil)
 	}
 	return true
 }
+
+func (c *KubeConfig) executeBuildRequest(method string, client *kubeclient.Client) bool {
+	switch method {
+	case "cancelbuild":
+		if len(c.Args) != 2 {
+			usageErrorf("usage: kubecfg [OPTIONS] cancelbuild <build>")
+		}
+		build, err := kubecfg.CancelBuild(c.Arg(1), client)
+		if err != nil {
+			fatalErrorf(err, "Error cancelling build %s: %v", c.Arg(1), err)
+		}
+		if err := c.getPrinter().PrintObj(&build, os.Stdout); err != nil {
+			fatalf("Failed to print: %v", err)
+		}
+	case "rebuild":
+		if len(c.Args) != 2 {
+			usageErrorf("usage: kubecfg [OPTIONS] rebuild <build>")
+		}
+		build, running, err := kubecfg.CloneBuild(c.Arg(1), client)
+		if err != nil {
+			fatalErrorf(err, "Error cloning build %s: %v", c.Arg(1), err)
+		}
+		if running {
+			fmt.Fprintf(os.Stderr, "Warning: build %s has not finished, both builds may run at the same time\n", c.Arg(1))
+		}
+		if err := c.getPrinter().PrintObj(&build, os.Stdout); err != nil {
+			fatalf("Failed to print: %v", err)
+		}
+	case "buildlogs":
+		if len(c.Args) != 2 {
+			usageErrorf("usage: kubecfg [OPTIONS] [--follow] buildlogs <build>")
+		}
+		if err := kubecfg.BuildLogs(c.Arg(1), client, c.Follow, os.Stdout); err != nil {
+			fatalErrorf(err, "Error reading logs of build %s: %v", c.Arg(1), err)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// executeOfflineRequest runs the actions that only read config files, before kubecfg connects
+// to a server, so that they work without one. It returns false if method isn't one of them.
+func (c *KubeConfig) executeOfflineRequest(method string) bool {
+	switch method {
+	case "validate":
+		if len(c.Args) > 2 {
+			usageErrorf("usage: kubecfg -c <config> validate [<%s>]", prettyWireStorage())
+		}
+		c.validateObjects(c.offlineStorage())
+	case "convert":
+		if len(c.Args) > 2 || len(c.ConvertTo) == 0 {
+			usageErrorf("usage: kubecfg -c <config> --to <API version> convert [<%s>]", prettyWireStorage())
+		}
+		c.convertObjects(c.offlineStorage())
+	default:
+		return false
+	}
+	return true
+}
+
+// offlineStorage returns the storage named by the argument of validate or convert, or an
+// empty string if the storage of each object is to be inferred from its kind.
+func (c *KubeConfig) offlineStorage() string {
+	storage := c.Arg(1)
+	if len(storage) > 0 && !checkStorage(storage) {
+		usageErrorf("Unknown resource %s, expected one of %s", storage, prettyWireStorage())
+	}
+	return storage
+}
+
+// validateObjects checks every object of the config as the server would when it is created,
+// printing each problem against the file and document index of the object, and exits with
+// kubecfg.ExitInvalid if any object isn't valid.
+func (c *KubeConfig) validateObjects(storage string) {
+	invalid := 0
+	for _, object := range c.readConfigObjects() {
+		errs := kubecfg.ValidateConfigObject(object, storage)
+		if len(errs) == 0 {
+			fmt.Printf("%v: valid\n", object)
+			continue
+		}
+		invalid++
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "Error validating %v: %v\n", object, err)
+		}
+	}
+	if invalid > 0 {
+		fmt.Fprintf(os.Stderr, "%d invalid objects\n", invalid)
+		exit(kubecfg.ExitInvalid)
+	}
+}
+
+// convertObjects prints every object of the config as the API version of --to: as JSON, a
+// list if there are several objects, or as a stream of YAML documents with --yaml. Objects
+// that can't be converted are reported against their file and document index, and nothing
+// is printed.
+func (c *KubeConfig) convertObjects(storage string) {
+	converted := []json.RawMessage{}
+	failures := 0
+	for _, object := range c.readConfigObjects() {
+		data, err := kubecfg.ConvertConfigObject(object, storage, c.ConvertTo)
+		if err != nil {
+			failures++
+			fmt.Fprintf(os.Stderr, "Error converting %v: %v\n", object, err)
+			continue
+		}
+		converted = append(converted, data)
+	}
+	if failures > 0 {
+		exit(kubecfg.ExitInvalid)
+	}
+	if c.YAML {
+		printer := &kubecfg.YAMLPrinter{}
+		for i, data := range converted {
+			if i > 0 {
+				fmt.Println("---")
+			}
+			if err := printer.Print(data, os.Stdout); err != nil {
+				fatalf("Error printing the converted objects: %v", err)
+			}
+		}
+		return
+	}
+	var output interface{} = converted
+	if len(converted) == 1 {
+		output = converted[0]
+	}
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		fatalf("Error printing the converted objects: %v", err)
+	}
+	fmt.Println(string(data))
+}
+
+func (c *KubeConfig) executePodRequest(method string, client *kubeclient.Client) bool {
+	switch method {
+	case "forward":
+		if len(c.Args) < 3 {
+			usageErrorf("usage: kubecfg [OPTIONS] forward <pod> <local port>:<pod port> [...]")
+		}
+		pairs, err := kubecfg.ParsePortPairs(c.Args[2:])
+		if err != nil {
+			usageErrorf("Error parsing ports: %v", err)
+		}
+		c.forwardPorts(c.Arg(1), pairs, client)
+	case "status":
+		if len(c.Args) != 2 || c.Arg(1) != "pods" {
+			usageErrorf("usage: kubecfg [OPTIONS] [-l <selector>] status pods")
+		}
+		c.validateSelectors()
+		r := client.Get().Namespace(c.Namespace).Path("podSummary").ParseSelectorParam("labels", c.Selector)
+		obj, err := c.doRequest(r, client)
+		c.printResponse(obj, err, client)
+	default:
+		return false
+	}
+	return true
+}
+
+// forwardPorts relays the connections made to the local ports of pairs to the pod, until
+// kubecfg is interrupted.
+func (c *KubeConfig) forwardPorts(podID string, pairs []kubecfg.PortPair, client *kubeclient.Client) {
+	// Fail early if there is no such pod, rather than on the first connection.
+	if err := client.Get().Namespace(c.Namespace).Path("pods").Name(podID).Do().Error(); err != nil {
+		fatalErrorf(err, "Error getting pod %s: %v", podID, err)
+	}
+	forwarder := &kubecfg.PortForwarder{
+		Client:    client,
+		Namespace: c.Namespace,
+		PodID:     podID,
+		Pairs:     pairs,
+		Out:       os.Stdout,
+		Err:       os.Stderr,
+	}
+	if err := forwarder.Listen(); err != nil {
+		fatalf("Error listening: %v", err)
+	}
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+	stop := make(chan struct{})
+	go func() {
+		<-interrupted
+		close(stop)
+	}()
+	forwarder.Serve(stop)
+}
+
+// resizeController changes the replica count of a controller, retrying on conflicting updates,
+// and prints the updated controller. If --wait is set, it blocks until the controller has the
+// requested number of pods.
+func (c *KubeConfig) resizeController(name string, replicas int, client *kubeclient.Client) {
+	controller, err := kubecfg.Resize(name, replicas, client, 3)
+	if err != nil {
+		c.failAction(err, "Error resizing %s: %v", name, err)
+	}
+	if c.Wait {
+		if err := kubecfg.WaitForReplicas(name, client, time.Second, c.Timeout, c.reportProgress()); err != nil {
+			c.failAction(err, "Error waiting for %s: %v", name, err)
+		}
+	}
+	if c.progress != nil {
+		c.progress.Result(nil, &controller)
+		return
+	}
+	if err := c.getPrinter().PrintObj(&controller, os.Stdout); err != nil {
+		fatalf("Failed to print: %v", err)
+	}
+}
+
+// stopController scales a controller to zero, waits for its pods to terminate and deletes it.
+// If --also-services is set, services labeled with the controller's selector are deleted too.
+func (c *KubeConfig) stopController(name string, client *kubeclient.Client) {
+	controller, err := client.GetReplicationController(name)
+	if err != nil {
+		c.failAction(err, "Error: %v", err)
+	}
+	if err := kubecfg.StopAndDeleteController(name, client, time.Second, c.GracePeriod, c.reportProgress()); err != nil {
+		c.failAction(err, "Error stopping %s: %v", name, err)
+	}
+	if c.progress == nil {
+		fmt.Printf("Deleted replicationControllers/%s\n", name)
+	}
+	if c.AlsoServices {
+		s := labels.Set(controller.DesiredState.ReplicaSelector).AsSelector()
+		services, err := client.ListServices(s)
+		if err != nil {
+			c.failAction(err, "Error listing services for %s: %v", name, err)
+		}
+		for _, service := range services.Items {
+			if err := client.DeleteService(service.ID); err != nil {
+				c.failAction(err, "Error deleting service %s: %v", service.ID, err)
+			}
+			if c.progress != nil {
+				c.progress.Progress(kubecfg.PhaseDeleted, "services/"+service.ID, "deleted")
+				continue
+			}
+			fmt.Printf("Deleted services/%s\n", service.ID)
+		}
+	}
+	if c.progress != nil {
+		c.progress.Result(nil, nil)
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// withHome points HOME at a temporary directory holding the given profile file for the
+// duration of a test, and returns the directory and a function restoring HOME.
+func withHome(t *testing.T, profiles string) (string, func()) {
+	dir, err := ioutil.TempDir("", "kubecfg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, ".kubecfg"), []byte(profiles), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	home := os.Getenv("HOME")
+	os.Setenv("HOME", dir)
+	return dir, func() {
+		os.Setenv("HOME", home)
+		os.RemoveAll(dir)
+	}
+}
+
+// parseFlags returns a KubeConfig with the kubecfg flags parsed from args.
+func parseFlags(t *testing.T, args ...string) (*KubeConfig, *pflag.FlagSet) {
+	cfg := &KubeConfig{}
+	flags := pflag.NewFlagSet("kubecfg", pflag.ContinueOnError)
+	cfg.bindFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return cfg, flags
+}
+
+func TestApplyProfileInsecure(t *testing.T) {
+	dir, restore := withHome(t, "default: dev\nprofiles:\n  dev:\n    host: https://dev.example.com\n    insecure: true\n")
+	defer restore()
+	authFile := filepath.Join(dir, "auth")
+	if err := ioutil.WriteFile(authFile, []byte(`{"User": "user", "Password": "pass", "insecure": true}`), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	table := []struct {
+		args     []string
+		insecure bool
+	}{
+		{[]string{"--auth=" + authFile}, true},
+		{[]string{"--auth=" + authFile, "--insecure-skip-tls-verify=false"}, false},
+		{[]string{"--auth=" + authFile, "--insecure-skip-tls-verify"}, true},
+	}
+	for _, item := range table {
+		cfg, flags := parseFlags(t, item.args...)
+		if err := cfg.ApplyProfile(flags); err != nil {
+			t.Fatalf("%v: unexpected error: %v", item.args, err)
+		}
+		if cfg.InsecureSkipTLSVerify != item.insecure {
+			t.Errorf("%v: expected insecure %t, got %t", item.args, item.insecure, cfg.InsecureSkipTLSVerify)
+		}
+		_, auth, _, err := cfg.connect(false)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", item.args, err)
+		}
+		if auth.Insecure != item.insecure {
+			t.Errorf("%v: expected the client to be insecure %t, got %t", item.args, item.insecure, auth.Insecure)
+		}
+	}
+}
+
+func TestApplyProfileMalformed(t *testing.T) {
+	_, restore := withHome(t, "profiles: [not a map")
+	defer restore()
+
+	cfg, flags := parseFlags(t)
+	if err := cfg.ApplyProfile(flags); err == nil {
+		t.Errorf("expected an error loading a malformed profile file")
+	}
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunLabel(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+	puts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "PUT" {
+			puts++
+			body, _ := ioutil.ReadAll(req.Body)
+			w.Write(body)
+			return
+		}
+		data, _ := api.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}, Labels: map[string]string{"name": "foo", "old": "x"}})
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "--yaml", "label", "pods/foo", "tier=frontend", "old-")
+	if code != kubecfg.ExitSuccess || !strings.Contains(output, "labels:\n  name: foo\n  tier: frontend\n") {
+		t.Errorf("unexpected exit code %d:\n%s", code, output)
+	}
+	if code := runKubecfg(t, server, "label", "pods/foo", "name=bar"); code != kubecfg.ExitError {
+		t.Errorf("expected an existing label not to be overwritten, got exit code %d", code)
+	}
+	if puts != 1 {
+		t.Errorf("expected a refused change not to be sent, got %d updates", puts)
+	}
+	if code := runKubecfg(t, server, "--overwrite", "label", "pods/foo", "name=bar"); code != kubecfg.ExitSuccess || puts != 2 {
+		t.Errorf("expected --overwrite to change the label, got exit code %d", code)
+	}
+	for _, args := range [][]string{{"label", "pods/foo"}, {"label", "pods", "a=b"}, {"label", "pods/foo", "a"}} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+}
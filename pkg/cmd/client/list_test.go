@@ -0,0 +1,501 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
+)
+
+func TestRunListSeveral(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	var lock sync.Mutex
+	selectors := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		storage := path.Base(req.URL.Path)
+		lock.Lock()
+		selectors[storage] = req.URL.Query().Get("labels")
+		lock.Unlock()
+		var obj interface{}
+		switch storage {
+		case "pods":
+			obj = &api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "frontend-1"}}}}
+		case "replicationControllers":
+			obj = &api.ReplicationControllerList{}
+		case "minions":
+			obj = &api.MinionList{Items: []api.Minion{{JSONBase: api.JSONBase{ID: "minion-1"}}}}
+		default:
+			statusHandler(t, api.Status{Status: api.StatusFailure, Code: http.StatusInternalServerError}).ServeHTTP(w, req)
+			return
+		}
+		data, err := api.Encode(obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "--label=name=frontend", "list", "all")
+	if code != kubecfg.ExitError {
+		t.Errorf("expected the failed services list to exit with %d, got %d", kubecfg.ExitError, code)
+	}
+	for _, storage := range allStorage {
+		if selectors[storage] != "name=frontend" {
+			t.Errorf("expected %s to be listed with the selector, got %q", storage, selectors[storage])
+		}
+	}
+	for _, expected := range []string{"pods:\n", "frontend-1", "\nreplicationControllers:\n", "\nminions:\n", "minion-1"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+	if strings.Contains(output, "services:") {
+		t.Errorf("expected the failed services list to be left out of the output:\n%s", output)
+	}
+
+	code, output = runKubecfgOutput(t, server, "--json", "list", "pods,minions")
+	if code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	lists := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(output), &lists); err != nil {
+		t.Fatalf("expected a single JSON object, got %v:\n%s", err, output)
+	}
+	pods := api.PodList{}
+	if err := api.DecodeInto(lists["pods"], &pods); err != nil || len(pods.Items) != 1 {
+		t.Errorf("unexpected pods %s: %v", lists["pods"], err)
+	}
+	if _, ok := lists["minions"]; !ok || len(lists) != 2 {
+		t.Errorf("unexpected lists: %s", output)
+	}
+
+	if code := runKubecfg(t, server, "list", "pods,frobs"); code != kubecfg.ExitUsage {
+		t.Errorf("expected an unknown resource to be a usage error, got %d", code)
+	}
+}
+
+func TestRunListServicesWide(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var obj interface{}
+		switch path.Base(req.URL.Path) {
+		case "services":
+			obj = &api.ServiceList{Items: []api.Service{{JSONBase: api.JSONBase{ID: "frontend"}, Port: 80}}}
+		case "endpoints":
+			obj = &api.EndpointsList{Items: []api.Endpoints{{JSONBase: api.JSONBase{ID: "frontend"}, Endpoints: []string{"10.0.0.1:80", "10.0.0.2:80"}}}}
+		case "serverconfig":
+			http.NotFound(w, req)
+			return
+		default:
+			t.Errorf("unexpected request %s", req.URL.Path)
+			return
+		}
+		data, err := api.Encode(obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "--wide", "list", "services")
+	if code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if fields[0] != "frontend" || fields[len(fields)-2] != "2" {
+		t.Errorf("expected the service to have 2 endpoints:\n%s", output)
+	}
+}
+
+func TestRunListControllersSummary(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var obj interface{}
+		switch path.Base(req.URL.Path) {
+		case "replicationControllers":
+			obj = &api.ReplicationControllerList{Items: []api.ReplicationController{{
+				JSONBase:     api.JSONBase{ID: "web"},
+				DesiredState: api.ReplicationControllerState{Replicas: 2, ReplicaSelector: map[string]string{"name": "web"}},
+			}}}
+		case "pods":
+			obj = &api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "web-1"}, Labels: map[string]string{"name": "web"}}}}
+		case "serverconfig":
+			http.NotFound(w, req)
+			return
+		default:
+			t.Errorf("unexpected request %s", req.URL.Path)
+			return
+		}
+		data, err := api.Encode(obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "--summary", "list", "replicationControllers")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if code != kubecfg.ExitSuccess || lines[len(lines)-1] != "1 replicationControllers: 1 resizing; 1 of 2 replicas" {
+		t.Errorf("expected a summary of the replicas, got exit code %d:\n%s", code, output)
+	}
+	code, output = runKubecfgOutput(t, server, "--summary", "--no-headers", "list", "replicationControllers")
+	if lines := strings.Split(strings.TrimSpace(output), "\n"); code != kubecfg.ExitSuccess || len(lines) != 1 {
+		t.Errorf("expected only the controller, got exit code %d:\n%s", code, output)
+	}
+}
+
+func TestRunListNamespaces(t *testing.T) {
+	_, restore := withHome(t, "profiles:\n  dev:\n    namespace: team1\n")
+	defer restore()
+
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requested = req.URL.Path
+		data, err := api.Encode(&api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "web", Namespace: "team1"}}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	table := []struct {
+		args      []string
+		path      string
+		namespace bool
+	}{
+		{[]string{"--profile=dev", "list", "pods"}, "/api/v1beta1/ns/team1/pods", false},
+		{[]string{"--profile=dev", "--namespace=other", "list", "pods"}, "/api/v1beta1/ns/other/pods", false},
+		{[]string{"--profile=dev", "--all-namespaces", "list", "pods"}, "/api/v1beta1/pods", true},
+	}
+	for _, item := range table {
+		requested = ""
+		code, output := runKubecfgOutput(t, server, item.args...)
+		if code != kubecfg.ExitSuccess || requested != item.path {
+			t.Errorf("%v: expected %s to be listed, got exit code %d and %s", item.args, item.path, code, requested)
+		}
+		if columns := strings.Fields(output); len(columns) == 0 || (columns[0] == "Namespace") != item.namespace {
+			t.Errorf("%v: expected a namespace column %t:\n%s", item.args, item.namespace, output)
+		}
+	}
+
+	for _, args := range [][]string{
+		{"--all-namespaces", "-c", "pod.json", "create", "pods"},
+		{"--all-namespaces", "-c", "pod.json", "update", "pods/web"},
+		{"--all-namespaces", "--namespace=other", "list", "pods"},
+	} {
+		requested = ""
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage || requested != "" {
+			t.Errorf("%v: expected a usage error before any request, got exit code %d and %s", args, code, requested)
+		}
+	}
+}
+
+func TestRunListFieldSelector(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	var lock sync.Mutex
+	var config *apiserver.ServerConfig
+	var serverVersion *version.Info
+	var requests, deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if req.URL.Path == "/version" {
+			if serverVersion == nil {
+				http.NotFound(w, req)
+				return
+			}
+			data, _ := json.Marshal(serverVersion)
+			w.Write(data)
+			return
+		}
+		if path.Base(req.URL.Path) == "serverconfig" {
+			if config == nil {
+				http.NotFound(w, req)
+				return
+			}
+			data, _ := json.Marshal(config)
+			w.Write(data)
+			return
+		}
+		if req.Method == "DELETE" {
+			deleted = append(deleted, path.Base(req.URL.Path))
+			statusHandler(t, api.Status{Status: api.StatusSuccess, Code: http.StatusOK}).ServeHTTP(w, req)
+			return
+		}
+		fields := req.URL.Query().Get("fields")
+		requests = append(requests, fields)
+		if len(fields) > 0 && config == nil {
+			statusHandler(t, api.Status{Status: api.StatusFailure, Code: http.StatusBadRequest, Reason: api.ReasonTypeBadRequest}).ServeHTTP(w, req)
+			return
+		}
+		pods := []api.Pod{{JSONBase: api.JSONBase{ID: "running-1"}, CurrentState: api.PodState{Status: api.PodRunning}}}
+		if len(fields) == 0 {
+			pods = append(pods, api.Pod{JSONBase: api.JSONBase{ID: "waiting-1"}, CurrentState: api.PodState{Status: api.PodWaiting}})
+		}
+		data, err := api.Encode(&api.PodList{Items: pods})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	oldServer := &version.Info{Major: "0", Minor: "0"}
+	table := []struct {
+		config   *apiserver.ServerConfig
+		version  *version.Info
+		args     []string
+		requests []string
+	}{
+		{&apiserver.ServerConfig{FieldSelectors: []string{"pods"}}, nil, nil, []string{"currentState.status=Running"}},
+		{&apiserver.ServerConfig{FieldSelectors: []string{}}, nil, nil, []string{""}},
+		{nil, nil, nil, []string{"currentState.status=Running", ""}},
+		{&apiserver.ServerConfig{FieldSelectors: []string{"pods"}}, oldServer, nil, []string{""}},
+		{&apiserver.ServerConfig{FieldSelectors: []string{"pods"}}, oldServer, []string{"--force-compat"}, []string{"currentState.status=Running"}},
+	}
+	for _, item := range table {
+		config, serverVersion, requests = item.config, item.version, nil
+		args := append(item.args, "--field-selector=currentState.status=Running", "list", "pods")
+		code, output := runKubecfgOutput(t, server, args...)
+		if code != kubecfg.ExitSuccess || !strings.Contains(output, "running-1") || strings.Contains(output, "waiting-1") {
+			t.Errorf("%#v %v: expected only the running pod, got exit code %d:\n%s", item.config, item.args, code, output)
+		}
+		if !reflect.DeepEqual(requests, item.requests) {
+			t.Errorf("%#v %v: expected requests %q, got %q", item.config, item.args, item.requests, requests)
+		}
+	}
+
+	config, serverVersion, requests = nil, nil, nil
+	if code := runKubecfg(t, server, "--yes", "--field-selector=currentState.status=Running", "delete", "pods"); code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if !reflect.DeepEqual(deleted, []string{"running-1"}) {
+		t.Errorf("expected only the running pod to be deleted, got %v", deleted)
+	}
+
+	requests = nil
+	for _, args := range [][]string{
+		{"--field-selector=currentState.status", "list", "pods"},
+		{"--label=name =web", "--field-selector=currentState.status=Running", "list", "pods"},
+		{"--field-selector=a=b=c", "delete", "pods"},
+		{"--fields=a!b", "list", "pods,minions"},
+	} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+	if len(requests) != 0 {
+		t.Errorf("expected malformed selectors to fail before any request, got %q", requests)
+	}
+}
+
+func TestRunListLimitedByServer(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	var lock sync.Mutex
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		if req.Method == "DELETE" {
+			deleted = append(deleted, path.Base(req.URL.Path))
+			statusHandler(t, api.Status{Status: api.StatusSuccess, Code: http.StatusOK}).ServeHTTP(w, req)
+			return
+		}
+		// The server answers with two pods at a time, out of five.
+		offset, _ := strconv.Atoi(req.URL.Query().Get("continue"))
+		list := &api.PodList{}
+		for i := offset; i < offset+2 && i < 5; i++ {
+			list.Items = append(list.Items, api.Pod{JSONBase: api.JSONBase{ID: "pod" + strconv.Itoa(i)}})
+		}
+		if offset+2 < 5 {
+			w.Header().Set(apiserver.ListTruncatedHeader, "true")
+			w.Header().Set(apiserver.ListContinueHeader, strconv.Itoa(offset+2))
+		}
+		data, err := api.Encode(list)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "list", "pods")
+	if code != kubecfg.ExitSuccess || !strings.Contains(output, "pod1") || strings.Contains(output, "pod2") {
+		t.Errorf("expected the first pods the server answered with, got %d:\n%s", code, output)
+	}
+
+	if code := runKubecfg(t, server, "--yes", "-l", "name=foo", "delete", "pods"); code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if !reflect.DeepEqual(deleted, []string{"pod0", "pod1", "pod2", "pod3", "pod4"}) {
+		t.Errorf("expected every matching pod to be deleted, got %v", deleted)
+	}
+}
+
+func TestRunGetSeveral(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	var lock sync.Mutex
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		paths = append(paths, req.URL.Path)
+		lock.Unlock()
+		var obj interface{}
+		switch req.URL.Path {
+		case "/api/v1beta1/pods":
+			obj = &api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "foo"}}}}
+		case "/api/v1beta1/services":
+			obj = &api.ServiceList{Items: []api.Service{{JSONBase: api.JSONBase{ID: "web"}, Port: 80}}}
+		case "/api/v1beta1/pods/foo", "/api/v1beta1/pods/baz":
+			obj = &api.Pod{JSONBase: api.JSONBase{ID: path.Base(req.URL.Path)}}
+		default:
+			statusHandler(t, api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound}).ServeHTTP(w, req)
+			return
+		}
+		data, err := api.Encode(obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "get", "pods", "foo", "baz")
+	if code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if strings.Count(output, "Name") != 1 || !strings.Contains(output, "foo") || !strings.Contains(output, "baz") {
+		t.Errorf("expected both pods in one table:\n%s", output)
+	}
+
+	code, output = runKubecfgOutput(t, server, "get", "pods", "foo", "bar", "baz")
+	if code != kubecfg.ExitNotFound {
+		t.Errorf("expected the missing pod to exit with %d, got %d", kubecfg.ExitNotFound, code)
+	}
+	if !strings.Contains(output, "foo") || !strings.Contains(output, "baz") || strings.Contains(output, "bar") {
+		t.Errorf("expected only the pods found in output:\n%s", output)
+	}
+
+	code, output = runKubecfgOutput(t, server, "--json", "get", "pods", "foo", "baz")
+	if code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	pods := api.PodList{}
+	if err := api.DecodeInto([]byte(output), &pods); err != nil || len(pods.Items) != 2 {
+		t.Errorf("expected a list of both pods, got %v:\n%s", err, output)
+	}
+
+	lock.Lock()
+	paths = []string{}
+	lock.Unlock()
+	code, output = runKubecfgOutput(t, server, "get", "pods")
+	if code != kubecfg.ExitSuccess || !strings.Contains(output, "foo") {
+		t.Errorf("expected the pods to be listed, got %d:\n%s", code, output)
+	}
+	if !reflect.DeepEqual(paths, []string{"/api/v1beta1/serverconfig", "/api/v1beta1/pods"}) {
+		t.Errorf("expected the list endpoint to be called, got %v", paths)
+	}
+
+	code, output = runKubecfgOutput(t, server, "get", "pods,services")
+	if code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	for _, expected := range []string{"pods:\n", "foo", "\nservices:\n", "web"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in output:\n%s", expected, output)
+		}
+	}
+}
+
+func TestRunListStreamed(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	var lock sync.Mutex
+	streamed := true
+	paths := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		paths = append(paths, path.Base(req.URL.Path))
+		var obj interface{}
+		switch path.Base(req.URL.Path) {
+		case "serverconfig":
+			data, _ := json.Marshal(apiserver.ServerConfig{StreamedLists: streamed})
+			w.Write(data)
+			return
+		case "pods":
+			obj = &api.PodList{Items: []api.Pod{
+				{JSONBase: api.JSONBase{ID: "foo"}, CurrentState: api.PodState{Status: api.PodRunning}},
+				{JSONBase: api.JSONBase{ID: "bar"}, CurrentState: api.PodState{Status: api.PodWaiting}},
+			}}
+		case "services":
+			obj = &api.ServiceList{Items: []api.Service{{JSONBase: api.JSONBase{ID: "frontend"}, Port: 80}}}
+		case "endpoints":
+			obj = &api.EndpointsList{Items: []api.Endpoints{{JSONBase: api.JSONBase{ID: "frontend"}, Endpoints: []string{"10.0.0.1:80"}}}}
+		default:
+			t.Errorf("unexpected request %s", req.URL.Path)
+			return
+		}
+		w.Header().Set(apiserver.ListContinueHeader, "next")
+		data, err := api.Encode(obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "--summary", "list", "pods")
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if code != kubecfg.ExitSuccess || len(lines) != 5 || !strings.HasPrefix(lines[2], "foo") || lines[4] != "2 pods: 1 running, 1 waiting" {
+		t.Errorf("expected the streamed pods and their summary, got exit code %d:\n%s", code, output)
+	}
+
+	code, output = runKubecfgOutput(t, server, "--json", "get", "pods")
+	pods := api.PodList{}
+	if err := api.DecodeInto([]byte(output), &pods); code != kubecfg.ExitSuccess || err != nil || len(pods.Items) != 2 {
+		t.Errorf("expected a list of both pods, got exit code %d, %v:\n%s", code, err, output)
+	}
+
+	code, output = runKubecfgOutput(t, server, "--wide", "list", "services")
+	lines = strings.Split(strings.TrimSpace(output), "\n")
+	if fields := strings.Fields(lines[len(lines)-1]); code != kubecfg.ExitSuccess || fields[0] != "frontend" || fields[len(fields)-2] != "1" {
+		t.Errorf("expected the service to have 1 endpoint, got exit code %d:\n%s", code, output)
+	}
+
+	lock.Lock()
+	streamed, paths = false, []string{}
+	lock.Unlock()
+	code, output = runKubecfgOutput(t, server, "list", "pods")
+	if code != kubecfg.ExitSuccess || !strings.Contains(output, "bar") || !reflect.DeepEqual(paths, []string{"serverconfig", "pods"}) {
+		t.Errorf("expected the pods to be listed whole, got exit code %d and requests %v:\n%s", code, paths, output)
+	}
+}
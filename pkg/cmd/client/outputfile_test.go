@@ -0,0 +1,61 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunOutputFile(t *testing.T) {
+	home, restore := withHome(t, "")
+	defer restore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, err := api.Encode(&api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "foo"}}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	_, table := runKubecfgOutput(t, server, "list", "pods")
+	for _, format := range []string{"json", "yaml"} {
+		path := filepath.Join(home, "pods."+format)
+		code, output := runKubecfgOutput(t, server, "--output-file="+path, "--output-file-format="+format, "list", "pods")
+		if code != kubecfg.ExitSuccess || output != table {
+			t.Errorf("%s: expected the table alone on stdout, got %d: %s", format, code, output)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if format == "yaml" {
+			if !strings.Contains(string(data), "id: foo") {
+				t.Errorf("expected the list as YAML in the file, got %q", data)
+			}
+			continue
+		}
+		var list api.PodList
+		if err := api.DecodeInto(data, &list); err != nil || len(list.Items) != 1 || list.Items[0].ID != "foo" {
+			t.Errorf("expected the list in the file, got %q: %v", data, err)
+		}
+	}
+
+	// A file that can't be written to only fails the command if it is required.
+	missing := filepath.Join(home, "missing", "pods.json")
+	if code, output := runKubecfgOutput(t, server, "--output-file="+missing, "list", "pods"); code != kubecfg.ExitSuccess || output != table {
+		t.Errorf("expected the table despite the file, got %d: %s", code, output)
+	}
+	if code := runKubecfg(t, server, "--output-file="+missing, "--output-file-required", "list", "pods"); code != kubecfg.ExitError {
+		t.Errorf("expected a required file to fail the command, got %d", code)
+	}
+	if code := runKubecfg(t, server, "--output-file-format=xml", "list", "pods"); code != kubecfg.ExitUsage {
+		t.Errorf("expected an unknown format to be a usage error, got %d", code)
+	}
+}
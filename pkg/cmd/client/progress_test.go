@@ -0,0 +1,108 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+// decodeProgress decodes output, the JSON events of a long action, and fails unless every line
+// is an event and the last is its result.
+func decodeProgress(t *testing.T, output string) []kubecfg.ProgressEvent {
+	events := []kubecfg.ProgressEvent{}
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		var event kubecfg.ProgressEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	if last := events[len(events)-1]; last.Phase != kubecfg.PhaseResult || last.Result == nil {
+		t.Fatalf("expected the events to end with a result, got %q", output)
+	}
+	for _, event := range events[:len(events)-1] {
+		if event.Result != nil {
+			t.Errorf("unexpected result before the last event: %#v", event)
+		}
+	}
+	return events
+}
+
+func TestRunProgressEvents(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var obj interface{} = &api.Status{Status: api.StatusWorking, Code: http.StatusAccepted, Details: &api.StatusDetails{ID: "op1"}}
+		code := http.StatusAccepted
+		if req.URL.Path == "/api/v1beta1/operations/op1" {
+			if polls++; polls > 1 {
+				obj, code = &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}, http.StatusOK
+			}
+		}
+		data, _ := api.Encode(obj)
+		w.WriteHeader(code)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "--json", "--wait", "delete", "pods/foo")
+	if code != kubecfg.ExitSuccess {
+		t.Fatalf("unexpected exit code %d: %s", code, output)
+	}
+	events := decodeProgress(t, output)
+	phases := []string{}
+	for _, event := range events {
+		phases = append(phases, event.Phase)
+	}
+	if expected := []string{kubecfg.PhaseAccepted, kubecfg.PhaseWaiting, kubecfg.PhaseResult}; !reflect.DeepEqual(phases, expected) {
+		t.Errorf("expected phases %v, got %v", expected, phases)
+	}
+	result := events[len(events)-1].Result
+	var pod api.Pod
+	if !result.Succeeded || api.DecodeInto(result.Output, &pod) != nil || pod.ID != "foo" {
+		t.Errorf("unexpected result: %#v", result)
+	}
+
+	for _, name := range []string{"a.json", "b.json"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(`{"kind": "Pod", "id": "foo"}`), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	notFound := httptest.NewServer(statusHandler(t, api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound, Message: "not found"}))
+	defer notFound.Close()
+	table := []struct {
+		args   []string
+		phases []string
+	}{
+		{[]string{"stop", "foo"}, []string{kubecfg.PhaseResult}},
+		{[]string{"rollingupdate", "foo"}, []string{kubecfg.PhaseResult}},
+		{[]string{"--wait", "resize", "foo", "2"}, []string{kubecfg.PhaseResult}},
+		{[]string{"--wait", "--config=" + dir, "create"}, []string{kubecfg.PhaseFailed, kubecfg.PhaseFailed, kubecfg.PhaseResult}},
+	}
+	for _, item := range table {
+		code, output := runKubecfgOutput(t, notFound, append([]string{"--json"}, item.args...)...)
+		if code != kubecfg.ExitNotFound {
+			t.Errorf("%v: expected exit code %d, got %d", item.args, kubecfg.ExitNotFound, code)
+		}
+		events := decodeProgress(t, output)
+		phases := []string{}
+		for _, event := range events {
+			phases = append(phases, event.Phase)
+		}
+		if !reflect.DeepEqual(phases, item.phases) {
+			t.Errorf("%v: expected phases %v, got %v", item.args, item.phases, phases)
+		}
+		if result := events[len(events)-1].Result; result.Succeeded || result.ExitCode != kubecfg.ExitNotFound {
+			t.Errorf("%v: unexpected result: %#v", item.args, result)
+		}
+	}
+}
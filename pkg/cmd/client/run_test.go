@@ -0,0 +1,45 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunControllerDryRun(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "--dry-run", "--yaml", "--env=FOO=a,b", "--env=BAR=c", "--volume=/var/log:/logs", "--restart-policy=never", "--memory=1024", "run", "nginx", "2", "web")
+	if code != kubecfg.ExitSuccess {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	for _, expected := range []string{"kind: ReplicationController", "name: FOO\n", "value: a,b\n", "name: BAR\n", "path: /var/log\n", "mountPath: /logs\n", "type: RestartNever\n", "memory: 1024\n"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in the controller, got\n%s", expected, output)
+		}
+	}
+	for _, args := range [][]string{
+		{"--env=FOO", "run", "nginx", "2", "web"},
+		{"--env=FOO=a", "--env=FOO=b", "run", "nginx", "2", "web"},
+		{"--volume=/var/log", "run", "nginx", "2", "web"},
+		{"--restart-policy=sometimes", "run", "nginx", "2", "web"},
+		{"--cpu=-1", "run", "nginx", "2", "web"},
+	} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("expected invalid options to fail before any request, got %d requests", requests)
+	}
+}
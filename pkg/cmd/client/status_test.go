@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunStatusPods(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1beta1/podSummary" {
+			t.Errorf("unexpected request: %s", req.URL)
+		}
+		query = req.URL.Query().Get("labels")
+		data, _ := api.Encode(&api.PodSummary{
+			Total:      3,
+			ByStatus:   map[string]int{"Running": 2, "Waiting": 1},
+			ByHost:     map[string]int{"host1": 2},
+			Unassigned: 1,
+		})
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	code, output := runKubecfgOutput(t, server, "-l", "name=frontend", "status", "pods")
+	if code != kubecfg.ExitSuccess {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	if query != "name=frontend" {
+		t.Errorf("expected the selector to be sent, got %q", query)
+	}
+	for _, expected := range []string{"Running", "host1", "<unassigned>", "3 pods"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected %q in the output, got %q", expected, output)
+		}
+	}
+	for _, args := range [][]string{
+		{"status"},
+		{"status", "services"},
+		{"status", "pods", "foo"},
+	} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+}
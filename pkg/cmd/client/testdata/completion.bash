@@ -0,0 +1,115 @@
+# bash completion for openshift
+# Generated by 'openshift kube completion bash', do not edit.
+
+_openshift_names()
+{
+    openshift kube completion names "$1" 2>/dev/null
+}
+
+_openshift()
+{
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local path="openshift" action="" resource="" skip="" words="" word i
+    for (( i=1; i < COMP_CWORD; i++ )); do
+        word="${COMP_WORDS[i]}"
+        if [[ -n "$skip" ]]; then
+            skip=""
+            continue
+        fi
+        case "$path $word" in
+            "openshift kube --api-prefix"|"openshift kube --auth"|"openshift kube --certificate-authority"|"openshift kube --client-certificate"|"openshift kube --client-key"|"openshift kube --config"|"openshift kube --conflict-retries"|"openshift kube --cpu"|"openshift kube --env"|"openshift kube --field-selector"|"openshift kube --fields"|"openshift kube --for"|"openshift kube --grace-period"|"openshift kube --host"|"openshift kube --label"|"openshift kube --listen"|"openshift kube --max-column-width"|"openshift kube --memory"|"openshift kube --namespace"|"openshift kube --output-file"|"openshift kube --output-file-format"|"openshift kube --patch"|"openshift kube --port"|"openshift kube --profile"|"openshift kube --proxy-cert"|"openshift kube --proxy-key"|"openshift kube --restart-policy"|"openshift kube --retries"|"openshift kube --retry-backoff"|"openshift kube --service"|"openshift kube --template"|"openshift kube --template_file"|"openshift kube --timeout"|"openshift kube --to"|"openshift kube --unix-socket"|"openshift kube --update"|"openshift kube --verbosity"|"openshift kube --volume"|"openshift kube --www"|"openshift kube -c"|"openshift kube -h"|"openshift kube -l"|"openshift kube -n"|"openshift kube -p"|"openshift kube -s"|"openshift kube -u")
+                skip=1
+                continue
+                ;;
+            *" -"*)
+                continue
+                ;;
+            "openshift kube")
+                path="openshift kube"
+                continue
+                ;;
+            "openshift kube completion")
+                path="openshift kube completion"
+                continue
+                ;;
+        esac
+        if [[ "$path" == "openshift kube" ]]; then
+            if [[ -z "$action" ]]; then
+                action="$word"
+            elif [[ -z "$resource" ]]; then
+                resource="$word"
+            fi
+        fi
+    done
+
+    if [[ "$cur" == -* ]]; then
+        case "$path" in
+            "openshift")
+                words="--help"
+                ;;
+            "openshift kube")
+                words="--all-namespaces --also-services --api-prefix --auth --certificate-authority --client-certificate --client-key --config --conflict-retries --cpu --dry-run --env --expect_version_match --field-selector --fields --follow --for --force --force-compat --grace-period --help --host --insecure-skip-tls-verify --json --label --listen --max-column-width --memory --merge-lists --namespace --no-headers --output-file --output-file-format --output-file-required --overwrite --patch --port --profile --proxy --proxy-cert --proxy-key --restart-policy --retries --retry-backoff --server_version --service --skip-id-check --stop-on-error --summary --template --template-raw --template_file --timeout --to --unix-socket --update --verbose --verbosity --volume --wait --watch --wide --www --yaml --yes -c -h -l -n -p -s -u"
+                ;;
+            "openshift kube completion")
+                words="--help"
+                ;;
+        esac
+        COMPREPLY=( $(compgen -W "$words" -- "$cur") )
+        return 0
+    fi
+
+    case "$path" in
+        "openshift")
+            words="kube"
+            ;;
+        "openshift kube")
+            case "$action" in
+                "")
+                    words="apply buildlogs cancelbuild completion config convert create delete describe diff forward get label list rebuild resize rm rollingupdate run status stop update validate wait"
+                    ;;
+                delete|describe|diff|get|label|list|update|wait)
+                    if [[ -n "$resource" ]]; then
+                        return 0
+                    fi
+                    words="builds endpoints events minions pods replicationControllers services"
+                    if [[ "$action" != list && "$cur" == */* ]]; then
+                        words="$(_openshift_names "${cur%%/*}" | sed "s|^|${cur%%/*}/|")"
+                    fi
+                    ;;
+                convert|validate)
+                    if [[ -n "$resource" ]]; then
+                        return 0
+                    fi
+                    words="builds endpoints events minions pods replicationControllers services"
+                    ;;
+                resize|rm|rollingupdate|stop)
+                    if [[ -n "$resource" ]]; then
+                        return 0
+                    fi
+                    words="$(_openshift_names replicationControllers)"
+                    ;;
+                buildlogs|cancelbuild|rebuild)
+                    if [[ -n "$resource" ]]; then
+                        return 0
+                    fi
+                    words="$(_openshift_names builds)"
+                    ;;
+                forward)
+                    if [[ -n "$resource" ]]; then
+                        return 0
+                    fi
+                    words="$(_openshift_names pods)"
+                    ;;
+                config)
+                    words="list use"
+                    ;;
+                status)
+                    words="pods"
+                    ;;
+            esac
+            ;;
+    esac
+    COMPREPLY=( $(compgen -W "$words" -- "$cur") )
+}
+
+complete -F _openshift openshift
@@ -0,0 +1,120 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunUpdateFromStdin(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	var updated api.Pod
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "GET" {
+			data, _ := api.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 5}})
+			w.Write(data)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if err := api.DecodeInto(body, &updated); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	restoreStdin := withStdin(t, `{"kind": "Pod", "id": "foo", "labels": {"name": "foo"}}`)
+	defer restoreStdin()
+	if code := runKubecfg(t, server, "-c", "-", "update", "pods/foo"); code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if updated.ID != "foo" || updated.ResourceVersion != 5 || updated.Labels["name"] != "foo" {
+		t.Errorf("expected the object from stdin at the live version, got %#v", updated)
+	}
+}
+
+func TestRunUpdatePartial(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	var updated api.ReplicationController
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == "GET" {
+			data, _ := api.Encode(&api.ReplicationController{
+				JSONBase:     api.JSONBase{ID: "foo", ResourceVersion: 5},
+				DesiredState: api.ReplicationControllerState{Replicas: 1, ReplicaSelector: map[string]string{"name": "foo"}},
+				CurrentState: api.ReplicationControllerState{Replicas: 1},
+				Labels:       map[string]string{"name": "foo"},
+			})
+			w.Write(data)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if err := api.DecodeInto(body, &updated); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	restoreStdin := withStdin(t, `{"id": "foo", "labels": {"tier": "frontend"}}`)
+	defer restoreStdin()
+	if code := runKubecfg(t, server, "-c", "-", "--patch=desiredState.replicas=3", "update", "replicationControllers/foo"); code != kubecfg.ExitSuccess {
+		t.Errorf("unexpected exit code %d", code)
+	}
+	if updated.ResourceVersion != 5 || updated.DesiredState.Replicas != 3 || updated.CurrentState.Replicas != 1 {
+		t.Errorf("expected the change over the live object, got %#v", updated)
+	}
+	if updated.DesiredState.ReplicaSelector["name"] != "foo" || !reflect.DeepEqual(updated.Labels, map[string]string{"name": "foo", "tier": "frontend"}) {
+		t.Errorf("expected the partial config to be merged, got %#v", updated)
+	}
+
+	for _, args := range [][]string{{"update", "pods/foo"}, {"--patch=desiredState.replicas", "update", "pods/foo"}} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+}
+
+func TestRunUpdateConflictRetry(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+
+	for _, retries := range []int{0, 1} {
+		gets, puts := 0, 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == "GET" {
+				gets++
+				data, _ := api.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: uint64(gets)}})
+				w.Write(data)
+				return
+			}
+			puts++
+			if puts == 1 {
+				data, _ := api.Encode(&api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeConflict})
+				w.WriteHeader(http.StatusConflict)
+				w.Write(data)
+				return
+			}
+			body, _ := ioutil.ReadAll(req.Body)
+			w.Write(body)
+		}))
+
+		code := runKubecfg(t, server, "--conflict-retries="+strconv.Itoa(retries), "--patch=labels.name=foo", "update", "pods/foo")
+		if retries == 0 && (code != kubecfg.ExitConflict || gets != 1 || puts != 1) {
+			t.Errorf("expected no retry, got exit code %d after %d reads and %d updates", code, gets, puts)
+		}
+		if retries == 1 && (code != kubecfg.ExitSuccess || gets != 2 || puts != 2) {
+			t.Errorf("expected the update to be retried, got exit code %d after %d reads and %d updates", code, gets, puts)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+// offlineServer returns a server that fails the test if kubecfg makes any request to it.
+func offlineServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("unexpected request: %s %s", req.Method, req.URL)
+	}))
+}
+
+func TestRunValidate(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	server := offlineServer(t)
+	defer server.Close()
+
+	valid := filepath.Join(dir, "valid.yaml")
+	if err := ioutil.WriteFile(valid, []byte("kind: Pod\nid: foo\ndesiredState:\n  manifest:\n    version: v1beta1\n    id: foo\n---\nkind: Service\nid: bar\nport: 80\nselector:\n  name: foo\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code, output := runKubecfgOutput(t, server, "-c", valid, "validate")
+	if code != kubecfg.ExitSuccess {
+		t.Errorf("expected valid objects, got exit code %d", code)
+	}
+	if !strings.Contains(output, "valid.yaml[1]: valid") {
+		t.Errorf("expected each object to be reported, got %q", output)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := ioutil.WriteFile(invalid, []byte(`[{"kind": "Pod", "id": "foo"}, {"kind": "Pod", "id": "Foo", "replica": 2}]`), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if code := runKubecfg(t, server, "-c", invalid, "validate"); code != kubecfg.ExitInvalid {
+		t.Errorf("expected invalid objects, got exit code %d", code)
+	}
+	if code := runKubecfg(t, server, "-c", valid, "validate", "services"); code != kubecfg.ExitInvalid {
+		t.Errorf("expected a pod sent to services to be invalid, got exit code %d", code)
+	}
+	for _, args := range [][]string{
+		{"validate"},
+		{"-c", valid, "validate", "nothings"},
+		{"-c", valid, "validate", "pods", "foo"},
+	} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	dir, restore := withHome(t, "")
+	defer restore()
+	server := offlineServer(t)
+	defer server.Close()
+
+	config := filepath.Join(dir, "pods.yaml")
+	if err := ioutil.WriteFile(config, []byte("kind: Pod\nid: foo\n---\nkind: Pod\nid: bar\n"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	code, output := runKubecfgOutput(t, server, "-c", config, "--to=v1beta1", "convert")
+	if code != kubecfg.ExitSuccess {
+		t.Fatalf("unexpected exit code %d", code)
+	}
+	objects, err := kubecfg.SplitConfigData([]byte(output))
+	if err != nil || len(objects) != 2 {
+		t.Fatalf("expected a list of 2 objects, got %v: %s", err, output)
+	}
+	for _, object := range objects {
+		if version, kind, _ := api.VersionAndKind(object); version != "v1beta1" || kind != "Pod" {
+			t.Errorf("expected a v1beta1 pod, got %s", object)
+		}
+	}
+	code, output = runKubecfgOutput(t, server, "-c", config, "--to=v1beta1", "--yaml", "convert")
+	if code != kubecfg.ExitSuccess || strings.Count(output, "apiVersion: v1beta1") != 2 || !strings.Contains(output, "\n---\n") {
+		t.Errorf("expected 2 YAML documents, got %d: %s", code, output)
+	}
+
+	if code := runKubecfg(t, server, "-c", config, "--to=v1beta2", "convert"); code != kubecfg.ExitInvalid {
+		t.Errorf("expected an unknown version to fail, got exit code %d", code)
+	}
+	if code := runKubecfg(t, server, "-c", config, "convert"); code != kubecfg.ExitUsage {
+		t.Errorf("expected a usage error without --to, got exit code %d", code)
+	}
+}
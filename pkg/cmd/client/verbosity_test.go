@@ -0,0 +1,31 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunVerbosityKeepsStdout(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, err := api.Encode(&api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "foo"}}}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	_, expected := runKubecfgOutput(t, server, "--json", "list", "pods")
+	for _, level := range []string{"--verbosity=2", "--verbosity=3"} {
+		code, output := runKubecfgOutput(t, server, level, "--json", "list", "pods")
+		if code != kubecfg.ExitSuccess || output != expected {
+			t.Errorf("%s: expected the output of the list alone, got %d: %s", level, code, output)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/kubecfg"
+)
+
+func TestRunWait(t *testing.T) {
+	_, restore := withHome(t, "")
+	defer restore()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1beta1/pods/foo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data, _ := api.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}, CurrentState: api.PodState{Status: api.PodRunning}})
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	for _, args := range [][]string{
+		{"--for=currentState.status=Running", "wait", "pods/foo"},
+		{"--for={.currentState.status}=Running", "wait", "pods", "foo"},
+	} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitSuccess {
+			t.Errorf("%v: unexpected exit code %d", args, code)
+		}
+	}
+	if code := runKubecfg(t, server, "--for=currentState.status=Terminated", "--timeout=200ms", "wait", "pods/foo"); code != kubecfg.ExitTimeout {
+		t.Errorf("expected a timeout, got exit code %d", code)
+	}
+	for _, args := range [][]string{
+		{"wait", "pods/foo"},
+		{"--for=delete", "wait", "pods"},
+		{"--for=delete", "wait", "pods/foo", "bar"},
+		{"--for=currentState.status", "wait", "pods/foo"},
+	} {
+		if code := runKubecfg(t, server, args...); code != kubecfg.ExitUsage {
+			t.Errorf("%v: expected a usage error, got %d", args, code)
+		}
+	}
+}
@@ -96,7 +96,8 @@ func startAllInOne() {
 		kubelet.ListenAndServeKubeletServer(k, cfg.Channel("http"), http.DefaultServeMux, minionHost, uint(minionPort))
 	}, 0)
 
-	// initialize OpenShift API
+	// initialize OpenShift API. The logs, the minion proxy and the index are served by the
+	// Kubernetes API only.
 	storage := map[string]apiserver.RESTStorage{
 		"services": service.NewRESTStorage(service.MakeMemoryRegistry()),
 	}
@@ -104,7 +105,7 @@ func startAllInOne() {
 	osPrefix := "/osapi/v1beta1"
 	osApi := &http.Server{
 		Addr:           osAddr,
-		Handler:        apiserver.New(storage, api.Codec, osPrefix),
+		Handler:        apiserver.NewWithConfig(storage, api.Codec, osPrefix, apiserver.Config{}),
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
@@ -118,6 +119,8 @@ func startAllInOne() {
 	kubeAddr := "127.0.0.1:8080"
 	kubePrefix := "/api/v1beta1"
 	kubeClient := client.New(fmt.Sprintf("http://%s", kubeAddr), nil)
+	// The controllers run with this master, so they always speak its binary encoding.
+	kubeClient.Binary = true
 	podInfoGetter := &client.HTTPPodInfoGetter{
 		Client: http.DefaultClient,
 		Port:   uint(minionPort),
@@ -128,6 +131,12 @@ func startAllInOne() {
 		HealthCheckMinions: true,
 		Minions:            []string{minionHost},
 		PodInfoGetter:      podInfoGetter,
+		EventTTL:           48 * time.Hour,
+		Handlers: &apiserver.Config{
+			EnableLogsSupport: true,
+			EnableProxy:       true,
+			EnableIndex:       true,
+		},
 	}
 	m := master.New(masterConfig)
 	go util.Forever(func() {
@@ -21,7 +21,7 @@ func (s *ServiceRegistryStorage) New() interface{} {
 	return &api.Service{}
 }
 
-func (s *ServiceRegistryStorage) Get(id string) (interface{}, error) {
+func (s *ServiceRegistryStorage) Get(ctx baseapi.Context, id string) (interface{}, error) {
 	service, err := s.registry.GetService(id)
 	if err != nil {
 		return service, err
@@ -32,7 +32,7 @@ func (s *ServiceRegistryStorage) Get(id string) (interface{}, error) {
 	return service, err
 }
 
-func (s *ServiceRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (s *ServiceRegistryStorage) List(ctx baseapi.Context, selector labels.Selector) (interface{}, error) {
 	var result api.ServiceList
 	services, err := s.registry.ListServices(selector)
 	if err == nil {
@@ -41,13 +41,13 @@ func (s *ServiceRegistryStorage) List(selector labels.Selector) (interface{}, er
 	return result, err
 }
 
-func (s *ServiceRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (s *ServiceRegistryStorage) Delete(ctx baseapi.Context, id string) (<-chan interface{}, error) {
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		return baseapi.Status{Status: baseapi.StatusSuccess}, s.registry.DeleteService(id)
 	}), nil
 }
 
-func (s *ServiceRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (s *ServiceRegistryStorage) Create(ctx baseapi.Context, obj interface{}) (<-chan interface{}, error) {
 	service := obj.(api.Service)
 	if len(service.ID) == 0 {
 		return nil, fmt.Errorf("id is unspecified: %#v", service)
@@ -57,11 +57,11 @@ func (s *ServiceRegistryStorage) Create(obj interface{}) (<-chan interface{}, er
 		if err := s.registry.CreateService(service); err != nil {
 			return nil, err
 		}
-		return s.Get(service.ID)
+		return s.Get(ctx, service.ID)
 	}), nil
 }
 
-func (s *ServiceRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (s *ServiceRegistryStorage) Update(ctx baseapi.Context, obj interface{}) (<-chan interface{}, error) {
 	service := obj.(api.Service)
 	if len(service.ID) == 0 {
 		return nil, fmt.Errorf("id is unspecified: %#v", service)
@@ -72,6 +72,6 @@ func (s *ServiceRegistryStorage) Update(obj interface{}) (<-chan interface{}, er
 		if err != nil {
 			return nil, err
 		}
-		return s.Get(service.ID)
+		return s.Get(ctx, service.ID)
 	}), nil
 }
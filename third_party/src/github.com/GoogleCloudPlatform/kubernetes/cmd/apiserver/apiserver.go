@@ -22,46 +22,105 @@ import (
 	"flag"
 	"net"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/admission"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/healthz"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/master"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	verflag "github.com/GoogleCloudPlatform/kubernetes/pkg/version/flag"
 	"github.com/golang/glog"
 )
 
 var (
-	port                        = flag.Uint("port", 8080, "The port to listen on.  Default 8080.")
+	port                        = flag.Uint("port", 8080, "The port to listen on, or 0 to serve only over HTTPS.  Default 8080.")
+	tlsPort                     = flag.Uint("tls_port", 0, "If non-zero, the port to serve HTTPS on, with -tls_cert_file and -tls_private_key_file. Both -port and -tls_port may be served at once. [default 0, no HTTPS]")
+	tlsCertFile                 = flag.String("tls_cert_file", "", "The file of the certificate to serve HTTPS with, reloaded when it changes")
+	tlsPrivateKeyFile           = flag.String("tls_private_key_file", "", "The file of the private key of -tls_cert_file")
+	clientCAFile                = flag.String("client_ca_file", "", "If set, HTTPS clients must present a certificate signed by one of the certificate authorities in this file")
+	tlsCertReloadPeriod         = flag.Duration("tls_cert_reload_period", time.Minute, "How often to check -tls_cert_file and -tls_private_key_file for changes. [default 1 minute]")
+	redirectToTLS               = flag.Bool("redirect_to_tls", false, "If true, requests to -port are redirected to -tls_port instead of being served")
 	address                     = flag.String("address", "127.0.0.1", "The address on the local server to listen to. Default 127.0.0.1")
 	apiPrefix                   = flag.String("api_prefix", "/api/v1beta1", "The prefix for API requests on the server. Default '/api/v1beta1'")
 	cloudProvider               = flag.String("cloud_provider", "", "The provider for cloud services.  Empty string for no provider.")
-	minionRegexp                = flag.String("minion_regexp", "", "If non empty, and -cloud_provider is specified, a regular expression for matching minion VMs")
+	minionRegexp                = flag.String("minion_regexp", "", "If non empty, and -cloud_provider is specified, a regular expression for matching minion VMs, which are added to -machines")
 	minionPort                  = flag.Uint("minion_port", 10250, "The port at which kubelet will be listening on the minions.")
-	healthCheckMinions          = flag.Bool("health_check_minions", true, "If true, health check minions and filter unhealthy ones. [default true]")
-	minionCacheTTL              = flag.Duration("minion_cache_ttl", 30*time.Second, "Duration of time to cache minion information. [default 30 seconds]")
+	healthCheckMinions          = flag.Bool("health_check_minions", true, "If true, health check minions, marking unhealthy ones unreachable and scheduling nothing onto them. [default true]")
+	minionCacheTTL              = flag.Duration("minion_cache_ttl", 30*time.Second, "How often to list the minions of the cloud provider and health check all minions. [default 30 seconds]")
+	admissionDefaultLabel       = flag.String("admission_default_label", "", "If set to key=value, the label key is set to value on every object created or updated without it")
+	admissionRequireLimits      = flag.Bool("admission_require_limits", false, "If true, reject pods with containers that don't set memory and cpu limits")
+	operationTTL                = flag.Duration("operation_ttl", 0, "If positive and -etcd_servers is set, keep the results of operations in etcd for this long, so they can be polled across restarts. [default 0, in memory only]")
+	eventTTL                    = flag.Duration("event_ttl", 48*time.Hour, "Amount of time to keep events in etcd before they expire. [default 48 hours]")
+	listCacheTTL                = flag.Duration("list_cache_ttl", 0, "If positive, cache responses to list requests for this long, e.g. 500ms, to absorb polling clients. [default 0, no cache]")
+	asyncOpWait                 = flag.Duration("async_op_wait", apiserver.DefaultAsyncOpWait, "How long requests that create, update or delete objects wait for their operation before they are answered with its ID. 0 answers them at once. [default 25ms]")
+	maxAsyncOpWait              = flag.Duration("max_async_op_wait", apiserver.DefaultMaxAsyncOpWait, "The longest requests may ask to wait for their operation with the wait parameter. [default 5s]")
+	slowRequestThreshold        = flag.Duration("slow_request_threshold", apiserver.DefaultSlowRequestThreshold, "How long a request may take before the time each of its steps took is logged. 0 logs nothing. [default 500ms]")
+	bodyReadTimeout             = flag.Duration("body_read_timeout", apiserver.DefaultBodyReadTimeout, "How long clients have to send the body of a request before it is answered with 408 Request Timeout. 0 waits forever. [default 30s]")
+	lenientParams               = flag.Bool("lenient_params", false, "If true, serve requests with query parameters the apiserver doesn't know, e.g. misspelled ones, instead of rejecting them. Requests may pass strictParams=true or false to choose for themselves. [default false]")
+	logInvalidBodies            = flag.Bool("log_invalid_bodies", false, "If true, log the start of the bodies of requests that can't be decoded or are invalid, with passwords and tokens redacted, at most 5 a minute. [default false]")
+	invalidBodyLogLimit         = flag.Int("invalid_body_log_limit", apiserver.DefaultBodyLogLimit, "How many bytes of each body -log_invalid_bodies logs. [default 1024]")
+	watchBufferSize             = flag.Int("watch_buffer_size", apiserver.DefaultWatchBufferSize, "The number of events each watch buffers for a client that reads them slowly. [default 100]")
+	watchBufferPolicy           = flag.String("watch_buffer_policy", string(apiserver.WatchBufferDrop), "What a watch does when its buffer is full: \"drop\" ends it with an error telling the client to list again, \"coalesce\" keeps only the newest event of each object, and drops the watch if that isn't enough. [default drop]")
+	watchReplaySize             = flag.Int("watch_replay_size", tools.DefaultWatchReplaySize, "The number of recent events of each resource kept to start watches from a recent resource version over the single etcd watch of the resource. Watches from older versions watch etcd themselves. [default 100]")
+	storageBreakerFailures      = flag.Int("storage_breaker_failures", 0, "If positive, the number of consecutive etcd calls that must fail for requests to be failed fast with 503 Service Unavailable for -storage_breaker_cooldown. [default 0, off]")
+	storageBreakerLatency       = flag.Duration("storage_breaker_latency", 0, "If positive, the average latency of recent etcd calls over which requests are failed fast with 503 Service Unavailable for -storage_breaker_cooldown. [default 0, off]")
+	storageBreakerCooldown      = flag.Duration("storage_breaker_cooldown", tools.DefaultBreakerCooldown, "How long requests are failed fast once etcd looks unavailable, before a single call probes it. [default 30s]")
+	mirrorURL                   = flag.String("mirror_url", "", "If set, the URL of a shadow apiserver, e.g. a new build to be tried with production traffic, that copies of the requests reading objects are sent to. Only the status codes of its answers are compared, and the mismatches counted in /metrics. [default \"\", no mirroring]")
+	mirrorRatio                 = flag.Float64("mirror_ratio", 1, "The share of the requests reading objects that -mirror_url is sent, more than 0 and at most 1. [default 1, every request]")
+	listWorkers                 = flag.Int("list_workers", 0, "The number of goroutines that match pod lists against label selectors. [default 0, one per CPU]")
+	validate                    = flag.Bool("validate", false, "If true, set up the apiserver without serving it, check each of the dependencies /healthz checks once, print a report, and exit 0 if they are all healthy or 1 otherwise")
 	etcdServerList, machineList util.StringList
+
+	legacyIDs util.StringList
 )
 
 func init() {
 	flag.Var(&etcdServerList, "etcd_servers", "List of etcd servers to watch (http://ip:port), comma separated")
 	flag.Var(&machineList, "machines", "List of machines to schedule onto, comma separated.")
+	flag.Var(&legacyIDs, "legacy_ids", "List of resource/id, e.g. pods/myPod, comma separated, naming objects created before IDs were validated that may still be created with those IDs.")
 }
 
 func verifyMinionFlags() {
-	if *cloudProvider == "" || *minionRegexp == "" {
-		if len(machineList) == 0 {
-			glog.Fatal("No machines specified!")
-		}
-		return
+	if (*cloudProvider == "" || *minionRegexp == "") && len(machineList) == 0 {
+		glog.Fatal("No machines specified!")
 	}
-	if len(machineList) != 0 {
-		glog.Info("-machines is overwritten by -minion_regexp")
+}
+
+func verifyTLSFlags() {
+	if *tlsPort != 0 && (*tlsCertFile == "" || *tlsPrivateKeyFile == "") {
+		glog.Fatal("-tls_port requires -tls_cert_file and -tls_private_key_file")
+	}
+	if *port == 0 && *tlsPort == 0 {
+		glog.Fatal("No port to serve on: set -port or -tls_port")
+	}
+	if *redirectToTLS && (*port == 0 || *tlsPort == 0) {
+		glog.Fatal("-redirect_to_tls requires both -port and -tls_port")
+	}
+	if policy := apiserver.WatchBufferPolicy(*watchBufferPolicy); policy != apiserver.WatchBufferDrop && policy != apiserver.WatchBufferCoalesce {
+		glog.Fatalf("-watch_buffer_policy must be %q or %q", apiserver.WatchBufferDrop, apiserver.WatchBufferCoalesce)
 	}
 }
 
+// healthChecks returns the checks of the dependencies of the apiserver process itself: the
+// directory glog writes to, if it doesn't log to stderr.
+func healthChecks() []healthz.Check {
+	if flag.Lookup("logtostderr").Value.String() == "true" {
+		return nil
+	}
+	logDir := flag.Lookup("log_dir").Value.String()
+	if len(logDir) == 0 {
+		logDir = os.TempDir()
+	}
+	return []healthz.Check{{Name: "logs", Check: healthz.WritableDir(logDir)}}
+}
+
 func main() {
 	flag.Parse()
 	util.InitLogs()
@@ -69,6 +128,7 @@ func main() {
 
 	verflag.PrintAndExitIfRequested()
 	verifyMinionFlags()
+	verifyTLSFlags()
 
 	var cloud cloudprovider.Interface
 	switch *cloudProvider {
@@ -93,26 +153,122 @@ func main() {
 
 	client := client.New("http://"+net.JoinHostPort(*address, strconv.Itoa(int(*port))), nil)
 
+	var admissionChain []apiserver.Admission
+	if len(*admissionDefaultLabel) > 0 {
+		parts := strings.SplitN(*admissionDefaultLabel, "=", 2)
+		if len(parts) != 2 || len(parts[0]) == 0 {
+			glog.Fatalf("-admission_default_label must be key=value, got %q", *admissionDefaultLabel)
+		}
+		admissionChain = append(admissionChain, admission.DefaultLabel(parts[0], parts[1]))
+	}
+	if *admissionRequireLimits {
+		admissionChain = append(admissionChain, admission.RequireResourceLimits())
+	}
+
+	// The master takes an AsyncOpWait, a SlowRequestThreshold and a BodyReadTimeout of 0 to
+	// mean the default.
+	wait := *asyncOpWait
+	if wait == 0 {
+		wait = -1
+	}
+	threshold := *slowRequestThreshold
+	if threshold == 0 {
+		threshold = -1
+	}
+	readTimeout := *bodyReadTimeout
+	if readTimeout == 0 {
+		readTimeout = -1
+	}
+	bodyLogLimit := 0
+	if *logInvalidBodies {
+		bodyLogLimit = *invalidBodyLogLimit
+	}
+
+	handlers := apiserver.DefaultConfig()
+	handlers.StorageBreaker = tools.BreakerConfig{
+		FailureThreshold: *storageBreakerFailures,
+		LatencyThreshold: *storageBreakerLatency,
+		Cooldown:         *storageBreakerCooldown,
+	}
+	if len(*mirrorURL) != 0 {
+		mirror, err := apiserver.NewMirror(*mirrorURL, *mirrorRatio)
+		if err != nil {
+			glog.Fatalf("Invalid -mirror_url or -mirror_ratio: %v", err)
+		}
+		handlers.Mirror = mirror
+	}
+
 	var m *master.Master
 	if len(etcdServerList) > 0 {
 		m = master.New(&master.Config{
-			Client:             client,
-			Cloud:              cloud,
-			EtcdServers:        etcdServerList,
-			HealthCheckMinions: *healthCheckMinions,
-			Minions:            machineList,
-			MinionCacheTTL:     *minionCacheTTL,
-			MinionRegexp:       *minionRegexp,
-			PodInfoGetter:      podInfoGetter,
+			Client:               client,
+			Cloud:                cloud,
+			EtcdServers:          etcdServerList,
+			HealthCheckMinions:   *healthCheckMinions,
+			Minions:              machineList,
+			MinionCacheTTL:       *minionCacheTTL,
+			MinionRegexp:         *minionRegexp,
+			PodInfoGetter:        podInfoGetter,
+			OperationTTL:         *operationTTL,
+			EventTTL:             *eventTTL,
+			ListCacheTTL:         *listCacheTTL,
+			AsyncOpWait:          wait,
+			MaxAsyncOpWait:       *maxAsyncOpWait,
+			SlowRequestThreshold: threshold,
+			BodyReadTimeout:      readTimeout,
+			ListWorkers:          *listWorkers,
+			Admission:            admissionChain,
+			LegacyIDs:            legacyIDs,
+			LenientParams:        *lenientParams,
+			InvalidBodyLogLimit:  bodyLogLimit,
+			WatchBufferSize:      *watchBufferSize,
+			WatchBufferPolicy:    apiserver.WatchBufferPolicy(*watchBufferPolicy),
+			WatchReplaySize:      *watchReplaySize,
+			Handlers:             &handlers,
+			HealthChecks:         healthChecks(),
 		})
 	} else {
 		m = master.NewMemoryServer(&master.Config{
-			Client:        client,
-			Cloud:         cloud,
-			Minions:       machineList,
-			PodInfoGetter: podInfoGetter,
+			Client:               client,
+			Cloud:                cloud,
+			Minions:              machineList,
+			PodInfoGetter:        podInfoGetter,
+			Admission:            admissionChain,
+			ListCacheTTL:         *listCacheTTL,
+			AsyncOpWait:          wait,
+			MaxAsyncOpWait:       *maxAsyncOpWait,
+			SlowRequestThreshold: threshold,
+			BodyReadTimeout:      readTimeout,
+			LegacyIDs:            legacyIDs,
+			LenientParams:        *lenientParams,
+			InvalidBodyLogLimit:  bodyLogLimit,
+			WatchBufferSize:      *watchBufferSize,
+			WatchBufferPolicy:    apiserver.WatchBufferPolicy(*watchBufferPolicy),
+			Handlers:             &handlers,
+			HealthChecks:         healthChecks(),
 		})
 	}
 
-	glog.Fatal(m.Run(net.JoinHostPort(*address, strconv.Itoa(int(*port))), *apiPrefix))
+	if *validate {
+		if !m.Validate(*apiPrefix, os.Stdout) {
+			util.FlushLogs()
+			os.Exit(1)
+		}
+		return
+	}
+
+	serving := &master.ServingOptions{
+		CertFile:         *tlsCertFile,
+		KeyFile:          *tlsPrivateKeyFile,
+		ClientCAFile:     *clientCAFile,
+		CertReloadPeriod: *tlsCertReloadPeriod,
+		RedirectToTLS:    *redirectToTLS,
+	}
+	if *port != 0 {
+		serving.Address = net.JoinHostPort(*address, strconv.Itoa(int(*port)))
+	}
+	if *tlsPort != 0 {
+		serving.TLSAddress = net.JoinHostPort(*address, strconv.Itoa(int(*tlsPort)))
+	}
+	glog.Fatal(m.Serve(serving, *apiPrefix))
 }
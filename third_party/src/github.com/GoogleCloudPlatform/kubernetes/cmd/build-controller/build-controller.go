@@ -17,7 +17,7 @@ var (
 	dockerBuilderImage = flag.String("docker_builder_image", "docker-builder", "Image to use when running 'docker build' builds")
 	dockerRegistry     = flag.String("docker_registry", "", "The address of the Docker registry that hosts built images")
 	stiBuilderImage    = flag.String("sti_builder_image", "sti-builder", "Image to use when running 'sti build' builds")
-	timeout            = flag.Int("timeout", 120, "The timeout to enforce for builds") // TODO: better description
+	timeout            = flag.Int("timeout", 120, "The number of seconds a build may run before it is failed, for builds that do not set a timeout")
 )
 
 func main() {
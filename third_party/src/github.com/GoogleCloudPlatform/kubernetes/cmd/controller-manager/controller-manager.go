@@ -32,7 +32,8 @@ import (
 )
 
 var (
-	master = flag.String("master", "", "The address of the Kubernetes API server")
+	master    = flag.String("master", "", "The address of the Kubernetes API server")
+	binaryAPI = flag.Bool("binary_api", false, "Talk to the API server in its binary encoding rather than JSON. The API server must be as recent as the controller manager")
 )
 
 func main() {
@@ -46,8 +47,9 @@ func main() {
 		glog.Fatal("usage: controller-manager -master <master>")
 	}
 
-	controllerManager := controller.MakeReplicationManager(
-		client.New("http://"+*master, nil))
+	kubeClient := client.New("http://"+*master, nil)
+	kubeClient.Binary = *binaryAPI
+	controllerManager := controller.MakeReplicationManager(kubeClient)
 
 	controllerManager.Run(10 * time.Second)
 	select {}
@@ -161,20 +161,17 @@ func runReplicationControllerTest(kubeClient *client.Client) {
 }
 
 func runAtomicPutTest(c *client.Client) {
-	var svc api.Service
-	err := c.Post().Path("services").Body(
-		api.Service{
-			JSONBase: api.JSONBase{ID: "atomicservice", APIVersion: "v1beta1"},
-			Port:     12345,
-			Labels: map[string]string{
-				"name": "atomicService",
-			},
-			// This is here because validation requires it.
-			Selector: map[string]string{
-				"foo": "bar",
-			},
+	svc, err := c.CreateService(api.Service{
+		JSONBase: api.JSONBase{ID: "atomicservice", APIVersion: "v1beta1"},
+		Port:     12345,
+		Labels: map[string]string{
+			"name": "atomicService",
 		},
-	).Do().Into(&svc)
+		// This is here because validation requires it.
+		Selector: map[string]string{
+			"foo": "bar",
+		},
+	})
 	if err != nil {
 		glog.Fatalf("Failed creating atomicService: %v", err)
 	}
@@ -192,13 +189,7 @@ func runAtomicPutTest(c *client.Client) {
 		go func(l, v string) {
 			for {
 				glog.Infof("Starting to update (%s, %s)", l, v)
-				var tmpSvc api.Service
-				err := c.Get().
-					Path("services").
-					Path(svc.ID).
-					PollPeriod(100 * time.Millisecond).
-					Do().
-					Into(&tmpSvc)
+				tmpSvc, err := c.GetService(svc.ID)
 				if err != nil {
 					glog.Errorf("Error getting atomicService: %v", err)
 					continue
@@ -209,14 +200,12 @@ func runAtomicPutTest(c *client.Client) {
 					tmpSvc.Selector[l] = v
 				}
 				glog.Infof("Posting update (%s, %s)", l, v)
-				err = c.Put().Path("services").Path(svc.ID).Body(&tmpSvc).Do().Error()
+				_, err = c.UpdateService(tmpSvc)
 				if err != nil {
-					if se, ok := err.(*client.StatusErr); ok {
-						if se.Status.Code == http.StatusConflict {
-							glog.Infof("Conflict: (%s, %s)", l, v)
-							// This is what we expect.
-							continue
-						}
+					if client.IsConflict(err) {
+						glog.Infof("Conflict: (%s, %s)", l, v)
+						// This is what we expect.
+						continue
 					}
 					glog.Errorf("Unexpected error putting atomicService: %v", err)
 					continue
@@ -228,7 +217,7 @@ func runAtomicPutTest(c *client.Client) {
 		}(label, value)
 	}
 	wg.Wait()
-	if err := c.Get().Path("services").Path(svc.ID).Do().Into(&svc); err != nil {
+	if svc, err = c.GetService(svc.ID); err != nil {
 		glog.Fatalf("Failed getting atomicService after writers are complete: %v", err)
 	}
 	if !reflect.DeepEqual(testLabels, labels.Set(svc.Selector)) {
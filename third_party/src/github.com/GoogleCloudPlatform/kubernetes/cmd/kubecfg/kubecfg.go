@@ -48,7 +48,7 @@ var (
 	portSpec      = flag.String("p", "", "The port spec, comma-separated list of <external>:<internal>,...")
 	servicePort   = flag.Int("s", -1, "If positive, create and run a corresponding service on this port, only used with 'run'")
 	authConfig    = flag.String("auth", os.Getenv("HOME")+"/.kubernetes_auth", "Path to the auth info file.  If missing, prompt the user.  Only used if doing https.")
-	json          = flag.Bool("json", false, "If true, print raw JSON for responses")
+	json          = flag.Bool("json", false, "If true, print responses as indented JSON")
 	yaml          = flag.Bool("yaml", false, "If true, print raw YAML for responses")
 	verbose       = flag.Bool("verbose", false, "If true, print extra information")
 	proxy         = flag.Bool("proxy", false, "If true, run a proxy to the api server")
@@ -136,6 +136,9 @@ func main() {
 	}
 
 	client := kube_client.New(masterServer, auth)
+	if *verbose {
+		kubecfg.LogServerConfig(client)
+	}
 
 	if *serverVersion {
 		got, err := client.ServerVersion()
@@ -266,12 +269,23 @@ func executeAPIRequest(method string, s *kube_client.Client) bool {
 			}
 			r.Body(data)
 		} else {
-			r.Body(readConfig(storage))
+			data := readConfig(storage)
+			if verb == "POST" {
+				if err := kubecfg.ValidateID(data); err != nil {
+					glog.Fatalf("Invalid object in %v: %v\n", *config, err)
+				}
+			}
+			r.Body(data)
 		}
 	}
 	result := r.Do()
 	obj, err := result.Get()
 	if err != nil {
+		if !*json && !*yaml {
+			for _, line := range kubecfg.InvalidFields(err) {
+				fmt.Fprintln(os.Stderr, line)
+			}
+		}
 		glog.Fatalf("Got request error: %v\n", err)
 		return false
 	}
@@ -279,7 +293,7 @@ func executeAPIRequest(method string, s *kube_client.Client) bool {
 	var printer kubecfg.ResourcePrinter
 	switch {
 	case *json:
-		printer = &kubecfg.IdentityPrinter{}
+		printer = &kubecfg.JSONPrinter{}
 	case *yaml:
 		printer = &kubecfg.YAMLPrinter{}
 	case len(*templateFile) > 0 || len(*templateStr) > 0:
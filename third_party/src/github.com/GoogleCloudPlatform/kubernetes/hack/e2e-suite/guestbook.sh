@@ -35,10 +35,10 @@ sleep 5
 POD_LIST_1=$($CLOUDCFG -json list pods | jq ".items[].id")
 echo "Pods running: ${POD_LIST_1}"
 
-$CLOUDCFG stop redisSlaveController
+$CLOUDCFG stop redis-slave-controller
 # Needed until issue #103 gets fixed
 sleep 25
-$CLOUDCFG rm redisSlaveController
+$CLOUDCFG rm redis-slave-controller
 $CLOUDCFG delete services/redismaster
 $CLOUDCFG delete pods/redis-master-2
 
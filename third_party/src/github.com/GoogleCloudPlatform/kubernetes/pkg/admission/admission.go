@@ -0,0 +1,75 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+)
+
+// DefaultLabel returns an Admission that sets the label key to value on every object
+// that has labels and doesn't set key itself.
+func DefaultLabel(key, value string) apiserver.Admission {
+	return apiserver.AdmissionFunc(func(verb, resource string, obj interface{}) error {
+		labels := labelsOf(obj)
+		if !labels.IsValid() {
+			return nil
+		}
+		if labels.IsNil() {
+			labels.Set(reflect.ValueOf(map[string]string{}))
+		}
+		m := labels.Interface().(map[string]string)
+		if _, ok := m[key]; !ok {
+			m[key] = value
+		}
+		return nil
+	})
+}
+
+// labelsOf returns the Labels field of the struct obj points to, or the zero Value if
+// it has none.
+func labelsOf(obj interface{}) reflect.Value {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+	labels := v.Elem().FieldByName("Labels")
+	if !labels.IsValid() || labels.Type() != reflect.TypeOf(map[string]string{}) {
+		return reflect.Value{}
+	}
+	return labels
+}
+
+// RequireResourceLimits returns an Admission that rejects pods with a container that
+// doesn't limit its memory and CPU.
+func RequireResourceLimits() apiserver.Admission {
+	return apiserver.AdmissionFunc(func(verb, resource string, obj interface{}) error {
+		pod, ok := obj.(*api.Pod)
+		if !ok {
+			return nil
+		}
+		for _, container := range pod.DesiredState.Manifest.Containers {
+			if container.Memory <= 0 || container.CPU <= 0 {
+				return fmt.Errorf("container %q must set memory and cpu limits", container.Name)
+			}
+		}
+		return nil
+	})
+}
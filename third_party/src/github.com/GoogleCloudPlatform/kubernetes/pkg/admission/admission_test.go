@@ -0,0 +1,72 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package admission
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+)
+
+func TestDefaultLabel(t *testing.T) {
+	admission := DefaultLabel("team", "ops")
+
+	pod := &api.Pod{}
+	if err := admission.Admit(apiserver.AdmitCreate, "pods", pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(pod.Labels, map[string]string{"team": "ops"}) {
+		t.Errorf("expected the label to be added, got %v", pod.Labels)
+	}
+
+	service := &api.Service{Labels: map[string]string{"team": "web", "name": "foo"}}
+	if err := admission.Admit(apiserver.AdmitUpdate, "services", service); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(service.Labels, map[string]string{"team": "web", "name": "foo"}) {
+		t.Errorf("expected an existing label to be kept, got %v", service.Labels)
+	}
+
+	if err := admission.Admit(apiserver.AdmitCreate, "minions", &api.Minion{}); err != nil {
+		t.Errorf("expected objects without labels to be admitted: %v", err)
+	}
+}
+
+func TestRequireResourceLimits(t *testing.T) {
+	admission := RequireResourceLimits()
+	pod := func(containers ...api.Container) *api.Pod {
+		return &api.Pod{DesiredState: api.PodState{Manifest: api.ContainerManifest{Containers: containers}}}
+	}
+
+	if err := admission.Admit(apiserver.AdmitCreate, "pods", pod(api.Container{Name: "a", Memory: 1, CPU: 1})); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	table := map[string]*api.Pod{
+		"no memory": pod(api.Container{Name: "a", Memory: 1, CPU: 1}, api.Container{Name: "b", CPU: 1}),
+		"no cpu":    pod(api.Container{Name: "a", Memory: 1}),
+	}
+	for name, item := range table {
+		if err := admission.Admit(apiserver.AdmitCreate, "pods", item); err == nil {
+			t.Errorf("%s: expected the pod to be denied", name)
+		}
+	}
+	if err := admission.Admit(apiserver.AdmitCreate, "services", &api.Service{}); err != nil {
+		t.Errorf("expected other objects to be admitted: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"time"
+)
+
+// Context describes the API request on whose behalf a storage operation is performed.
+type Context struct {
+	// User is the name of the authenticated user making the request, or empty if the
+	// request was not authenticated.
+	User string
+	// RequestID identifies the request, e.g. in logs and audit records.
+	RequestID string
+	// Deadline is the time by which a synchronous request must complete. It is zero if
+	// the request has no deadline.
+	Deadline time.Time
+	// Namespace is the namespace the request is scoped to, or NamespaceAll if it spans
+	// all namespaces.
+	Namespace string
+	// Done is closed when the client that made the request goes away, so that work done
+	// only to answer it can be abandoned. It is nil, and never closed, for work that is not
+	// done on behalf of a request or whose client can't be watched.
+	Done <-chan struct{}
+	// Force is true if the request asked, with force=true, to be carried out despite the
+	// checks that may be overridden, e.g. that replication controllers don't select the
+	// same pods.
+	Force bool
+}
+
+// ErrCancelled is returned by work abandoned because the client of its request went away.
+var ErrCancelled = errors.New("the client of the request went away")
+
+const (
+	// NamespaceDefault is the namespace of objects that were not given one, and of
+	// requests that don't name one.
+	NamespaceDefault = "default"
+	// NamespaceAll is the namespace of requests that span all namespaces, e.g. lists
+	// that don't name a namespace.
+	NamespaceAll = ""
+)
+
+// NamespaceSeparator joins the ID of an object and its namespace in the name registries
+// store the object under, if it is not in the default namespace. Namespaces can't contain
+// it and IDs may not, so the names of objects in different namespaces never collide.
+const NamespaceSeparator = "_"
+
+// QualifiedID returns the name under which registries store the object called id in
+// namespace. Objects in the default namespace, or without one, are stored under their own
+// ID, as they were before namespaces existed.
+func QualifiedID(namespace, id string) string {
+	if namespace == NamespaceAll || namespace == NamespaceDefault {
+		return id
+	}
+	return id + NamespaceSeparator + namespace
+}
+
+// NewContext returns a Context for work that is not done on behalf of an API request.
+// It spans all namespaces.
+func NewContext() Context {
+	return Context{}
+}
+
+// NewDefaultContext returns a Context like NewContext, scoped to the default namespace.
+func NewDefaultContext() Context {
+	return Context{Namespace: NamespaceDefault}
+}
+
+// HasDeadline returns true if the request must complete by ctx.Deadline.
+func (ctx Context) HasDeadline() bool {
+	return !ctx.Deadline.IsZero()
+}
+
+// Err returns ErrCancelled once the client of the request of ctx has gone away, and nil
+// until then.
+func (ctx Context) Err() error {
+	select {
+	case <-ctx.Done:
+		return ErrCancelled
+	default:
+		return nil
+	}
+}
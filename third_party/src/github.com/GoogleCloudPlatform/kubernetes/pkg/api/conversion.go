@@ -0,0 +1,124 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/v1beta1"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/conversion"
+)
+
+// conversionFuncs convert between the internal objects and the objects of v1beta1. Objects
+// without a conversion here are copied field by field, which only works while both versions
+// have the same fields. The top-level objects people store are converted one field at a time
+// explicitly, so that renaming one of their fields in a version only takes changing their
+// conversions.
+//
+// TODO: Consider inverting dependency chain-- imagine v1beta1 package
+// registering all of these functions. Then, if you want to be able to understand
+// v1beta1 objects, you just import that package for its side effects.
+var conversionFuncs = []interface{}{
+	// EnvVar's Key is deprecated in favor of Name.
+	func(in *EnvVar, out *v1beta1.EnvVar) error {
+		out.Value = in.Value
+		out.Key = in.Name
+		out.Name = in.Name
+		return nil
+	},
+	func(in *v1beta1.EnvVar, out *EnvVar) error {
+		out.Value = in.Value
+		if in.Name != "" {
+			out.Name = in.Name
+		} else {
+			out.Name = in.Key
+		}
+		return nil
+	},
+
+	func(in *Pod, out *v1beta1.Pod) error {
+		return convertFields(
+			field{"JSONBase", &in.JSONBase, &out.JSONBase},
+			field{"Labels", &in.Labels, &out.Labels},
+			field{"DesiredState", &in.DesiredState, &out.DesiredState},
+			field{"CurrentState", &in.CurrentState, &out.CurrentState},
+		)
+	},
+	func(in *v1beta1.Pod, out *Pod) error {
+		return convertFields(
+			field{"JSONBase", &in.JSONBase, &out.JSONBase},
+			field{"Labels", &in.Labels, &out.Labels},
+			field{"DesiredState", &in.DesiredState, &out.DesiredState},
+			field{"CurrentState", &in.CurrentState, &out.CurrentState},
+		)
+	},
+
+	func(in *ReplicationController, out *v1beta1.ReplicationController) error {
+		return convertFields(
+			field{"JSONBase", &in.JSONBase, &out.JSONBase},
+			field{"DesiredState", &in.DesiredState, &out.DesiredState},
+			field{"CurrentState", &in.CurrentState, &out.CurrentState},
+			field{"Labels", &in.Labels, &out.Labels},
+		)
+	},
+	func(in *v1beta1.ReplicationController, out *ReplicationController) error {
+		return convertFields(
+			field{"JSONBase", &in.JSONBase, &out.JSONBase},
+			field{"DesiredState", &in.DesiredState, &out.DesiredState},
+			field{"CurrentState", &in.CurrentState, &out.CurrentState},
+			field{"Labels", &in.Labels, &out.Labels},
+		)
+	},
+
+	func(in *Service, out *v1beta1.Service) error {
+		out.Port = in.Port
+		out.Protocol = in.Protocol
+		out.CreateExternalLoadBalancer = in.CreateExternalLoadBalancer
+		out.ContainerPort = in.ContainerPort
+		return convertFields(
+			field{"JSONBase", &in.JSONBase, &out.JSONBase},
+			field{"Labels", &in.Labels, &out.Labels},
+			field{"Selector", &in.Selector, &out.Selector},
+		)
+	},
+	func(in *v1beta1.Service, out *Service) error {
+		out.Port = in.Port
+		out.Protocol = in.Protocol
+		out.CreateExternalLoadBalancer = in.CreateExternalLoadBalancer
+		out.ContainerPort = in.ContainerPort
+		return convertFields(
+			field{"JSONBase", &in.JSONBase, &out.JSONBase},
+			field{"Labels", &in.Labels, &out.Labels},
+			field{"Selector", &in.Selector, &out.Selector},
+		)
+	},
+}
+
+// field is a field of an object being converted, and the field it is converted to.
+type field struct {
+	name    string
+	in, out interface{}
+}
+
+// convertFields converts each of fields, stopping at the first that fails. Its error names
+// the field.
+func convertFields(fields ...field) error {
+	for _, f := range fields {
+		if err := Convert(f.in, f.out); err != nil {
+			return conversion.NewFieldError(f.name, err)
+		}
+	}
+	return nil
+}
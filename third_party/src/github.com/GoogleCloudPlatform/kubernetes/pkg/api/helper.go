@@ -18,6 +18,7 @@ package api
 
 import (
 	"fmt"
+	"mime"
 	"reflect"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/v1beta1"
@@ -41,6 +42,18 @@ type resourceVersioner interface {
 }
 
 var Codec codec
+
+// GobCodec encodes and decodes the same objects as Codec, in the binary format of
+// encoding/gob. It is much cheaper than JSON, for clients and servers that both speak it.
+var GobCodec codec
+
+const (
+	// JSONMediaType is the media type of objects encoded with Codec.
+	JSONMediaType = "application/json"
+	// GobMediaType is the media type of objects encoded with GobCodec.
+	GobMediaType = "application/vnd.kubernetes+gob"
+)
+
 var ResourceVersioner resourceVersioner
 
 var conversionScheme *conversion.Scheme
@@ -53,6 +66,7 @@ func init() {
 	AddKnownTypes("",
 		PodList{},
 		Pod{},
+		PodSummary{},
 		ReplicationControllerList{},
 		ReplicationController{},
 		ServiceList{},
@@ -64,11 +78,16 @@ func init() {
 		ServerOp{},
 		ContainerManifestList{},
 		Endpoints{},
+		EndpointsList{},
 		Binding{},
+		ObjectLabels{},
+		Event{},
+		EventList{},
 	)
 	AddKnownTypes("v1beta1",
 		v1beta1.PodList{},
 		v1beta1.Pod{},
+		v1beta1.PodSummary{},
 		v1beta1.ReplicationControllerList{},
 		v1beta1.ReplicationController{},
 		v1beta1.ServiceList{},
@@ -80,37 +99,28 @@ func init() {
 		v1beta1.ServerOp{},
 		v1beta1.ContainerManifestList{},
 		v1beta1.Endpoints{},
+		v1beta1.EndpointsList{},
 		v1beta1.Binding{},
+		v1beta1.ObjectLabels{},
+		v1beta1.Event{},
+		v1beta1.EventList{},
 	)
-
-	// TODO: when we get more of this stuff, move to its own file. This is not a
-	// good home for lots of conversion functions.
-	// TODO: Consider inverting dependency chain-- imagine v1beta1 package
-	// registering all of these functions. Then, if you want to be able to understand
-	// v1beta1 objects, you just import that package for its side effects.
-	AddConversionFuncs(
-		// EnvVar's Key is deprecated in favor of Name.
-		func(in *EnvVar, out *v1beta1.EnvVar) error {
-			out.Value = in.Value
-			out.Key = in.Name
-			out.Name = in.Name
-			return nil
-		},
-		func(in *v1beta1.EnvVar, out *EnvVar) error {
-			out.Value = in.Value
-			if in.Name != "" {
-				out.Name = in.Name
-			} else {
-				out.Name = in.Key
-			}
-			return nil
-		},
-	)
+	AddConversionFuncs(conversionFuncs...)
 
 	Codec = conversionScheme
+	GobCodec = conversionScheme.GobCodec()
 	ResourceVersioner = NewJSONBaseResourceVersioner()
 }
 
+// CodecForMediaType returns the codec of objects of the media type in contentType, the value
+// of a Content-Type header: GobCodec for GobMediaType, and Codec for anything else.
+func CodecForMediaType(contentType string) codec {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == GobMediaType {
+		return GobCodec
+	}
+	return Codec
+}
+
 // AddKnownTypes registers the types of the arguments to the marshaller of the package api.
 // Encode() refuses the object unless its type is registered with AddKnownTypes.
 func AddKnownTypes(version string, types ...interface{}) {
@@ -220,8 +230,7 @@ func EncodeOrDie(obj interface{}) string {
 // objects, whether they be in our storage layer (e.g., etcd), or in user's
 // config files.
 //
-// TODO/next steps: When we add our second versioned type, this package will
-// need a version of Encode that lets you choose the wire version. A configurable
+// TODO/next steps: EncodeToVersion lets you choose the wire version. A configurable
 // default will be needed, to allow operating in clusters that haven't yet
 // upgraded.
 //
@@ -229,6 +238,12 @@ func Encode(obj interface{}) (data []byte, err error) {
 	return conversionScheme.Encode(obj)
 }
 
+// EncodeToVersion is like Encode, but encodes obj as the given API version, which must be
+// one that types were added to with AddKnownTypes.
+func EncodeToVersion(obj interface{}, version string) (data []byte, err error) {
+	return conversionScheme.EncodeToVersion(obj, version)
+}
+
 // Ensures that obj is a pointer of some sort. Returns a reflect.Value of the
 // dereferenced pointer, ensuring that it is settable/addressable.
 // Returns an error if this is not possible.
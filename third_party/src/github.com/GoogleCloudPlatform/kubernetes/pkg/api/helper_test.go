@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/conversion"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/fsouza/go-dockerclient"
 	"github.com/google/gofuzz"
@@ -44,6 +45,7 @@ var apiObjectFuzzer = fuzz.New().NilChance(.5).NumElements(1, 1).Funcs(
 		j.ResourceVersion = c.RandUint64() >> 8
 		j.SelfLink = c.RandString()
 		j.CreationTimestamp = c.RandString()
+		j.LastModified = c.RandString()
 	},
 	func(intstr *util.IntOrString, c fuzz.Continue) {
 		// util.IntOrString will panic if its kind is set wrong.
@@ -136,25 +138,32 @@ func runTest(t *testing.T, source interface{}) {
 	}
 }
 
+// apiTypes are the registered API types, which the codecs must round trip.
+// TODO: auto-fill all fields.
+var apiTypes = []interface{}{
+	&PodList{},
+	&Pod{},
+	&PodSummary{},
+	&ServiceList{},
+	&Service{},
+	&ReplicationControllerList{},
+	&ReplicationController{},
+	&MinionList{},
+	&Minion{},
+	&Status{},
+	&ServerOpList{},
+	&ServerOp{},
+	&ContainerManifestList{},
+	&Endpoints{},
+	&EndpointsList{},
+	&Binding{},
+	&ObjectLabels{},
+	&Event{},
+	&EventList{},
+}
+
 func TestTypes(t *testing.T) {
-	// TODO: auto-fill all fields.
-	table := []interface{}{
-		&PodList{},
-		&Pod{},
-		&ServiceList{},
-		&Service{},
-		&ReplicationControllerList{},
-		&ReplicationController{},
-		&MinionList{},
-		&Minion{},
-		&Status{},
-		&ServerOpList{},
-		&ServerOp{},
-		&ContainerManifestList{},
-		&Endpoints{},
-		&Binding{},
-	}
-	for _, item := range table {
+	for _, item := range apiTypes {
 		// Try a few times, since runTest uses random values.
 		for i := 0; i < *fuzzIters; i++ {
 			runTest(t, item)
@@ -162,6 +171,133 @@ func TestTypes(t *testing.T) {
 	}
 }
 
+// runConversionTest checks that a random source converts to v1beta1 and back to itself,
+// without going through the codec.
+func runConversionTest(t *testing.T, source interface{}) {
+	name := reflect.TypeOf(source).Elem().Name()
+	apiObjectFuzzer.Fuzz(source)
+	external, err := New("v1beta1", name)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Convert(source, external); err != nil {
+		t.Errorf("%v: %v (%#v)", name, err, source)
+		return
+	}
+	internal := reflect.New(reflect.TypeOf(source).Elem()).Interface()
+	if err := Convert(external, internal); err != nil {
+		t.Errorf("%v: %v (%#v)", name, err, external)
+		return
+	}
+	if !reflect.DeepEqual(source, internal) {
+		t.Errorf("%v: diff: %v", name, objDiff(source, internal))
+	}
+}
+
+func TestConversionRoundTrip(t *testing.T) {
+	for _, item := range apiTypes {
+		for i := 0; i < *fuzzIters; i++ {
+			runConversionTest(t, item)
+		}
+	}
+}
+
+func TestConvertFieldsNamesTheField(t *testing.T) {
+	name, out := "foo", 0
+	err := convertFields(field{"JSONBase", &JSONBase{}, &JSONBase{}}, field{"Name", &name, &out})
+	fieldErr, ok := err.(*conversion.FieldError)
+	if !ok || fieldErr.Path != "Name" {
+		t.Errorf("expected the error to name the field, got %v", err)
+	}
+}
+
+// runGobTest checks that GobCodec round trips a random source, and decodes the same object
+// as Codec does from the JSON encoding of source.
+func runGobTest(t *testing.T, source interface{}) {
+	name := reflect.TypeOf(source).Elem().Name()
+	apiObjectFuzzer.Fuzz(source)
+	j, err := FindJSONBase(source)
+	if err != nil {
+		t.Fatalf("Unexpected error %v for %#v", err, source)
+	}
+	j.SetKind("")
+	j.SetAPIVersion("")
+
+	data, err := GobCodec.Encode(source)
+	if err != nil {
+		t.Errorf("%v: %v (%#v)", name, err, source)
+		return
+	}
+	obj2, err := GobCodec.Decode(data)
+	if err != nil {
+		t.Errorf("%v: %v", name, err)
+		return
+	}
+	if !reflect.DeepEqual(source, obj2) {
+		t.Errorf("1: %v: diff: %v", name, objDiff(source, obj2))
+		return
+	}
+	obj3 := reflect.New(reflect.TypeOf(source).Elem()).Interface()
+	if err := GobCodec.DecodeInto(data, obj3); err != nil {
+		t.Errorf("2: %v: %v", name, err)
+		return
+	}
+	if !reflect.DeepEqual(source, obj3) {
+		t.Errorf("3: %v: diff: %v", name, objDiff(source, obj3))
+		return
+	}
+
+	jsonData, err := Codec.Encode(source)
+	if err != nil {
+		t.Errorf("%v: %v (%#v)", name, err, source)
+		return
+	}
+	fromJSON, err := Codec.Decode(jsonData)
+	if err != nil {
+		t.Errorf("%v: %v", name, err)
+		return
+	}
+	if !reflect.DeepEqual(fromJSON, obj2) {
+		t.Errorf("4: %v: JSON and gob decode differently: %v", name, objDiff(fromJSON, obj2))
+	}
+}
+
+func TestGobTypes(t *testing.T) {
+	for _, item := range apiTypes {
+		for i := 0; i < *fuzzIters; i++ {
+			runGobTest(t, item)
+		}
+	}
+}
+
+func TestGobDecodeIntoWrongKind(t *testing.T) {
+	data, err := GobCodec.Encode(&Pod{JSONBase: JSONBase{ID: "foo"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := GobCodec.DecodeInto(data, &Service{}); err == nil {
+		t.Errorf("expected a pod not to be decoded into a service")
+	}
+	if _, err := GobCodec.Decode([]byte(`{"kind":"Pod","apiVersion":"v1beta1"}`)); err == nil {
+		t.Errorf("expected JSON to be rejected")
+	}
+}
+
+func TestCodecForMediaType(t *testing.T) {
+	table := map[string]codec{
+		"":                                    Codec,
+		"application/json":                    Codec,
+		"application/vnd.kubernetes+gob":      GobCodec,
+		"application/vnd.kubernetes+gob; v=1": GobCodec,
+		"text/plain":                          Codec,
+	}
+	for contentType, expected := range table {
+		if codec := CodecForMediaType(contentType); codec != expected {
+			t.Errorf("%q: expected %#v, got %#v", contentType, expected, codec)
+		}
+	}
+}
+
 func TestEncode_NonPtr(t *testing.T) {
 	pod := Pod{
 		Labels: map[string]string{"name": "foo"},
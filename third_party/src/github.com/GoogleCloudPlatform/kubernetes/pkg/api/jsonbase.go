@@ -57,6 +57,12 @@ type JSONBaseInterface interface {
 	SetKind(kind string)
 	ResourceVersion() uint64
 	SetResourceVersion(version uint64)
+	Namespace() string
+	SetNamespace(namespace string)
+	CreationTimestamp() string
+	SetCreationTimestamp(timestamp string)
+	LastModified() string
+	SetLastModified(timestamp string)
 }
 
 type genericJSONBase struct {
@@ -64,6 +70,9 @@ type genericJSONBase struct {
 	apiVersion      *string
 	kind            *string
 	resourceVersion *uint64
+	namespace       *string
+	creation        *string
+	lastModified    *string
 }
 
 func (g genericJSONBase) ID() string {
@@ -98,6 +107,30 @@ func (g genericJSONBase) SetResourceVersion(version uint64) {
 	*g.resourceVersion = version
 }
 
+func (g genericJSONBase) Namespace() string {
+	return *g.namespace
+}
+
+func (g genericJSONBase) SetNamespace(namespace string) {
+	*g.namespace = namespace
+}
+
+func (g genericJSONBase) CreationTimestamp() string {
+	return *g.creation
+}
+
+func (g genericJSONBase) SetCreationTimestamp(timestamp string) {
+	*g.creation = timestamp
+}
+
+func (g genericJSONBase) LastModified() string {
+	return *g.lastModified
+}
+
+func (g genericJSONBase) SetLastModified(timestamp string) {
+	*g.lastModified = timestamp
+}
+
 // fieldPtr puts the address address of fieldName, which must be a member of v,
 // into dest, which must be an address of a variable to which this field's address
 // can be assigned.
@@ -140,5 +173,14 @@ func newGenericJSONBase(v reflect.Value) (genericJSONBase, error) {
 	if err := fieldPtr(v, "ResourceVersion", &g.resourceVersion); err != nil {
 		return g, err
 	}
+	if err := fieldPtr(v, "Namespace", &g.namespace); err != nil {
+		return g, err
+	}
+	if err := fieldPtr(v, "CreationTimestamp", &g.creation); err != nil {
+		return g, err
+	}
+	if err := fieldPtr(v, "LastModified", &g.lastModified); err != nil {
+		return g, err
+	}
 	return g, nil
 }
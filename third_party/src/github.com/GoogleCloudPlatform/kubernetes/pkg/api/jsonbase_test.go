@@ -23,10 +23,13 @@ import (
 
 func TestGenericJSONBase(t *testing.T) {
 	j := JSONBase{
-		ID:              "foo",
-		APIVersion:      "a",
-		Kind:            "b",
-		ResourceVersion: 1,
+		ID:                "foo",
+		APIVersion:        "a",
+		Kind:              "b",
+		ResourceVersion:   1,
+		Namespace:         "e",
+		CreationTimestamp: "g",
+		LastModified:      "h",
 	}
 	g, err := newGenericJSONBase(reflect.ValueOf(&j).Elem())
 	if err != nil {
@@ -46,11 +49,23 @@ func TestGenericJSONBase(t *testing.T) {
 	if e, a := uint64(1), jbi.ResourceVersion(); e != a {
 		t.Errorf("expected %v, got %v", e, a)
 	}
+	if e, a := "e", jbi.Namespace(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := "g", jbi.CreationTimestamp(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := "h", jbi.LastModified(); e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
 
 	jbi.SetID("bar")
 	jbi.SetAPIVersion("c")
 	jbi.SetKind("d")
 	jbi.SetResourceVersion(2)
+	jbi.SetNamespace("f")
+	jbi.SetCreationTimestamp("i")
+	jbi.SetLastModified("j")
 
 	// Prove that jbi changes the original object.
 	if e, a := "bar", j.ID; e != a {
@@ -65,6 +80,15 @@ func TestGenericJSONBase(t *testing.T) {
 	if e, a := uint64(2), j.ResourceVersion; e != a {
 		t.Errorf("expected %v, got %v", e, a)
 	}
+	if e, a := "f", j.Namespace; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := "i", j.CreationTimestamp; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
+	if e, a := "j", j.LastModified; e != a {
+		t.Errorf("expected %v, got %v", e, a)
+	}
 }
 
 func TestResourceVersionerOfAPI(t *testing.T) {
@@ -186,8 +186,9 @@ type Container struct {
 	RestartPolicy string `yaml:"restartPolicy,omitempty" json:"restartPolicy,omitempty"`
 }
 
-// Event is the representation of an event logged to etcd backends
-type Event struct {
+// ContainerEvent is the representation of an event in the life of a container, which
+// kubelets log to etcd backends.
+type ContainerEvent struct {
 	Event     string             `json:"event,omitempty"`
 	Manifest  *ContainerManifest `json:"manifest,omitempty"`
 	Container *Container         `json:"container,omitempty"`
@@ -204,6 +205,12 @@ type JSONBase struct {
 	SelfLink          string `json:"selfLink,omitempty" yaml:"selfLink,omitempty"`
 	ResourceVersion   uint64 `json:"resourceVersion,omitempty" yaml:"resourceVersion,omitempty"`
 	APIVersion        string `json:"apiVersion,omitempty" yaml:"apiVersion,omitempty"`
+	// LastModified is when the object was last updated, in RFC 3339 format. It is set by the
+	// apiserver, as CreationTimestamp is, and is empty until the first update.
+	LastModified string `json:"lastModified,omitempty" yaml:"lastModified,omitempty"`
+	// Namespace is the namespace the object was created in. Objects without a
+	// namespace are in the default namespace.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 }
 
 // PodStatus represents a status of a pod.
@@ -261,6 +268,24 @@ type PodList struct {
 	Items    []Pod `json:"items" yaml:"items,omitempty"`
 }
 
+// PodSummary counts the pods a list of pods would hold by status and by host, served as the
+// podSummary resource so that clients can chart pods without reading them all.
+type PodSummary struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// Total is the number of pods counted.
+	Total int `json:"total" yaml:"total"`
+	// ByStatus counts the pods by current status. Pods whose status isn't known yet are
+	// counted under PodSummaryUnknown.
+	ByStatus map[string]int `json:"byStatus" yaml:"byStatus"`
+	// ByHost counts the pods by the host they are assigned to. Pods not assigned to a host
+	// yet are only counted in Unassigned.
+	ByHost     map[string]int `json:"byHost" yaml:"byHost"`
+	Unassigned int            `json:"unassigned" yaml:"unassigned"`
+}
+
+// PodSummaryUnknown is the status a PodSummary counts pods without a status under.
+const PodSummaryUnknown = "Unknown"
+
 // Pod is a collection of containers, used as either input (create, update) or as output (list, get)
 type Pod struct {
 	JSONBase     `json:",inline" yaml:",inline"`
@@ -286,6 +311,10 @@ type ReplicationControllerList struct {
 type ReplicationController struct {
 	JSONBase     `json:",inline" yaml:",inline"`
 	DesiredState ReplicationControllerState `json:"desiredState,omitempty" yaml:"desiredState,omitempty"`
+	// CurrentState is recorded by the replication manager: its Replicas are the pods that
+	// match the selector and haven't terminated. It is written through the status
+	// subresource, and only its Replicas are set.
+	CurrentState ReplicationControllerState `json:"currentState,omitempty" yaml:"currentState,omitempty"`
 	Labels       map[string]string          `json:"labels,omitempty" yaml:"labels,omitempty"`
 }
 
@@ -307,6 +336,9 @@ type ServiceList struct {
 type Service struct {
 	JSONBase `json:",inline" yaml:",inline"`
 	Port     int `json:"port,omitempty" yaml:"port,omitempty"`
+	// Protocol is the protocol of Port, TCP or UDP. Defaults to TCP. No two services may
+	// have the same port and protocol.
+	Protocol string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
 
 	// This service's labels.
 	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
@@ -327,13 +359,50 @@ type Endpoints struct {
 	Endpoints []string `json:"endpoints,omitempty" yaml:"endpoints,omitempty"`
 }
 
+// EndpointsList is a list of endpoints.
+type EndpointsList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Endpoints `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
 // Minion is a worker node in Kubernetenes.
 // The name of the minion according to etcd is in JSONBase.ID.
 type Minion struct {
 	JSONBase `json:",inline" yaml:",inline"`
 	// Queried from cloud provider, if available.
 	HostIP string `json:"hostIP,omitempty" yaml:"hostIP,omitempty"`
-}
+	// Status is whether the kubelet of the minion answered its last health check, if the
+	// apiserver checks the health of minions.
+	Status MinionStatus `json:"status,omitempty" yaml:"status,omitempty"`
+	// Addresses are the IP addresses of the minion, if known.
+	Addresses []string `json:"addresses,omitempty" yaml:"addresses,omitempty"`
+	// Usage is what the pods running on the minion request, if the apiserver knows the pods.
+	Usage *MinionUsage `json:"usage,omitempty" yaml:"usage,omitempty"`
+}
+
+// MinionUsage sums what the containers of the pods running on a minion request.
+type MinionUsage struct {
+	// Pods is the number of pods on the minion.
+	Pods int `json:"pods" yaml:"pods"`
+	// Memory and CPU are the memory and CPU the containers request in total. Containers that
+	// don't request memory or CPU count as requesting none.
+	Memory int `json:"memory" yaml:"memory"`
+	CPU    int `json:"cpu" yaml:"cpu"`
+	// UnboundedPods is the number of pods with a container that doesn't request memory or
+	// doesn't request CPU, and so may use any amount of it.
+	UnboundedPods int `json:"unboundedPods" yaml:"unboundedPods"`
+}
+
+// MinionStatus represents whether the kubelet of a minion can be reached.
+type MinionStatus string
+
+// These are the valid statuses of minions.
+const (
+	// MinionReachable means that the kubelet of the minion answered its last health check.
+	MinionReachable MinionStatus = "Reachable"
+	// MinionUnreachable means that the kubelet of the minion failed its last health check.
+	MinionUnreachable MinionStatus = "Unreachable"
+)
 
 // MinionList is a list of minions.
 type MinionList struct {
@@ -341,6 +410,35 @@ type MinionList struct {
 	Items    []Minion `json:"minions,omitempty" yaml:"minions,omitempty"`
 }
 
+// ObjectReference identifies an API object, e.g. the object an Event is about.
+type ObjectReference struct {
+	Kind      string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	ID        string `json:"id,omitempty" yaml:"id,omitempty"`
+}
+
+// Event is a report of something that happened to an object, e.g. a pod that could not be
+// scheduled. Events are kept for a limited time.
+type Event struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	// InvolvedObject is the object the event is about.
+	InvolvedObject ObjectReference `json:"involvedObject,omitempty" yaml:"involvedObject,omitempty"`
+	// Reason is a short, machine readable description of what happened, e.g. "failedScheduling".
+	Reason string `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// Message is a human readable description of what happened.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Source is the component that reported the event, e.g. "scheduler".
+	Source string `json:"source,omitempty" yaml:"source,omitempty"`
+	// Timestamp is the time the event happened, in RFC 3339 format.
+	Timestamp string `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+}
+
+// EventList is a list of events.
+type EventList struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Items    []Event `json:"items,omitempty" yaml:"items,omitempty"`
+}
+
 // Binding is written by a scheduler to cause a pod to be bound to a host.
 type Binding struct {
 	JSONBase `json:",inline" yaml:",inline"`
@@ -348,6 +446,14 @@ type Binding struct {
 	Host     string `json:"host" yaml:"host"`
 }
 
+// ObjectLabels are the labels of a pod, service or replication controller, served as its
+// labels subresource. ResourceVersion is the resource version of the object: labels sent
+// with a resource version are only stored if the object hasn't changed since.
+type ObjectLabels struct {
+	JSONBase `json:",inline" yaml:",inline"`
+	Labels   map[string]string `json:"labels" yaml:"labels"`
+}
+
 // Status is a return value for calls that don't return other objects.
 // TODO: this could go in apiserver, but I'm including it here so clients needn't
 // import both.
@@ -384,6 +490,15 @@ type StatusDetails struct {
 	// The kind attribute of the resource associated with the status ReasonType.
 	// On some operations may differ from the requested resource Kind.
 	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// The Causes array includes more details associated with the ReasonType
+	// failure. Not all ReasonTypes may provide detailed causes.
+	Causes []StatusCause `json:"causes,omitempty" yaml:"causes,omitempty"`
+	// ElapsedMilliseconds is, for an operation in progress, how long ago it was created.
+	ElapsedMilliseconds int64 `json:"elapsedMilliseconds,omitempty" yaml:"elapsedMilliseconds,omitempty"`
+	// RetryAfterSeconds is, for an operation in progress, how long the server suggests
+	// waiting before polling it again, and for a request refused because the server is
+	// unavailable, before retrying it, as the Retry-After header of the response does.
+	RetryAfterSeconds int `json:"retryAfterSeconds,omitempty" yaml:"retryAfterSeconds,omitempty"`
 }
 
 // Values of Status.Status
@@ -440,13 +555,126 @@ const (
 	// conflict.
 	// Status code 409
 	ReasonTypeConflict ReasonType = "conflict"
+
+	// ReasonTypeInvalid means the requested create or update operation cannot be
+	// completed due to invalid data provided as part of the request. The client may
+	// need to alter the request.
+	// Details (optional):
+	//   "kind"   string - the kind attribute of the invalid resource
+	//   "id"     string - the identifier of the invalid resource
+	//   "causes"        - one or more StatusCause entries indicating the data in the
+	//                     provided resource that was invalid
+	// Status code 422
+	ReasonTypeInvalid ReasonType = "invalid"
+
+	// ReasonTypeForbidden means the server refused the request, e.g. because it
+	// violates a cluster policy.
+	// Details (optional):
+	//   "kind" string - the kind attribute of the resource
+	//   "id"   string - the identifier of the resource
+	// Status code 403
+	ReasonTypeForbidden ReasonType = "forbidden"
+
+	// ReasonTypeMethodNotAllowed means the resource exists, but does not support the
+	// operation requested of it, e.g. because it is read-only.
+	// Details (optional):
+	//   "kind" string - the kind attribute of the resource
+	//   "id"   string - the operation that is not supported
+	// Status code 405
+	ReasonTypeMethodNotAllowed ReasonType = "methodNotAllowed"
+
+	// ReasonTypeBadRequest means the request itself was malformed, e.g. because it
+	// contradicts itself.
+	// Details (optional):
+	//   "kind" string - the kind attribute of the resource
+	//   "id"   string - the identifier of the resource
+	// Status code 400
+	ReasonTypeBadRequest ReasonType = "bad_request"
+
+	// ReasonTypeUnsupportedMediaType means the body of the request is encoded in a media
+	// type the server doesn't decode.
+	// Status code 415
+	ReasonTypeUnsupportedMediaType ReasonType = "unsupported_media_type"
+
+	// ReasonTypeTimeout means the client took too long to send the request, e.g. its body.
+	// Status code 408
+	ReasonTypeTimeout ReasonType = "timeout"
+
+	// ReasonTypeServiceUnavailable means a dependency of the server, such as its storage,
+	// is unavailable, and the request may be retried later.
+	// Status code 503
+	ReasonTypeServiceUnavailable ReasonType = "service_unavailable"
+
+	// ReasonTypeInternalError means the server failed unexpectedly while handling the
+	// request. Details of the failure are only logged by the server.
+	// Status code 500
+	ReasonTypeInternalError ReasonType = "InternalError"
 )
 
-// ServerOp is an operation delivered to API clients.
+// StatusCause provides more information about an api.Status failure, including
+// cases when multiple errors are encountered.
+type StatusCause struct {
+	// A machine-readable description of the cause of the error. If this value is
+	// empty there is no information available.
+	Type CauseType `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// A human-readable description of the cause of the error. This field may be
+	// presented as-is to a reader.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// The field of the resource that has caused this error, as named by its JSON
+	// serialization. May include dot and postfix notation for nested attributes.
+	// Arrays are zero-indexed. Fields may appear more than once in an array of
+	// causes due to fields having multiple errors.
+	//
+	// Examples:
+	//   "id"
+	//   "desiredState.manifest.containers[0].ports[1].containerPort"
+	Field string `json:"field,omitempty" yaml:"field,omitempty"`
+}
+
+// CauseType is a machine readable value providing more detail about what
+// occurred in a status response. An operation may have multiple causes for a
+// status (whether failure, success, or working).
+type CauseType string
+
+const (
+	// CauseTypeFieldValueNotFound is used to report failure to find a requested value
+	// (e.g. looking up an ID).
+	CauseTypeFieldValueNotFound CauseType = "fieldValueNotFound"
+	// CauseTypeFieldValueInvalid is used to report required values that are not
+	// provided (e.g. empty strings, null values, or empty arrays), or malformed values
+	// (e.g. failed regex match).
+	CauseTypeFieldValueInvalid CauseType = "fieldValueInvalid"
+	// CauseTypeFieldValueDuplicate is used to report collisions of values that must be
+	// unique (e.g. unique IDs).
+	CauseTypeFieldValueDuplicate CauseType = "fieldValueDuplicate"
+	// CauseTypeFieldValueNotSupported is used to report valid (as per formatting rules)
+	// values that can not be handled (e.g. an enumerated string).
+	CauseTypeFieldValueNotSupported CauseType = "fieldValueNotSupported"
+	// CauseTypeFieldValueConflict is used to report values that conflict with those of
+	// another object, named in the message (e.g. overlapping selectors).
+	CauseTypeFieldValueConflict CauseType = "fieldValueConflict"
+)
+
+// ServerOp is an operation delivered to API clients. Its namespace is that of the request
+// which created it.
 type ServerOp struct {
 	JSONBase `yaml:",inline" json:",inline"`
+	// Resource is the resource the operation acts on, e.g. "pods".
+	Resource string `yaml:"resource,omitempty" json:"resource,omitempty"`
+	// Name is the ID of the object the operation acts on, if it is known.
+	Name string `yaml:"name,omitempty" json:"name,omitempty"`
+	// Owner is the authenticated user whose request created the operation, if any.
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+	// Status is ServerOpPending until the operation completes, then ServerOpComplete.
+	Status string `yaml:"status,omitempty" json:"status,omitempty"`
 }
 
+// Values of ServerOp.Status
+const (
+	ServerOpPending  = "pending"
+	ServerOpComplete = "complete"
+)
+
 // ServerOpList is a list of operations, as delivered to API clients.
 type ServerOpList struct {
 	JSONBase `yaml:",inline" json:",inline"`
@@ -455,9 +683,13 @@ type ServerOpList struct {
 
 // WatchEvent objects are streamed from the api server in response to a watch request.
 type WatchEvent struct {
-	// The type of the watch event; added, modified, or deleted.
+	// The type of the watch event; added, modified, deleted, or error.
 	Type watch.EventType
 
+	// ResourceVersion is the resource version of Object, from which a watch may be resumed.
+	// It is left out of events whose object has none, such as errors.
+	ResourceVersion uint64 `json:",omitempty" yaml:",omitempty"`
+
 	// For added or modified objects, this is the new object; for deleted objects,
 	// it's the state of the object immediately prior to its deletion.
 	Object APIObject
@@ -18,6 +18,7 @@ package api
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
@@ -64,126 +65,242 @@ func makeNotFoundError(field string, value interface{}) ValidationError {
 	return ValidationError{ErrTypeNotFound, field, value}
 }
 
-// A helper for accumulating errors.  This could be moved to util if anyone else needs it.
-type errorList []error
+// ValidationErrorList is a list of errors found while validating an object,
+// typically ValidationErrors.
+type ValidationErrorList []error
 
-func (list *errorList) Append(errs ...error) {
+// Append adds errs to the list.
+func (list *ValidationErrorList) Append(errs ...error) {
 	*list = append(*list, errs...)
 }
 
-func validateVolumes(volumes []Volume) (util.StringSet, errorList) {
-	allErrs := errorList{}
+// Prefix returns the list with prefix prepended to the field of each
+// ValidationError, so that fields name their path from the object being
+// validated. Other errors are left as they are.
+func (list ValidationErrorList) Prefix(prefix string) ValidationErrorList {
+	for i := range list {
+		if err, ok := list[i].(ValidationError); ok {
+			if strings.HasPrefix(err.ErrorField, "[") {
+				err.ErrorField = prefix + err.ErrorField
+			} else if len(err.ErrorField) != 0 {
+				err.ErrorField = prefix + "." + err.ErrorField
+			} else {
+				err.ErrorField = prefix
+			}
+			list[i] = err
+		}
+	}
+	return list
+}
+
+// PrefixIndex returns the list with the index of an array element prepended to
+// the field of each ValidationError.
+func (list ValidationErrorList) PrefixIndex(index int) ValidationErrorList {
+	return list.Prefix(fmt.Sprintf("[%d]", index))
+}
+
+// ValidateFunc validates an API object, given a pointer to it. It may set default
+// values on the object.
+type ValidateFunc func(obj interface{}) ValidationErrorList
+
+var validators = map[reflect.Type]ValidateFunc{}
+
+func init() {
+	AddValidator(&Pod{}, func(obj interface{}) ValidationErrorList { return ValidatePod(obj.(*Pod)) })
+	AddValidator(&Service{}, func(obj interface{}) ValidationErrorList { return ValidateService(obj.(*Service)) })
+	AddValidator(&ReplicationController{}, func(obj interface{}) ValidationErrorList {
+		return ValidateReplicationController(obj.(*ReplicationController))
+	})
+	AddValidator(&ObjectLabels{}, func(obj interface{}) ValidationErrorList { return ValidateObjectLabels(obj.(*ObjectLabels)) })
+}
+
+// AddValidator registers fn as the validation of objects of the type that obj, a
+// pointer to an API object, points to. Like AddKnownTypes, it should only be
+// called while the program is initialized.
+func AddValidator(obj interface{}, fn ValidateFunc) {
+	validators[reflect.TypeOf(obj)] = fn
+}
+
+// Validate validates obj, a pointer to an API object, with the validation
+// registered for its type. Objects of types without validation are valid.
+func Validate(obj interface{}) ValidationErrorList {
+	fn, ok := validators[reflect.TypeOf(obj)]
+	if !ok {
+		return nil
+	}
+	return fn(obj)
+}
+
+// reservedIDs are the path segments the apiserver serves itself below a resource or in
+// place of one. An object called after one of them could never be fetched or deleted
+// through its URL.
+var reservedIDs = util.NewStringSet("ns", "operation", "operations", "proxy", "redirect", "watch")
+
+// subdomainIDKinds are the kinds whose IDs may be DNS subdomains rather than DNS labels,
+// because they name hosts.
+var subdomainIDKinds = util.NewStringSet("Minion")
+
+// InvalidIDError reports an object ID that breaks one of the rules of ValidateObjectID.
+type InvalidIDError struct {
+	ID string
+	// Rule is the rule ID breaks, e.g. "must be no more than 63 characters".
+	Rule string
+}
+
+func (e InvalidIDError) Error() string {
+	return fmt.Sprintf("id: %v '%v': %s", ErrTypeInvalid, e.ID, e.Rule)
+}
+
+// ValidateObjectID checks the ID of obj, a pointer to an API object about to be created,
+// against the rules every object ID must follow so that the object can be named in a URL:
+// the ID is a DNS label, or a DNS subdomain for minions, and is not a path segment the
+// apiserver reserves. Empty IDs are left to the validation of each kind, since storages
+// may assign them. The same rules are checked by the apiserver and by clients.
+func ValidateObjectID(obj interface{}) ValidationErrorList {
+	jsonBase, err := FindJSONBaseRO(obj)
+	if err != nil || len(jsonBase.ID) == 0 {
+		return nil
+	}
+	id := jsonBase.ID
+	if reservedIDs.Has(id) {
+		return ValidationErrorList{InvalidIDError{id, "is reserved for a path segment of the API"}}
+	}
+	if subdomainIDKinds.Has(reflect.Indirect(reflect.ValueOf(obj)).Type().Name()) {
+		switch {
+		case len(id) > util.DNSSubdomainMaxLength:
+			return ValidationErrorList{InvalidIDError{id, fmt.Sprintf("must be no more than %d characters", util.DNSSubdomainMaxLength)}}
+		case !util.IsDNSSubdomain(id):
+			return ValidationErrorList{InvalidIDError{id, "must be DNS labels separated by '.', each of lowercase letters, digits and '-', starting and ending with a letter or digit"}}
+		}
+		return nil
+	}
+	switch {
+	case len(id) > util.DNSLabelMaxLength:
+		return ValidationErrorList{InvalidIDError{id, fmt.Sprintf("must be no more than %d characters", util.DNSLabelMaxLength)}}
+	case !util.IsDNSLabel(id):
+		return ValidationErrorList{InvalidIDError{id, "must consist of lowercase letters, digits and '-', starting and ending with a letter or digit"}}
+	}
+	return nil
+}
+
+func validateVolumes(volumes []Volume) (util.StringSet, ValidationErrorList) {
+	allErrs := ValidationErrorList{}
 
 	allNames := util.StringSet{}
 	for i := range volumes {
 		vol := &volumes[i] // so we can set default values
-		errs := errorList{}
+		errs := ValidationErrorList{}
 		// TODO(thockin) enforce that a source is set once we deprecate the implied form.
 		if vol.Source != nil {
-			errs = validateSource(vol.Source)
+			errs = validateSource(vol.Source).Prefix("source")
 		}
 		if !util.IsDNSLabel(vol.Name) {
-			errs.Append(makeInvalidError("Volume.Name", vol.Name))
+			errs.Append(makeInvalidError("name", vol.Name))
 		} else if allNames.Has(vol.Name) {
-			errs.Append(makeDuplicateError("Volume.Name", vol.Name))
+			errs.Append(makeDuplicateError("name", vol.Name))
 		}
 		if len(errs) == 0 {
 			allNames.Insert(vol.Name)
 		} else {
-			allErrs.Append(errs...)
+			allErrs.Append(errs.PrefixIndex(i)...)
 		}
 	}
 	return allNames, allErrs
 }
 
-func validateSource(source *VolumeSource) errorList {
+func validateSource(source *VolumeSource) ValidationErrorList {
 	numVolumes := 0
-	allErrs := errorList{}
+	allErrs := ValidationErrorList{}
 	if source.HostDirectory != nil {
 		numVolumes++
-		allErrs.Append(validateHostDir(source.HostDirectory)...)
+		allErrs.Append(validateHostDir(source.HostDirectory).Prefix("hostDir")...)
 	}
 	if source.EmptyDirectory != nil {
 		numVolumes++
 		//EmptyDirs have nothing to validate
 	}
 	if numVolumes != 1 {
-		allErrs.Append(makeInvalidError("Volume.Source", source))
+		allErrs.Append(makeInvalidError("", source))
 	}
 	return allErrs
 }
 
-func validateHostDir(hostDir *HostDirectory) errorList {
-	allErrs := errorList{}
+func validateHostDir(hostDir *HostDirectory) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 	if hostDir.Path == "" {
-		allErrs.Append(makeNotFoundError("HostDir.Path", hostDir.Path))
+		allErrs.Append(makeNotFoundError("path", hostDir.Path))
 	}
 	return allErrs
 }
 
 var supportedPortProtocols = util.NewStringSet("TCP", "UDP")
 
-func validatePorts(ports []Port) errorList {
-	allErrs := errorList{}
+func validatePorts(ports []Port) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 
 	allNames := util.StringSet{}
 	for i := range ports {
+		pErrs := ValidationErrorList{}
 		port := &ports[i] // so we can set default values
 		if len(port.Name) > 0 {
 			if len(port.Name) > 63 || !util.IsDNSLabel(port.Name) {
-				allErrs.Append(makeInvalidError("Port.Name", port.Name))
+				pErrs.Append(makeInvalidError("name", port.Name))
 			} else if allNames.Has(port.Name) {
-				allErrs.Append(makeDuplicateError("Port.name", port.Name))
+				pErrs.Append(makeDuplicateError("name", port.Name))
 			} else {
 				allNames.Insert(port.Name)
 			}
 		}
 		if !util.IsValidPortNum(port.ContainerPort) {
-			allErrs.Append(makeInvalidError("Port.ContainerPort", port.ContainerPort))
+			pErrs.Append(makeInvalidError("containerPort", port.ContainerPort))
 		}
 		if port.HostPort == 0 {
 			port.HostPort = port.ContainerPort
 		} else if !util.IsValidPortNum(port.HostPort) {
-			allErrs.Append(makeInvalidError("Port.HostPort", port.HostPort))
+			pErrs.Append(makeInvalidError("hostPort", port.HostPort))
 		}
 		if len(port.Protocol) == 0 {
 			port.Protocol = "TCP"
 		} else if !supportedPortProtocols.Has(strings.ToUpper(port.Protocol)) {
-			allErrs.Append(makeNotSupportedError("Port.Protocol", port.Protocol))
+			pErrs.Append(makeNotSupportedError("protocol", port.Protocol))
 		}
+		allErrs.Append(pErrs.PrefixIndex(i)...)
 	}
 	return allErrs
 }
 
-func validateEnv(vars []EnvVar) errorList {
-	allErrs := errorList{}
+func validateEnv(vars []EnvVar) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 
 	for i := range vars {
+		vErrs := ValidationErrorList{}
 		ev := &vars[i] // so we can set default values
 		if len(ev.Name) == 0 {
-			allErrs.Append(makeInvalidError("EnvVar.Name", ev.Name))
+			vErrs.Append(makeInvalidError("name", ev.Name))
 		}
 		if !util.IsCIdentifier(ev.Name) {
-			allErrs.Append(makeInvalidError("EnvVar.Name", ev.Name))
+			vErrs.Append(makeInvalidError("name", ev.Name))
 		}
+		allErrs.Append(vErrs.PrefixIndex(i)...)
 	}
 	return allErrs
 }
 
-func validateVolumeMounts(mounts []VolumeMount, volumes util.StringSet) errorList {
-	allErrs := errorList{}
+func validateVolumeMounts(mounts []VolumeMount, volumes util.StringSet) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 
 	for i := range mounts {
+		mErrs := ValidationErrorList{}
 		mnt := &mounts[i] // so we can set default values
 		if len(mnt.Name) == 0 {
-			allErrs.Append(makeInvalidError("VolumeMount.Name", mnt.Name))
+			mErrs.Append(makeInvalidError("name", mnt.Name))
 		} else if !volumes.Has(mnt.Name) {
-			allErrs.Append(makeNotFoundError("VolumeMount.Name", mnt.Name))
+			mErrs.Append(makeNotFoundError("name", mnt.Name))
 		}
 		if len(mnt.MountPath) == 0 {
 			// Backwards compat.
 			if len(mnt.Path) == 0 {
-				allErrs.Append(makeInvalidError("VolumeMount.MountPath", mnt.MountPath))
+				mErrs.Append(makeInvalidError("mountPath", mnt.MountPath))
 			} else {
 				glog.Warning("DEPRECATED: VolumeMount.Path has been replaced by VolumeMount.MountPath")
 				mnt.MountPath = mnt.Path
@@ -193,54 +310,59 @@ func validateVolumeMounts(mounts []VolumeMount, volumes util.StringSet) errorLis
 		if len(mnt.MountType) != 0 {
 			glog.Warning("DEPRECATED: VolumeMount.MountType will be removed. The Volume struct will handle types")
 		}
+		allErrs.Append(mErrs.PrefixIndex(i)...)
 	}
 	return allErrs
 }
 
 // AccumulateUniquePorts runs an extraction function on each Port of each Container,
 // accumulating the results and returning an error if any ports conflict.
-func AccumulateUniquePorts(containers []Container, accumulator map[int]bool, extract func(*Port) int) errorList {
-	allErrs := errorList{}
+func AccumulateUniquePorts(containers []Container, accumulator map[int]bool, extract func(*Port) int) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 
 	for ci := range containers {
+		cErrs := ValidationErrorList{}
 		ctr := &containers[ci]
 		for pi := range ctr.Ports {
 			port := extract(&ctr.Ports[pi])
 			if accumulator[port] {
-				allErrs.Append(makeDuplicateError("Port", port))
+				cErrs.Append(ValidationErrorList{makeDuplicateError("port", port)}.PrefixIndex(pi).Prefix("ports")...)
 			} else {
 				accumulator[port] = true
 			}
 		}
+		allErrs.Append(cErrs.PrefixIndex(ci)...)
 	}
 	return allErrs
 }
 
 // Checks for colliding Port.HostPort values across a slice of containers.
-func checkHostPortConflicts(containers []Container) errorList {
+func checkHostPortConflicts(containers []Container) ValidationErrorList {
 	allPorts := map[int]bool{}
 	return AccumulateUniquePorts(containers, allPorts, func(p *Port) int { return p.HostPort })
 }
 
-func validateContainers(containers []Container, volumes util.StringSet) errorList {
-	allErrs := errorList{}
+func validateContainers(containers []Container, volumes util.StringSet) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 
 	allNames := util.StringSet{}
 	for i := range containers {
+		cErrs := ValidationErrorList{}
 		ctr := &containers[i] // so we can set default values
 		if !util.IsDNSLabel(ctr.Name) {
-			allErrs.Append(makeInvalidError("Container.Name", ctr.Name))
+			cErrs.Append(makeInvalidError("name", ctr.Name))
 		} else if allNames.Has(ctr.Name) {
-			allErrs.Append(makeDuplicateError("Container.Name", ctr.Name))
+			cErrs.Append(makeDuplicateError("name", ctr.Name))
 		} else {
 			allNames.Insert(ctr.Name)
 		}
 		if len(ctr.Image) == 0 {
-			allErrs.Append(makeInvalidError("Container.Image", ctr.Name))
+			cErrs.Append(makeInvalidError("image", ctr.Name))
 		}
-		allErrs.Append(validatePorts(ctr.Ports)...)
-		allErrs.Append(validateEnv(ctr.Env)...)
-		allErrs.Append(validateVolumeMounts(ctr.VolumeMounts, volumes)...)
+		cErrs.Append(validatePorts(ctr.Ports).Prefix("ports")...)
+		cErrs.Append(validateEnv(ctr.Env).Prefix("env")...)
+		cErrs.Append(validateVolumeMounts(ctr.VolumeMounts, volumes).Prefix("volumeMounts")...)
+		allErrs.Append(cErrs.PrefixIndex(i)...)
 	}
 	// Check for colliding ports across all containers.
 	// TODO(thockin): This really is dependent on the network config of the host (IP per pod?)
@@ -252,77 +374,122 @@ func validateContainers(containers []Container, volumes util.StringSet) errorLis
 	return allErrs
 }
 
+// validateLabels tests that the keys of a set of labels, or of a label selector,
+// are valid label keys.
+func validateLabels(labels map[string]string) ValidationErrorList {
+	allErrs := ValidationErrorList{}
+	for k := range labels {
+		if !util.IsLabelKey(k) {
+			allErrs.Append(makeInvalidError(k, k))
+		}
+	}
+	return allErrs
+}
+
+// ValidateObjectLabels tests that the keys and values of a set of object labels follow the
+// syntax of labels. Unlike the labels of objects created before values were checked, labels
+// changed on their own must have valid values too.
+func ValidateObjectLabels(objectLabels *ObjectLabels) ValidationErrorList {
+	allErrs := validateLabels(objectLabels.Labels)
+	for k, v := range objectLabels.Labels {
+		if !util.IsLabelValue(v) {
+			allErrs.Append(makeInvalidError(k, v))
+		}
+	}
+	return allErrs.Prefix("labels")
+}
+
 var supportedManifestVersions = util.NewStringSet("v1beta1", "v1beta2")
 
 // ValidateManifest tests that the specified ContainerManifest has valid data.
 // This includes checking formatting and uniqueness.  It also canonicalizes the
 // structure by setting default values and implementing any backwards-compatibility
 // tricks.
-func ValidateManifest(manifest *ContainerManifest) []error {
-	allErrs := errorList{}
+func ValidateManifest(manifest *ContainerManifest) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 
 	if len(manifest.Version) == 0 {
-		allErrs.Append(makeInvalidError("ContainerManifest.Version", manifest.Version))
+		allErrs.Append(makeInvalidError("version", manifest.Version))
 	} else if !supportedManifestVersions.Has(strings.ToLower(manifest.Version)) {
-		allErrs.Append(makeNotSupportedError("ContainerManifest.Version", manifest.Version))
+		allErrs.Append(makeNotSupportedError("version", manifest.Version))
 	}
 	allVolumes, errs := validateVolumes(manifest.Volumes)
 	if len(errs) != 0 {
-		allErrs.Append(errs...)
+		allErrs.Append(errs.Prefix("volumes")...)
 	}
-	allErrs.Append(validateContainers(manifest.Containers, allVolumes)...)
-	return []error(allErrs)
+	allErrs.Append(validateContainers(manifest.Containers, allVolumes).Prefix("containers")...)
+	return allErrs
 }
 
-func ValidatePodState(podState *PodState) []error {
-	allErrs := errorList(ValidateManifest(&podState.Manifest))
+func ValidatePodState(podState *PodState) ValidationErrorList {
+	allErrs := ValidateManifest(&podState.Manifest).Prefix("manifest")
 	if podState.RestartPolicy.Type == "" {
 		podState.RestartPolicy.Type = RestartAlways
 	} else if podState.RestartPolicy.Type != RestartAlways &&
 		podState.RestartPolicy.Type != RestartOnFailure &&
 		podState.RestartPolicy.Type != RestartNever {
-		allErrs.Append(makeNotSupportedError("PodState.RestartPolicy.Type", podState.RestartPolicy.Type))
+		allErrs.Append(makeNotSupportedError("restartpolicy.type", podState.RestartPolicy.Type))
 	}
 
-	return []error(allErrs)
+	return allErrs
 }
 
 // Pod tests if required fields in the pod are set.
-func ValidatePod(pod *Pod) []error {
-	allErrs := errorList{}
+func ValidatePod(pod *Pod) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 	if pod.ID == "" {
-		allErrs.Append(makeInvalidError("Pod.ID", pod.ID))
+		allErrs.Append(makeInvalidError("id", pod.ID))
 	}
-	allErrs.Append(ValidatePodState(&pod.DesiredState)...)
-	return []error(allErrs)
+	allErrs.Append(validateLabels(pod.Labels).Prefix("labels")...)
+	allErrs.Append(ValidatePodState(&pod.DesiredState).Prefix("desiredState")...)
+	return allErrs
 }
 
 // ValidateService tests if required fields in the service are set.
-func ValidateService(service *Service) []error {
-	allErrs := errorList{}
+func ValidateService(service *Service) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 	if service.ID == "" {
-		allErrs.Append(makeInvalidError("Service.ID", service.ID))
+		allErrs.Append(makeInvalidError("id", service.ID))
 	} else if !util.IsDNS952Label(service.ID) {
-		allErrs.Append(makeInvalidError("Service.ID", service.ID))
+		allErrs.Append(makeInvalidError("id", service.ID))
+	}
+	if service.Port != 0 && !util.IsValidPortNum(service.Port) {
+		allErrs.Append(makeInvalidError("port", service.Port))
 	}
+	if len(service.Protocol) == 0 {
+		service.Protocol = "TCP"
+	} else if !supportedPortProtocols.Has(strings.ToUpper(service.Protocol)) {
+		allErrs.Append(makeNotSupportedError("protocol", service.Protocol))
+	}
+	allErrs.Append(validateLabels(service.Labels).Prefix("labels")...)
 	if labels.Set(service.Selector).AsSelector().Empty() {
-		allErrs.Append(makeInvalidError("Service.Selector", service.Selector))
+		allErrs.Append(makeInvalidError("selector", service.Selector))
 	}
-	return []error(allErrs)
+	allErrs.Append(validateLabels(service.Selector).Prefix("selector")...)
+	return allErrs
 }
 
 // ValidateReplicationController tests if required fields in the replication controller are set.
-func ValidateReplicationController(controller *ReplicationController) []error {
-	errors := []error{}
+func ValidateReplicationController(controller *ReplicationController) ValidationErrorList {
+	allErrs := ValidationErrorList{}
 	if controller.ID == "" {
-		errors = append(errors, makeInvalidError("ReplicationController.ID", controller.ID))
+		allErrs.Append(makeInvalidError("id", controller.ID))
 	}
-	if labels.Set(controller.DesiredState.ReplicaSelector).AsSelector().Empty() {
-		errors = append(errors, makeInvalidError("ReplicationController.ReplicaSelector", controller.DesiredState.ReplicaSelector))
+	allErrs.Append(validateLabels(controller.Labels).Prefix("labels")...)
+	allErrs.Append(validateReplicationControllerState(&controller.DesiredState).Prefix("desiredState")...)
+	return allErrs
+}
+
+func validateReplicationControllerState(state *ReplicationControllerState) ValidationErrorList {
+	allErrs := ValidationErrorList{}
+	if labels.Set(state.ReplicaSelector).AsSelector().Empty() {
+		allErrs.Append(makeInvalidError("replicaSelector", state.ReplicaSelector))
 	}
-	if controller.DesiredState.Replicas < 0 {
-		errors = append(errors, makeInvalidError("ReplicationController.Replicas", controller.DesiredState.Replicas ))
+	allErrs.Append(validateLabels(state.ReplicaSelector).Prefix("replicaSelector")...)
+	if state.Replicas < 0 {
+		allErrs.Append(makeInvalidError("replicas", state.Replicas))
 	}
-	errors = append(errors, ValidateManifest(&controller.DesiredState.PodTemplate.DesiredState.Manifest)...)
-	return errors
+	allErrs.Append(validateLabels(state.PodTemplate.Labels).Prefix("podTemplate.labels")...)
+	allErrs.Append(ValidateManifest(&state.PodTemplate.DesiredState.Manifest).Prefix("podTemplate.desiredState.manifest")...)
+	return allErrs
 }
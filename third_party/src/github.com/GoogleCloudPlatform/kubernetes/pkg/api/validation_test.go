@@ -363,6 +363,20 @@ func TestValidateService(t *testing.T) {
 	if len(errs) != 2 {
 		t.Errorf("Unexpected error list: %#v", errs)
 	}
+
+	service := &Service{JSONBase: JSONBase{ID: "foo"}, Port: 8080, Selector: map[string]string{"foo": "bar"}}
+	if errs := ValidateService(service); len(errs) != 0 || service.Protocol != "TCP" {
+		t.Errorf("expected the protocol to default to TCP, got %q: %#v", service.Protocol, errs)
+	}
+	for _, invalid := range []Service{
+		{JSONBase: JSONBase{ID: "foo"}, Port: 65536, Selector: map[string]string{"foo": "bar"}},
+		{JSONBase: JSONBase{ID: "foo"}, Port: -1, Selector: map[string]string{"foo": "bar"}},
+		{JSONBase: JSONBase{ID: "foo"}, Port: 53, Protocol: "SCTP", Selector: map[string]string{"foo": "bar"}},
+	} {
+		if errs := ValidateService(&invalid); len(errs) != 1 {
+			t.Errorf("%d/%s: expected an error, got %#v", invalid.Port, invalid.Protocol, errs)
+		}
+	}
 }
 
 func TestValidateReplicationController(t *testing.T) {
@@ -431,3 +445,87 @@ func TestValidateReplicationController(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateFieldPaths(t *testing.T) {
+	pod := Pod{
+		JSONBase: JSONBase{ID: "foo"},
+		Labels:   map[string]string{"bad key": "bar"},
+		DesiredState: PodState{
+			Manifest: ContainerManifest{
+				Version: "v1beta1",
+				Containers: []Container{
+					{Name: "ok", Image: "image"},
+					{Name: "bad", Image: "image", Ports: []Port{{ContainerPort: 80}, {ContainerPort: -1}}},
+				},
+			},
+		},
+	}
+	errs := Validate(&pod)
+	fields := []string{}
+	for _, err := range errs {
+		fields = append(fields, err.(ValidationError).ErrorField)
+	}
+	expected := []string{"labels.bad key", "desiredState.manifest.containers[1].ports[1].containerPort"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected fields %v, got %v (%v)", expected, fields, errs)
+	}
+}
+
+func TestValidateObjectLabels(t *testing.T) {
+	if errs := Validate(&ObjectLabels{Labels: map[string]string{"name": "web", "tier": "", "example.com/v": "1.2"}}); len(errs) != 0 {
+		t.Errorf("unexpected errors: %v", errs)
+	}
+	errs := Validate(&ObjectLabels{Labels: map[string]string{"bad key": "web", "tier": "bad value"}})
+	fields := util.NewStringSet()
+	for _, err := range errs {
+		fields.Insert(err.(ValidationError).ErrorField)
+	}
+	if expected := []string{"labels.bad key", "labels.tier"}; !reflect.DeepEqual(fields.List(), expected) {
+		t.Errorf("expected fields %v, got %v (%v)", expected, fields.List(), errs)
+	}
+}
+
+func TestValidateUnregistered(t *testing.T) {
+	if errs := Validate(&PodList{}); len(errs) != 0 {
+		t.Errorf("expected types without validation to be valid: %v", errs)
+	}
+}
+
+func TestValidateObjectID(t *testing.T) {
+	valid := []interface{}{
+		&Pod{JSONBase: JSONBase{ID: "frontend-1"}},
+		&Pod{},
+		&Service{JSONBase: JSONBase{ID: strings.Repeat("a", 63)}},
+		&Minion{JSONBase: JSONBase{ID: "minion-1.example.com"}},
+		&PodList{},
+	}
+	for _, obj := range valid {
+		if errs := ValidateObjectID(obj); len(errs) != 0 {
+			t.Errorf("expected %#v to be valid: %v", obj, errs)
+		}
+	}
+
+	invalid := map[string]struct {
+		obj  interface{}
+		rule string
+	}{
+		"reserved":    {&Pod{JSONBase: JSONBase{ID: "watch"}}, "is reserved for a path segment of the API"},
+		"uppercase":   {&Pod{JSONBase: JSONBase{ID: "frontendController"}}, "must consist of lowercase letters, digits and '-', starting and ending with a letter or digit"},
+		"slash":       {&ReplicationController{JSONBase: JSONBase{ID: "a/b"}}, "must consist of lowercase letters, digits and '-', starting and ending with a letter or digit"},
+		"dash":        {&Service{JSONBase: JSONBase{ID: "frontend-"}}, "must consist of lowercase letters, digits and '-', starting and ending with a letter or digit"},
+		"dots":        {&Pod{JSONBase: JSONBase{ID: "a.b"}}, "must consist of lowercase letters, digits and '-', starting and ending with a letter or digit"},
+		"long":        {&Pod{JSONBase: JSONBase{ID: strings.Repeat("a", 64)}}, "must be no more than 63 characters"},
+		"minion":      {&Minion{JSONBase: JSONBase{ID: "Minion_1"}}, "must be DNS labels separated by '.', each of lowercase letters, digits and '-', starting and ending with a letter or digit"},
+		"long minion": {&Minion{JSONBase: JSONBase{ID: strings.Repeat("a.", 127)}}, "must be no more than 253 characters"},
+	}
+	for name, test := range invalid {
+		errs := ValidateObjectID(test.obj)
+		if len(errs) != 1 {
+			t.Errorf("%s: expected one error, got %v", name, errs)
+			continue
+		}
+		if err, ok := errs[0].(InvalidIDError); !ok || err.Rule != test.rule {
+			t.Errorf("%s: expected the rule %q, got %v", name, test.rule, errs[0])
+		}
+	}
+}
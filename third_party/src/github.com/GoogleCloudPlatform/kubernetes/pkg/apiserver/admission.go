@@ -0,0 +1,52 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+// Verbs passed to Admission.Admit.
+const (
+	AdmitCreate = "create"
+	AdmitUpdate = "update"
+)
+
+// Admission enforces cluster policy on objects clients create or update, before they
+// are validated and handed to their RESTStorage.
+type Admission interface {
+	// Admit is called with the verb (AdmitCreate or AdmitUpdate), the resource the
+	// request is for (e.g. "pods") and the decoded object. It may change obj in place,
+	// or return an error to reject the request, which is reported to the client as
+	// forbidden.
+	Admit(verb, resource string, obj interface{}) error
+}
+
+// AdmissionFunc adapts a function to Admission.
+type AdmissionFunc func(verb, resource string, obj interface{}) error
+
+// Admit implements Admission.
+func (f AdmissionFunc) Admit(verb, resource string, obj interface{}) error {
+	return f(verb, resource, obj)
+}
+
+// admit runs obj through each of chain in order, stopping at the first that
+// rejects it.
+func admit(chain []Admission, verb, resource string, obj interface{}) error {
+	for _, admission := range chain {
+		if err := admission.Admit(verb, resource, obj); err != nil {
+			return NewForbiddenErr(objectKind(obj), objectID(obj), err)
+		}
+	}
+	return nil
+}
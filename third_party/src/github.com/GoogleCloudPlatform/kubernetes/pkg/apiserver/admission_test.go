@@ -0,0 +1,141 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestAdmission(t *testing.T) {
+	calls := []string{}
+	record := AdmissionFunc(func(verb, resource string, obj interface{}) error {
+		calls = append(calls, fmt.Sprintf("%s %s", verb, resource))
+		return nil
+	})
+	rename := AdmissionFunc(func(verb, resource string, obj interface{}) error {
+		obj.(*Simple).Name += "-admitted"
+		return nil
+	})
+	simpleStorage := &SimpleRESTStorage{item: Simple{Name: "foo"}}
+	handler := New(map[string]RESTStorage{"simple": simpleStorage}, codec, "/prefix/version", record, rename)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	client := http.Client{}
+
+	data, _ := codec.Encode(Simple{Name: "foo"})
+	for _, item := range []struct{ method, path string }{
+		{"GET", "/prefix/version/simple"},
+		{"GET", "/prefix/version/simple/id"},
+		{"POST", "/prefix/version/simple"},
+		{"PUT", "/prefix/version/simple/id"},
+		{"DELETE", "/prefix/version/simple/id"},
+	} {
+		request, err := http.NewRequest(item.method, server.URL+item.path, bytes.NewBuffer(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := client.Do(request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if expected := []string{"create simple", "update simple"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected the chain to run for writes only, got %v", calls)
+	}
+	if simpleStorage.created == nil || simpleStorage.created.Name != "foo-admitted" {
+		t.Errorf("expected the created object to be mutated, got %#v", simpleStorage.created)
+	}
+	if simpleStorage.updated == nil || simpleStorage.updated.Name != "foo-admitted" {
+		t.Errorf("expected the updated object to be mutated, got %#v", simpleStorage.updated)
+	}
+}
+
+func TestAdmissionCreateIfMissing(t *testing.T) {
+	calls := []string{}
+	record := AdmissionFunc(func(verb, resource string, obj interface{}) error {
+		calls = append(calls, fmt.Sprintf("%s %s", verb, resource))
+		return nil
+	})
+	data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}, Name: "foo"})
+	for _, exists := range []bool{true, false} {
+		simpleStorage := &SimpleRESTStorage{}
+		expected := []string{"update simple"}
+		if !exists {
+			simpleStorage.errors = map[string]error{"get": NewNotFoundErr("simple", "bar")}
+			expected = []string{"create simple"}
+		}
+		server := httptest.NewServer(New(map[string]RESTStorage{"simple": simpleStorage}, codec, "/prefix/version", record))
+		request, err := http.NewRequest("PUT", server.URL+"/prefix/version/simple/bar?createIfMissing=true", bytes.NewBuffer(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := http.DefaultClient.Do(request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		server.Close()
+		if !reflect.DeepEqual(calls, expected) {
+			t.Errorf("exists %t: expected the chain to run once as %v, got %v", exists, expected, calls)
+		}
+		calls = []string{}
+	}
+}
+
+func TestAdmissionDenied(t *testing.T) {
+	called := false
+	deny := AdmissionFunc(func(verb, resource string, obj interface{}) error {
+		return errors.New("not allowed")
+	})
+	after := AdmissionFunc(func(verb, resource string, obj interface{}) error {
+		called = true
+		return nil
+	})
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{"simple": simpleStorage}, codec, "/prefix/version", deny, after)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}, Name: "foo"})
+	response, err := http.Post(server.URL+"/prefix/version/simple", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected response %#v", response)
+	}
+	var status api.Status
+	body, err := extractBody(response, &status)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Reason != api.ReasonTypeForbidden || status.Details == nil || status.Details.ID != "bar" {
+		t.Errorf("unexpected status: %#v (%s)", status, body)
+	}
+	if called {
+		t.Errorf("expected the chain to stop at the first denial")
+	}
+	if simpleStorage.created != nil {
+		t.Errorf("expected the denied object not to reach the storage: %#v", simpleStorage.created)
+	}
+}
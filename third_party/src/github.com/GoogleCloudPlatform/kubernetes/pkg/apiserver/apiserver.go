@@ -19,17 +19,22 @@ package apiserver
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"net/http"
 	"path"
+	"reflect"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"code.google.com/p/go-uuid/uuid"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/healthz"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/httplog"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 	"github.com/golang/glog"
 )
@@ -42,18 +47,127 @@ type Codec interface {
 	DecodeInto(data []byte, obj interface{}) error
 }
 
+// StreamEncoder may be implemented by Codecs that can write an encoded object to a stream,
+// without returning the whole encoding first. Nothing must be written if the object can't be
+// encoded. The apiserver writes large responses, e.g. lists, with it.
+type StreamEncoder interface {
+	EncodeToStream(w io.Writer, obj interface{}) error
+}
+
+const (
+	// DefaultAsyncOpWait is how long requests wait for their operations to complete before
+	// they are answered with the ID of the operation, just long enough for most simple
+	// writes.
+	DefaultAsyncOpWait = 25 * time.Millisecond
+	// DefaultMaxAsyncOpWait is the longest a request may ask to wait for its operation.
+	DefaultMaxAsyncOpWait = 5 * time.Second
+
+	// defaultTimeout is how long synchronous requests wait for their operation if they
+	// name no timeout.
+	defaultTimeout = 30 * time.Second
+)
+
 // APIServer is an HTTPHandler that delegates to RESTStorage objects.
 // It handles URLs of the form:
 // ${prefix}/${storage_key}[/${object_name}]
+// ${prefix}/ns/${namespace}/${storage_key}[/${object_name}]
 // Where 'prefix' is an arbitrary string, and 'storage_key' points to a RESTStorage object stored in storage.
+// Requests that don't name a namespace are in the default namespace, except lists, which span all namespaces.
 //
 // TODO: consider migrating this to go-restful which is a more full-featured version of the same thing.
 type APIServer struct {
 	storage     map[string]RESTStorage
 	codec       Codec
+	codecs      map[string]Codec
 	ops         *Operations
+	admission   []Admission
+	events      *EventRecorder
+	lists       *listCache
+	authn       Authenticator
 	asyncOpWait time.Duration
 	handler     http.Handler
+
+	// maxAsyncOpWait caps the wait requests ask for.
+	maxAsyncOpWait time.Duration
+	// panics counts the requests whose handler panicked, accessed atomically.
+	panics uint64
+	// aborts counts the requests abandoned because their client went away, accessed
+	// atomically.
+	aborts uint64
+	// prefix is the path s serves the API under, without a trailing slash.
+	prefix string
+	// apiVersion is the version of the API s serves, the last segment of its prefix.
+	apiVersion string
+	// slowRequestThreshold is how long a request may take before its trace is logged.
+	slowRequestThreshold time.Duration
+	// latencies count how long the steps of requests take.
+	latencies *latencyHistograms
+
+	// listDependents maps resources to the other resources whose lists their writes change.
+	listDependents map[string][]string
+	// legacyIDs maps resources to the IDs that objects may be created with even though they
+	// break the rules of api.ValidateObjectID.
+	legacyIDs map[string]util.StringSet
+	// strictParams rejects requests with unknown query parameters, unless they pass
+	// strictParams=false.
+	strictParams bool
+	// invalidBodies logs the bodies of requests that can't be decoded or are invalid.
+	invalidBodies *bodyLogger
+	// watches configures the buffers that keep slow watch clients from blocking storages.
+	watches *watchBuffers
+	// schema describes the objects and resources s serves.
+	schema *Schema
+	// indexCountTimeout is how long the index waits for the objects of resources to be counted.
+	indexCountTimeout time.Duration
+	// listLimits are the limits of the lists of resources, see SetListLimit.
+	listLimits map[string]listLimit
+	// bodyReadTimeout is how long clients have to send the bodies of requests.
+	bodyReadTimeout time.Duration
+	// instrumentedStorage is the etcd client the storages call, if it is instrumented.
+	instrumentedStorage *tools.InstrumentedEtcdClient
+	// now is the clock the creation and modification timestamps of objects are read from.
+	now func() time.Time
+	// mirror, if set, mirrors the API requests that read objects to a shadow apiserver.
+	mirror *Mirror
+}
+
+// Config selects the handlers an APIServer serves besides the API, for NewWithConfig.
+type Config struct {
+	// Operations tracks asynchronous operations. If nil, NewOperations is used.
+	Operations *Operations
+	// EnableLogsSupport serves the files in LogDir at /logs/.
+	EnableLogsSupport bool
+	// LogDir is the directory served at /logs/, or /var/log/ if empty.
+	LogDir string
+	// EnableProxy serves /proxy/minion/, which proxies requests to the kubelets of minions.
+	EnableProxy bool
+	// ProxyTransport carries the requests proxied to minions. If nil,
+	// http.DefaultTransport is used.
+	ProxyTransport http.RoundTripper
+	// EnableIndex serves the index of the resources and their number of objects at /.
+	EnableIndex bool
+	// HealthChecks are run by every request for /healthz, which fails if one of them does.
+	HealthChecks []healthz.Check
+	// InstrumentedStorage, if set, is the etcd client the storages call. The latencies of
+	// its calls are served in /metrics, along with the state of its breaker, which also
+	// fails /healthz while it is tripped.
+	InstrumentedStorage *tools.InstrumentedEtcdClient
+	// StorageBreaker configures the breaker of InstrumentedStorage, which fails requests
+	// with 503 Service Unavailable, without calling etcd, once etcd looks unavailable. Its
+	// zero value never trips it.
+	StorageBreaker tools.BreakerConfig
+	// Mirror, if set, sends a sample of the API requests that read objects to a shadow
+	// apiserver. Its counters are served in /metrics.
+	Mirror *Mirror
+}
+
+// DefaultConfig returns the Config of New, which serves every handler.
+func DefaultConfig() Config {
+	return Config{
+		EnableLogsSupport: true,
+		EnableProxy:       true,
+		EnableIndex:       true,
+	}
 }
 
 // New creates a new APIServer object. 'storage' contains a map of handlers. 'codec'
@@ -62,195 +176,754 @@ type APIServer struct {
 // The codec will be used to decode the request body into an object pointer returned by
 // RESTStorage.New().  The Create() and Update() methods should cast their argument to
 // the type returned by New().
-// TODO: add multitype codec serialization
-func New(storage map[string]RESTStorage, codec Codec, prefix string) *APIServer {
+//
+// Objects that clients create or update are passed through each of 'admission' in order
+// before they reach their storage. Codecs for other media types than JSON may be added with
+// AddCodec.
+func New(storage map[string]RESTStorage, codec Codec, prefix string, admission ...Admission) *APIServer {
+	return NewWithConfig(storage, codec, prefix, DefaultConfig(), admission...)
+}
+
+// NewWithOperations is like New, but tracks asynchronous operations in ops, e.g. to keep
+// their results across restarts with NewPersistentOperations.
+func NewWithOperations(storage map[string]RESTStorage, codec Codec, prefix string, ops *Operations, admission ...Admission) *APIServer {
+	config := DefaultConfig()
+	config.Operations = ops
+	return NewWithConfig(storage, codec, prefix, config, admission...)
+}
+
+// NewWithConfig is like New, but only serves the logs, the minion proxy and the welcome
+// page if config enables them. Requests for disabled handlers are answered with 404.
+func NewWithConfig(storage map[string]RESTStorage, codec Codec, prefix string, config Config, admission ...Admission) *APIServer {
+	ops := config.Operations
+	if ops == nil {
+		ops = NewOperations()
+	}
 	s := &APIServer{
-		storage: storage,
-		codec:   codec,
-		ops:     NewOperations(),
-		// Delay just long enough to handle most simple write operations
-		asyncOpWait: time.Millisecond * 25,
+		storage:   storage,
+		codec:     codec,
+		codecs:    map[string]Codec{api.JSONMediaType: codec},
+		ops:       ops,
+		admission: admission,
+
+		asyncOpWait:    DefaultAsyncOpWait,
+		maxAsyncOpWait: DefaultMaxAsyncOpWait,
+
+		slowRequestThreshold: DefaultSlowRequestThreshold,
+		latencies:            newLatencyHistograms(),
+		strictParams:         true,
+		watches:              &watchBuffers{size: DefaultWatchBufferSize, policy: WatchBufferDrop},
+		indexCountTimeout:    DefaultIndexCountTimeout,
+		bodyReadTimeout:      DefaultBodyReadTimeout,
+		instrumentedStorage:  config.InstrumentedStorage,
+		now:                  time.Now,
+		mirror:               config.Mirror,
+	}
+	healthChecks := config.HealthChecks
+	if config.InstrumentedStorage != nil {
+		config.InstrumentedStorage.SetBreaker(config.StorageBreaker)
+		healthChecks = append(append([]healthz.Check{}, healthChecks...), healthz.Check{Name: "storage breaker", Check: config.InstrumentedStorage.HealthCheck})
 	}
 
 	mux := http.NewServeMux()
 
 	prefix = strings.TrimRight(prefix, "/")
+	s.prefix = prefix
+	s.apiVersion = path.Base(prefix)
 
 	// Primary API handlers
 	restPrefix := prefix + "/"
 	mux.Handle(restPrefix, http.StripPrefix(restPrefix, http.HandlerFunc(s.handleREST)))
 
+	// Limits and defaults of the apiserver
+	mux.HandleFunc(path.Join(prefix, "serverconfig"), s.handleServerConfig)
+
+	// Description of the objects and resources of the API
+	s.schema = newSchema(storage, codec, prefix, s.apiVersion)
+	mux.HandleFunc(path.Join(prefix, "schema"), s.handleSchema)
+
 	// Watch API handlers
 	watchPrefix := path.Join(prefix, "watch") + "/"
-	mux.Handle(watchPrefix, http.StripPrefix(watchPrefix, &WatchHandler{storage, codec}))
+	mux.Handle(watchPrefix, http.StripPrefix(watchPrefix, &WatchHandler{storage, s.negotiate, s.requestContext, s.checkParameters, s.watches, s.notFound}))
 
 	// Support services for the apiserver
-	logsPrefix := "/logs/"
-	mux.Handle(logsPrefix, http.StripPrefix(logsPrefix, http.FileServer(http.Dir("/var/log/"))))
-	healthz.InstallHandler(mux)
+	if config.EnableLogsSupport {
+		logDir := config.LogDir
+		if len(logDir) == 0 {
+			logDir = "/var/log/"
+		}
+		logsPrefix := "/logs/"
+		mux.Handle(logsPrefix, http.StripPrefix(logsPrefix, http.FileServer(http.Dir(logDir))))
+	}
+	healthz.InstallHandler(mux, healthChecks...)
 	mux.HandleFunc("/version", handleVersion)
-	mux.HandleFunc("/", handleIndex)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	if config.EnableIndex {
+		mux.HandleFunc("/", s.handleIndex)
+	} else {
+		mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) { s.notFound(w, req, "") })
+	}
 
 	// Handle both operations and operations/* with the same handler
-	handler := &OperationHandler{s.ops, s.codec}
+	handler := &OperationHandler{s.ops, s.codec, s.checkParameters, s.notFound, s.prefix}
 	operationPrefix := path.Join(prefix, "operations")
 	mux.Handle(operationPrefix, http.StripPrefix(operationPrefix, handler))
 	operationsPrefix := operationPrefix + "/"
 	mux.Handle(operationsPrefix, http.StripPrefix(operationsPrefix, handler))
 
 	// Proxy minion requests
-	mux.Handle("/proxy/minion/", http.StripPrefix("/proxy/minion", http.HandlerFunc(handleProxyMinion)))
+	if config.EnableProxy {
+		mux.Handle("/proxy/minion/", http.StripPrefix("/proxy/minion", &minionProxy{config.ProxyTransport}))
+	}
 
 	s.handler = mux
 
 	return s
 }
 
-// ServeHTTP implements the standard net/http interface.
+// SetEventRecorder makes s record an event through events whenever a storage fails to
+// create, update or delete an object.
+func (s *APIServer) SetEventRecorder(events *EventRecorder) {
+	s.events = events
+}
+
+// SetAuthenticator makes s verify the credentials of requests with authn, and pass the user
+// they belong to to storage in api.Context.User. Without an authenticator, requests are
+// anonymous.
+func (s *APIServer) SetAuthenticator(authn Authenticator) {
+	s.authn = authn
+}
+
+// SetListCacheTTL makes s keep its responses to list requests for ttl, until an object of
+// the listed resource is created, updated or deleted. Clients that must see the storage's
+// current list pass fresh=true. A ttl of 0 disables the cache.
+func (s *APIServer) SetListCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		s.lists = nil
+		return
+	}
+	s.lists = newListCache(ttl)
+}
+
+// SetListCacheDependency makes creating, updating or deleting objects of resource through
+// s also drop the cached lists of dependents, for storages that change objects of other
+// resources, e.g. bindings assigning pods to hosts.
+func (s *APIServer) SetListCacheDependency(resource string, dependents ...string) {
+	if s.listDependents == nil {
+		s.listDependents = map[string][]string{}
+	}
+	s.listDependents[resource] = dependents
+}
+
+// AllowLegacyID lets objects of resource called id be created through s even though id
+// breaks the rules of api.ValidateObjectID, so that objects created before IDs were
+// validated can still be recreated while their clients move to valid IDs.
+func (s *APIServer) AllowLegacyID(resource, id string) {
+	if s.legacyIDs == nil {
+		s.legacyIDs = map[string]util.StringSet{}
+	}
+	if s.legacyIDs[resource] == nil {
+		s.legacyIDs[resource] = util.NewStringSet()
+	}
+	s.legacyIDs[resource].Insert(id)
+}
+
+// SetAsyncOpWait makes requests that create, update or delete objects through s wait up to
+// wait for their operation to complete, and answer with the ID of the operation if it takes
+// longer. A wait of 0 answers them at once. A request may ask for another wait with the
+// wait parameter, up to max.
+func (s *APIServer) SetAsyncOpWait(wait, max time.Duration) {
+	s.asyncOpWait = wait
+	s.maxAsyncOpWait = max
+}
+
+// SetSlowRequestThreshold makes s log how long each step of a request took, e.g. decoding,
+// the storage call and the operation wait, when the request takes longer than threshold. A
+// threshold that isn't positive disables the log. The steps are counted in the latency
+// histograms of /metrics either way.
+func (s *APIServer) SetSlowRequestThreshold(threshold time.Duration) {
+	s.slowRequestThreshold = threshold
+}
+
+// SetInvalidBodyLogLimit makes s log the first limit bytes of the bodies of requests that
+// can't be decoded or fail validation, along with their request ID, so that the error a
+// client got can be debugged. Passwords and tokens are redacted, and only a few bodies are
+// logged each minute. A limit that isn't positive, the default, logs nothing.
+func (s *APIServer) SetInvalidBodyLogLimit(limit int) {
+	if limit <= 0 {
+		s.invalidBodies = nil
+		return
+	}
+	s.invalidBodies = newBodyLogger(limit)
+}
+
+// listsChangedBy returns the resources whose cached lists a write to resource invalidates.
+func (s *APIServer) listsChangedBy(resource string) []string {
+	return append([]string{resource}, s.listDependents[resource]...)
+}
+
+// ServeHTTP implements the standard net/http interface. Requests are given an ID, in the
+// X-Request-Id header, unless the client or a proxy supplied one. If the handler of a request
+// panics, it is answered with a Status naming the request, and the panic is logged.
 func (s *APIServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	defer func() {
-		if x := recover(); x != nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			fmt.Fprint(w, "apis panic. Look in log for details.")
-			glog.Infof("APIServer panic'd on %v %v: %#v\n%s\n", req.Method, req.RequestURI, x, debug.Stack())
-		}
-	}()
+	if len(req.Header.Get("X-Request-Id")) == 0 {
+		req.Header.Set("X-Request-Id", uuid.NewUUID().String())
+	}
+	if s.mirrors(req) {
+		var mirrored func()
+		w, mirrored = s.mirror.track(w, req)
+		defer mirrored()
+	}
 	defer httplog.MakeLogged(req, &w).StacktraceWhen(
 		httplog.StatusIsNot(
 			http.StatusOK,
+			http.StatusCreated,
 			http.StatusAccepted,
 			http.StatusConflict,
 			http.StatusNotFound,
+			http.StatusMethodNotAllowed,
 		),
 	).Log()
+	// Deferred after the logger, so that the logger records the answer and its stack.
+	defer func() {
+		if x := recover(); x != nil {
+			atomic.AddUint64(&s.panics, 1)
+			glog.Errorf("APIServer panic'd on %v %v: %#v\n%s\n", req.Method, req.RequestURI, x, debug.Stack())
+			s.writePanic(req.Header.Get("X-Request-Id"), w)
+		}
+	}()
 
 	// Dispatch to the internal handler
 	s.handler.ServeHTTP(w, req)
 }
 
-// handleREST handles requests to all our RESTStorage objects.
+// mirrors returns true if req is an API request s may mirror to a shadow apiserver.
+// Operations are left out, as the shadow runs operations of its own.
+func (s *APIServer) mirrors(req *http.Request) bool {
+	return s.mirror != nil && strings.HasPrefix(req.URL.Path, s.prefix+"/") && !strings.HasPrefix(req.URL.Path, s.prefix+"/operations")
+}
+
+// writePanic answers the request requestID, whose handler panicked, with a Status that
+// leaves out the details of the panic. The Status is encoded with s.codec, or as plain JSON
+// if the codec fails too.
+func (s *APIServer) writePanic(requestID string, w http.ResponseWriter) {
+	status := &api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusInternalServerError,
+		Reason:  api.ReasonTypeInternalError,
+		Message: fmt.Sprintf("internal error while serving request %s, see the apiserver log for details", requestID),
+	}
+	output, err := encodeRecovering(s.codec, status)
+	if err != nil {
+		glog.Errorf("Unable to encode the status of request %s: %v", requestID, err)
+		output, _ = json.Marshal(status)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status.Code)
+	w.Write(output)
+}
+
+// encodeRecovering encodes obj with codec, returning an error if codec panics.
+func encodeRecovering(codec Codec, obj interface{}) (data []byte, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("the codec panicked: %v", x)
+		}
+	}()
+	return codec.Encode(obj)
+}
+
+// handleREST handles requests to all our RESTStorage objects. OPTIONS requests are answered
+// with the ResourceOptions of their path, or with the APIOptions of all resources on
+// ${prefix}/.
 func (s *APIServer) handleREST(w http.ResponseWriter, req *http.Request) {
-	parts := splitPath(req.URL.Path)
-	if len(parts) < 1 {
-		notFound(w, req)
+	namespace, parts, ok := splitNamespace(splitPath(req.URL.Path), req.Method)
+	if ok && len(parts) == 0 && req.Method == "OPTIONS" {
+		s.writeAPIOptions(w)
+		return
+	}
+	if !ok || len(parts) < 1 {
+		s.notFound(w, req, "")
 		return
 	}
 	storage := s.storage[parts[0]]
 	if storage == nil {
 		httplog.LogOf(w).Addf("'%v' has no storage object", parts[0])
-		notFound(w, req)
+		s.notFound(w, req, parts[0])
 		return
 	}
+	if req.Method == "OPTIONS" {
+		if !writeResourceOptions(parts[0], storage, len(parts), w) {
+			s.notFound(w, req, parts[0])
+		}
+		return
+	}
+
+	codecs, err := s.negotiate(req)
+	if err != nil {
+		errorJSON(err, codecs.out, w)
+		return
+	}
+	tr := newTrace(req.Method + " " + req.URL.Path)
+	defer tr.finish(s.slowRequestThreshold, s.latencies)
+	ctx := s.requestContext(req)
+	ctx.Namespace = namespace
+	gone, answered := clientGone(w)
+	defer answered()
+	ctx.Done = gone
+	s.handleRESTStorage(ctx, parts, req, w, storage, codecs, tr)
+}
+
+// allowedMethods returns the methods handleRESTStorage serves for storage on paths of the given
+// length, not counting the namespace: collections, objects, and subresources of objects. Which
+// methods are served on collections and objects, and whether subresources can be patched,
+// depends on the interfaces storage implements.
+// ok is false if no paths of that length are served.
+func allowedMethods(storage RESTStorage, length int) (methods []string, ok bool) {
+	switch length {
+	case 1:
+		_, lister := asLister(storage)
+		_, fieldLister := asResourceFieldLister(storage)
+		if lister || fieldLister {
+			methods = append(methods, "GET")
+		}
+		if _, ok := asCreater(storage); ok {
+			methods = append(methods, "POST")
+		}
+	case 2:
+		if _, ok := asGetter(storage); ok {
+			methods = append(methods, "GET")
+		}
+		if _, ok := asUpdater(storage); ok {
+			methods = append(methods, "PUT")
+		}
+		if _, ok := asDeleter(storage); ok {
+			methods = append(methods, "DELETE")
+		}
+	case 3:
+		methods = []string{"GET", "PUT", "POST"}
+		if _, ok := storage.(SubresourcePatcher); ok {
+			methods = append(methods, "PATCH")
+		}
+	default:
+		return nil, false
+	}
+	return methods, true
+}
 
-	s.handleRESTStorage(parts, req, w, storage)
+// hasMethod returns true if method is one of methods.
+func hasMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// methodNotAllowed renders a 405 error for resource, naming the allowed methods in the Allow header.
+func methodNotAllowed(resource string, allowed []string, req *http.Request, w http.ResponseWriter, codec Codec) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	errorJSON(NewMethodNotSupported(resource, req.Method), codec, w)
 }
 
-// handleRESTStorage is the main dispatcher for a storage object.  It switches on the HTTP method, and then
-// on path length, according to the following table:
-//   Method     Path          Action
-//   GET        /foo          list
-//   GET        /foo/bar      get 'bar'
-//   POST       /foo          create
-//   PUT        /foo/bar      update 'bar'
-//   DELETE     /foo/bar      delete 'bar'
-// Returns 404 if the method/pattern doesn't match one of these entries
+// handleRESTStorage is the main dispatcher for a storage object, for requests in the namespace of ctx.
+// It switches on the HTTP method, and then on path length, according to the following table:
+//
+//	Method     Path          Action
+//	GET        /foo          list
+//	GET        /foo/bar      get 'bar'
+//	POST       /foo          create
+//	PUT        /foo/bar      update 'bar'
+//	DELETE     /foo/bar      delete 'bar'
+//
+// Returns 404 if the path doesn't match one of these entries, and 405 with an Allow header listing
+// the methods of the entries it matches if only the method doesn't. Entries are only served if the
+// storage implements the interface of their action, e.g. Lister for list.
+// Paths of the form /foo/bar/baz name subresource 'baz' of 'bar', and are served by
+// handleSubresource if the storage is a SubresourceStorage.
+// Objects are put in the namespace of the request, which storages find in the api.Context they are
+// passed, and store them under their ID qualified with the namespace. IDs may not contain
+// api.NamespaceSeparator. Lists across all namespaces name the namespace of each item.
+// Request bodies are decoded and answers encoded with the codecs negotiated for the request,
+// see AddCodec. The steps of the request are recorded in tr.
+// Objects sent to create and update are passed through the admission chain, which rejects them
+// with 403, defaulted if the storage is a Defaulter, and then validated with api.Validate,
+// which rejects them with 422.
+// If s has an EventRecorder, storage failures to create, update or delete an object are
+// recorded as events about the object.
 // The s accepts several query parameters:
-//    sync=[false|true] Synchronous request (only applies to create, update, delete operations)
-//    timeout=<duration> Timeout for synchronous requests, only applies if sync=true
-//    labels=<label-selector> Used for filtering list operations
-func (s *APIServer) handleRESTStorage(parts []string, req *http.Request, w http.ResponseWriter, storage RESTStorage) {
-	sync := req.URL.Query().Get("sync") == "true"
-	timeout := parseTimeout(req.URL.Query().Get("timeout"))
+//
+//	sync=[false|true] Synchronous request (only applies to create, update, delete operations)
+//	timeout=<duration> Timeout for synchronous requests, only applies if sync=true
+//	wait=<duration> How long to wait for the operation before answering 202 with its ID (only applies
+//	                to create, update, delete operations), capped by the maximum set with SetAsyncOpWait.
+//	                Ignored if sync=true, since synchronous requests wait up to their timeout
+//	labels=<label-selector> Used for filtering list operations
+//	fields=<field-selector> Used for filtering list operations, if the storage is a ResourceFieldLister
+//	fresh=[false|true] Bypass the list cache (only applies to list operations)
+//	sort=<field path>[,desc] Order the items of list operations by a field, such as desiredState.replicas,
+//	                ascending unless desc is given. Items with the same value are ordered by ID
+//	limit=<number> The most items list operations answer with, capped by the maximum set with SetListLimit
+//	continue=<token> Continue a list operation answered with a ListContinueHeader token after its items
+//	dryRun=[false|true] Check and return the object without storing it (only applies to create, update operations)
+//	createIfMissing=[false|true] Create the object if it doesn't exist (only applies to update operations)
+//	force=[false|true] Override the checks of the storage that may be overridden, see api.Context.Force
+//	                (only applies to create, update operations)
+//	<view>=[false|true] Answer list operations with a view of the objects instead, if the storage is a
+//	                ListViewer with that view, e.g. usedPorts=true for services
+//	strictParams=[true|false] Whether to reject parameters that don't apply to the request, see SetStrictParams
+//
+// Requests passing parameters that don't apply to them, such as misspelled ones, are rejected with 400
+// naming the parameter, unless they have an empty value. Sync and timeout are accepted on any request.
+// Parameters with invalid values are rejected with 400 naming every one of them, see ParseRequestOptions.
+// An update with createIfMissing=true creates the object instead if the storage doesn't have it, and
+// answers 201 rather than 200 when it does. Which of the two is decided before the object is admitted,
+// with the verb of the write made. The ID of the object must be the one in the path.
+func (s *APIServer) handleRESTStorage(ctx api.Context, parts []string, req *http.Request, w http.ResponseWriter, storage RESTStorage, codecs requestCodecs, tr *trace) {
+	allowed, ok := allowedMethods(storage, len(parts))
+	if !ok {
+		s.notFound(w, req, parts[0])
+		return
+	}
+	if !hasMethod(allowed, req.Method) {
+		methodNotAllowed(parts[0], allowed, req, w, codecs.out)
+		return
+	}
+	if err := s.checkParameters(req.URL.Query(), queryParameters(storage, len(parts), req.Method)); err != nil {
+		errorJSON(NewBadRequestErr(objectKind(storage.New()), strings.Join(parts[1:], "/"), err), codecs.out, w)
+		return
+	}
+	opts, err := ParseRequestOptions(req)
+	if err != nil {
+		errorJSON(err, codecs.out, w)
+		return
+	}
+	ctx.Force = opts.Force
+	if opts.Sync {
+		ctx.Deadline = time.Now().Add(opts.Timeout)
+	}
+	wait := s.operationWait(opts)
+	if len(parts) == 3 {
+		s.handleSubresource(ctx, parts, req, w, storage, opts, codecs)
+		return
+	}
 	switch req.Method {
 	case "GET":
 		switch len(parts) {
 		case 1:
-			selector, err := labels.ParseSelector(req.URL.Query().Get("labels"))
-			if err != nil {
-				errorJSON(err, s.codec, w)
+			if err := checkSort(storage.New(), opts.sort); err != nil {
+				errorJSON(NewBadRequestErr(objectKind(storage.New()), "", err), codecs.out, w)
+				return
+			}
+			selector, field := opts.Labels, opts.Fields
+			if viewer, ok := storage.(ListViewer); ok {
+				if view, ok := requestedView(viewer, req.URL.Query()); ok {
+					obj, err := viewer.ListView(ctx, view, selector)
+					if err != nil {
+						errorJSON(err, codecs.out, w)
+						return
+					}
+					tr.step(stepStorage)
+					writeRawJSON(http.StatusOK, obj, w)
+					tr.step(stepEncode)
+					return
+				}
+			}
+			page := s.listPage(parts[0], opts)
+			key := listCacheKey{parts[0], ctx.Namespace, selector.String(), field.String(), opts.Sort, codecs.out.mediaType}
+			// Parts of lists aren't cached, as their headers would have to be.
+			var data []byte
+			var generation uint64
+			cached := false
+			if !page.paged() {
+				data, generation, cached = s.lists.get(key, opts.Fresh)
+			}
+			if cached {
+				writeEncoded(http.StatusOK, codecs.out, data, w)
+				tr.step(stepEncode)
+				return
+			}
+			var list interface{}
+			if lister, ok := asResourceFieldLister(storage); ok {
+				list, err = lister.ListFields(ctx, selector, field)
+			} else if !field.Empty() {
+				err = fmt.Errorf("no field selector implemented for %s", parts[0])
+			} else {
+				lister, _ := asLister(storage)
+				list, err = lister.List(ctx, selector)
+			}
+			if s.abandoned(ctx, w) {
 				return
 			}
-			list, err := storage.List(selector)
 			if err != nil {
-				errorJSON(err, s.codec, w)
+				errorJSON(err, codecs.out, w)
+				return
+			}
+			tr.step(stepStorage)
+			presentObject(list)
+			filterNamespace(list, ctx.Namespace)
+			if err := sortList(list, opts.sort); err != nil {
+				errorJSON(NewBadRequestErr(objectKind(storage.New()), "", err), codecs.out, w)
 				return
 			}
-			writeJSON(http.StatusOK, s.codec, list, w)
+			if page.paged() {
+				page.apply(list, w)
+				writeJSON(http.StatusOK, codecs.out, list, w)
+				tr.step(stepEncode)
+				return
+			}
+			s.writeList(ctx, key, generation, list, codecs.out, w)
+			tr.step(stepEncode)
 		case 2:
-			item, err := storage.Get(parts[1])
+			if err := checkID(objectKind(storage.New()), parts[1]); err != nil {
+				errorJSON(err, codecs.out, w)
+				return
+			}
+			getter, _ := asGetter(storage)
+			item, err := getter.Get(ctx, parts[1])
 			if err != nil {
-				errorJSON(err, s.codec, w)
+				errorJSON(err, codecs.out, w)
 				return
 			}
-			writeJSON(http.StatusOK, s.codec, item, w)
-		default:
-			notFound(w, req)
+			tr.step(stepStorage)
+			presentObject(item)
+			if !inNamespace(item, ctx.Namespace) {
+				errorJSON(NewNotFoundErr(objectKind(item), parts[1]), codecs.out, w)
+				return
+			}
+			writeJSON(http.StatusOK, codecs.out, item, w)
+			tr.step(stepEncode)
 		}
 
 	case "POST":
-		if len(parts) != 1 {
-			notFound(w, req)
+		body, err := s.readBody(req, w)
+		if err != nil {
+			errorJSON(err, codecs.out, w)
 			return
 		}
-		body, err := readBody(req)
-		if err != nil {
-			errorJSON(err, s.codec, w)
+		s.create(ctx, parts[0], body, storage, opts.DryRun, wait, http.StatusOK, codecs, tr, w)
+
+	case "DELETE":
+		if err := checkID(objectKind(storage.New()), parts[1]); err != nil {
+			errorJSON(err, codecs.out, w)
 			return
 		}
-		obj := storage.New()
-		err = s.codec.DecodeInto(body, obj)
+		ref := objectReference(ctx, objectKind(storage.New()), parts[1])
+		deleter, _ := asDeleter(storage)
+		out, err := deleter.Delete(ctx, parts[1])
 		if err != nil {
-			errorJSON(err, s.codec, w)
+			s.events.Eventf(ref, EventReasonFailedDelete, "%v", err)
+			errorJSON(err, codecs.out, w)
 			return
 		}
-		out, err := storage.Create(obj)
+		tr.step(stepStorage)
+		out = s.events.recordFailures(ref, EventReasonFailedDelete, out)
+		out = s.lists.invalidateOn(s.listsChangedBy(parts[0]), out)
+		op := s.createOperation(ctx, parts[0], parts[1], presentResults(out), wait)
+		tr.step(stepWait)
+		s.finishReq(ctx, op, "", codecs.out, w)
+		tr.step(stepEncode)
+
+	case "PUT":
+		body, err := s.readBody(req, w)
 		if err != nil {
-			errorJSON(err, s.codec, w)
+			errorJSON(err, codecs.out, w)
 			return
 		}
-		op := s.createOperation(out, sync, timeout)
-		s.finishReq(op, w)
-
-	case "DELETE":
-		if len(parts) != 2 {
-			notFound(w, req)
+		if err := checkID(objectKind(storage.New()), parts[1]); err != nil {
+			errorJSON(err, codecs.out, w)
 			return
 		}
-		out, err := storage.Delete(parts[1])
+		// Whether the object is created is decided before it is prepared, so that the
+		// admission chain and the checks run once, for the write that is made.
+		_, canCreate := asCreater(storage)
+		createIfMissing := opts.CreateIfMissing && canCreate
+		creating := createIfMissing && missing(ctx, storage, parts[1])
+		verb := AdmitUpdate
+		if creating {
+			verb = AdmitCreate
+		}
+		obj, ref, err := s.prepareObject(ctx, verb, parts[0], body, storage, codecs.in, tr)
 		if err != nil {
-			errorJSON(err, s.codec, w)
+			errorJSON(err, codecs.out, w)
 			return
 		}
-		op := s.createOperation(out, sync, timeout)
-		s.finishReq(op, w)
-
-	case "PUT":
-		if len(parts) != 2 {
-			notFound(w, req)
-			return
+		if createIfMissing {
+			if id := objectID(obj); id != parts[1] {
+				errorJSON(NewBadRequestErr(objectKind(obj), parts[1], fmt.Errorf("the ID in the body, %q, does not match the path", id)), codecs.out, w)
+				return
+			}
 		}
-		body, err := readBody(req)
-		if err != nil {
-			errorJSON(err, s.codec, w)
+		if creating {
+			s.createObject(ctx, parts[0], obj, ref, storage, opts.DryRun, wait, http.StatusCreated, codecs, tr, w)
 			return
 		}
-		obj := storage.New()
-		err = s.codec.DecodeInto(body, obj)
-		if err != nil {
-			errorJSON(err, s.codec, w)
+		if opts.DryRun {
+			s.writeDryRun(obj, http.StatusOK, codecs.out, w)
+			tr.step(stepEncode)
 			return
 		}
-		out, err := storage.Update(obj)
+		updater, _ := asUpdater(storage)
+		out, err := updater.Update(ctx, obj)
 		if err != nil {
-			errorJSON(err, s.codec, w)
+			s.events.Eventf(ref, EventReasonFailedUpdate, "%v", err)
+			errorJSON(err, codecs.out, w)
 			return
 		}
-		op := s.createOperation(out, sync, timeout)
-		s.finishReq(op, w)
+		tr.step(stepStorage)
+		out = s.events.recordFailures(ref, EventReasonFailedUpdate, out)
+		out = s.lists.invalidateOn(s.listsChangedBy(parts[0]), out)
+		op := s.createOperation(ctx, parts[0], parts[1], presentResults(out), wait)
+		tr.step(stepWait)
+		s.finishReq(ctx, op, "", codecs.out, w)
+		tr.step(stepEncode)
+	}
+}
+
+// create creates the object body describes in storage, answering with 201 Created once it
+// is created, or with dryRunCode if dryRun is true. The steps of the creation are recorded
+// in tr.
+func (s *APIServer) create(ctx api.Context, resource string, body []byte, storage RESTStorage, dryRun bool, wait time.Duration, dryRunCode int, codecs requestCodecs, tr *trace, w http.ResponseWriter) {
+	obj, ref, err := s.prepareObject(ctx, AdmitCreate, resource, body, storage, codecs.in, tr)
+	if err != nil {
+		errorJSON(err, codecs.out, w)
+		return
+	}
+	s.createObject(ctx, resource, obj, ref, storage, dryRun, wait, dryRunCode, codecs, tr, w)
+}
+
+// createObject creates obj, prepared by prepareObject to be created, in storage, as create
+// does.
+func (s *APIServer) createObject(ctx api.Context, resource string, obj interface{}, ref api.ObjectReference, storage RESTStorage, dryRun bool, wait time.Duration, dryRunCode int, codecs requestCodecs, tr *trace, w http.ResponseWriter) {
+	if dryRun {
+		s.writeDryRun(obj, dryRunCode, codecs.out, w)
+		tr.step(stepEncode)
+		return
+	}
+	creater, _ := asCreater(storage)
+	out, err := creater.Create(ctx, obj)
+	if err != nil {
+		s.events.Eventf(ref, EventReasonFailedCreate, "%v", err)
+		errorJSON(err, codecs.out, w)
+		return
+	}
+	tr.step(stepStorage)
+	out = s.events.recordFailures(ref, EventReasonFailedCreate, out)
+	out = s.lists.invalidateOn(s.listsChangedBy(resource), out)
+	op := s.createOperation(ctx, resource, objectID(obj), presentResults(out), wait)
+	tr.step(stepWait)
+	s.finishReq(ctx, op, s.resourcePath(ctx, resource), codecs.out, w)
+	tr.step(stepEncode)
+}
+
+// missing returns true if storage finds no object called id in the namespace of ctx.
+// Storages that can't get objects are assumed to have it.
+func missing(ctx api.Context, storage RESTStorage, id string) bool {
+	getter, ok := asGetter(storage)
+	if !ok {
+		return false
+	}
+	_, err := getter.Get(ctx, id)
+	return IsNotFound(err)
+}
+
+// prepareObject decodes body, which a client sent to create or update an object of
+// storage, and readies the object to be handed to storage. The object is passed through
+// the admission chain, defaulted by storage if it is being created and storage is a
+// Defaulter, validated, put in the namespace of ctx and given its timestamps. The IDs of objects being created
+// must follow the rules of api.ValidateObjectID, unless they were allowed with
+// AllowLegacyID. The body is decoded with codec, and the decoding and the checks are
+// recorded in tr as separate steps. Dry runs share this path, so they
+// reject exactly the objects that would be rejected. The returned reference names the
+// object as the client did, for events about it.
+func (s *APIServer) prepareObject(ctx api.Context, verb, resource string, body []byte, storage RESTStorage, codec Codec, tr *trace) (interface{}, api.ObjectReference, error) {
+	obj := storage.New()
+	if err := codec.DecodeInto(body, obj); err != nil {
+		s.invalidBodies.log(ctx.RequestID, body, err)
+		return nil, api.ObjectReference{}, err
+	}
+	tr.step(stepDecode)
+	if err := admit(s.admission, verb, resource, obj); err != nil {
+		return nil, api.ObjectReference{}, err
+	}
+	if defaulter, ok := asDefaulter(storage); ok && verb == AdmitCreate {
+		defaulter.Default(ctx, obj)
+	}
+	if verb == AdmitCreate && !s.legacyIDs[resource].Has(objectID(obj)) {
+		if errs := api.ValidateObjectID(obj); len(errs) != 0 {
+			err := NewInvalidErr(objectKind(obj), objectID(obj), errs)
+			s.invalidBodies.log(ctx.RequestID, body, err)
+			return nil, api.ObjectReference{}, err
+		}
+	}
+	if err := validate(obj); err != nil {
+		s.invalidBodies.log(ctx.RequestID, body, err)
+		return nil, api.ObjectReference{}, err
+	}
+	ref := objectReference(ctx, objectKind(obj), objectID(obj))
+	if err := namespaceObject(ctx, obj); err != nil {
+		return nil, api.ObjectReference{}, err
+	}
+	s.stampObject(ctx, verb, storage, obj)
+	tr.step(stepValidate)
+	return obj, ref, nil
+}
 
-	default:
-		notFound(w, req)
+// writeDryRun answers a dry run with obj as it would have been handed to its storage,
+// encoded with codec.
+func (s *APIServer) writeDryRun(obj interface{}, code int, codec Codec, w http.ResponseWriter) {
+	presentObject(obj)
+	writeJSON(code, codec, obj, w)
+}
+
+// validate runs the validation registered with api.AddValidator for obj, returning an
+// error that satisfies IsInvalid if obj is not valid.
+func validate(obj interface{}) error {
+	errs := api.Validate(obj)
+	if len(errs) == 0 {
+		return nil
+	}
+	return NewInvalidErr(objectKind(obj), objectID(obj), errs)
+}
+
+// objectKind names the kind of obj as storages do in errors, e.g. "pod" or
+// "replicationController".
+func objectKind(obj interface{}) string {
+	kind := reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+	return strings.ToLower(kind[:1]) + kind[1:]
+}
+
+// objectID returns the ID of obj, or "" if it has none.
+func objectID(obj interface{}) string {
+	jsonBase, err := api.FindJSONBaseRO(obj)
+	if err != nil {
+		return ""
 	}
+	return jsonBase.ID
+}
+
+// RequestContext returns the api.Context of req, for an anonymous user. A request ID is
+// generated unless the client or a proxy supplied one.
+func RequestContext(req *http.Request) api.Context {
+	ctx := api.NewContext()
+	ctx.RequestID = req.Header.Get("X-Request-Id")
+	if len(ctx.RequestID) == 0 {
+		ctx.RequestID = uuid.NewUUID().String()
+	}
+	return ctx
+}
+
+// requestContext returns the api.Context of req, with the user whose credentials s's
+// authenticator verified, if any.
+func (s *APIServer) requestContext(req *http.Request) api.Context {
+	ctx := RequestContext(req)
+	if s.authn != nil {
+		if user, ok := s.authn.AuthenticateRequest(req); ok {
+			ctx.User = user
+		}
+	}
+	return ctx
 }
 
 // handleVersionReq writes the server's version information.
@@ -258,55 +931,184 @@ func handleVersion(w http.ResponseWriter, req *http.Request) {
 	writeRawJSON(http.StatusOK, version.Get(), w)
 }
 
-// createOperation creates an operation to process a channel response
-func (s *APIServer) createOperation(out <-chan interface{}, sync bool, timeout time.Duration) *Operation {
-	op := s.ops.NewOperation(out)
-	if sync {
-		op.WaitFor(timeout)
-	} else if s.asyncOpWait != 0 {
-		op.WaitFor(s.asyncOpWait)
+// metrics are the counters of the apiserver.
+type metrics struct {
+	listCacheMetrics
+	latencyMetrics
+	watchBufferMetrics
+	Panics            uint64 `json:"panics"`
+	ClientDisconnects uint64 `json:"clientDisconnects"`
+	// Storage has the counters of the instrumented storage, if there is one.
+	Storage *tools.StorageMetrics `json:"storage,omitempty"`
+	// Mirror has the counters of the requests mirrored to a shadow apiserver, if any are.
+	Mirror *MirrorMetrics `json:"mirror,omitempty"`
+}
+
+// handleMetrics writes the counters of the apiserver, e.g. the hits and misses of its list
+// cache, the histograms of the latencies of the steps of requests, the watches dropped and
+// events coalesced for slow clients, the number of requests whose handler panicked, and the
+// number of requests abandoned because their client went away, the latencies of the calls
+// of the instrumented storage and the state of its breaker, and the requests mirrored to a
+// shadow apiserver.
+func (s *APIServer) handleMetrics(w http.ResponseWriter, req *http.Request) {
+	m := metrics{s.lists.metrics(), s.latencies.metrics(), s.watches.metrics(), atomic.LoadUint64(&s.panics), atomic.LoadUint64(&s.aborts), nil, nil}
+	if s.instrumentedStorage != nil {
+		storage := s.instrumentedStorage.Metrics()
+		m.Storage = &storage
+	}
+	if s.mirror != nil {
+		mirror := s.mirror.Metrics()
+		m.Mirror = &mirror
+	}
+	writeRawJSON(http.StatusOK, m, w)
+}
+
+// createOperation creates an operation to process a channel response, waiting up to wait for
+// it to complete, or until the client of the request of ctx goes away. The operation records the resource and name of the object the request of
+// ctx acts on, and the user who made it.
+func (s *APIServer) createOperation(ctx api.Context, resource, name string, out <-chan interface{}, wait time.Duration) *Operation {
+	op := s.ops.NewOperationFor(out, OperationInfo{
+		Resource:  resource,
+		Namespace: ctx.Namespace,
+		Name:      name,
+		Owner:     ctx.User,
+	})
+	if wait > 0 {
+		op.waitFor(wait, ctx.Done)
 	}
 	return op
 }
 
+// operationWait returns how long a request with opts waits for its operation to complete.
+// Synchronous requests wait up to their timeout, whatever wait they ask for; others wait for
+// the duration of their wait parameter, up to s.maxAsyncOpWait, or for s.asyncOpWait if
+// they have none.
+func (s *APIServer) operationWait(opts *RequestOptions) time.Duration {
+	if opts.Sync {
+		return opts.Timeout
+	}
+	if !opts.HasWait {
+		return s.asyncOpWait
+	}
+	if opts.Wait > s.maxAsyncOpWait {
+		return s.maxAsyncOpWait
+	}
+	return opts.Wait
+}
+
 // finishReq finishes up a request, waiting until the operation finishes or, after a timeout, creating an
-// Operation to receive the result and returning its ID down the writer.
-func (s *APIServer) finishReq(op *Operation, w http.ResponseWriter) {
+// Operation to receive the result and returning its ID down the writer, encoded with codec.
+// If the request creates an object in the resource at path created, a completed creation is
+// answered with 201 Created and a Location header with the path of the object, and one
+// still in progress with 202 Accepted and a Location header with the path of the operation.
+// Other requests pass an empty created, and are answered with 200 OK once complete. Results
+// that are a status are answered with its code instead. Requests whose client has gone away
+// aren't answered; their operation goes on.
+func (s *APIServer) finishReq(ctx api.Context, op *Operation, created string, codec Codec, w http.ResponseWriter) {
+	if s.abandoned(ctx, w) {
+		return
+	}
 	obj, complete := op.StatusOrResult()
-	if complete {
-		status := http.StatusOK
-		switch stat := obj.(type) {
-		case api.Status:
-			httplog.LogOf(w).Addf("programmer error: use *api.Status as a result, not api.Status.")
-			if stat.Code != 0 {
-				status = stat.Code
-			}
-		case *api.Status:
-			if stat.Code != 0 {
-				status = stat.Code
-			}
+	if !complete {
+		if len(created) != 0 {
+			w.Header().Set("Location", path.Join(s.prefix, "operations", op.ID))
+		}
+		op.writePending(obj, s.prefix, codec, w)
+		return
+	}
+	status := http.StatusOK
+	if len(created) != 0 {
+		status = http.StatusCreated
+	}
+	switch stat := obj.(type) {
+	case api.Status:
+		httplog.LogOf(w).Addf("programmer error: use *api.Status as a result, not api.Status.")
+		if stat.Code != 0 {
+			status = stat.Code
+		}
+	case *api.Status:
+		if stat.Code != 0 {
+			status = stat.Code
+		}
+	}
+	if len(created) != 0 && status < http.StatusMultipleChoices {
+		name := op.Info.Name
+		if jsonBase, err := api.FindJSONBase(obj); err == nil && len(jsonBase.ID()) != 0 {
+			// Storages may name the objects they create.
+			name = jsonBase.ID()
+		}
+		if len(name) != 0 {
+			w.Header().Set("Location", path.Join(created, name))
 		}
-		writeJSON(status, s.codec, obj, w)
-	} else {
-		writeJSON(http.StatusAccepted, s.codec, obj, w)
 	}
+	writeJSON(status, codec, obj, w)
 }
 
-// writeJSON renders an object as JSON to the response
+// writeJSON renders an object to the response, encoded with codec: as JSON unless codec
+// is a typedCodec of another media type.
 func writeJSON(statusCode int, codec Codec, object interface{}, w http.ResponseWriter) {
 	output, err := codec.Encode(object)
 	if err != nil {
 		errorJSON(err, codec, w)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
+	writeEncoded(statusCode, codec, output, w)
+}
+
+// writeList renders a list to the response, encoded with codec. If s caches lists, the
+// encoding is stored in the cache under key, compact whether or not the list is written
+// indented. Otherwise it is written with the streaming encoder of the codec, if the codec has
+// one and the list isn't to be indented, which stops writing if the client of the request of
+// ctx goes away.
+func (s *APIServer) writeList(ctx api.Context, key listCacheKey, generation uint64, list interface{}, codec typedCodec, w http.ResponseWriter) {
+	if encoder, ok := codec.Codec.(StreamEncoder); ok && s.lists == nil && !codec.pretty {
+		out := cancellableWriter{&statusOnWrite{w: w, status: http.StatusOK, contentType: codec.mediaType}, ctx}
+		if err := encoder.EncodeToStream(out, list); err != nil && !s.abandoned(ctx, w) {
+			errorJSON(err, codec, w)
+		}
+		return
+	}
+	data, err := codec.Encode(list)
+	if err != nil {
+		errorJSON(err, codec, w)
+		return
+	}
+	s.lists.put(key, generation, data)
+	writeEncoded(http.StatusOK, codec, data, w)
+}
+
+// statusOnWrite writes the content type and status of a response just before the first
+// write of its body, so that an encoder that fails before writing can still report an error.
+type statusOnWrite struct {
+	w           http.ResponseWriter
+	status      int
+	contentType string
+	wrote       bool
+}
+
+func (s *statusOnWrite) Write(data []byte) (int, error) {
+	if !s.wrote {
+		s.w.Header().Set("Content-Type", s.contentType)
+		s.w.WriteHeader(s.status)
+		s.wrote = true
+	}
+	return s.w.Write(data)
+}
+
+// writeEncoded writes an object that has already been encoded with codec to the response,
+// indented if the request asked for pretty JSON.
+func writeEncoded(statusCode int, codec Codec, data []byte, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", contentType(codec))
 	w.WriteHeader(statusCode)
-	w.Write(output)
+	w.Write(indent(codec, data))
 }
 
 // errorJSON renders an error to the response
 func errorJSON(err error, codec Codec, w http.ResponseWriter) {
 	status := errToAPIStatus(err)
+	if status.Code == http.StatusServiceUnavailable && status.Details != nil && status.Details.RetryAfterSeconds > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(status.Details.RetryAfterSeconds))
+	}
 	writeJSON(status.Code, codec, status, w)
 }
 
@@ -322,22 +1124,6 @@ func writeRawJSON(statusCode int, object interface{}, w http.ResponseWriter) {
 	w.Write(output)
 }
 
-func parseTimeout(str string) time.Duration {
-	if str != "" {
-		timeout, err := time.ParseDuration(str)
-		if err == nil {
-			return timeout
-		}
-		glog.Errorf("Failed to parse: %#v '%s'", err, str)
-	}
-	return 30 * time.Second
-}
-
-func readBody(req *http.Request) ([]byte, error) {
-	defer req.Body.Close()
-	return ioutil.ReadAll(req.Body)
-}
-
 // splitPath returns the segments for a URL path
 func splitPath(path string) []string {
 	path = strings.Trim(path, "/")
@@ -32,8 +32,10 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+	"github.com/coreos/go-etcd/etcd"
 )
 
 func convert(obj interface{}) (interface{}, error) {
@@ -45,6 +47,16 @@ var codec = api.Codec
 func init() {
 	api.AddKnownTypes("", Simple{}, SimpleList{})
 	api.AddKnownTypes("v1beta1", Simple{}, SimpleList{})
+	api.AddValidator(&Simple{}, validateSimple)
+}
+
+// validateSimple rejects Simples named "invalid".
+func validateSimple(obj interface{}) api.ValidationErrorList {
+	simple := obj.(*Simple)
+	if simple.Name == "invalid" {
+		return api.ValidationErrorList{api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "name", BadValue: simple.Name}}
+	}
+	return nil
 }
 
 type Simple struct {
@@ -64,6 +76,8 @@ type SimpleRESTStorage struct {
 	deleted string
 	updated *Simple
 	created *Simple
+	// The context of the last Get or Create call.
+	ctx api.Context
 
 	// These are set when Watch is called
 	fakeWatch                *watch.FakeWatcher
@@ -76,18 +90,19 @@ type SimpleRESTStorage struct {
 	injectedFunction func(obj interface{}) (returnObj interface{}, err error)
 }
 
-func (storage *SimpleRESTStorage) List(labels.Selector) (interface{}, error) {
+func (storage *SimpleRESTStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	result := &SimpleList{
 		Items: storage.list,
 	}
 	return result, storage.errors["list"]
 }
 
-func (storage *SimpleRESTStorage) Get(id string) (interface{}, error) {
+func (storage *SimpleRESTStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	storage.ctx = ctx
 	return storage.item, storage.errors["get"]
 }
 
-func (storage *SimpleRESTStorage) Delete(id string) (<-chan interface{}, error) {
+func (storage *SimpleRESTStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	storage.deleted = id
 	if err := storage.errors["delete"]; err != nil {
 		return nil, err
@@ -104,7 +119,8 @@ func (storage *SimpleRESTStorage) New() interface{} {
 	return &Simple{}
 }
 
-func (storage *SimpleRESTStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (storage *SimpleRESTStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	storage.ctx = ctx
 	storage.created = obj.(*Simple)
 	if err := storage.errors["create"]; err != nil {
 		return nil, err
@@ -117,7 +133,7 @@ func (storage *SimpleRESTStorage) Create(obj interface{}) (<-chan interface{}, e
 	}), nil
 }
 
-func (storage *SimpleRESTStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (storage *SimpleRESTStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	storage.updated = obj.(*Simple)
 	if err := storage.errors["update"]; err != nil {
 		return nil, err
@@ -131,7 +147,7 @@ func (storage *SimpleRESTStorage) Update(obj interface{}) (<-chan interface{}, e
 }
 
 // Implement ResourceWatcher.
-func (storage *SimpleRESTStorage) Watch(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+func (storage *SimpleRESTStorage) Watch(ctx api.Context, label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
 	storage.requestedLabelSelector = label
 	storage.requestedFieldSelector = field
 	storage.requestedResourceVersion = resourceVersion
@@ -158,17 +174,14 @@ func TestNotFound(t *testing.T) {
 		Path   string
 	}
 	cases := map[string]T{
-		"PATCH method":                 {"PATCH", "/prefix/version/foo"},
-		"GET long prefix":              {"GET", "/prefix/"},
-		"GET missing storage":          {"GET", "/prefix/version/blah"},
-		"GET with extra segment":       {"GET", "/prefix/version/foo/bar/baz"},
-		"POST with extra segment":      {"POST", "/prefix/version/foo/bar"},
-		"DELETE without extra segment": {"DELETE", "/prefix/version/foo"},
-		"DELETE with extra segment":    {"DELETE", "/prefix/version/foo/bar/baz"},
-		"PUT without extra segment":    {"PUT", "/prefix/version/foo"},
-		"PUT with extra segment":       {"PUT", "/prefix/version/foo/bar/baz"},
-		"watch missing storage":        {"GET", "/prefix/version/watch/"},
-		"watch with bad method":        {"POST", "/prefix/version/watch/foo/bar"},
+		"GET long prefix":         {"GET", "/prefix/"},
+		"GET missing storage":     {"GET", "/prefix/version/blah"},
+		"GET missing subresource": {"GET", "/prefix/version/foo/bar/baz"},
+		"PUT missing subresource": {"PUT", "/prefix/version/foo/bar/baz"},
+		"GET with extra segments": {"GET", "/prefix/version/foo/bar/baz/qux"},
+		"DELETE missing storage":  {"DELETE", "/prefix/version/blah/bar"},
+		"watch missing storage":   {"GET", "/prefix/version/watch/"},
+		"watch with bad method":   {"POST", "/prefix/version/watch/foo/bar"},
 	}
 	handler := New(map[string]RESTStorage{
 		"foo": &SimpleRESTStorage{},
@@ -192,6 +205,122 @@ func TestNotFound(t *testing.T) {
 	}
 }
 
+func TestMethodNotAllowed(t *testing.T) {
+	methods := []string{"GET", "POST", "PUT", "DELETE", "PATCH"}
+	allowed := map[string][]string{
+		"/simple":          {"GET", "POST"},
+		"/simple/web":      {"GET", "PUT", "DELETE"},
+		"/simple/web/name": {"GET", "PUT", "POST"},
+	}
+	for path, methodsAllowed := range allowed {
+		for _, method := range methods {
+			storage := &nameStorage{mapStorage{items: map[string]Simple{
+				"web": {JSONBase: api.JSONBase{ID: "web"}, Name: "web"},
+			}}}
+			server := httptest.NewServer(New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version"))
+			body := &Simple{JSONBase: api.JSONBase{ID: "web"}, Name: "web"}
+			if method == "POST" && path == "/simple" {
+				body.ID = "other"
+			}
+			req, err := http.NewRequest(method, server.URL+"/prefix/version"+path+"?sync=true", bytes.NewBufferString(api.EncodeOrDie(body)))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			response, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			response.Body.Close()
+			server.Close()
+
+			if !hasMethod(methodsAllowed, method) {
+				if response.StatusCode != http.StatusMethodNotAllowed {
+					t.Errorf("%s %s: expected %d, got %d", method, path, http.StatusMethodNotAllowed, response.StatusCode)
+				}
+				if e, a := strings.Join(methodsAllowed, ", "), response.Header.Get("Allow"); e != a {
+					t.Errorf("%s %s: expected Allow %q, got %q", method, path, e, a)
+				}
+			} else if method == "POST" && path == "/simple" {
+				if response.StatusCode != http.StatusCreated {
+					t.Errorf("%s %s: expected %d, got %d", method, path, http.StatusCreated, response.StatusCode)
+				}
+			} else if response.StatusCode != http.StatusOK {
+				t.Errorf("%s %s: expected %d, got %d", method, path, http.StatusOK, response.StatusCode)
+			}
+		}
+	}
+}
+
+func TestMethodNotSupportedByStorage(t *testing.T) {
+	storage := &SimpleRESTStorage{errors: map[string]error{"create": NewMethodNotSupported("simple", "create")}}
+	server := httptest.NewServer(New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version"))
+	defer server.Close()
+
+	response, err := http.Post(server.URL+"/prefix/version/simple", "application/json", bytes.NewBufferString(api.EncodeOrDie(&Simple{})))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected %d, got %d", http.StatusMethodNotAllowed, response.StatusCode)
+	}
+}
+
+// readOnlyStorage serves a single Simple, and implements only Getter and Lister.
+type readOnlyStorage struct {
+	item Simple
+}
+
+func (s *readOnlyStorage) New() interface{} {
+	return &Simple{}
+}
+
+func (s *readOnlyStorage) List(ctx api.Context, label labels.Selector) (interface{}, error) {
+	return &SimpleList{Items: []Simple{s.item}}, nil
+}
+
+func (s *readOnlyStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	if id != s.item.ID {
+		return nil, NewNotFoundErr("simple", id)
+	}
+	return &s.item, nil
+}
+
+func TestReadOnlyStorage(t *testing.T) {
+	storage := &readOnlyStorage{Simple{JSONBase: api.JSONBase{ID: "web"}, Name: "web"}}
+	server := httptest.NewServer(New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version"))
+	defer server.Close()
+
+	table := []struct {
+		method, path string
+		code         int
+	}{
+		{"GET", "/simple", http.StatusOK},
+		{"GET", "/simple/web", http.StatusOK},
+		{"GET", "/simple/missing", http.StatusNotFound},
+		{"POST", "/simple", http.StatusMethodNotAllowed},
+		{"PUT", "/simple/web", http.StatusMethodNotAllowed},
+		{"DELETE", "/simple/web", http.StatusMethodNotAllowed},
+	}
+	for _, item := range table {
+		req, err := http.NewRequest(item.method, server.URL+"/prefix/version"+item.path, bytes.NewBufferString(api.EncodeOrDie(&storage.item)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != item.code {
+			t.Errorf("%s %s: expected %d, got %d", item.method, item.path, item.code, response.StatusCode)
+		}
+		if item.code == http.StatusMethodNotAllowed && response.Header.Get("Allow") != "GET" {
+			t.Errorf("%s %s: expected only GET to be allowed, got %q", item.method, item.path, response.Header.Get("Allow"))
+		}
+	}
+}
+
 func TestVersion(t *testing.T) {
 	handler := New(map[string]RESTStorage{}, codec, "/prefix/version")
 	server := httptest.NewServer(handler)
@@ -254,6 +383,23 @@ func TestErrorList(t *testing.T) {
 	}
 }
 
+func TestFieldListUnsupported(t *testing.T) {
+	storage := map[string]RESTStorage{}
+	simpleStorage := SimpleRESTStorage{}
+	storage["simple"] = &simpleStorage
+	handler := New(storage, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Get(server.URL + "/prefix/version/simple?fields=status%3Dfailed")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("Unexpected status: %d, Expected: %d, %#v", resp.StatusCode, http.StatusInternalServerError, resp)
+	}
+}
+
 func TestNonEmptyList(t *testing.T) {
 	storage := map[string]RESTStorage{}
 	simpleStorage := SimpleRESTStorage{
@@ -472,6 +618,246 @@ func TestCreate(t *testing.T) {
 	}
 }
 
+func TestCreateInvalid(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{
+		"foo": simpleStorage,
+	}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	client := http.Client{}
+
+	for _, method := range []string{"POST", "PUT"} {
+		path := "/prefix/version/foo"
+		if method == "PUT" {
+			path += "/bar"
+		}
+		data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}, Name: "invalid"})
+		request, err := http.NewRequest(method, server.URL+path, bytes.NewBuffer(data))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response, err := client.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if response.StatusCode != 422 {
+			t.Errorf("%s: unexpected response %#v", method, response)
+		}
+		var status api.Status
+		body, err := extractBody(response, &status)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := &api.StatusDetails{
+			Kind:   "simple",
+			ID:     "bar",
+			Causes: []api.StatusCause{{Type: api.CauseTypeFieldValueInvalid, Message: "invalid value 'invalid'", Field: "name"}},
+		}
+		if status.Reason != api.ReasonTypeInvalid || !reflect.DeepEqual(status.Details, expected) {
+			t.Errorf("%s: unexpected status: %#v (%s)", method, status, body)
+		}
+	}
+	if simpleStorage.created != nil || simpleStorage.updated != nil {
+		t.Errorf("expected invalid objects not to reach the storage: %#v", simpleStorage)
+	}
+}
+
+func TestCreateInvalidID(t *testing.T) {
+	storage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version")
+	handler.AllowLegacyID("foo", "Legacy")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	invalid := map[string]string{
+		"watch":        "invalid value 'watch': is reserved for a path segment of the API",
+		"Bar":          "invalid value 'Bar': must consist of lowercase letters, digits and '-', starting and ending with a letter or digit",
+		"a/b":          "invalid value 'a/b': must consist of lowercase letters, digits and '-', starting and ending with a letter or digit",
+		"Legacy-other": "invalid value 'Legacy-other': must consist of lowercase letters, digits and '-', starting and ending with a letter or digit",
+	}
+	for id, message := range invalid {
+		data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: id}})
+		status := expectApiStatus(t, "POST", server.URL+"/prefix/version/foo", data, 422)
+		expected := &api.StatusDetails{
+			Kind:   "simple",
+			ID:     id,
+			Causes: []api.StatusCause{{Type: api.CauseTypeFieldValueInvalid, Message: message, Field: "id"}},
+		}
+		if status.Reason != api.ReasonTypeInvalid || !reflect.DeepEqual(status.Details, expected) {
+			t.Errorf("%s: unexpected status: %#v", id, status)
+		}
+	}
+	if storage.created != nil {
+		t.Errorf("expected invalid IDs not to reach the storage: %#v", storage.created)
+	}
+
+	// Legacy IDs may still be created, and existing objects are updated whatever their ID.
+	for _, test := range []struct {
+		method, path, id string
+		code             int
+	}{{"POST", "", "Legacy", http.StatusCreated}, {"PUT", "/Bar", "Bar", http.StatusOK}} {
+		data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: test.id}})
+		request, _ := http.NewRequest(test.method, server.URL+"/prefix/version/foo"+test.path, bytes.NewBuffer(data))
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != test.code {
+			t.Errorf("%s %s: expected success, got %d", test.method, test.id, response.StatusCode)
+		}
+	}
+	if storage.created == nil || storage.created.ID != "Legacy" || storage.updated == nil {
+		t.Errorf("expected the legacy object to be created and the other updated, got %#v", storage)
+	}
+}
+
+// DefaultingStorage names the Simples it creates without a name.
+type DefaultingStorage struct {
+	SimpleRESTStorage
+	name string
+}
+
+func (storage *DefaultingStorage) Default(ctx api.Context, obj interface{}) {
+	simple := obj.(*Simple)
+	if len(simple.Name) == 0 {
+		simple.Name = storage.name
+	}
+}
+
+func TestCreateDefaultsBeforeValidating(t *testing.T) {
+	storage := &DefaultingStorage{name: "invalid"}
+	handler := New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}})
+	status := expectApiStatus(t, "POST", server.URL+"/prefix/version/foo", data, 422)
+	if status.Details == nil || len(status.Details.Causes) != 1 || status.Details.Causes[0].Field != "name" {
+		t.Errorf("expected the defaulted name to be rejected, got %#v", status)
+	}
+	if storage.created != nil {
+		t.Errorf("expected the object not to reach the storage: %#v", storage.created)
+	}
+
+	// Updates are not defaulted.
+	storage.name = "foo"
+	request, _ := http.NewRequest("PUT", server.URL+"/prefix/version/foo/bar", bytes.NewBuffer(data))
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if storage.updated == nil || storage.updated.Name != "" {
+		t.Errorf("expected the update to reach the storage undefaulted, got %#v", storage.updated)
+	}
+}
+
+func TestCreateDryRun(t *testing.T) {
+	storage := &DefaultingStorage{name: "defaulted"}
+	handler := New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}})
+	for _, method := range []string{"POST", "PUT"} {
+		path := server.URL + "/prefix/version/foo"
+		if method == "PUT" {
+			path += "/bar"
+		}
+		request, _ := http.NewRequest(method, path+"?dryRun=true", bytes.NewBuffer(data))
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("%s: unexpected response %#v", method, response)
+		}
+		var out Simple
+		body, err := extractBody(response, &out)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if method == "POST" && out.Name != "defaulted" {
+			t.Errorf("%s: expected the object as it would be created, got %s", method, body)
+		}
+	}
+	if storage.created != nil || storage.updated != nil {
+		t.Errorf("expected a dry run not to reach the storage: %#v", storage)
+	}
+
+	invalid, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}, Name: "invalid"})
+	expectApiStatus(t, "POST", server.URL+"/prefix/version/foo?dryRun=true", invalid, 422)
+}
+
+func TestUpdateCreateIfMissing(t *testing.T) {
+	put := func(server *httptest.Server, path string, obj Simple) *http.Response {
+		data, _ := codec.Encode(obj)
+		request, _ := http.NewRequest("PUT", server.URL+"/prefix/version/foo/"+path, bytes.NewBuffer(data))
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return response
+	}
+	item := Simple{JSONBase: api.JSONBase{ID: "bar"}}
+
+	// The object exists: it is updated.
+	storage := &DefaultingStorage{name: "defaulted"}
+	server := httptest.NewServer(New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version"))
+	defer server.Close()
+	if response := put(server, "bar?createIfMissing=true", item); response.StatusCode != http.StatusOK {
+		t.Errorf("expected an update, got %d", response.StatusCode)
+	}
+	if storage.updated == nil || storage.created != nil {
+		t.Errorf("expected an update, got %#v", storage)
+	}
+
+	// Get finds no object: it is created, and defaulted as it is.
+	storage = &DefaultingStorage{name: "defaulted"}
+	storage.errors = map[string]error{"get": NewNotFoundErr("simple", "bar")}
+	server = httptest.NewServer(New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version"))
+	defer server.Close()
+	if response := put(server, "bar?createIfMissing=true&dryRun=true", item); response.StatusCode != http.StatusCreated {
+		t.Errorf("expected a dry run to tell a create, got %d", response.StatusCode)
+	}
+	if response := put(server, "bar?createIfMissing=true", item); response.StatusCode != http.StatusCreated {
+		t.Errorf("expected a create, got %d", response.StatusCode)
+	}
+	if storage.created == nil || storage.created.Name != "defaulted" || storage.updated != nil {
+		t.Errorf("expected a defaulted create, got %#v", storage)
+	}
+	if response := put(server, "bar", item); response.StatusCode != http.StatusOK || storage.updated == nil {
+		t.Errorf("expected no create without createIfMissing, got %d", response.StatusCode)
+	}
+
+	// Update finds no object after Get found it: the object was admitted as an update, so
+	// it isn't created.
+	storage = &DefaultingStorage{}
+	storage.errors = map[string]error{"update": NewNotFoundErr("simple", "bar")}
+	server = httptest.NewServer(New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version"))
+	defer server.Close()
+	if response := put(server, "bar?createIfMissing=true", item); response.StatusCode != http.StatusNotFound {
+		t.Errorf("expected not found, got %d", response.StatusCode)
+	}
+	if storage.created != nil {
+		t.Errorf("expected no create, got %#v", storage)
+	}
+
+	// The ID in the body must be the one in the path.
+	storage = &DefaultingStorage{}
+	server = httptest.NewServer(New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version"))
+	defer server.Close()
+	for _, obj := range []Simple{{JSONBase: api.JSONBase{ID: "baz"}}, {}} {
+		if response := put(server, "bar?createIfMissing=true", obj); response.StatusCode != http.StatusBadRequest {
+			t.Errorf("expected a mismatched ID %q to be refused, got %d", obj.ID, response.StatusCode)
+		}
+	}
+	if storage.created != nil || storage.updated != nil {
+		t.Errorf("expected mismatched IDs not to reach the storage: %#v", storage)
+	}
+}
+
 func TestCreateNotFound(t *testing.T) {
 	handler := New(map[string]RESTStorage{
 		"simple": &SimpleRESTStorage{
@@ -500,18 +886,6 @@ func TestCreateNotFound(t *testing.T) {
 	}
 }
 
-func TestParseTimeout(t *testing.T) {
-	if d := parseTimeout(""); d != 30*time.Second {
-		t.Errorf("blank timeout produces %v", d)
-	}
-	if d := parseTimeout("not a timeout"); d != 30*time.Second {
-		t.Errorf("bad timeout produces %v", d)
-	}
-	if d := parseTimeout("10s"); d != 10*time.Second {
-		t.Errorf("10s timeout produced: %v", d)
-	}
-}
-
 func TestSyncCreate(t *testing.T) {
 	storage := SimpleRESTStorage{
 		injectedFunction: func(obj interface{}) (interface{}, error) {
@@ -522,6 +896,7 @@ func TestSyncCreate(t *testing.T) {
 	handler := New(map[string]RESTStorage{
 		"foo": &storage,
 	}, codec, "/prefix/version")
+	handler.now = func() time.Time { return time.Date(2014, 7, 1, 12, 0, 0, 0, time.UTC) }
 	server := httptest.NewServer(handler)
 	client := http.Client{}
 
@@ -552,11 +927,74 @@ func TestSyncCreate(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
+	// Objects are created in the default namespace unless the request names another, and
+	// stamped with the time they were created.
+	simple.Namespace = api.NamespaceDefault
+	simple.CreationTimestamp = "2014-07-01T12:00:00Z"
 	if !reflect.DeepEqual(itemOut, simple) {
 		t.Errorf("Unexpected data: %#v, expected %#v (%s)", itemOut, simple, string(body))
 	}
-	if response.StatusCode != http.StatusOK {
-		t.Errorf("Unexpected status: %d, Expected: %d, %#v", response.StatusCode, http.StatusOK, response)
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("Unexpected status: %d, Expected: %d, %#v", response.StatusCode, http.StatusCreated, response)
+	}
+}
+
+func TestCreateLocation(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	table := []struct {
+		path     string
+		result   func(obj interface{}) (interface{}, error)
+		code     int
+		location string
+	}{
+		{"/foo?sync=true", nil, http.StatusCreated, "/prefix/version/foo/bar"},
+		{"/ns/other/foo?sync=true", nil, http.StatusCreated, "/prefix/version/ns/other/foo/bar"},
+		// Storages may name the objects they create.
+		{"/foo?sync=true", func(obj interface{}) (interface{}, error) {
+			return &Simple{JSONBase: api.JSONBase{ID: "named"}}, nil
+		}, http.StatusCreated, "/prefix/version/foo/named"},
+		{"/foo?sync=true", func(obj interface{}) (interface{}, error) {
+			return nil, NewAlreadyExistsErr("foo", "bar")
+		}, http.StatusConflict, ""},
+		{"/foo?wait=1ms", func(obj interface{}) (interface{}, error) {
+			<-block
+			return obj, nil
+		}, http.StatusAccepted, "/prefix/version/operations/"},
+	}
+	for i, item := range table {
+		storage := &SimpleRESTStorage{injectedFunction: item.result}
+		server := httptest.NewServer(New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version"))
+		data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}})
+		response, err := http.Post(server.URL+"/prefix/version"+item.path, "application/json", bytes.NewBuffer(data))
+		server.Close()
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		var status api.Status
+		body, _ := extractBody(response, &status)
+		location := response.Header.Get("Location")
+		if item.code == http.StatusAccepted && status.Details != nil {
+			item.location += status.Details.ID
+		}
+		if response.StatusCode != item.code || location != item.location {
+			t.Errorf("%d: expected %d with Location %q, got %d with %q: %s", i, item.code, item.location, response.StatusCode, location, body)
+		}
+	}
+
+	// Other requests have no Location.
+	storage := &SimpleRESTStorage{}
+	server := httptest.NewServer(New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version"))
+	defer server.Close()
+	data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}})
+	request, _ := http.NewRequest("PUT", server.URL+"/prefix/version/foo/bar?sync=true", bytes.NewBuffer(data))
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK || len(response.Header.Get("Location")) != 0 {
+		t.Errorf("unexpected response %d with Location %q", response.StatusCode, response.Header.Get("Location"))
 	}
 }
 
@@ -631,6 +1069,49 @@ func TestAsyncDelayReturnsError(t *testing.T) {
 	}
 }
 
+func TestAsyncOpWait(t *testing.T) {
+	const slow = 100 * time.Millisecond
+	table := []struct {
+		delay     time.Duration
+		wait, max time.Duration
+		query     string
+		code      int
+	}{
+		{delay: 0, wait: time.Second, max: time.Second, code: http.StatusOK},
+		{delay: slow, wait: time.Millisecond, max: time.Second, code: http.StatusAccepted},
+		{delay: slow, wait: 0, max: time.Second, code: http.StatusAccepted},
+		{delay: slow, wait: time.Millisecond, max: time.Second, query: "?wait=1s", code: http.StatusOK},
+		{delay: slow, wait: time.Second, max: time.Second, query: "?wait=0s", code: http.StatusAccepted},
+		{delay: slow, wait: time.Millisecond, max: time.Millisecond, query: "?wait=1s", code: http.StatusAccepted},
+		{delay: slow, wait: 0, max: 0, query: "?sync=true&wait=0s", code: http.StatusOK},
+		{delay: 0, wait: time.Second, max: time.Second, query: "?wait=soon", code: http.StatusBadRequest},
+		{delay: 0, wait: time.Second, max: time.Second, query: "?wait=-1s", code: http.StatusBadRequest},
+	}
+	for i, item := range table {
+		delay := item.delay
+		storage := SimpleRESTStorage{
+			injectedFunction: func(obj interface{}) (interface{}, error) {
+				time.Sleep(delay)
+				return &api.Status{Status: api.StatusSuccess}, nil
+			},
+		}
+		handler := New(map[string]RESTStorage{"foo": &storage}, codec, "/prefix/version")
+		handler.SetAsyncOpWait(item.wait, item.max)
+		server := httptest.NewServer(handler)
+
+		request, _ := http.NewRequest("DELETE", server.URL+"/prefix/version/foo/bar"+item.query, nil)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		response.Body.Close()
+		if response.StatusCode != item.code {
+			t.Errorf("%d: expected %d, got %d", i, item.code, response.StatusCode)
+		}
+		server.Close()
+	}
+}
+
 func TestAsyncCreateError(t *testing.T) {
 	ch := make(chan struct{})
 	storage := SimpleRESTStorage{
@@ -659,7 +1140,7 @@ func TestAsyncCreateError(t *testing.T) {
 	ch <- struct{}{}
 	time.Sleep(time.Millisecond)
 
-	finalStatus := expectApiStatus(t, "GET", fmt.Sprintf("%s/prefix/version/operations/%s?after=1", server.URL, status.Details.ID), []byte{}, http.StatusOK)
+	finalStatus := expectApiStatus(t, "GET", fmt.Sprintf("%s/prefix/version/operations/%s", server.URL, status.Details.ID), []byte{}, http.StatusOK)
 	expectedErr := NewAlreadyExistsErr("foo", "bar")
 	expectedStatus := &api.Status{
 		Status:  api.StatusFailure,
@@ -737,3 +1218,313 @@ func TestSyncCreateTimeout(t *testing.T) {
 		t.Errorf("Unexpected status %#v", itemOut)
 	}
 }
+
+func TestRequestContextWithoutAuthenticator(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	server := httptest.NewServer(New(map[string]RESTStorage{"simple": simpleStorage}, codec, "/prefix/version"))
+	defer server.Close()
+
+	request, _ := http.NewRequest("GET", server.URL+"/prefix/version/simple/id", nil)
+	request.SetBasicAuth("alice", "anything")
+	if _, err := http.DefaultClient.Do(request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx := simpleStorage.ctx; len(ctx.User) != 0 {
+		t.Errorf("expected an anonymous request, got %#v", ctx)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{"simple": simpleStorage}, codec, "/prefix/version")
+	handler.SetAuthenticator(BasicAuthenticator{"alice": "secret"})
+	server := httptest.NewServer(handler)
+
+	request, _ := http.NewRequest("GET", server.URL+"/prefix/version/simple/id", nil)
+	request.SetBasicAuth("alice", "secret")
+	request.Header.Set("X-Request-Id", "abc")
+	if _, err := http.DefaultClient.Do(request); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx := simpleStorage.ctx; ctx.User != "alice" || ctx.RequestID != "abc" || ctx.HasDeadline() {
+		t.Errorf("unexpected context %#v", ctx)
+	}
+
+	for _, password := range []string{"wrong", ""} {
+		request, _ = http.NewRequest("GET", server.URL+"/prefix/version/simple/id", nil)
+		request.SetBasicAuth("alice", password)
+		if _, err := http.DefaultClient.Do(request); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if ctx := simpleStorage.ctx; len(ctx.User) != 0 {
+			t.Errorf("expected an anonymous request for password %q, got %#v", password, ctx)
+		}
+	}
+
+	data, _ := codec.Encode(&Simple{Name: "foo"})
+	before := time.Now()
+	resp, err := http.Post(server.URL+"/prefix/version/simple?sync=true&timeout=5s", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	ctx := simpleStorage.ctx
+	if len(ctx.User) != 0 || len(ctx.RequestID) == 0 {
+		t.Errorf("expected an anonymous request with a generated id, got %#v", ctx)
+	}
+	if !ctx.HasDeadline() || ctx.Deadline.Before(before.Add(5*time.Second)) || ctx.Deadline.After(time.Now().Add(5*time.Second)) {
+		t.Errorf("expected a deadline in 5s, got %v", ctx.Deadline)
+	}
+}
+
+// ContextlessStorage is a ContextlessRESTStorage that records the id it was asked for.
+type ContextlessStorage struct {
+	SimpleRESTStorage
+	got string
+}
+
+func (storage *ContextlessStorage) List(selector labels.Selector) (interface{}, error) {
+	return storage.SimpleRESTStorage.List(api.NewContext(), selector)
+}
+
+func (storage *ContextlessStorage) Get(id string) (interface{}, error) {
+	storage.got = id
+	return storage.item, nil
+}
+
+func (storage *ContextlessStorage) Delete(id string) (<-chan interface{}, error) {
+	return storage.SimpleRESTStorage.Delete(api.NewContext(), id)
+}
+
+func (storage *ContextlessStorage) Create(obj interface{}) (<-chan interface{}, error) {
+	return storage.SimpleRESTStorage.Create(api.NewContext(), obj)
+}
+
+func (storage *ContextlessStorage) Update(obj interface{}) (<-chan interface{}, error) {
+	return storage.SimpleRESTStorage.Update(api.NewContext(), obj)
+}
+
+func TestIgnoreContext(t *testing.T) {
+	contextless := &ContextlessStorage{SimpleRESTStorage: SimpleRESTStorage{item: Simple{Name: "foo"}}}
+	storage := IgnoreContext(contextless)
+	if _, ok := asResourceWatcher(storage); ok {
+		t.Errorf("expected a storage without Watch not to become a ResourceWatcher")
+	}
+	if _, ok := asDefaulter(storage); ok {
+		t.Errorf("expected a storage without Default not to become a Defaulter")
+	}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Get(server.URL + "/prefix/version/simple/id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var item Simple
+	body, _ := ioutil.ReadAll(resp.Body)
+	if err := codec.DecodeInto(body, &item); err != nil || item.Name != "foo" || contextless.got != "id" {
+		t.Errorf("unexpected response %s (%v), storage got %q", string(body), err, contextless.got)
+	}
+
+	resp, err = http.Get(server.URL + "/prefix/version/simple?fields=name%3Dfoo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected field selectors to be refused, got %d", resp.StatusCode)
+	}
+}
+
+// ReadOnlyContextlessStorage is a ContextlessRESTStorage that can only get objects, and
+// defaults the objects it would create.
+type ReadOnlyContextlessStorage struct {
+	item Simple
+}
+
+func (storage *ReadOnlyContextlessStorage) New() interface{} {
+	return &Simple{}
+}
+
+func (storage *ReadOnlyContextlessStorage) Get(id string) (interface{}, error) {
+	return storage.item, nil
+}
+
+func (storage *ReadOnlyContextlessStorage) Default(obj interface{}) {
+	obj.(*Simple).Name = "defaulted"
+}
+
+func TestIgnoreContextPartialStorage(t *testing.T) {
+	storage := IgnoreContext(&ReadOnlyContextlessStorage{item: Simple{Name: "foo"}})
+	if _, ok := storage.(Getter); ok {
+		t.Errorf("expected the adapter not to implement operations itself")
+	}
+	if _, ok := asGetter(storage); !ok {
+		t.Errorf("expected the Get of the storage to be served")
+	}
+	if defaulter, ok := asDefaulter(storage); !ok {
+		t.Errorf("expected the Default of the storage to be used")
+	} else {
+		item := &Simple{}
+		defaulter.Default(api.NewContext(), item)
+		if item.Name != "defaulted" {
+			t.Errorf("unexpected defaulted object %#v", item)
+		}
+	}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+
+	resp, err := http.Get(server.URL + "/prefix/version/simple/id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	for _, method := range []string{"PUT", "DELETE"} {
+		req, _ := http.NewRequest(method, server.URL+"/prefix/version/simple/id", bytes.NewBufferString("{}"))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Allow") != "GET" {
+			t.Errorf("%s: expected %d allowing GET, got %d allowing %q", method, http.StatusMethodNotAllowed, resp.StatusCode, resp.Header.Get("Allow"))
+		}
+	}
+	for _, method := range []string{"GET", "POST"} {
+		req, _ := http.NewRequest(method, server.URL+"/prefix/version/simple", bytes.NewBufferString("{}"))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("%s: expected %d, got %d", method, http.StatusMethodNotAllowed, resp.StatusCode)
+		}
+	}
+}
+
+// PanickingRESTStorage is a SimpleRESTStorage whose Get panics.
+type PanickingRESTStorage struct {
+	SimpleRESTStorage
+}
+
+func (storage *PanickingRESTStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	panic("secret details of the failure")
+}
+
+// panickingCodec is a Codec whose Encode panics.
+type panickingCodec struct {
+	Codec
+}
+
+func (panickingCodec) Encode(obj interface{}) ([]byte, error) {
+	panic("encoding failed")
+}
+
+func TestPanicStatus(t *testing.T) {
+	for _, c := range []Codec{codec, panickingCodec{codec}} {
+		handler := New(map[string]RESTStorage{"foo": &PanickingRESTStorage{}}, c, "/prefix/version")
+		server := httptest.NewServer(handler)
+
+		request, _ := http.NewRequest("GET", server.URL+"/prefix/version/foo/bar", nil)
+		request.Header.Set("X-Request-Id", "request-1")
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != http.StatusInternalServerError || response.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected response %#v", response)
+		}
+		var status api.Status
+		if err := json.Unmarshal(body, &status); err != nil {
+			t.Fatalf("expected a JSON status, got %v: %s", err, body)
+		}
+		if status.Status != api.StatusFailure || status.Code != http.StatusInternalServerError || status.Reason != api.ReasonTypeInternalError {
+			t.Errorf("unexpected status %#v", status)
+		}
+		if !strings.Contains(status.Message, "request-1") || strings.Contains(string(body), "secret") {
+			t.Errorf("expected the status to name the request and leave out the panic: %s", body)
+		}
+
+		response, err = http.Get(server.URL + "/metrics")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		body, _ = ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		var counters metrics
+		if err := json.Unmarshal(body, &counters); err != nil || counters.Panics != 1 {
+			t.Errorf("expected the panic to be counted, got %s: %v", body, err)
+		}
+		server.Close()
+	}
+}
+
+func TestStorageUnavailable(t *testing.T) {
+	fake := tools.MakeFakeEtcdClient(t)
+	fake.Data["/broken"] = tools.EtcdResponseWithError{R: &etcd.Response{}, E: errors.New("connection refused")}
+	storage := tools.NewInstrumentedEtcdClient(fake)
+	config := DefaultConfig()
+	config.InstrumentedStorage = storage
+	config.StorageBreaker = tools.BreakerConfig{FailureThreshold: 1, Cooldown: 90 * time.Second}
+	simple := &SimpleRESTStorage{}
+	server := httptest.NewServer(NewWithConfig(map[string]RESTStorage{"foo": simple}, codec, "/prefix/version", config))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected a healthy server, got %d", response.StatusCode)
+	}
+
+	// The storage trips the breaker, and fails the calls of the REST storage.
+	if _, err := storage.Get("/broken", false, false); err == nil {
+		t.Fatalf("expected an error")
+	}
+	_, err = storage.Get("/foo/id", false, false)
+	simple.errors = map[string]error{"get": err}
+	response, err = http.Get(server.URL + "/prefix/version/foo/id")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	var status api.Status
+	if err := json.Unmarshal(body, &status); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if response.StatusCode != http.StatusServiceUnavailable || status.Reason != api.ReasonTypeServiceUnavailable || status.Message != "storage unavailable" {
+		t.Errorf("unexpected response %d: %s", response.StatusCode, body)
+	}
+	if response.Header.Get("Retry-After") != "90" || status.Details == nil || status.Details.RetryAfterSeconds != 90 {
+		t.Errorf("expected a retry after the cooldown, got %q: %s", response.Header.Get("Retry-After"), body)
+	}
+
+	response, err = http.Get(server.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ = ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusInternalServerError || !strings.Contains(string(body), "FAILED storage breaker: the storage breaker is open") {
+		t.Errorf("expected the tripped breaker to fail the health check, got %d: %s", response.StatusCode, body)
+	}
+
+	response, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ = ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	var counters metrics
+	if err := json.Unmarshal(body, &counters); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if counters.Storage == nil || counters.Storage.Breaker != tools.BreakerOpen || counters.Storage.Trips != 1 || counters.Storage.Rejected != 1 || counters.Storage.Operations["get"].Failures != 1 {
+		t.Errorf("unexpected storage metrics: %s", body)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package apiserver
 
 import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
@@ -44,3 +45,54 @@ func MakeAsync(fn WorkFunc) <-chan interface{} {
 	}()
 	return channel
 }
+
+// Progress is an intermediate value of an operation. Sent on the channel an operation awaits,
+// it replaces what the operation reports while it is pending, without completing it. Any
+// other value sent on the channel is the final result and completes the operation.
+type Progress struct {
+	Object interface{}
+}
+
+// ProgressFunc reports obj as the progress of an operation.
+type ProgressFunc func(obj interface{})
+
+// ProgressWorkFunc is a WorkFunc which may report progress before returning its result.
+type ProgressWorkFunc func(progress ProgressFunc) (result interface{}, err error)
+
+// MakeAsyncWithProgress is MakeAsync for work that reports its progress, e.g. with
+// ProgressStatus.
+func MakeAsyncWithProgress(fn ProgressWorkFunc) <-chan interface{} {
+	channel := make(chan interface{})
+	go func() {
+		defer util.HandleCrash()
+		obj, err := fn(func(obj interface{}) { channel <- Progress{obj} })
+		if err != nil {
+			channel <- errToAPIStatus(err)
+		} else {
+			channel <- obj
+		}
+		close(channel)
+	}()
+	return channel
+}
+
+// ProgressStatus returns a working Status with message, for reporting the progress of an
+// operation.
+func ProgressStatus(message string) *api.Status {
+	return &api.Status{
+		Status:  api.StatusWorking,
+		Reason:  api.ReasonTypeWorking,
+		Message: message,
+	}
+}
+
+// FinalResult returns the final result sent on a channel returned by a RESTStorage, skipping
+// any progress sent before it.
+func FinalResult(channel <-chan interface{}) interface{} {
+	for obj := range channel {
+		if _, ok := obj.(Progress); !ok {
+			return obj
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// Authenticator verifies the credentials of requests to the apiserver.
+type Authenticator interface {
+	// AuthenticateRequest returns the name of the user whose credentials req carries, and
+	// false if it carries none or they are not valid.
+	AuthenticateRequest(req *http.Request) (user string, ok bool)
+}
+
+// BasicAuthenticator is an Authenticator that checks the basic auth credentials of a
+// request against a map of user names to passwords.
+type BasicAuthenticator map[string]string
+
+// AuthenticateRequest implements Authenticator.
+func (a BasicAuthenticator) AuthenticateRequest(req *http.Request) (string, bool) {
+	user, password, ok := req.BasicAuth()
+	if !ok {
+		return "", false
+	}
+	expected, ok := a[user]
+	if !ok || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+		return "", false
+	}
+	return user, true
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// DefaultBodyLogLimit is the number of bytes of the bodies of invalid requests that are
+// logged, unless another limit is given to SetInvalidBodyLogLimit.
+const DefaultBodyLogLimit = 1024
+
+// bodyLogsPerMinute caps the bodies a bodyLogger logs, so that a misbehaving client can't
+// flood the log.
+const bodyLogsPerMinute = 5
+
+// secretFields matches the values of JSON fields named password or token, which are
+// redacted from logged bodies.
+var secretFields = regexp.MustCompile(`(?i)("(?:password|token)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// bodyLogger logs the start of the bodies of requests that can't be decoded or are
+// invalid, so that the failure a client reports can be debugged from the apiserver log.
+// At most bodyLogsPerMinute bodies are logged each minute.
+// A nil *bodyLogger logs nothing.
+type bodyLogger struct {
+	limit int
+	now   func() time.Time
+	logf  func(format string, args ...interface{})
+
+	lock sync.Mutex
+	// logged counts the bodies logged since windowStart.
+	logged      int
+	windowStart time.Time
+}
+
+// newBodyLogger returns a bodyLogger that logs up to limit bytes of each body.
+func newBodyLogger(limit int) *bodyLogger {
+	return &bodyLogger{
+		limit: limit,
+		now:   time.Now,
+		logf:  glog.Infof,
+	}
+}
+
+// allow returns true if another body may be logged in the current minute.
+func (l *bodyLogger) allow() bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	if now.Sub(l.windowStart) >= time.Minute {
+		l.windowStart = now
+		l.logged = 0
+	}
+	if l.logged >= bodyLogsPerMinute {
+		return false
+	}
+	l.logged++
+	return true
+}
+
+// log logs body, the body of the request requestID, which failed with err. Passwords and
+// tokens are redacted, and only the first bytes are logged.
+func (l *bodyLogger) log(requestID string, body []byte, err error) {
+	if l == nil || !l.allow() {
+		return
+	}
+	redacted := redactBody(body)
+	if len(redacted) > l.limit {
+		l.logf("Request %s failed with %v, first %d of %d bytes of the body: %q", requestID, err, l.limit, len(redacted), redacted[:l.limit])
+		return
+	}
+	l.logf("Request %s failed with %v, body: %q", requestID, err, redacted)
+}
+
+// redactBody returns body with the values of password and token fields replaced. It is
+// redacted before it is truncated, so that a secret cut in half is still found.
+func redactBody(body []byte) []byte {
+	return secretFields.ReplaceAll(body, []byte(`$1"<redacted>"`))
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactBody(t *testing.T) {
+	table := map[string]string{
+		`{"id":"a","password":"hunter2"}`:             `{"id":"a","password":"<redacted>"}`,
+		`{"Token" : "a\"b", "user": "me"}`:            `{"Token" : "<redacted>", "user": "me"}`,
+		`{"auth":{"password":"x","token":"y"}}`:       `{"auth":{"password":"<redacted>","token":"<redacted>"}}`,
+		`{"passwordHint":"x","tokens":"y"}`:           `{"passwordHint":"x","tokens":"y"}`,
+		`{"id":"a","password":"unterminated, invalid`: `{"id":"a","password":"unterminated, invalid`,
+	}
+	for body, expected := range table {
+		if actual := string(redactBody([]byte(body))); actual != expected {
+			t.Errorf("%s: expected %s, got %s", body, expected, actual)
+		}
+	}
+}
+
+func TestBodyLoggerLimits(t *testing.T) {
+	now := time.Unix(0, 0)
+	lines := []string{}
+	logger := newBodyLogger(8)
+	logger.now = func() time.Time { return now }
+	logger.logf = func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	failure := errors.New("bad")
+
+	logger.log("req-1", []byte(`{"id":"abcdefgh"}`), failure)
+	if len(lines) != 1 || !strings.Contains(lines[0], "req-1") || !strings.Contains(lines[0], "first 8 of 17 bytes") || !strings.Contains(lines[0], `"{\"id\":\"a"`) {
+		t.Errorf("unexpected log %q", lines)
+	}
+	for i := 1; i < bodyLogsPerMinute+2; i++ {
+		logger.log("req", []byte("{}"), failure)
+	}
+	if len(lines) != bodyLogsPerMinute {
+		t.Errorf("expected %d bodies to be logged in a minute, got %d", bodyLogsPerMinute, len(lines))
+	}
+	now = now.Add(time.Minute)
+	logger.log("req-2", []byte("{}"), failure)
+	if len(lines) != bodyLogsPerMinute+1 || !strings.Contains(lines[len(lines)-1], "req-2") {
+		t.Errorf("expected bodies to be logged again the next minute, got %q", lines)
+	}
+
+	// A nil bodyLogger logs nothing.
+	var disabled *bodyLogger
+	disabled.log("req", []byte("{}"), failure)
+}
+
+func TestInvalidBodiesAreLogged(t *testing.T) {
+	handler := New(map[string]RESTStorage{"foo": &SimpleRESTStorage{}}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	post := func(requestID, body string) {
+		request, _ := http.NewRequest("POST", server.URL+"/prefix/version/foo", bytes.NewBufferString(body))
+		request.Header.Set("X-Request-Id", requestID)
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+	}
+
+	// Nothing is logged by default.
+	post("req-0", `{"kind":`)
+	if handler.invalidBodies != nil {
+		t.Fatalf("expected bodies not to be logged by default")
+	}
+
+	handler.SetInvalidBodyLogLimit(DefaultBodyLogLimit)
+	lines := []string{}
+	handler.invalidBodies.logf = func(format string, args ...interface{}) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	post("req-1", `{"kind":`)
+	post("req-2", `{"kind":"Simple","id":"bar","name":"invalid","password":"hunter2"}`)
+	post("req-3", `{"kind":"Simple","id":"bar"}`)
+	if len(lines) != 2 {
+		t.Fatalf("expected the bodies of the failed requests to be logged, got %q", lines)
+	}
+	if !strings.Contains(lines[0], "req-1") || !strings.Contains(lines[0], `"{\"kind\":"`) {
+		t.Errorf("unexpected log of the body that can't be decoded: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "req-2") || !strings.Contains(lines[1], "<redacted>") || strings.Contains(lines[1], "hunter2") {
+		t.Errorf("unexpected log of the invalid body: %s", lines[1])
+	}
+}
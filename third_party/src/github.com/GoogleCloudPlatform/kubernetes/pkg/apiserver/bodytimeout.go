@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// DefaultBodyReadTimeout is how long clients have to send the body of a request, unless
+// another timeout is given to SetBodyReadTimeout.
+const DefaultBodyReadTimeout = 30 * time.Second
+
+// errDeadlineExceeded is returned by readAllWithin when the timeout passes.
+var errDeadlineExceeded = errors.New("deadline exceeded")
+
+type readResult struct {
+	data []byte
+	err  error
+}
+
+// readAllWithin reads r to its end, or returns errDeadlineExceeded if that takes longer than
+// timeout, so that a client sending a body slowly can't hold up its handler. The body is read
+// by a single goroutine into a buffer of its own; if the timeout passes, that goroutine is left
+// blocked on r until the connection of the request is closed.
+func readAllWithin(r io.Reader, timeout time.Duration) ([]byte, error) {
+	done := make(chan readResult, 1)
+	go func() {
+		data, err := ioutil.ReadAll(r)
+		done <- readResult{data, err}
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result := <-done:
+		return result.data, result.err
+	case <-timer.C:
+		return nil, errDeadlineExceeded
+	}
+}
+
+// readBody reads the body of req, which must be received within s.bodyReadTimeout if it is
+// positive. A body that takes longer is answered with 408 Request Timeout, and the connection
+// is closed after the answer rather than waiting for the rest of the body. Watches and the
+// minion proxy don't read bodies this way, as they hold their connections open on purpose.
+func (s *APIServer) readBody(req *http.Request, w http.ResponseWriter) ([]byte, error) {
+	if s.bodyReadTimeout <= 0 {
+		defer req.Body.Close()
+		return ioutil.ReadAll(req.Body)
+	}
+	body, err := readAllWithin(req.Body, s.bodyReadTimeout)
+	if err == errDeadlineExceeded {
+		// Closing the body would wait for the rest of it; the server closes the connection
+		// instead once the answer is sent, which ends the read left behind.
+		w.Header().Set("Connection", "close")
+		return nil, NewRequestTimeoutErr(s.bodyReadTimeout)
+	}
+	req.Body.Close()
+	return body, err
+}
+
+// SetBodyReadTimeout makes s answer requests whose body it doesn't receive within timeout
+// with 408 Request Timeout. A timeout that isn't positive waits for bodies forever.
+func (s *APIServer) SetBodyReadTimeout(timeout time.Duration) {
+	s.bodyReadTimeout = timeout
+}
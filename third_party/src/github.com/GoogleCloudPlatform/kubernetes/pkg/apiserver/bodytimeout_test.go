@@ -0,0 +1,99 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestReadAllWithin(t *testing.T) {
+	r, w := io.Pipe()
+	go func() {
+		w.Write([]byte("a"))
+		w.Write([]byte("b"))
+		w.Close()
+	}()
+	if data, err := readAllWithin(r, time.Second); string(data) != "ab" || err != nil {
+		t.Errorf("expected the whole body, got %q %v", data, err)
+	}
+
+	r, w = io.Pipe()
+	defer w.Close()
+	go w.Write([]byte("a"))
+	// Nothing more is written.
+	if _, err := readAllWithin(r, 100*time.Millisecond); err != errDeadlineExceeded {
+		t.Errorf("expected the deadline to pass, got %v", err)
+	}
+}
+
+func TestSlowBodyTimesOut(t *testing.T) {
+	handler := New(map[string]RESTStorage{"simple": &SimpleRESTStorage{}}, codec, "/prefix/version")
+	handler.SetBodyReadTimeout(100 * time.Millisecond)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("POST /prefix/version/simple HTTP/1.1\r\nHost: test\r\nContent-Length: 100\r\n\r\n{")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Dribble the body a byte at a time, well past the timeout.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for i := 0; i < 50; i++ {
+			select {
+			case <-stop:
+				return
+			case <-time.After(20 * time.Millisecond):
+			}
+			if _, err := conn.Write([]byte(" ")); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	response, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusRequestTimeout || !response.Close {
+		t.Errorf("unexpected response: %#v", response)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	var status api.Status
+	if err := codec.DecodeInto(body, &status); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if status.Reason != api.ReasonTypeTimeout {
+		t.Errorf("unexpected status: %#v", status)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// typedCodec is a Codec with the media type of its encodings, which responses are labeled
+// with in their Content-Type.
+type typedCodec struct {
+	Codec
+	mediaType string
+	// pretty is true if JSON responses are indented for people to read. Codecs encode compact
+	// JSON, which writeEncoded indents.
+	pretty bool
+}
+
+// contentType returns the media type of the encodings of codec. Codecs other than
+// typedCodecs encode JSON.
+func contentType(codec Codec) string {
+	if typed, ok := codec.(typedCodec); ok {
+		return typed.mediaType
+	}
+	return api.JSONMediaType
+}
+
+// indent returns data, as encoded by codec, indented if codec is a pretty typedCodec of JSON.
+func indent(codec Codec, data []byte) []byte {
+	typed, ok := codec.(typedCodec)
+	if !ok || !typed.pretty || typed.mediaType != api.JSONMediaType {
+		return data
+	}
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, data, "", "  "); err != nil {
+		return data
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// requestCodecs are the codecs negotiated for a request. Each direction is negotiated on
+// its own, so a client may e.g. send a binary body and read a JSON answer.
+type requestCodecs struct {
+	// in decodes the body of the request, and is picked by its Content-Type.
+	in Codec
+	// out encodes the answer, and is picked by the Accept header of the request.
+	out typedCodec
+}
+
+// AddCodec makes s decode request bodies whose Content-Type is mediaType with codec, and
+// encode the answers to requests that accept mediaType with it. The codec passed to New
+// is registered for "application/json", and used for requests that name no media type s
+// has a codec for.
+func (s *APIServer) AddCodec(mediaType string, codec Codec) {
+	s.codecs[mediaType] = codec
+}
+
+// negotiate picks the codecs of req. The answer is encoded with the codec of the first media
+// type in the Accept header that s has a codec for, regardless of its quality factor, or as
+// JSON if there is none. JSON answers are compact, unless the request has the parameter
+// pretty=true, or accepts the media type with the parameter pretty=true, e.g.
+// "application/json; pretty=true". Bodies of POST and PUT requests without a Content-Type
+// are decoded as JSON, and a Content-Type s has no codec for is an error, which is encoded
+// with the negotiated codec.
+func (s *APIServer) negotiate(req *http.Request) (requestCodecs, error) {
+	codecs := requestCodecs{in: s.codec, out: typedCodec{Codec: s.codec, mediaType: api.JSONMediaType}}
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(accepted)
+		if err != nil {
+			continue
+		}
+		if codec, ok := s.codecs[mediaType]; ok {
+			codecs.out = typedCodec{codec, mediaType, params["pretty"] == "true"}
+			break
+		}
+	}
+	if req.URL.Query().Get("pretty") == "true" {
+		codecs.out.pretty = true
+	}
+	header := req.Header.Get("Content-Type")
+	if len(header) == 0 || (req.Method != "POST" && req.Method != "PUT") {
+		return codecs, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return codecs, NewUnsupportedMediaTypeErr(header)
+	}
+	codec, ok := s.codecs[mediaType]
+	if !ok {
+		return codecs, NewUnsupportedMediaTypeErr(mediaType)
+	}
+	codecs.in = codec
+	return codecs, nil
+}
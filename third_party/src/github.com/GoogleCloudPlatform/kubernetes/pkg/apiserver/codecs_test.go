@@ -0,0 +1,198 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestNegotiatedCodecs(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{list: []Simple{{Name: "a"}}}
+	handler := New(map[string]RESTStorage{"foo": simpleStorage}, codec, "/prefix/version")
+	handler.AddCodec(api.GobMediaType, api.GobCodec)
+	handler.SetAsyncOpWait(time.Second, time.Second)
+	handler.SetListCacheTTL(time.Minute)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	jsonBody, _ := codec.Encode(&Simple{Name: "json"})
+	gobBody, _ := api.GobCodec.Encode(&Simple{Name: "gob"})
+	table := []struct {
+		method, path string
+		contentType  string
+		accept       string
+		body         []byte
+
+		expectedCode        int
+		expectedContentType string
+		expectedName        string
+	}{
+		{"POST", "/foo", api.JSONMediaType, "", jsonBody, http.StatusCreated, api.JSONMediaType, "json"},
+		{"POST", "/foo", "", "", jsonBody, http.StatusCreated, api.JSONMediaType, "json"},
+		{"POST", "/foo", api.GobMediaType, api.GobMediaType, gobBody, http.StatusCreated, api.GobMediaType, "gob"},
+		// Each direction is negotiated on its own.
+		{"POST", "/foo", api.GobMediaType, api.JSONMediaType, gobBody, http.StatusCreated, api.JSONMediaType, "gob"},
+		{"POST", "/foo", api.JSONMediaType, api.GobMediaType, jsonBody, http.StatusCreated, api.GobMediaType, "json"},
+		// The first media type with a codec is picked.
+		{"POST", "/foo", "", "text/html, " + api.GobMediaType + ";q=0.5, application/json", jsonBody, http.StatusCreated, api.GobMediaType, "json"},
+		{"POST", "/foo", "", "text/html", jsonBody, http.StatusCreated, api.JSONMediaType, "json"},
+		// Lists are cached apart for each media type.
+		{"GET", "/foo", "", api.GobMediaType, nil, http.StatusOK, api.GobMediaType, ""},
+		{"GET", "/foo", "", "", nil, http.StatusOK, api.JSONMediaType, ""},
+		{"GET", "/foo", "", api.GobMediaType, nil, http.StatusOK, api.GobMediaType, ""},
+	}
+	for i, item := range table {
+		request, _ := http.NewRequest(item.method, server.URL+"/prefix/version"+item.path, bytes.NewReader(item.body))
+		if len(item.contentType) > 0 {
+			request.Header.Set("Content-Type", item.contentType)
+		}
+		if len(item.accept) > 0 {
+			request.Header.Set("Accept", item.accept)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if response.StatusCode != item.expectedCode || response.Header.Get("Content-Type") != item.expectedContentType {
+			t.Errorf("%d: unexpected response %d %s: %q", i, response.StatusCode, response.Header.Get("Content-Type"), body)
+			continue
+		}
+		responseCodec := api.CodecForMediaType(item.expectedContentType)
+		if item.method == "GET" {
+			var list SimpleList
+			if err := responseCodec.DecodeInto(body, &list); err != nil || len(list.Items) != 1 || list.Items[0].Name != "a" {
+				t.Errorf("%d: unexpected list %#v: %v", i, list, err)
+			}
+			continue
+		}
+		var simple Simple
+		if err := responseCodec.DecodeInto(body, &simple); err != nil || simple.Name != item.expectedName {
+			t.Errorf("%d: unexpected object %#v: %v", i, simple, err)
+		}
+	}
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{"foo": simpleStorage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	gobBody, _ := api.GobCodec.Encode(&Simple{Name: "gob"})
+	response, err := http.Post(server.URL+"/prefix/version/foo", api.GobMediaType, bytes.NewReader(gobBody))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var status api.Status
+	body, err := extractBody(response, &status)
+	if err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if response.StatusCode != http.StatusUnsupportedMediaType || status.Reason != api.ReasonTypeUnsupportedMediaType {
+		t.Errorf("unexpected response %d: %#v", response.StatusCode, status)
+	}
+	if simpleStorage.created != nil {
+		t.Errorf("expected nothing to be created, got %#v", simpleStorage.created)
+	}
+
+	// GETs have no body, so their Content-Type is ignored.
+	request, _ := http.NewRequest("GET", server.URL+"/prefix/version/foo", nil)
+	request.Header.Set("Content-Type", "text/plain")
+	response, err = http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("unexpected response %d", response.StatusCode)
+	}
+}
+
+func TestPrettyJSON(t *testing.T) {
+	items := []Simple{}
+	for i := 0; i < 500; i++ {
+		items = append(items, Simple{Name: fmt.Sprintf("item-%d", i)})
+	}
+	for _, cached := range []bool{false, true} {
+		handler := New(map[string]RESTStorage{"foo": &SimpleRESTStorage{list: items, item: items[1]}}, codec, "/prefix/version")
+		if cached {
+			handler.SetListCacheTTL(time.Minute)
+		}
+		server := httptest.NewServer(handler)
+
+		get := func(path, accept string) string {
+			request, _ := http.NewRequest("GET", server.URL+"/prefix/version"+path, nil)
+			if len(accept) > 0 {
+				request.Header.Set("Accept", accept)
+			}
+			response, err := http.DefaultClient.Do(request)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer response.Body.Close()
+			body, _ := ioutil.ReadAll(response.Body)
+			if response.Header.Get("Content-Type") != api.JSONMediaType {
+				t.Fatalf("unexpected response %d %s: %s", response.StatusCode, response.Header.Get("Content-Type"), body)
+			}
+			return string(body)
+		}
+
+		// Lists are compact whether they are streamed or cached, and before and after an
+		// indented copy of them is asked for.
+		first := get("/foo", "")
+		pretty := get("/foo?pretty=true", "")
+		again := get("/foo", "")
+		for _, body := range []string{first, again} {
+			compact := &bytes.Buffer{}
+			if err := json.Compact(compact, []byte(body)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if size := len(strings.TrimSpace(body)); size != compact.Len() {
+				t.Errorf("cached=%t: expected a compact list of %d bytes, got %d", cached, compact.Len(), size)
+			}
+		}
+		if len(pretty) <= len(first) {
+			t.Errorf("cached=%t: expected an indented list, got %d bytes for %d compact ones", cached, len(pretty), len(first))
+		}
+		for _, body := range []string{
+			pretty,
+			get("/foo", "application/json; pretty=true"),
+			get("/foo/item-1?pretty=true", ""),
+			get("/foo/item-1?pretty=true&frobnicate=true", ""),
+		} {
+			if !strings.Contains(body, "\n  \"") {
+				t.Errorf("cached=%t: expected indented JSON, got %s", cached, body)
+			}
+		}
+		if body := get("/foo/item-1", ""); strings.Contains(strings.TrimSpace(body), "\n") {
+			t.Errorf("cached=%t: expected compact JSON, got %s", cached, body)
+		}
+		server.Close()
+	}
+}
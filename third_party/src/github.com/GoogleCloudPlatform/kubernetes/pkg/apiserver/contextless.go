@@ -0,0 +1,208 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// ContextlessRESTStorage is RESTStorage as it was before its methods were passed an
+// api.Context. Storages that have no use for the context can keep implementing it, and the
+// contextless interfaces of the operations they support, and be served with IgnoreContext.
+type ContextlessRESTStorage interface {
+	New() interface{}
+}
+
+// ContextlessLister is Lister without the api.Context.
+type ContextlessLister interface {
+	List(labels.Selector) (interface{}, error)
+}
+
+// ContextlessGetter is Getter without the api.Context.
+type ContextlessGetter interface {
+	Get(id string) (interface{}, error)
+}
+
+// ContextlessDeleter is Deleter without the api.Context.
+type ContextlessDeleter interface {
+	Delete(id string) (<-chan interface{}, error)
+}
+
+// ContextlessCreater is Creater without the api.Context.
+type ContextlessCreater interface {
+	Create(interface{}) (<-chan interface{}, error)
+}
+
+// ContextlessUpdater is Updater without the api.Context.
+type ContextlessUpdater interface {
+	Update(interface{}) (<-chan interface{}, error)
+}
+
+// ContextlessResourceWatcher is ResourceWatcher without the api.Context.
+type ContextlessResourceWatcher interface {
+	Watch(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
+}
+
+// ContextlessResourceFieldLister is ResourceFieldLister without the api.Context.
+type ContextlessResourceFieldLister interface {
+	ListFields(label, field labels.Selector) (interface{}, error)
+}
+
+// ContextlessDefaulter is Defaulter without the api.Context.
+type ContextlessDefaulter interface {
+	Default(obj interface{})
+}
+
+// IgnoreContext adapts storage to RESTStorage by dropping the api.Context of each call. The
+// apiserver serves exactly the operations whose contextless interfaces storage implements.
+// The adapter itself only has New; the apiserver looks through it for the operations.
+func IgnoreContext(storage ContextlessRESTStorage) RESTStorage {
+	return &contextless{storage}
+}
+
+type contextless struct {
+	storage ContextlessRESTStorage
+}
+
+func (c *contextless) New() interface{} {
+	return c.storage.New()
+}
+
+// The as* functions return the implementation of an operation interface by storage: storage
+// itself, or an adapter of the storage wrapped by IgnoreContext. ok is false if there is none.
+
+func asLister(storage RESTStorage) (Lister, bool) {
+	if c, ok := storage.(*contextless); ok {
+		lister, ok := c.storage.(ContextlessLister)
+		return contextlessLister{lister}, ok
+	}
+	lister, ok := storage.(Lister)
+	return lister, ok
+}
+
+func asGetter(storage RESTStorage) (Getter, bool) {
+	if c, ok := storage.(*contextless); ok {
+		getter, ok := c.storage.(ContextlessGetter)
+		return contextlessGetter{getter}, ok
+	}
+	getter, ok := storage.(Getter)
+	return getter, ok
+}
+
+func asDeleter(storage RESTStorage) (Deleter, bool) {
+	if c, ok := storage.(*contextless); ok {
+		deleter, ok := c.storage.(ContextlessDeleter)
+		return contextlessDeleter{deleter}, ok
+	}
+	deleter, ok := storage.(Deleter)
+	return deleter, ok
+}
+
+func asCreater(storage RESTStorage) (Creater, bool) {
+	if c, ok := storage.(*contextless); ok {
+		creater, ok := c.storage.(ContextlessCreater)
+		return contextlessCreater{creater}, ok
+	}
+	creater, ok := storage.(Creater)
+	return creater, ok
+}
+
+func asUpdater(storage RESTStorage) (Updater, bool) {
+	if c, ok := storage.(*contextless); ok {
+		updater, ok := c.storage.(ContextlessUpdater)
+		return contextlessUpdater{updater}, ok
+	}
+	updater, ok := storage.(Updater)
+	return updater, ok
+}
+
+func asResourceWatcher(storage RESTStorage) (ResourceWatcher, bool) {
+	if c, ok := storage.(*contextless); ok {
+		watcher, ok := c.storage.(ContextlessResourceWatcher)
+		return contextlessWatcher{watcher}, ok
+	}
+	watcher, ok := storage.(ResourceWatcher)
+	return watcher, ok
+}
+
+func asResourceFieldLister(storage RESTStorage) (ResourceFieldLister, bool) {
+	if c, ok := storage.(*contextless); ok {
+		lister, ok := c.storage.(ContextlessResourceFieldLister)
+		return contextlessFieldLister{lister}, ok
+	}
+	lister, ok := storage.(ResourceFieldLister)
+	return lister, ok
+}
+
+func asDefaulter(storage RESTStorage) (Defaulter, bool) {
+	if c, ok := storage.(*contextless); ok {
+		defaulter, ok := c.storage.(ContextlessDefaulter)
+		return contextlessDefaulter{defaulter}, ok
+	}
+	defaulter, ok := storage.(Defaulter)
+	return defaulter, ok
+}
+
+type contextlessLister struct{ ContextlessLister }
+
+func (c contextlessLister) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	return c.ContextlessLister.List(selector)
+}
+
+type contextlessGetter struct{ ContextlessGetter }
+
+func (c contextlessGetter) Get(ctx api.Context, id string) (interface{}, error) {
+	return c.ContextlessGetter.Get(id)
+}
+
+type contextlessDeleter struct{ ContextlessDeleter }
+
+func (c contextlessDeleter) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
+	return c.ContextlessDeleter.Delete(id)
+}
+
+type contextlessCreater struct{ ContextlessCreater }
+
+func (c contextlessCreater) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	return c.ContextlessCreater.Create(obj)
+}
+
+type contextlessUpdater struct{ ContextlessUpdater }
+
+func (c contextlessUpdater) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	return c.ContextlessUpdater.Update(obj)
+}
+
+type contextlessWatcher struct{ ContextlessResourceWatcher }
+
+func (c contextlessWatcher) Watch(ctx api.Context, label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.ContextlessResourceWatcher.Watch(label, field, resourceVersion)
+}
+
+type contextlessFieldLister struct{ ContextlessResourceFieldLister }
+
+func (c contextlessFieldLister) ListFields(ctx api.Context, label, field labels.Selector) (interface{}, error) {
+	return c.ContextlessResourceFieldLister.ListFields(label, field)
+}
+
+type contextlessDefaulter struct{ ContextlessDefaulter }
+
+func (c contextlessDefaulter) Default(ctx api.Context, obj interface{}) {
+	c.ContextlessDefaulter.Default(obj)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/httplog"
+)
+
+// clientGone returns a channel that is closed when the client of the request answered with w
+// goes away, and a func to call once the request is answered, which stops watching the
+// client. The channel is nil, and never closed, if w can't tell when its client goes away.
+// A request's client may only be watched once, as w tells only one receiver.
+func clientGone(w http.ResponseWriter) (<-chan struct{}, func()) {
+	cn, ok := httplog.Unlogged(w).(http.CloseNotifier)
+	if !ok {
+		return nil, func() {}
+	}
+	closed := cn.CloseNotify()
+	gone := make(chan struct{})
+	answered := make(chan struct{})
+	go func() {
+		select {
+		case <-closed:
+			close(gone)
+		case <-answered:
+		}
+	}()
+	return gone, func() { close(answered) }
+}
+
+// abandoned returns true if the client of the request of ctx has gone away, in which case
+// the request is counted as aborted and nothing more should be done to answer it.
+func (s *APIServer) abandoned(ctx api.Context, w http.ResponseWriter) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	atomic.AddUint64(&s.aborts, 1)
+	httplog.LogOf(w).Addf("client went away, request %s abandoned", ctx.RequestID)
+	return true
+}
+
+// cancellableWriter is a writer that fails once the client of the request of ctx has gone
+// away, so that streaming encoders stop writing what nobody will read.
+type cancellableWriter struct {
+	io.Writer
+	ctx api.Context
+}
+
+func (w cancellableWriter) Write(data []byte) (int, error) {
+	if err := w.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return w.Writer.Write(data)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// slowListStorage is a SimpleRESTStorage whose lists take until the client goes away.
+type slowListStorage struct {
+	SimpleRESTStorage
+	listing   chan struct{}
+	cancelled chan error
+}
+
+func (storage *slowListStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	close(storage.listing)
+	select {
+	case <-ctx.Done:
+		storage.cancelled <- ctx.Err()
+		return nil, ctx.Err()
+	case <-time.After(10 * time.Second):
+		storage.cancelled <- nil
+		return &SimpleList{}, nil
+	}
+}
+
+// sendAndHangUp sends request to server and closes the connection once ready is closed,
+// without reading the answer.
+func sendAndHangUp(t *testing.T, server *httptest.Server, request string, ready <-chan struct{}) {
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-ready
+	conn.Close()
+}
+
+// expectAborts waits for handler to count aborts requests abandoned by their client.
+func expectAborts(t *testing.T, handler *APIServer, aborts uint64) {
+	for i := 0; i < 500; i++ {
+		if atomic.LoadUint64(&handler.aborts) == aborts {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("expected %d aborted requests, got %d", aborts, atomic.LoadUint64(&handler.aborts))
+}
+
+func TestClientDisconnectCancelsList(t *testing.T) {
+	storage := &slowListStorage{listing: make(chan struct{}), cancelled: make(chan error, 1)}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	sendAndHangUp(t, server, "GET /prefix/version/simple HTTP/1.1\r\nHost: test\r\n\r\n", storage.listing)
+	if err := <-storage.cancelled; err != api.ErrCancelled {
+		t.Errorf("expected the list to be cancelled, got %v", err)
+	}
+	expectAborts(t, handler, 1)
+}
+
+func TestClientDisconnectStopsWaitingForOperation(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	storage := &SimpleRESTStorage{
+		injectedFunction: func(obj interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return obj, nil
+		},
+	}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	body := `{"kind": "Simple", "id": "foo"}`
+	request := fmt.Sprintf("POST /prefix/version/simple?sync=true HTTP/1.1\r\nHost: test\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+	sendAndHangUp(t, server, request, started)
+	// The request is abandoned while the operation is still blocked.
+	expectAborts(t, handler, 1)
+}
+
+func TestCancellableWriter(t *testing.T) {
+	done := make(chan struct{})
+	out := &strings.Builder{}
+	w := cancellableWriter{out, api.Context{Done: done}}
+	if _, err := w.Write([]byte("a")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	close(done)
+	if _, err := w.Write([]byte("b")); err != api.ErrCancelled {
+		t.Errorf("expected the write to be cancelled, got %v", err)
+	}
+	if out.String() != "a" {
+		t.Errorf("expected only the write before the cancellation, got %q", out.String())
+	}
+}
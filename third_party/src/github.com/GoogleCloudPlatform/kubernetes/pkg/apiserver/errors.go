@@ -19,6 +19,8 @@ package apiserver
 import (
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
@@ -76,6 +78,179 @@ func NewConflictErr(kind, name string, err error) error {
 	}}
 }
 
+// NewFieldConflictErr returns an error indicating the item can't be created or updated as
+// provided because its field conflicts with the same field of other, another item of the
+// same kind, as err explains.
+func NewFieldConflictErr(kind, name, field, other string, err error) error {
+	return &apiServerError{api.Status{
+		Status: api.StatusFailure,
+		Code:   http.StatusConflict,
+		Reason: api.ReasonTypeConflict,
+		Details: &api.StatusDetails{
+			Kind: kind,
+			ID:   name,
+			Causes: []api.StatusCause{{
+				Type:    api.CauseTypeFieldValueConflict,
+				Field:   field,
+				Message: fmt.Sprintf("conflicts with %s %q: %v", kind, other, err),
+			}},
+		},
+		Message: fmt.Sprintf("%s %q conflicts with %s %q: %v", kind, name, kind, other, err),
+	}}
+}
+
+// NewForbiddenErr returns an error indicating the request for the item was refused
+// because of err, e.g. by an Admission.
+func NewForbiddenErr(kind, name string, err error) error {
+	return &apiServerError{api.Status{
+		Status: api.StatusFailure,
+		Code:   http.StatusForbidden,
+		Reason: api.ReasonTypeForbidden,
+		Details: &api.StatusDetails{
+			Kind: kind,
+			ID:   name,
+		},
+		Message: fmt.Sprintf("%s %q is forbidden: %v", kind, name, err),
+	}}
+}
+
+// NewMethodNotSupported returns an error indicating that resources of the kind do not
+// support action, e.g. because they are read-only.
+func NewMethodNotSupported(kind, action string) error {
+	return &apiServerError{api.Status{
+		Status: api.StatusFailure,
+		Code:   http.StatusMethodNotAllowed,
+		Reason: api.ReasonTypeMethodNotAllowed,
+		Details: &api.StatusDetails{
+			Kind: kind,
+			ID:   action,
+		},
+		Message: fmt.Sprintf("%s is not supported on resources of kind %q", action, kind),
+	}}
+}
+
+// NewBadRequestErr returns an error indicating the request for the item is malformed
+// because of err.
+func NewBadRequestErr(kind, name string, err error) error {
+	return &apiServerError{api.Status{
+		Status: api.StatusFailure,
+		Code:   http.StatusBadRequest,
+		Reason: api.ReasonTypeBadRequest,
+		Details: &api.StatusDetails{
+			Kind: kind,
+			ID:   name,
+		},
+		Message: fmt.Sprintf("request for %s %q is malformed: %v", kind, name, err),
+	}}
+}
+
+// NewInvalidParametersErr returns an error indicating the request is malformed because the
+// query parameters the causes name have invalid values. Its message names all of them.
+func NewInvalidParametersErr(causes []api.StatusCause) error {
+	messages := make([]string, 0, len(causes))
+	for _, cause := range causes {
+		messages = append(messages, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+	}
+	return &apiServerError{api.Status{
+		Status: api.StatusFailure,
+		Code:   http.StatusBadRequest,
+		Reason: api.ReasonTypeBadRequest,
+		Details: &api.StatusDetails{
+			Causes: causes,
+		},
+		Message: fmt.Sprintf("invalid query parameters: %s", strings.Join(messages, "; ")),
+	}}
+}
+
+// NewUnsupportedMediaTypeErr returns an error indicating that the body of the request is of
+// mediaType, which the server can't decode.
+func NewUnsupportedMediaTypeErr(mediaType string) error {
+	return &apiServerError{api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusUnsupportedMediaType,
+		Reason:  api.ReasonTypeUnsupportedMediaType,
+		Message: fmt.Sprintf("the body of the request is of media type %q, which is not supported", mediaType),
+	}}
+}
+
+// NewRequestTimeoutErr returns an error indicating that the client didn't send the body of
+// the request within timeout.
+func NewRequestTimeoutErr(timeout time.Duration) error {
+	return &apiServerError{api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusRequestTimeout,
+		Reason:  api.ReasonTypeTimeout,
+		Message: fmt.Sprintf("the body of the request was not received within %v", timeout),
+	}}
+}
+
+// NewStorageUnavailableErr returns an error indicating the storage failed the request without
+// trying it, because it was found unavailable, and that it may be retried after retryAfter.
+func NewStorageUnavailableErr(retryAfter time.Duration) error {
+	return &apiServerError{api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusServiceUnavailable,
+		Reason:  api.ReasonTypeServiceUnavailable,
+		Message: "storage unavailable",
+		Details: &api.StatusDetails{RetryAfterSeconds: int((retryAfter + time.Second - 1) / time.Second)},
+	}}
+}
+
+// WithCause returns err, an error created by one of the New*Err functions, with the error
+// that caused it appended to its message, e.g. the error of etcd it was translated from, for
+// debugging. Other errors are returned unchanged.
+func WithCause(err, cause error) error {
+	apiErr, ok := err.(*apiServerError)
+	if !ok || cause == nil {
+		return err
+	}
+	withCause := *apiErr
+	withCause.Message = fmt.Sprintf("%s: %v", apiErr.Message, cause)
+	return &withCause
+}
+
+// causeTypes maps the types of validation errors to the causes they are reported as.
+var causeTypes = map[api.ValidationErrorEnum]api.CauseType{
+	api.ErrTypeInvalid:      api.CauseTypeFieldValueInvalid,
+	api.ErrTypeNotSupported: api.CauseTypeFieldValueNotSupported,
+	api.ErrTypeDuplicate:    api.CauseTypeFieldValueDuplicate,
+	api.ErrTypeNotFound:     api.CauseTypeFieldValueNotFound,
+}
+
+// NewInvalidErr returns an error indicating the item is invalid and cannot be processed.
+func NewInvalidErr(kind, name string, errs api.ValidationErrorList) error {
+	causes := make([]api.StatusCause, 0, len(errs))
+	for i := range errs {
+		switch err := errs[i].(type) {
+		case api.ValidationError:
+			causes = append(causes, api.StatusCause{
+				Type:    causeTypes[err.ErrorType],
+				Message: fmt.Sprintf("%s '%v'", err.ErrorType, err.BadValue),
+				Field:   err.ErrorField,
+			})
+		case api.InvalidIDError:
+			causes = append(causes, api.StatusCause{
+				Type:    api.CauseTypeFieldValueInvalid,
+				Message: fmt.Sprintf("%s '%v': %s", api.ErrTypeInvalid, err.ID, err.Rule),
+				Field:   "id",
+			})
+		default:
+			causes = append(causes, api.StatusCause{Message: errs[i].Error()})
+		}
+	}
+	return &apiServerError{api.Status{
+		Status: api.StatusFailure,
+		Code:   422, // RFC 4918 for StatusUnprocessableEntity
+		Reason: api.ReasonTypeInvalid,
+		Details: &api.StatusDetails{
+			Kind:   kind,
+			ID:     name,
+			Causes: causes,
+		},
+		Message: fmt.Sprintf("%s %q is invalid: %v", kind, name, errs),
+	}}
+}
+
 // IsNotFound returns true if the specified error was created by NewNotFoundErr
 func IsNotFound(err error) bool {
 	return reasonForError(err) == api.ReasonTypeNotFound
@@ -91,6 +266,27 @@ func IsConflict(err error) bool {
 	return reasonForError(err) == api.ReasonTypeConflict
 }
 
+// IsInvalid determines if the err is an error which indicates the provided resource is not valid.
+func IsInvalid(err error) bool {
+	return reasonForError(err) == api.ReasonTypeInvalid
+}
+
+// IsForbidden determines if the err is an error which indicates the request was refused.
+func IsForbidden(err error) bool {
+	return reasonForError(err) == api.ReasonTypeForbidden
+}
+
+// IsMethodNotSupported determines if the err is an error which indicates the resource does
+// not support the operation requested.
+func IsMethodNotSupported(err error) bool {
+	return reasonForError(err) == api.ReasonTypeMethodNotAllowed
+}
+
+// IsBadRequest determines if the err is an error which indicates the request was malformed.
+func IsBadRequest(err error) bool {
+	return reasonForError(err) == api.ReasonTypeBadRequest
+}
+
 func reasonForError(err error) api.ReasonType {
 	switch t := err.(type) {
 	case *apiServerError:
@@ -113,6 +309,8 @@ func errToAPIStatus(err error) *api.Status {
 		//TODO: replace me with NewUpdateConflictErr
 		case tools.IsEtcdTestFailed(err):
 			status = http.StatusConflict
+		case tools.IsStorageUnavailable(err):
+			return errToAPIStatus(NewStorageUnavailableErr(err.(*tools.StorageUnavailableError).RetryAfter))
 		}
 		return &api.Status{
 			Status:  api.StatusFailure,
@@ -123,12 +321,6 @@ func errToAPIStatus(err error) *api.Status {
 	}
 }
 
-// notFound renders a simple not found error
-func notFound(w http.ResponseWriter, req *http.Request) {
-	w.WriteHeader(http.StatusNotFound)
-	fmt.Fprintf(w, "Not Found: %#v", req.RequestURI)
-}
-
 // badGatewayError renders a simple bad gateway error
 func badGatewayError(w http.ResponseWriter, req *http.Request) {
 	w.WriteHeader(http.StatusBadGateway)
@@ -19,6 +19,8 @@ package apiserver
 import (
 	"errors"
 	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 )
 
 func TestErrorNew(t *testing.T) {
@@ -39,4 +41,13 @@ func TestErrorNew(t *testing.T) {
 	if !IsNotFound(NewNotFoundErr("test", "3")) {
 		t.Errorf("expected to be not found")
 	}
+
+	err = NewFieldConflictErr("test", "4", "selector", "5", errors.New("message"))
+	if !IsConflict(err) {
+		t.Errorf("expected to be conflict")
+	}
+	causes := errToAPIStatus(err).Details.Causes
+	if len(causes) != 1 || causes[0].Type != api.CauseTypeFieldValueConflict || causes[0].Field != "selector" {
+		t.Errorf("expected the conflicting field as the cause, got %#v", causes)
+	}
 }
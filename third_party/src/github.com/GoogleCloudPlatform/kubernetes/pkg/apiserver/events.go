@@ -0,0 +1,106 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"time"
+
+	"code.google.com/p/go-uuid/uuid"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// Reasons of the events the apiserver records when storage operations fail.
+const (
+	EventReasonFailedCreate = "failedCreate"
+	EventReasonFailedUpdate = "failedUpdate"
+	EventReasonFailedDelete = "failedDelete"
+)
+
+// EventSink stores the events recorded by an EventRecorder, e.g. an event registry.
+type EventSink interface {
+	CreateEvent(event api.Event) error
+}
+
+// EventRecorder records events that happen to objects, on behalf of a single component.
+// A nil *EventRecorder records nothing.
+type EventRecorder struct {
+	sink   EventSink
+	source string
+	now    func() time.Time
+}
+
+// NewEventRecorder returns an EventRecorder that stores events in sink, as reported by
+// source, e.g. "apiserver" or "scheduler".
+func NewEventRecorder(sink EventSink, source string) *EventRecorder {
+	return &EventRecorder{
+		sink:   sink,
+		source: source,
+		now:    time.Now,
+	}
+}
+
+// Eventf records that something happened to the object ref refers to. reason is a short,
+// machine readable description, and the message is formatted from format and args.
+// The event is put in the namespace of the object; its ID is left for the sink to qualify.
+// Events are recorded on a best effort basis: failures to store them are only logged.
+func (r *EventRecorder) Eventf(ref api.ObjectReference, reason, format string, args ...interface{}) {
+	if r == nil {
+		return
+	}
+	event := api.Event{
+		JSONBase: api.JSONBase{
+			ID:        uuid.NewUUID().String(),
+			Namespace: ref.Namespace,
+		},
+		InvolvedObject: ref,
+		Reason:         reason,
+		Message:        fmt.Sprintf(format, args...),
+		Source:         r.source,
+		Timestamp:      r.now().UTC().Format(time.RFC3339),
+	}
+	if err := r.sink.CreateEvent(event); err != nil {
+		glog.Errorf("Unable to record event %#v: %v", event, err)
+	}
+}
+
+// objectReference refers to the object called id in the namespace of ctx.
+func objectReference(ctx api.Context, kind, id string) api.ObjectReference {
+	return api.ObjectReference{Kind: kind, Namespace: ctx.Namespace, ID: id}
+}
+
+// recordFailures forwards the results of an asynchronous storage operation on the object
+// ref refers to, recording an event with reason for each result that reports a failure.
+func (r *EventRecorder) recordFailures(ref api.ObjectReference, reason string, in <-chan interface{}) <-chan interface{} {
+	if r == nil {
+		return in
+	}
+	out := make(chan interface{})
+	go func() {
+		defer util.HandleCrash()
+		defer close(out)
+		for obj := range in {
+			if status, ok := obj.(*api.Status); ok && status.Status == api.StatusFailure {
+				r.Eventf(ref, reason, "%s", status.Message)
+			}
+			out <- obj
+		}
+	}()
+	return out
+}
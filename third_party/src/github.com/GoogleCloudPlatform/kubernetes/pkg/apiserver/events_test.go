@@ -0,0 +1,141 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+type fakeEventSink struct {
+	lock   sync.Mutex
+	events []api.Event
+	err    error
+}
+
+func (s *fakeEventSink) CreateEvent(event api.Event) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.events = append(s.events, event)
+	return s.err
+}
+
+func (s *fakeEventSink) recorded() []api.Event {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]api.Event{}, s.events...)
+}
+
+func TestEventRecorder(t *testing.T) {
+	sink := &fakeEventSink{err: errors.New("sink is full")}
+	recorder := NewEventRecorder(sink, "scheduler")
+	recorder.now = func() time.Time { return time.Date(2014, 7, 1, 12, 0, 0, 0, time.UTC) }
+	ref := api.ObjectReference{Kind: "pod", Namespace: "other", ID: "foo"}
+	recorder.Eventf(ref, "failedScheduling", "no fit on %d minions", 3)
+
+	events := sink.recorded()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %#v", events)
+	}
+	event := events[0]
+	if event.InvolvedObject != ref || event.Reason != "failedScheduling" || event.Message != "no fit on 3 minions" ||
+		event.Source != "scheduler" || event.Timestamp != "2014-07-01T12:00:00Z" {
+		t.Errorf("unexpected event: %#v", event)
+	}
+	if event.Namespace != "other" || len(event.ID) == 0 || strings.Contains(event.ID, api.NamespaceSeparator) {
+		t.Errorf("expected the event to be stored in the namespace of the object: %#v", event)
+	}
+
+	var nilRecorder *EventRecorder
+	nilRecorder.Eventf(ref, "failedScheduling", "not recorded")
+}
+
+func TestFailedCreateRecordsEvent(t *testing.T) {
+	storage := SimpleRESTStorage{
+		errors: map[string]error{"create": errors.New("no room")},
+	}
+	sink := &fakeEventSink{}
+	handler := New(map[string]RESTStorage{"foo": &storage}, codec, "/prefix/version")
+	handler.SetEventRecorder(NewEventRecorder(sink, "apiserver"))
+	server := httptest.NewServer(handler)
+
+	data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}})
+	expectApiStatus(t, "POST", fmt.Sprintf("%s/prefix/version/foo", server.URL), data, http.StatusInternalServerError)
+
+	events := sink.recorded()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %#v", events)
+	}
+	expected := api.ObjectReference{Kind: "simple", Namespace: api.NamespaceDefault, ID: "bar"}
+	if events[0].InvolvedObject != expected || events[0].Reason != EventReasonFailedCreate || events[0].Message != "no room" {
+		t.Errorf("unexpected event: %#v", events[0])
+	}
+}
+
+func TestFailedAsyncDeleteRecordsEvent(t *testing.T) {
+	storage := SimpleRESTStorage{
+		injectedFunction: func(obj interface{}) (interface{}, error) {
+			return nil, NewConflictErr("simple", "bar", errors.New("in use"))
+		},
+	}
+	sink := &fakeEventSink{}
+	handler := New(map[string]RESTStorage{"foo": &storage}, codec, "/prefix/version")
+	handler.SetEventRecorder(NewEventRecorder(sink, "apiserver"))
+	server := httptest.NewServer(handler)
+
+	expectApiStatus(t, "DELETE", fmt.Sprintf("%s/prefix/version/foo/bar?sync=true", server.URL), nil, http.StatusConflict)
+
+	events := sink.recorded()
+	if len(events) != 1 {
+		t.Fatalf("expected one event, got %#v", events)
+	}
+	expected := api.ObjectReference{Kind: "simple", Namespace: api.NamespaceDefault, ID: "bar"}
+	if events[0].InvolvedObject != expected || events[0].Reason != EventReasonFailedDelete {
+		t.Errorf("unexpected event: %#v", events[0])
+	}
+}
+
+func TestSuccessfulCreateRecordsNoEvent(t *testing.T) {
+	storage := SimpleRESTStorage{}
+	sink := &fakeEventSink{}
+	handler := New(map[string]RESTStorage{"foo": &storage}, codec, "/prefix/version")
+	handler.SetEventRecorder(NewEventRecorder(sink, "apiserver"))
+	server := httptest.NewServer(handler)
+
+	data, _ := codec.Encode(Simple{JSONBase: api.JSONBase{ID: "bar"}})
+	response, err := http.Post(server.URL+"/prefix/version/foo?sync=true", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("unexpected response: %#v", response)
+	}
+
+	if events := sink.recorded(); len(events) != 0 {
+		t.Errorf("unexpected events: %#v", events)
+	}
+}
@@ -17,18 +17,146 @@ limitations under the License.
 package apiserver
 
 import (
-	"fmt"
+	"html/template"
+	"mime"
 	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 )
 
-// handleIndex is the root index page for Kubernetes
-func handleIndex(w http.ResponseWriter, req *http.Request) {
+// DefaultIndexCountTimeout is how long the index waits for the objects of each resource to
+// be counted before it shows the count as unavailable.
+const DefaultIndexCountTimeout = time.Second
+
+// Index is the description of the apiserver served at /.
+type Index struct {
+	Version   version.Info    `json:"version"`
+	Resources []IndexResource `json:"resources"`
+}
+
+// IndexResource is a resource served by the apiserver, with the number of its objects. Count
+// is nil if they couldn't be counted in time.
+type IndexResource struct {
+	Name  string `json:"name"`
+	Link  string `json:"link"`
+	Count *int   `json:"count"`
+}
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><title>Kubernetes</title></head>
+<body>
+<h1>Welcome to Kubernetes</h1>
+<p>Version {{.Version.Major}}.{{.Version.Minor}} ({{.Version.GitCommit}})</p>
+<table>
+<tr><th>Resource</th><th>Objects</th></tr>
+{{range .Resources}}<tr><td><a href="{{.Link}}">{{.Name}}</a></td><td>{{if .Count}}{{.Count}}{{else}}unavailable{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// handleIndex is the root index page for Kubernetes. It lists the resources of the API and
+// the number of objects of each, as JSON unless the request prefers HTML, as browsers do.
+func (s *APIServer) handleIndex(w http.ResponseWriter, req *http.Request) {
 	if req.URL.Path != "/" && req.URL.Path != "/index.html" {
-		notFound(w, req)
+		s.notFound(w, req, "")
 		return
 	}
+	index := s.index(req)
+	if !acceptsHTML(req) {
+		writeRawJSON(http.StatusOK, index, w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	// TODO: serve this out of a file?
-	data := "<html><body>Welcome to Kubernetes</body></html>"
-	fmt.Fprint(w, data)
+	indexTemplate.Execute(w, index)
+}
+
+// index counts the objects of each listable resource, all at once and waiting at most
+// s.indexCountTimeout for them.
+func (s *APIServer) index(req *http.Request) Index {
+	ctx := s.requestContext(req)
+	names := []string{}
+	for name := range s.storage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	counts := make([]chan int, len(names))
+	for i, name := range names {
+		lister, ok := asLister(s.storage[name])
+		if !ok {
+			continue
+		}
+		counts[i] = make(chan int, 1)
+		go func(count chan<- int) {
+			defer util.HandleCrash()
+			// A count that fails is sent as -1, so that it isn't waited for.
+			n := -1
+			defer func() { count <- n }()
+			list, err := lister.List(ctx, labels.Everything())
+			if err != nil {
+				return
+			}
+			filterNamespace(list, ctx.Namespace)
+			if items, ok := listItems(list); ok {
+				n = items.Len()
+			}
+		}(counts[i])
+	}
+
+	index := Index{Version: version.Get(), Resources: []IndexResource{}}
+	timeout := time.After(s.indexCountTimeout)
+	expired := false
+	for i, name := range names {
+		if counts[i] == nil {
+			continue
+		}
+		resource := IndexResource{Name: name, Link: path.Join(s.prefix, name)}
+		count := -1
+		if !expired {
+			select {
+			case count = <-counts[i]:
+			case <-timeout:
+				expired = true
+			}
+		}
+		if expired {
+			// Once the time is up, only the counts already done are shown.
+			select {
+			case count = <-counts[i]:
+			default:
+			}
+		}
+		if count >= 0 {
+			resource.Count = &count
+		}
+		index.Resources = append(index.Resources, resource)
+	}
+	return index
+}
+
+// acceptsHTML returns true if the request lists text/html among the media types it accepts
+// before any JSON one.
+func acceptsHTML(req *http.Request) bool {
+	for _, accepted := range strings.Split(req.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(accepted)
+		if err != nil {
+			continue
+		}
+		switch {
+		case mediaType == "text/html":
+			return true
+		case strings.HasSuffix(mediaType, "json"):
+			return false
+		}
+	}
+	return false
 }
@@ -0,0 +1,134 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// blockedStorage is a SimpleRESTStorage whose lists don't return until unblocked.
+type blockedStorage struct {
+	SimpleRESTStorage
+	unblock chan struct{}
+}
+
+func (storage *blockedStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	<-storage.unblock
+	return storage.SimpleRESTStorage.List(ctx, selector)
+}
+
+func getIndex(t *testing.T, server *httptest.Server, accept string) (string, string) {
+	req, _ := http.NewRequest("GET", server.URL+"/", nil)
+	if len(accept) != 0 {
+		req.Header.Set("Accept", accept)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", resp.StatusCode, body)
+	}
+	return resp.Header.Get("Content-Type"), string(body)
+}
+
+func TestIndex(t *testing.T) {
+	blocked := &blockedStorage{unblock: make(chan struct{})}
+	defer close(blocked.unblock)
+	storage := map[string]RESTStorage{
+		"simple":  &SimpleRESTStorage{list: []Simple{{Name: "a"}, {Name: "b"}}},
+		"failing": &SimpleRESTStorage{errors: map[string]error{"list": errors.New("etcd is down")}},
+		"blocked": blocked,
+	}
+	handler := New(storage, codec, "/prefix/version")
+	handler.indexCountTimeout = 10 * time.Millisecond
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	contentType, body := getIndex(t, server, "")
+	if contentType != "application/json" {
+		t.Errorf("expected JSON by default, got %s", contentType)
+	}
+	var index Index
+	if err := json.Unmarshal([]byte(body), &index); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if len(index.Version.Major) == 0 {
+		t.Errorf("expected the version of the server, got %#v", index.Version)
+	}
+	counts := map[string]string{}
+	for _, resource := range index.Resources {
+		if resource.Link != "/prefix/version/"+resource.Name {
+			t.Errorf("unexpected link of %s: %s", resource.Name, resource.Link)
+		}
+		counts[resource.Name] = "unavailable"
+		if resource.Count != nil {
+			counts[resource.Name] = strconv.Itoa(*resource.Count)
+		}
+	}
+	expected := map[string]string{"blocked": "unavailable", "failing": "unavailable", "simple": "2"}
+	if len(counts) != len(expected) {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+	for name, count := range expected {
+		if counts[name] != count {
+			t.Errorf("%s: expected %s, got %s", name, count, counts[name])
+		}
+	}
+
+	contentType, body = getIndex(t, server, "text/html,application/xhtml+xml,*/*;q=0.8")
+	if !strings.HasPrefix(contentType, "text/html") {
+		t.Errorf("expected HTML for a browser, got %s", contentType)
+	}
+	for _, expected := range []string{`<a href="/prefix/version/simple">simple</a></td><td>2</td>`, "<td>unavailable</td>", "Version "} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("expected %q in the page, got %s", expected, body)
+		}
+	}
+}
+
+func TestAcceptsHTML(t *testing.T) {
+	table := map[string]bool{
+		"":                                  false,
+		"*/*":                               false,
+		"application/json":                  false,
+		"text/html":                         true,
+		"application/json, text/html":       false,
+		"text/html;q=0.9, application/json": true,
+		"application/xhtml+xml, text/html":  true,
+	}
+	for accept, expected := range table {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept", accept)
+		if acceptsHTML(req) != expected {
+			t.Errorf("%q: expected %v", accept, expected)
+		}
+	}
+}
@@ -17,33 +17,52 @@ limitations under the License.
 package apiserver
 
 import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // RESTStorage is a generic interface for RESTful storage services
-// Resources which are exported to the RESTful API of apiserver need to implement this interface.
+// Resources which are exported to the RESTful API of apiserver need to implement this interface,
+// and the interfaces of the operations they support: Lister, Getter, Creater, Updater, Deleter
+// and ResourceWatcher. The apiserver answers requests for other operations with 405.
+// Every method but New is passed the api.Context of the request it serves.
 type RESTStorage interface {
 	// New returns an empty object that can be used with Create and Update after request data has been put into it.
 	// This object must be a pointer type for use with Codec.DecodeInto([]byte, interface{})
 	New() interface{}
+}
 
+// Lister is implemented by RESTStorage objects that can be listed.
+type Lister interface {
 	// List selects resources in the storage which match to the selector.
-	// TODO: add field selector in addition to label selector.
-	List(labels.Selector) (interface{}, error)
+	List(ctx api.Context, selector labels.Selector) (interface{}, error)
+}
 
+// Getter is implemented by RESTStorage objects whose resources can be read one at a time.
+type Getter interface {
 	// Get finds a resource in the storage by id and returns it.
 	// Although it can return an arbitrary error value, IsNotFound(err) is true for the
 	// returned error value err when the specified resource is not found.
-	Get(id string) (interface{}, error)
+	Get(ctx api.Context, id string) (interface{}, error)
+}
 
+// Deleter is implemented by RESTStorage objects whose resources can be deleted.
+type Deleter interface {
 	// Delete finds a resource in the storage and deletes it.
 	// Although it can return an arbitrary error value, IsNotFound(err) is true for the
 	// returned error value err when the specified resource is not found.
-	Delete(id string) (<-chan interface{}, error)
+	Delete(ctx api.Context, id string) (<-chan interface{}, error)
+}
+
+// Creater is implemented by RESTStorage objects that can create resources.
+type Creater interface {
+	Create(ctx api.Context, obj interface{}) (<-chan interface{}, error)
+}
 
-	Create(interface{}) (<-chan interface{}, error)
-	Update(interface{}) (<-chan interface{}, error)
+// Updater is implemented by RESTStorage objects whose resources can be replaced.
+type Updater interface {
+	Update(ctx api.Context, obj interface{}) (<-chan interface{}, error)
 }
 
 // ResourceWatcher should be implemented by all RESTStorage objects that
@@ -53,5 +72,30 @@ type ResourceWatcher interface {
 	// are supported; an error should be returned if 'field' tries to select on a field that
 	// isn't supported. 'resourceVersion' allows for continuing/starting a watch at a
 	// particular version.
-	Watch(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
+	Watch(ctx api.Context, label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
+}
+
+// ResourceFieldLister may be implemented by Lister objects that can filter lists on
+// the object's fields as well as its labels. As with ResourceWatcher, an error should be
+// returned if 'field' selects on a field that isn't supported.
+type ResourceFieldLister interface {
+	ListFields(ctx api.Context, label, field labels.Selector) (interface{}, error)
+}
+
+// Defaulter may be implemented by Creater objects that fill in fields of the objects they
+// create, such as a generated ID. The apiserver defaults an object before validating it,
+// so that it is validated as it will be stored.
+type Defaulter interface {
+	Default(ctx api.Context, obj interface{})
+}
+
+// ListViewer may be implemented by Lister objects that answer lists with other views of
+// their objects, chosen with a query parameter set to true, such as the ports services are
+// using, at GET /services?usedPorts=true. Views are written as plain JSON rather than with
+// the codec, so they needn't be API objects.
+type ListViewer interface {
+	// ListViews returns the names of the views, which are the query parameters choosing them.
+	ListViews() []string
+	// ListView returns the view called name of the objects that match selector.
+	ListView(ctx api.Context, name string, selector labels.Selector) (interface{}, error)
 }
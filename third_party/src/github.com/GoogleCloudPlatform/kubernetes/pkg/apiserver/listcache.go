@@ -0,0 +1,174 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// listCache keeps the encoded responses to list requests for a short time, so that
+// clients polling the same list don't each hit the storage and re-encode the result.
+// Entries are keyed by resource, namespace and selectors, and all entries of a resource
+// are dropped whenever an object of that resource, or of a resource whose writes change
+// it, is created, updated or deleted through the apiserver. Changes made by other means
+// show up once the entries expire. At most maxListCacheEntries responses are kept.
+// Cached responses are never modified once stored.
+// A nil *listCache caches nothing.
+type listCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	lock    sync.Mutex
+	entries map[listCacheKey]listCacheEntry
+	// generations counts the invalidations of each resource, so that a list computed
+	// before an invalidation is not stored after it.
+	generations map[string]uint64
+	hits        uint64
+	misses      uint64
+}
+
+// maxListCacheEntries bounds the number of responses a listCache keeps, since every
+// distinct selector clients list with makes an entry.
+const maxListCacheEntries = 1000
+
+type listCacheKey struct {
+	resource  string
+	namespace string
+	labels    string
+	fields    string
+	sort      string
+	// mediaType is the media type the list is encoded in.
+	mediaType string
+}
+
+type listCacheEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// newListCache returns a listCache that keeps responses for ttl.
+func newListCache(ttl time.Duration) *listCache {
+	return &listCache{
+		ttl:         ttl,
+		now:         time.Now,
+		entries:     map[listCacheKey]listCacheEntry{},
+		generations: map[string]uint64{},
+	}
+}
+
+// get returns the cached response for key, and the generation of its resource, which must
+// be passed to put when the response is computed after a miss. If fresh is true, the
+// cached response is bypassed and get misses.
+func (c *listCache) get(key listCacheKey, fresh bool) (data []byte, generation uint64, ok bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	entry, ok := c.entries[key]
+	if ok && !fresh && c.now().Before(entry.expires) {
+		c.hits++
+		return entry.data, c.generations[key.resource], true
+	}
+	delete(c.entries, key)
+	c.misses++
+	return nil, c.generations[key.resource], false
+}
+
+// put stores data as the response for key, unless the resource has been invalidated
+// since generation was returned by get. Expired entries are swept when the cache is full;
+// if it is still full, data is not stored.
+func (c *listCache) put(key listCacheKey, generation uint64, data []byte) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.generations[key.resource] != generation {
+		return
+	}
+	now := c.now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= maxListCacheEntries {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxListCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = listCacheEntry{data: data, expires: now.Add(c.ttl)}
+}
+
+// invalidate drops the cached responses for resources.
+func (c *listCache) invalidate(resources ...string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, resource := range resources {
+		c.generations[resource]++
+	}
+	for key := range c.entries {
+		for _, resource := range resources {
+			if key.resource == resource {
+				delete(c.entries, key)
+			}
+		}
+	}
+}
+
+// invalidateOn drops the cached responses for resources now, and again as each result of
+// the asynchronous storage operation in arrives, since the operation may still change the
+// resources after it was accepted.
+func (c *listCache) invalidateOn(resources []string, in <-chan interface{}) <-chan interface{} {
+	if c == nil {
+		return in
+	}
+	c.invalidate(resources...)
+	out := make(chan interface{})
+	go func() {
+		defer util.HandleCrash()
+		defer close(out)
+		for obj := range in {
+			c.invalidate(resources...)
+			out <- obj
+		}
+	}()
+	return out
+}
+
+// listCacheMetrics counts how the list requests to a listCache were answered.
+type listCacheMetrics struct {
+	Hits   uint64 `json:"listCacheHits"`
+	Misses uint64 `json:"listCacheMisses"`
+}
+
+// metrics returns the hit and miss counts of c.
+func (c *listCache) metrics() listCacheMetrics {
+	if c == nil {
+		return listCacheMetrics{}
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return listCacheMetrics{Hits: c.hits, Misses: c.misses}
+}
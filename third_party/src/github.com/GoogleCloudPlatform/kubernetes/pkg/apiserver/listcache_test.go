@@ -0,0 +1,192 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// countingStorage counts the calls to List of the storage it wraps.
+type countingStorage struct {
+	*SimpleRESTStorage
+	lists int
+}
+
+func (s *countingStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	s.lists++
+	return s.SimpleRESTStorage.List(ctx, selector)
+}
+
+func getList(t *testing.T, url string) []Simple {
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var list SimpleList
+	if _, err := extractBody(response, &list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return list.Items
+}
+
+func TestListCache(t *testing.T) {
+	storage := &countingStorage{SimpleRESTStorage: &SimpleRESTStorage{list: []Simple{{Name: "foo"}}}}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	handler.SetListCacheTTL(time.Hour)
+	server := httptest.NewServer(handler)
+
+	getList(t, server.URL+"/prefix/version/simple")
+	items := getList(t, server.URL+"/prefix/version/simple")
+	if storage.lists != 1 {
+		t.Errorf("expected the second list to be cached, storage was listed %d times", storage.lists)
+	}
+	if len(items) != 1 || items[0].Name != "foo" {
+		t.Errorf("unexpected items: %#v", items)
+	}
+
+	getList(t, server.URL+"/prefix/version/simple?labels=a%3Db")
+	if storage.lists != 2 {
+		t.Errorf("expected lists with other selectors not to be cached, storage was listed %d times", storage.lists)
+	}
+
+	getList(t, server.URL+"/prefix/version/simple?fresh=true")
+	if storage.lists != 3 {
+		t.Errorf("expected fresh lists to bypass the cache, storage was listed %d times", storage.lists)
+	}
+
+	data, _ := codec.Encode(Simple{Name: "bar"})
+	response, err := http.Post(server.URL+"/prefix/version/simple?sync=true", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	getList(t, server.URL+"/prefix/version/simple")
+	if storage.lists != 4 {
+		t.Errorf("expected a create to invalidate the cache, storage was listed %d times", storage.lists)
+	}
+
+	response, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	var metrics listCacheMetrics
+	if err := json.Unmarshal(body, &metrics); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if metrics.Hits != 1 || metrics.Misses != 4 {
+		t.Errorf("unexpected metrics: %s", body)
+	}
+}
+
+func TestListCacheExpires(t *testing.T) {
+	now := time.Now()
+	cache := newListCache(time.Second)
+	cache.now = func() time.Time { return now }
+	key := listCacheKey{resource: "simple"}
+
+	_, generation, _ := cache.get(key, false)
+	cache.put(key, generation, []byte("list"))
+	if data, _, ok := cache.get(key, false); !ok || string(data) != "list" {
+		t.Errorf("expected a hit, got %q %v", data, ok)
+	}
+	now = now.Add(2 * time.Second)
+	if _, _, ok := cache.get(key, false); ok {
+		t.Errorf("expected the entry to expire")
+	}
+}
+
+func TestListCacheDropsListsComputedBeforeInvalidation(t *testing.T) {
+	cache := newListCache(time.Hour)
+	key := listCacheKey{resource: "simple"}
+
+	_, generation, _ := cache.get(key, false)
+	cache.invalidate("simple")
+	cache.put(key, generation, []byte("stale"))
+	if _, _, ok := cache.get(key, false); ok {
+		t.Errorf("expected the stale list not to be cached")
+	}
+}
+
+func TestListCacheDisabled(t *testing.T) {
+	storage := &countingStorage{SimpleRESTStorage: &SimpleRESTStorage{}}
+	server := httptest.NewServer(New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version"))
+
+	getList(t, server.URL+"/prefix/version/simple")
+	getList(t, server.URL+"/prefix/version/simple")
+	if storage.lists != 2 {
+		t.Errorf("expected no caching by default, storage was listed %d times", storage.lists)
+	}
+}
+
+func TestListCacheSweepsExpiredEntriesWhenFull(t *testing.T) {
+	now := time.Now()
+	cache := newListCache(time.Second)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < maxListCacheEntries; i++ {
+		cache.put(listCacheKey{resource: "simple", labels: fmt.Sprintf("a=%d", i)}, 0, []byte("list"))
+	}
+	key := listCacheKey{resource: "simple", labels: "b=1"}
+	cache.put(key, 0, []byte("list"))
+	if _, _, ok := cache.get(key, false); ok {
+		t.Errorf("expected a full cache not to store more entries")
+	}
+	if len(cache.entries) != maxListCacheEntries {
+		t.Errorf("expected %d entries, got %d", maxListCacheEntries, len(cache.entries))
+	}
+
+	now = now.Add(2 * time.Second)
+	cache.put(key, 0, []byte("list"))
+	if _, _, ok := cache.get(key, false); !ok {
+		t.Errorf("expected the entry to be stored once expired entries are swept")
+	}
+	if len(cache.entries) != 1 {
+		t.Errorf("expected the expired entries to be swept, got %d entries", len(cache.entries))
+	}
+}
+
+func TestListCacheDependency(t *testing.T) {
+	storage := &countingStorage{SimpleRESTStorage: &SimpleRESTStorage{}}
+	handler := New(map[string]RESTStorage{"simple": storage, "bindings": &SimpleRESTStorage{}}, codec, "/prefix/version")
+	handler.SetListCacheTTL(time.Hour)
+	handler.SetListCacheDependency("bindings", "simple")
+	server := httptest.NewServer(handler)
+
+	getList(t, server.URL+"/prefix/version/simple")
+	data, _ := codec.Encode(Simple{Name: "bar"})
+	response, err := http.Post(server.URL+"/prefix/version/bindings?sync=true", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	getList(t, server.URL+"/prefix/version/simple")
+	if storage.lists != 2 {
+		t.Errorf("expected a binding to invalidate the lists it changes, storage was listed %d times", storage.lists)
+	}
+}
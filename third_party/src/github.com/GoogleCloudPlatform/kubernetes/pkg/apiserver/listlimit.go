@@ -0,0 +1,123 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+const (
+	// ListTruncatedHeader is set to "true" on the responses to list requests that don't hold
+	// every item of the list.
+	ListTruncatedHeader = "X-Kubernetes-List-Truncated"
+	// ListContinueHeader holds the token to pass as the continue parameter of the next list
+	// request to get the items after those of a truncated list.
+	ListContinueHeader = "X-Kubernetes-List-Continue"
+)
+
+// listLimit is the number of items the lists of a resource hold when a request doesn't name
+// a limit, and the most they may hold. 0 is no limit.
+type listLimit struct {
+	defaultLimit int
+	max          int
+}
+
+// SetListLimit makes the lists of resource served by s hold up to defaultLimit items when a
+// request doesn't name a limit, and up to max whatever the request names, so that a client
+// can't pull a whole large collection at once. Lists that don't hold every item are answered
+// with ListTruncatedHeader and a ListContinueHeader token to get the next items. A limit that
+// isn't positive doesn't limit lists. Watches aren't limited.
+func (s *APIServer) SetListLimit(resource string, defaultLimit, max int) {
+	if s.listLimits == nil {
+		s.listLimits = map[string]listLimit{}
+	}
+	s.listLimits[resource] = listLimit{defaultLimit, max}
+}
+
+// listPage is the part of a list a request gets: limit items from offset, or every item from
+// offset if limit is 0.
+type listPage struct {
+	offset int
+	limit  int
+}
+
+// paged returns true if p isn't the whole list.
+func (p listPage) paged() bool {
+	return p.offset > 0 || p.limit > 0
+}
+
+// listPage returns the part of a list of resource the request with opts gets, from its
+// limit and continue parameters and the limits of resource.
+func (s *APIServer) listPage(resource string, opts *RequestOptions) listPage {
+	page := listPage{offset: opts.offset, limit: opts.Limit}
+	limits := s.listLimits[resource]
+	if page.limit == 0 {
+		page.limit = limits.defaultLimit
+	}
+	if limits.max > 0 && (page.limit == 0 || page.limit > limits.max) {
+		page.limit = limits.max
+	}
+	if page.limit < 0 {
+		page.limit = 0
+	}
+	return page
+}
+
+// apply keeps the items of list, a pointer to a list object, that are on p, and sets the
+// headers of w if items are left out after them.
+func (p listPage) apply(list interface{}, w http.ResponseWriter) {
+	items, ok := listItems(list)
+	if !ok {
+		return
+	}
+	start := p.offset
+	if start > items.Len() {
+		start = items.Len()
+	}
+	end := items.Len()
+	if p.limit > 0 && start+p.limit < end {
+		end = start + p.limit
+		w.Header().Set(ListTruncatedHeader, "true")
+		w.Header().Set(ListContinueHeader, encodeContinue(end))
+	}
+	kept := reflect.MakeSlice(items.Type(), end-start, end-start)
+	reflect.Copy(kept, items.Slice(start, end))
+	items.Set(kept)
+}
+
+// encodeContinue returns the continue token of the items of a list from offset. Tokens are
+// opaque to clients, so that what they hold may change. An offset skips or repeats items if
+// the list changes between requests, which clients listing in pages must tolerate.
+func encodeContinue(offset int) string {
+	return base64.URLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeContinue returns the offset of a continue token.
+func decodeContinue(token string) (int, error) {
+	data, err := base64.URLEncoding.DecodeString(token)
+	if err == nil {
+		var offset int
+		if offset, err = strconv.Atoi(string(data)); err == nil && offset >= 0 {
+			return offset, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid continue token %q", token)
+}
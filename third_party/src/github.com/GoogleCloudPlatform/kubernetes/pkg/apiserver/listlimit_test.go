@@ -0,0 +1,126 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// getPage lists url and returns the names of the items and the continue token, which is
+// empty if the list isn't truncated.
+func getPage(t *testing.T, url string) ([]string, string) {
+	response, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var list SimpleList
+	if _, err := extractBody(response, &list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.Name)
+	}
+	truncated := response.Header.Get(ListTruncatedHeader) == "true"
+	token := response.Header.Get(ListContinueHeader)
+	if truncated != (len(token) > 0) {
+		t.Errorf("%s: expected a continue token with the truncated header, got %q and %q", url, response.Header.Get(ListTruncatedHeader), token)
+	}
+	return names, token
+}
+
+func TestListLimit(t *testing.T) {
+	storage := &SimpleRESTStorage{
+		list: []Simple{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}},
+		item: Simple{Name: "a"},
+	}
+	handler := New(map[string]RESTStorage{"simple": storage, "other": storage}, codec, "/prefix/version")
+	handler.SetListLimit("simple", 1, 2)
+	handler.SetListCacheTTL(time.Hour)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	table := []struct {
+		query    string
+		expected []string
+		more     bool
+	}{
+		{"", []string{"a"}, true},
+		{"limit=2", []string{"a", "b"}, true},
+		{"limit=100", []string{"a", "b"}, true},
+		{"limit=2&continue=" + url.QueryEscape(encodeContinue(3)), []string{"d", "e"}, false},
+		{"limit=2&continue=" + url.QueryEscape(encodeContinue(10)), []string{}, false},
+	}
+	for _, item := range table {
+		names, token := getPage(t, server.URL+"/prefix/version/simple?"+item.query)
+		if !reflect.DeepEqual(names, item.expected) || (len(token) > 0) != item.more {
+			t.Errorf("%q: expected %v (more: %v), got %v and %q", item.query, item.expected, item.more, names, token)
+		}
+	}
+
+	// Following the continue tokens lists every item, in pages of at most the maximum.
+	all := []string{}
+	next := "/prefix/version/simple?limit=100"
+	for pages := 0; len(next) > 0; pages++ {
+		if pages > 5 {
+			t.Fatalf("expected the continue tokens to reach the end of the list, got %v", all)
+		}
+		names, token := getPage(t, server.URL+next)
+		all = append(all, names...)
+		next = ""
+		if len(token) > 0 {
+			next = "/prefix/version/simple?limit=100&continue=" + url.QueryEscape(token)
+		}
+	}
+	if !reflect.DeepEqual(all, []string{"a", "b", "c", "d", "e"}) {
+		t.Errorf("unexpected items: %v", all)
+	}
+
+	// Other resources aren't limited unless they ask.
+	if names, token := getPage(t, server.URL+"/prefix/version/other"); len(names) != 5 || len(token) > 0 {
+		t.Errorf("expected the whole list, got %v and %q", names, token)
+	}
+	if names, _ := getPage(t, server.URL+"/prefix/version/other?limit=3"); len(names) != 3 {
+		t.Errorf("expected a limit to be honored, got %v", names)
+	}
+
+	// A tiny default doesn't get in the way of reading an object.
+	var item Simple
+	response, err := http.Get(server.URL + "/prefix/version/simple/a")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := extractBody(response, &item); err != nil || item.Name != "a" || response.Header.Get(ListTruncatedHeader) != "" {
+		t.Errorf("unexpected object %#v: %v", item, err)
+	}
+
+	for _, query := range []string{"limit=0", "limit=x", "continue=nope"} {
+		response, err := http.Get(server.URL + "/prefix/version/simple?" + query)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, response.StatusCode)
+		}
+	}
+}
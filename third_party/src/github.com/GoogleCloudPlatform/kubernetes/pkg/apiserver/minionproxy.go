@@ -31,7 +31,13 @@ import (
 	"github.com/golang/glog"
 )
 
-func handleProxyMinion(w http.ResponseWriter, req *http.Request) {
+// minionProxy proxies requests to the kubelets of minions.
+type minionProxy struct {
+	// transport carries the requests. If nil, http.DefaultTransport is used.
+	transport http.RoundTripper
+}
+
+func (p *minionProxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	path := strings.TrimLeft(req.URL.Path, "/")
 	rawQuery := req.URL.RawQuery
 
@@ -50,13 +56,7 @@ func handleProxyMinion(w http.ResponseWriter, req *http.Request) {
 		badGatewayError(w, req)
 		return
 	}
-	minionHost := parts[0]
-	_, port, _ := net.SplitHostPort(minionHost)
-	if port == "" {
-		// Couldn't retrieve port information
-		// TODO: Retrieve port info from a common object
-		minionHost += ":10250"
-	}
+	minionHost := MinionHost(parts[0])
 	minionPath := "/" + parts[1]
 
 	minionURL := &url.URL{
@@ -69,14 +69,33 @@ func handleProxyMinion(w http.ResponseWriter, req *http.Request) {
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(minionURL)
-	proxy.Transport = &minionTransport{}
+	proxy.Transport = &minionTransport{p.transport}
 	proxy.ServeHTTP(w, newReq)
 }
 
-type minionTransport struct{}
+// MinionHost returns the host and port of the kubelet of minion, which is minion itself if
+// it names a port, and the default kubelet port of minion otherwise.
+func MinionHost(minion string) string {
+	_, port, _ := net.SplitHostPort(minion)
+	if port == "" {
+		// Couldn't retrieve port information
+		// TODO: Retrieve port info from a common object
+		return minion + ":10250"
+	}
+	return minion
+}
+
+type minionTransport struct {
+	// base carries the requests. If nil, http.DefaultTransport is used.
+	base http.RoundTripper
+}
 
 func (t *minionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
-	resp, err := http.DefaultTransport.RoundTrip(req)
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
 
 	if err != nil {
 		if strings.Contains(err.Error(), "connection refused") {
@@ -23,6 +23,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -80,7 +82,7 @@ func TestMinionProxy(t *testing.T) {
 	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		w.Write([]byte(req.URL.Path))
 	}))
-	server := httptest.NewServer(http.HandlerFunc(handleProxyMinion))
+	server := httptest.NewServer(&minionProxy{})
 	//client := http.Client{}
 	proxy, _ := url.Parse(proxyServer.URL)
 
@@ -142,3 +144,59 @@ func TestApiServerMinionProxy(t *testing.T) {
 		t.Errorf("unexpected response body %s", actual)
 	}
 }
+
+// fakeTransport answers every request with the host and path it was sent to.
+type fakeTransport struct{}
+
+func (fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"text/plain"}},
+		Body:       ioutil.NopCloser(strings.NewReader(req.URL.Host + req.URL.Path)),
+	}, nil
+}
+
+func TestNewWithConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apiserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "kubelet.log"), []byte("started"), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	get := func(server *httptest.Server, path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	config := Config{EnableLogsSupport: true, LogDir: dir, EnableProxy: true, ProxyTransport: fakeTransport{}, EnableIndex: true}
+	enabled := httptest.NewServer(NewWithConfig(nil, codec, "/prefix", config))
+	defer enabled.Close()
+	for path, expected := range map[string]string{
+		"/logs/kubelet.log":                "started",
+		"/proxy/minion/minion1:10250/pods": "minion1:10250/pods",
+		"/":                                `"resources"`,
+	} {
+		if code, body := get(enabled, path); code != http.StatusOK || !strings.Contains(body, expected) {
+			t.Errorf("%s: expected %q, got %d: %s", path, expected, code, body)
+		}
+	}
+
+	disabled := httptest.NewServer(NewWithConfig(nil, codec, "/prefix", Config{}))
+	defer disabled.Close()
+	for _, path := range []string{"/logs/kubelet.log", "/proxy/minion/minion1:10250/pods", "/"} {
+		if code, _ := get(disabled, path); code != http.StatusNotFound {
+			t.Errorf("%s: expected a disabled handler to answer 404, got %d", path, code)
+		}
+	}
+	if code, _ := get(disabled, "/version"); code != http.StatusOK {
+		t.Errorf("expected the version to be served, got %d", code)
+	}
+}
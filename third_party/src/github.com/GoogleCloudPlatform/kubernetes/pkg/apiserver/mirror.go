@@ -0,0 +1,253 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// DefaultMirrorQueueSize is the number of mirrored requests that may wait to be sent to the
+// shadow apiserver. Requests mirrored while the queue is full are dropped.
+const DefaultMirrorQueueSize = 100
+
+// mirrorWorkers is the number of goroutines of a Mirror that send requests to the shadow.
+const mirrorWorkers = 4
+
+// mirrorTimeout is how long the shadow apiserver has to answer a mirrored request.
+const mirrorTimeout = 30 * time.Second
+
+// mismatchLogsPerMinute caps the mismatches a Mirror logs, so that a shadow that answers
+// every request differently can't flood the log.
+const mismatchLogsPerMinute = 5
+
+// Mirror sends copies of the read requests an apiserver serves, such as gets, lists and the
+// requests that start watches, to a shadow apiserver, e.g. a new build to be tried with
+// production traffic before it is rolled out. The answers of the shadow are ignored, except
+// for their status code: the requests it answers with another code than the apiserver did
+// are counted, and a few of them logged. Requests that create, update or delete objects are
+// never mirrored.
+//
+// Mirroring adds no latency to the requests served: copies are queued, and sent by a few
+// goroutines of their own. Copies that find the queue full are dropped.
+type Mirror struct {
+	shadow *url.URL
+	ratio  float64
+	client *http.Client
+	queue  chan *mirroredRequest
+	random func() float64
+	now    func() time.Time
+	logf   func(format string, args ...interface{})
+
+	// The counters of MirrorMetrics, accessed atomically.
+	mirrored   uint64
+	dropped    uint64
+	mismatches uint64
+	failures   uint64
+
+	lock sync.Mutex
+	// logged counts the mismatches logged since windowStart.
+	logged      int
+	windowStart time.Time
+}
+
+// mirroredRequest is a copy of a request the apiserver answered with status, waiting to be
+// sent to the shadow apiserver.
+type mirroredRequest struct {
+	uri    string
+	header http.Header
+	status int
+}
+
+// MirrorMetrics counts the requests of a Mirror.
+type MirrorMetrics struct {
+	// Mirrored counts the requests sent to the shadow apiserver.
+	Mirrored uint64 `json:"mirrored"`
+	// Dropped counts the requests not sent because the queue was full.
+	Dropped uint64 `json:"dropped"`
+	// Mismatches counts the requests the shadow answered with another status code.
+	Mismatches uint64 `json:"mismatches"`
+	// Failures counts the requests the shadow didn't answer.
+	Failures uint64 `json:"failures"`
+}
+
+// NewMirror returns a Mirror that sends a ratio, between 0 and 1, of the read requests it
+// sees to the shadow apiserver at shadowURL, e.g. http://10.0.0.2:8080.
+func NewMirror(shadowURL string, ratio float64) (*Mirror, error) {
+	shadow, err := url.Parse(shadowURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid shadow URL %q: %v", shadowURL, err)
+	}
+	if (shadow.Scheme != "http" && shadow.Scheme != "https") || len(shadow.Host) == 0 {
+		return nil, fmt.Errorf("invalid shadow URL %q: expected http://host[:port] or https://host[:port]", shadowURL)
+	}
+	if ratio <= 0 || ratio > 1 {
+		return nil, fmt.Errorf("invalid mirroring ratio %v: expected more than 0 and at most 1", ratio)
+	}
+	m := &Mirror{
+		shadow: shadow,
+		ratio:  ratio,
+		client: &http.Client{Timeout: mirrorTimeout},
+		queue:  make(chan *mirroredRequest, DefaultMirrorQueueSize),
+		random: rand.Float64,
+		now:    time.Now,
+		logf:   glog.Warningf,
+	}
+	for i := 0; i < mirrorWorkers; i++ {
+		go m.run()
+	}
+	return m, nil
+}
+
+// Handler returns handler, with a sample of the read requests it serves mirrored by m.
+func (m *Mirror) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w, done := m.track(w, req)
+		defer done()
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// Metrics returns the counters of m.
+func (m *Mirror) Metrics() MirrorMetrics {
+	return MirrorMetrics{
+		Mirrored:   atomic.LoadUint64(&m.mirrored),
+		Dropped:    atomic.LoadUint64(&m.dropped),
+		Mismatches: atomic.LoadUint64(&m.mismatches),
+		Failures:   atomic.LoadUint64(&m.failures),
+	}
+}
+
+// track returns the writer to answer req with, and a func to call once it is answered. If
+// req is to be mirrored, it is queued as soon as its status is written, so that watches are
+// mirrored when they start. Requests that upgrade their connection, such as watches over
+// websockets, can't be replayed and are never mirrored.
+func (m *Mirror) track(w http.ResponseWriter, req *http.Request) (http.ResponseWriter, func()) {
+	if req.Method != "GET" || len(req.Header.Get("Upgrade")) != 0 || m.random() >= m.ratio {
+		return w, func() {}
+	}
+	header := http.Header{}
+	for key, values := range req.Header {
+		header[key] = append([]string{}, values...)
+	}
+	tracked := &mirrorResponseWriter{ResponseWriter: w}
+	tracked.answered = func(status int) {
+		m.enqueue(&mirroredRequest{uri: req.URL.RequestURI(), header: header, status: status})
+	}
+	return tracked, func() { tracked.once.Do(func() { tracked.answered(http.StatusOK) }) }
+}
+
+// enqueue queues r to be sent to the shadow, or drops it if the queue is full.
+func (m *Mirror) enqueue(r *mirroredRequest) {
+	select {
+	case m.queue <- r:
+	default:
+		atomic.AddUint64(&m.dropped, 1)
+	}
+}
+
+// run sends the queued requests to the shadow, forever.
+func (m *Mirror) run() {
+	for r := range m.queue {
+		m.send(r)
+	}
+}
+
+// send sends r to the shadow, and counts it as a mismatch if the shadow answers it with
+// another status code than the apiserver did. Only the status of the answer is read, so
+// that a mirrored watch ends as soon as it has started.
+func (m *Mirror) send(r *mirroredRequest) {
+	atomic.AddUint64(&m.mirrored, 1)
+	req, err := http.NewRequest("GET", strings.TrimRight(m.shadow.String(), "/")+r.uri, nil)
+	if err != nil {
+		atomic.AddUint64(&m.failures, 1)
+		return
+	}
+	req.Header = r.header
+	resp, err := m.client.Do(req)
+	if err != nil {
+		atomic.AddUint64(&m.failures, 1)
+		if m.allowLog() {
+			m.logf("Shadow apiserver failed GET %s: %v", r.uri, err)
+		}
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode == r.status {
+		return
+	}
+	atomic.AddUint64(&m.mismatches, 1)
+	if m.allowLog() {
+		m.logf("Shadow apiserver answered GET %s with %d, the apiserver with %d", r.uri, resp.StatusCode, r.status)
+	}
+}
+
+// allowLog returns true if another mismatch may be logged in the current minute.
+func (m *Mirror) allowLog() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := m.now()
+	if now.Sub(m.windowStart) >= time.Minute {
+		m.windowStart = now
+		m.logged = 0
+	}
+	if m.logged >= mismatchLogsPerMinute {
+		return false
+	}
+	m.logged++
+	return true
+}
+
+// mirrorResponseWriter calls answered with the status of the answer to a mirrored request
+// once it is known. It can flush and tell when its client goes away if the writer it wraps
+// can, so that watches can be mirrored.
+type mirrorResponseWriter struct {
+	http.ResponseWriter
+	answered func(status int)
+	once     sync.Once
+}
+
+func (w *mirrorResponseWriter) WriteHeader(status int) {
+	w.once.Do(func() { w.answered(status) })
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *mirrorResponseWriter) Write(data []byte) (int, error) {
+	w.once.Do(func() { w.answered(http.StatusOK) })
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *mirrorResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *mirrorResponseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
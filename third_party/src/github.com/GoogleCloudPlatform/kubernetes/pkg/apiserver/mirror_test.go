@@ -0,0 +1,140 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestNewMirror(t *testing.T) {
+	table := []struct {
+		url   string
+		ratio float64
+		valid bool
+	}{
+		{"http://10.0.0.2:8080", 1, true},
+		{"https://shadow", 0.1, true},
+		{"shadow:8080", 1, false},
+		{"http://", 1, false},
+		{"http://shadow", 0, false},
+		{"http://shadow", 1.5, false},
+	}
+	for _, item := range table {
+		if _, err := NewMirror(item.url, item.ratio); (err == nil) != item.valid {
+			t.Errorf("%s with ratio %v: expected valid=%v, got %v", item.url, item.ratio, item.valid, err)
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	var lock sync.Mutex
+	mirrored := []string{}
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		lock.Lock()
+		mirrored = append(mirrored, req.Method+" "+req.URL.RequestURI()+" "+req.Header.Get("X-Test"))
+		lock.Unlock()
+		// The shadow has every object, the apiserver only those created.
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer shadow.Close()
+
+	mirror, err := NewMirror(shadow.URL, 0.5)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sample := 0.0
+	mirror.random = func() float64 {
+		lock.Lock()
+		defer lock.Unlock()
+		return sample
+	}
+	logged := []string{}
+	mirror.logf = func(format string, args ...interface{}) {
+		lock.Lock()
+		defer lock.Unlock()
+		logged = append(logged, format)
+	}
+	config := DefaultConfig()
+	config.Mirror = mirror
+	handler := NewWithConfig(map[string]RESTStorage{"simple": &mapStorage{items: map[string]Simple{}}}, codec, "/prefix/version", config)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	prefix := server.URL + "/prefix/version"
+
+	if code, body := request(t, "POST", prefix+"/simple?sync=true", &Simple{JSONBase: api.JSONBase{ID: "web"}}); code != http.StatusCreated {
+		t.Fatalf("unexpected response %d: %s", code, body)
+	}
+	for _, path := range []string{"/simple?labels=a%3Db", "/simple/web", "/simple/db", "/operations"} {
+		req, _ := http.NewRequest("GET", prefix+path, nil)
+		req.Header.Set("X-Test", "copied")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	// Requests left out of the sample aren't mirrored.
+	lock.Lock()
+	sample = 0.5
+	lock.Unlock()
+	request(t, "GET", prefix+"/simple/web", nil)
+	request(t, "GET", server.URL+"/healthz", nil)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for mirror.Metrics().Mirrored+mirror.Metrics().Failures < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Let any request that shouldn't have been mirrored arrive.
+	time.Sleep(50 * time.Millisecond)
+
+	lock.Lock()
+	defer lock.Unlock()
+	expected := map[string]bool{
+		"GET /prefix/version/simple?labels=a%3Db copied": true,
+		"GET /prefix/version/simple/web copied":          true,
+		"GET /prefix/version/simple/db copied":           true,
+	}
+	if len(mirrored) != len(expected) {
+		t.Errorf("expected %d requests to be mirrored, got %v", len(expected), mirrored)
+	}
+	for _, r := range mirrored {
+		if !expected[r] {
+			t.Errorf("unexpected mirrored request %q", r)
+		}
+	}
+	if metrics := mirror.Metrics(); metrics != (MirrorMetrics{Mirrored: 3, Mismatches: 1}) {
+		t.Errorf("expected the missing pod to be a mismatch, got %#v", metrics)
+	}
+	if len(logged) != 1 {
+		t.Errorf("expected the mismatch to be logged, got %v", logged)
+	}
+}
+
+func TestMirrorDropsWhenFull(t *testing.T) {
+	mirror := &Mirror{queue: make(chan *mirroredRequest, 1)}
+	mirror.enqueue(&mirroredRequest{uri: "/a"})
+	mirror.enqueue(&mirroredRequest{uri: "/b"})
+	if metrics := mirror.Metrics(); metrics.Dropped != 1 {
+		t.Errorf("expected the request finding the queue full to be dropped, got %#v", metrics)
+	}
+}
@@ -0,0 +1,206 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// namespaceSegment introduces the namespace of a request in paths of the form
+// ${prefix}/ns/${namespace}/${storage_key}[/${object_name}].
+const namespaceSegment = "ns"
+
+// resourcePath returns the path of resource in the namespace of ctx, the path its objects
+// are served under.
+func (s *APIServer) resourcePath(ctx api.Context, resource string) string {
+	if len(ctx.Namespace) == 0 || ctx.Namespace == api.NamespaceDefault {
+		return path.Join(s.prefix, resource)
+	}
+	return path.Join(s.prefix, namespaceSegment, ctx.Namespace, resource)
+}
+
+// splitNamespace returns the namespace named by the leading "ns/${namespace}" of parts,
+// and the parts that follow it. If parts name no namespace, the request is in the default
+// namespace, except lists, which span all namespaces. ok is false if the namespace named
+// is not valid.
+func splitNamespace(parts []string, method string) (namespace string, rest []string, ok bool) {
+	if len(parts) >= 2 && parts[0] == namespaceSegment {
+		return parts[1], parts[2:], util.IsDNSLabel(parts[1])
+	}
+	if method == "GET" && len(parts) == 1 {
+		return api.NamespaceAll, parts, true
+	}
+	return api.NamespaceDefault, parts, true
+}
+
+// namespaceObject prepares obj, which a client asked to create or update in the namespace
+// of ctx, to be handed to its storage: it is put in the namespace. Storages store it under
+// its ID qualified with the namespace, see api.QualifiedID.
+func namespaceObject(ctx api.Context, obj interface{}) error {
+	jsonBase, err := api.FindJSONBase(obj)
+	if err != nil {
+		// Objects without a JSONBase are not namespaced.
+		return nil
+	}
+	namespace := jsonBase.Namespace()
+	if len(namespace) != 0 && namespace != ctx.Namespace {
+		return NewInvalidErr(objectKind(obj), jsonBase.ID(), api.ValidationErrorList{
+			api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "namespace", BadValue: namespace},
+		})
+	}
+	if strings.Contains(jsonBase.ID(), api.NamespaceSeparator) {
+		return NewInvalidErr(objectKind(obj), jsonBase.ID(), api.ValidationErrorList{
+			api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "id", BadValue: jsonBase.ID()},
+		})
+	}
+	jsonBase.SetNamespace(ctx.Namespace)
+	return nil
+}
+
+// checkID returns an error satisfying IsNotFound if no object can be called id. IDs may not
+// contain api.NamespaceSeparator, so that they can't name an object of another namespace.
+func checkID(kind, id string) error {
+	if strings.Contains(id, api.NamespaceSeparator) {
+		return NewNotFoundErr(kind, id)
+	}
+	return nil
+}
+
+// presentObject prepares obj, or each item of obj if it is a list, as they are returned
+// by a storage, to be sent to a client: objects stored without a namespace are put in the
+// default namespace.
+func presentObject(obj interface{}) {
+	if items, ok := listItems(obj); ok {
+		for i := 0; i < items.Len(); i++ {
+			presentObject(items.Index(i).Addr().Interface())
+		}
+		return
+	}
+	if _, ok := obj.(*api.Status); ok {
+		return
+	}
+	jsonBase, err := api.FindJSONBase(obj)
+	if err != nil {
+		return
+	}
+	if len(jsonBase.Namespace()) == 0 {
+		jsonBase.SetNamespace(api.NamespaceDefault)
+	}
+}
+
+// inNamespace returns true if obj is in namespace.
+func inNamespace(obj interface{}, namespace string) bool {
+	if namespace == api.NamespaceAll {
+		return true
+	}
+	jsonBase, err := api.FindJSONBaseRO(obj)
+	if err != nil {
+		return true
+	}
+	if len(jsonBase.Namespace) == 0 {
+		return namespace == api.NamespaceDefault
+	}
+	return jsonBase.Namespace == namespace
+}
+
+// filterNamespace removes the items of list that are not in namespace.
+func filterNamespace(list interface{}, namespace string) {
+	items, ok := listItems(list)
+	if !ok || namespace == api.NamespaceAll {
+		return
+	}
+	kept := reflect.MakeSlice(items.Type(), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		if inNamespace(items.Index(i).Addr().Interface(), namespace) {
+			kept = reflect.Append(kept, items.Index(i))
+		}
+	}
+	items.Set(kept)
+}
+
+// listItems returns the Items slice of the list obj points to.
+func listItems(obj interface{}) (reflect.Value, bool) {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+	items := v.Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice {
+		return reflect.Value{}, false
+	}
+	return items, true
+}
+
+// presentResults forwards the results of an asynchronous storage operation, passing each
+// through presentObject.
+func presentResults(in <-chan interface{}) <-chan interface{} {
+	out := make(chan interface{})
+	go func() {
+		defer util.HandleCrash()
+		defer close(out)
+		for obj := range in {
+			presentObject(obj)
+			out <- obj
+		}
+	}()
+	return out
+}
+
+// namespaceWatcher passes the objects of the events of a watch through presentObject, and
+// drops events for objects outside its namespace.
+type namespaceWatcher struct {
+	watching  watch.Interface
+	namespace string
+	result    chan watch.Event
+}
+
+func newNamespaceWatcher(watching watch.Interface, namespace string) *namespaceWatcher {
+	w := &namespaceWatcher{
+		watching:  watching,
+		namespace: namespace,
+		result:    make(chan watch.Event),
+	}
+	go w.loop()
+	return w
+}
+
+func (w *namespaceWatcher) loop() {
+	defer util.HandleCrash()
+	defer close(w.result)
+	for event := range w.watching.ResultChan() {
+		presentObject(event.Object)
+		if inNamespace(event.Object, w.namespace) {
+			w.result <- event
+		}
+	}
+}
+
+// ResultChan implements watch.Interface.
+func (w *namespaceWatcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// Stop implements watch.Interface.
+func (w *namespaceWatcher) Stop() {
+	w.watching.Stop()
+}
@@ -0,0 +1,190 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// mapStorage keeps Simples in memory by their ID, qualified with their namespace.
+type mapStorage struct {
+	items map[string]Simple
+}
+
+func (s *mapStorage) New() interface{} {
+	return &Simple{}
+}
+
+func (s *mapStorage) List(ctx api.Context, label labels.Selector) (interface{}, error) {
+	list := &SimpleList{}
+	for _, item := range s.items {
+		list.Items = append(list.Items, item)
+	}
+	return list, nil
+}
+
+func (s *mapStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	item, ok := s.items[api.QualifiedID(ctx.Namespace, id)]
+	if !ok {
+		return nil, NewNotFoundErr("simple", id)
+	}
+	return &item, nil
+}
+
+func (s *mapStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
+	delete(s.items, api.QualifiedID(ctx.Namespace, id))
+	return MakeAsync(func() (interface{}, error) {
+		return &api.Status{Status: api.StatusSuccess}, nil
+	}), nil
+}
+
+func (s *mapStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	simple := obj.(*Simple)
+	id := api.QualifiedID(simple.Namespace, simple.ID)
+	if _, ok := s.items[id]; ok {
+		return nil, NewAlreadyExistsErr("simple", simple.ID)
+	}
+	s.items[id] = *simple
+	return MakeAsync(func() (interface{}, error) {
+		return simple, nil
+	}), nil
+}
+
+func (s *mapStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	simple := obj.(*Simple)
+	s.items[api.QualifiedID(simple.Namespace, simple.ID)] = *simple
+	return MakeAsync(func() (interface{}, error) {
+		return simple, nil
+	}), nil
+}
+
+func request(t *testing.T, method, url string, obj interface{}) (int, []byte) {
+	var body []byte
+	if obj != nil {
+		body = []byte(api.EncodeOrDie(obj))
+	}
+	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return response.StatusCode, data
+}
+
+func TestNamespaces(t *testing.T) {
+	storage := &mapStorage{items: map[string]Simple{
+		// Stored before namespaces existed.
+		"legacy": {JSONBase: api.JSONBase{ID: "legacy"}, Name: "legacy"},
+	}}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	prefix := server.URL + "/prefix/version"
+
+	for _, path := range []string{"/simple", "/ns/a/simple", "/ns/b/simple"} {
+		code, body := request(t, "POST", prefix+path+"?sync=true", &Simple{JSONBase: api.JSONBase{ID: "web"}, Name: path})
+		if code != http.StatusCreated {
+			t.Fatalf("%s: unexpected response %d: %s", path, code, body)
+		}
+	}
+	stored := []string{}
+	for id := range storage.items {
+		stored = append(stored, id)
+	}
+	sort.Strings(stored)
+	if expected := []string{"legacy", "web", "web_a", "web_b"}; !reflect.DeepEqual(stored, expected) {
+		t.Errorf("expected objects to be stored under qualified IDs %v, got %v", expected, stored)
+	}
+	if item := storage.items["web_a"]; item.ID != "web" || item.Namespace != "a" {
+		t.Errorf("expected the storage to be handed the ID the client chose: %#v", item)
+	}
+
+	var item Simple
+	code, body := request(t, "GET", prefix+"/ns/a/simple/web", nil)
+	if err := codec.DecodeInto(body, &item); err != nil || code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", code, body)
+	}
+	if item.ID != "web" || item.Namespace != "a" || item.Name != "/ns/a/simple" {
+		t.Errorf("unexpected item: %#v", item)
+	}
+	code, body = request(t, "GET", prefix+"/simple/legacy", nil)
+	if err := codec.DecodeInto(body, &item); err != nil || code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", code, body)
+	}
+	if item.ID != "legacy" || item.Namespace != api.NamespaceDefault {
+		t.Errorf("expected legacy objects to be in the default namespace: %#v", item)
+	}
+	if code, body := request(t, "GET", prefix+"/simple/web_a", nil); code != http.StatusNotFound {
+		t.Errorf("expected objects of other namespaces not to be found by their qualified ID: %d %s", code, body)
+	}
+	if code, body := request(t, "DELETE", prefix+"/simple/web_a", nil); code != http.StatusNotFound {
+		t.Errorf("expected objects of other namespaces not to be deleted by their qualified ID: %d %s", code, body)
+	}
+
+	lists := map[string][]string{
+		"/simple":            {"a/web", "b/web", "default/legacy", "default/web"},
+		"/ns/a/simple":       {"a/web"},
+		"/ns/default/simple": {"default/legacy", "default/web"},
+	}
+	for path, expected := range lists {
+		var list SimpleList
+		code, body := request(t, "GET", prefix+path, nil)
+		if err := codec.DecodeInto(body, &list); err != nil || code != http.StatusOK {
+			t.Fatalf("%s: unexpected response %d: %s", path, code, body)
+		}
+		names := []string{}
+		for _, item := range list.Items {
+			names = append(names, item.Namespace+"/"+item.ID)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expected %v, got %v", path, expected, names)
+		}
+	}
+
+	code, body = request(t, "POST", prefix+"/simple", &Simple{JSONBase: api.JSONBase{ID: "web_a"}})
+	if code != 422 {
+		t.Errorf("expected an ID that looks qualified to be invalid, got %d: %s", code, body)
+	}
+	if item := storage.items["web_a"]; item.Namespace != "a" {
+		t.Errorf("expected the object of namespace a to be left alone: %#v", item)
+	}
+	code, body = request(t, "PUT", prefix+"/ns/a/simple/web", &Simple{JSONBase: api.JSONBase{ID: "web", Namespace: "b"}})
+	if code != 422 {
+		t.Errorf("expected an object of another namespace to be invalid, got %d: %s", code, body)
+	}
+	if code, body := request(t, "GET", prefix+"/ns/Not_A_Label/simple", nil); code != http.StatusNotFound {
+		t.Errorf("expected an invalid namespace not to be found, got %d: %s", code, body)
+	}
+}
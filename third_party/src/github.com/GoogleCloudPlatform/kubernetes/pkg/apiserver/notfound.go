@@ -0,0 +1,88 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// maxSuggestionDistance is the largest edit distance between an unknown resource and one the
+// apiserver serves at which the latter is suggested, enough for most typos such as a missing
+// plural.
+const maxSuggestionDistance = 2
+
+// notFound answers a request for a path s doesn't serve with an api.Status that lists the
+// resources s serves. resource is the segment of the path that names a resource, if any: the
+// closest resource to it is suggested when it isn't one.
+func (s *APIServer) notFound(w http.ResponseWriter, req *http.Request, resource string) {
+	resources := make([]string, 0, len(s.storage))
+	for name := range s.storage {
+		resources = append(resources, name)
+	}
+	sort.Strings(resources)
+	// The path of the request URL may have had a prefix stripped by the handler.
+	path := strings.SplitN(req.RequestURI, "?", 2)[0]
+	if len(path) == 0 {
+		path = req.URL.Path
+	}
+	status := notFoundStatus(path, resource, resources)
+	writeJSON(status.Code, s.codec, status, w)
+}
+
+// notFoundStatus returns the status of a request for path, which names resource unless it
+// is empty, on an apiserver that serves resources.
+func notFoundStatus(path, resource string, resources []string) *api.Status {
+	status := &api.Status{
+		Status: api.StatusFailure,
+		Code:   http.StatusNotFound,
+		Reason: api.ReasonTypeNotFound,
+	}
+	known := false
+	for _, name := range resources {
+		known = known || name == resource
+	}
+	if len(resource) == 0 || known {
+		status.Message = fmt.Sprintf("the server has nothing at %q.", path)
+	} else {
+		status.Message = fmt.Sprintf("the server doesn't have a resource %q", resource)
+		status.Details = &api.StatusDetails{Kind: resource}
+		if suggestion, ok := suggestResource(resource, resources); ok {
+			status.Message += fmt.Sprintf(", did you mean %q?", suggestion)
+		} else {
+			status.Message += "."
+		}
+	}
+	status.Message += fmt.Sprintf(" The resources are: %s", strings.Join(resources, ", "))
+	return status
+}
+
+// suggestResource returns the resource closest to name among resources, the first one in
+// order among those as close, if it is within maxSuggestionDistance edits of name.
+func suggestResource(name string, resources []string) (string, bool) {
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, resource := range resources {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(resource)); distance < bestDistance {
+			best, bestDistance = resource, distance
+		}
+	}
+	return best, bestDistance <= maxSuggestionDistance
+}
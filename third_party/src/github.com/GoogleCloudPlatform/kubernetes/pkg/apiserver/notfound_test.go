@@ -0,0 +1,120 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSuggestResource(t *testing.T) {
+	resources := []string{"builds", "minions", "pods", "replicationControllers", "services"}
+	table := []struct {
+		name       string
+		suggestion string
+	}{
+		{"pod", "pods"},
+		{"Pods", "pods"},
+		{"servics", "services"},
+		{"minion", "minions"},
+		{"replicationcontroller", "replicationControllers"},
+		{"bulids", "builds"},
+		{"po", "pods"},
+		{"xyz", ""},
+		{"events", ""},
+		{"", ""},
+	}
+	for _, item := range table {
+		suggestion, ok := suggestResource(item.name, resources)
+		if !ok {
+			suggestion = ""
+		}
+		if suggestion != item.suggestion {
+			t.Errorf("%q: expected %q, got %q", item.name, item.suggestion, suggestion)
+		}
+	}
+}
+
+func TestNotFoundStatus(t *testing.T) {
+	storage := map[string]RESTStorage{
+		"simple": &SimpleRESTStorage{},
+		"other":  &SimpleRESTStorage{},
+	}
+	server := httptest.NewServer(New(storage, codec, "/prefix/version"))
+	defer server.Close()
+
+	table := []struct {
+		path    string
+		message string
+		kind    string
+	}{
+		{"/prefix/version/simpel", `the server doesn't have a resource "simpel", did you mean "simple"? The resources are: other, simple`, "simpel"},
+		{"/prefix/version/watch/simpel", `the server doesn't have a resource "simpel", did you mean "simple"? The resources are: other, simple`, "simpel"},
+		{"/prefix/version/unrelated", `the server doesn't have a resource "unrelated". The resources are: other, simple`, "unrelated"},
+		{"/prefix/version/simple/a/b/c", `the server has nothing at "/prefix/version/simple/a/b/c". The resources are: other, simple`, ""},
+		{"/prefix/version/operations/1/extra", `the server has nothing at "/prefix/version/operations/1/extra". The resources are: other, simple`, ""},
+		{"/prefix/version/operations/missing", `operation "missing" not found`, "operation"},
+	}
+	for _, item := range table {
+		code, body := request(t, "GET", server.URL+item.path, nil)
+		if code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d: %s", item.path, code, body)
+			continue
+		}
+		var status struct {
+			Kind    string `json:"kind"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Reason  string `json:"reason"`
+			Code    int    `json:"code"`
+			Details *struct {
+				Kind string `json:"kind"`
+			} `json:"details"`
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			t.Errorf("%s: expected a status, got %s", item.path, body)
+			continue
+		}
+		if status.Kind != "Status" || status.Status != "failure" || status.Reason != "not_found" || status.Code != http.StatusNotFound {
+			t.Errorf("%s: unexpected status %s", item.path, body)
+		}
+		if status.Message != item.message {
+			t.Errorf("%s: expected %q, got %q", item.path, item.message, status.Message)
+		}
+		kind := ""
+		if status.Details != nil {
+			kind = status.Details.Kind
+		}
+		if kind != item.kind {
+			t.Errorf("%s: expected details of %q, got %q", item.path, item.kind, kind)
+		}
+	}
+}
+
+func TestNotFoundWithoutIndex(t *testing.T) {
+	handler := NewWithConfig(map[string]RESTStorage{"simple": &SimpleRESTStorage{}}, codec, "/prefix/version", Config{})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	code, body := request(t, "GET", server.URL+"/simple", nil)
+	if code != http.StatusNotFound || !strings.Contains(string(body), `The resources are: simple`) {
+		t.Errorf("expected a status listing the resources, got %d: %s", code, body)
+	}
+}
@@ -17,57 +17,152 @@ limitations under the License.
 package apiserver
 
 import (
+	"fmt"
 	"net/http"
+	"net/url"
+	"path"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
 )
 
 type OperationHandler struct {
 	ops   *Operations
 	codec Codec
+	// checkParams rejects query parameters of a request that aren't allowed.
+	checkParams func(query url.Values, allowed util.StringSet) error
+	// notFound answers the requests for paths that aren't operations, see APIServer.notFound.
+	notFound func(w http.ResponseWriter, req *http.Request, resource string)
+	// prefix is the path the API is served under, which operations are polled below.
+	prefix string
 }
 
+// operationListParameters are the query parameters of requests listing operations.
+var operationListParameters = util.NewStringSet("resource", "name", "owner", "status")
+
+// ServeHTTP serves the operations of h:
+//
+//	GET /operations      list the operations, filtered by the query parameters
+//	                     resource, name, owner and status (pending or complete)
+//	GET /operations/id   get the status or result of an operation
+//
+// The answer for a single operation is its status or result, so what the operation acts on
+// is sent in the headers X-Operation-Resource, X-Operation-Namespace, X-Operation-Name and
+// X-Operation-Owner, when it is known. Operations in progress are answered as writePending
+// does.
 func (h *OperationHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	parts := splitPath(req.URL.Path)
 	if len(parts) > 1 || req.Method != "GET" {
-		notFound(w, req)
+		h.notFound(w, req, "")
+		return
+	}
+	allowed := util.NewStringSet()
+	if len(parts) == 0 {
+		allowed = operationListParameters
+	}
+	if err := h.checkParams(req.URL.Query(), allowed); err != nil {
+		errorJSON(NewBadRequestErr("operation", strings.Join(parts, ""), err), h.codec, w)
+		return
+	}
+	// The parameters tolerated on any request are checked as they are for other requests.
+	p := &optionParser{query: req.URL.Query()}
+	p.requestOptions()
+	filter := parseOperationFilter(p)
+	if err := p.err(); err != nil {
+		errorJSON(err, h.codec, w)
 		return
 	}
 	if len(parts) == 0 {
-		// List outstanding operations.
-		list := h.ops.List()
+		list := h.ops.List(filter)
 		writeJSON(http.StatusOK, h.codec, list, w)
 		return
 	}
 
 	op := h.ops.Get(parts[0])
 	if op == nil {
-		notFound(w, req)
+		errorJSON(NewNotFoundErr("operation", parts[0]), h.codec, w)
 		return
 	}
 
+	for header, value := range map[string]string{
+		"X-Operation-Resource":  op.Info.Resource,
+		"X-Operation-Namespace": op.Info.Namespace,
+		"X-Operation-Name":      op.Info.Name,
+		"X-Operation-Owner":     op.Info.Owner,
+	} {
+		if len(value) > 0 {
+			w.Header().Set(header, value)
+		}
+	}
 	obj, complete := op.StatusOrResult()
 	if complete {
 		writeJSON(http.StatusOK, h.codec, obj, w)
 	} else {
-		writeJSON(http.StatusAccepted, h.codec, obj, w)
+		op.writePending(obj, h.prefix, h.codec, w)
+	}
+}
+
+// OperationInfo describes the request an operation was created for.
+type OperationInfo struct {
+	// Resource is the resource the request acted on, e.g. "pods".
+	Resource string
+	// Namespace and Name identify the object the request acted on. Name is empty if it isn't
+	// known.
+	Namespace string
+	Name      string
+	// Owner is the authenticated user who made the request, or empty if it wasn't
+	// authenticated.
+	Owner string
+}
+
+// OperationFilter selects the operations Operations.List returns. Empty fields match any
+// operation.
+type OperationFilter struct {
+	Resource string
+	Name     string
+	Owner    string
+	// Status is api.ServerOpPending or api.ServerOpComplete.
+	Status string
+}
+
+// parseOperationFilter returns the OperationFilter given by the parameters p parses.
+func parseOperationFilter(p *optionParser) OperationFilter {
+	filter := OperationFilter{
+		Resource: p.query.Get("resource"),
+		Name:     p.query.Get("name"),
+		Owner:    p.query.Get("owner"),
+		Status:   p.query.Get("status"),
+	}
+	switch filter.Status {
+	case "", api.ServerOpPending, api.ServerOpComplete:
+	default:
+		p.invalid("status", filter.Status, fmt.Sprintf("should be %s or %s", api.ServerOpPending, api.ServerOpComplete))
 	}
+	return filter
 }
 
 // Operation represents an ongoing action which the server is performing.
 type Operation struct {
-	ID       string
+	ID string
+	// Info describes the request the operation was created for. It doesn't change.
+	Info     OperationInfo
 	result   interface{}
+	progress interface{}
 	awaiting <-chan interface{}
+	created  time.Time
 	finished *time.Time
 	lock     sync.Mutex
 	notify   chan struct{}
+	store    OperationStore
+	// ops records how long the operation took once it completes, if it is set.
+	ops *Operations
 }
 
 // Operations tracks all the ongoing operations.
@@ -78,24 +173,58 @@ type Operations struct {
 	// 'lock' guards the ops map.
 	lock sync.Mutex
 	ops  map[string]*Operation
+
+	// If set, operations are also recorded here, so that they can be found after a restart.
+	store OperationStore
+
+	// latencies are the durations of the recent operations of each resource, guarded by
+	// 'lock'.
+	latencies map[string]*movingAverage
 }
 
 // NewOperations returns a new Operations repository.
 func NewOperations() *Operations {
 	ops := &Operations{
-		ops: map[string]*Operation{},
+		ops:       map[string]*Operation{},
+		latencies: map[string]*movingAverage{},
 	}
 	go util.Forever(func() { ops.expire(10 * time.Minute) }, 5*time.Minute)
 	return ops
 }
 
+// NewPersistentOperations returns a new Operations repository which also records
+// operations in store. Operations missing from memory, e.g. because the apiserver
+// restarted, are looked up in store.
+func NewPersistentOperations(store OperationStore) *Operations {
+	ops := NewOperations()
+	ops.store = store
+	// Start numbering where no earlier apiserver process could have reached, so that the
+	// ids of stored operations are never reused.
+	ops.lastID = time.Now().UnixNano()
+	return ops
+}
+
 // NewOperation adds a new operation. It is lock-free.
 func (ops *Operations) NewOperation(from <-chan interface{}) *Operation {
+	return ops.NewOperationFor(from, OperationInfo{})
+}
+
+// NewOperationFor adds a new operation for the request info describes. It is lock-free.
+func (ops *Operations) NewOperationFor(from <-chan interface{}, info OperationInfo) *Operation {
 	id := atomic.AddInt64(&ops.lastID, 1)
 	op := &Operation{
 		ID:       strconv.FormatInt(id, 10),
+		Info:     info,
 		awaiting: from,
+		created:  time.Now(),
 		notify:   make(chan struct{}),
+		store:    ops.store,
+		ops:      ops,
+	}
+	if ops.store != nil {
+		if err := ops.store.Start(op.ID); err != nil {
+			glog.Errorf("Unable to record operation %s: %v", op.ID, err)
+		}
 	}
 	go op.wait()
 	go ops.insert(op)
@@ -109,8 +238,8 @@ func (ops *Operations) insert(op *Operation) {
 	ops.ops[op.ID] = op
 }
 
-// List operations for an API client.
-func (ops *Operations) List() api.ServerOpList {
+// List the operations filter selects for an API client.
+func (ops *Operations) List(filter OperationFilter) api.ServerOpList {
 	ops.lock.Lock()
 	defer ops.lock.Unlock()
 
@@ -121,16 +250,78 @@ func (ops *Operations) List() api.ServerOpList {
 	sort.StringSlice(ids).Sort()
 	ol := api.ServerOpList{}
 	for _, id := range ids {
-		ol.Items = append(ol.Items, api.ServerOp{JSONBase: api.JSONBase{ID: id}})
+		if item := ops.ops[id].serverOp(); filter.matches(item) {
+			ol.Items = append(ol.Items, item)
+		}
 	}
 	return ol
 }
 
+// matches returns true if filter selects op.
+func (filter OperationFilter) matches(op api.ServerOp) bool {
+	return (len(filter.Resource) == 0 || filter.Resource == op.Resource) &&
+		(len(filter.Name) == 0 || filter.Name == op.Name) &&
+		(len(filter.Owner) == 0 || filter.Owner == op.Owner) &&
+		(len(filter.Status) == 0 || filter.Status == op.Status)
+}
+
+// serverOp returns op as it is listed to API clients.
+func (op *Operation) serverOp() api.ServerOp {
+	op.lock.Lock()
+	defer op.lock.Unlock()
+	status := api.ServerOpPending
+	if op.finished != nil {
+		status = api.ServerOpComplete
+	}
+	return api.ServerOp{
+		JSONBase: api.JSONBase{ID: op.ID, Namespace: op.Info.Namespace},
+		Resource: op.Info.Resource,
+		Name:     op.Info.Name,
+		Owner:    op.Info.Owner,
+		Status:   status,
+	}
+}
+
 // Get returns the operation with the given ID, or nil
 func (ops *Operations) Get(id string) *Operation {
 	ops.lock.Lock()
-	defer ops.lock.Unlock()
-	return ops.ops[id]
+	op := ops.ops[id]
+	ops.lock.Unlock()
+	if op != nil || ops.store == nil {
+		return op
+	}
+	return ops.restore(id)
+}
+
+// restore returns a finished Operation for an operation found only in ops.store, or nil.
+// An operation that was still in progress when it was stored can no longer complete, so its
+// result is a Status telling the client to inspect the object it acted on instead.
+func (ops *Operations) restore(id string) *Operation {
+	result, finished, err := ops.store.Get(id)
+	if err != nil {
+		if !IsNotFound(err) {
+			glog.Errorf("Unable to read operation %s: %v", id, err)
+		}
+		return nil
+	}
+	if !finished {
+		result = &api.Status{
+			Status:  api.StatusFailure,
+			Code:    http.StatusInternalServerError,
+			Reason:  api.ReasonTypeUnknown,
+			Message: fmt.Sprintf("operation %s was interrupted by a server restart; inspect the object it acted on to find out whether it completed", id),
+			Details: &api.StatusDetails{ID: id, Kind: "operation"},
+		}
+	}
+	finishedAt := time.Now()
+	op := &Operation{
+		ID:       id,
+		result:   result,
+		finished: &finishedAt,
+		notify:   make(chan struct{}),
+	}
+	close(op.notify)
+	return op
 }
 
 // Garbage collect operations that have finished longer than maxAge ago.
@@ -148,18 +339,39 @@ func (ops *Operations) expire(maxAge time.Duration) {
 }
 
 // Waits forever for the operation to complete; call via go when
-// the operation is created. Sets op.finished when the operation
-// does complete, and closes the notify channel, in case there
-// are any WaitFor() calls in progress.
+// the operation is created. Records any Progress received before
+// the result, sets op.finished when the operation does complete,
+// and closes the notify channel, in case there are any WaitFor()
+// calls in progress.
 // Does not keep op locked while waiting.
 func (op *Operation) wait() {
 	defer util.HandleCrash()
-	result := <-op.awaiting
+	var result interface{}
+	for obj := range op.awaiting {
+		progress, ok := obj.(Progress)
+		if !ok {
+			result = obj
+			break
+		}
+		op.lock.Lock()
+		op.progress = progress.Object
+		op.lock.Unlock()
+	}
+	// Record the result before announcing it, so that it can't be lost by a restart.
+	if op.store != nil {
+		if err := op.store.Finish(op.ID, result); err != nil {
+			glog.Errorf("Unable to record the result of operation %s: %v", op.ID, err)
+		}
+	}
+
+	finished := time.Now()
+	if op.ops != nil {
+		op.ops.observe(op.Info.Resource, finished.Sub(op.created))
+	}
 
 	op.lock.Lock()
 	defer op.lock.Unlock()
 	op.result = result
-	finished := time.Now()
 	op.finished = &finished
 	close(op.notify)
 }
@@ -167,9 +379,16 @@ func (op *Operation) wait() {
 // WaitFor waits for the specified duration, or until the operation finishes,
 // whichever happens first.
 func (op *Operation) WaitFor(timeout time.Duration) {
+	op.waitFor(timeout, nil)
+}
+
+// waitFor is WaitFor, which also stops waiting once cancel is closed. A nil cancel is never
+// closed.
+func (op *Operation) waitFor(timeout time.Duration, cancel <-chan struct{}) {
 	select {
 	case <-time.After(timeout):
 	case <-op.notify:
+	case <-cancel:
 	}
 }
 
@@ -184,12 +403,22 @@ func (op *Operation) expired(limitTime time.Time) bool {
 }
 
 // StatusOrResult returns status information or the result of the operation if it is complete,
-// with a bool indicating true in the latter case.
+// with a bool indicating true in the latter case. The status information is the latest
+// progress of the operation, if it reported any.
 func (op *Operation) StatusOrResult() (description interface{}, finished bool) {
 	op.lock.Lock()
 	defer op.lock.Unlock()
 
 	if op.finished == nil {
+		if status, ok := op.progress.(*api.Status); ok && status.Details == nil {
+			// Tell the client which operation to poll, as a working Status does.
+			withDetails := *status
+			withDetails.Details = &api.StatusDetails{ID: op.ID, Kind: "operation"}
+			return &withDetails, false
+		}
+		if op.progress != nil {
+			return op.progress, false
+		}
 		return api.Status{
 			Status:  api.StatusWorking,
 			Reason:  api.ReasonTypeWorking,
@@ -198,3 +427,116 @@ func (op *Operation) StatusOrResult() (description interface{}, finished bool) {
 	}
 	return op.result, true
 }
+
+// writePending answers a request for op, which is still in progress, with obj, its status
+// or progress. A status is given the ID and the link of op to poll, and how long ago op was
+// created. The Retry-After header, and the status, suggest when to poll op again: once the
+// recent operations of its resource would have completed, see Operations.RetryAfter.
+func (op *Operation) writePending(obj interface{}, prefix string, codec Codec, w http.ResponseWriter) {
+	elapsed := time.Since(op.created)
+	retryAfter := DefaultRetryAfter
+	if op.ops != nil {
+		retryAfter = op.ops.RetryAfter(op.Info.Resource, elapsed)
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter/time.Second)))
+
+	var status api.Status
+	switch obj := obj.(type) {
+	case api.Status:
+		status = obj
+	case *api.Status:
+		status = *obj
+	default:
+		writeJSON(http.StatusAccepted, codec, obj, w)
+		return
+	}
+	details := api.StatusDetails{ID: op.ID, Kind: "operation"}
+	if status.Details != nil {
+		details = *status.Details
+	}
+	details.ElapsedMilliseconds = int64(elapsed / time.Millisecond)
+	details.RetryAfterSeconds = int(retryAfter / time.Second)
+	status.Details = &details
+	status.SelfLink = path.Join(prefix, "operations", op.ID)
+	writeJSON(http.StatusAccepted, codec, &status, w)
+}
+
+const (
+	// DefaultRetryAfter is how long clients are told to wait before polling an operation of
+	// a resource none of whose operations have completed yet.
+	DefaultRetryAfter = time.Second
+	// MaxRetryAfter caps how long clients are told to wait before polling an operation.
+	MaxRetryAfter = 30 * time.Second
+	// operationLatencyWindow is the number of recent operations of a resource whose
+	// durations are averaged.
+	operationLatencyWindow = 10
+)
+
+// movingAverage is the average of the most recent durations added to it.
+type movingAverage struct {
+	samples []time.Duration
+	next    int
+}
+
+// add adds d, replacing the oldest duration once the window is full.
+func (a *movingAverage) add(d time.Duration) {
+	if len(a.samples) < operationLatencyWindow {
+		a.samples = append(a.samples, d)
+		return
+	}
+	a.samples[a.next] = d
+	a.next = (a.next + 1) % len(a.samples)
+}
+
+func (a *movingAverage) mean() time.Duration {
+	var sum time.Duration
+	for _, d := range a.samples {
+		sum += d
+	}
+	return sum / time.Duration(len(a.samples))
+}
+
+// observe records that an operation of resource took d to complete.
+func (ops *Operations) observe(resource string, d time.Duration) {
+	ops.lock.Lock()
+	defer ops.lock.Unlock()
+	average, ok := ops.latencies[resource]
+	if !ok {
+		average = &movingAverage{}
+		ops.latencies[resource] = average
+	}
+	average.add(d)
+}
+
+// AverageLatency returns the average duration of the recent operations of resource, and
+// false if none has completed yet.
+func (ops *Operations) AverageLatency(resource string) (time.Duration, bool) {
+	ops.lock.Lock()
+	defer ops.lock.Unlock()
+	average, ok := ops.latencies[resource]
+	if !ok {
+		return 0, false
+	}
+	return average.mean(), true
+}
+
+// RetryAfter returns how long a client should wait before polling an operation of resource
+// that has been in progress for elapsed: until the average duration of the recent
+// operations of resource has passed, in whole seconds, at least one and at most
+// MaxRetryAfter. Operations of resources without any completed operation are polled after
+// DefaultRetryAfter.
+func (ops *Operations) RetryAfter(resource string, elapsed time.Duration) time.Duration {
+	average, ok := ops.AverageLatency(resource)
+	if !ok {
+		return DefaultRetryAfter
+	}
+	left := average - elapsed
+	retryAfter := (left + time.Second - 1) / time.Second * time.Second
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	if retryAfter > MaxRetryAfter {
+		retryAfter = MaxRetryAfter
+	}
+	return retryAfter
+}
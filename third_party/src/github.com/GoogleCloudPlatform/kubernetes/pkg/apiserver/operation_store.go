@@ -0,0 +1,163 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+)
+
+// OperationStore persists operations, so that an apiserver can report the outcome of
+// operations started before it restarted. Implementations must be safe for concurrent use.
+type OperationStore interface {
+	// Start records that the operation with the given id is in progress.
+	Start(id string) error
+	// Finish records the result of the operation with the given id.
+	Finish(id string, result interface{}) error
+	// Get returns what is known about the operation with the given id. finished is false if
+	// the operation was started but its result was never recorded. If the store has no
+	// record of the operation, the error satisfies IsNotFound.
+	Get(id string) (result interface{}, finished bool, err error)
+}
+
+// MemoryOperationStore is an OperationStore that keeps operations in memory for a fixed
+// time. It is shared by apiservers in the same process, mainly for testing.
+type MemoryOperationStore struct {
+	ttl  time.Duration
+	lock sync.Mutex
+	ops  map[string]storedOperation
+}
+
+type storedOperation struct {
+	result   interface{}
+	finished bool
+	expires  time.Time
+}
+
+// NewMemoryOperationStore returns a MemoryOperationStore that forgets operations ttl after
+// they were last recorded.
+func NewMemoryOperationStore(ttl time.Duration) *MemoryOperationStore {
+	return &MemoryOperationStore{
+		ttl: ttl,
+		ops: map[string]storedOperation{},
+	}
+}
+
+// Start implements OperationStore.
+func (s *MemoryOperationStore) Start(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ops[id] = storedOperation{expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+// Finish implements OperationStore.
+func (s *MemoryOperationStore) Finish(id string, result interface{}) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ops[id] = storedOperation{result: result, finished: true, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+// Get implements OperationStore.
+func (s *MemoryOperationStore) Get(id string) (interface{}, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	op, ok := s.ops[id]
+	if !ok || time.Now().After(op.expires) {
+		delete(s.ops, id)
+		return nil, false, NewNotFoundErr("operation", id)
+	}
+	return op.result, op.finished, nil
+}
+
+// EtcdOperationStore is an OperationStore that keeps operations in etcd, which expires
+// them after a fixed time.
+type EtcdOperationStore struct {
+	client tools.EtcdGetSet
+	codec  Codec
+	ttl    time.Duration
+}
+
+// etcdOperation is the form in which EtcdOperationStore writes an operation to etcd.
+type etcdOperation struct {
+	Finished bool            `json:"finished"`
+	Result   json.RawMessage `json:"result,omitempty"`
+}
+
+// NewEtcdOperationStore returns an EtcdOperationStore that encodes results with codec, and
+// keeps operations for ttl after they were last recorded.
+func NewEtcdOperationStore(client tools.EtcdGetSet, codec Codec, ttl time.Duration) *EtcdOperationStore {
+	return &EtcdOperationStore{
+		client: client,
+		codec:  codec,
+		ttl:    ttl,
+	}
+}
+
+func makeOperationKey(id string) string {
+	return "/registry/operations/" + id
+}
+
+func (s *EtcdOperationStore) set(id string, op etcdOperation) error {
+	data, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Set(makeOperationKey(id), string(data), uint64(s.ttl.Seconds()))
+	return err
+}
+
+// Start implements OperationStore.
+func (s *EtcdOperationStore) Start(id string) error {
+	return s.set(id, etcdOperation{})
+}
+
+// Finish implements OperationStore.
+func (s *EtcdOperationStore) Finish(id string, result interface{}) error {
+	data, err := s.codec.Encode(result)
+	if err != nil {
+		return err
+	}
+	return s.set(id, etcdOperation{Finished: true, Result: data})
+}
+
+// Get implements OperationStore.
+func (s *EtcdOperationStore) Get(id string) (interface{}, bool, error) {
+	response, err := s.client.Get(makeOperationKey(id), false, false)
+	if tools.IsEtcdNotFound(err) {
+		return nil, false, NewNotFoundErr("operation", id)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	var op etcdOperation
+	if err := json.Unmarshal([]byte(response.Node.Value), &op); err != nil {
+		return nil, false, err
+	}
+	if !op.Finished {
+		return nil, false, nil
+	}
+	result, err := s.codec.Decode(op.Result)
+	if err != nil {
+		return nil, false, err
+	}
+	return result, true, nil
+}
@@ -21,11 +21,13 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
 )
 
 func TestOperation(t *testing.T) {
@@ -91,6 +93,74 @@ func TestOperation(t *testing.T) {
 	}
 }
 
+func TestOperationProgress(t *testing.T) {
+	ops := NewOperations()
+	c := make(chan interface{})
+	op := ops.NewOperation(c)
+
+	c <- Progress{ProgressStatus("halfway")}
+	c <- Progress{"three quarters"}
+	c <- Progress{ProgressStatus("almost done")}
+	status := waitForProgress(t, op, "almost done")
+	if status.Details == nil || status.Details.ID != op.ID {
+		t.Errorf("expected the progress to name the operation, got %#v", status.Details)
+	}
+	op.WaitFor(10 * time.Millisecond)
+	if _, complete := op.StatusOrResult(); complete {
+		t.Errorf("expected the operation to wait for its final result")
+	}
+
+	c <- "All done"
+	op.WaitFor(time.Minute)
+	if obj, complete := op.StatusOrResult(); !complete || obj != "All done" {
+		t.Errorf("expected the final result, got %#v (complete: %t)", obj, complete)
+	}
+}
+
+// waitForProgress waits until op reports a working Status with message, failing the test if
+// op completes first.
+func waitForProgress(t *testing.T, op *Operation, message string) *api.Status {
+	for start := time.Now(); time.Since(start) < time.Minute; time.Sleep(time.Millisecond) {
+		obj, complete := op.StatusOrResult()
+		if complete {
+			t.Fatalf("expected progress not to complete the operation, got %#v", obj)
+		}
+		if status, ok := obj.(*api.Status); ok && status.Message == message {
+			if status.Status != api.StatusWorking {
+				t.Errorf("unexpected progress %#v", status)
+			}
+			return status
+		}
+	}
+	t.Fatalf("operation never reported %q", message)
+	return nil
+}
+
+func TestMakeAsyncWithProgress(t *testing.T) {
+	proceed := make(chan struct{})
+	out := MakeAsyncWithProgress(func(progress ProgressFunc) (interface{}, error) {
+		progress(ProgressStatus("working"))
+		<-proceed
+		return "done", nil
+	})
+	op := NewOperations().NewOperation(out)
+	waitForProgress(t, op, "working")
+	close(proceed)
+	op.WaitFor(time.Minute)
+	if obj, complete := op.StatusOrResult(); !complete || obj != "done" {
+		t.Errorf("expected the final result, got %#v (complete: %t)", obj, complete)
+	}
+
+	out = MakeAsyncWithProgress(func(progress ProgressFunc) (interface{}, error) {
+		progress("step 1")
+		progress("step 2")
+		return "result", nil
+	})
+	if obj := FinalResult(out); obj != "result" {
+		t.Errorf("expected FinalResult to skip the progress, got %#v", obj)
+	}
+}
+
 func TestOperationsList(t *testing.T) {
 	simpleStorage := &SimpleRESTStorage{}
 	handler := New(map[string]RESTStorage{
@@ -139,6 +209,106 @@ func TestOperationsList(t *testing.T) {
 	}
 }
 
+func TestOperationsListFilter(t *testing.T) {
+	proceed := make(chan struct{})
+	defer close(proceed)
+	simpleStorage := &SimpleRESTStorage{
+		injectedFunction: func(obj interface{}) (interface{}, error) {
+			if id, ok := obj.(string); ok && id == "blocked" {
+				<-proceed
+			}
+			return &api.Status{Status: api.StatusSuccess}, nil
+		},
+	}
+	handler := New(map[string]RESTStorage{"foo": simpleStorage}, codec, "/prefix/version")
+	handler.SetAuthenticator(BasicAuthenticator{"alice": "secret"})
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	// The deletion of "blocked" stays pending until the test ends.
+	request, _ := http.NewRequest("DELETE", server.URL+"/prefix/version/ns/other/foo/blocked?wait=0", nil)
+	request.SetBasicAuth("alice", "secret")
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var status api.Status
+	extractBody(response, &status)
+	if status.Details == nil || len(status.Details.ID) == 0 {
+		t.Fatalf("expected a pending operation, got %#v", status)
+	}
+	ids := map[string]string{"blocked": status.Details.ID}
+	done := handler.ops.NewOperationFor(closedChannel(), OperationInfo{Resource: "foo", Name: "done"})
+	done.WaitFor(time.Minute)
+	ids["done"] = done.ID
+
+	// Operations are inserted in the background.
+	for i := 0; len(handler.ops.List(OperationFilter{Resource: "foo"}).Items) < 2 && i < 100; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	table := []struct {
+		query    string
+		expected []string
+	}{
+		{"resource=foo&name=blocked", []string{"blocked"}},
+		{"owner=alice", []string{"blocked"}},
+		{"status=pending&resource=foo", []string{"blocked"}},
+		{"status=complete&name=done", []string{"done"}},
+		{"resource=bar", []string{}},
+	}
+	for _, item := range table {
+		response, err := http.Get(server.URL + "/prefix/version/operations?" + item.query)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var list api.ServerOpList
+		if _, err := extractBody(response, &list); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		actual := []string{}
+		for _, op := range list.Items {
+			actual = append(actual, op.Name)
+			if op.ID != ids[op.Name] {
+				t.Errorf("%s: unexpected operation %#v", item.query, op)
+			}
+		}
+		if !reflect.DeepEqual(actual, item.expected) {
+			t.Errorf("%s: expected %v, got %v", item.query, item.expected, actual)
+		}
+	}
+	response, err = http.Get(server.URL + "/prefix/version/operations?status=running")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected an invalid status to be refused, got %d", response.StatusCode)
+	}
+
+	response, err = http.Get(server.URL + "/prefix/version/operations/" + ids["blocked"])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"X-Operation-Resource":  "foo",
+		"X-Operation-Namespace": "other",
+		"X-Operation-Name":      "blocked",
+		"X-Operation-Owner":     "alice",
+	}
+	for header, value := range expected {
+		if actual := response.Header.Get(header); actual != value {
+			t.Errorf("expected %s %q, got %q", header, value, actual)
+		}
+	}
+}
+
+// closedChannel returns a channel which yields a successful Status.
+func closedChannel() <-chan interface{} {
+	c := make(chan interface{}, 1)
+	c <- &api.Status{Status: api.StatusSuccess}
+	close(c)
+	return c
+}
+
 func TestOpGet(t *testing.T) {
 	simpleStorage := &SimpleRESTStorage{}
 	handler := New(map[string]RESTStorage{
@@ -195,3 +365,159 @@ func TestOpGet(t *testing.T) {
 		t.Errorf("Unexpected response %#v", response)
 	}
 }
+
+func TestPersistentOperationsSurviveRestart(t *testing.T) {
+	store := NewMemoryOperationStore(time.Minute)
+	ops := NewPersistentOperations(store)
+
+	done := make(chan interface{}, 1)
+	done <- &api.Status{Status: api.StatusSuccess}
+	finished := ops.NewOperation(done)
+	finished.WaitFor(time.Second)
+	lost := ops.NewOperation(make(chan interface{}))
+
+	// A new Operations shares nothing with the old one but the store.
+	restarted := NewPersistentOperations(store)
+	op := restarted.Get(finished.ID)
+	if op == nil {
+		t.Fatalf("expected finished operation %s to be restored", finished.ID)
+	}
+	if obj, complete := op.StatusOrResult(); !complete || obj.(*api.Status).Status != api.StatusSuccess {
+		t.Errorf("unexpected result %#v", obj)
+	}
+
+	op = restarted.Get(lost.ID)
+	if op == nil {
+		t.Fatalf("expected in-flight operation %s to be restored", lost.ID)
+	}
+	obj, complete := op.StatusOrResult()
+	status, ok := obj.(*api.Status)
+	if !complete || !ok || status.Status != api.StatusFailure || status.Reason != api.ReasonTypeUnknown {
+		t.Errorf("expected an unknown failure for an interrupted operation, got %#v", obj)
+	}
+
+	if restarted.Get("missing") != nil {
+		t.Errorf("expected no operation for an unknown id")
+	}
+	if next := restarted.NewOperation(make(chan interface{})); next.ID == finished.ID || next.ID == lost.ID {
+		t.Errorf("expected new operation ids not to collide with stored ones, got %s", next.ID)
+	}
+}
+
+func TestEtcdOperationStore(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.ExpectNotFoundGet("/registry/operations/missing")
+	store := NewEtcdOperationStore(fakeClient, codec, time.Minute)
+
+	if _, _, err := store.Get("missing"); !IsNotFound(err) {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if err := store.Start("1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, finished, err := store.Get("1"); err != nil || finished {
+		t.Errorf("expected an unfinished operation, got %v %v", finished, err)
+	}
+	if err := store.Finish("1", &Simple{Name: "foo"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, finished, err := store.Get("1")
+	if err != nil || !finished {
+		t.Fatalf("expected a finished operation, got %v %v", finished, err)
+	}
+	if simple, ok := result.(*Simple); !ok || simple.Name != "foo" {
+		t.Errorf("unexpected result %#v", result)
+	}
+}
+
+func TestOperationLatencyAverage(t *testing.T) {
+	ops := NewOperations()
+	if _, ok := ops.AverageLatency("foo"); ok {
+		t.Errorf("expected no average before any operation completes")
+	}
+	if retryAfter := ops.RetryAfter("foo", 0); retryAfter != DefaultRetryAfter {
+		t.Errorf("expected the default retry after, got %v", retryAfter)
+	}
+
+	for i := 0; i < operationLatencyWindow; i++ {
+		ops.observe("foo", time.Second)
+	}
+	ops.observe("foo", 11*time.Second)
+	// The oldest second has been replaced.
+	if average, _ := ops.AverageLatency("foo"); average != 2*time.Second {
+		t.Errorf("expected an average of 2s, got %v", average)
+	}
+	table := map[time.Duration]time.Duration{
+		0:                       2 * time.Second,
+		500 * time.Millisecond:  2 * time.Second,
+		1500 * time.Millisecond: time.Second,
+		10 * time.Second:        time.Second,
+	}
+	for elapsed, expected := range table {
+		if retryAfter := ops.RetryAfter("foo", elapsed); retryAfter != expected {
+			t.Errorf("after %v: expected %v, got %v", elapsed, expected, retryAfter)
+		}
+	}
+	ops.observe("bar", time.Hour)
+	if retryAfter := ops.RetryAfter("bar", 0); retryAfter != MaxRetryAfter {
+		t.Errorf("expected %v, got %v", MaxRetryAfter, retryAfter)
+	}
+
+	c := make(chan interface{}, 1)
+	op := ops.NewOperationFor(c, OperationInfo{Resource: "baz"})
+	c <- "done"
+	op.WaitFor(10 * time.Second)
+	if _, ok := ops.AverageLatency("baz"); !ok {
+		t.Errorf("expected the completed operation to be averaged")
+	}
+}
+
+func TestPendingOperationHints(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	storage := &SimpleRESTStorage{
+		injectedFunction: func(obj interface{}) (interface{}, error) {
+			<-release
+			return obj, nil
+		},
+	}
+	handler := New(map[string]RESTStorage{"foo": storage}, codec, "/prefix/version")
+	handler.asyncOpWait = 0
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data, _ := codec.Encode(Simple{Name: "foo"})
+	response, err := http.Post(server.URL+"/prefix/version/foo", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var status api.Status
+	if _, err := extractBody(response, &status); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusAccepted || status.Details == nil || len(status.Details.ID) == 0 {
+		t.Fatalf("unexpected response: %d %#v", response.StatusCode, status)
+	}
+	id := status.Details.ID
+	selfLink := "/prefix/version/operations/" + id
+	if status.SelfLink != selfLink || status.Details.RetryAfterSeconds != 1 || response.Header.Get("Retry-After") != "1" {
+		t.Errorf("unexpected hints: %#v %#v %v", status, status.Details, response.Header)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	response, err = http.Get(server.URL + selfLink)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	status = api.Status{}
+	if _, err := extractBody(response, &status); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusAccepted || status.Details == nil {
+		t.Fatalf("unexpected response: %d %#v", response.StatusCode, status)
+	}
+	if status.SelfLink != selfLink || status.Details.ID != id || status.Details.ElapsedMilliseconds < 10 ||
+		status.Details.RetryAfterSeconds != 1 || response.Header.Get("Retry-After") != "1" {
+		t.Errorf("unexpected hints: %#v %#v %v", status, status.Details, response.Header)
+	}
+}
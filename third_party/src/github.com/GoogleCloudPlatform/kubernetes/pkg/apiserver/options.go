@@ -0,0 +1,124 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// ResourceOptions describes what clients can do with a resource, its objects, or their
+// subresources. It answers OPTIONS requests to their paths, along with an Allow header
+// naming the same methods.
+type ResourceOptions struct {
+	// Kind is always "ResourceOptions".
+	Kind     string `json:"kind"`
+	Resource string `json:"resource"`
+	// Methods are the methods served on the path, including OPTIONS.
+	Methods []string `json:"methods"`
+	// Parameters are the query parameters those methods accept, e.g. "labels".
+	Parameters []string `json:"parameters"`
+	// Watch is true if the resource can be watched at ${prefix}/watch/${resource}.
+	Watch bool `json:"watch"`
+}
+
+// APIOptions lists the ResourceOptions of the collections of all resources, sorted by
+// resource. It answers OPTIONS requests to the prefix of the API.
+type APIOptions struct {
+	// Kind is always "APIOptions".
+	Kind      string            `json:"kind"`
+	Resources []ResourceOptions `json:"resources"`
+}
+
+// resourceOptions returns the ResourceOptions of paths of the given length under resource,
+// whose storage is storage. ok is false if no paths of that length are served.
+func resourceOptions(resource string, storage RESTStorage, length int) (options ResourceOptions, ok bool) {
+	methods, ok := allowedMethods(storage, length)
+	if !ok {
+		return ResourceOptions{}, false
+	}
+	parameters := util.NewStringSet()
+	if hasMethod(methods, "POST") || hasMethod(methods, "PUT") || hasMethod(methods, "DELETE") {
+		parameters.Insert("sync", "timeout", "wait")
+	}
+	_, creater := asCreater(storage)
+	_, updater := asUpdater(storage)
+	switch length {
+	case 1:
+		if hasMethod(methods, "GET") {
+			parameters.Insert("labels", "fresh", "sort")
+			if viewer, ok := storage.(ListViewer); ok {
+				parameters.Insert(viewer.ListViews()...)
+			}
+		}
+		if _, ok := asResourceFieldLister(storage); ok {
+			parameters.Insert("fields")
+		}
+		if creater {
+			parameters.Insert("dryRun", "force")
+		}
+	case 2:
+		if updater {
+			parameters.Insert("dryRun", "force")
+		}
+		if updater && creater {
+			parameters.Insert("createIfMissing")
+		}
+	case 3:
+		parameters.Insert("fields")
+	}
+	_, watch := asResourceWatcher(storage)
+	return ResourceOptions{
+		Kind:       "ResourceOptions",
+		Resource:   resource,
+		Methods:    append(methods, "OPTIONS"),
+		Parameters: parameters.List(),
+		Watch:      watch,
+	}, true
+}
+
+// writeResourceOptions answers an OPTIONS request for a path of the given length under
+// resource. It returns false, without answering, if resource has no such path.
+func writeResourceOptions(resource string, storage RESTStorage, length int, w http.ResponseWriter) bool {
+	options, ok := resourceOptions(resource, storage, length)
+	if !ok {
+		return false
+	}
+	w.Header().Set("Allow", strings.Join(options.Methods, ", "))
+	writeRawJSON(http.StatusOK, options, w)
+	return true
+}
+
+// writeAPIOptions answers an OPTIONS request for the prefix of s.
+func (s *APIServer) writeAPIOptions(w http.ResponseWriter) {
+	options := APIOptions{Kind: "APIOptions", Resources: []ResourceOptions{}}
+	resources := make([]string, 0, len(s.storage))
+	for resource := range s.storage {
+		resources = append(resources, resource)
+	}
+	sort.Strings(resources)
+	for _, resource := range resources {
+		if resourceOptions, ok := resourceOptions(resource, s.storage[resource], 1); ok {
+			options.Resources = append(options.Resources, resourceOptions)
+		}
+	}
+	w.Header().Set("Allow", "OPTIONS")
+	writeRawJSON(http.StatusOK, options, w)
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// options sends an OPTIONS request for url, and decodes the answer into obj.
+func options(t *testing.T, url string, obj interface{}) *http.Response {
+	req, _ := http.NewRequest("OPTIONS", url, nil)
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(response.Body)
+	if response.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(body, obj); err != nil {
+			t.Errorf("unexpected error: %v (%s)", err, body)
+		}
+	}
+	return response
+}
+
+func TestResourceOptions(t *testing.T) {
+	readOnly := &readOnlyStorage{Simple{JSONBase: api.JSONBase{ID: "web"}, Name: "web"}}
+	handler := New(map[string]RESTStorage{"simple": &SimpleRESTStorage{}, "readonly": readOnly}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	table := []struct {
+		path     string
+		code     int
+		expected ResourceOptions
+	}{
+		{"/simple", http.StatusOK, ResourceOptions{
+			Kind:       "ResourceOptions",
+			Resource:   "simple",
+			Methods:    []string{"GET", "POST", "OPTIONS"},
+			Parameters: []string{"dryRun", "force", "fresh", "labels", "sort", "sync", "timeout", "wait"},
+			Watch:      true,
+		}},
+		{"/ns/other/simple/web", http.StatusOK, ResourceOptions{
+			Kind:       "ResourceOptions",
+			Resource:   "simple",
+			Methods:    []string{"GET", "PUT", "DELETE", "OPTIONS"},
+			Parameters: []string{"createIfMissing", "dryRun", "force", "sync", "timeout", "wait"},
+			Watch:      true,
+		}},
+		{"/readonly/web", http.StatusOK, ResourceOptions{
+			Kind:       "ResourceOptions",
+			Resource:   "readonly",
+			Methods:    []string{"GET", "OPTIONS"},
+			Parameters: []string{},
+		}},
+		{"/missing", http.StatusNotFound, ResourceOptions{}},
+		{"/simple/web/name/extra", http.StatusNotFound, ResourceOptions{}},
+	}
+	for _, item := range table {
+		var actual ResourceOptions
+		response := options(t, server.URL+"/prefix/version"+item.path, &actual)
+		if response.StatusCode != item.code {
+			t.Errorf("%s: expected %d, got %d", item.path, item.code, response.StatusCode)
+			continue
+		}
+		if item.code != http.StatusOK {
+			continue
+		}
+		if len(actual.Parameters) == 0 {
+			actual.Parameters = []string{}
+		}
+		if !reflect.DeepEqual(actual, item.expected) {
+			t.Errorf("%s: expected %#v, got %#v", item.path, item.expected, actual)
+		}
+		if allow := response.Header.Get("Allow"); allow != strings.Join(item.expected.Methods, ", ") {
+			t.Errorf("%s: unexpected Allow %q", item.path, allow)
+		}
+	}
+
+	var all APIOptions
+	response := options(t, server.URL+"/prefix/version/", &all)
+	if response.StatusCode != http.StatusOK || all.Kind != "APIOptions" || len(all.Resources) != 2 {
+		t.Fatalf("unexpected answer %d: %#v", response.StatusCode, all)
+	}
+	if all.Resources[0].Resource != "readonly" || all.Resources[1].Resource != "simple" || !reflect.DeepEqual(all.Resources[1].Methods, []string{"GET", "POST", "OPTIONS"}) {
+		t.Errorf("unexpected resources %#v", all.Resources)
+	}
+}
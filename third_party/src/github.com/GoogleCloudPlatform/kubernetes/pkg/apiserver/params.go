@@ -0,0 +1,175 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// toleratedParameters may be passed to any request, even one they mean nothing to: clients
+// that make synchronous requests send sync and timeout with all of them, and pretty asks for
+// indented JSON whatever the answer is.
+var toleratedParameters = util.NewStringSet("sync", "timeout", "strictParams", "pretty")
+
+// watchParameters are the query parameters of watch requests.
+var watchParameters = util.NewStringSet("labels", "fields", "resourceVersion")
+
+// queryParameters returns the query parameters handleRESTStorage accepts for method on
+// paths of the given length under storage, besides toleratedParameters.
+func queryParameters(storage RESTStorage, length int, method string) util.StringSet {
+	parameters := util.NewStringSet()
+	if method == "POST" || method == "PUT" || method == "DELETE" || method == "PATCH" {
+		parameters.Insert("sync", "timeout", "wait")
+	}
+	_, creater := asCreater(storage)
+	_, updater := asUpdater(storage)
+	switch length {
+	case 1:
+		switch method {
+		case "GET":
+			// Storages that can't filter by fields refuse field selectors themselves.
+			parameters.Insert("labels", "fields", "fresh", "sort", "limit", "continue")
+			if viewer, ok := storage.(ListViewer); ok {
+				parameters.Insert(viewer.ListViews()...)
+			}
+		case "POST":
+			if creater {
+				parameters.Insert("dryRun", "force")
+			}
+		}
+	case 2:
+		if method == "PUT" && updater {
+			parameters.Insert("dryRun", "force")
+			if creater {
+				parameters.Insert("createIfMissing")
+			}
+		}
+	case 3:
+		if method == "GET" {
+			parameters.Insert("fields")
+		}
+	}
+	return parameters
+}
+
+// SetStrictParams makes s reject requests with query parameters it doesn't know, such as
+// misspelled ones, if strict is true, which is the default. Requests may still pass
+// strictParams=false, or strictParams=true if strict is false, to choose for themselves,
+// e.g. so that old clients keep working while they are fixed.
+func (s *APIServer) SetStrictParams(strict bool) {
+	s.strictParams = strict
+}
+
+// checkParameters returns an error naming the first query parameter in query, by name, that
+// is neither one of allowed nor one of toleratedParameters, along with the allowed parameter
+// it most likely is a misspelling of, unless the parameters aren't checked for the request.
+// Parameters with empty values ask for nothing, and are ignored.
+func (s *APIServer) checkParameters(query url.Values, allowed util.StringSet) error {
+	strict := s.strictParams
+	switch query.Get("strictParams") {
+	case "true":
+		strict = true
+	case "false":
+		strict = false
+	}
+	if !strict {
+		return nil
+	}
+	names := []string{}
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if allowed.Has(name) || toleratedParameters.Has(name) || len(query.Get(name)) == 0 {
+			continue
+		}
+		if suggestion, ok := closestParameter(name, append(allowed.List(), "sync", "timeout")); ok {
+			return fmt.Errorf("unknown query parameter %q, did you mean %q?", name, suggestion)
+		}
+		if len(allowed) == 0 {
+			return fmt.Errorf("unknown query parameter %q, the request takes none", name)
+		}
+		return fmt.Errorf("unknown query parameter %q, the request takes %s", name, strings.Join(allowed.List(), ", "))
+	}
+	return nil
+}
+
+// closestParameter returns the parameter of candidates that takes the fewest edits to turn
+// name into, if it takes at most a third of the length of name, or two edits for short
+// names. Ties go to the first of candidates.
+func closestParameter(name string, candidates []string) (string, bool) {
+	max := len(name) / 3
+	if max < 2 {
+		max = 2
+	}
+	closest, best := "", max+1
+	for _, parameter := range candidates {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(parameter)); distance < best {
+			closest, best = parameter, distance
+		}
+	}
+	return closest, best <= max
+}
+
+// editDistance returns the Levenshtein distance between a and b: the number of characters
+// that must be inserted, deleted or replaced to turn a into b.
+func editDistance(a, b string) int {
+	// previous[j] is the distance between the first i-1 characters of a and the first j of b.
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// requestedView returns the view of viewer that query asks for with a parameter set to true.
+func requestedView(viewer ListViewer, query url.Values) (string, bool) {
+	for _, view := range viewer.ListViews() {
+		if query.Get(view) == "true" {
+			return view, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestEditDistance(t *testing.T) {
+	table := []struct {
+		a, b     string
+		expected int
+	}{
+		{"", "", 0},
+		{"sync", "sync", 0},
+		{"synch", "sync", 1},
+		{"label", "labels", 1},
+		{"dryrun", "dryRun", 1},
+		{"", "wait", 4},
+		{"kitten", "sitting", 3},
+	}
+	for _, item := range table {
+		if actual := editDistance(item.a, item.b); actual != item.expected {
+			t.Errorf("%q to %q: expected %d, got %d", item.a, item.b, item.expected, actual)
+		}
+	}
+}
+
+func TestUnknownParameters(t *testing.T) {
+	handler := New(map[string]RESTStorage{"simple": &SimpleRESTStorage{}}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	table := []struct {
+		method, path string
+		code         int
+		message      string
+	}{
+		{"GET", "/prefix/version/simple?label=name%3Dfoo", http.StatusBadRequest, `unknown query parameter "label", did you mean "labels"?`},
+		{"GET", "/prefix/version/simple?labels=name%3Dfoo&sync=true&timeout=1s", http.StatusOK, ""},
+		{"POST", "/prefix/version/simple?synch=true", http.StatusBadRequest, `unknown query parameter "synch", did you mean "sync"?`},
+		{"POST", "/prefix/version/simple?dryRun=true&fresh=true", http.StatusBadRequest, `unknown query parameter "fresh", the request takes dryRun, force, sync, timeout, wait`},
+		{"GET", "/prefix/version/simple/web?labels=name%3Dfoo", http.StatusBadRequest, `unknown query parameter "labels", the request takes none`},
+		{"GET", "/prefix/version/simple?label=", http.StatusOK, ""},
+		{"GET", "/prefix/version/simple?label=name%3Dfoo&strictParams=false", http.StatusOK, ""},
+		{"GET", "/prefix/version/watch/simple?resourceVersions=1", http.StatusBadRequest, `unknown query parameter "resourceVersions", did you mean "resourceVersion"?`},
+		{"GET", "/prefix/version/operations?state=pending", http.StatusBadRequest, `unknown query parameter "state", did you mean "status"?`},
+		{"GET", "/prefix/version/operations?status=pending", http.StatusOK, ""},
+	}
+	for _, item := range table {
+		body := `{"name": "web"}`
+		if item.method == "GET" {
+			body = ""
+		}
+		req, _ := http.NewRequest(item.method, server.URL+item.path, strings.NewReader(body))
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if item.code != http.StatusBadRequest {
+			response.Body.Close()
+			if response.StatusCode != item.code {
+				t.Errorf("%s %s: expected %d, got %d", item.method, item.path, item.code, response.StatusCode)
+			}
+			continue
+		}
+		var status api.Status
+		data, err := extractBody(response, &status)
+		if err != nil || response.StatusCode != item.code || !strings.Contains(status.Message, item.message) {
+			t.Errorf("%s %s: expected %d with %q, got %d: %s", item.method, item.path, item.code, item.message, response.StatusCode, data)
+		}
+	}
+
+	handler.SetStrictParams(false)
+	for path, code := range map[string]int{
+		"/prefix/version/simple?label=name%3Dfoo":                   http.StatusOK,
+		"/prefix/version/simple?label=name%3Dfoo&strictParams=true": http.StatusBadRequest,
+	} {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != code {
+			t.Errorf("%s: expected %d, got %d", path, code, response.StatusCode)
+		}
+	}
+}
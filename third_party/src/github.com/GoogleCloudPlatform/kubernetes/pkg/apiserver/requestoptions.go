@@ -0,0 +1,196 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// RequestOptions are the query parameters of a request, parsed and checked. Which of them
+// apply to a request depends on its path and method, see queryParameters; those that don't
+// apply are left at their zero values by clients.
+type RequestOptions struct {
+	// Sync asks to wait up to Timeout for the operation of the request to complete.
+	Sync    bool
+	Timeout time.Duration
+	// Wait is how long to wait for the operation of the request before answering with its
+	// ID, if HasWait is true.
+	Wait    time.Duration
+	HasWait bool
+	// Labels and Fields select the objects of lists and watches. They are never nil.
+	Labels labels.Selector
+	Fields labels.Selector
+	// ResourceVersion is the version after which a watch starts.
+	ResourceVersion uint64
+	// Fresh bypasses the list cache.
+	Fresh bool
+	// Sort is the order of the items of a list, as given, e.g. "desiredState.replicas,desc".
+	Sort string
+	// Limit is the most items a list is answered with, or 0 if the request names no limit.
+	Limit int
+	// Continue is the token of the list request this one continues, if any.
+	Continue        string
+	DryRun          bool
+	CreateIfMissing bool
+	Force           bool
+	Pretty          bool
+
+	// sort is Sort parsed, or nil if the list is left in the order of its storage.
+	sort *listSort
+	// offset is the position in the list of the first item Continue asks for.
+	offset int
+}
+
+// ParseRequestOptions parses the query parameters of req that handlers share. It returns an
+// error satisfying IsBadRequest that lists every invalid parameter, rather than just the
+// first, with a cause for each. Parameters that are absent or empty take their defaults.
+func ParseRequestOptions(req *http.Request) (*RequestOptions, error) {
+	p := &optionParser{query: req.URL.Query()}
+	opts := p.requestOptions()
+	if err := p.err(); err != nil {
+		return nil, err
+	}
+	return opts, nil
+}
+
+// optionParser parses the parameters of query, recording a cause for each invalid one.
+type optionParser struct {
+	query  url.Values
+	causes []api.StatusCause
+}
+
+// requestOptions parses the parameters of RequestOptions.
+func (p *optionParser) requestOptions() *RequestOptions {
+	query := p.query
+	opts := &RequestOptions{
+		Sync:            p.bool("sync"),
+		Timeout:         defaultTimeout,
+		Labels:          p.selector("labels"),
+		Fields:          p.selector("fields"),
+		Fresh:           p.bool("fresh"),
+		Sort:            query.Get("sort"),
+		Continue:        query.Get("continue"),
+		DryRun:          p.bool("dryRun"),
+		CreateIfMissing: p.bool("createIfMissing"),
+		Force:           p.bool("force"),
+		Pretty:          p.bool("pretty"),
+	}
+	if timeout, ok := p.duration("timeout"); ok {
+		opts.Timeout = timeout
+	}
+	opts.Wait, opts.HasWait = p.duration("wait")
+	if value := query.Get("resourceVersion"); len(value) > 0 {
+		version, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			p.invalid("resourceVersion", value, "should be a resource version")
+		}
+		opts.ResourceVersion = version
+	}
+	if value := query.Get("limit"); len(value) > 0 {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			p.invalid("limit", value, "should be a positive number")
+		}
+		opts.Limit = limit
+	}
+	if len(opts.Continue) > 0 {
+		offset, err := decodeContinue(opts.Continue)
+		if err != nil {
+			p.invalid("continue", opts.Continue, "should be a token from "+ListContinueHeader)
+		}
+		opts.offset = offset
+	}
+	order, err := parseSort(opts.Sort)
+	if err != nil {
+		p.invalid("sort", opts.Sort, err.Error())
+	}
+	opts.sort = order
+	return opts
+}
+
+// err returns an error listing the invalid parameters by name, or nil if there are none.
+func (p *optionParser) err() error {
+	if len(p.causes) == 0 {
+		return nil
+	}
+	sort.Sort(causesByField(p.causes))
+	return NewInvalidParametersErr(p.causes)
+}
+
+// invalid records that value is not a valid value of the parameter name, because of why.
+func (p *optionParser) invalid(name, value, why string) {
+	p.causes = append(p.causes, api.StatusCause{
+		Type:    api.CauseTypeFieldValueInvalid,
+		Message: fmt.Sprintf("invalid value %q: %s", value, why),
+		Field:   name,
+	})
+}
+
+// bool parses the parameter name, which must be true or false.
+func (p *optionParser) bool(name string) bool {
+	switch value := p.query.Get(name); value {
+	case "", "false":
+		return false
+	case "true":
+		return true
+	default:
+		p.invalid(name, value, "should be true or false")
+		return false
+	}
+}
+
+// duration parses the parameter name, which must be a duration that isn't negative, such as
+// "10s". It returns false if the parameter is absent or invalid.
+func (p *optionParser) duration(name string) (time.Duration, bool) {
+	value := p.query.Get(name)
+	if len(value) == 0 {
+		return 0, false
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		p.invalid(name, value, "should be a duration that isn't negative, such as 10s")
+		return 0, false
+	}
+	return d, true
+}
+
+// selector parses the parameter name, a label selector. It returns labels.Everything() if
+// the parameter is absent or invalid.
+func (p *optionParser) selector(name string) labels.Selector {
+	value := p.query.Get(name)
+	selector, err := labels.ParseSelector(value)
+	if err != nil {
+		p.invalid(name, value, err.Error())
+		return labels.Everything()
+	}
+	return selector
+}
+
+// causesByField orders causes by the parameter they are about.
+type causesByField []api.StatusCause
+
+func (c causesByField) Len() int           { return len(c) }
+func (c causesByField) Less(i, j int) bool { return c[i].Field < c[j].Field }
+func (c causesByField) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
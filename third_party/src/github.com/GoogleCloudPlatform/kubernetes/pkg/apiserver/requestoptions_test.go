@@ -0,0 +1,147 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func parseQuery(t *testing.T, query string) (*RequestOptions, error) {
+	req, err := http.NewRequest("GET", "/prefix/version/foo?"+query, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return ParseRequestOptions(req)
+}
+
+func TestParseRequestOptions(t *testing.T) {
+	table := map[string]func(*RequestOptions){
+		"": func(*RequestOptions) {},
+		"sync=true&timeout=10s": func(o *RequestOptions) {
+			o.Sync, o.Timeout = true, 10*time.Second
+		},
+		"sync=false&wait=0": func(o *RequestOptions) {
+			o.Wait, o.HasWait = 0, true
+		},
+		"wait=1m": func(o *RequestOptions) {
+			o.Wait, o.HasWait = time.Minute, true
+		},
+		"resourceVersion=1492&fresh=true": func(o *RequestOptions) {
+			o.ResourceVersion, o.Fresh = 1492, true
+		},
+		"sort=desiredState.replicas,desc": func(o *RequestOptions) {
+			o.Sort = "desiredState.replicas,desc"
+			o.sort = &listSort{field: "desiredState.replicas", path: []string{"desiredState", "replicas"}, desc: true}
+		},
+		"limit=5&continue=" + url.QueryEscape(encodeContinue(10)): func(o *RequestOptions) {
+			o.Limit, o.Continue, o.offset = 5, encodeContinue(10), 10
+		},
+		"dryRun=true&createIfMissing=true&force=true&pretty=true": func(o *RequestOptions) {
+			o.DryRun, o.CreateIfMissing, o.Force, o.Pretty = true, true, true, true
+		},
+		"timeout=&wait=&limit=": func(*RequestOptions) {},
+	}
+	for query, modify := range table {
+		expected := &RequestOptions{Timeout: defaultTimeout}
+		modify(expected)
+		opts, err := parseQuery(t, query)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", query, err)
+			continue
+		}
+		if opts.Labels.String() != "" || opts.Fields.String() != "" {
+			t.Errorf("%q: unexpected selectors %v %v", query, opts.Labels, opts.Fields)
+		}
+		opts.Labels, opts.Fields = nil, nil
+		if !reflect.DeepEqual(opts, expected) {
+			t.Errorf("%q: expected %#v, got %#v", query, expected, opts)
+		}
+	}
+
+	opts, err := parseQuery(t, "labels=name%3Dfoo&fields=Host%3D")
+	if err != nil || opts.Labels.String() != "name=foo" || opts.Fields.String() != "Host=" {
+		t.Errorf("unexpected selectors %#v: %v", opts, err)
+	}
+}
+
+func TestParseRequestOptionsInvalid(t *testing.T) {
+	table := map[string][]string{
+		"sync=yes":                             {"sync"},
+		"timeout=soon":                         {"timeout"},
+		"timeout=-1s&wait=-1s":                 {"timeout", "wait"},
+		"labels=x%3D%3Da%3D%3Db":               {"labels"},
+		"resourceVersion=latest":               {"resourceVersion"},
+		"limit=0&continue=nope":                {"continue", "limit"},
+		"sort=id,asc&fresh=1&dryRun=maybe":     {"dryRun", "fresh", "sort"},
+		"force=no&createIfMissing=1&pretty=ok": {"createIfMissing", "force", "pretty"},
+	}
+	for query, expected := range table {
+		_, err := parseQuery(t, query)
+		if !IsBadRequest(err) {
+			t.Errorf("%q: expected a bad request, got %v", query, err)
+			continue
+		}
+		causes := err.(*apiServerError).Status.Details.Causes
+		fields := []string{}
+		for _, cause := range causes {
+			fields = append(fields, cause.Field)
+			if cause.Type != api.CauseTypeFieldValueInvalid {
+				t.Errorf("%q: unexpected cause %#v", query, cause)
+			}
+		}
+		if !reflect.DeepEqual(fields, expected) {
+			t.Errorf("%q: expected causes for %v, got %v", query, expected, fields)
+		}
+	}
+}
+
+func TestInvalidParametersRejected(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{
+		"simple": simpleStorage,
+	}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	table := map[string]int{
+		"/prefix/version/simple?limit=x&sort=,desc&labels=x%3D%3Da%3D%3Db":      3,
+		"/prefix/version/simple/id?timeout=soon":                                1,
+		"/prefix/version/watch/simple?resourceVersion=x&fields=x%3D%3Da%3D%3Db": 2,
+		"/prefix/version/operations?status=running&timeout=soon":                2,
+	}
+	for path, count := range table {
+		response, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var status api.Status
+		if _, err := extractBody(response, &status); err != nil {
+			t.Errorf("%s: unexpected error: %v", path, err)
+			continue
+		}
+		if response.StatusCode != http.StatusBadRequest || status.Details == nil || len(status.Details.Causes) != count {
+			t.Errorf("%s: expected 400 with %d causes, got %d: %#v", path, count, response.StatusCode, status)
+		}
+	}
+}
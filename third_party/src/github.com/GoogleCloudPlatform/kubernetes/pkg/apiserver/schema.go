@@ -0,0 +1,334 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is the version of the Schema structure. Like ServerConfigVersion, it evolves
+// apart from the API the schema describes; fields are only added to it within a version.
+const SchemaVersion = "v1"
+
+// The types of fields whose values aren't objects described by the schema.
+const (
+	SchemaString  = "string"
+	SchemaInteger = "integer"
+	SchemaNumber  = "number"
+	SchemaBoolean = "boolean"
+	// SchemaAny is the type of fields that may hold any JSON value, e.g. an embedded object
+	// of any kind, or a value that is either an integer or a string.
+	SchemaAny = "any"
+)
+
+// KnownTypesLister may be implemented by Codecs that can list the types they encode, such as
+// conversion.Scheme. The APIServer describes them in its Schema.
+type KnownTypesLister interface {
+	// KnownTypes returns the types of version, by the kind they are encoded with.
+	KnownTypes(version string) map[string]reflect.Type
+}
+
+// Schema describes the objects and resources an APIServer serves, so that clients can be
+// generated from it. It is served at ${prefix}/schema, and generated once, when the APIServer
+// is created. Everything in it is sorted, so that the same API is always described the same way.
+type Schema struct {
+	// Kind is always "Schema", and APIVersion the SchemaVersion.
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+
+	// Version is the version of the API the schema describes.
+	Version string `json:"version"`
+	// Kinds describe the objects the API encodes, by kind. They are only known if the codec of
+	// the APIServer is a KnownTypesLister.
+	Kinds []TypeSchema `json:"kinds"`
+	// Definitions describe the other objects the fields of kinds hold.
+	Definitions []TypeSchema `json:"definitions"`
+	// Resources describe the paths the API serves. Every path under Prefix is also served
+	// under NamespacedPrefix, for the objects of a single namespace.
+	Prefix           string           `json:"prefix"`
+	NamespacedPrefix string           `json:"namespacedPrefix"`
+	Resources        []ResourceSchema `json:"resources"`
+}
+
+// TypeSchema describes the fields of an object, in the order they are encoded.
+type TypeSchema struct {
+	Name   string        `json:"name"`
+	Fields []FieldSchema `json:"fields"`
+}
+
+// FieldSchema describes a field of an object.
+type FieldSchema struct {
+	// Name is the key of the field in JSON.
+	Name string `json:"name"`
+	// Type is one of the Schema* types, or the name of a kind or definition.
+	Type string `json:"type"`
+	// List is true if the field holds a list of Type, and Map if it holds an object whose
+	// values are Type.
+	List bool `json:"list,omitempty"`
+	Map  bool `json:"map,omitempty"`
+	// Optional is true if the field is left out of objects that don't set it.
+	Optional bool `json:"optional,omitempty"`
+	// Embedded is the name of the struct the field belongs to if the object embeds the fields
+	// of that struct among its own, as kinds do with JSONBase.
+	Embedded string `json:"embedded,omitempty"`
+}
+
+// ResourceSchema describes the paths of a resource.
+type ResourceSchema struct {
+	// Name is the name of the resource in paths, and Kind the kind of its objects.
+	Name  string       `json:"name"`
+	Kind  string       `json:"kind"`
+	Paths []PathSchema `json:"paths"`
+}
+
+// PathSchema describes a path and the methods it is served for. Path segments in braces,
+// like {id}, stand for the ID of an object.
+type PathSchema struct {
+	Path    string   `json:"path"`
+	Methods []string `json:"methods"`
+}
+
+// handleSchema writes the Schema of s.
+func (s *APIServer) handleSchema(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		methodNotAllowed("schema", []string{"GET"}, req, w, s.codec)
+		return
+	}
+	writeRawJSON(http.StatusOK, s.schema, w)
+}
+
+// newSchema describes the types codec encodes for version, and the resources in storage
+// served under prefix.
+func newSchema(storage map[string]RESTStorage, codec Codec, prefix, version string) *Schema {
+	schema := &Schema{
+		Kind:       "Schema",
+		APIVersion: SchemaVersion,
+
+		Version:          version,
+		Kinds:            []TypeSchema{},
+		Definitions:      []TypeSchema{},
+		Prefix:           prefix,
+		NamespacedPrefix: path.Join(prefix, "ns", "{namespace}"),
+		Resources:        []ResourceSchema{},
+	}
+
+	if lister, ok := codec.(KnownTypesLister); ok {
+		d := &describer{names: map[reflect.Type]string{}, taken: map[string]bool{}}
+		types := lister.KnownTypes(version)
+		kinds := []string{}
+		for kind, t := range types {
+			d.names[t] = kind
+			d.taken[kind] = true
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			schema.Kinds = append(schema.Kinds, TypeSchema{kind, d.fields(types[kind])})
+		}
+		schema.Definitions = d.definitions
+		sort.Sort(typeSchemasByName(schema.Definitions))
+	}
+
+	names := []string{}
+	for name := range storage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		schema.Resources = append(schema.Resources, describeResource(prefix, name, storage[name]))
+	}
+	return schema
+}
+
+// describeResource lists the paths under prefix that serve the resource name from storage.
+func describeResource(prefix, name string, storage RESTStorage) ResourceSchema {
+	resource := ResourceSchema{
+		Name:  name,
+		Kind:  reflect.Indirect(reflect.ValueOf(storage.New())).Type().Name(),
+		Paths: []PathSchema{},
+	}
+	add := func(methods []string, segments ...string) {
+		if len(methods) > 0 {
+			resource.Paths = append(resource.Paths, PathSchema{path.Join(append([]string{prefix}, segments...)...), methods})
+		}
+	}
+	collection, _ := allowedMethods(storage, 1)
+	add(collection, name)
+	object, _ := allowedMethods(storage, 2)
+	add(object, name, "{id}")
+	if subresources, ok := storage.(SubresourceStorage); ok {
+		methods, _ := allowedMethods(storage, 3)
+		supported := append([]string{}, subresources.Subresources()...)
+		sort.Strings(supported)
+		for _, subresource := range supported {
+			add(methods, name, "{id}", subresource)
+		}
+	}
+	if _, ok := asResourceWatcher(storage); ok {
+		add([]string{"GET"}, "watch", name)
+	}
+	return resource
+}
+
+// describer describes the fields of types, collecting the structs they refer to as
+// definitions.
+type describer struct {
+	// names are the names the structs described so far are referred to with, and taken the
+	// names in use.
+	names map[reflect.Type]string
+	taken map[string]bool
+	// definitions describe the structs that aren't kinds.
+	definitions []TypeSchema
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	timeType          = reflect.TypeOf(time.Time{})
+)
+
+// fields describes the fields of the struct t as encoding/json encodes them.
+func (d *describer) fields(t reflect.Type) []FieldSchema {
+	fields := []FieldSchema{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) != 0 {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma != -1 {
+			name, options = tag[:comma], tag[comma+1:]
+		}
+		if field.Anonymous && len(name) == 0 && field.Type.Kind() == reflect.Struct {
+			embedded := d.name(field.Type)
+			for _, inner := range d.fields(field.Type) {
+				if len(inner.Embedded) == 0 {
+					inner.Embedded = embedded
+				}
+				fields = append(fields, inner)
+			}
+			continue
+		}
+		if len(name) == 0 {
+			name = field.Name
+		}
+		schema := FieldSchema{Name: name, Optional: hasOption(options, "omitempty")}
+		d.describe(field.Type, &schema)
+		fields = append(fields, schema)
+	}
+	return fields
+}
+
+// describe sets the type of schema to the one of values of t.
+func (d *describer) describe(t reflect.Type, schema *FieldSchema) {
+	if t.Kind() == reflect.Ptr {
+		schema.Optional = true
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoding/json writes bytes as base64 strings.
+			schema.Type = SchemaString
+			return
+		}
+		schema.List = true
+		schema.Type = d.typeName(t.Elem())
+	case reflect.Map:
+		schema.Map = true
+		schema.Type = d.typeName(t.Elem())
+	default:
+		schema.Type = d.typeName(t)
+	}
+}
+
+// typeName returns the schema type of a value of t, describing t as a definition if it is a
+// struct that hasn't been described yet.
+func (d *describer) typeName(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return SchemaString
+	}
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		return SchemaAny
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return SchemaString
+	case reflect.Bool:
+		return SchemaBoolean
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return SchemaInteger
+	case reflect.Float32, reflect.Float64:
+		return SchemaNumber
+	case reflect.Struct:
+		if name, ok := d.names[t]; ok {
+			return name
+		}
+		name := d.name(t)
+		d.definitions = append(d.definitions, TypeSchema{Name: name})
+		index := len(d.definitions) - 1
+		fields := d.fields(t)
+		d.definitions[index].Fields = fields
+		return name
+	}
+	// Interfaces, and lists and maps nested in lists and maps.
+	return SchemaAny
+}
+
+// name returns the name t is referred to with, choosing one if t hasn't been named yet. Structs
+// are named after their type, qualified with their package if another struct has the name.
+func (d *describer) name(t reflect.Type) string {
+	if name, ok := d.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if d.taken[name] || len(name) == 0 {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	d.names[t] = name
+	d.taken[name] = true
+	return name
+}
+
+// hasOption returns true if options, the comma separated options of a struct tag, include option.
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+type typeSchemasByName []TypeSchema
+
+func (s typeSchemasByName) Len() int           { return len(s) }
+func (s typeSchemasByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s typeSchemasByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
@@ -0,0 +1,172 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func findType(types []TypeSchema, name string) *TypeSchema {
+	for i := range types {
+		if types[i].Name == name {
+			return &types[i]
+		}
+	}
+	return nil
+}
+
+func findField(t *testing.T, types []TypeSchema, typeName, fieldName string) FieldSchema {
+	schema := findType(types, typeName)
+	if schema == nil {
+		t.Fatalf("expected %s to be described", typeName)
+	}
+	for _, field := range schema.Fields {
+		if field.Name == fieldName {
+			return field
+		}
+	}
+	t.Fatalf("expected %s to have the field %s, got %#v", typeName, fieldName, schema.Fields)
+	return FieldSchema{}
+}
+
+func TestSchema(t *testing.T) {
+	storage := map[string]RESTStorage{
+		"simple":   &SimpleRESTStorage{},
+		"names":    &patchableNameStorage{},
+		"readonly": &readOnlyStorage{},
+	}
+	server := httptest.NewServer(New(storage, codec, "/api/v1beta1"))
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/v1beta1/schema")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", response.StatusCode, body)
+	}
+	var schema Schema
+	if err := json.Unmarshal(body, &schema); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if schema.Kind != "Schema" || schema.APIVersion != SchemaVersion || schema.Version != "v1beta1" {
+		t.Errorf("unexpected header %q %q %q", schema.Kind, schema.APIVersion, schema.Version)
+	}
+	if schema.Prefix != "/api/v1beta1" || schema.NamespacedPrefix != "/api/v1beta1/ns/{namespace}" {
+		t.Errorf("unexpected prefixes %q %q", schema.Prefix, schema.NamespacedPrefix)
+	}
+
+	// Kinds embed the fields of JSONBase, refer to other kinds and to definitions. Simple embeds
+	// the internal JSONBase, whose name is taken by the one of v1beta1.
+	simple := findType(schema.Kinds, "Simple")
+	if simple == nil {
+		t.Fatalf("expected the registered kinds to be described")
+	}
+	expected := []FieldSchema{
+		{Name: "kind", Type: SchemaString, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "id", Type: SchemaString, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "creationTimestamp", Type: SchemaString, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "selfLink", Type: SchemaString, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "resourceVersion", Type: SchemaInteger, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "apiVersion", Type: SchemaString, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "lastModified", Type: SchemaString, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "namespace", Type: SchemaString, Optional: true, Embedded: "api.JSONBase"},
+		{Name: "name", Type: SchemaString, Optional: true},
+	}
+	if !reflect.DeepEqual(simple.Fields, expected) {
+		t.Errorf("expected %#v, got %#v", expected, simple.Fields)
+	}
+	if field := findField(t, schema.Kinds, "SimpleList", "items"); field.Type != "Simple" || !field.List {
+		t.Errorf("expected a list of a kind, got %#v", field)
+	}
+	if field := findField(t, schema.Kinds, "Pod", "id"); field.Embedded != "JSONBase" {
+		t.Errorf("expected the fields of JSONBase, got %#v", field)
+	}
+	if field := findField(t, schema.Kinds, "Pod", "labels"); field.Type != SchemaString || !field.Map {
+		t.Errorf("expected a map of strings, got %#v", field)
+	}
+	if field := findField(t, schema.Kinds, "Pod", "desiredState"); field.Type != "PodState" {
+		t.Errorf("expected a reference to a definition, got %#v", field)
+	}
+	if field := findField(t, schema.Definitions, "Port", "containerPort"); field.Type != SchemaInteger {
+		t.Errorf("expected an integer, got %#v", field)
+	}
+	if field := findField(t, schema.Definitions, "TCPSocketProbe", "port"); field.Type != SchemaAny {
+		t.Errorf("expected a field with its own encoding to hold anything, got %#v", field)
+	}
+	if findType(schema.Definitions, "Pod") != nil {
+		t.Errorf("expected kinds not to be repeated as definitions")
+	}
+
+	expectedResources := []ResourceSchema{
+		{Name: "names", Kind: "Simple", Paths: []PathSchema{
+			{"/api/v1beta1/names", []string{"GET", "POST"}},
+			{"/api/v1beta1/names/{id}", []string{"GET", "PUT", "DELETE"}},
+			{"/api/v1beta1/names/{id}/name", []string{"GET", "PUT", "POST", "PATCH"}},
+		}},
+		{Name: "readonly", Kind: "Simple", Paths: []PathSchema{
+			{"/api/v1beta1/readonly", []string{"GET"}},
+			{"/api/v1beta1/readonly/{id}", []string{"GET"}},
+		}},
+		{Name: "simple", Kind: "Simple", Paths: []PathSchema{
+			{"/api/v1beta1/simple", []string{"GET", "POST"}},
+			{"/api/v1beta1/simple/{id}", []string{"GET", "PUT", "DELETE"}},
+			{"/api/v1beta1/watch/simple", []string{"GET"}},
+		}},
+	}
+	if !reflect.DeepEqual(schema.Resources, expectedResources) {
+		t.Errorf("expected %#v, got %#v", expectedResources, schema.Resources)
+	}
+
+	response, err = http.Post(server.URL+"/api/v1beta1/schema", "application/json", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected the schema to be read-only, got %d", response.StatusCode)
+	}
+}
+
+func TestSchemaIsStable(t *testing.T) {
+	storage := map[string]RESTStorage{"simple": &SimpleRESTStorage{}, "names": &nameStorage{}}
+	first, err := json.Marshal(newSchema(storage, codec, "/api/v1beta1", "v1beta1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		again, _ := json.Marshal(newSchema(storage, codec, "/api/v1beta1", "v1beta1"))
+		if !bytes.Equal(first, again) {
+			t.Fatalf("expected the same schema every time, got\n%s\nand\n%s", first, again)
+		}
+	}
+}
+
+func TestSchemaWithoutKnownTypes(t *testing.T) {
+	schema := newSchema(map[string]RESTStorage{}, struct{ Codec }{codec}, "/api/v1beta1", "v1beta1")
+	if len(schema.Kinds) != 0 || len(schema.Definitions) != 0 || len(schema.Resources) != 0 {
+		t.Errorf("expected nothing to be described, got %#v", schema)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ServerConfigVersion is the version of the ServerConfig structure, which evolves on its own,
+// apart from the API the server serves.
+const ServerConfigVersion = "v1"
+
+// ServerConfig describes the limits and defaults an APIServer applies to requests, so that
+// clients can adapt to them. It is served at ${prefix}/serverconfig. Durations are written as
+// time.Duration strings, e.g. "30s".
+type ServerConfig struct {
+	// Kind is always "ServerConfig", and APIVersion the ServerConfigVersion.
+	Kind       string `json:"kind"`
+	APIVersion string `json:"apiVersion"`
+
+	// APIVersions are the versions of the API the server serves.
+	APIVersions []string `json:"apiVersions"`
+	// MaxBodyBytes is the largest request body accepted, or 0 if bodies aren't limited.
+	MaxBodyBytes int64 `json:"maxBodyBytes"`
+	// DefaultTimeout is how long a request with sync=true waits for its operation if it
+	// names no timeout.
+	DefaultTimeout string `json:"defaultTimeout"`
+	// AsyncOpWait is how long a request without sync=true waits for its operation if it
+	// names no wait, and MaxAsyncOpWait the longest wait it may name.
+	AsyncOpWait    string `json:"asyncOpWait"`
+	MaxAsyncOpWait string `json:"maxAsyncOpWait"`
+	// WatchHeartbeatInterval is how often watches send an event while nothing changes, or
+	// "0s" if they don't.
+	WatchHeartbeatInterval string `json:"watchHeartbeatInterval"`
+	// ListCacheTTL is how long responses to list requests may be served from a cache, or
+	// "0s" if they aren't cached.
+	ListCacheTTL string `json:"listCacheTTL"`
+	// StreamedLists is true if lists are encoded as they are written rather than served from
+	// a cache, so that clients may decode their items as they arrive.
+	StreamedLists bool `json:"streamedLists"`
+	// FieldSelectors are the resources whose lists can be filtered with a field selector,
+	// sorted. Servers that predate field selectors leave it out.
+	FieldSelectors []string `json:"fieldSelectors"`
+}
+
+// serverConfig returns the current ServerConfig of s.
+func (s *APIServer) serverConfig() ServerConfig {
+	var listCacheTTL time.Duration
+	if s.lists != nil {
+		listCacheTTL = s.lists.ttl
+	}
+	_, streamedLists := s.codec.(StreamEncoder)
+	return ServerConfig{
+		Kind:       "ServerConfig",
+		APIVersion: ServerConfigVersion,
+
+		APIVersions:            []string{s.apiVersion},
+		MaxBodyBytes:           0,
+		DefaultTimeout:         defaultTimeout.String(),
+		AsyncOpWait:            s.asyncOpWait.String(),
+		MaxAsyncOpWait:         s.maxAsyncOpWait.String(),
+		WatchHeartbeatInterval: time.Duration(0).String(),
+		ListCacheTTL:           listCacheTTL.String(),
+		StreamedLists:          streamedLists && s.lists == nil,
+		FieldSelectors:         s.fieldSelectors(),
+	}
+}
+
+// fieldSelectors returns the resources of s whose lists can be filtered by fields, sorted.
+func (s *APIServer) fieldSelectors() []string {
+	resources := []string{}
+	for name, storage := range s.storage {
+		if _, ok := asResourceFieldLister(storage); ok {
+			resources = append(resources, name)
+		}
+	}
+	sort.Strings(resources)
+	return resources
+}
+
+// handleServerConfig writes the ServerConfig of s.
+func (s *APIServer) handleServerConfig(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" {
+		methodNotAllowed("serverconfig", []string{"GET"}, req, w, s.codec)
+		return
+	}
+	writeRawJSON(http.StatusOK, s.serverConfig(), w)
+}
@@ -0,0 +1,96 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// fieldListingStorage is a SimpleRESTStorage whose lists can be filtered by fields.
+type fieldListingStorage struct {
+	SimpleRESTStorage
+}
+
+func (storage *fieldListingStorage) ListFields(ctx api.Context, label, field labels.Selector) (interface{}, error) {
+	return storage.List(ctx, label)
+}
+
+func TestServerConfig(t *testing.T) {
+	storage := map[string]RESTStorage{"foo": &SimpleRESTStorage{}, "bar": &fieldListingStorage{}}
+	handler := New(storage, codec, "/api/v1beta1")
+	handler.SetAsyncOpWait(time.Second, time.Minute)
+	handler.SetListCacheTTL(500 * time.Millisecond)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/api/v1beta1/serverconfig")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", response.StatusCode, body)
+	}
+	var config ServerConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	expected := ServerConfig{
+		Kind:                   "ServerConfig",
+		APIVersion:             "v1",
+		APIVersions:            []string{"v1beta1"},
+		DefaultTimeout:         "30s",
+		AsyncOpWait:            "1s",
+		MaxAsyncOpWait:         "1m0s",
+		WatchHeartbeatInterval: "0s",
+		ListCacheTTL:           "500ms",
+		FieldSelectors:         []string{"bar"},
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("expected %#v, got %#v", expected, config)
+	}
+
+	response, err = http.Post(server.URL+"/api/v1beta1/serverconfig", "application/json", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected the server config to be read-only, got %d", response.StatusCode)
+	}
+}
+
+func TestServerConfigStreamedLists(t *testing.T) {
+	handler := New(map[string]RESTStorage{"foo": &SimpleRESTStorage{}}, codec, "/api/v1beta1")
+	if config := handler.serverConfig(); !config.StreamedLists {
+		t.Errorf("expected lists to be streamed without a list cache")
+	}
+	handler.SetListCacheTTL(time.Second)
+	if config := handler.serverConfig(); config.StreamedLists {
+		t.Errorf("expected cached lists not to be streamed")
+	}
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// listSort is the order a client asked the items of a list in, with sort=<field path>[,desc].
+type listSort struct {
+	// field is the field path as given, e.g. "desiredState.replicas".
+	field string
+	// path are the JSON names of the fields leading to the field.
+	path []string
+	desc bool
+}
+
+// parseSort parses the sort parameter of a list request. An empty value leaves the list in
+// the order of its storage, and returns a nil listSort.
+func parseSort(value string) (*listSort, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	parts := strings.Split(value, ",")
+	s := &listSort{field: parts[0]}
+	switch {
+	case len(parts) == 2 && parts[1] == "desc":
+		s.desc = true
+	case len(parts) != 1:
+		return nil, fmt.Errorf("sort should be <field path>[,desc], not %q", value)
+	}
+	for _, name := range strings.Split(s.field, ".") {
+		if len(name) == 0 {
+			return nil, fmt.Errorf("invalid sort field %q", s.field)
+		}
+		s.path = append(s.path, name)
+	}
+	return s, nil
+}
+
+// sortList sorts the items of list, a pointer to a list object, by the field s names. Items
+// with the same value of the field are ordered by namespace and ID, so that the order of a
+// list that doesn't change is the same from one request to the next. It returns an error
+// naming the field if the items have no such field, or it can't be sorted by.
+func sortList(list interface{}, s *listSort) error {
+	items, ok := listItems(list)
+	if !ok || s == nil {
+		return nil
+	}
+	key, err := sortKey(items.Type().Elem(), s)
+	if err != nil {
+		return err
+	}
+	sorted := &sortedItems{keys: make([]sortItemKey, items.Len()), desc: s.desc}
+	for i := range sorted.keys {
+		item := items.Index(i)
+		jsonBase, _ := api.FindJSONBaseRO(item.Addr().Interface())
+		sorted.keys[i] = sortItemKey{value: key(item), id: api.QualifiedID(jsonBase.Namespace, jsonBase.ID), index: i}
+	}
+	sort.Sort(sorted)
+	// Move the items to their sorted places.
+	reordered := reflect.MakeSlice(items.Type(), items.Len(), items.Len())
+	for i, key := range sorted.keys {
+		reordered.Index(i).Set(items.Index(key.index))
+	}
+	items.Set(reordered)
+	return nil
+}
+
+// checkSort returns the error sortList would return for the items of a storage whose New
+// returns item, so that a list request naming a field the items don't have is refused
+// before the storage lists anything.
+func checkSort(item interface{}, s *listSort) error {
+	if s == nil {
+		return nil
+	}
+	t := reflect.TypeOf(item)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	_, err := sortKey(t, s)
+	return err
+}
+
+// sortValue is the value of the field a list is sorted by, for one item. Only one of the
+// fields is used, depending on the kind of the field.
+type sortValue struct {
+	// null is true for a nil pointer, which sorts before any value.
+	null bool
+	s    string
+	i    int64
+	u    uint64
+	f    float64
+}
+
+func (a sortValue) less(b sortValue) bool {
+	switch {
+	case a.null || b.null:
+		return a.null && !b.null
+	case a.s != b.s:
+		return a.s < b.s
+	case a.i != b.i:
+		return a.i < b.i
+	case a.u != b.u:
+		return a.u < b.u
+	}
+	return a.f < b.f
+}
+
+// sortKey returns a function that returns the sortValue of an item of type t, or an error
+// if s names no field of t that can be sorted by. The ID and creation timestamp of an
+// object are read directly, without walking the fields by their names.
+func sortKey(t reflect.Type, s *listSort) (func(reflect.Value) sortValue, error) {
+	if len(s.path) == 1 && (s.path[0] == "id" || s.path[0] == "creationTimestamp") {
+		if field, ok := t.FieldByName("JSONBase"); ok && field.Type == reflect.TypeOf(api.JSONBase{}) {
+			if s.path[0] == "id" {
+				return func(item reflect.Value) sortValue {
+					return sortValue{s: item.FieldByName("JSONBase").Interface().(api.JSONBase).ID}
+				}, nil
+			}
+			return func(item reflect.Value) sortValue {
+				return sortValue{s: item.FieldByName("JSONBase").Interface().(api.JSONBase).CreationTimestamp}
+			}, nil
+		}
+	}
+
+	index := [][]int{}
+	for _, name := range s.path {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("unknown sort field %q", s.field)
+		}
+		field, ok := jsonField(t, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown sort field %q", s.field)
+		}
+		index = append(index, field.Index)
+		t = field.Type
+	}
+	kind := t.Kind()
+	if kind == reflect.Ptr {
+		kind = t.Elem().Kind()
+	}
+	switch kind {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+	default:
+		return nil, fmt.Errorf("sort field %q is not a string, number or boolean and can't be sorted by", s.field)
+	}
+	return func(item reflect.Value) sortValue {
+		v := item
+		for _, i := range index {
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return sortValue{null: true}
+				}
+				v = v.Elem()
+			}
+			v = v.FieldByIndex(i)
+		}
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return sortValue{null: true}
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.String:
+			return sortValue{s: v.String()}
+		case reflect.Bool:
+			if v.Bool() {
+				return sortValue{i: 1}
+			}
+			return sortValue{}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return sortValue{i: v.Int()}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return sortValue{u: v.Uint()}
+		}
+		return sortValue{f: v.Float()}
+	}, nil
+}
+
+// jsonField returns the field of the struct type t that is encoded as name, looking into
+// the structs t embeds inline, such as JSONBase.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) != 0 {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && len(tag) == 0 && field.Type.Kind() == reflect.Struct {
+			if inner, ok := jsonField(field.Type, name); ok {
+				inner.Index = append([]int{i}, inner.Index...)
+				return inner, true
+			}
+			continue
+		}
+		if tag == name || (len(tag) == 0 && field.Name == name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// sortItemKey is what an item is sorted by, and its index in the unsorted list.
+type sortItemKey struct {
+	value sortValue
+	id    string
+	index int
+}
+
+// sortedItems sorts the keys of the items of a list.
+type sortedItems struct {
+	keys []sortItemKey
+	desc bool
+}
+
+func (s *sortedItems) Len() int      { return len(s.keys) }
+func (s *sortedItems) Swap(i, j int) { s.keys[i], s.keys[j] = s.keys[j], s.keys[i] }
+func (s *sortedItems) Less(i, j int) bool {
+	a, b := s.keys[i], s.keys[j]
+	if s.desc {
+		a, b = b, a
+	}
+	if a.value.less(b.value) {
+		return true
+	}
+	if b.value.less(a.value) {
+		return false
+	}
+	// Ties are broken the same way whichever the direction, so that the same items come
+	// first among equals.
+	if s.keys[i].id != s.keys[j].id {
+		return s.keys[i].id < s.keys[j].id
+	}
+	return s.keys[i].index < s.keys[j].index
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+type sortableState struct {
+	Ready bool `json:"ready"`
+	Count *int `json:"count,omitempty"`
+}
+
+type sortable struct {
+	api.JSONBase `json:",inline"`
+	Replicas     int               `json:"replicas"`
+	Weight       float64           `json:"weight"`
+	State        sortableState     `json:"state"`
+	Labels       map[string]string `json:"labels"`
+}
+
+type sortableList struct {
+	Items []sortable
+}
+
+func sortedIDs(list *sortableList) []string {
+	ids := []string{}
+	for _, item := range list.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestParseSort(t *testing.T) {
+	table := map[string]*listSort{
+		"":                      nil,
+		"id":                    {field: "id", path: []string{"id"}},
+		"state.ready,desc":      {field: "state.ready", path: []string{"state", "ready"}, desc: true},
+		"desiredState.replicas": {field: "desiredState.replicas", path: []string{"desiredState", "replicas"}},
+	}
+	for value, expected := range table {
+		s, err := parseSort(value)
+		if err != nil || !reflect.DeepEqual(s, expected) {
+			t.Errorf("%q: expected %#v, got %#v (%v)", value, expected, s, err)
+		}
+	}
+	for _, value := range []string{"id,asc", "id,desc,desc", ",desc", "state..ready", "state."} {
+		if _, err := parseSort(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestSortList(t *testing.T) {
+	one, two := 1, 2
+	newList := func() *sortableList {
+		return &sortableList{Items: []sortable{
+			{JSONBase: api.JSONBase{ID: "c", CreationTimestamp: "2014-08-01T00:00:00Z"}, Replicas: 2, Weight: 0.5, State: sortableState{Ready: true, Count: &two}},
+			{JSONBase: api.JSONBase{ID: "a", CreationTimestamp: "2014-08-03T00:00:00Z"}, Replicas: 10, Weight: 1.5},
+			{JSONBase: api.JSONBase{ID: "b", CreationTimestamp: "2014-08-02T00:00:00Z"}, Replicas: 2, Weight: -1, State: sortableState{Count: &one}},
+		}}
+	}
+	table := map[string][]string{
+		"id":                {"a", "b", "c"},
+		"id,desc":           {"c", "b", "a"},
+		"creationTimestamp": {"c", "b", "a"},
+		// Ties are ordered by ID in both directions.
+		"replicas":      {"b", "c", "a"},
+		"replicas,desc": {"a", "b", "c"},
+		"weight":        {"b", "c", "a"},
+		"state.ready":   {"a", "b", "c"},
+		// Nil pointers come first.
+		"state.count":      {"a", "b", "c"},
+		"state.count,desc": {"c", "b", "a"},
+	}
+	for value, expected := range table {
+		s, _ := parseSort(value)
+		list := newList()
+		if err := sortList(list, s); err != nil {
+			t.Errorf("%s: unexpected error: %v", value, err)
+			continue
+		}
+		if ids := sortedIDs(list); !reflect.DeepEqual(ids, expected) {
+			t.Errorf("%s: expected %v, got %v", value, expected, ids)
+		}
+	}
+
+	for _, value := range []string{"name", "state.missing", "replicas.value", "labels", "state"} {
+		s, _ := parseSort(value)
+		err := sortList(newList(), s)
+		if err == nil || !strings.Contains(err.Error(), value) {
+			t.Errorf("%s: expected an error naming the field, got %v", value, err)
+		}
+	}
+}
+
+func TestSortListIsStable(t *testing.T) {
+	s, _ := parseSort("replicas")
+	var first []string
+	// However the storage orders the items, they are listed in the same order.
+	for _, order := range [][]string{{"a", "b", "c", "d"}, {"d", "c", "b", "a"}, {"c", "a", "d", "b"}} {
+		list := &sortableList{}
+		for _, id := range order {
+			list.Items = append(list.Items, sortable{JSONBase: api.JSONBase{ID: id}, Replicas: 1})
+		}
+		if err := sortList(list, s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids := sortedIDs(list)
+		if first == nil {
+			first = ids
+		}
+		if !reflect.DeepEqual(ids, first) {
+			t.Errorf("expected %v, got %v", first, ids)
+		}
+	}
+}
+
+func TestListSortParameter(t *testing.T) {
+	storage := &SimpleRESTStorage{list: []Simple{
+		{JSONBase: api.JSONBase{ID: "a"}, Name: "beta"},
+		{JSONBase: api.JSONBase{ID: "b"}, Name: "alpha"},
+		{JSONBase: api.JSONBase{ID: "c"}, Name: "gamma"},
+	}}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	names := func(items []Simple) []string {
+		names := []string{}
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	if got := names(getList(t, server.URL+"/prefix/version/simple?sort=name")); !reflect.DeepEqual(got, []string{"alpha", "beta", "gamma"}) {
+		t.Errorf("unexpected order %v", got)
+	}
+	if got := names(getList(t, server.URL+"/prefix/version/simple?sort=name,desc")); !reflect.DeepEqual(got, []string{"gamma", "beta", "alpha"}) {
+		t.Errorf("unexpected order %v", got)
+	}
+	if got := names(storage.list); !reflect.DeepEqual(got, []string{"beta", "alpha", "gamma"}) {
+		t.Errorf("expected the items of the storage to be left alone, got %v", got)
+	}
+
+	// Unknown fields are refused before the storage lists anything.
+	storage.errors = map[string]error{"list": errors.New("listed")}
+	for _, value := range []string{"size", "name,up"} {
+		response, err := http.Get(server.URL + "/prefix/version/simple?sort=" + value)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var status api.Status
+		body, _ := extractBody(response, &status)
+		if response.StatusCode != http.StatusBadRequest || !strings.Contains(status.Message, value) {
+			t.Errorf("%s: expected a bad request naming the field, got %d: %s", value, response.StatusCode, body)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// SubresourceStorage may be implemented by RESTStorage objects that serve parts of their
+// objects on their own, at /resource/id/subresource. Subresources let a client read or write
+// e.g. the binding of a pod, or the status of a build, without touching the rest of the object.
+type SubresourceStorage interface {
+	// Subresources returns the names of the subresources the storage serves.
+	Subresources() []string
+
+	// NewSubresource returns an empty object that can be used with UpdateSubresource after
+	// request data has been put into it. It is only called with supported subresource names.
+	NewSubresource(subresource string) interface{}
+
+	// GetSubresource returns the named subresource of the object with the given id.
+	// IsNotFound(err) is true for the returned error value err when the object is not found.
+	GetSubresource(ctx api.Context, id, subresource string) (interface{}, error)
+
+	// UpdateSubresource replaces the named subresource of the object with the given id,
+	// leaving the rest of the object as it is.
+	UpdateSubresource(ctx api.Context, id, subresource string, obj interface{}) (<-chan interface{}, error)
+}
+
+// SubresourceFieldGetter may be implemented by SubresourceStorage objects whose subresources
+// are lists that can be filtered on the fields of their items, as with ResourceFieldLister.
+type SubresourceFieldGetter interface {
+	// GetSubresourceFields returns the named subresource of the object with the given id,
+	// keeping the items that 'field' selects.
+	GetSubresourceFields(ctx api.Context, id, subresource string, field labels.Selector) (interface{}, error)
+}
+
+// MergePatchMediaType is the media type of the bodies of PATCH requests for subresources: a
+// JSON merge patch, as described by RFC 7386. JSON bodies are read as merge patches too.
+const MergePatchMediaType = "application/merge-patch+json"
+
+// MergePatch is a JSON merge patch of a subresource: fields set in it are changed, fields
+// set to nil are removed, and fields missing from it are left as they are. Nested objects
+// are merged the same way.
+type MergePatch map[string]interface{}
+
+// SubresourcePatcher may be implemented by SubresourceStorage objects that can merge changes
+// into their subresources atomically, so that clients changing different parts of a
+// subresource don't overwrite each other's changes.
+type SubresourcePatcher interface {
+	// PatchSubresource merges patch into the named subresource of the object with the given
+	// id, leaving the rest of the object as it is.
+	PatchSubresource(ctx api.Context, id, subresource string, patch MergePatch) (<-chan interface{}, error)
+}
+
+// findSubresource returns storage as a SubresourceStorage if it serves the named subresource,
+// and otherwise a not found error naming the subresources it does serve.
+func findSubresource(storage RESTStorage, resource, id, subresource string) (SubresourceStorage, error) {
+	var supported []string
+	if subresources, ok := storage.(SubresourceStorage); ok {
+		supported = subresources.Subresources()
+		for _, name := range supported {
+			if name == subresource {
+				return subresources, nil
+			}
+		}
+	}
+	message := fmt.Sprintf("%s %q has no subresource %q", resource, id, subresource)
+	if len(supported) == 0 {
+		message += ", and no subresources are supported"
+	} else {
+		message += fmt.Sprintf(", supported subresources are: %s", strings.Join(supported, ", "))
+	}
+	return nil, &apiServerError{api.Status{
+		Status: api.StatusFailure,
+		Code:   http.StatusNotFound,
+		Reason: api.ReasonTypeNotFound,
+		Details: &api.StatusDetails{
+			Kind: resource,
+			ID:   id,
+		},
+		Message: message,
+	}}
+}
+
+// handleSubresource serves requests for /resource/id/subresource, in the namespace of ctx:
+//
+//	Method     Path              Action
+//	GET        /foo/bar/baz      get subresource 'baz' of 'bar'
+//	PUT, POST  /foo/bar/baz      update subresource 'baz' of 'bar'
+//	PATCH      /foo/bar/baz      merge a MergePatch into subresource 'baz' of 'bar'
+//
+// Updates are passed through the admission chain as updates of the resource "foo/baz". They are
+// not validated with api.Validate, since a subresource is only part of an object; the storage
+// is responsible for validating what it is sent. Subresources that are lists keep the items of
+// the namespace of ctx, and, if the storage is a SubresourceFieldGetter, the items the fields
+// parameter selects.
+func (s *APIServer) handleSubresource(ctx api.Context, parts []string, req *http.Request, w http.ResponseWriter, storage RESTStorage, opts *RequestOptions, codecs requestCodecs) {
+	resource, name, subresource := parts[0], parts[1], parts[2]
+	subresources, err := findSubresource(storage, resource, name, subresource)
+	if err != nil {
+		errorJSON(err, codecs.out, w)
+		return
+	}
+	if err := checkID(objectKind(storage.New()), name); err != nil {
+		errorJSON(err, codecs.out, w)
+		return
+	}
+	switch req.Method {
+	case "GET":
+		field := opts.Fields
+		var item interface{}
+		if getter, ok := subresources.(SubresourceFieldGetter); ok {
+			item, err = getter.GetSubresourceFields(ctx, name, subresource, field)
+		} else if !field.Empty() {
+			err = fmt.Errorf("no field selector implemented for %s/%s", resource, subresource)
+		} else {
+			item, err = subresources.GetSubresource(ctx, name, subresource)
+		}
+		if err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		presentObject(item)
+		filterNamespace(item, ctx.Namespace)
+		writeJSON(http.StatusOK, codecs.out, item, w)
+
+	case "PUT", "POST":
+		body, err := s.readBody(req, w)
+		if err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		obj := subresources.NewSubresource(subresource)
+		if err := codecs.in.DecodeInto(body, obj); err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		if err := admit(s.admission, AdmitUpdate, resource+"/"+subresource, obj); err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		out, err := subresources.UpdateSubresource(ctx, name, subresource, obj)
+		if err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		out = s.lists.invalidateOn(s.listsChangedBy(resource), out)
+		op := s.createOperation(ctx, resource, name, presentResults(out), s.operationWait(opts))
+		s.finishReq(ctx, op, "", codecs.out, w)
+
+	case "PATCH":
+		patch, err := s.readMergePatch(req, w)
+		if err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		if err := admit(s.admission, AdmitUpdate, resource+"/"+subresource, patch); err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		out, err := storage.(SubresourcePatcher).PatchSubresource(ctx, name, subresource, patch)
+		if err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		out = s.lists.invalidateOn(s.listsChangedBy(resource), out)
+		op := s.createOperation(ctx, resource, name, presentResults(out), s.operationWait(opts))
+		s.finishReq(ctx, op, "", codecs.out, w)
+	}
+}
+
+// readMergePatch reads the MergePatch in the body of req, which must be a JSON object sent as
+// MergePatchMediaType or JSON.
+func (s *APIServer) readMergePatch(req *http.Request, w http.ResponseWriter) (MergePatch, error) {
+	if header := req.Header.Get("Content-Type"); len(header) > 0 {
+		mediaType, _, err := mime.ParseMediaType(header)
+		if err != nil {
+			return nil, NewUnsupportedMediaTypeErr(header)
+		}
+		if mediaType != MergePatchMediaType && mediaType != api.JSONMediaType {
+			return nil, NewUnsupportedMediaTypeErr(mediaType)
+		}
+	}
+	body, err := s.readBody(req, w)
+	if err != nil {
+		return nil, err
+	}
+	patch := MergePatch{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		return nil, NewBadRequestErr("mergePatch", "", fmt.Errorf("the body is not a JSON object: %v", err))
+	}
+	return patch, nil
+}
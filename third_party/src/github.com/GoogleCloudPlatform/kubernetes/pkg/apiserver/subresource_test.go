@@ -0,0 +1,259 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// nameStorage is a mapStorage that serves the Name of its Simples as the subresource "name".
+type nameStorage struct {
+	mapStorage
+}
+
+func (s *nameStorage) Subresources() []string {
+	return []string{"name"}
+}
+
+func (s *nameStorage) NewSubresource(subresource string) interface{} {
+	return &Simple{}
+}
+
+func (s *nameStorage) GetSubresource(ctx api.Context, id, subresource string) (interface{}, error) {
+	id = api.QualifiedID(ctx.Namespace, id)
+	item, ok := s.items[id]
+	if !ok {
+		return nil, NewNotFoundErr("simple", id)
+	}
+	return &Simple{JSONBase: api.JSONBase{ID: item.ID, Namespace: item.Namespace}, Name: item.Name}, nil
+}
+
+func (s *nameStorage) UpdateSubresource(ctx api.Context, id, subresource string, obj interface{}) (<-chan interface{}, error) {
+	id = api.QualifiedID(ctx.Namespace, id)
+	item, ok := s.items[id]
+	if !ok {
+		return nil, NewNotFoundErr("simple", id)
+	}
+	item.Name = obj.(*Simple).Name
+	s.items[id] = item
+	return MakeAsync(func() (interface{}, error) {
+		return &item, nil
+	}), nil
+}
+
+func TestSubresources(t *testing.T) {
+	storage := &nameStorage{mapStorage{items: map[string]Simple{
+		"web":   {JSONBase: api.JSONBase{ID: "web", CreationTimestamp: "then"}, Name: "old"},
+		"web_a": {JSONBase: api.JSONBase{ID: "web", Namespace: "a"}, Name: "in a"},
+	}}}
+	handler := New(map[string]RESTStorage{
+		"simple": storage,
+		"plain":  &mapStorage{items: map[string]Simple{}},
+	}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	prefix := server.URL + "/prefix/version"
+
+	code, body := request(t, "GET", prefix+"/simple/web/name", nil)
+	if code != http.StatusOK || !strings.Contains(string(body), `"old"`) {
+		t.Errorf("unexpected response %d: %s", code, body)
+	}
+	code, body = request(t, "GET", prefix+"/ns/a/simple/web/name", nil)
+	if code != http.StatusOK || !strings.Contains(string(body), `"in a"`) || !strings.Contains(string(body), `"id":"web"`) {
+		t.Errorf("unexpected response %d: %s", code, body)
+	}
+
+	code, body = request(t, "PUT", prefix+"/simple/web/name?sync=true", &Simple{JSONBase: api.JSONBase{CreationTimestamp: "now"}, Name: "new"})
+	if code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", code, body)
+	}
+	if item := storage.items["web"]; item.Name != "new" || item.CreationTimestamp != "then" {
+		t.Errorf("expected only the name to change, got %#v", item)
+	}
+
+	table := []struct {
+		method, path string
+		contains     string
+	}{
+		{"GET", "/simple/web/other", "name"},
+		{"PUT", "/simple/web/other", "name"},
+		{"GET", "/plain/web/name", "no subresources"},
+	}
+	for _, item := range table {
+		code, body := request(t, item.method, prefix+item.path, &Simple{})
+		if code != http.StatusNotFound {
+			t.Errorf("%s %s: expected not found, got %d: %s", item.method, item.path, code, body)
+		}
+		status := api.Status{}
+		if err := codec.DecodeInto(body, &status); err != nil {
+			t.Errorf("%s %s: unexpected error: %v", item.method, item.path, err)
+			continue
+		}
+		if status.Reason != api.ReasonTypeNotFound || !strings.Contains(status.Message, item.contains) {
+			t.Errorf("%s %s: expected the supported subresources to be named, got %#v", item.method, item.path, status)
+		}
+	}
+}
+
+// peersStorage is a nameStorage that also serves, as the subresource "peers", the list of its
+// Simples, of every namespace, that have the same Name, filtered on the field "id".
+type peersStorage struct {
+	nameStorage
+}
+
+func (s *peersStorage) Subresources() []string {
+	return []string{"name", "peers"}
+}
+
+func (s *peersStorage) GetSubresourceFields(ctx api.Context, id, subresource string, field labels.Selector) (interface{}, error) {
+	if subresource != "peers" {
+		return s.GetSubresource(ctx, id, subresource)
+	}
+	item, ok := s.items[api.QualifiedID(ctx.Namespace, id)]
+	if !ok {
+		return nil, NewNotFoundErr("simple", id)
+	}
+	list := &SimpleList{}
+	for _, peer := range s.items {
+		if peer.Name == item.Name && field.Matches(labels.Set{"id": peer.ID}) {
+			list.Items = append(list.Items, peer)
+		}
+	}
+	return list, nil
+}
+
+func TestSubresourceFields(t *testing.T) {
+	storage := &peersStorage{nameStorage{mapStorage{items: map[string]Simple{
+		"web":   {JSONBase: api.JSONBase{ID: "web"}, Name: "frontend"},
+		"ui":    {JSONBase: api.JSONBase{ID: "ui"}, Name: "frontend"},
+		"db":    {JSONBase: api.JSONBase{ID: "db"}, Name: "backend"},
+		"web_a": {JSONBase: api.JSONBase{ID: "web", Namespace: "a"}, Name: "frontend"},
+	}}}}
+	handler := New(map[string]RESTStorage{
+		"simple": storage,
+		"plain":  &nameStorage{mapStorage{items: storage.items}},
+	}, codec, "/prefix/version")
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	prefix := server.URL + "/prefix/version"
+
+	table := []struct {
+		path string
+		ids  []string
+	}{
+		{"/simple/web/peers", []string{"ui", "web"}},
+		{"/simple/web/peers?fields=id%3Dui", []string{"ui"}},
+		{"/ns/a/simple/web/peers", []string{"web"}},
+	}
+	for _, item := range table {
+		code, body := request(t, "GET", prefix+item.path, nil)
+		if code != http.StatusOK {
+			t.Errorf("%s: unexpected response %d: %s", item.path, code, body)
+			continue
+		}
+		list := SimpleList{}
+		if err := codec.DecodeInto(body, &list); err != nil {
+			t.Errorf("%s: unexpected error: %v", item.path, err)
+			continue
+		}
+		ids := []string{}
+		for _, simple := range list.Items {
+			ids = append(ids, simple.ID)
+		}
+		sort.Strings(ids)
+		if !reflect.DeepEqual(ids, item.ids) {
+			t.Errorf("%s: expected %v, got %v", item.path, item.ids, ids)
+		}
+	}
+
+	if code, body := request(t, "GET", prefix+"/plain/web/name?fields=id%3Dweb", nil); code != http.StatusInternalServerError || !strings.Contains(string(body), "no field selector") {
+		t.Errorf("expected a field selector to be rejected by a storage without a field getter, got %d: %s", code, body)
+	}
+}
+
+// patchableNameStorage is a nameStorage whose name subresource can be patched.
+type patchableNameStorage struct {
+	nameStorage
+	patches []MergePatch
+}
+
+func (s *patchableNameStorage) PatchSubresource(ctx api.Context, id, subresource string, patch MergePatch) (<-chan interface{}, error) {
+	s.patches = append(s.patches, patch)
+	name, _ := patch["name"].(string)
+	return s.UpdateSubresource(ctx, id, subresource, &Simple{Name: name})
+}
+
+func TestPatchSubresource(t *testing.T) {
+	storage := &patchableNameStorage{nameStorage: nameStorage{mapStorage{items: map[string]Simple{
+		"web": {JSONBase: api.JSONBase{ID: "web"}, Name: "web"},
+	}}}}
+	server := httptest.NewServer(New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version"))
+	defer server.Close()
+
+	table := []struct {
+		contentType string
+		body        string
+		code        int
+	}{
+		{MergePatchMediaType, `{"name": "patched", "other": null}`, http.StatusOK},
+		{"", `{"name": "plain"}`, http.StatusOK},
+		{api.JSONMediaType, `{"name": "json"}`, http.StatusOK},
+		{"text/plain", `{"name": "text"}`, http.StatusUnsupportedMediaType},
+		{MergePatchMediaType, `["name"]`, http.StatusBadRequest},
+	}
+	for _, item := range table {
+		req, _ := http.NewRequest("PATCH", server.URL+"/prefix/version/simple/web/name?sync=true", strings.NewReader(item.body))
+		if len(item.contentType) > 0 {
+			req.Header.Set("Content-Type", item.contentType)
+		}
+		response, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != item.code {
+			t.Errorf("%s %s: expected %d, got %d", item.contentType, item.body, item.code, response.StatusCode)
+		}
+	}
+	if len(storage.patches) != 3 || !reflect.DeepEqual(storage.patches[0], MergePatch{"name": "patched", "other": nil}) {
+		t.Errorf("unexpected patches %#v", storage.patches)
+	}
+	if name := storage.items["web"].Name; name != "json" {
+		t.Errorf("expected the last patch to be stored, got %q", name)
+	}
+
+	// Storages that can't patch subresources don't allow PATCH.
+	plain := httptest.NewServer(New(map[string]RESTStorage{"simple": &storage.nameStorage}, codec, "/prefix/version"))
+	defer plain.Close()
+	req, _ := http.NewRequest("PATCH", plain.URL+"/prefix/version/simple/web/name", strings.NewReader(`{}`))
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusMethodNotAllowed || response.Header.Get("Allow") != "GET, PUT, POST" {
+		t.Errorf("unexpected response %d, Allow %q", response.StatusCode, response.Header.Get("Allow"))
+	}
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// stampObject sets the timestamps of obj, which a client sent to create or update an object
+// of storage, from the server clock, replacing whatever the client sent so that they can't
+// be forged. Objects being created are created now, and have not been modified yet. Objects
+// being updated were modified now, and keep the creation timestamp of the object they
+// replace, which is read from storage.
+func (s *APIServer) stampObject(ctx api.Context, verb string, storage RESTStorage, obj interface{}) {
+	jsonBase, err := api.FindJSONBase(obj)
+	if err != nil {
+		return
+	}
+	now := s.now().UTC().Format(time.RFC3339)
+	if verb == AdmitCreate {
+		jsonBase.SetCreationTimestamp(now)
+		jsonBase.SetLastModified("")
+		return
+	}
+	created := ""
+	if getter, ok := asGetter(storage); ok {
+		if current, err := getter.Get(ctx, jsonBase.ID()); err == nil {
+			if currentBase, err := api.FindJSONBaseRO(current); err == nil {
+				created = currentBase.CreationTimestamp
+			}
+		}
+	}
+	jsonBase.SetCreationTimestamp(created)
+	jsonBase.SetLastModified(now)
+}
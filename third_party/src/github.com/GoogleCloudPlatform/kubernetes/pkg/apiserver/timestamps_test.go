@@ -0,0 +1,71 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestTimestamps(t *testing.T) {
+	storage := &mapStorage{items: map[string]Simple{}}
+	handler := New(map[string]RESTStorage{"simple": storage}, codec, "/prefix/version")
+	now := time.Date(2014, 7, 1, 12, 0, 0, 0, time.UTC)
+	handler.now = func() time.Time { return now }
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	prefix := server.URL + "/prefix/version"
+
+	forged := api.JSONBase{ID: "web", CreationTimestamp: "2000-01-01T00:00:00Z", LastModified: "2000-01-01T00:00:00Z"}
+	code, body := request(t, "POST", prefix+"/simple?sync=true", &Simple{JSONBase: forged, Name: "old"})
+	if code != http.StatusCreated {
+		t.Fatalf("unexpected response %d: %s", code, body)
+	}
+	var item Simple
+	if err := codec.DecodeInto(body, &item); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if item.CreationTimestamp != "2014-07-01T12:00:00Z" || item.LastModified != "" {
+		t.Errorf("expected the object to be created now and not modified, got %#v", item.JSONBase)
+	}
+	if stored := storage.items["web"]; stored.CreationTimestamp != item.CreationTimestamp {
+		t.Errorf("expected the creation timestamp to be stored, got %#v", stored.JSONBase)
+	}
+
+	now = now.Add(time.Hour)
+	code, body = request(t, "PUT", prefix+"/simple/web?sync=true", &Simple{JSONBase: forged, Name: "new"})
+	if code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", code, body)
+	}
+	stored := storage.items["web"]
+	if stored.Name != "new" || stored.CreationTimestamp != "2014-07-01T12:00:00Z" || stored.LastModified != "2014-07-01T13:00:00Z" {
+		t.Errorf("expected the update to keep the creation timestamp and be modified now, got %#v", stored)
+	}
+
+	// Dry runs show the timestamps the object would be stored with.
+	code, body = request(t, "POST", prefix+"/simple?dryRun=true", &Simple{JSONBase: api.JSONBase{ID: "db", CreationTimestamp: "then"}})
+	if err := codec.DecodeInto(body, &item); err != nil || code != http.StatusOK {
+		t.Fatalf("unexpected response %d: %s", code, body)
+	}
+	if item.CreationTimestamp != "2014-07-01T13:00:00Z" {
+		t.Errorf("expected the dry run to be created now, got %#v", item.JSONBase)
+	}
+}
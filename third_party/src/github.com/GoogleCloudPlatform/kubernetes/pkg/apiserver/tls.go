@@ -0,0 +1,140 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// CertificateReloader holds the certificate of a server, loaded from a certificate file
+// and a key file, and loads it again when the files change. Servers get the certificate
+// for each new connection, so connections made before a reload are kept.
+type CertificateReloader struct {
+	certFile, keyFile string
+
+	// lock guards the fields below.
+	lock sync.RWMutex
+	cert *tls.Certificate
+	// certPEM and keyPEM are the contents of the files cert was loaded from.
+	certPEM, keyPEM []byte
+}
+
+// NewCertificateReloader loads the certificate in certFile with the key in keyFile.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads the certificate again if its files changed, and returns true if it did. If
+// the files can't be loaded, e.g. because only one of them was replaced yet, the previous
+// certificate is kept.
+func (r *CertificateReloader) Reload() (bool, error) {
+	certPEM, err := ioutil.ReadFile(r.certFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to read certificate %s: %v", r.certFile, err)
+	}
+	keyPEM, err := ioutil.ReadFile(r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("unable to read key %s: %v", r.keyFile, err)
+	}
+	r.lock.RLock()
+	unchanged := bytes.Equal(certPEM, r.certPEM) && bytes.Equal(keyPEM, r.keyPEM)
+	r.lock.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return false, fmt.Errorf("unable to load certificate %s with key %s: %v", r.certFile, r.keyFile, err)
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cert, r.certPEM, r.keyPEM = &cert, certPEM, keyPEM
+	return true, nil
+}
+
+// Watch checks the files of the certificate for changes every period, forever.
+func (r *CertificateReloader) Watch(period time.Duration) {
+	util.Forever(func() {
+		reloaded, err := r.Reload()
+		if err != nil {
+			glog.Errorf("Keeping the current certificate: %v", err)
+			return
+		}
+		if reloaded {
+			glog.Infof("Reloaded the certificate %s", r.certFile)
+		}
+	}, period)
+}
+
+// GetCertificate returns the current certificate. It is a tls.Config.GetCertificate.
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.cert, nil
+}
+
+// NewServerTLSConfig returns the configuration of a TLS server presenting the certificate of
+// certs. If clientCAFile is set, clients must present a certificate signed by one of the
+// certificate authorities in it.
+func NewServerTLSConfig(certs *CertificateReloader, clientCAFile string) (*tls.Config, error) {
+	config := &tls.Config{GetCertificate: certs.GetCertificate}
+	if len(clientCAFile) > 0 {
+		data, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client certificate authority %s: %v", clientCAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("unable to load client certificate authority %s: no PEM encoded certificates found", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
+// RedirectToTLS returns a handler redirecting requests to the same host and path on the
+// HTTPS port of tlsAddress, so that a plain HTTP port can be kept while clients move to TLS.
+func RedirectToTLS(tlsAddress string) (http.Handler, error) {
+	_, port, err := net.SplitHostPort(tlsAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TLS address %q: %v", tlsAddress, err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		host, _, err := net.SplitHostPort(req.Host)
+		if err != nil {
+			host = req.Host
+		}
+		target := "https://" + net.JoinHostPort(host, port) + req.URL.RequestURI()
+		http.Redirect(w, req, target, http.StatusTemporaryRedirect)
+	}), nil
+}
@@ -0,0 +1,219 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA signs certificates for tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns a certificate and key signed by ca, in PEM, for 127.0.0.1 if usage is
+// x509.ExtKeyUsageServerAuth.
+func (ca *testCA) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestServeTLSWithClientCA(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apiserver-tls")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t)
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+	certPEM, keyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	writeFile(t, caFile, ca.pem)
+
+	certs, err := NewCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	config, err := NewServerTLSConfig(certs, caFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	handler := New(map[string]RESTStorage{"foo": &SimpleRESTStorage{}}, codec, "/prefix/version")
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = tls.NewListener(server.Listener, config)
+	server.Start()
+	defer server.Close()
+	url := "https://" + server.Listener.Addr().String() + "/prefix/version/foo/bar"
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if response, err := anonymous.Get(url); err == nil {
+		response.Body.Close()
+		t.Errorf("expected a client without a certificate to be refused")
+	}
+
+	clientCertPEM, clientKeyPEM := ca.issue(t, 3, x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transport := &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientCert}}}
+	client := &http.Client{Transport: transport}
+	get := func() *x509.Certificate {
+		response, err := client.Get(url)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			t.Errorf("unexpected response %#v", response)
+		}
+		return response.TLS.PeerCertificates[0]
+	}
+	if serial := get().SerialNumber.Int64(); serial != 2 {
+		t.Errorf("unexpected server certificate %d", serial)
+	}
+
+	certPEM, keyPEM = ca.issue(t, 4, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+	if reloaded, err := certs.Reload(); !reloaded || err != nil {
+		t.Fatalf("expected the certificate to be reloaded: %v", err)
+	}
+	if serial := get().SerialNumber.Int64(); serial != 2 {
+		t.Errorf("expected the open connection to be kept, got certificate %d", serial)
+	}
+	transport.CloseIdleConnections()
+	if serial := get().SerialNumber.Int64(); serial != 4 {
+		t.Errorf("expected new connections to get the reloaded certificate, got %d", serial)
+	}
+}
+
+func TestCertificateReloaderKeepsCertificate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "apiserver-tls")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ca := newTestCA(t)
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM, keyPEM := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	writeFile(t, keyFile, keyPEM)
+
+	certs, err := NewCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reloaded, err := certs.Reload(); reloaded || err != nil {
+		t.Errorf("expected unchanged files not to be reloaded: %v", err)
+	}
+	before, _ := certs.GetCertificate(nil)
+
+	// Only the certificate was replaced so far.
+	certPEM, _ = ca.issue(t, 3, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM)
+	if _, err := certs.Reload(); err == nil {
+		t.Errorf("expected a certificate that doesn't match its key to be rejected")
+	}
+	if after, _ := certs.GetCertificate(nil); after != before {
+		t.Errorf("expected the previous certificate to be kept")
+	}
+}
+
+func TestRedirectToTLS(t *testing.T) {
+	redirect, err := RedirectToTLS("0.0.0.0:8443")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request, _ := http.NewRequest("GET", "http://example.com:8080/api/v1beta1/pods?labels=a%3Db", nil)
+	w := httptest.NewRecorder()
+	redirect.ServeHTTP(w, request)
+	if w.Code != http.StatusTemporaryRedirect || w.Header().Get("Location") != "https://example.com:8443/api/v1beta1/pods?labels=a%3Db" {
+		t.Errorf("unexpected redirect %d to %s", w.Code, w.Header().Get("Location"))
+	}
+	if _, err := RedirectToTLS("8443"); err == nil {
+		t.Errorf("expected an address without a port to be rejected")
+	}
+}
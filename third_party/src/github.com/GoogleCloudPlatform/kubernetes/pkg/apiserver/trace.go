@@ -0,0 +1,180 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// DefaultSlowRequestThreshold is how long a request may take before the steps it took are
+// logged.
+const DefaultSlowRequestThreshold = 500 * time.Millisecond
+
+// Names of the steps of a request that handleRESTStorage traces.
+const (
+	stepDecode    = "decode"
+	stepValidate  = "validate"
+	stepStorage   = "storage"
+	stepWait      = "wait"
+	stepEncode    = "encode"
+	stepTotal     = "total"
+	maxTraceSteps = 8
+)
+
+// trace records how long each step of a request took. Steps are kept in a fixed array, and
+// the breakdown is only formatted when the request turns out to be slow, so that tracing
+// costs close to nothing for fast requests. A nil *trace records nothing.
+type trace struct {
+	name  string
+	start time.Time
+	last  time.Time
+	steps [maxTraceSteps]traceStep
+	count int
+}
+
+type traceStep struct {
+	name     string
+	duration time.Duration
+}
+
+// newTrace starts tracing the request called name.
+func newTrace(name string) *trace {
+	now := time.Now()
+	return &trace{name: name, start: now, last: now}
+}
+
+// step records that the step called name ended now, and started when the previous step
+// ended.
+func (t *trace) step(name string) {
+	if t == nil {
+		return
+	}
+	now := time.Now()
+	if t.count < maxTraceSteps {
+		t.steps[t.count] = traceStep{name, now.Sub(t.last)}
+		t.count++
+	}
+	t.last = now
+}
+
+// finish ends the trace, adds its steps to latencies, and logs them if the whole request
+// took longer than threshold. A threshold that isn't positive logs nothing.
+func (t *trace) finish(threshold time.Duration, latencies *latencyHistograms) {
+	if t == nil {
+		return
+	}
+	total := time.Since(t.start)
+	for _, step := range t.steps[:t.count] {
+		latencies.observe(step.name, step.duration)
+	}
+	latencies.observe(stepTotal, total)
+	if threshold <= 0 || total <= threshold {
+		return
+	}
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "Slow request %s took %v:", t.name, total)
+	for _, step := range t.steps[:t.count] {
+		fmt.Fprintf(buf, " %s=%v", step.name, step.duration)
+	}
+	glog.Warning(buf.String())
+}
+
+// latencyBuckets are the upper bounds of the buckets of latencyHistograms. Latencies above
+// the last bound are counted in a final bucket.
+var latencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	25 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// latencyHistograms count the latencies of the steps of requests, by step. A nil
+// *latencyHistograms counts nothing.
+type latencyHistograms struct {
+	lock  sync.Mutex
+	steps map[string]*latencyHistogram
+}
+
+// latencyHistogram is the histogram of the latencies of a step, as served by /metrics.
+type latencyHistogram struct {
+	// Buckets counts the latencies of at most each of LatencyBuckets, and the last one the
+	// latencies above them all.
+	Buckets []uint64 `json:"buckets"`
+	Count   uint64   `json:"count"`
+	// Sum is the total of the latencies, in seconds.
+	Sum float64 `json:"sum"`
+}
+
+func newLatencyHistograms() *latencyHistograms {
+	return &latencyHistograms{steps: map[string]*latencyHistogram{}}
+}
+
+// observe counts a latency of step.
+func (h *latencyHistograms) observe(step string, latency time.Duration) {
+	if h == nil {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	histogram, ok := h.steps[step]
+	if !ok {
+		histogram = &latencyHistogram{Buckets: make([]uint64, len(latencyBuckets)+1)}
+		h.steps[step] = histogram
+	}
+	bucket := 0
+	for bucket < len(latencyBuckets) && latency > latencyBuckets[bucket] {
+		bucket++
+	}
+	histogram.Buckets[bucket]++
+	histogram.Count++
+	histogram.Sum += latency.Seconds()
+}
+
+// latencyMetrics are the histograms of the latencies of requests, by step, and the upper
+// bounds of their buckets.
+type latencyMetrics struct {
+	LatencyBuckets []string                    `json:"latencyBuckets"`
+	Latencies      map[string]latencyHistogram `json:"latencies"`
+}
+
+// metrics returns a copy of the histograms of h.
+func (h *latencyHistograms) metrics() latencyMetrics {
+	m := latencyMetrics{Latencies: map[string]latencyHistogram{}}
+	for _, bound := range latencyBuckets {
+		m.LatencyBuckets = append(m.LatencyBuckets, bound.String())
+	}
+	if h == nil {
+		return m
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for step, histogram := range h.steps {
+		copied := *histogram
+		copied.Buckets = append([]uint64(nil), histogram.Buckets...)
+		m.Latencies[step] = copied
+	}
+	return m
+}
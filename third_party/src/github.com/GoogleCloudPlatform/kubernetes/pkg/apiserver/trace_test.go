@@ -0,0 +1,111 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistograms(t *testing.T) {
+	latencies := newLatencyHistograms()
+	latencies.observe("storage", 0)
+	latencies.observe("storage", time.Millisecond)
+	latencies.observe("storage", 300*time.Millisecond)
+	latencies.observe("storage", time.Minute)
+	m := latencies.metrics()
+	expected := latencyHistogram{Buckets: []uint64{2, 0, 0, 0, 1, 0, 0, 0, 1}, Count: 4, Sum: 60.301}
+	if histogram := m.Latencies["storage"]; !reflect.DeepEqual(histogram, expected) {
+		t.Errorf("expected %#v, got %#v", expected, histogram)
+	}
+	if len(m.LatencyBuckets) != len(latencyBuckets) || m.LatencyBuckets[0] != "1ms" {
+		t.Errorf("unexpected buckets %v", m.LatencyBuckets)
+	}
+
+	// Nil traces and histograms record nothing.
+	var tr *trace
+	tr.step(stepStorage)
+	tr.finish(time.Nanosecond, nil)
+	var none *latencyHistograms
+	none.observe("storage", time.Second)
+	if m := none.metrics(); len(m.Latencies) != 0 {
+		t.Errorf("unexpected latencies %#v", m.Latencies)
+	}
+}
+
+func TestTraceSteps(t *testing.T) {
+	latencies := newLatencyHistograms()
+	tr := newTrace("POST /foo")
+	for i := 0; i < maxTraceSteps+2; i++ {
+		tr.step(stepStorage)
+	}
+	// A threshold this short logs the trace.
+	tr.finish(time.Nanosecond, latencies)
+	m := latencies.metrics()
+	if m.Latencies[stepStorage].Count != maxTraceSteps || m.Latencies[stepTotal].Count != 1 {
+		t.Errorf("expected the steps beyond %d to be dropped, got %#v", maxTraceSteps, m.Latencies)
+	}
+}
+
+func TestRequestLatencyMetrics(t *testing.T) {
+	handler := New(map[string]RESTStorage{"foo": &SimpleRESTStorage{}}, codec, "/prefix/version")
+	handler.SetSlowRequestThreshold(time.Nanosecond)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	data, _ := codec.Encode(&Simple{Name: "foo"})
+	response, err := http.Post(server.URL+"/prefix/version/foo", "application/json", bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+	response, err = http.Get(server.URL + "/prefix/version/foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+
+	response, err = http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	var counters metrics
+	if err := json.Unmarshal(body, &counters); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	expected := map[string]uint64{
+		stepDecode:   1,
+		stepValidate: 1,
+		stepStorage:  2,
+		stepWait:     1,
+		stepEncode:   2,
+		stepTotal:    2,
+	}
+	for step, count := range expected {
+		if counters.Latencies[step].Count != count {
+			t.Errorf("expected %d %s latencies, got %s", count, step, body)
+		}
+	}
+}
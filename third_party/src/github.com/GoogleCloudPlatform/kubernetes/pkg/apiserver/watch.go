@@ -17,63 +17,75 @@ limitations under the License.
 package apiserver
 
 import (
+	"bytes"
+	"encoding/gob"
 	"encoding/json"
+	"io"
 	"net/http"
 	"net/url"
-	"strconv"
 
 	"code.google.com/p/go.net/websocket"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/httplog"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 type WatchHandler struct {
 	storage map[string]RESTStorage
-	codec   Codec
-}
-
-func getWatchParams(query url.Values) (label, field labels.Selector, resourceVersion uint64) {
-	if s, err := labels.ParseSelector(query.Get("labels")); err != nil {
-		label = labels.Everything()
-	} else {
-		label = s
-	}
-	if s, err := labels.ParseSelector(query.Get("fields")); err != nil {
-		field = labels.Everything()
-	} else {
-		field = s
-	}
-	if rv, err := strconv.ParseUint(query.Get("resourceVersion"), 10, 64); err == nil {
-		resourceVersion = rv
-	}
-	return label, field, resourceVersion
+	// negotiate picks the codecs of a request, as for the REST path.
+	negotiate func(req *http.Request) (requestCodecs, error)
+	// context returns the api.Context of a request.
+	context func(req *http.Request) api.Context
+	// checkParams rejects query parameters of a request that aren't allowed.
+	checkParams func(query url.Values, allowed util.StringSet) error
+	// buffers configures the buffer of each watch, so that a slow client can't block the
+	// storage it watches.
+	buffers *watchBuffers
+	// notFound answers the requests for paths that can't be watched, see APIServer.notFound.
+	notFound func(w http.ResponseWriter, req *http.Request, resource string)
 }
 
 // handleWatch processes a watch request
 func (h *WatchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	parts := splitPath(req.URL.Path)
-	if len(parts) < 1 || req.Method != "GET" {
-		notFound(w, req)
+	namespace, parts, ok := splitNamespace(splitPath(req.URL.Path), req.Method)
+	if !ok || len(parts) != 1 || req.Method != "GET" {
+		h.notFound(w, req, "")
 		return
 	}
 	storage := h.storage[parts[0]]
 	if storage == nil {
-		notFound(w, req)
+		h.notFound(w, req, parts[0])
 		return
 	}
-	if watcher, ok := storage.(ResourceWatcher); ok {
-		label, field, resourceVersion := getWatchParams(req.URL.Query())
-		watching, err := watcher.Watch(label, field, resourceVersion)
+	if watcher, ok := asResourceWatcher(storage); ok {
+		codecs, err := h.negotiate(req)
+		if err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		if err := h.checkParams(req.URL.Query(), watchParameters); err != nil {
+			errorJSON(NewBadRequestErr(objectKind(storage.New()), "", err), codecs.out, w)
+			return
+		}
+		opts, err := ParseRequestOptions(req)
+		if err != nil {
+			errorJSON(err, codecs.out, w)
+			return
+		}
+		ctx := h.context(req)
+		ctx.Namespace = namespace
+		gone, answered := clientGone(w)
+		defer answered()
+		ctx.Done = gone
+		watching, err := watcher.Watch(ctx, opts.Labels, opts.Fields, opts.ResourceVersion)
 		if err != nil {
-			errorJSON(err, h.codec, w)
+			errorJSON(err, codecs.out, w)
 			return
 		}
+		watching = newBufferedWatch(newNamespaceWatcher(watching, namespace), h.buffers)
 
-		// TODO: This is one watch per connection. We want to multiplex, so that
-		// multiple watches of the same thing don't create two watches downstream.
-		watchServer := &WatchServer{watching}
+		watchServer := &WatchServer{watching, codecs.out, gone}
 		if req.Header.Get("Connection") == "Upgrade" && req.Header.Get("Upgrade") == "websocket" {
 			websocket.Handler(watchServer.HandleWS).ServeHTTP(httplog.Unlogged(w), req)
 		} else {
@@ -82,16 +94,72 @@ func (h *WatchHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	notFound(w, req)
+	h.notFound(w, req, parts[0])
 }
 
 // WatchServer serves a watch.Interface over a websocket or vanilla HTTP.
 type WatchServer struct {
 	watching watch.Interface
+	// codec encodes the objects of the events.
+	codec typedCodec
+	// gone is closed when the client goes away, see clientGone. If it is nil, ServeHTTP
+	// watches the client itself.
+	gone <-chan struct{}
 }
 
-// HandleWS implements a websocket handler.
+// watchFrame is an event as framed on streams whose codec doesn't encode JSON. Each event is
+// written as a gob of a watchFrame, whose Object is encoded with the codec of the stream.
+type watchFrame struct {
+	Type            watch.EventType
+	ResourceVersion uint64
+	Object          []byte
+}
+
+// encodeWatchEvent returns event as it is written to a stream whose objects are encoded
+// with codec. Streams of JSON have one api.WatchEvent per line, whose fields are always in
+// the order Type, ResourceVersion, Object, so that readers can dispatch on the type before
+// decoding the object. ResourceVersion is that of the object, and is left out of events
+// whose object has none, such as errors. Streams of other encodings are series of gobs of
+// watchFrames.
+func encodeWatchEvent(codec typedCodec, event watch.Event) ([]byte, error) {
+	object, err := codec.Encode(event.Object)
+	if err != nil {
+		return nil, err
+	}
+	resourceVersion, _ := api.ResourceVersioner.ResourceVersion(event.Object)
+	if codec.mediaType != api.JSONMediaType {
+		buf := &bytes.Buffer{}
+		err := gob.NewEncoder(buf).Encode(&watchFrame{event.Type, resourceVersion, object})
+		return buf.Bytes(), err
+	}
+	data, err := json.Marshal(&struct {
+		Type            watch.EventType
+		ResourceVersion uint64 `json:",omitempty"`
+		Object          json.RawMessage
+	}{event.Type, resourceVersion, object})
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeWatchEvent writes event to w in a single Write, so that each event is a message of
+// its own on a websocket.
+func (s *WatchServer) writeWatchEvent(w io.Writer, event watch.Event) error {
+	data, err := encodeWatchEvent(s.codec, event)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// HandleWS implements a websocket handler. Events encoded as JSON are sent as text messages,
+// and others as binary messages.
 func (w *WatchServer) HandleWS(ws *websocket.Conn) {
+	if w.codec.mediaType != api.JSONMediaType {
+		ws.PayloadType = websocket.BinaryFrame
+	}
 	done := make(chan struct{})
 	go func() {
 		var unused interface{}
@@ -110,11 +178,7 @@ func (w *WatchServer) HandleWS(ws *websocket.Conn) {
 				// End of results.
 				return
 			}
-			err := websocket.JSON.Send(ws, &api.WatchEvent{
-				Type:   event.Type,
-				Object: api.APIObject{event.Object},
-			})
-			if err != nil {
+			if err := w.writeWatchEvent(ws, event); err != nil {
 				// Client disconnect.
 				w.watching.Stop()
 				return
@@ -123,14 +187,19 @@ func (w *WatchServer) HandleWS(ws *websocket.Conn) {
 	}
 }
 
-// ServeHTTP serves a series of JSON encoded events via straight HTTP with
+// ServeHTTP serves a series of encoded events via straight HTTP with
 // Transfer-Encoding: chunked.
 func (self *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	loggedW := httplog.LogOf(w)
 	w = httplog.Unlogged(w)
 
-	cn, ok := w.(http.CloseNotifier)
-	if !ok {
+	gone := self.gone
+	if gone == nil {
+		var answered func()
+		gone, answered = clientGone(w)
+		defer answered()
+	}
+	if gone == nil {
 		loggedW.Addf("unable to get CloseNotifier")
 		http.NotFound(loggedW, req)
 		return
@@ -142,14 +211,14 @@ func (self *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	loggedW.Header().Set("Content-Type", self.codec.mediaType)
 	loggedW.Header().Set("Transfer-Encoding", "chunked")
 	loggedW.WriteHeader(http.StatusOK)
 	flusher.Flush()
 
-	encoder := json.NewEncoder(w)
 	for {
 		select {
-		case <-cn.CloseNotify():
+		case <-gone:
 			self.watching.Stop()
 			return
 		case event, ok := <-self.watching.ResultChan():
@@ -157,11 +226,7 @@ func (self *WatchServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				// End of results.
 				return
 			}
-			err := encoder.Encode(&api.WatchEvent{
-				Type:   event.Type,
-				Object: api.APIObject{event.Object},
-			})
-			if err != nil {
+			if err := self.writeWatchEvent(w, event); err != nil {
 				// Client disconnect.
 				self.watching.Stop()
 				return
@@ -17,11 +17,14 @@ limitations under the License.
 package apiserver
 
 import (
+	"bufio"
+	"encoding/gob"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 
 	"code.google.com/p/go.net/websocket"
@@ -142,6 +145,96 @@ func TestWatchHTTP(t *testing.T) {
 	}
 }
 
+// watchStream opens a watch of the resource "foo" of server, accepting mediaType.
+func watchStream(t *testing.T, server *httptest.Server, mediaType string) *http.Response {
+	request, err := http.NewRequest("GET", server.URL+"/prefix/version/watch/foo", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request.Header.Set("Accept", mediaType)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != mediaType {
+		t.Fatalf("unexpected response %#v", response)
+	}
+	return response
+}
+
+func TestWatchHTTPEventEnvelope(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	server := httptest.NewServer(New(map[string]RESTStorage{"foo": simpleStorage}, codec, "/prefix/version"))
+	defer server.Close()
+	response := watchStream(t, server, api.JSONMediaType)
+	defer response.Body.Close()
+
+	events := []watch.Event{
+		{Type: watch.Added, Object: &Simple{JSONBase: api.JSONBase{ResourceVersion: 2}, Name: "a"}},
+		{Type: watch.Modified, Object: &Simple{JSONBase: api.JSONBase{ResourceVersion: 3}, Name: "b"}},
+		{Type: watch.Deleted, Object: &Simple{JSONBase: api.JSONBase{ResourceVersion: 4}, Name: "b"}},
+		{Type: watch.Error, Object: &api.Status{Status: api.StatusFailure, Message: "etcd went away"}},
+	}
+	go func() {
+		for _, event := range events {
+			simpleStorage.fakeWatch.Action(event.Type, event.Object)
+		}
+		simpleStorage.fakeWatch.Stop()
+	}()
+
+	reader := bufio.NewReader(response.Body)
+	for _, expected := range events {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(line, `{"Type":"`+string(expected.Type)+`",`) {
+			t.Errorf("expected the type to come first, got %s", line)
+		}
+		var got api.WatchEvent
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		version, _ := api.ResourceVersioner.ResourceVersion(expected.Object)
+		if got.Type != expected.Type || got.ResourceVersion != version || !reflect.DeepEqual(got.Object.Object, expected.Object) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+		if expected.Type == watch.Error && strings.Contains(line, "ResourceVersion") {
+			t.Errorf("expected no resource version in an error event, got %s", line)
+		}
+	}
+	if _, err := reader.ReadString('\n'); err == nil {
+		t.Errorf("expected the stream to end")
+	}
+}
+
+func TestWatchHTTPNegotiatedCodec(t *testing.T) {
+	simpleStorage := &SimpleRESTStorage{}
+	handler := New(map[string]RESTStorage{"foo": simpleStorage}, codec, "/prefix/version")
+	handler.AddCodec(api.GobMediaType, api.GobCodec)
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	response := watchStream(t, server, api.GobMediaType)
+	defer response.Body.Close()
+
+	expected := &Simple{JSONBase: api.JSONBase{ResourceVersion: 7}, Name: "gob"}
+	go simpleStorage.fakeWatch.Modify(expected)
+	var frame watchFrame
+	if err := gob.NewDecoder(response.Body).Decode(&frame); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if frame.Type != watch.Modified || frame.ResourceVersion != 7 {
+		t.Errorf("unexpected frame %#v", frame)
+	}
+	obj, err := api.GobCodec.Decode(frame.Object)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(obj, expected) {
+		t.Errorf("expected %#v, got %#v", expected, obj)
+	}
+}
+
 func TestWatchParamParsing(t *testing.T) {
 	simpleStorage := &SimpleRESTStorage{}
 	handler := New(map[string]RESTStorage{
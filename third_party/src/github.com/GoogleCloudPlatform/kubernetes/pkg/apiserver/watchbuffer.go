@@ -0,0 +1,244 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// WatchBufferPolicy is what a watch does when its client reads events more slowly than the
+// storage produces them, and the events waiting for the client fill its buffer.
+type WatchBufferPolicy string
+
+const (
+	// WatchBufferDrop ends the watch with an error event telling the client to list the
+	// resource again and watch from there.
+	WatchBufferDrop WatchBufferPolicy = "drop"
+	// WatchBufferCoalesce replaces an event waiting for the client with a newer event for
+	// the same object, so that the client only sees the latest state of each object. An
+	// object added and deleted before the client reads either event is left out. The
+	// watch is still dropped if the buffer fills with events for different objects.
+	WatchBufferCoalesce WatchBufferPolicy = "coalesce"
+)
+
+// DefaultWatchBufferSize is the number of events each watch buffers for its client, unless
+// another size is given to SetWatchBuffer.
+const DefaultWatchBufferSize = 100
+
+// watchBuffers configures the buffers of the watches of an APIServer, and counts what they
+// did to keep slow clients from stalling the storage.
+type watchBuffers struct {
+	size   int
+	policy WatchBufferPolicy
+
+	// dropped and coalesced are accessed atomically.
+	dropped   uint64
+	coalesced uint64
+}
+
+// watchBufferMetrics counts the watches dropped and the events coalesced for slow clients.
+type watchBufferMetrics struct {
+	DroppedWatches  uint64 `json:"watchesDropped"`
+	CoalescedEvents uint64 `json:"watchEventsCoalesced"`
+}
+
+// metrics returns the counters of b.
+func (b *watchBuffers) metrics() watchBufferMetrics {
+	return watchBufferMetrics{
+		DroppedWatches:  atomic.LoadUint64(&b.dropped),
+		CoalescedEvents: atomic.LoadUint64(&b.coalesced),
+	}
+}
+
+// SetWatchBuffer makes each watch served by s buffer up to size events for its client, and
+// apply policy when they don't fit. By default, DefaultWatchBufferSize events are buffered
+// and watches are dropped with WatchBufferDrop. A size that isn't positive keeps the default.
+func (s *APIServer) SetWatchBuffer(size int, policy WatchBufferPolicy) error {
+	if policy != WatchBufferDrop && policy != WatchBufferCoalesce {
+		return fmt.Errorf("unknown watch buffer policy %q, expected %q or %q", policy, WatchBufferDrop, WatchBufferCoalesce)
+	}
+	if size <= 0 {
+		size = DefaultWatchBufferSize
+	}
+	s.watches.size = size
+	s.watches.policy = policy
+	return nil
+}
+
+// bufferedWatch passes the events of a watch on as its client reads them, keeping up to the
+// size of its watchBuffers waiting, so that a slow client doesn't block the storage.
+type bufferedWatch struct {
+	watching watch.Interface
+	buffers  *watchBuffers
+	result   chan watch.Event
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	// pending are the events waiting for the client, and queued the index in pending of the
+	// event of each object, for coalescing.
+	pending []pendingEvent
+	queued  map[string]int
+}
+
+// pendingEvent is an event waiting for the client, with the key of its object. The key is
+// kept so that the object isn't read again once it is handed to the client, which encodes it.
+type pendingEvent struct {
+	watch.Event
+	key string
+}
+
+func newBufferedWatch(watching watch.Interface, buffers *watchBuffers) *bufferedWatch {
+	w := &bufferedWatch{
+		watching: watching,
+		buffers:  buffers,
+		result:   make(chan watch.Event),
+		stop:     make(chan struct{}),
+		queued:   map[string]int{},
+	}
+	go w.loop()
+	return w
+}
+
+// ResultChan implements watch.Interface.
+func (w *bufferedWatch) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// Stop implements watch.Interface.
+func (w *bufferedWatch) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.watching.Stop()
+	})
+}
+
+func (w *bufferedWatch) loop() {
+	defer util.HandleCrash()
+	defer close(w.result)
+	in := w.watching.ResultChan()
+	for in != nil || len(w.pending) > 0 {
+		// Only offer an event to the client when there is one.
+		var out chan watch.Event
+		var next watch.Event
+		if len(w.pending) > 0 {
+			out, next = w.result, w.pending[0].Event
+		}
+		select {
+		case <-w.stop:
+			return
+		case out <- next:
+			w.pop()
+		case event, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			if !w.push(event) {
+				w.drop()
+				return
+			}
+		}
+	}
+}
+
+// push adds event to the pending events, coalescing it with a pending event for the same
+// object if the policy asks for that. It returns false if the event doesn't fit.
+func (w *bufferedWatch) push(event watch.Event) bool {
+	key := eventKey(event)
+	if w.buffers.policy == WatchBufferCoalesce && len(key) > 0 {
+		if i, ok := w.queued[key]; ok {
+			atomic.AddUint64(&w.buffers.coalesced, 1)
+			// The client never saw an object that is added and then modified, or added and
+			// then deleted, in which case it hears of neither.
+			if w.pending[i].Type == watch.Added {
+				switch event.Type {
+				case watch.Modified:
+					event.Type = watch.Added
+				case watch.Deleted:
+					w.remove(i)
+					return true
+				}
+			}
+			w.pending[i] = pendingEvent{event, key}
+			return true
+		}
+	}
+	if len(w.pending) >= w.buffers.size {
+		return false
+	}
+	if len(key) > 0 {
+		w.queued[key] = len(w.pending)
+	}
+	w.pending = append(w.pending, pendingEvent{event, key})
+	return true
+}
+
+// pop removes the first pending event, once the client has read it.
+func (w *bufferedWatch) pop() {
+	w.remove(0)
+}
+
+// remove removes the pending event at index i.
+func (w *bufferedWatch) remove(i int) {
+	delete(w.queued, w.pending[i].key)
+	w.pending = append(w.pending[:i], w.pending[i+1:]...)
+	for key, j := range w.queued {
+		if j > i {
+			w.queued[key] = j - 1
+		}
+	}
+}
+
+// drop ends the watch of a client that fell behind: the storage's watch is stopped, the
+// pending events are discarded, and the client is sent an error event telling it to list
+// the resource again.
+func (w *bufferedWatch) drop() {
+	atomic.AddUint64(&w.buffers.dropped, 1)
+	w.watching.Stop()
+	// Don't leave the storage blocked on events nobody reads.
+	go func() {
+		for _ = range w.watching.ResultChan() {
+		}
+	}()
+	w.pending, w.queued = nil, nil
+	status := &api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusGone,
+		Message: fmt.Sprintf("the watch fell more than %d events behind and was closed, list the resource again and watch from its resourceVersion", w.buffers.size),
+	}
+	select {
+	case <-w.stop:
+	case w.result <- watch.Event{Type: watch.Error, Object: status}:
+	}
+}
+
+// eventKey returns the namespace and ID of the object of event, or "" if it has no ID.
+func eventKey(event watch.Event) string {
+	jsonBase, err := api.FindJSONBaseRO(event.Object)
+	if err != nil || len(jsonBase.ID) == 0 {
+		return ""
+	}
+	return api.QualifiedID(jsonBase.Namespace, jsonBase.ID)
+}
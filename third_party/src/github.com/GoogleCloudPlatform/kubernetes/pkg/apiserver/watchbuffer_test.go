@@ -0,0 +1,237 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apiserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/httplog"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// slowResponseWriter is the ResponseWriter of a client that stops reading: every Write
+// blocks until release is closed.
+type slowResponseWriter struct {
+	header http.Header
+	// started receives a value as each Write begins.
+	started chan struct{}
+	release chan struct{}
+
+	lock    sync.Mutex
+	written bytes.Buffer
+}
+
+func newSlowResponseWriter() *slowResponseWriter {
+	return &slowResponseWriter{
+		header:  http.Header{},
+		started: make(chan struct{}, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (w *slowResponseWriter) Header() http.Header      { return w.header }
+func (w *slowResponseWriter) WriteHeader(int)          {}
+func (w *slowResponseWriter) Flush()                   {}
+func (w *slowResponseWriter) CloseNotify() <-chan bool { return make(chan bool) }
+func (w *slowResponseWriter) events() *json.Decoder    { return json.NewDecoder(&w.written) }
+func (w *slowResponseWriter) Write(data []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.release
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.written.Write(data)
+}
+
+// serveSlowWatch serves the events of fake to a slow client through a bufferedWatch with
+// buffers. The returned channel is closed once the watch is served.
+func serveSlowWatch(fake *watch.FakeWatcher, buffers *watchBuffers) (*slowResponseWriter, chan struct{}) {
+	slow := newSlowResponseWriter()
+	var w http.ResponseWriter = slow
+	req, _ := http.NewRequest("GET", "/prefix/version/watch/simple", nil)
+	httplog.MakeLogged(req, &w)
+	server := &WatchServer{newBufferedWatch(fake, buffers), typedCodec{Codec: codec, mediaType: api.JSONMediaType}, nil}
+	done := make(chan struct{})
+	go func() {
+		server.ServeHTTP(w, req)
+		close(done)
+	}()
+	return slow, done
+}
+
+func simpleEvent(t watch.EventType, id, name string) watch.Event {
+	return watch.Event{Type: t, Object: &Simple{JSONBase: api.JSONBase{ID: id}, Name: name}}
+}
+
+// expectEvents checks that the events decoded from decoder are expected, and that no others
+// follow.
+func expectEvents(t *testing.T, decoder *json.Decoder, expected ...watch.Event) {
+	for _, e := range expected {
+		var got api.WatchEvent
+		if err := decoder.Decode(&got); err != nil {
+			t.Fatalf("expected %#v, got error %v", e, err)
+		}
+		if got.Type != e.Type {
+			t.Errorf("expected an event of type %s, got %#v", e.Type, got)
+			continue
+		}
+		if status, ok := e.Object.(*api.Status); ok {
+			if got, ok := got.Object.Object.(*api.Status); !ok || got.Code != status.Code {
+				t.Errorf("expected a status with code %d, got %#v", status.Code, got)
+			}
+			continue
+		}
+		if got, ok := got.Object.Object.(*Simple); !ok || got.Name != e.Object.(*Simple).Name {
+			t.Errorf("expected %#v, got %#v", e.Object, got)
+		}
+	}
+	var extra api.WatchEvent
+	if err := decoder.Decode(&extra); err != io.EOF {
+		t.Errorf("expected no more events, got %#v (%v)", extra, err)
+	}
+}
+
+func TestBufferedWatchDropsSlowClients(t *testing.T) {
+	buffers := &watchBuffers{size: 2, policy: WatchBufferDrop}
+	fake := watch.NewFake()
+	slow, done := serveSlowWatch(fake, buffers)
+
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "a"}, Name: "a1"})
+	<-slow.started
+	fake.Modify(&Simple{JSONBase: api.JSONBase{ID: "a"}, Name: "a2"})
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "b"}, Name: "b1"})
+	// This event doesn't fit, which stops the watch of the storage.
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "c"}, Name: "c1"})
+	close(slow.release)
+	<-done
+
+	if !fake.Stopped {
+		t.Errorf("expected the watch of the storage to be stopped")
+	}
+	expectEvents(t, slow.events(),
+		simpleEvent(watch.Added, "a", "a1"),
+		watch.Event{Type: watch.Error, Object: &api.Status{Code: http.StatusGone}},
+	)
+	if m := buffers.metrics(); m.DroppedWatches != 1 || m.CoalescedEvents != 0 {
+		t.Errorf("unexpected metrics %#v", m)
+	}
+}
+
+func TestBufferedWatchCoalescesEvents(t *testing.T) {
+	buffers := &watchBuffers{size: 2, policy: WatchBufferCoalesce}
+	fake := watch.NewFake()
+	slow, done := serveSlowWatch(fake, buffers)
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "a"}, Name: "a1"})
+	<-slow.started
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "b"}, Name: "b1"})
+	fake.Modify(&Simple{JSONBase: api.JSONBase{ID: "b"}, Name: "b2"})
+	fake.Modify(&Simple{JSONBase: api.JSONBase{ID: "a"}, Name: "a2"})
+	fake.Modify(&Simple{JSONBase: api.JSONBase{ID: "a"}, Name: "a3"})
+	fake.Delete(&Simple{JSONBase: api.JSONBase{ID: "b"}, Name: "b3"})
+	fake.Stop()
+	close(slow.release)
+	<-done
+	// The pending events keep their order, and only the newest event of each object is
+	// sent. An object that is added, modified and deleted before the client reads it is
+	// never heard of.
+	expectEvents(t, slow.events(),
+		simpleEvent(watch.Added, "a", "a1"),
+		simpleEvent(watch.Modified, "a", "a3"),
+	)
+	if m := buffers.metrics(); m.DroppedWatches != 0 || m.CoalescedEvents != 3 {
+		t.Errorf("unexpected metrics %#v", m)
+	}
+
+	// An object that is added and modified before the client reads it is still added, and
+	// an object added and deleted leaves room for others.
+	buffers = &watchBuffers{size: 2, policy: WatchBufferCoalesce}
+	fake = watch.NewFake()
+	slow, done = serveSlowWatch(fake, buffers)
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "a"}, Name: "a1"})
+	<-slow.started
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "b"}, Name: "b1"})
+	fake.Modify(&Simple{JSONBase: api.JSONBase{ID: "b"}, Name: "b2"})
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "c"}, Name: "c1"})
+	fake.Delete(&Simple{JSONBase: api.JSONBase{ID: "c"}, Name: "c2"})
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "d"}, Name: "d1"})
+	fake.Stop()
+	close(slow.release)
+	<-done
+	expectEvents(t, slow.events(),
+		simpleEvent(watch.Added, "a", "a1"),
+		simpleEvent(watch.Added, "b", "b2"),
+		simpleEvent(watch.Added, "d", "d1"),
+	)
+	if m := buffers.metrics(); m.DroppedWatches != 0 || m.CoalescedEvents != 2 {
+		t.Errorf("unexpected metrics %#v", m)
+	}
+
+	// Events for more objects than fit still drop the watch.
+	buffers = &watchBuffers{size: 1, policy: WatchBufferCoalesce}
+	fake = watch.NewFake()
+	slow, done = serveSlowWatch(fake, buffers)
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "a"}, Name: "a1"})
+	<-slow.started
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "b"}, Name: "b1"})
+	fake.Add(&Simple{JSONBase: api.JSONBase{ID: "c"}, Name: "c1"})
+	close(slow.release)
+	<-done
+	expectEvents(t, slow.events(),
+		simpleEvent(watch.Added, "a", "a1"),
+		watch.Event{Type: watch.Error, Object: &api.Status{Code: http.StatusGone}},
+	)
+	if m := buffers.metrics(); m.DroppedWatches != 1 {
+		t.Errorf("unexpected metrics %#v", m)
+	}
+}
+
+func TestSetWatchBuffer(t *testing.T) {
+	handler := New(map[string]RESTStorage{}, codec, "/prefix/version")
+	if handler.watches.size != DefaultWatchBufferSize || handler.watches.policy != WatchBufferDrop {
+		t.Errorf("unexpected default buffers %#v", handler.watches)
+	}
+	if err := handler.SetWatchBuffer(10, "newest"); err == nil {
+		t.Errorf("expected an unknown policy to be refused")
+	}
+	if err := handler.SetWatchBuffer(10, WatchBufferCoalesce); err != nil || handler.watches.size != 10 || handler.watches.policy != WatchBufferCoalesce {
+		t.Errorf("unexpected buffers %#v: %v", handler.watches, err)
+	}
+
+	handler.watches.dropped, handler.watches.coalesced = 2, 5
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	response, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	var metrics watchBufferMetrics
+	if err := json.Unmarshal(body, &metrics); err != nil {
+		t.Fatalf("unexpected error: %v (%s)", err, body)
+	}
+	if metrics.DroppedWatches != 2 || metrics.CoalescedEvents != 5 {
+		t.Errorf("unexpected metrics: %s", body)
+	}
+}
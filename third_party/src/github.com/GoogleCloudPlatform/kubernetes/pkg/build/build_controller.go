@@ -3,6 +3,7 @@ package build
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/golang/glog"
 )
@@ -37,8 +39,8 @@ func MakeBuildController(kubeClient client.Interface, dockerBuilderImage, docker
 	}
 
 	bc.typeStrategies = map[buildconfigapi.BuildType]buildTypeStrategy{
-		buildconfigapi.BuildType("docker"): bc.dockerBuildStrategy,
-		buildconfigapi.BuildType("sti"):    bc.stiBuildStrategy,
+		buildconfigapi.DockerBuildType: bc.dockerBuildStrategy,
+		buildconfigapi.STIBuildType:    bc.stiBuildStrategy,
 	}
 
 	return bc
@@ -52,35 +54,74 @@ func (bc *BuildController) Run(period time.Duration) {
 
 // The main sync loop. Iterates over current builds and delegates syncing.
 func (bc *BuildController) synchronize() {
-	builds, err := bc.kubeClient.ListBuilds()
+	builds, err := bc.kubeClient.ListBuilds(labels.Everything())
 	if err != nil {
 		glog.Errorf("Error listing builds: %v (%#v)", err, err)
 		return
 	}
 
 	for _, build := range builds.Items {
+		previous := build
 		nextStatus, err := bc.process(&build)
 		if err != nil {
 			glog.Errorf("Error processing build ID %v: %#v", build.ID, err)
 		}
 
 		if nextStatus != build.Status {
-			build.Status = nextStatus
-			if _, err := bc.kubeClient.UpdateBuild(build); err != nil {
+			recordTransition(&build, previous, nextStatus, err, time.Now())
+			// Only the status is sent, so that changes made to the rest of the build
+			// meanwhile are kept.
+			if _, err := bc.kubeClient.UpdateBuildStatus(build); err != nil {
 				glog.Errorf("Error updating build ID %v to status %v: %#v", build.ID, nextStatus, err)
 			}
 		}
 	}
 }
 
+// recordTransition moves build to status next, recording when it started or finished, and
+// why it moved. previous is the build as it was before it was processed, and err the error
+// that made it move, if any. A message set while processing the build is kept; otherwise
+// the message is err, or describes the new status.
+func recordTransition(build *buildapi.Build, previous buildapi.Build, next buildapi.BuildStatus, err error, now time.Time) {
+	build.Status = next
+	switch next {
+	case buildapi.BuildRunning:
+		build.StartTimestamp = now.Format(time.UnixDate)
+	case buildapi.BuildComplete, buildapi.BuildFailed:
+		build.CompletionTimestamp = now.Format(time.UnixDate)
+	}
+	if build.Reason == previous.Reason {
+		build.Reason = ""
+	}
+	if build.Message != previous.Message {
+		return
+	}
+	switch {
+	case err != nil:
+		build.Message = err.Error()
+	case next == buildapi.BuildPending:
+		build.Message = fmt.Sprintf("The build will run in pod %s", build.PodID)
+	case next == buildapi.BuildRunning:
+		build.Message = fmt.Sprintf("Pod %s was created to run the build", build.PodID)
+	case next == buildapi.BuildComplete:
+		build.Message = "The build completed"
+	default:
+		build.Message = ""
+	}
+}
+
 func (bc BuildController) hasTimeoutElapsed(build *buildapi.Build) (bool, error) {
-	timestamp, err := time.Parse(time.UnixDate, build.CreationTimestamp)
+	timestamp, err := time.Parse(time.RFC3339, build.CreationTimestamp)
 	if err != nil {
 		return false, err
 	}
 
+	timeout := bc.timeout
+	if build.Timeout > 0 {
+		timeout = build.Timeout
+	}
 	elapsed := time.Since(timestamp)
-	if int(elapsed.Seconds()) > bc.timeout {
+	if int(elapsed.Seconds()) > timeout {
 		return true, nil
 	}
 
@@ -100,7 +141,7 @@ func (bc *BuildController) process(build *buildapi.Build) (buildapi.BuildStatus,
 	case buildapi.BuildPending:
 		makePodSpec, ok := bc.typeStrategies[build.Config.Type]
 		if !ok {
-			return build.Status, fmt.Errorf("No build type for %s")
+			return build.Status, fmt.Errorf("No build type for %s", build.Config.Type)
 		}
 
 		podSpec := makePodSpec(*build)
@@ -115,6 +156,7 @@ func (bc *BuildController) process(build *buildapi.Build) (buildapi.BuildStatus,
 				return build.Status, err // no transition, already handled by someone else
 			}
 
+			build.Reason = buildapi.BuildReasonPodCreationFailed
 			return buildapi.BuildFailed, err
 		}
 
@@ -125,11 +167,19 @@ func (bc *BuildController) process(build *buildapi.Build) (buildapi.BuildStatus,
 			return buildapi.BuildFailed, err
 		}
 		if timedOut {
+			build.Reason = buildapi.BuildReasonTimeout
+			if err := bc.kubeClient.DeletePod(build.PodID); err != nil && !client.IsNotFound(err) {
+				glog.Errorf("Error deleting pod of timed out build ID %v: %#v", build.ID, err)
+			}
 			return buildapi.BuildFailed, fmt.Errorf("Build timed out")
 		}
 
 		pod, err := bc.kubeClient.GetPod(build.PodID)
 		if err != nil {
+			if client.IsNotFound(err) {
+				build.Reason = buildapi.BuildReasonPodDeleted
+				return buildapi.BuildFailed, fmt.Errorf("Pod for build ID %v was deleted before the build finished", build.ID)
+			}
 			return build.Status, fmt.Errorf("Error retrieving pod for build ID %v: %#v", build.ID, err)
 		}
 
@@ -141,12 +191,23 @@ func (bc *BuildController) process(build *buildapi.Build) (buildapi.BuildStatus,
 		var nextStatus = buildapi.BuildComplete
 
 		// check the exit codes of all the containers in the pod
-		for _, info := range pod.CurrentState.Info {
-			if info.State.ExitCode != 0 {
+		names := []string{}
+		for name := range pod.CurrentState.Info {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if info := pod.CurrentState.Info[name]; info.State.ExitCode != 0 && nextStatus != buildapi.BuildFailed {
 				nextStatus = buildapi.BuildFailed
+				build.Reason = buildapi.BuildReasonContainerFailed
+				build.Message = fmt.Sprintf("Container %s of pod %s exited with code %d", name, build.PodID, info.State.ExitCode)
 			}
 		}
 
+		if nextStatus == buildapi.BuildComplete {
+			build.OutputImage = buildapi.OutputImageReference(buildapi.OutputImage(build, bc.dockerRegistry))
+		}
+
 		// Attempt to clean up the pod before the terminal transition.
 		// TODO: Pod cleanup in general is a larger problem - this is a quick
 		// hack to facilitate development.
@@ -157,6 +218,20 @@ func (bc *BuildController) process(build *buildapi.Build) (buildapi.BuildStatus,
 		}
 
 		return nextStatus, nil
+	case buildapi.BuildCancelled:
+		// The pod is deleted on every sync until it is gone, in case it was created after
+		// the build was cancelled.
+		if len(build.PodID) == 0 {
+			return build.Status, nil
+		}
+		if _, err := bc.kubeClient.GetPod(build.PodID); err != nil {
+			return build.Status, nil
+		}
+		glog.Infof("Deleting pod %s of cancelled build %s", build.PodID, build.ID)
+		if err := bc.kubeClient.DeletePod(build.PodID); err != nil {
+			return build.Status, fmt.Errorf("Error deleting pod for build ID %v: %#v", build.ID, err)
+		}
+		return build.Status, nil
 	default:
 		return build.Status, nil
 	}
@@ -193,6 +268,18 @@ func (bc BuildController) setupDockerSocket(podSpec *api.Pod) {
 	}
 }
 
+// outputEnv returns the environment that tells the builder of build where to push its
+// image: BUILD_TAG is the image and DOCKER_REGISTRY the registry, as builders push to, and
+// OUTPUT_IMAGE the full reference of the image.
+func (bc BuildController) outputEnv(build *buildapi.Build) []api.EnvVar {
+	registry, image := buildapi.OutputImage(build, bc.dockerRegistry)
+	return []api.EnvVar{
+		{Name: "BUILD_TAG", Value: image},
+		{Name: "DOCKER_REGISTRY", Value: registry},
+		{Name: "OUTPUT_IMAGE", Value: buildapi.OutputImageReference(registry, image)},
+	}
+}
+
 func (bc BuildController) dockerBuildStrategy(build buildapi.Build) api.Pod {
 	return api.Pod{
 		JSONBase: api.JSONBase{
@@ -206,11 +293,9 @@ func (bc BuildController) dockerBuildStrategy(build buildapi.Build) api.Pod {
 						Name:          "docker-build",
 						Image:         bc.dockerBuilderImage,
 						RestartPolicy: "runOnce",
-						Env: []api.EnvVar{
-							{Name: "BUILD_TAG", Value: build.Config.ImageTag},
-							{Name: "DOCKER_CONTEXT_URL", Value: build.Config.SourceURI},
-							{Name: "DOCKER_REGISTRY", Value: bc.dockerRegistry},
-						},
+						Env: append(bc.outputEnv(&build),
+							api.EnvVar{Name: "DOCKER_CONTEXT_URL", Value: build.Config.SourceURI},
+						),
 					},
 				},
 			},
@@ -231,13 +316,11 @@ func (bc BuildController) stiBuildStrategy(build buildapi.Build) api.Pod {
 						Name:          "sti-build",
 						Image:         bc.stiBuilderImage,
 						RestartPolicy: "runOnce",
-						Env: []api.EnvVar{
-							{Name: "BUILD_TAG", Value: build.Config.ImageTag},
-							{Name: "DOCKER_REGISTRY", Value: bc.dockerRegistry},
-							{Name: "SOURCE_URI", Value: build.Config.SourceURI},
-							{Name: "SOURCE_REF", Value: build.Config.SourceRef},
-							{Name: "BUILDER_IMAGE", Value: build.Config.BuilderImage},
-						},
+						Env: append(bc.outputEnv(&build),
+							api.EnvVar{Name: "SOURCE_URI", Value: build.Config.SourceURI},
+							api.EnvVar{Name: "SOURCE_REF", Value: build.Config.SourceRef},
+							api.EnvVar{Name: "BUILDER_IMAGE", Value: build.Config.BuilderImage},
+						),
 					},
 				},
 			},
@@ -0,0 +1,242 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/fsouza/go-dockerclient"
+)
+
+// podClient serves builds and pods from memory, and records deleted pods.
+type podClient struct {
+	client.FakeClient
+	builds  []buildapi.Build
+	pods    map[string]api.Pod
+	deleted []string
+	updated []buildapi.Build
+}
+
+func (c *podClient) ListBuilds(selector labels.Selector) (buildapi.BuildList, error) {
+	return buildapi.BuildList{Items: c.builds}, nil
+}
+
+func (c *podClient) UpdateBuildStatus(build buildapi.Build) (buildapi.Build, error) {
+	c.updated = append(c.updated, build)
+	return build, nil
+}
+
+func (c *podClient) GetPod(name string) (api.Pod, error) {
+	pod, ok := c.pods[name]
+	if !ok {
+		return api.Pod{}, &client.StatusErr{Status: api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound}}
+	}
+	return pod, nil
+}
+
+func (c *podClient) DeletePod(name string) error {
+	if _, ok := c.pods[name]; !ok {
+		return &client.StatusErr{Status: api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound}}
+	}
+	delete(c.pods, name)
+	c.deleted = append(c.deleted, name)
+	return nil
+}
+
+func runningBuild(id string, age time.Duration, timeout int) buildapi.Build {
+	return buildapi.Build{
+		JSONBase: api.JSONBase{ID: id, CreationTimestamp: time.Now().Add(-age).UTC().Format(time.RFC3339)},
+		Status:   buildapi.BuildRunning,
+		PodID:    "build-" + id,
+		Timeout:  timeout,
+	}
+}
+
+func runningPod(id string) api.Pod {
+	return api.Pod{JSONBase: api.JSONBase{ID: id}, CurrentState: api.PodState{Status: api.PodRunning}}
+}
+
+func TestBuildPodDisappears(t *testing.T) {
+	fake := &podClient{
+		builds: []buildapi.Build{runningBuild("gone", time.Second, 0), runningBuild("alive", time.Second, 0)},
+		pods:   map[string]api.Pod{"build-alive": runningPod("build-alive")},
+	}
+	bc := MakeBuildController(fake, "docker-builder", "", "sti-builder", 120)
+	bc.synchronize()
+
+	if len(fake.updated) != 1 {
+		t.Fatalf("expected one build to be updated, got %#v", fake.updated)
+	}
+	build := fake.updated[0]
+	if build.ID != "gone" || build.Status != buildapi.BuildFailed || build.Reason != buildapi.BuildReasonPodDeleted {
+		t.Errorf("expected build to fail because its pod was deleted, got %#v", build)
+	}
+	if len(fake.deleted) != 0 {
+		t.Errorf("expected no pods to be deleted, got %v", fake.deleted)
+	}
+}
+
+func TestBuildOutputImage(t *testing.T) {
+	output := &buildapi.BuildOutput{Registry: "registry:5000", ImageName: "openshift/ruby", TagTemplate: "b${BUILD_ID}-${COMMIT}"}
+	build := runningBuild("42", time.Second, 0)
+	build.Output = output
+	build.Revision = &buildapi.SourceRevision{Commit: "0123456789abcdef"}
+	untagged := runningBuild("43", time.Second, 0)
+	untagged.Config.ImageTag = "openshift/hello"
+	fake := &podClient{
+		builds: []buildapi.Build{build, untagged},
+		pods: map[string]api.Pod{
+			"build-42": {JSONBase: api.JSONBase{ID: "build-42"}, CurrentState: api.PodState{Status: api.PodTerminated}},
+			"build-43": {JSONBase: api.JSONBase{ID: "build-43"}, CurrentState: api.PodState{Status: api.PodTerminated}},
+		},
+	}
+	bc := MakeBuildController(fake, "docker-builder", "default:5000", "sti-builder", 120)
+
+	env := map[string]string{}
+	for _, v := range bc.dockerBuildStrategy(build).DesiredState.Manifest.Containers[0].Env {
+		env[v.Name] = v.Value
+	}
+	expected := map[string]string{"BUILD_TAG": "openshift/ruby:b42-0123456", "DOCKER_REGISTRY": "registry:5000", "OUTPUT_IMAGE": "registry:5000/openshift/ruby:b42-0123456"}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("expected %s=%s in the environment of the build pod, got %q", name, value, env[name])
+		}
+	}
+
+	bc.synchronize()
+	images := []string{}
+	for _, build := range fake.updated {
+		if build.Status != buildapi.BuildComplete {
+			t.Errorf("expected build %s to complete, got %s", build.ID, build.Status)
+		}
+		images = append(images, build.OutputImage)
+	}
+	if !reflect.DeepEqual(images, []string{"registry:5000/openshift/ruby:b42-0123456", "default:5000/openshift/hello"}) {
+		t.Errorf("unexpected output images: %v", images)
+	}
+}
+
+func TestBuildTimeout(t *testing.T) {
+	fake := &podClient{
+		builds: []buildapi.Build{
+			// Exceeds the controller's default timeout.
+			runningBuild("default", 10*time.Minute, 0),
+			// Exceeds its own timeout, but not the default.
+			runningBuild("short", 10*time.Second, 5),
+			// Within its own timeout, which is longer than the default.
+			runningBuild("long", 10*time.Minute, 3600),
+		},
+		pods: map[string]api.Pod{
+			"build-default": runningPod("build-default"),
+			"build-short":   runningPod("build-short"),
+			"build-long":    runningPod("build-long"),
+		},
+	}
+	bc := MakeBuildController(fake, "docker-builder", "", "sti-builder", 120)
+	bc.synchronize()
+
+	failed := []string{}
+	for _, build := range fake.updated {
+		if build.Status != buildapi.BuildFailed || build.Reason != buildapi.BuildReasonTimeout {
+			t.Errorf("expected build to time out, got %#v", build)
+		}
+		failed = append(failed, build.ID)
+	}
+	if !reflect.DeepEqual(failed, []string{"default", "short"}) {
+		t.Errorf("unexpected timed out builds: %v", failed)
+	}
+	if !reflect.DeepEqual(fake.deleted, []string{"build-default", "build-short"}) {
+		t.Errorf("expected pods of timed out builds to be deleted, got %v", fake.deleted)
+	}
+}
+
+func TestBuildTransitionsAreRecorded(t *testing.T) {
+	terminated := func(id string, exitCode int) api.Pod {
+		return api.Pod{
+			JSONBase: api.JSONBase{ID: id},
+			CurrentState: api.PodState{
+				Status: api.PodTerminated,
+				Info:   api.PodInfo{"build": docker.Container{State: docker.State{ExitCode: exitCode}}},
+			},
+		}
+	}
+	fake := &podClient{
+		builds: []buildapi.Build{
+			{JSONBase: api.JSONBase{ID: "new"}, Status: buildapi.BuildNew, Config: buildconfigapi.BuildConfig{Type: buildconfigapi.DockerBuildType}},
+			{JSONBase: api.JSONBase{ID: "pending"}, Status: buildapi.BuildPending, PodID: "build-pending", Config: buildconfigapi.BuildConfig{Type: buildconfigapi.DockerBuildType}, Message: "The build will run in pod build-pending"},
+			runningBuild("complete", time.Second, 0),
+			runningBuild("failed", time.Second, 0),
+			runningBuild("running", time.Second, 0),
+		},
+		pods: map[string]api.Pod{
+			"build-complete": terminated("build-complete", 0),
+			"build-failed":   terminated("build-failed", 2),
+			"build-running":  runningPod("build-running"),
+		},
+	}
+	for i := range fake.builds {
+		if fake.builds[i].Status == buildapi.BuildRunning {
+			fake.builds[i].StartTimestamp = fake.builds[i].CreationTimestamp
+		}
+	}
+	bc := MakeBuildController(fake, "docker-builder", "", "sti-builder", 120)
+	bc.synchronize()
+
+	updated := map[string]buildapi.Build{}
+	for _, build := range fake.updated {
+		updated[build.ID] = build
+	}
+	if len(updated) != 4 {
+		t.Fatalf("expected the builds that changed status to be updated, got %#v", fake.updated)
+	}
+	if build := updated["new"]; build.Status != buildapi.BuildPending || build.Message != "The build will run in pod build-docker-new" || len(build.StartTimestamp) != 0 {
+		t.Errorf("unexpected new build %#v", build)
+	}
+	if build := updated["pending"]; build.Status != buildapi.BuildRunning || build.Message != "Pod build-pending was created to run the build" || len(build.StartTimestamp) == 0 || len(build.CompletionTimestamp) != 0 {
+		t.Errorf("unexpected pending build %#v", build)
+	}
+	if build := updated["complete"]; build.Status != buildapi.BuildComplete || build.Reason != "" || build.Message != "The build completed" || len(build.CompletionTimestamp) == 0 {
+		t.Errorf("unexpected complete build %#v", build)
+	}
+	build := updated["failed"]
+	if build.Status != buildapi.BuildFailed || build.Reason != buildapi.BuildReasonContainerFailed || build.Message != "Container build of pod build-failed exited with code 2" || len(build.CompletionTimestamp) == 0 {
+		t.Errorf("unexpected failed build %#v", build)
+	}
+	if build.StartTimestamp != fake.builds[3].StartTimestamp {
+		t.Errorf("expected the start of the build to be kept, got %#v", build)
+	}
+}
+
+func TestRecordTransition(t *testing.T) {
+	now := time.Unix(1000, 0)
+	previous := buildapi.Build{Status: buildapi.BuildRunning, PodID: "build-a", Message: "Pod build-a was created to run the build"}
+	build := previous
+	build.Reason = buildapi.BuildReasonTimeout
+	recordTransition(&build, previous, buildapi.BuildFailed, fmt.Errorf("Build timed out"), now)
+	if build.Status != buildapi.BuildFailed || build.Reason != buildapi.BuildReasonTimeout || build.Message != "Build timed out" || build.CompletionTimestamp != now.Format(time.UnixDate) {
+		t.Errorf("unexpected build %#v", build)
+	}
+}
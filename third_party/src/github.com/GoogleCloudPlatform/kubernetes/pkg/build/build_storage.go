@@ -18,6 +18,7 @@ package build
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"code.google.com/p/go-uuid/uuid"
@@ -25,6 +26,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // BuildRegistryStorage is an implementation of RESTStorage for the api server.
@@ -43,20 +45,66 @@ func (storage *BuildRegistryStorage) New() interface{} {
 }
 
 // List obtains a list of Builds that match selector.
-func (storage *BuildRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (storage *BuildRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	return storage.ListFields(ctx, selector, labels.Everything())
+}
+
+// ListFields obtains a list of Builds whose labels match label and whose fields match
+// field, implementing apiserver.ResourceFieldLister.
+func (storage *BuildRegistryStorage) ListFields(ctx api.Context, label, field labels.Selector) (interface{}, error) {
+	if err := validateBuildFields(field); err != nil {
+		return nil, err
+	}
 	result := buildapi.BuildList{}
 	builds, err := storage.registry.ListBuilds()
 	if err == nil {
 		for _, build := range builds.Items {
-			result.Items = append(result.Items, build)
+			if label.Matches(labels.Set(build.Labels)) && field.Matches(buildFields(&build)) {
+				result.Items = append(result.Items, build)
+			}
 		}
 	}
 	return result, err
 }
 
+// Watch returns Build events via a watch.Interface, implementing apiserver.ResourceWatcher.
+func (storage *BuildRegistryStorage) Watch(ctx api.Context, label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return storage.registry.WatchBuilds(label, field, resourceVersion)
+}
+
+// buildFields returns the fields of a Build that can be selected on.
+func buildFields(build *buildapi.Build) labels.Set {
+	return labels.Set{"status": string(build.Status)}
+}
+
+// validateBuildFields returns an error if field selects on a field that buildFields
+// does not provide, or on a status that does not exist.
+func validateBuildFields(field labels.Selector) error {
+	if field.Empty() {
+		return nil
+	}
+	// Selectors render each term as "name=value" or "name!=value".
+	for _, term := range strings.Split(field.String(), ",") {
+		parts := strings.SplitN(term, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid field selector term %q", term)
+		}
+		name, value := strings.TrimSuffix(parts[0], "!"), parts[1]
+		if name != "status" {
+			return fmt.Errorf("no field selector implemented for builds on %q", name)
+		}
+		switch buildapi.BuildStatus(value) {
+		case buildapi.BuildNew, buildapi.BuildPending, buildapi.BuildRunning, buildapi.BuildComplete, buildapi.BuildFailed, buildapi.BuildCancelled:
+		default:
+			return fmt.Errorf("unknown build status %q", value)
+		}
+	}
+	return nil
+}
+
 // Get obtains the build specified by its id.
-func (storage *BuildRegistryStorage) Get(id string) (interface{}, error) {
-	build, err := storage.registry.GetBuild(id)
+func (storage *BuildRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	build, err := storage.registry.GetBuild(api.QualifiedID(ctx.Namespace, id))
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +112,9 @@ func (storage *BuildRegistryStorage) Get(id string) (interface{}, error) {
 }
 
 // Delete asynchronously deletes the Build specified by its id.
-func (storage *BuildRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (storage *BuildRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	return apiserver.MakeAsync(func() (interface{}, error) {
-		return api.Status{Status: api.StatusSuccess}, storage.registry.DeleteBuild(id)
+		return api.Status{Status: api.StatusSuccess}, storage.registry.DeleteBuild(api.QualifiedID(ctx.Namespace, id))
 	}), nil
 }
 
@@ -77,11 +125,12 @@ func (storage *BuildRegistryStorage) Extract(body []byte) (interface{}, error) {
 	return result, err
 }
 
-// Create registers a given new Build instance to storage.registry.
-func (storage *BuildRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+// Default implements apiserver.Defaulter, filling in the ID, status and creation time
+// of a Build if they are not set.
+func (storage *BuildRegistryStorage) Default(ctx api.Context, obj interface{}) {
 	build, ok := obj.(*buildapi.Build)
 	if !ok {
-		return nil, fmt.Errorf("not a build: %#v", obj)
+		return
 	}
 	if len(build.ID) == 0 {
 		build.ID = uuid.NewUUID().String()
@@ -89,12 +138,20 @@ func (storage *BuildRegistryStorage) Create(obj interface{}) (<-chan interface{}
 	if len(build.Status) == 0 {
 		build.Status = buildapi.BuildNew
 	}
-
 	if build.CreationTimestamp == "" {
-		build.CreationTimestamp = time.Now().Format(time.UnixDate)
+		build.CreationTimestamp = time.Now().UTC().Format(time.RFC3339)
 	}
+}
 
-	return apiserver.MakeAsync(func() (interface{}, error) {
+// Create registers a given new Build instance to storage.registry.
+func (storage *BuildRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	build, ok := obj.(*buildapi.Build)
+	if !ok {
+		return nil, fmt.Errorf("not a build: %#v", obj)
+	}
+	storage.Default(ctx, build)
+	return apiserver.MakeAsyncWithProgress(func(progress apiserver.ProgressFunc) (interface{}, error) {
+		progress(apiserver.ProgressStatus(fmt.Sprintf("storing build %s", build.ID)))
 		err := storage.registry.CreateBuild(*build)
 		if err != nil {
 			return nil, err
@@ -104,7 +161,7 @@ func (storage *BuildRegistryStorage) Create(obj interface{}) (<-chan interface{}
 }
 
 // Update replaces a given Build instance with an existing instance in storage.registry.
-func (storage *BuildRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (storage *BuildRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	build, ok := obj.(*buildapi.Build)
 	if !ok {
 		return nil, fmt.Errorf("not a build: %#v", obj)
@@ -112,6 +169,13 @@ func (storage *BuildRegistryStorage) Update(obj interface{}) (<-chan interface{}
 	if len(build.ID) == 0 {
 		return nil, fmt.Errorf("ID should not be empty: %#v", build)
 	}
+	existing, err := storage.registry.GetBuild(api.QualifiedID(build.Namespace, build.ID))
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTransition(existing.Status, build.Status); err != nil {
+		return nil, apiserver.NewConflictErr("build", build.ID, err)
+	}
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		err := storage.registry.UpdateBuild(*build)
 		if err != nil {
@@ -120,3 +184,71 @@ func (storage *BuildRegistryStorage) Update(obj interface{}) (<-chan interface{}
 		return build, nil
 	}), nil
 }
+
+// Subresources implements apiserver.SubresourceStorage. The status of a build is its
+// Status, Reason, Message, PodID, timestamps and OutputImage, which the build controller
+// updates as the build runs.
+func (storage *BuildRegistryStorage) Subresources() []string {
+	return []string{"status"}
+}
+
+func (storage *BuildRegistryStorage) NewSubresource(subresource string) interface{} {
+	return &buildapi.Build{}
+}
+
+func (storage *BuildRegistryStorage) GetSubresource(ctx api.Context, id, subresource string) (interface{}, error) {
+	return storage.Get(ctx, id)
+}
+
+// UpdateSubresource copies the status of the build it receives onto the Build specified by
+// id. The configuration of the existing build is kept, whatever the received build says,
+// even if it is changed while the status is updated.
+func (storage *BuildRegistryStorage) UpdateSubresource(ctx api.Context, id, subresource string, obj interface{}) (<-chan interface{}, error) {
+	status, ok := obj.(*buildapi.Build)
+	if !ok {
+		return nil, fmt.Errorf("not a build: %#v", obj)
+	}
+	buildID := api.QualifiedID(ctx.Namespace, id)
+	existing, err := storage.registry.GetBuild(buildID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTransition(existing.Status, status.Status); err != nil {
+		return nil, apiserver.NewConflictErr("build", id, err)
+	}
+	return apiserver.MakeAsync(func() (interface{}, error) {
+		build, err := storage.registry.UpdateBuildStatus(buildID, func(build *buildapi.Build) error {
+			// The build may have changed since it was checked.
+			if err := validateTransition(build.Status, status.Status); err != nil {
+				return apiserver.NewConflictErr("build", id, err)
+			}
+			build.Status = status.Status
+			build.Reason = status.Reason
+			build.Message = status.Message
+			build.PodID = status.PodID
+			build.StartTimestamp = status.StartTimestamp
+			build.CompletionTimestamp = status.CompletionTimestamp
+			build.OutputImage = status.OutputImage
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return build, nil
+	}), nil
+}
+
+// validateTransition returns an error if a build may not move from status 'from' to 'to'.
+// Builds can be cancelled until they finish, and a cancelled build stays cancelled.
+func validateTransition(from, to buildapi.BuildStatus) error {
+	if from == to {
+		return nil
+	}
+	switch {
+	case from == buildapi.BuildCancelled:
+		return fmt.Errorf("build was cancelled")
+	case to == buildapi.BuildCancelled && (from == buildapi.BuildComplete || from == buildapi.BuildFailed):
+		return fmt.Errorf("build is already %s", from)
+	}
+	return nil
+}
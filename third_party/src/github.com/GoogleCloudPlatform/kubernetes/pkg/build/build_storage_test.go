@@ -0,0 +1,113 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+func TestListBuildsFiltered(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "a"}, Labels: map[string]string{"deploy": "1"}, Status: buildapi.BuildFailed})
+	registry.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "b"}, Labels: map[string]string{"deploy": "1"}, Status: buildapi.BuildComplete})
+	registry.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "c"}, Labels: map[string]string{"deploy": "2"}, Status: buildapi.BuildFailed})
+	registry.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "d"}, Status: buildapi.BuildRunning})
+	storage := NewBuildRegistryStorage(registry).(*BuildRegistryStorage)
+
+	table := []struct {
+		label, field string
+		expected     []string
+	}{
+		{"", "", []string{"a", "b", "c", "d"}},
+		{"deploy=1", "", []string{"a", "b"}},
+		{"", "status=failed", []string{"a", "c"}},
+		{"deploy=1", "status=failed", []string{"a"}},
+		{"deploy=1", "status!=failed", []string{"b"}},
+	}
+	for _, item := range table {
+		label, _ := labels.ParseSelector(item.label)
+		field, _ := labels.ParseSelector(item.field)
+		obj, err := storage.ListFields(api.NewContext(), label, field)
+		if err != nil {
+			t.Fatalf("%s %s: unexpected error: %v", item.label, item.field, err)
+		}
+		ids := []string{}
+		for _, build := range obj.(buildapi.BuildList).Items {
+			ids = append(ids, build.ID)
+		}
+		sort.Strings(ids)
+		if !reflect.DeepEqual(ids, item.expected) {
+			t.Errorf("%s %s: expected %v, got %v", item.label, item.field, item.expected, ids)
+		}
+	}
+}
+
+func TestListBuildsUnsupportedField(t *testing.T) {
+	storage := NewBuildRegistryStorage(MakeMemoryRegistry()).(*BuildRegistryStorage)
+	for _, selector := range []string{"podID=foo", "status=broken"} {
+		field, _ := labels.ParseSelector(selector)
+		if _, err := storage.ListFields(api.NewContext(), labels.Everything(), field); err == nil {
+			t.Errorf("%s: expected an error", selector)
+		}
+	}
+}
+
+func TestUpdateBuildStatus(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateBuild(buildapi.Build{
+		JSONBase: api.JSONBase{ID: "a"},
+		Config:   buildconfigapi.BuildConfig{Type: buildconfigapi.DockerBuildType, SourceURI: "git://source"},
+		Status:   buildapi.BuildNew,
+	})
+	registry.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "b"}, Status: buildapi.BuildCancelled})
+	storage := NewBuildRegistryStorage(registry).(*BuildRegistryStorage)
+
+	channel, err := storage.UpdateSubresource(api.NewContext(), "a", "status", &buildapi.Build{
+		Status:         buildapi.BuildPending,
+		Reason:         buildapi.BuildReasonPodCreationFailed,
+		Message:        "retrying",
+		PodID:          "build-a",
+		StartTimestamp: "Mon Jan  2 15:04:05 MST 2006",
+		OutputImage:    "registry/a:1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-channel
+	build, _ := registry.GetBuild("a")
+	if build.Status != buildapi.BuildPending || build.PodID != "build-a" || build.Config.SourceURI != "git://source" {
+		t.Errorf("expected only the status of the build to change, got %#v", build)
+	}
+	if build.Reason != buildapi.BuildReasonPodCreationFailed || build.Message != "retrying" || build.StartTimestamp != "Mon Jan  2 15:04:05 MST 2006" {
+		t.Errorf("expected the reason, message and times of the transition to be stored, got %#v", build)
+	}
+	if build.OutputImage != "registry/a:1" {
+		t.Errorf("expected the output image to be stored, got %#v", build)
+	}
+
+	if _, err := storage.UpdateSubresource(api.NewContext(), "b", "status", &buildapi.Build{Status: buildapi.BuildRunning}); !apiserver.IsConflict(err) {
+		t.Errorf("expected a cancelled build to stay cancelled, got %v", err)
+	}
+}
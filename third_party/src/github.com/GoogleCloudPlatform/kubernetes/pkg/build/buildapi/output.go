@@ -0,0 +1,89 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildapi
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ShortCommitLength is the number of characters of a commit SHA ${COMMIT} is replaced with
+// in tag templates.
+const ShortCommitLength = 7
+
+// tagVariable matches the variables of tag templates.
+var tagVariable = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// validTag matches the tags docker accepts.
+var validTag = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+
+// ExpandTagTemplate returns template with ${BUILD_ID} replaced with buildID and ${COMMIT}
+// with the first ShortCommitLength characters of commit. Other variables are kept as they
+// are.
+func ExpandTagTemplate(template, buildID, commit string) string {
+	if len(commit) > ShortCommitLength {
+		commit = commit[:ShortCommitLength]
+	}
+	return tagVariable.ReplaceAllStringFunc(template, func(variable string) string {
+		switch variable {
+		case "${BUILD_ID}":
+			return buildID
+		case "${COMMIT}":
+			return commit
+		}
+		return variable
+	})
+}
+
+// IsValidTagTemplate returns true if template only uses the variables ExpandTagTemplate
+// replaces, and is a valid tag once they are replaced.
+func IsValidTagTemplate(template string) bool {
+	return validTag.MatchString(ExpandTagTemplate(template, "id", "0123456789abcdef"))
+}
+
+// OutputImage returns the registry the build pushes its image to, or defaultRegistry if the
+// build doesn't name one, and the image, its repository and tag if it has one.
+func OutputImage(build *Build, defaultRegistry string) (registry, image string) {
+	registry, image = defaultRegistry, build.Config.ImageTag
+	output := build.Output
+	if output == nil {
+		return registry, image
+	}
+	if len(output.Registry) > 0 {
+		registry = output.Registry
+	}
+	if len(output.ImageName) > 0 {
+		image = output.ImageName
+	}
+	if len(output.TagTemplate) > 0 {
+		commit := ""
+		if build.Revision != nil {
+			commit = build.Revision.Commit
+		}
+		image += ":" + ExpandTagTemplate(output.TagTemplate, build.ID, commit)
+	}
+	return registry, image
+}
+
+// OutputImageReference returns the full reference of the image the build pushes to
+// registry, e.g. "registry:5000/openshift/ruby-hello:b42".
+func OutputImageReference(registry, image string) string {
+	if len(registry) == 0 {
+		return image
+	}
+	return strings.TrimRight(registry, "/") + "/" + image
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildapi
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+)
+
+func TestExpandTagTemplate(t *testing.T) {
+	table := map[string]string{
+		"latest":                 "latest",
+		"${BUILD_ID}":            "42",
+		"v1-${COMMIT}":           "v1-0123456",
+		"${BUILD_ID}.${COMMIT}":  "42.0123456",
+		"${UNKNOWN}-${BUILD_ID}": "${UNKNOWN}-42",
+	}
+	for template, expected := range table {
+		if tag := ExpandTagTemplate(template, "42", "0123456789abcdef"); tag != expected {
+			t.Errorf("%q: expected %q, got %q", template, expected, tag)
+		}
+	}
+	if tag := ExpandTagTemplate("c${COMMIT}", "42", "abc"); tag != "cabc" {
+		t.Errorf("expected a short commit to be kept whole, got %q", tag)
+	}
+}
+
+func TestOutputImage(t *testing.T) {
+	build := &Build{JSONBase: api.JSONBase{ID: "42"}, Config: buildconfigapi.BuildConfig{ImageTag: "openshift/hello"}}
+	table := []struct {
+		output    *BuildOutput
+		reference string
+	}{
+		{nil, "default:5000/openshift/hello"},
+		{&BuildOutput{TagTemplate: "b${BUILD_ID}"}, "default:5000/openshift/hello:b42"},
+		{&BuildOutput{Registry: "other/", ImageName: "openshift/ruby"}, "other/openshift/ruby"},
+	}
+	for _, item := range table {
+		build.Output = item.output
+		if reference := OutputImageReference(OutputImage(build, "default:5000")); reference != item.reference {
+			t.Errorf("%#v: expected %q, got %q", item.output, item.reference, reference)
+		}
+	}
+	if reference := OutputImageReference(OutputImage(&Build{Config: buildconfigapi.BuildConfig{ImageTag: "hello"}}, "")); reference != "hello" {
+		t.Errorf("expected the image alone without a registry, got %q", reference)
+	}
+}
+
+func TestValidateBuildTagTemplate(t *testing.T) {
+	config := buildconfigapi.BuildConfig{Type: buildconfigapi.DockerBuildType, SourceURI: "git://source", ImageTag: "hello"}
+	for _, template := range []string{"", "latest", "v1.${BUILD_ID}-${COMMIT}", "${COMMIT}"} {
+		build := &Build{Config: config, Output: &BuildOutput{TagTemplate: template}}
+		if errs := ValidateBuild(build); len(errs) != 0 {
+			t.Errorf("%q: unexpected errors: %v", template, errs)
+		}
+	}
+	for _, template := range []string{"v1 beta", "a/b", "tag:1", ".hidden", "${BRANCH}", "${BUILD_ID", "é"} {
+		build := &Build{Config: config, Output: &BuildOutput{TagTemplate: template}}
+		errs := ValidateBuild(build)
+		if len(errs) != 1 || errs[0].(api.ValidationError).ErrorField != "output.tagTemplate" {
+			t.Errorf("%q: expected the tag template to be invalid, got %v", template, errs)
+		}
+	}
+}
@@ -25,9 +25,75 @@ import (
 // the status of the operation and a reference to the Pod which runs the build.
 type Build struct {
 	api.JSONBase `json:",inline" yaml:",inline"`
+	Labels       map[string]string          `json:"labels,omitempty" yaml:"labels,omitempty"`
 	Config       buildconfigapi.BuildConfig `json:"config,omitempty" yaml:"config,omitempty"`
 	Status       BuildStatus                `json:"status,omitempty" yaml:"status,omitempty"`
 	PodID        string                     `json:"podID,omitempty" yaml:"podID,omitempty"`
+	Revision     *SourceRevision            `json:"revision,omitempty" yaml:"revision,omitempty"`
+	// ParentID is the id of the build this build was cloned from, if any.
+	ParentID string `json:"parentID,omitempty" yaml:"parentID,omitempty"`
+	// Timeout is the number of seconds the build may run before it is failed. If zero, the
+	// build controller's default applies.
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Reason explains why the build is in its current status, e.g. why it failed.
+	Reason BuildReason `json:"reason,omitempty" yaml:"reason,omitempty"`
+	// Message describes the last change of the status of the build for humans.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// StartTimestamp is when the pod of the build was created, in time.UnixDate format.
+	StartTimestamp string `json:"startTimestamp,omitempty" yaml:"startTimestamp,omitempty"`
+	// CompletionTimestamp is when the build completed, failed or was cancelled, in
+	// time.UnixDate format.
+	CompletionTimestamp string `json:"completionTimestamp,omitempty" yaml:"completionTimestamp,omitempty"`
+	// Output names the image the build pushes. If nil, Config.ImageTag is pushed to the
+	// registry of the build controller.
+	Output *BuildOutput `json:"output,omitempty" yaml:"output,omitempty"`
+	// OutputImage is the reference of the image the build pushed, set when it completes.
+	OutputImage string `json:"outputImage,omitempty" yaml:"outputImage,omitempty"`
+}
+
+// BuildOutput names the image a build pushes.
+type BuildOutput struct {
+	// Registry is the host, and optionally the port, of the registry the image is pushed
+	// to. If empty, the registry of the build controller is used.
+	Registry string `json:"registry,omitempty" yaml:"registry,omitempty"`
+	// ImageName is the repository of the image, e.g. "openshift/ruby-hello". If empty,
+	// Config.ImageTag is used.
+	ImageName string `json:"imageName,omitempty" yaml:"imageName,omitempty"`
+	// TagTemplate is the tag of the image, in which ${BUILD_ID} is replaced with the ID of
+	// the build and ${COMMIT} with the short SHA of the commit it builds, which is empty for
+	// builds started by hand. If empty, the image isn't tagged.
+	TagTemplate string `json:"tagTemplate,omitempty" yaml:"tagTemplate,omitempty"`
+}
+
+// BuildReason is a machine readable explanation of a build's status.
+type BuildReason string
+
+const (
+	// BuildReasonTimeout means the build ran longer than its timeout.
+	BuildReasonTimeout BuildReason = "Timeout"
+	// BuildReasonPodDeleted means the build's pod disappeared before the build finished.
+	BuildReasonPodDeleted BuildReason = "PodDeleted"
+	// BuildReasonPodCreationFailed means the pod of the build could not be created.
+	BuildReasonPodCreationFailed BuildReason = "PodCreationFailed"
+	// BuildReasonContainerFailed means a container of the build's pod exited with an error.
+	BuildReasonContainerFailed BuildReason = "ContainerFailed"
+	// BuildReasonCancelled means a client asked for the build to be stopped.
+	BuildReasonCancelled BuildReason = "Cancelled"
+)
+
+// SourceRevision describes the commit a Build was triggered for, when it was started by
+// a source change rather than by hand.
+type SourceRevision struct {
+	// Commit is the id of the commit, e.g. a git SHA.
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	// Ref is the branch or tag the commit was pushed to, e.g. "refs/heads/master".
+	Ref string `json:"ref,omitempty" yaml:"ref,omitempty"`
+	// SourceURI is the repository the commit was pushed to.
+	SourceURI string `json:"sourceUri,omitempty" yaml:"sourceUri,omitempty"`
+	// Message is the commit message.
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Author is the name and email of the commit author.
+	Author string `json:"author,omitempty" yaml:"author,omitempty"`
 }
 
 // BuildStatus represents the status of a Build at a point in time.
@@ -39,6 +105,9 @@ const (
 	BuildRunning  BuildStatus = "running"
 	BuildComplete BuildStatus = "complete"
 	BuildFailed   BuildStatus = "failed"
+	// BuildCancelled means the build was stopped at a client's request. Its pod is
+	// deleted by the build controller.
+	BuildCancelled BuildStatus = "cancelled"
 )
 
 // BuildList is a collection of Builds.
@@ -0,0 +1,75 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/google/gofuzz"
+)
+
+// buildFuzzer leaves the kind and version of objects blank, as they are in memory.
+var buildFuzzer = fuzz.New().NilChance(.5).NumElements(1, 1).Funcs(
+	func(j *api.JSONBase, c fuzz.Continue) {
+		j.ID = c.RandString()
+		j.ResourceVersion = c.RandUint64() >> 8
+		j.SelfLink = c.RandString()
+		j.CreationTimestamp = c.RandString()
+		j.LastModified = c.RandString()
+		j.Namespace = c.RandString()
+	},
+)
+
+// Builds have the same representation in every version, so they must come back from v1beta1
+// unchanged, both converted and through the codec.
+func TestBuildRoundTrip(t *testing.T) {
+	for _, source := range []interface{}{&Build{}, &BuildList{}} {
+		name := reflect.TypeOf(source).Elem().Name()
+		for i := 0; i < 50; i++ {
+			buildFuzzer.Fuzz(source)
+
+			external, err := api.New("v1beta1", name)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			internal := reflect.New(reflect.TypeOf(source).Elem()).Interface()
+			if err := api.Convert(source, external); err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if err := api.Convert(external, internal); err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if !reflect.DeepEqual(source, internal) {
+				t.Errorf("%s: expected %#v, got %#v", name, source, internal)
+			}
+
+			data, err := api.Encode(source)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			decoded, err := api.Decode(data)
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", name, err)
+			}
+			if !reflect.DeepEqual(source, decoded) {
+				t.Errorf("%s: expected %#v, got %#v", name, source, decoded)
+			}
+		}
+	}
+}
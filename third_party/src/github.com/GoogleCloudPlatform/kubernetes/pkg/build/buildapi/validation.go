@@ -0,0 +1,60 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildapi
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+func init() {
+	api.AddValidator(&Build{}, func(obj interface{}) api.ValidationErrorList {
+		return ValidateBuild(obj.(*Build))
+	})
+}
+
+var knownBuildStatuses = util.NewStringSet(
+	string(BuildNew),
+	string(BuildPending),
+	string(BuildRunning),
+	string(BuildComplete),
+	string(BuildFailed),
+	string(BuildCancelled),
+)
+
+// ValidateBuild tests that the fields of a build are valid. The ID and status may
+// be empty, in which case they are set when the build is created.
+func ValidateBuild(build *Build) api.ValidationErrorList {
+	allErrs := api.ValidationErrorList{}
+	for k := range build.Labels {
+		if !util.IsLabelKey(k) {
+			allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "labels." + k, BadValue: k})
+		}
+	}
+	allErrs.Append(buildconfigapi.ValidateBuildConfig(&build.Config).Prefix("config")...)
+	if len(build.Status) != 0 && !knownBuildStatuses.Has(string(build.Status)) {
+		allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeNotSupported, ErrorField: "status", BadValue: build.Status})
+	}
+	if build.Timeout < 0 {
+		allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "timeout", BadValue: build.Timeout})
+	}
+	if build.Output != nil && len(build.Output.TagTemplate) != 0 && !IsValidTagTemplate(build.Output.TagTemplate) {
+		allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "output.tagTemplate", BadValue: build.Output.TagTemplate})
+	}
+	return allErrs
+}
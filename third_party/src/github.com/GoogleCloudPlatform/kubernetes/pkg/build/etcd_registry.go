@@ -20,7 +20,9 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // TODO: Need to add a reconciler loop that makes sure that things in pods are reflected into
@@ -44,6 +46,7 @@ func (registry *EtcdRegistry) helper() *tools.EtcdHelper {
 	return &tools.EtcdHelper{registry.etcdClient, api.Codec, api.ResourceVersioner}
 }
 
+// makeBuildKey returns the key of the build with id, qualified with its namespace.
 func makeBuildKey(id string) string {
 	return "/builds/" + id
 }
@@ -55,6 +58,17 @@ func (registry *EtcdRegistry) ListBuilds() (buildapi.BuildList, error) {
 	return list, err
 }
 
+// WatchBuilds begins watching for new, changed, or deleted Builds.
+func (registry *EtcdRegistry) WatchBuilds(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	if err := validateBuildFields(field); err != nil {
+		return nil, err
+	}
+	return registry.helper().WatchList("/builds", resourceVersion, func(obj interface{}) bool {
+		build := obj.(*buildapi.Build)
+		return label.Matches(labels.Set(build.Labels)) && field.Matches(buildFields(build))
+	})
+}
+
 // GetBuild gets a specific Build specified by its ID.
 func (registry *EtcdRegistry) GetBuild(buildID string) (*buildapi.Build, error) {
 	var build buildapi.Build
@@ -70,12 +84,38 @@ func (registry *EtcdRegistry) GetBuild(buildID string) (*buildapi.Build, error)
 
 // CreateBuild creates a new Build.
 func (registry *EtcdRegistry) CreateBuild(build buildapi.Build) error {
-	return registry.UpdateBuild(build)
+	err := registry.helper().CreateObj(makeBuildKey(api.QualifiedID(build.Namespace, build.ID)), build)
+	if tools.IsEtcdNodeExist(err) {
+		return apiserver.NewAlreadyExistsErr("build", build.ID)
+	}
+	return err
 }
 
 // UpdateBuild replaces an existing Build.
 func (registry *EtcdRegistry) UpdateBuild(build buildapi.Build) error {
-	return registry.helper().SetObj(makeBuildKey(build.ID), build)
+	return registry.helper().SetObj(makeBuildKey(api.QualifiedID(build.Namespace, build.ID)), build)
+}
+
+// UpdateBuildStatus changes the Build specified by its ID as update says, retrying if the
+// build changes before the change is stored.
+func (registry *EtcdRegistry) UpdateBuildStatus(buildID string, update BuildUpdateFunc) (*buildapi.Build, error) {
+	var updated *buildapi.Build
+	err := registry.helper().AtomicUpdate(makeBuildKey(buildID), &buildapi.Build{}, func(obj interface{}) (interface{}, error) {
+		build := obj.(*buildapi.Build)
+		// AtomicUpdate passes an object without a resource version if there is none.
+		if build.ResourceVersion == 0 {
+			return nil, apiserver.NewNotFoundErr("build", buildID)
+		}
+		if err := update(build); err != nil {
+			return nil, err
+		}
+		updated = build
+		return build, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
 }
 
 // DeleteBuild deletes a Build specified by its ID.
@@ -0,0 +1,109 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestEtcdCreateBuildAlreadyExisting(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	registry := MakeEtcdRegistry(fakeClient)
+	if err := registry.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "foo"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := registry.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "foo"}}); !apiserver.IsAlreadyExists(err) {
+		t.Errorf("expected an already exists error, got %v", err)
+	}
+}
+
+func TestEtcdUpdateBuildStatus(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	registry := MakeEtcdRegistry(fakeClient)
+	fakeClient.Set("/builds/foo", api.EncodeOrDie(buildapi.Build{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Config:   buildconfigapi.BuildConfig{Type: buildconfigapi.DockerBuildType, SourceURI: "git://source"},
+		Status:   buildapi.BuildNew,
+	}), 0)
+
+	build, err := registry.UpdateBuildStatus("foo", func(build *buildapi.Build) error {
+		build.Status = buildapi.BuildPending
+		build.Message = "The build will run in pod build-foo"
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if build.Status != buildapi.BuildPending {
+		t.Errorf("expected the updated build to be returned, got %#v", build)
+	}
+	stored, err := registry.GetBuild("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stored.Status != buildapi.BuildPending || stored.Message != "The build will run in pod build-foo" || stored.Config.SourceURI != "git://source" {
+		t.Errorf("expected only the status of the build to change, got %#v", stored)
+	}
+
+	fakeClient.ExpectNotFoundGet("/builds/bar")
+	if _, err := registry.UpdateBuildStatus("bar", func(*buildapi.Build) error { return nil }); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestEtcdWatchBuildTransitions(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	registry := MakeEtcdRegistry(fakeClient)
+	watching, err := registry.WatchBuilds(labels.Everything(), labels.Everything(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClient.WaitForWatchCompletion()
+
+	responses := []*etcd.Response{
+		{Action: "create", Node: &etcd.Node{Value: api.EncodeOrDie(buildapi.Build{JSONBase: api.JSONBase{ID: "foo"}, Status: buildapi.BuildNew})}},
+		{Action: "compareAndSwap", Node: &etcd.Node{Value: api.EncodeOrDie(buildapi.Build{JSONBase: api.JSONBase{ID: "foo"}, Status: buildapi.BuildFailed, Reason: buildapi.BuildReasonPodCreationFailed})}},
+	}
+	expected := []watch.EventType{watch.Added, watch.Modified}
+	for i, response := range responses {
+		fakeClient.WatchResponse <- response
+		select {
+		case event := <-watching.ResultChan():
+			build, ok := event.Object.(*buildapi.Build)
+			if !ok || event.Type != expected[i] || build.ID != "foo" {
+				t.Errorf("%d: unexpected event %#v", i, event)
+			}
+			if i == 1 && (build.Status != buildapi.BuildFailed || build.Reason != buildapi.BuildReasonPodCreationFailed) {
+				t.Errorf("expected the transition and its reason, got %#v", build)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("%d: expected an event", i)
+		}
+	}
+	watching.Stop()
+}
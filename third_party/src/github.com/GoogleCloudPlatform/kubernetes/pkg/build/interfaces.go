@@ -16,7 +16,11 @@ limitations under the License.
 
 package build
 
-import "github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
 
 // BuildRegistry is an interface for things that know how to store Builds.
 type BuildRegistry interface {
@@ -25,4 +29,13 @@ type BuildRegistry interface {
 	CreateBuild(build buildapi.Build) error
 	UpdateBuild(build buildapi.Build) error
 	DeleteBuild(buildID string) error
+	WatchBuilds(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
+	// UpdateBuildStatus changes the build with buildID as update says, atomically with
+	// respect to any other update of it. update is called again if the build changes
+	// meanwhile.
+	UpdateBuildStatus(buildID string, update BuildUpdateFunc) (*buildapi.Build, error)
 }
+
+// BuildUpdateFunc changes the status of the current state of a build, or returns an error
+// to leave the build as it is.
+type BuildUpdateFunc func(build *buildapi.Build) error
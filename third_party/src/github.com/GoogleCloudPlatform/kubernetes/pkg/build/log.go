@@ -0,0 +1,126 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+)
+
+// LogHandler serves requests of the form:
+// ${prefix}/builds/${id}/log[?follow=true]
+// by proxying the log of the build's container from the kubelet on the host of the build's
+// pod. All other requests are passed to a delegate handler.
+type LogHandler struct {
+	prefix   string
+	delegate http.Handler
+	builds   BuildRegistry
+	pods     registry.PodRegistry
+}
+
+// NewLogHandler creates a LogHandler for the API rooted at prefix.
+func NewLogHandler(prefix string, delegate http.Handler, builds BuildRegistry, pods registry.PodRegistry) *LogHandler {
+	return &LogHandler{
+		prefix:   strings.TrimRight(prefix, "/") + "/",
+		delegate: delegate,
+		builds:   builds,
+		pods:     pods,
+	}
+}
+
+// ServeHTTP implements the standard net/http interface.
+func (h *LogHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, h.prefix) {
+		h.delegate.ServeHTTP(w, req)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, h.prefix), "/"), "/")
+	if len(parts) != 3 || parts[0] != "builds" || parts[2] != "log" || req.Method != "GET" {
+		h.delegate.ServeHTTP(w, req)
+		return
+	}
+	id := parts[1]
+
+	build, err := h.builds.GetBuild(id)
+	if err != nil {
+		if apiserver.IsNotFound(err) || tools.IsEtcdNotFound(err) {
+			writeStatus(w, http.StatusNotFound, fmt.Sprintf("build %q not found", id))
+			return
+		}
+		writeStatus(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var pod *api.Pod
+	if len(build.PodID) > 0 {
+		pod, err = h.pods.GetPod(build.PodID)
+		if err != nil && !apiserver.IsNotFound(err) && !tools.IsEtcdNotFound(err) {
+			writeStatus(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	if pod == nil || len(pod.CurrentState.Host) == 0 || len(pod.DesiredState.Manifest.Containers) == 0 {
+		switch build.Status {
+		case buildapi.BuildComplete, buildapi.BuildFailed, buildapi.BuildCancelled:
+			writeStatus(w, http.StatusGone, fmt.Sprintf("build %q is %s and its pod %q no longer exists", id, build.Status, build.PodID))
+		default:
+			writeStatus(w, http.StatusNotFound, fmt.Sprintf("build %q is %s and has no running pod yet", id, build.Status))
+		}
+		return
+	}
+
+	host := pod.CurrentState.Host
+	if _, port, _ := net.SplitHostPort(host); port == "" {
+		// TODO: Retrieve port info from a common object
+		host += ":10250"
+	}
+	follow := req.URL.Query().Get("follow") == "true"
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: host})
+	if follow {
+		proxy.FlushInterval = 100 * time.Millisecond
+	}
+	// Reuse the incoming request, so the kubelet request ends when the client goes away.
+	req.URL.Path = fmt.Sprintf("/containerLogs/%s/%s", pod.ID, pod.DesiredState.Manifest.Containers[0].Name)
+	req.URL.RawQuery = ""
+	if follow {
+		req.URL.RawQuery = "follow=true"
+	}
+	proxy.ServeHTTP(w, req)
+}
+
+// writeStatus renders a failure Status with the given code and message.
+func writeStatus(w http.ResponseWriter, code int, message string) {
+	data, err := api.Encode(&api.Status{Status: api.StatusFailure, Code: code, Message: message})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry"
+)
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestLogHandler(t *testing.T) {
+	kubelet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "%s follow=%s", req.URL.Path, req.URL.Query().Get("follow"))
+	}))
+	defer kubelet.Close()
+	host := strings.TrimPrefix(kubelet.URL, "http://")
+
+	builds := MakeMemoryRegistry()
+	builds.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "running"}, Status: buildapi.BuildRunning, PodID: "build-running"})
+	builds.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "done"}, Status: buildapi.BuildComplete, PodID: "build-done"})
+	builds.CreateBuild(buildapi.Build{JSONBase: api.JSONBase{ID: "new"}, Status: buildapi.BuildNew})
+	pods := registry.MakeMemoryRegistry()
+	pods.CreatePod(host, api.Pod{
+		JSONBase: api.JSONBase{ID: "build-running"},
+		DesiredState: api.PodState{
+			Host:     host,
+			Manifest: api.ContainerManifest{Containers: []api.Container{{Name: "sti-build"}}},
+		},
+		CurrentState: api.PodState{Host: host},
+	})
+
+	delegated := false
+	delegate := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		delegated = true
+	})
+	server := httptest.NewServer(NewLogHandler("/api/v1beta1", delegate, builds, pods))
+	defer server.Close()
+
+	code, body := get(t, server.URL+"/api/v1beta1/builds/running/log")
+	if code != http.StatusOK || body != "/containerLogs/build-running/sti-build follow=" {
+		t.Errorf("unexpected response %d: %s", code, body)
+	}
+	code, body = get(t, server.URL+"/api/v1beta1/builds/running/log?follow=true")
+	if code != http.StatusOK || body != "/containerLogs/build-running/sti-build follow=true" {
+		t.Errorf("unexpected response %d: %s", code, body)
+	}
+
+	table := map[string]int{
+		"done":    http.StatusGone,
+		"new":     http.StatusNotFound,
+		"missing": http.StatusNotFound,
+	}
+	for id, expected := range table {
+		code, body := get(t, server.URL+"/api/v1beta1/builds/"+id+"/log")
+		if code != expected {
+			t.Errorf("%s: expected %d, got %d: %s", id, expected, code, body)
+		}
+		if !strings.Contains(body, id) {
+			t.Errorf("%s: expected the build to be named in %s", id, body)
+		}
+	}
+
+	if get(t, server.URL+"/api/v1beta1/builds/running"); !delegated {
+		t.Errorf("expected other paths to be delegated")
+	}
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package build
 
 import (
+	"errors"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 // An implementation of BuildRegistry that is backed by memory
@@ -51,7 +56,7 @@ func (registry *MemoryRegistry) GetBuild(buildID string) (*buildapi.Build, error
 }
 
 func (registry *MemoryRegistry) CreateBuild(build buildapi.Build) error {
-	registry.buildData[build.ID] = build
+	registry.buildData[api.QualifiedID(build.Namespace, build.ID)] = build
 	return nil
 }
 
@@ -64,9 +69,26 @@ func (registry *MemoryRegistry) DeleteBuild(buildID string) error {
 }
 
 func (registry *MemoryRegistry) UpdateBuild(build buildapi.Build) error {
-	if _, ok := registry.buildData[build.ID]; !ok {
+	buildID := api.QualifiedID(build.Namespace, build.ID)
+	if _, ok := registry.buildData[buildID]; !ok {
 		return apiserver.NewNotFoundErr("build", build.ID)
 	}
-	registry.buildData[build.ID] = build
+	registry.buildData[buildID] = build
 	return nil
 }
+
+func (registry *MemoryRegistry) UpdateBuildStatus(buildID string, update BuildUpdateFunc) (*buildapi.Build, error) {
+	build, ok := registry.buildData[buildID]
+	if !ok {
+		return nil, apiserver.NewNotFoundErr("build", buildID)
+	}
+	if err := update(&build); err != nil {
+		return nil, err
+	}
+	registry.buildData[buildID] = build
+	return &build, nil
+}
+
+func (registry *MemoryRegistry) WatchBuilds(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return nil, errors.New("unimplemented")
+}
@@ -0,0 +1,109 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+)
+
+// defaultRef is the branch built when a BuildConfig doesn't name one.
+const defaultRef = "master"
+
+// GitHub handles the push and ping events sent by GitHub webhooks.
+type GitHub struct{}
+
+type gitHubPushEvent struct {
+	Ref        string `json:"ref"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	HeadCommit *struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		Author  struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"head_commit"`
+	Repository struct {
+		URL      string `json:"url"`
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+// Extract implements Plugin. Push events to the branch named by the config's SourceRef
+// yield a Build of the pushed commit; ping events and pushes to other branches yield none.
+func (GitHub) Extract(config *buildconfigapi.BuildConfig, req *http.Request) (*buildapi.Build, string, error) {
+	switch event := req.Header.Get("X-GitHub-Event"); event {
+	case "ping":
+		return nil, "ping received", nil
+	case "push":
+	case "":
+		return nil, "", fmt.Errorf("missing X-GitHub-Event header")
+	default:
+		return nil, "", fmt.Errorf("unsupported GitHub event %q", event)
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	var push gitHubPushEvent
+	if err := json.Unmarshal(body, &push); err != nil {
+		return nil, "", fmt.Errorf("unable to parse push event: %v", err)
+	}
+	if len(push.Ref) == 0 {
+		return nil, "", fmt.Errorf("push event has no ref")
+	}
+
+	ref := config.SourceRef
+	if len(ref) == 0 {
+		ref = defaultRef
+	}
+	if branch := strings.TrimPrefix(push.Ref, "refs/heads/"); branch != ref && push.Ref != ref {
+		return nil, fmt.Sprintf("push to %s does not match %s", push.Ref, ref), nil
+	}
+	if push.Deleted || push.HeadCommit == nil {
+		return nil, fmt.Sprintf("push to %s has no commit", push.Ref), nil
+	}
+
+	revision := &buildapi.SourceRevision{
+		Commit:    push.HeadCommit.ID,
+		Ref:       push.Ref,
+		SourceURI: push.Repository.CloneURL,
+		Message:   push.HeadCommit.Message,
+		Author:    fmt.Sprintf("%s <%s>", push.HeadCommit.Author.Name, push.HeadCommit.Author.Email),
+	}
+	if len(revision.SourceURI) == 0 {
+		revision.SourceURI = push.Repository.URL
+	}
+
+	build := &buildapi.Build{
+		Config:   *config,
+		Revision: revision,
+	}
+	// Build exactly the pushed commit, even if the branch moves before the build starts.
+	build.Config.SourceRef = revision.Commit
+	build.Config.Secret = ""
+	return build, "", nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook starts builds in response to notifications from source code hosts.
+package webhook
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+	"github.com/golang/glog"
+)
+
+// Plugin turns a webhook request for a BuildConfig into a Build. A nil Build with a nil
+// error means the request was valid but should not start a build; the reason is returned
+// as the message.
+type Plugin interface {
+	Extract(config *buildconfigapi.BuildConfig, req *http.Request) (build *buildapi.Build, message string, err error)
+}
+
+// Handler serves requests of the form:
+// ${prefix}/buildConfigs/${id}/webhooks/${secret}/${plugin}
+// and passes all other requests to a delegate handler.
+type Handler struct {
+	prefix   string
+	delegate http.Handler
+	configs  buildconfig.BuildConfigRegistry
+	builds   apiserver.Creater
+	plugins  map[string]Plugin
+}
+
+// NewHandler creates a Handler for the API rooted at prefix. Builds are created through
+// 'builds', so they are subject to the same defaulting as builds created by clients.
+func NewHandler(prefix string, delegate http.Handler, configs buildconfig.BuildConfigRegistry, builds apiserver.Creater) *Handler {
+	return &Handler{
+		prefix:   strings.TrimRight(prefix, "/") + "/",
+		delegate: delegate,
+		configs:  configs,
+		builds:   builds,
+		plugins: map[string]Plugin{
+			"github": GitHub{},
+		},
+	}
+}
+
+// ServeHTTP implements the standard net/http interface.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !strings.HasPrefix(req.URL.Path, h.prefix) {
+		h.delegate.ServeHTTP(w, req)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, h.prefix), "/"), "/")
+	if len(parts) != 5 || parts[0] != "buildConfigs" || parts[2] != "webhooks" {
+		h.delegate.ServeHTTP(w, req)
+		return
+	}
+	id, secret, name := parts[1], parts[3], parts[4]
+
+	if req.Method != "POST" {
+		writeStatus(w, http.StatusMethodNotAllowed, api.StatusFailure, fmt.Sprintf("webhooks only accept POST, got %s", req.Method))
+		return
+	}
+	plugin, ok := h.plugins[name]
+	if !ok {
+		writeStatus(w, http.StatusNotFound, api.StatusFailure, fmt.Sprintf("unknown webhook type %q", name))
+		return
+	}
+	config, err := h.configs.GetBuildConfig(id)
+	if err != nil {
+		if apiserver.IsNotFound(err) {
+			writeStatus(w, http.StatusNotFound, api.StatusFailure, err.Error())
+			return
+		}
+		writeStatus(w, http.StatusInternalServerError, api.StatusFailure, err.Error())
+		return
+	}
+	// Don't reveal whether the config exists to callers without the secret, nor, through
+	// the time taken to compare them, how much of the secret they got right.
+	if len(config.Secret) == 0 || subtle.ConstantTimeCompare([]byte(secret), []byte(config.Secret)) != 1 {
+		glog.Infof("Rejected %s webhook for build config %s: secret does not match", name, id)
+		writeStatus(w, http.StatusNotFound, api.StatusFailure, fmt.Sprintf("buildconfig %q not found", id))
+		return
+	}
+
+	build, message, err := plugin.Extract(config, req)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, api.StatusFailure, err.Error())
+		return
+	}
+	if build == nil {
+		glog.Infof("Ignored %s webhook for build config %s: %s", name, id, message)
+		writeStatus(w, http.StatusOK, api.StatusSuccess, message)
+		return
+	}
+
+	out, err := h.builds.Create(apiserver.RequestContext(req), build)
+	if err != nil {
+		writeStatus(w, http.StatusInternalServerError, api.StatusFailure, err.Error())
+		return
+	}
+	result := apiserver.FinalResult(out)
+	if status, ok := result.(*api.Status); ok {
+		writeJSON(w, status.Code, status)
+		return
+	}
+	glog.Infof("Created build %s from %s webhook for build config %s", build.ID, name, id)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// writeStatus renders a Status with the given code, status and message.
+func writeStatus(w http.ResponseWriter, code int, status, message string) {
+	writeJSON(w, code, &api.Status{Status: status, Code: code, Message: message})
+}
+
+// writeJSON renders an API object as JSON to the response.
+func writeJSON(w http.ResponseWriter, code int, obj interface{}) {
+	data, err := api.Encode(obj)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+)
+
+// gitHubPushPayload is a trimmed push event as delivered by GitHub.
+const gitHubPushPayload = `{
+  "ref": "refs/heads/master",
+  "before": "9049f1265b7d61be4a8904a9a27120d2064dab3b",
+  "after": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+  "created": false,
+  "deleted": false,
+  "forced": false,
+  "compare": "https://github.com/openshift/ruby-hello-world/compare/9049f1265b7d...0d1a26e67d8f",
+  "commits": [
+    {
+      "id": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+      "distinct": true,
+      "message": "Update README.md",
+      "timestamp": "2014-08-11T13:40:32-04:00",
+      "url": "https://github.com/openshift/ruby-hello-world/commit/0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+      "author": {"name": "Jane Doe", "email": "jane@example.com", "username": "jdoe"},
+      "committer": {"name": "Jane Doe", "email": "jane@example.com", "username": "jdoe"},
+      "added": [],
+      "removed": [],
+      "modified": ["README.md"]
+    }
+  ],
+  "head_commit": {
+    "id": "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+    "distinct": true,
+    "message": "Update README.md",
+    "timestamp": "2014-08-11T13:40:32-04:00",
+    "url": "https://github.com/openshift/ruby-hello-world/commit/0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+    "author": {"name": "Jane Doe", "email": "jane@example.com", "username": "jdoe"},
+    "committer": {"name": "Jane Doe", "email": "jane@example.com", "username": "jdoe"},
+    "added": [],
+    "removed": [],
+    "modified": ["README.md"]
+  },
+  "repository": {
+    "id": 20040591,
+    "name": "ruby-hello-world",
+    "url": "https://github.com/openshift/ruby-hello-world",
+    "clone_url": "https://github.com/openshift/ruby-hello-world.git",
+    "default_branch": "master",
+    "master_branch": "master"
+  },
+  "pusher": {"name": "jdoe", "email": "jane@example.com"}
+}`
+
+// gitHubBranchPushPayload is a push to a branch other than master.
+const gitHubBranchPushPayload = `{
+  "ref": "refs/heads/feature",
+  "after": "5f0a5c4ba35e3d39d4c19d2b4ab21208a5c6e323",
+  "deleted": false,
+  "head_commit": {
+    "id": "5f0a5c4ba35e3d39d4c19d2b4ab21208a5c6e323",
+    "message": "Work in progress",
+    "author": {"name": "Jane Doe", "email": "jane@example.com"}
+  },
+  "repository": {
+    "url": "https://github.com/openshift/ruby-hello-world",
+    "clone_url": "https://github.com/openshift/ruby-hello-world.git"
+  }
+}`
+
+// gitHubPingPayload is sent by GitHub when a webhook is first configured.
+const gitHubPingPayload = `{
+  "zen": "Keep it logically awesome.",
+  "hook_id": 2713895,
+  "hook": {
+    "url": "https://api.github.com/repos/openshift/ruby-hello-world/hooks/2713895",
+    "name": "web",
+    "events": ["push"],
+    "active": true,
+    "config": {"url": "https://master.example.com/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github", "content_type": "json"}
+  }
+}`
+
+type fixture struct {
+	server   *httptest.Server
+	builds   build.BuildRegistry
+	fallback bool
+}
+
+func newFixture(t *testing.T) *fixture {
+	configs := buildconfig.MakeMemoryRegistry()
+	configs.CreateBuildConfig(buildconfigapi.BuildConfig{
+		JSONBase:  api.JSONBase{ID: "hello"},
+		Type:      "sti",
+		SourceURI: "https://github.com/openshift/ruby-hello-world.git",
+		ImageTag:  "openshift/hello",
+		Secret:    "s3cr3t",
+	})
+	configs.CreateBuildConfig(buildconfigapi.BuildConfig{
+		JSONBase: api.JSONBase{ID: "nosecret"},
+	})
+	builds := build.MakeMemoryRegistry()
+	f := &fixture{builds: builds}
+	delegate := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		f.fallback = true
+	})
+	f.server = httptest.NewServer(NewHandler("/api/v1beta1", delegate, configs, build.NewBuildRegistryStorage(builds).(apiserver.Creater)))
+	return f
+}
+
+func (f *fixture) post(t *testing.T, path, event, payload string) (int, string) {
+	req, err := http.NewRequest("POST", f.server.URL+path, strings.NewReader(payload))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(event) > 0 {
+		req.Header.Set("X-GitHub-Event", event)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func (f *fixture) listBuilds(t *testing.T) []buildapi.Build {
+	list, err := f.builds.ListBuilds()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return list.Items
+}
+
+func TestGitHubPushCreatesBuild(t *testing.T) {
+	f := newFixture(t)
+	defer f.server.Close()
+
+	code, body := f.post(t, "/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github", "push", gitHubPushPayload)
+	if code != http.StatusOK {
+		t.Fatalf("unexpected status %d: %s", code, body)
+	}
+	builds := f.listBuilds(t)
+	if len(builds) != 1 {
+		t.Fatalf("expected 1 build, got %#v", builds)
+	}
+	b := builds[0]
+	if b.Status != buildapi.BuildNew || len(b.ID) == 0 {
+		t.Errorf("expected a new build with an id, got %#v", b)
+	}
+	if b.Config.Type != "sti" || b.Config.ImageTag != "openshift/hello" {
+		t.Errorf("expected the build config to be copied, got %#v", b.Config)
+	}
+	if b.Config.SourceRef != "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c" {
+		t.Errorf("expected the pushed commit to be built, got %s", b.Config.SourceRef)
+	}
+	if len(b.Config.Secret) != 0 {
+		t.Errorf("expected the secret not to be copied into the build")
+	}
+	expected := buildapi.SourceRevision{
+		Commit:    "0d1a26e67d8f5eaf1f6ba5c57fc3c7d91ac0fd1c",
+		Ref:       "refs/heads/master",
+		SourceURI: "https://github.com/openshift/ruby-hello-world.git",
+		Message:   "Update README.md",
+		Author:    "Jane Doe <jane@example.com>",
+	}
+	if b.Revision == nil || *b.Revision != expected {
+		t.Errorf("expected revision %#v, got %#v", expected, b.Revision)
+	}
+}
+
+func TestGitHubPingAndOtherBranches(t *testing.T) {
+	f := newFixture(t)
+	defer f.server.Close()
+
+	if code, body := f.post(t, "/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github", "ping", gitHubPingPayload); code != http.StatusOK {
+		t.Errorf("unexpected status for ping %d: %s", code, body)
+	}
+	code, body := f.post(t, "/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github", "push", gitHubBranchPushPayload)
+	if code != http.StatusOK {
+		t.Errorf("unexpected status for other branch %d: %s", code, body)
+	}
+	if !strings.Contains(body, "refs/heads/feature") {
+		t.Errorf("expected the ignored ref in the response, got %s", body)
+	}
+	if builds := f.listBuilds(t); len(builds) != 0 {
+		t.Errorf("expected no builds, got %#v", builds)
+	}
+}
+
+func TestGitHubWebhookErrors(t *testing.T) {
+	f := newFixture(t)
+	defer f.server.Close()
+
+	table := []struct {
+		path    string
+		event   string
+		payload string
+		code    int
+	}{
+		{"/api/v1beta1/buildConfigs/hello/webhooks/wrong/github", "push", gitHubPushPayload, http.StatusNotFound},
+		{"/api/v1beta1/buildConfigs/nosecret/webhooks//github", "push", gitHubPushPayload, http.StatusNotFound},
+		{"/api/v1beta1/buildConfigs/missing/webhooks/s3cr3t/github", "push", gitHubPushPayload, http.StatusNotFound},
+		{"/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/gitlab", "push", gitHubPushPayload, http.StatusNotFound},
+		{"/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github", "", gitHubPushPayload, http.StatusBadRequest},
+		{"/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github", "issues", "{}", http.StatusBadRequest},
+		{"/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github", "push", "{not json", http.StatusBadRequest},
+	}
+	for _, item := range table {
+		if code, body := f.post(t, item.path, item.event, item.payload); code != item.code {
+			t.Errorf("%s (%s): expected %d, got %d: %s", item.path, item.event, item.code, code, body)
+		}
+	}
+	if builds := f.listBuilds(t); len(builds) != 0 {
+		t.Errorf("expected no builds, got %#v", builds)
+	}
+
+	resp, err := http.Get(f.server.URL + "/api/v1beta1/buildConfigs/hello/webhooks/s3cr3t/github")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET, got %d", resp.StatusCode)
+	}
+}
+
+func TestOtherPathsAreDelegated(t *testing.T) {
+	f := newFixture(t)
+	defer f.server.Close()
+
+	for _, path := range []string{"/api/v1beta1/buildConfigs/hello", "/api/v1beta1/builds", "/healthz"} {
+		f.fallback = false
+		f.post(t, path, "", "{}")
+		if !f.fallback {
+			t.Errorf("expected %s to be delegated", path)
+		}
+	}
+}
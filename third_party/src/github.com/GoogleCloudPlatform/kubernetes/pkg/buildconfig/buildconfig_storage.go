@@ -43,7 +43,7 @@ func (storage *BuildConfigRegistryStorage) New() interface{} {
 }
 
 // List obtains a list of BuildConfigs that match selector.
-func (storage *BuildConfigRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (storage *BuildConfigRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	result := buildconfigapi.BuildConfigList{}
 	buildConfigs, err := storage.registry.ListBuildConfigs()
 	if err == nil {
@@ -55,8 +55,8 @@ func (storage *BuildConfigRegistryStorage) List(selector labels.Selector) (inter
 }
 
 // Get obtains the BuildConfig specified by its id.
-func (storage *BuildConfigRegistryStorage) Get(id string) (interface{}, error) {
-	buildConfig, err := storage.registry.GetBuildConfig(id)
+func (storage *BuildConfigRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	buildConfig, err := storage.registry.GetBuildConfig(api.QualifiedID(ctx.Namespace, id))
 	if err != nil {
 		return nil, err
 	}
@@ -64,9 +64,9 @@ func (storage *BuildConfigRegistryStorage) Get(id string) (interface{}, error) {
 }
 
 // Delete asynchronously deletes the BuildConfig specified by its id.
-func (storage *BuildConfigRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (storage *BuildConfigRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	return apiserver.MakeAsync(func() (interface{}, error) {
-		return api.Status{Status: api.StatusSuccess}, storage.registry.DeleteBuildConfig(id)
+		return api.Status{Status: api.StatusSuccess}, storage.registry.DeleteBuildConfig(api.QualifiedID(ctx.Namespace, id))
 	}), nil
 }
 
@@ -77,20 +77,28 @@ func (storage *BuildConfigRegistryStorage) Extract(body []byte) (interface{}, er
 	return result, err
 }
 
-// Create registers a given new BuildConfig instance to storage.registry.
-func (storage *BuildConfigRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+// Default implements apiserver.Defaulter, filling in the ID and creation time of a
+// BuildConfig if they are not set.
+func (storage *BuildConfigRegistryStorage) Default(ctx api.Context, obj interface{}) {
 	buildConfig, ok := obj.(*buildconfigapi.BuildConfig)
 	if !ok {
-		return nil, fmt.Errorf("not a build: %#v", obj)
+		return
 	}
 	if len(buildConfig.ID) == 0 {
 		buildConfig.ID = uuid.NewUUID().String()
 	}
-
 	if buildConfig.CreationTimestamp == "" {
-		buildConfig.CreationTimestamp = time.Now().Format(time.UnixDate)
+		buildConfig.CreationTimestamp = time.Now().UTC().Format(time.RFC3339)
 	}
+}
 
+// Create registers a given new BuildConfig instance to storage.registry.
+func (storage *BuildConfigRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	buildConfig, ok := obj.(*buildconfigapi.BuildConfig)
+	if !ok {
+		return nil, fmt.Errorf("not a build: %#v", obj)
+	}
+	storage.Default(ctx, buildConfig)
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		err := storage.registry.CreateBuildConfig(*buildConfig)
 		if err != nil {
@@ -101,7 +109,7 @@ func (storage *BuildConfigRegistryStorage) Create(obj interface{}) (<-chan inter
 }
 
 // Update replaces a given BuildConfig instance with an existing instance in storage.registry.
-func (storage *BuildConfigRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (storage *BuildConfigRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	build, ok := obj.(*buildconfigapi.BuildConfig)
 	if !ok {
 		return nil, fmt.Errorf("not a build: %#v", obj)
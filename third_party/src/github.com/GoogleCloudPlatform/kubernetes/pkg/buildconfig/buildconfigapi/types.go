@@ -9,10 +9,20 @@ type BuildConfig struct {
 	ImageTag     string    `json:"imageTag,omitempty" yaml:"imageTag,omitempty"`
 	BuilderImage string    `json:"builderImage,omitempty" yaml:"builderImage,omitempty"`
 	SourceRef    string    `json:"sourceRef,omitempty" yaml:"sourceRef,omitempty"`
+	// Secret must appear in the URL of webhooks that trigger builds from this config.
+	// Webhooks are disabled while it is empty.
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
 }
 
 type BuildType string
 
+const (
+	// DockerBuildType builds an image from the Dockerfile at the root of the source.
+	DockerBuildType BuildType = "docker"
+	// STIBuildType builds an image by running the source through BuilderImage.
+	STIBuildType BuildType = "sti"
+)
+
 // BuildList is a collection of Builds.
 type BuildConfigList struct {
 	api.JSONBase `json:",inline" yaml:",inline"`
@@ -0,0 +1,50 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package buildconfigapi
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func init() {
+	api.AddValidator(&BuildConfig{}, func(obj interface{}) api.ValidationErrorList {
+		return ValidateBuildConfig(obj.(*BuildConfig))
+	})
+}
+
+// ValidateBuildConfig tests that the fields required to run a build of config are set.
+func ValidateBuildConfig(config *BuildConfig) api.ValidationErrorList {
+	allErrs := api.ValidationErrorList{}
+	switch config.Type {
+	case DockerBuildType:
+	case STIBuildType:
+		if len(config.BuilderImage) == 0 {
+			allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "builderImage", BadValue: config.BuilderImage})
+		}
+	case "":
+		allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "type", BadValue: config.Type})
+	default:
+		allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeNotSupported, ErrorField: "type", BadValue: config.Type})
+	}
+	if len(config.SourceURI) == 0 {
+		allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "sourceUri", BadValue: config.SourceURI})
+	}
+	if len(config.ImageTag) == 0 {
+		allErrs.Append(api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "imageTag", BadValue: config.ImageTag})
+	}
+	return allErrs
+}
@@ -44,6 +44,8 @@ func (registry *EtcdRegistry) helper() *tools.EtcdHelper {
 	return &tools.EtcdHelper{registry.etcdClient, api.Codec, api.ResourceVersioner}
 }
 
+// makeBuildConfigKey returns the key of the build config with id, qualified with its
+// namespace.
 func makeBuildConfigKey(id string) string {
 	return "/build-configs/" + id
 }
@@ -75,7 +77,7 @@ func (registry *EtcdRegistry) CreateBuildConfig(buildConfig buildconfigapi.Build
 
 // UpdateBuildConfig replaces an existing BuildConfig.
 func (registry *EtcdRegistry) UpdateBuildConfig(buildConfig buildconfigapi.BuildConfig) error {
-	return registry.helper().SetObj(makeBuildConfigKey(buildConfig.ID), buildConfig)
+	return registry.helper().SetObj(makeBuildConfigKey(api.QualifiedID(buildConfig.Namespace, buildConfig.ID)), buildConfig)
 }
 
 // DeleteBuildConfig deletes a BuildConfig specified by its ID.
@@ -17,6 +17,7 @@ limitations under the License.
 package buildconfig
 
 import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
 )
@@ -51,7 +52,7 @@ func (registry *MemoryRegistry) GetBuildConfig(buildConfigID string) (*buildconf
 }
 
 func (registry *MemoryRegistry) CreateBuildConfig(buildConfig buildconfigapi.BuildConfig) error {
-	registry.buildConfigData[buildConfig.ID] = buildConfig
+	registry.buildConfigData[api.QualifiedID(buildConfig.Namespace, buildConfig.ID)] = buildConfig
 	return nil
 }
 
@@ -64,9 +65,10 @@ func (registry *MemoryRegistry) DeleteBuildConfig(buildConfigID string) error {
 }
 
 func (registry *MemoryRegistry) UpdateBuildConfig(buildConfig buildconfigapi.BuildConfig) error {
-	if _, ok := registry.buildConfigData[buildConfig.ID]; !ok {
+	buildConfigID := api.QualifiedID(buildConfig.Namespace, buildConfig.ID)
+	if _, ok := registry.buildConfigData[buildConfigID]; !ok {
 		return apiserver.NewNotFoundErr("buildconfig", buildConfig.ID)
 	}
-	registry.buildConfigData[buildConfig.ID] = buildConfig
+	registry.buildConfigData[buildConfigID] = buildConfig
 	return nil
 }
@@ -16,7 +16,8 @@ limitations under the License.
 
 // Package cache is a client-side caching mechanism. It is useful for
 // reducing the number of server calls you'd otherwise need to make.
-// Reflector watches a server and updates a Store. Two stores are provided;
+// Reflector lists the objects of a ListerWatcher, such as a ListWatch of a
+// resource, then watches their changes and updates a Store. Two stores are provided;
 // one that simply caches objects (for example, to allow a scheduler to
 // list currently available minions), and one that additionally acts as
 // a FIFO queue (for example, to allow a scheduler to process incoming
@@ -78,6 +78,20 @@ func (f *FIFO) Get(ID string) (item interface{}, exists bool) {
 	return item, exists
 }
 
+// Replace sets the items to items, and puts them all in the queue in place of the items
+// that were waiting there.
+func (f *FIFO) Replace(items map[string]interface{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.items = make(map[string]interface{}, len(items))
+	f.queue = make([]string, 0, len(items))
+	for ID, item := range items {
+		f.items[ID] = item
+		f.queue = append(f.queue, ID)
+	}
+	f.cond.Broadcast()
+}
+
 // Pop waits until an item is ready and returns it. If multiple items are
 // ready, they are returned in the order in which they were added/updated.
 // The item is removed from the queue (and the store) before it is returned,
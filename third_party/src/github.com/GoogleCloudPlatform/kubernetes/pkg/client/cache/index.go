@@ -0,0 +1,62 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// ListSelected returns the items of store whose labels match selector. Items without labels
+// only match a selector that selects everything.
+func ListSelected(store Store, selector labels.Selector) []interface{} {
+	selected := []interface{}{}
+	for _, item := range store.List() {
+		if selector.Matches(labels.Set(labelsOf(item))) {
+			selected = append(selected, item)
+		}
+	}
+	return selected
+}
+
+// IndexByLabel returns the items of store grouped by the value of their label key. Items
+// without the label are left out.
+func IndexByLabel(store Store, key string) map[string][]interface{} {
+	index := map[string][]interface{}{}
+	for _, item := range store.List() {
+		if value, ok := labelsOf(item)[key]; ok {
+			index[value] = append(index[value], item)
+		}
+	}
+	return index
+}
+
+// labelsOf returns the Labels field of obj, a pointer to a struct such as a pod, or nil if it
+// has none.
+func labelsOf(obj interface{}) map[string]string {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	field := v.Elem().FieldByName("Labels")
+	if !field.IsValid() {
+		return nil
+	}
+	labels, _ := field.Interface().(map[string]string)
+	return labels
+}
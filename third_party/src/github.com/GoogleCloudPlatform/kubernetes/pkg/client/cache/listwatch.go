@@ -0,0 +1,73 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// ListerWatcher is the source of the objects a Reflector keeps in its store.
+type ListerWatcher interface {
+	// List returns a list object, such as *api.PodList.
+	List() (interface{}, error)
+	// Watch watches the changes made at or after resourceVersion, or the current objects
+	// and then every change if resourceVersion is 0.
+	Watch(resourceVersion uint64) (watch.Interface, error)
+}
+
+// ListWatch lists and watches the objects of a resource that match a label selector.
+type ListWatch struct {
+	Client   *client.Client
+	Resource string
+	// Selector is nil for every object of the resource.
+	Selector labels.Selector
+}
+
+// List implements ListerWatcher.
+func (lw *ListWatch) List() (interface{}, error) {
+	return lw.Client.Get().
+		Path(lw.Resource).
+		SelectorParam("labels", lw.selector()).
+		Do().
+		Get()
+}
+
+// Watch implements ListerWatcher.
+func (lw *ListWatch) Watch(resourceVersion uint64) (watch.Interface, error) {
+	return lw.Client.Get().
+		Path("watch").
+		Path(lw.Resource).
+		UintParam("resourceVersion", resourceVersion).
+		SelectorParam("labels", lw.selector()).
+		Watch()
+}
+
+func (lw *ListWatch) selector() labels.Selector {
+	if lw.Selector == nil {
+		return labels.Everything()
+	}
+	return lw.Selector
+}
+
+func (lw *ListWatch) String() string {
+	if lw.Selector == nil || lw.Selector.Empty() {
+		return lw.Resource
+	}
+	return lw.Resource + " matching " + lw.Selector.String()
+}
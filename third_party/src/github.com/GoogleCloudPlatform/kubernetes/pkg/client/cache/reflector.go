@@ -17,11 +17,11 @@ limitations under the License.
 package cache
 
 import (
+	"fmt"
 	"reflect"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 	"github.com/golang/glog"
@@ -37,51 +37,132 @@ type Store interface {
 	Delete(ID string, obj interface{})
 	List() []interface{}
 	Get(ID string) (item interface{}, exists bool)
+	// Replace sets the contents of the store to items, by ID, dropping any other item.
+	Replace(items map[string]interface{})
 }
 
 // Reflector watches a specified resource and causes all changes to be reflected in the given store.
 type Reflector struct {
-	kubeClient   *client.Client
-	resource     string
-	expectedType reflect.Type
-	store        Store
+	listerWatcher ListerWatcher
+	expectedType  reflect.Type
+	store         Store
+	// period is how long Run waits before listing or watching again after a watch ends.
+	period time.Duration
+
+	// resourceVersion is the version of the last change seen, from which the next watch
+	// starts. listed is false until the store is filled from a list, and again after a watch
+	// fails, so that the changes that watch missed are listed. Both are only used by the
+	// goroutine of Run.
+	resourceVersion uint64
+	listed          bool
 }
 
 // NewReflector makes a new Reflector object which will keep the given store up to
-// date with the server's contents for the given resource. Reflector promises to
-// only put things in the store that have the type of expectedType.
-// TODO: define a query so you only locally cache a subset of items.
-func NewReflector(resource string, kubeClient *client.Client, expectedType interface{}, store Store) *Reflector {
+// date with the objects lw lists and watches. Reflector promises to only put things
+// in the store that have the type of expectedType.
+func NewReflector(lw ListerWatcher, expectedType interface{}, store Store) *Reflector {
 	gc := &Reflector{
-		resource:     resource,
-		kubeClient:   kubeClient,
-		store:        store,
-		expectedType: reflect.TypeOf(expectedType),
+		listerWatcher: lw,
+		store:         store,
+		expectedType:  reflect.TypeOf(expectedType),
+		period:        5 * time.Second,
 	}
 	return gc
 }
 
+// Run starts a goroutine that fills the store with a list of the objects, then keeps it
+// current by watching their changes from the version of that list. A watch that ends is
+// resumed from the last change seen; one that fails, or can't be opened, leads to a new list
+// first, as the server may no longer have the changes since that version.
 func (gc *Reflector) Run() {
 	go util.Forever(func() {
-		w, err := gc.startWatch()
+		if err := gc.listAndWatch(); err != nil {
+			glog.Errorf("failed to list and watch %v: %v", gc.listerWatcher, err)
+		}
+	}, gc.period)
+}
+
+// listAndWatch lists the objects unless the store is current, then applies the changes of a
+// single watch to the store until the watch ends.
+func (gc *Reflector) listAndWatch() error {
+	if !gc.listed {
+		if err := gc.list(); err != nil {
+			return err
+		}
+		gc.listed = true
+	}
+	w, err := gc.startWatch()
+	if err != nil {
+		gc.listed = false
+		return err
+	}
+	if err := gc.watchHandler(w); err != nil {
+		gc.listed = false
+		return err
+	}
+	return nil
+}
+
+// list replaces the contents of the store by the objects listed, and sets the version to
+// watch from to the latest version among them.
+func (gc *Reflector) list() error {
+	list, err := gc.listerWatcher.List()
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected a list, got %#v", list)
+	}
+	items := v.Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice {
+		return fmt.Errorf("expected a list, got %#v", list)
+	}
+	// Lists don't have a version of their own, but the version of each item is that of its
+	// last change, so the latest one is the version of the list.
+	resourceVersion := uint64(0)
+	contents := map[string]interface{}{}
+	for i := 0; i < items.Len(); i++ {
+		obj := items.Index(i).Addr().Interface()
+		if e, a := gc.expectedType, reflect.TypeOf(obj); e != a {
+			return fmt.Errorf("expected type %v, but list item had type %v", e, a)
+		}
+		jsonBase, err := api.FindJSONBase(obj)
 		if err != nil {
-			glog.Errorf("failed to watch %v: %v", gc.resource, err)
-			return
+			return err
 		}
-		gc.watchHandler(w)
-	}, 5*time.Second)
+		contents[storeID(jsonBase)] = obj
+		if jsonBase.ResourceVersion() > resourceVersion {
+			resourceVersion = jsonBase.ResourceVersion()
+		}
+	}
+	gc.store.Replace(contents)
+	gc.resourceVersion = resourceVersion
+	return nil
 }
 
+// startWatch watches the changes after the last one seen, or from the current state if none
+// has been seen.
 func (gc *Reflector) startWatch() (watch.Interface, error) {
-	return gc.kubeClient.Get().Path(gc.resource).Path("watch").Watch()
+	from := gc.resourceVersion
+	if from != 0 {
+		from++
+	}
+	return gc.listerWatcher.Watch(from)
 }
 
-func (gc *Reflector) watchHandler(w watch.Interface) {
+// watchHandler applies the events of w to the store until w ends. It returns an error if the
+// server ended w with one.
+func (gc *Reflector) watchHandler(w watch.Interface) error {
+	defer w.Stop()
 	for {
 		event, ok := <-w.ResultChan()
 		if !ok {
-			glog.Errorf("unexpected watch close")
-			return
+			glog.V(2).Infof("watch of %v closed", gc.listerWatcher)
+			return nil
+		}
+		if event.Type == watch.Error {
+			return fmt.Errorf("watch failed: %#v", event.Object)
 		}
 		if e, a := gc.expectedType, reflect.TypeOf(event.Object); e != a {
 			glog.Errorf("expected type %v, but watch event object had type %v", e, a)
@@ -94,13 +175,23 @@ func (gc *Reflector) watchHandler(w watch.Interface) {
 		}
 		switch event.Type {
 		case watch.Added:
-			gc.store.Add(jsonBase.ID(), event.Object)
+			gc.store.Add(storeID(jsonBase), event.Object)
 		case watch.Modified:
-			gc.store.Update(jsonBase.ID(), event.Object)
+			gc.store.Update(storeID(jsonBase), event.Object)
 		case watch.Deleted:
-			gc.store.Delete(jsonBase.ID(), event.Object)
+			gc.store.Delete(storeID(jsonBase), event.Object)
 		default:
 			glog.Errorf("unable to understand watch event %#v", event)
+			continue
+		}
+		if jsonBase.ResourceVersion() > gc.resourceVersion {
+			gc.resourceVersion = jsonBase.ResourceVersion()
 		}
 	}
 }
+
+// storeID returns the ID an object is stored under: its ID, qualified by its namespace
+// unless it is in the default one.
+func storeID(jsonBase api.JSONBaseInterface) string {
+	return api.QualifiedID(jsonBase.Namespace(), jsonBase.ID())
+}
@@ -17,18 +17,32 @@ limitations under the License.
 package cache
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
+// testLW is a ListerWatcher that lists and watches through functions.
+type testLW struct {
+	list  func() (interface{}, error)
+	watch func(resourceVersion uint64) (watch.Interface, error)
+}
+
+func (lw *testLW) List() (interface{}, error) { return lw.list() }
+func (lw *testLW) Watch(resourceVersion uint64) (watch.Interface, error) {
+	return lw.watch(resourceVersion)
+}
+
 func TestReflector_watchHandler(t *testing.T) {
 	s := NewStore()
-	g := NewReflector("foo", nil, &api.Pod{}, s)
+	g := NewReflector(nil, &api.Pod{}, s)
 	fw := watch.NewFake()
 	s.Add("foo", &api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
 	s.Add("bar", &api.Pod{JSONBase: api.JSONBase{ID: "bar"}})
@@ -39,7 +53,9 @@ func TestReflector_watchHandler(t *testing.T) {
 		fw.Delete(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
 		fw.Stop()
 	}()
-	g.watchHandler(fw)
+	if err := g.watchHandler(fw); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
 
 	table := []struct {
 		ID     string
@@ -63,32 +79,173 @@ func TestReflector_watchHandler(t *testing.T) {
 			t.Errorf("%v: expected %v, got %v", item.ID, e, a)
 		}
 	}
+	if e, a := uint64(55), g.resourceVersion; e != a {
+		t.Errorf("expected the last resource version to be %v, got %v", e, a)
+	}
+}
+
+func TestReflector_listAndWatch(t *testing.T) {
+	lists := 0
+	watches := make(chan *watch.FakeWatcher, 1)
+	froms := make(chan uint64, 1)
+	lw := &testLW{
+		list: func() (interface{}, error) {
+			lists++
+			return &api.PodList{Items: []api.Pod{
+				{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 3}},
+				{JSONBase: api.JSONBase{ID: "bar", Namespace: "other", ResourceVersion: 1}},
+			}}, nil
+		},
+		watch: func(resourceVersion uint64) (watch.Interface, error) {
+			froms <- resourceVersion
+			fw := watch.NewFake()
+			watches <- fw
+			return fw, nil
+		},
+	}
+	s := NewStore()
+	s.Add("stale", &api.Pod{JSONBase: api.JSONBase{ID: "stale"}})
+	g := NewReflector(lw, &api.Pod{}, s)
+
+	// The store is filled from the list, and changes are watched from after its version.
+	done := make(chan error)
+	go func() { done <- g.listAndWatch() }()
+	if e, a := uint64(4), <-froms; e != a {
+		t.Errorf("expected a watch from %v, got %v", e, a)
+	}
+	if _, exists := s.Get("stale"); exists {
+		t.Errorf("expected the list to replace the contents of the store")
+	}
+	if _, exists := s.Get("bar" + api.NamespaceSeparator + "other"); !exists {
+		t.Errorf("expected the listed pod to be stored by its qualified ID")
+	}
+	fw := <-watches
+	fw.Add(&api.Pod{JSONBase: api.JSONBase{ID: "baz", ResourceVersion: 7}})
+	fw.Stop()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// A watch that ends is resumed from the last change, without listing.
+	go func() { done <- g.listAndWatch() }()
+	if e, a := uint64(8), <-froms; e != a {
+		t.Errorf("expected a watch from %v, got %v", e, a)
+	}
+	fw = <-watches
+	fw.Action(watch.Error, &api.Status{Status: api.StatusFailure, Message: "too old"})
+	if err := <-done; err == nil {
+		t.Errorf("expected the watch error")
+	}
+	if _, exists := s.Get("baz"); !exists || lists != 1 {
+		t.Errorf("expected the watched pod to be kept until the next list, with %d lists", lists)
+	}
+
+	// A watch that fails is followed by a new list.
+	go func() { done <- g.listAndWatch() }()
+	if e, a := uint64(4), <-froms; e != a {
+		t.Errorf("expected a watch from %v, got %v", e, a)
+	}
+	if _, exists := s.Get("baz"); exists || lists != 2 {
+		t.Errorf("expected the store to be listed again, with %d lists", lists)
+	}
+	(<-watches).Stop()
+	<-done
 }
 
-func TestReflector_startWatch(t *testing.T) {
-	table := []struct{ resource, path string }{
-		{"pods", "/api/v1beta1/pods/watch"},
-		{"services", "/api/v1beta1/services/watch"},
+func TestReflector_listAndWatchErrors(t *testing.T) {
+	listErr := fmt.Errorf("list failed")
+	watchErr := fmt.Errorf("watch failed")
+	lists := 0
+	lw := &testLW{
+		list: func() (interface{}, error) {
+			lists++
+			if lists == 1 {
+				return nil, listErr
+			}
+			return &api.PodList{}, nil
+		},
+		watch: func(resourceVersion uint64) (watch.Interface, error) { return nil, watchErr },
+	}
+	g := NewReflector(lw, &api.Pod{}, NewStore())
+	if err := g.listAndWatch(); err != listErr {
+		t.Errorf("expected %v, got %v", listErr, err)
+	}
+	if err := g.listAndWatch(); err != watchErr {
+		t.Errorf("expected %v, got %v", watchErr, err)
+	}
+	if g.listed || lists != 2 {
+		t.Errorf("expected a failed watch to be followed by a list, got %d lists", lists)
+	}
+
+	lw.list = func() (interface{}, error) { return &api.ServiceList{Items: []api.Service{{}}}, nil }
+	if err := g.listAndWatch(); err == nil {
+		t.Errorf("expected an error for items of another type")
+	}
+}
+
+func TestReflector_concurrentReads(t *testing.T) {
+	fw := watch.NewFake()
+	lw := &testLW{
+		list:  func() (interface{}, error) { return &api.PodList{}, nil },
+		watch: func(resourceVersion uint64) (watch.Interface, error) { return fw, nil },
+	}
+	s := NewStore()
+	g := NewReflector(lw, &api.Pod{}, s)
+	done := make(chan error)
+	go func() { done <- g.listAndWatch() }()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Get("pod")
+				ListSelected(s, labels.Set{"name": "pod"}.AsSelector())
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		fw.Modify(&api.Pod{JSONBase: api.JSONBase{ID: "pod", ResourceVersion: uint64(i + 1)}, Labels: map[string]string{"name": "pod"}})
+	}
+	fw.Stop()
+	wg.Wait()
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if obj, _ := s.Get("pod"); obj.(*api.Pod).ResourceVersion != 100 {
+		t.Errorf("expected the last version of the pod, got %#v", obj)
+	}
+}
+
+func TestListWatch(t *testing.T) {
+	table := []struct {
+		resource string
+		selector labels.Selector
+		path     string
+		query    string
+	}{
+		{"pods", nil, "/api/v1beta1/watch/pods", "labels=&resourceVersion=5"},
+		{"services", labels.Set{"name": "foo"}.AsSelector(), "/api/v1beta1/watch/services", "labels=name%3Dfoo&resourceVersion=5"},
 	}
 	for _, testItem := range table {
 		got := make(chan struct{})
 		srv := httptest.NewServer(http.HandlerFunc(
 			func(w http.ResponseWriter, req *http.Request) {
 				w.WriteHeader(http.StatusNotFound)
-				if req.URL.Path == testItem.path {
+				if req.URL.Path == testItem.path && req.URL.RawQuery == testItem.query {
 					close(got)
 					return
 				}
-				t.Errorf("unexpected path %v", req.URL.Path)
+				t.Errorf("unexpected request %v", req.URL)
 			}))
-		s := NewStore()
-		c := client.New(srv.URL, nil)
-		g := NewReflector(testItem.resource, c, &api.Pod{}, s)
-		_, err := g.startWatch()
+		lw := &ListWatch{Client: client.New(srv.URL, nil), Resource: testItem.resource, Selector: testItem.selector}
+		_, err := lw.Watch(5)
 		// We're just checking that it watches the right path.
 		if err == nil {
 			t.Errorf("unexpected non-error")
 		}
 		<-got
+		srv.Close()
 	}
 }
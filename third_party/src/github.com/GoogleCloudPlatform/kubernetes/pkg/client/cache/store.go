@@ -67,6 +67,17 @@ func (c *cache) Get(ID string) (item interface{}, exists bool) {
 	return item, exists
 }
 
+// Replace sets the contents of the cache to items.
+func (c *cache) Replace(items map[string]interface{}) {
+	copied := make(map[string]interface{}, len(items))
+	for ID, item := range items {
+		copied[ID] = item
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.items = copied
+}
+
 // NewStore returns a Store implemented simply with a map and a lock.
 func NewStore() Store {
 	return &cache{items: map[string]interface{}{}}
@@ -19,6 +19,8 @@ package cache
 import (
 	"testing"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
@@ -57,6 +59,15 @@ func doTestStore(t *testing.T, store Store) {
 	if len(found) != 3 {
 		t.Errorf("extra items")
 	}
+
+	store.Replace(map[string]interface{}{"a": "f", "g": "h"})
+	found = util.StringSet{}
+	for _, item := range store.List() {
+		found.Insert(item.(string))
+	}
+	if !found.HasAll("f", "h") || len(found) != 2 {
+		t.Errorf("expected the replaced items, got %v", found.List())
+	}
 }
 
 func TestCache(t *testing.T) {
@@ -66,3 +77,33 @@ func TestCache(t *testing.T) {
 func TestFIFOCache(t *testing.T) {
 	doTestStore(t, NewFIFO())
 }
+
+func TestLabelHelpers(t *testing.T) {
+	store := NewStore()
+	store.Add("a", &api.Pod{JSONBase: api.JSONBase{ID: "a"}, Labels: map[string]string{"name": "foo", "tier": "web"}})
+	store.Add("b", &api.Pod{JSONBase: api.JSONBase{ID: "b"}, Labels: map[string]string{"name": "foo"}})
+	store.Add("c", &api.Pod{JSONBase: api.JSONBase{ID: "c"}, Labels: map[string]string{"name": "bar"}})
+	store.Add("d", &api.Pod{JSONBase: api.JSONBase{ID: "d"}})
+
+	ids := func(items []interface{}) util.StringSet {
+		set := util.StringSet{}
+		for _, item := range items {
+			set.Insert(item.(*api.Pod).ID)
+		}
+		return set
+	}
+	if selected := ids(ListSelected(store, labels.Set{"name": "foo"}.AsSelector())); !selected.HasAll("a", "b") || len(selected) != 2 {
+		t.Errorf("expected a and b, got %v", selected.List())
+	}
+	if e, a := 4, len(ListSelected(store, labels.Everything())); e != a {
+		t.Errorf("expected %v items, got %v", e, a)
+	}
+
+	index := IndexByLabel(store, "name")
+	if len(index) != 2 || len(index["foo"]) != 2 || len(index["bar"]) != 1 {
+		t.Errorf("unexpected index %#v", index)
+	}
+	if index := IndexByLabel(store, "tier"); len(index) != 1 || ids(index["web"]).List()[0] != "a" {
+		t.Errorf("unexpected index %#v", index)
+	}
+}
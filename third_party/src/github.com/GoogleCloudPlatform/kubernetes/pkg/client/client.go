@@ -23,9 +23,12 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
@@ -43,37 +46,130 @@ type Interface interface {
 	DeletePod(name string) error
 	CreatePod(api.Pod) (api.Pod, error)
 	UpdatePod(api.Pod) (api.Pod, error)
+	WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
 
 	ListReplicationControllers(selector labels.Selector) (api.ReplicationControllerList, error)
 	GetReplicationController(name string) (api.ReplicationController, error)
 	CreateReplicationController(api.ReplicationController) (api.ReplicationController, error)
 	UpdateReplicationController(api.ReplicationController) (api.ReplicationController, error)
+	UpdateReplicationControllerStatus(api.ReplicationController) (api.ReplicationController, error)
 	DeleteReplicationController(string) error
 	WatchReplicationControllers(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
 
+	ListServices(selector labels.Selector) (api.ServiceList, error)
 	GetService(name string) (api.Service, error)
 	CreateService(api.Service) (api.Service, error)
 	UpdateService(api.Service) (api.Service, error)
 	DeleteService(string) error
 
-	ListBuilds() (buildapi.BuildList, error)
+	ListMinions() (api.MinionList, error)
+	GetMinion(name string) (api.Minion, error)
+	CreateMinion(api.Minion) (api.Minion, error)
+	DeleteMinion(name string) error
+
+	ListBuilds(selector labels.Selector) (buildapi.BuildList, error)
+	GetBuild(name string) (buildapi.Build, error)
+	CreateBuild(buildapi.Build) (buildapi.Build, error)
 	UpdateBuild(buildapi.Build) (buildapi.Build, error)
+	UpdateBuildStatus(buildapi.Build) (buildapi.Build, error)
+	DeleteBuild(name string) error
+	WatchBuilds(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
 }
 
 // StatusErr might get returned from an api call if your request is still being processed
-// and hence the expected return data is not available yet.
+// and hence the expected return data is not available yet. It is also returned for requests
+// that fail with a status, which carries the reason they failed; see IsNotFound, IsConflict
+// and IsAlreadyExists.
 type StatusErr struct {
 	Status api.Status
 }
 
+// Error returns the message of the status and its reason, if the server gave a message.
 func (s *StatusErr) Error() string {
-	return fmt.Sprintf("Status: %v (%#v)", s.Status.Status, s.Status)
+	if len(s.Status.Message) == 0 {
+		return fmt.Sprintf("Status: %v (%#v)", s.Status.Status, s.Status)
+	}
+	if len(s.Status.Reason) == 0 {
+		return s.Status.Message
+	}
+	return fmt.Sprintf("%s (%s)", s.Status.Message, s.Status.Reason)
+}
+
+// IsNotFound returns true if err is the server's answer to a request for a missing object.
+func IsNotFound(err error) bool {
+	return hasReason(err, api.ReasonTypeNotFound, http.StatusNotFound)
+}
+
+// IsConflict returns true if err is the server's answer to an update of an object that
+// changed since it was read.
+func IsConflict(err error) bool {
+	return hasReason(err, api.ReasonTypeConflict, http.StatusConflict)
+}
+
+// IsAlreadyExists returns true if err is the server's answer to the creation of an object
+// that exists.
+func IsAlreadyExists(err error) bool {
+	statusErr, ok := err.(*StatusErr)
+	return ok && statusErr.Status.Reason == api.ReasonTypeAlreadyExists
+}
+
+// hasReason returns true if err is a *StatusErr with the given reason, or, if the server
+// gave none, the given code.
+func hasReason(err error, reason api.ReasonType, code int) bool {
+	statusErr, ok := err.(*StatusErr)
+	if !ok {
+		return false
+	}
+	if len(statusErr.Status.Reason) > 0 {
+		return statusErr.Status.Reason == reason
+	}
+	return statusErr.Status.Code == code
+}
+
+// ConnectionError is returned when a request could not be delivered to the server, as
+// opposed to the server responding with an error.
+type ConnectionError struct {
+	Method string
+	Host   string
+	Path   string
+	Err    error
+}
+
+func (e *ConnectionError) Error() string {
+	msg := fmt.Sprintf("unable to connect to %s for %s %s: %v", e.Host, e.Method, e.Path, e.Err)
+	if hint := e.hint(); len(hint) > 0 {
+		msg += " (" + hint + ")"
+	}
+	return msg
+}
+
+// hint suggests a likely cause of the failure, if one can be recognized.
+func (e *ConnectionError) hint() string {
+	text := e.Err.Error()
+	switch {
+	case strings.Contains(text, "server gave HTTP response to HTTPS client"):
+		return "the server appears to speak plain HTTP, try an http:// host"
+	case strings.HasPrefix(e.Host, "http://") && (strings.Contains(text, "malformed HTTP") || strings.HasSuffix(text, "EOF")):
+		return "the server may expect HTTPS, try an https:// host"
+	case strings.Contains(text, "connection refused"):
+		return "is the server running and is the host correct?"
+	}
+	return ""
 }
 
 // AuthInfo is used to store authorization information
 type AuthInfo struct {
 	User     string
 	Password string
+	// CertFile and KeyFile are the client certificate and its key, presented to servers
+	// that require TLS client authentication.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// CAFile is the PEM encoded certificate authority used to verify the server. If
+	// empty, the system roots are used.
+	CAFile string `json:"caFile,omitempty"`
+	// Insecure disables verification of the server's certificate.
+	Insecure bool `json:"insecure,omitempty"`
 }
 
 // Client is the actual implementation of a Kubernetes client.
@@ -85,6 +181,17 @@ type Client struct {
 	Sync       bool
 	PollPeriod time.Duration
 	Timeout    time.Duration
+	// RetryPolicy decides which failed requests are retried, and when.
+	RetryPolicy RetryPolicy
+	// Binary makes requests send the objects in their bodies encoded with api.GobCodec, and
+	// ask for answers in it too. Answers are decoded according to their Content-Type, so
+	// servers that answer in JSON are understood as well.
+	Binary bool
+
+	// sleep waits between retries; tests replace it.
+	sleep      func(time.Duration)
+	retryLock  sync.Mutex
+	retryStats RetryStats
 }
 
 // New creates a new client object.
@@ -102,49 +209,82 @@ func New(host string, auth *AuthInfo) *Client {
 		Sync:       false,
 		PollPeriod: time.Second * 20,
 		Timeout:    time.Second * 20,
+		RetryPolicy: RetryPolicy{
+			Backoff:    time.Second,
+			MaxBackoff: 30 * time.Second,
+			MaxElapsed: 2 * time.Minute,
+		},
+		sleep: time.Sleep,
 	}
 }
 
-// Execute a request, adds authentication (if auth != nil), and HTTPS cert ignoring.
-func (c *Client) doRequest(request *http.Request) ([]byte, error) {
-	if c.auth != nil {
+// NewTLS creates a new client object whose TLS connections are configured by auth: the
+// server's certificate is verified unless auth.Insecure is set, and a client certificate
+// is presented if auth names one.
+func NewTLS(host string, auth *AuthInfo) (*Client, error) {
+	c := New(host, auth)
+	config := &tls.Config{}
+	if auth != nil {
+		var err error
+		if config, err = auth.TLSConfig(); err != nil {
+			return nil, err
+		}
+	}
+	c.httpClient.Transport = &http.Transport{TLSClientConfig: config}
+	return c, nil
+}
+
+// setBasicAuth adds the client's username and password to request. Nothing is added if the
+// client has no username, e.g. when it authenticates with a certificate only.
+func (c *Client) setBasicAuth(request *http.Request) {
+	if c.auth != nil && len(c.auth.User) > 0 {
 		request.SetBasicAuth(c.auth.User, c.auth.Password)
 	}
+}
+
+// Execute a request, adds authentication (if auth != nil), and HTTPS cert ignoring.
+func (c *Client) doRequest(request *http.Request) ([]byte, error) {
+	body, _, err := c.doRequestResponse(request)
+	return body, err
+}
+
+// doRequestResponse is doRequest, but also returns the response of the server, whose body
+// is already read and closed. The response is nil if the server could not be reached.
+func (c *Client) doRequestResponse(request *http.Request) ([]byte, *http.Response, error) {
+	c.setBasicAuth(request)
 	response, err := c.httpClient.Do(request)
 	if err != nil {
-		return nil, err
+		return nil, nil, &ConnectionError{Method: request.Method, Host: c.host, Path: request.URL.Path, Err: err}
 	}
 	defer response.Body.Close()
 	body, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return body, err
+		return body, response, err
 	}
 
 	// Did the server give us a status response?
 	isStatusResponse := false
 	var status api.Status
-	if err := api.DecodeInto(body, &status); err == nil && status.Status != "" {
+	codec := api.CodecForMediaType(response.Header.Get("Content-Type"))
+	if err := codec.DecodeInto(body, &status); err == nil && status.Status != "" {
 		isStatusResponse = true
 	}
 
-	switch {
-	case response.StatusCode == http.StatusConflict:
+	if response.StatusCode < http.StatusOK || response.StatusCode > http.StatusPartialContent {
 		// Return error given by server, if there was one.
 		if isStatusResponse {
-			return nil, &StatusErr{status}
+			return nil, response, &StatusErr{status}
 		}
-		fallthrough
-	case response.StatusCode < http.StatusOK || response.StatusCode > http.StatusPartialContent:
-		return nil, fmt.Errorf("request [%#v] failed (%d) %s: %s", request, response.StatusCode, response.Status, string(body))
+		return nil, response, fmt.Errorf("request [%#v] failed (%d) %s: %s", request, response.StatusCode, response.Status, string(body))
 	}
 
 	// If the server gave us a status back, look at what it was.
 	if isStatusResponse && status.Status != api.StatusSuccess {
 		// "Working" requests need to be handled specially.
 		// "Failed" requests are clearly just an error and it makes sense to return them as such.
-		return nil, &StatusErr{status}
+		return nil, response, &StatusErr{status}
 	}
-	return body, err
+	return body, response, err
 }
 
 // Underlying base implementation of performing a request.
@@ -183,13 +323,13 @@ func (c *Client) ListPods(selector labels.Selector) (result api.PodList, err err
 
 // GetPod takes the name of the pod, and returns the corresponding Pod object, and an error if it occurs
 func (c *Client) GetPod(name string) (result api.Pod, err error) {
-	err = c.Get().Path("pods").Path(name).Do().Into(&result)
+	err = c.Get().Path("pods").Name(name).Do().Into(&result)
 	return
 }
 
 // DeletePod takes the name of the pod, and returns an error if one occurs
 func (c *Client) DeletePod(name string) error {
-	return c.Delete().Path("pods").Path(name).Do().Error()
+	return c.Delete().Path("pods").Name(name).Do().Error()
 }
 
 // CreatePod takes the representation of a pod.  Returns the server's representation of the pod, and an error, if it occurs
@@ -204,10 +344,21 @@ func (c *Client) UpdatePod(pod api.Pod) (result api.Pod, err error) {
 		err = fmt.Errorf("invalid update object, missing resource version: %v", pod)
 		return
 	}
-	err = c.Put().Path("pods").Path(pod.ID).Body(pod).Do().Into(&result)
+	err = c.Put().Path("pods").Name(pod.ID).Body(pod).Do().Into(&result)
 	return
 }
 
+// WatchPods returns a watch.Interface that watches the requested pods.
+func (c *Client) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.Get().
+		Path("watch").
+		Path("pods").
+		UintParam("resourceVersion", resourceVersion).
+		SelectorParam("labels", label).
+		SelectorParam("fields", field).
+		Watch()
+}
+
 // ListReplicationControllers takes a selector, and returns the list of replication controllers that match that selector
 func (c *Client) ListReplicationControllers(selector labels.Selector) (result api.ReplicationControllerList, err error) {
 	err = c.Get().Path("replicationControllers").SelectorParam("labels", selector).Do().Into(&result)
@@ -216,7 +367,7 @@ func (c *Client) ListReplicationControllers(selector labels.Selector) (result ap
 
 // GetReplicationController returns information about a particular replication controller
 func (c *Client) GetReplicationController(name string) (result api.ReplicationController, err error) {
-	err = c.Get().Path("replicationControllers").Path(name).Do().Into(&result)
+	err = c.Get().Path("replicationControllers").Name(name).Do().Into(&result)
 	return
 }
 
@@ -232,13 +383,21 @@ func (c *Client) UpdateReplicationController(controller api.ReplicationControlle
 		err = fmt.Errorf("invalid update object, missing resource version: %v", controller)
 		return
 	}
-	err = c.Put().Path("replicationControllers").Path(controller.ID).Body(controller).Do().Into(&result)
+	err = c.Put().Path("replicationControllers").Name(controller.ID).Body(controller).Do().Into(&result)
+	return
+}
+
+// UpdateReplicationControllerStatus records the current state of an existing replication
+// controller through its status subresource, leaving the rest of the controller as the
+// server has it.
+func (c *Client) UpdateReplicationControllerStatus(controller api.ReplicationController) (result api.ReplicationController, err error) {
+	err = c.Put().Namespace(controller.Namespace).Path("replicationControllers").Name(controller.ID).Path("status").Body(controller).Do().Into(&result)
 	return
 }
 
 // DeleteReplicationController deletes an existing replication controller.
 func (c *Client) DeleteReplicationController(name string) error {
-	return c.Delete().Path("replicationControllers").Path(name).Do().Error()
+	return c.Delete().Path("replicationControllers").Name(name).Do().Error()
 }
 
 // WatchReplicationControllers returns a watch.Interface that watches the requested controllers.
@@ -252,9 +411,15 @@ func (c *Client) WatchReplicationControllers(label, field labels.Selector, resou
 		Watch()
 }
 
+// ListServices takes a selector, and returns the list of services that match that selector.
+func (c *Client) ListServices(selector labels.Selector) (result api.ServiceList, err error) {
+	err = c.Get().Path("services").SelectorParam("labels", selector).Do().Into(&result)
+	return
+}
+
 // GetService returns information about a particular service.
 func (c *Client) GetService(name string) (result api.Service, err error) {
-	err = c.Get().Path("services").Path(name).Do().Into(&result)
+	err = c.Get().Path("services").Name(name).Do().Into(&result)
 	return
 }
 
@@ -270,18 +435,42 @@ func (c *Client) UpdateService(svc api.Service) (result api.Service, err error)
 		err = fmt.Errorf("invalid update object, missing resource version: %v", svc)
 		return
 	}
-	err = c.Put().Path("services").Path(svc.ID).Body(svc).Do().Into(&result)
+	err = c.Put().Path("services").Name(svc.ID).Body(svc).Do().Into(&result)
 	return
 }
 
 // DeleteService deletes an existing service.
 func (c *Client) DeleteService(name string) error {
-	return c.Delete().Path("services").Path(name).Do().Error()
+	return c.Delete().Path("services").Name(name).Do().Error()
+}
+
+// ListMinions returns the minions of the cluster.
+func (c *Client) ListMinions() (result api.MinionList, err error) {
+	err = c.Get().Path("minions").Do().Into(&result)
+	return
 }
 
-// ServerVersion retrieves and parses the server's version.
+// GetMinion returns information about a particular minion.
+func (c *Client) GetMinion(name string) (result api.Minion, err error) {
+	err = c.Get().Path("minions").Name(name).Do().Into(&result)
+	return
+}
+
+// CreateMinion adds a minion to the cluster.
+func (c *Client) CreateMinion(minion api.Minion) (result api.Minion, err error) {
+	err = c.Post().Path("minions").Body(minion).Do().Into(&result)
+	return
+}
+
+// DeleteMinion removes a minion from the cluster.
+func (c *Client) DeleteMinion(name string) error {
+	return c.Delete().Path("minions").Name(name).Do().Error()
+}
+
+// ServerVersion retrieves and parses the server's version. It is never an operation, so
+// a server that answers with one is not polled.
 func (c *Client) ServerVersion() (*version.Info, error) {
-	body, err := c.Get().AbsPath("/version").Do().Raw()
+	body, err := c.Get().AbsPath("/version").PollPeriod(0).Do().Raw()
 	if err != nil {
 		return nil, err
 	}
@@ -293,14 +482,62 @@ func (c *Client) ServerVersion() (*version.Info, error) {
 	return &info, nil
 }
 
-// ListBuilds returns a list of builds.
-func (c *Client) ListBuilds() (result buildapi.BuildList, err error) {
-	err = c.Get().Path("builds").Do().Into(&result)
+// ServerConfig retrieves the limits and defaults the apiserver applies to requests.
+func (c *Client) ServerConfig() (*apiserver.ServerConfig, error) {
+	body, err := c.Get().Path("serverconfig").Do().Raw()
+	if err != nil {
+		return nil, err
+	}
+	var config apiserver.ServerConfig
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("Got '%s': %v", string(body), err)
+	}
+	return &config, nil
+}
+
+// ListBuilds takes a selector, and returns the list of builds that match that selector.
+func (c *Client) ListBuilds(selector labels.Selector) (result buildapi.BuildList, err error) {
+	err = c.Get().Path("builds").SelectorParam("labels", selector).Do().Into(&result)
+	return
+}
+
+// GetBuild returns information about a particular build.
+func (c *Client) GetBuild(name string) (result buildapi.Build, err error) {
+	err = c.Get().Path("builds").Name(name).Do().Into(&result)
+	return
+}
+
+// CreateBuild creates a new build.
+func (c *Client) CreateBuild(build buildapi.Build) (result buildapi.Build, err error) {
+	err = c.Post().Path("builds").Body(build).Do().Into(&result)
 	return
 }
 
 // UpdateBuild updates an existing build.
 func (c *Client) UpdateBuild(build buildapi.Build) (result buildapi.Build, err error) {
-	err = c.Put().Path("builds").Path(build.ID).Body(build).Do().Into(&result)
+	err = c.Put().Path("builds").Name(build.ID).Body(build).Do().Into(&result)
+	return
+}
+
+// UpdateBuildStatus updates the status of an existing build through its status
+// subresource, leaving the rest of the build as the server has it.
+func (c *Client) UpdateBuildStatus(build buildapi.Build) (result buildapi.Build, err error) {
+	err = c.Put().Path("builds").Name(build.ID).Path("status").Body(build).Do().Into(&result)
 	return
 }
+
+// DeleteBuild deletes an existing build.
+func (c *Client) DeleteBuild(name string) error {
+	return c.Delete().Path("builds").Name(name).Do().Error()
+}
+
+// WatchBuilds returns a watch.Interface that watches the requested builds.
+func (c *Client) WatchBuilds(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.Get().
+		Path("watch").
+		Path("builds").
+		UintParam("resourceVersion", resourceVersion).
+		SelectorParam("labels", label).
+		SelectorParam("fields", field).
+		Watch()
+}
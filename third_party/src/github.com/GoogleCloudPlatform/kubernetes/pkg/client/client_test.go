@@ -18,6 +18,7 @@ package client
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -25,6 +26,8 @@ import (
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
@@ -241,6 +244,19 @@ func TestUpdateController(t *testing.T) {
 	c.Validate(t, receivedController, err)
 }
 
+func TestUpdateControllerStatus(t *testing.T) {
+	controller := api.ReplicationController{
+		JSONBase:     api.JSONBase{ID: "foo", Namespace: "ns"},
+		CurrentState: api.ReplicationControllerState{Replicas: 3},
+	}
+	c := &testClient{
+		Request:  testRequest{Method: "PUT", Path: "/ns/ns/replicationControllers/foo/status", Body: &controller},
+		Response: Response{StatusCode: 200, Body: &controller},
+	}
+	response, err := c.Setup().UpdateReplicationControllerStatus(controller)
+	c.Validate(t, &response, err)
+}
+
 func TestDeleteController(t *testing.T) {
 	c := &testClient{
 		Request:  testRequest{Method: "DELETE", Path: "/replicationControllers/foo"},
@@ -406,11 +422,176 @@ func TestDeleteService(t *testing.T) {
 	c.Validate(t, nil, err)
 }
 
+func TestListServices(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "GET", Path: "/services", Query: url.Values{"labels": []string{"name=baz"}}},
+		Response: Response{StatusCode: 200, Body: api.ServiceList{Items: []api.Service{{JSONBase: api.JSONBase{ID: "service-1"}}}}},
+	}
+	response, err := c.Setup().ListServices(labels.Set{"name": "baz"}.AsSelector())
+	c.Validate(t, response, err)
+}
+
+func TestListMinions(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "GET", Path: "/minions"},
+		Response: Response{StatusCode: 200, Body: api.MinionList{Items: []api.Minion{{JSONBase: api.JSONBase{ID: "minion-1"}}}}},
+	}
+	response, err := c.Setup().ListMinions()
+	c.Validate(t, response, err)
+}
+
+func TestGetMinion(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "GET", Path: "/minions/minion-1"},
+		Response: Response{StatusCode: 200, Body: &api.Minion{JSONBase: api.JSONBase{ID: "minion-1"}, HostIP: "10.0.0.1"}},
+	}
+	response, err := c.Setup().GetMinion("minion-1")
+	c.Validate(t, &response, err)
+}
+
+func TestCreateMinion(t *testing.T) {
+	minion := api.Minion{JSONBase: api.JSONBase{ID: "minion-1"}}
+	c := &testClient{
+		Request:  testRequest{Method: "POST", Path: "/minions", Body: &minion},
+		Response: Response{StatusCode: 200, Body: &minion},
+	}
+	response, err := c.Setup().CreateMinion(minion)
+	c.Validate(t, &response, err)
+}
+
+func TestDeleteMinion(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "DELETE", Path: "/minions/minion-1"},
+		Response: Response{StatusCode: 200},
+	}
+	err := c.Setup().DeleteMinion("minion-1")
+	c.Validate(t, nil, err)
+}
+
+func TestListBuilds(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "GET", Path: "/builds", Query: url.Values{"labels": []string{"name=baz"}}},
+		Response: Response{StatusCode: 200, Body: buildapi.BuildList{Items: []buildapi.Build{{JSONBase: api.JSONBase{ID: "build-1"}}}}},
+	}
+	response, err := c.Setup().ListBuilds(labels.Set{"name": "baz"}.AsSelector())
+	c.Validate(t, response, err)
+}
+
+func TestGetBuild(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "GET", Path: "/builds/build-1"},
+		Response: Response{StatusCode: 200, Body: &buildapi.Build{JSONBase: api.JSONBase{ID: "build-1"}, Status: buildapi.BuildRunning}},
+	}
+	response, err := c.Setup().GetBuild("build-1")
+	c.Validate(t, &response, err)
+}
+
+func TestCreateBuild(t *testing.T) {
+	build := buildapi.Build{JSONBase: api.JSONBase{ID: "build-1"}}
+	c := &testClient{
+		Request:  testRequest{Method: "POST", Path: "/builds", Body: &build},
+		Response: Response{StatusCode: 200, Body: &build},
+	}
+	response, err := c.Setup().CreateBuild(build)
+	c.Validate(t, &response, err)
+}
+
+func TestUpdateBuild(t *testing.T) {
+	build := buildapi.Build{JSONBase: api.JSONBase{ID: "build-1", ResourceVersion: 1}, Status: buildapi.BuildComplete}
+	c := &testClient{
+		Request:  testRequest{Method: "PUT", Path: "/builds/build-1", Body: &build},
+		Response: Response{StatusCode: 200, Body: &build},
+	}
+	response, err := c.Setup().UpdateBuild(build)
+	c.Validate(t, &response, err)
+}
+
+func TestUpdateBuildStatus(t *testing.T) {
+	build := buildapi.Build{JSONBase: api.JSONBase{ID: "build-1"}, Status: buildapi.BuildFailed, Reason: buildapi.BuildReasonTimeout}
+	c := &testClient{
+		Request:  testRequest{Method: "PUT", Path: "/builds/build-1/status", Body: &build},
+		Response: Response{StatusCode: 200, Body: &build},
+	}
+	response, err := c.Setup().UpdateBuildStatus(build)
+	c.Validate(t, &response, err)
+}
+
+func TestDeleteBuild(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "DELETE", Path: "/builds/build-1"},
+		Response: Response{StatusCode: 200},
+	}
+	err := c.Setup().DeleteBuild("build-1")
+	c.Validate(t, nil, err)
+}
+
+func TestNamesAreEscaped(t *testing.T) {
+	c := &testClient{
+		Request:  testRequest{Method: "GET", Path: "/pods/a/b?c d%"},
+		Response: Response{StatusCode: 200, Body: api.Pod{JSONBase: api.JSONBase{ID: "foo"}}},
+	}
+	pod, err := c.Setup().GetPod("a/b?c d%")
+	c.Validate(t, pod, err)
+	if uri := c.handler.RequestReceived.RequestURI; uri != "/api/v1beta1/pods/a%2Fb%3Fc%20d%25?" {
+		t.Errorf("expected the name to be sent as a single escaped segment, got %s", uri)
+	}
+
+	for _, name := range []string{"", ".", ".."} {
+		c := &testClient{Error: true}
+		err := c.Setup().DeletePod(name)
+		c.Validate(t, nil, err)
+		if c.handler.RequestReceived != nil {
+			t.Errorf("%q: expected no request to be made, got %#v", name, c.handler.RequestReceived)
+		}
+	}
+}
+
+func TestStatusErrReason(t *testing.T) {
+	table := []struct {
+		status                       api.Status
+		message                      string
+		notFound, conflict, existing bool
+	}{
+		{
+			status:   api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound, Message: `pod "foo" not found`},
+			message:  `pod "foo" not found (not_found)`,
+			notFound: true,
+		},
+		{
+			status:   api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeConflict, Message: "stale"},
+			message:  "stale (conflict)",
+			conflict: true,
+		},
+		{
+			status:   api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeAlreadyExists, Message: "exists"},
+			message:  "exists (already_exists)",
+			existing: true,
+		},
+		{
+			status:   api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Message: "gone"},
+			message:  "gone",
+			notFound: true,
+		},
+	}
+	for _, item := range table {
+		err := &StatusErr{item.status}
+		if err.Error() != item.message {
+			t.Errorf("expected %q, got %q", item.message, err.Error())
+		}
+		if IsNotFound(err) != item.notFound || IsConflict(err) != item.conflict || IsAlreadyExists(err) != item.existing {
+			t.Errorf("%#v: unexpected reason", item.status)
+		}
+	}
+	if IsNotFound(errors.New("not found")) {
+		t.Errorf("expected an error without a status not to be recognized")
+	}
+}
+
 func TestMakeRequest(t *testing.T) {
 	testClients := []testClient{
 		{Request: testRequest{Method: "GET", Path: "/good"}, Response: Response{StatusCode: 200}},
 		{Request: testRequest{Method: "GET", Path: "/bad%ZZ"}, Error: true},
-		{Client: New("", &AuthInfo{"foo", "bar"}), Request: testRequest{Method: "GET", Path: "/auth", Header: "Authorization"}, Response: Response{StatusCode: 200}},
+		{Client: New("", &AuthInfo{User: "foo", Password: "bar"}), Request: testRequest{Method: "GET", Path: "/auth", Header: "Authorization"}, Response: Response{StatusCode: 200}},
 		{Client: &Client{httpClient: http.DefaultClient}, Request: testRequest{Method: "GET", Path: "/nocertificate"}, Error: true},
 		{Request: testRequest{Method: "GET", Path: "/error"}, Response: Response{StatusCode: 500}, Error: true},
 		{Request: testRequest{Method: "POST", Path: "/faildecode"}, Response: Response{StatusCode: 200, Body: "aaaaa"}, Target: &struct{}{}, Error: true},
@@ -485,6 +666,49 @@ func TestDoRequestAcceptedSuccess(t *testing.T) {
 	fakeHandler.ValidateRequest(t, "/foo/bar", "GET", nil)
 }
 
+func TestDoRequestErrorStatus(t *testing.T) {
+	table := []struct {
+		code   int
+		status *api.Status
+	}{
+		{http.StatusNotFound, &api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound}},
+		{http.StatusConflict, &api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeAlreadyExists}},
+		{422, &api.Status{Status: api.StatusFailure, Code: 422, Reason: api.ReasonTypeInvalid}},
+		{http.StatusInternalServerError, &api.Status{Status: api.StatusFailure, Code: http.StatusInternalServerError}},
+		{http.StatusInternalServerError, nil},
+	}
+	for _, item := range table {
+		body := "not a status"
+		if item.status != nil {
+			data, _ := api.Encode(item.status)
+			body = string(data)
+		}
+		fakeHandler := util.FakeHandler{
+			StatusCode:   item.code,
+			ResponseBody: body,
+			T:            t,
+		}
+		testServer := httptest.NewServer(&fakeHandler)
+		request, _ := http.NewRequest("GET", testServer.URL+"/foo/bar", nil)
+		_, err := New(testServer.URL, nil).doRequest(request)
+		testServer.Close()
+		se, ok := err.(*StatusErr)
+		if item.status == nil {
+			if err == nil || ok {
+				t.Errorf("%d: expected a plain error for a response without a status, got %#v", item.code, err)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%d: expected a StatusErr, got %#v", item.code, err)
+			continue
+		}
+		if !reflect.DeepEqual(se.Status, *item.status) {
+			t.Errorf("%d: expected %#v, got %#v", item.code, *item.status, se.Status)
+		}
+	}
+}
+
 func TestGetServerVersion(t *testing.T) {
 	expect := version.Info{
 		Major:     "foo",
@@ -511,3 +735,50 @@ func TestGetServerVersion(t *testing.T) {
 		t.Errorf("expected %v, got %v", e, a)
 	}
 }
+
+func TestGetServerConfig(t *testing.T) {
+	expect := apiserver.ServerConfig{Kind: "ServerConfig", APIVersion: "v1", APIVersions: []string{"v1beta1"}, DefaultTimeout: "30s"}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.Path
+		output, _ := json.Marshal(expect)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(output)
+	}))
+	defer server.Close()
+	client := New(server.URL, nil)
+
+	got, err := client.ServerConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/api/v1beta1/serverconfig" || !reflect.DeepEqual(expect, *got) {
+		t.Errorf("expected %v from /api/v1beta1/serverconfig, got %v from %s", expect, *got, path)
+	}
+}
+
+func TestDoRequestWithoutUser(t *testing.T) {
+	table := []struct {
+		auth      *AuthInfo
+		basicAuth bool
+	}{
+		{nil, false},
+		{&AuthInfo{CertFile: "cert", KeyFile: "key"}, false},
+		{&AuthInfo{User: "user", Password: "pass"}, true},
+	}
+	for _, item := range table {
+		var received *http.Request
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			w.Write([]byte(`{"kind": "Status", "status": "success"}`))
+		}))
+		c := New(testServer.URL, item.auth)
+		if err := c.Get().Path("pods").Do().Error(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		testServer.Close()
+		if _, _, ok := received.BasicAuth(); ok != item.basicAuth {
+			t.Errorf("%#v: expected basic auth %t, got %t", item.auth, item.basicAuth, ok)
+		}
+	}
+}
@@ -55,6 +55,11 @@ func (client *FakeClient) UpdatePod(pod api.Pod) (api.Pod, error) {
 	return api.Pod{}, nil
 }
 
+func (client *FakeClient) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	client.Actions = append(client.Actions, "watch-pods")
+	return watch.NewFake(), nil
+}
+
 func (client *FakeClient) ListReplicationControllers(selector labels.Selector) (api.ReplicationControllerList, error) {
 	client.Actions = append(client.Actions, "list-controllers")
 	return api.ReplicationControllerList{}, nil
@@ -75,6 +80,11 @@ func (client *FakeClient) UpdateReplicationController(controller api.Replication
 	return api.ReplicationController{}, nil
 }
 
+func (client *FakeClient) UpdateReplicationControllerStatus(controller api.ReplicationController) (api.ReplicationController, error) {
+	client.Actions = append(client.Actions, "update-controller-status")
+	return api.ReplicationController{}, nil
+}
+
 func (client *FakeClient) DeleteReplicationController(controller string) error {
 	client.Actions = append(client.Actions, "delete-controller")
 	return nil
@@ -85,6 +95,11 @@ func (client *FakeClient) WatchReplicationControllers(label, field labels.Select
 	return watch.NewFake(), nil
 }
 
+func (client *FakeClient) ListServices(selector labels.Selector) (api.ServiceList, error) {
+	client.Actions = append(client.Actions, "list-services")
+	return api.ServiceList{}, nil
+}
+
 func (client *FakeClient) GetService(name string) (api.Service, error) {
 	client.Actions = append(client.Actions, "get-controller")
 	return api.Service{}, nil
@@ -105,12 +120,57 @@ func (client *FakeClient) DeleteService(controller string) error {
 	return nil
 }
 
-func (client *FakeClient) ListBuilds() (buildapi.BuildList, error) {
+func (client *FakeClient) ListMinions() (api.MinionList, error) {
+	client.Actions = append(client.Actions, "list-minions")
+	return api.MinionList{}, nil
+}
+
+func (client *FakeClient) GetMinion(name string) (api.Minion, error) {
+	client.Actions = append(client.Actions, "get-minion")
+	return api.Minion{}, nil
+}
+
+func (client *FakeClient) CreateMinion(minion api.Minion) (api.Minion, error) {
+	client.Actions = append(client.Actions, "create-minion")
+	return api.Minion{}, nil
+}
+
+func (client *FakeClient) DeleteMinion(name string) error {
+	client.Actions = append(client.Actions, "delete-minion")
+	return nil
+}
+
+func (client *FakeClient) ListBuilds(selector labels.Selector) (buildapi.BuildList, error) {
 	client.Actions = append(client.Actions, "list-builds")
 	return buildapi.BuildList{}, nil
 }
 
+func (client *FakeClient) GetBuild(name string) (buildapi.Build, error) {
+	client.Actions = append(client.Actions, "get-build")
+	return buildapi.Build{}, nil
+}
+
+func (client *FakeClient) CreateBuild(buildapi.Build) (buildapi.Build, error) {
+	client.Actions = append(client.Actions, "create-build")
+	return buildapi.Build{}, nil
+}
+
 func (client *FakeClient) UpdateBuild(buildapi.Build) (buildapi.Build, error) {
 	client.Actions = append(client.Actions, "update-build")
 	return buildapi.Build{}, nil
 }
+
+func (client *FakeClient) UpdateBuildStatus(buildapi.Build) (buildapi.Build, error) {
+	client.Actions = append(client.Actions, "update-build-status")
+	return buildapi.Build{}, nil
+}
+
+func (client *FakeClient) DeleteBuild(name string) error {
+	client.Actions = append(client.Actions, "delete-build")
+	return nil
+}
+
+func (client *FakeClient) WatchBuilds(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	client.Actions = append(client.Actions, "watch-builds")
+	return watch.NewFake(), nil
+}
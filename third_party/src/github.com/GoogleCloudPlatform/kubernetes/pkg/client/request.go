@@ -25,6 +25,7 @@ import (
 	"net/url"
 	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
@@ -86,7 +87,7 @@ func (c *Client) Delete() *Request {
 
 // PollFor makes a request to do a single poll of the completion of the given operation.
 func (c *Client) PollFor(operationID string) *Request {
-	return c.Get().Path("operations").Path(operationID).Sync(false).PollPeriod(0)
+	return c.Get().Path("operations").Name(operationID).Sync(false).PollPeriod(0)
 }
 
 // Request allows for building up a request to a server in a chained fashion.
@@ -103,6 +104,10 @@ type Request struct {
 	timeout    time.Duration
 	sync       bool
 	pollPeriod time.Duration
+	idempotent bool
+
+	// contentType is the media type of body, if it is known.
+	contentType string
 }
 
 // Path appends an item to the request path. You must call Path at least once.
@@ -114,6 +119,38 @@ func (r *Request) Path(item string) *Request {
 	return r
 }
 
+// Name appends the name of an object to the request path as a single, escaped segment, so
+// that a name with a slash, question mark or percent sign can't change the request. Use it
+// rather than Path for names that come from users or objects. An empty name, or one of "."
+// and "..", is an error, rather than a request for the whole collection.
+func (r *Request) Name(name string) *Request {
+	if r.err != nil {
+		return r
+	}
+	if len(name) == 0 || name == "." || name == ".." {
+		r.err = fmt.Errorf("invalid object name %q", name)
+		return r
+	}
+	r.path = path.Join(r.path, escapeName(name))
+	return r
+}
+
+// escapeName escapes name for use as a segment of a URL path.
+func escapeName(name string) string {
+	return strings.Replace(url.QueryEscape(name), "+", "%20", -1)
+}
+
+// Namespace scopes the request to namespace, by appending ns/{namespace} to the request path.
+// Call it before the Path of the resource. An empty namespace leaves the path as it is, so the
+// server picks the namespace of the request.
+func (r *Request) Namespace(namespace string) *Request {
+	if r.err != nil || len(namespace) == 0 {
+		return r
+	}
+	r.path = path.Join(r.path, "ns", namespace)
+	return r
+}
+
 // Sync sets sync/async call status by setting the "sync" parameter to "true"/"false"
 func (r *Request) Sync(sync bool) *Request {
 	if r.err != nil {
@@ -155,6 +192,14 @@ func (r *Request) SelectorParam(paramName string, s labels.Selector) *Request {
 	return r.setParam(paramName, s.String())
 }
 
+// Param creates a query parameter with the given string value.
+func (r *Request) Param(paramName, value string) *Request {
+	if r.err != nil {
+		return r
+	}
+	return r.setParam(paramName, value)
+}
+
 // UintParam creates a query parameter with the given value.
 func (r *Request) UintParam(paramName string, u uint64) *Request {
 	if r.err != nil {
@@ -172,6 +217,17 @@ func (r *Request) setParam(paramName, value string) *Request {
 	return r
 }
 
+// Idempotent marks the request as safe to repeat, so that it is retried on failures like a
+// GET, according to the client's RetryPolicy. POST, PUT and DELETE requests are only retried
+// if they are marked.
+func (r *Request) Idempotent(idempotent bool) *Request {
+	if r.err != nil {
+		return r
+	}
+	r.idempotent = idempotent
+	return r
+}
+
 // Timeout makes the request use the given duration as a timeout. Sets the "timeout"
 // parameter. Ignored if sync=false.
 func (r *Request) Timeout(d time.Duration) *Request {
@@ -186,7 +242,8 @@ func (r *Request) Timeout(d time.Duration) *Request {
 // If obj is a string, try to read a file of that name.
 // If obj is a []byte, send it directly.
 // If obj is an io.Reader, use it directly.
-// Otherwise, assume obj is an api type and marshall it correctly.
+// Otherwise, assume obj is an api type and marshall it correctly, with api.GobCodec if the
+// client is Binary.
 func (r *Request) Body(obj interface{}) *Request {
 	if r.err != nil {
 		return r
@@ -204,12 +261,17 @@ func (r *Request) Body(obj interface{}) *Request {
 	case io.Reader:
 		r.body = t
 	default:
-		data, err := api.Encode(obj)
+		codec, contentType := api.Codec, api.JSONMediaType
+		if r.c.Binary {
+			codec, contentType = api.GobCodec, api.GobMediaType
+		}
+		data, err := codec.Encode(obj)
 		if err != nil {
 			r.err = err
 			return r
 		}
 		r.body = bytes.NewBuffer(data)
+		r.contentType = contentType
 	}
 	return r
 }
@@ -254,12 +316,10 @@ func (r *Request) Watch() (watch.Interface, error) {
 	if err != nil {
 		return nil, err
 	}
-	if r.c.auth != nil {
-		req.SetBasicAuth(r.c.auth.User, r.c.auth.Password)
-	}
+	r.c.setBasicAuth(req)
 	response, err := r.c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, &ConnectionError{Method: req.Method, Host: r.c.host, Path: req.URL.Path, Err: err}
 	}
 	if response.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("Got status: %v", response.StatusCode)
@@ -267,17 +327,49 @@ func (r *Request) Watch() (watch.Interface, error) {
 	return watch.NewStreamWatcher(tools.NewAPIEventDecoder(response.Body)), nil
 }
 
+// Stream formats and executes the request, and returns the response body unread, for
+// responses that aren't API objects, such as logs. The caller must close it. A response
+// with an error code is returned as an error, as by Do.
+func (r *Request) Stream() (io.ReadCloser, error) {
+	body, _, err := r.StreamResponse()
+	return body, err
+}
+
+// StreamResponse is like Stream, but also returns the headers of the response, e.g. for the
+// continue token of a list whose items are decoded as they arrive.
+func (r *Request) StreamResponse() (io.ReadCloser, http.Header, error) {
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+	req, err := http.NewRequest(r.verb, r.finalURL(), r.body)
+	if err != nil {
+		return nil, nil, err
+	}
+	r.c.setBasicAuth(req)
+	response, err := r.c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, &ConnectionError{Method: req.Method, Host: r.c.host, Path: req.URL.Path, Err: err}
+	}
+	if response.StatusCode < http.StatusOK || response.StatusCode > http.StatusPartialContent {
+		defer response.Body.Close()
+		body, _ := ioutil.ReadAll(response.Body)
+		var status api.Status
+		codec := api.CodecForMediaType(response.Header.Get("Content-Type"))
+		if err := codec.DecodeInto(body, &status); err == nil && status.Status != "" {
+			return nil, nil, &StatusErr{status}
+		}
+		return nil, nil, fmt.Errorf("request for %s failed (%d): %s", req.URL.Path, response.StatusCode, string(body))
+	}
+	return response.Body, response.Header, nil
+}
+
 // Do formats and executes the request. Returns the API object received, or an error.
 func (r *Request) Do() Result {
 	for {
 		if r.err != nil {
 			return Result{err: r.err}
 		}
-		req, err := http.NewRequest(r.verb, r.finalURL(), r.body)
-		if err != nil {
-			return Result{err: err}
-		}
-		respBody, err := r.c.doRequest(req)
+		respBody, header, err := r.doRequestWithRetries()
 		if err != nil {
 			if statusErr, ok := err.(*StatusErr); ok {
 				if statusErr.Status.Status == api.StatusWorking && r.pollPeriod != 0 {
@@ -296,17 +388,71 @@ func (r *Request) Do() Result {
 				}
 			}
 		}
-		return Result{respBody, err}
+		return Result{respBody, header, err}
+	}
+}
+
+// doRequestWithRetries executes the request, retrying it according to the client's
+// RetryPolicy if it is a GET or is marked Idempotent. The body is read once, and sent again
+// with each retry. The header of the answer is returned with its body.
+func (r *Request) doRequestWithRetries() ([]byte, http.Header, error) {
+	var body []byte
+	if r.body != nil {
+		data, err := ioutil.ReadAll(r.body)
+		if err != nil {
+			return nil, nil, err
+		}
+		body = data
+	}
+	policy := r.c.RetryPolicy
+	sleep := r.c.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	start := time.Now()
+	for retry := 0; ; retry++ {
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(body)
+		}
+		req, err := http.NewRequest(r.verb, r.finalURL(), reqBody)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(r.contentType) > 0 {
+			req.Header.Set("Content-Type", r.contentType)
+		}
+		if r.c.Binary {
+			req.Header.Set("Accept", api.GobMediaType+", "+api.JSONMediaType)
+		}
+		respBody, response, err := r.c.doRequestResponse(req)
+		header := http.Header{}
+		if response != nil {
+			header = response.Header
+		}
+		if retry >= policy.MaxRetries || (r.verb != "GET" && !r.idempotent) || !retryable(response, err) {
+			return respBody, header, err
+		}
+		delay := policy.delay(retry, response)
+		if policy.MaxElapsed > 0 && time.Since(start)+delay > policy.MaxElapsed {
+			return respBody, header, err
+		}
+		glog.Infof("Retrying in %v after error: %v", delay, err)
+		r.c.countRetry(response, err)
+		sleep(delay)
 	}
 }
 
 // Result contains the result of calling Request.Do().
 type Result struct {
 	body []byte
-	err  error
+	// header is the header of the response, whose Content-Type is the media type of body.
+	header http.Header
+	err    error
 }
 
-// Raw returns the raw result.
+// Raw returns the raw result, which is encoded with api.GobCodec rather than JSON if the
+// client is Binary and the server speaks it.
 func (r Result) Raw() ([]byte, error) {
 	return r.body, r.err
 }
@@ -316,7 +462,7 @@ func (r Result) Get() (interface{}, error) {
 	if r.err != nil {
 		return nil, r.err
 	}
-	return api.Decode(r.body)
+	return api.CodecForMediaType(r.header.Get("Content-Type")).Decode(r.body)
 }
 
 // Into stores the result into obj, if possible..
@@ -324,7 +470,24 @@ func (r Result) Into(obj interface{}) error {
 	if r.err != nil {
 		return r.err
 	}
-	return api.DecodeInto(r.body, obj)
+	return api.CodecForMediaType(r.header.Get("Content-Type")).DecodeInto(r.body, obj)
+}
+
+// Header returns the header of the response, which is empty if the server could not be
+// reached.
+func (r Result) Header() http.Header {
+	if r.header == nil {
+		return http.Header{}
+	}
+	return r.header
+}
+
+// RetryAfter returns how long the server asked to wait, with a Retry-After header, before
+// making the request again, such as before polling an operation still in progress, and false
+// if it didn't ask.
+func (r Result) RetryAfter() (time.Duration, bool) {
+	d := parseRetryAfter(r.Header().Get("Retry-After"), time.Now())
+	return d, d >= 0
 }
 
 // Returns the error executing the request, nil if no error occurred.
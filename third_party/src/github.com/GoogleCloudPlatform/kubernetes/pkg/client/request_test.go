@@ -20,11 +20,14 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -150,6 +153,60 @@ func TestDoRequestNewWayObj(t *testing.T) {
 	}
 }
 
+func TestBinaryRequest(t *testing.T) {
+	reqObj := &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
+	expectedObj := &api.Service{Port: 12345}
+	// A binary client understands servers answering in either encoding.
+	for _, answerType := range []string{api.GobMediaType, api.JSONMediaType} {
+		var received *http.Request
+		var receivedObj api.Pod
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			received = req
+			body, _ := ioutil.ReadAll(req.Body)
+			if err := api.GobCodec.DecodeInto(body, &receivedObj); err != nil {
+				t.Errorf("%s: unexpected error: %v", answerType, err)
+			}
+			data, _ := api.CodecForMediaType(answerType).Encode(expectedObj)
+			w.Header().Set("Content-Type", answerType)
+			w.Write(data)
+		}))
+		c := New(testServer.URL, nil)
+		c.Binary = true
+		obj, err := c.Post().Path("foo").Body(reqObj).Do().Get()
+		testServer.Close()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", answerType, err)
+			continue
+		}
+		if !reflect.DeepEqual(obj, expectedObj) {
+			t.Errorf("%s: expected %#v, got %#v", answerType, expectedObj, obj)
+		}
+		if !reflect.DeepEqual(&receivedObj, reqObj) {
+			t.Errorf("%s: expected the server to receive %#v, got %#v", answerType, reqObj, receivedObj)
+		}
+		if received.Header.Get("Content-Type") != api.GobMediaType || !strings.HasPrefix(received.Header.Get("Accept"), api.GobMediaType) {
+			t.Errorf("%s: unexpected headers %#v", answerType, received.Header)
+		}
+	}
+}
+
+func TestBinaryRequestStatus(t *testing.T) {
+	status := &api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeConflict}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, _ := api.GobCodec.Encode(status)
+		w.Header().Set("Content-Type", api.GobMediaType)
+		w.WriteHeader(http.StatusConflict)
+		w.Write(data)
+	}))
+	defer testServer.Close()
+	c := New(testServer.URL, nil)
+	c.Binary = true
+	err := c.Get().Path("foo").Do().Error()
+	if statusErr, ok := err.(*StatusErr); !ok || !reflect.DeepEqual(&statusErr.Status, status) {
+		t.Errorf("expected the status to be decoded, got %#v", err)
+	}
+}
+
 func TestDoRequestNewWayFile(t *testing.T) {
 	reqObj := &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
 	reqBodyExpected, err := api.Encode(reqObj)
@@ -228,6 +285,16 @@ func TestAbsPath(t *testing.T) {
 	}
 }
 
+func TestNamespace(t *testing.T) {
+	c := New("", nil)
+	if r := c.Get().Namespace("a").Path("pods").Path("foo"); r.path != "/api/v1beta1/ns/a/pods/foo" {
+		t.Errorf("unexpected path: %s", r.path)
+	}
+	if r := c.Get().Namespace("").Path("pods"); r.path != "/api/v1beta1/pods" {
+		t.Errorf("unexpected path: %s", r.path)
+	}
+}
+
 func TestSync(t *testing.T) {
 	c := New("", nil)
 	r := c.Get()
@@ -412,7 +479,7 @@ func TestWatch(t *testing.T) {
 
 		encoder := json.NewEncoder(w)
 		for _, item := range table {
-			encoder.Encode(&api.WatchEvent{item.t, api.APIObject{item.obj}})
+			encoder.Encode(&api.WatchEvent{Type: item.t, Object: api.APIObject{item.obj}})
 			flusher.Flush()
 		}
 	}))
@@ -442,3 +509,149 @@ func TestWatch(t *testing.T) {
 		t.Fatal("Unexpected non-close")
 	}
 }
+
+// flakyHandler fails the first 'failures' requests: it answers them with 'code' and the
+// 'retryAfter' header if code is set, and drops the connection otherwise.
+type flakyHandler struct {
+	failures   int
+	code       int
+	retryAfter string
+	t          *testing.T
+
+	lock     sync.Mutex
+	requests int
+	bodies   []string
+}
+
+func (f *flakyHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	f.lock.Lock()
+	f.requests++
+	f.bodies = append(f.bodies, string(body))
+	fail := f.requests <= f.failures
+	f.lock.Unlock()
+	if fail && f.code != 0 {
+		if len(f.retryAfter) > 0 {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		w.WriteHeader(f.code)
+		w.Write([]byte(`{"kind": "Status", "status": "failure"}`))
+		return
+	}
+	if fail {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			f.t.Fatalf("unexpected error: %v", err)
+		}
+		conn.Close()
+		return
+	}
+	w.Write([]byte(`{"kind": "Status", "status": "success"}`))
+}
+
+// count returns the number of requests received so far.
+func (f *flakyHandler) count() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.requests
+}
+
+func TestRetryGET(t *testing.T) {
+	handler := &flakyHandler{failures: 2, t: t}
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+	c := New(testServer.URL, nil)
+	c.RetryPolicy.MaxRetries = 2
+	c.RetryPolicy.Backoff = time.Millisecond
+	if err := c.Get().Path("pods").Do().Error(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if handler.count() != 3 {
+		t.Errorf("expected 3 requests, got %d", handler.count())
+	}
+}
+
+func TestRetryGETExhausted(t *testing.T) {
+	handler := &flakyHandler{failures: 3, t: t}
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+	c := New(testServer.URL, nil)
+	c.RetryPolicy.MaxRetries = 1
+	c.RetryPolicy.Backoff = time.Millisecond
+	err := c.Get().Path("pods").Do().Error()
+	connErr, ok := err.(*ConnectionError)
+	if !ok {
+		t.Fatalf("expected a connection error, got %#v", err)
+	}
+	if !strings.Contains(connErr.Error(), testServer.URL) || !strings.Contains(connErr.Error(), "/api/v1beta1/pods") {
+		t.Errorf("expected host and path in error, got %v", connErr)
+	}
+	if handler.count() != 2 {
+		t.Errorf("expected 2 requests, got %d", handler.count())
+	}
+}
+
+func TestNoRetryPOST(t *testing.T) {
+	handler := &flakyHandler{failures: 1, t: t}
+	testServer := httptest.NewServer(handler)
+	defer testServer.Close()
+	c := New(testServer.URL, nil)
+	c.RetryPolicy.MaxRetries = 3
+	c.RetryPolicy.Backoff = time.Millisecond
+	if err := c.Post().Path("pods").Body([]byte("{}")).Do().Error(); err == nil {
+		t.Errorf("expected error")
+	}
+	if handler.count() != 1 {
+		t.Errorf("expected 1 request, got %d", handler.count())
+	}
+}
+
+func TestConnectionErrorHint(t *testing.T) {
+	table := []struct {
+		host string
+		err  string
+		hint string
+	}{
+		{"https://localhost:8080", "http: server gave HTTP response to HTTPS client", "http://"},
+		{"http://localhost:8443", "malformed HTTP response \"\\x15\\x03\\x01\"", "https://"},
+		{"http://localhost:8443", "EOF", "https://"},
+		{"http://localhost:8080", "dial tcp 127.0.0.1:8080: connection refused", "server running"},
+		{"https://localhost:8443", "EOF", ""},
+	}
+	for _, item := range table {
+		err := &ConnectionError{Method: "GET", Host: item.host, Path: "/api/v1beta1/pods", Err: errors.New(item.err)}
+		hint := err.hint()
+		if (item.hint == "") != (hint == "") || !strings.Contains(hint, item.hint) {
+			t.Errorf("expected hint containing %q for %s %q, got %q", item.hint, item.host, item.err, hint)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/api/v1beta1/builds/foo/log" {
+			fmt.Fprintf(w, "follow=%s", req.URL.Query().Get("follow"))
+			return
+		}
+		w.WriteHeader(http.StatusGone)
+		w.Write([]byte(api.EncodeOrDie(&api.Status{Status: api.StatusFailure, Code: http.StatusGone, Message: "gone"})))
+	}))
+	defer server.Close()
+	c := New(server.URL, nil)
+
+	body, err := c.Get().Path("builds").Path("foo").Path("log").Param("follow", "true").Stream()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := ioutil.ReadAll(body)
+	body.Close()
+	if string(data) != "follow=true" {
+		t.Errorf("unexpected body: %s", data)
+	}
+
+	_, err = c.Get().Path("builds").Path("bar").Path("log").Stream()
+	statusErr, ok := err.(*StatusErr)
+	if !ok || statusErr.Status.Code != http.StatusGone {
+		t.Errorf("expected a gone StatusErr, got %#v", err)
+	}
+}
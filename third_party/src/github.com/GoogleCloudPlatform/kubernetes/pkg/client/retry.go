@@ -0,0 +1,138 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides which failed requests a Client retries, and how long it waits before
+// each retry. Requests that fail to reach the server, and requests answered with 429 or a
+// 5xx other than 501, are retried if they are GETs or were marked Idempotent. POST, PUT and
+// DELETE requests are never retried otherwise, since they may already have taken effect.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried after its first attempt.
+	MaxRetries int
+	// Backoff is the delay before the first retry. It doubles after each retry, and up to
+	// half of it is added at random, so that clients don't retry in lockstep.
+	Backoff time.Duration
+	// MaxBackoff caps the delay before a retry, including the delay the server asks for in
+	// the Retry-After header of a 429 or 503 response. Zero means no cap.
+	MaxBackoff time.Duration
+	// MaxElapsed caps the time from the first attempt to the end of the last delay; a retry
+	// that would start later is not made. Zero means no cap.
+	MaxElapsed time.Duration
+}
+
+// RetryStats counts the retries a Client has performed, by their cause.
+type RetryStats struct {
+	// ConnectionErrors counts retries of requests that failed to reach the server.
+	ConnectionErrors uint64
+	// Throttled counts retries of requests answered with 429 or 503.
+	Throttled uint64
+	// ServerErrors counts retries of requests answered with other 5xx codes.
+	ServerErrors uint64
+}
+
+// Total returns the number of retries s counts.
+func (s RetryStats) Total() uint64 {
+	return s.ConnectionErrors + s.Throttled + s.ServerErrors
+}
+
+// RetryStats returns the retries c has performed so far.
+func (c *Client) RetryStats() RetryStats {
+	c.retryLock.Lock()
+	defer c.retryLock.Unlock()
+	return c.retryStats
+}
+
+// countRetry records a retry of a request that failed with err, after the server answered
+// with response if it was reached.
+func (c *Client) countRetry(response *http.Response, err error) {
+	c.retryLock.Lock()
+	defer c.retryLock.Unlock()
+	switch {
+	case response == nil:
+		c.retryStats.ConnectionErrors++
+	case throttled(response):
+		c.retryStats.Throttled++
+	default:
+		c.retryStats.ServerErrors++
+	}
+}
+
+// retryable returns true if a request that failed with err, after the server answered with
+// response if it was reached, may succeed when retried.
+func retryable(response *http.Response, err error) bool {
+	if _, ok := err.(*ConnectionError); ok {
+		return true
+	}
+	if err == nil || response == nil {
+		return false
+	}
+	code := response.StatusCode
+	return code == http.StatusTooManyRequests || (code >= 500 && code != http.StatusNotImplemented)
+}
+
+// throttled returns true if response asks the client to come back later.
+func throttled(response *http.Response) bool {
+	return response.StatusCode == http.StatusTooManyRequests || response.StatusCode == http.StatusServiceUnavailable
+}
+
+// delay returns how long to wait before retry number retry, counting from 0, of a request
+// the server answered with response, if it was reached. The Retry-After header of a 429 or
+// 503 response takes precedence over the backoff.
+func (p RetryPolicy) delay(retry int, response *http.Response) time.Duration {
+	d := time.Duration(-1)
+	if response != nil && throttled(response) {
+		d = parseRetryAfter(response.Header.Get("Retry-After"), time.Now())
+	}
+	if d < 0 {
+		d = p.Backoff << uint(retry)
+		if d > 0 {
+			d += time.Duration(rand.Int63n(int64(d)/2 + 1))
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+// parseRetryAfter returns the delay a Retry-After header value asks for, given in seconds or
+// as an HTTP date, or -1 if value is empty or malformed.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if len(value) == 0 {
+		return -1
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return -1
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if d := date.Sub(now); d > 0 {
+			return d
+		}
+		return 0
+	}
+	return -1
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newRetryingClient returns a client of server that retries up to retries times, recording
+// the delays it would wait instead of waiting.
+func newRetryingClient(server *httptest.Server, retries int, delays *[]time.Duration) *Client {
+	c := New(server.URL, nil)
+	c.RetryPolicy = RetryPolicy{MaxRetries: retries, Backoff: time.Millisecond}
+	c.sleep = func(d time.Duration) { *delays = append(*delays, d) }
+	return c
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	handler := &flakyHandler{failures: 2, code: http.StatusBadGateway, t: t}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	var delays []time.Duration
+	c := newRetryingClient(server, 3, &delays)
+
+	if err := c.Get().Path("pods").Do().Error(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if handler.count() != 3 {
+		t.Errorf("expected 3 requests, got %d", handler.count())
+	}
+	if len(delays) != 2 || delays[0] < time.Millisecond || delays[0] > 3*time.Millisecond/2 ||
+		delays[1] < 2*time.Millisecond || delays[1] > 3*time.Millisecond {
+		t.Errorf("expected jittered exponential delays, got %v", delays)
+	}
+	if stats := c.RetryStats(); stats.ServerErrors != 2 || stats.Total() != 2 {
+		t.Errorf("unexpected retry stats %#v", stats)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	for _, code := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		handler := &flakyHandler{failures: 1, code: code, retryAfter: "7", t: t}
+		server := httptest.NewServer(handler)
+		var delays []time.Duration
+		c := newRetryingClient(server, 1, &delays)
+
+		if err := c.Get().Path("pods").Do().Error(); err != nil {
+			t.Errorf("%d: unexpected error: %v", code, err)
+		}
+		if len(delays) != 1 || delays[0] != 7*time.Second {
+			t.Errorf("%d: expected to wait as asked, got %v", code, delays)
+		}
+		if stats := c.RetryStats(); stats.Throttled != 1 {
+			t.Errorf("%d: unexpected retry stats %#v", code, stats)
+		}
+		server.Close()
+	}
+}
+
+func TestRetryCaps(t *testing.T) {
+	handler := &flakyHandler{failures: 5, code: http.StatusServiceUnavailable, retryAfter: "60", t: t}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	var delays []time.Duration
+	c := newRetryingClient(server, 10, &delays)
+	c.RetryPolicy.MaxBackoff = time.Second
+	c.RetryPolicy.MaxElapsed = time.Minute
+
+	if err := c.Get().Path("pods").Do().Error(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, d := range delays {
+		if d != time.Second {
+			t.Errorf("expected delays capped at a second, got %v", delays)
+		}
+	}
+
+	handler = &flakyHandler{failures: 5, code: http.StatusServiceUnavailable, retryAfter: "60", t: t}
+	server = httptest.NewServer(handler)
+	defer server.Close()
+	delays = nil
+	c = newRetryingClient(server, 10, &delays)
+	c.RetryPolicy.MaxElapsed = time.Minute
+	if err := c.Get().Path("pods").Do().Error(); err == nil {
+		t.Errorf("expected an error")
+	}
+	if handler.count() != 1 || len(delays) != 0 {
+		t.Errorf("expected no retry past the elapsed time cap, got %d requests", handler.count())
+	}
+
+	handler = &flakyHandler{failures: 5, code: http.StatusInternalServerError, t: t}
+	server = httptest.NewServer(handler)
+	defer server.Close()
+	delays = nil
+	c = newRetryingClient(server, 2, &delays)
+	if err := c.Get().Path("pods").Do().Error(); err == nil {
+		t.Errorf("expected an error")
+	}
+	if handler.count() != 3 {
+		t.Errorf("expected the attempts to be capped, got %d requests", handler.count())
+	}
+}
+
+func TestNoRetryOfWritesUnlessIdempotent(t *testing.T) {
+	for _, verb := range []string{"POST", "PUT", "DELETE"} {
+		handler := &flakyHandler{failures: 1, code: http.StatusServiceUnavailable, t: t}
+		server := httptest.NewServer(handler)
+		var delays []time.Duration
+		c := newRetryingClient(server, 3, &delays)
+		if err := c.Verb(verb).Path("pods").Body([]byte("{}")).Do().Error(); err == nil {
+			t.Errorf("%s: expected error", verb)
+		}
+		if handler.count() != 1 {
+			t.Errorf("%s: expected 1 request, got %d", verb, handler.count())
+		}
+		server.Close()
+	}
+
+	handler := &flakyHandler{failures: 1, code: http.StatusServiceUnavailable, t: t}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+	var delays []time.Duration
+	c := newRetryingClient(server, 3, &delays)
+	if err := c.Put().Path("pods/foo").Body([]byte(`{"id": "foo"}`)).Idempotent(true).Do().Error(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if handler.count() != 2 || handler.bodies[0] != `{"id": "foo"}` || handler.bodies[1] != handler.bodies[0] {
+		t.Errorf("expected the body to be sent again, got %q", handler.bodies)
+	}
+}
+
+func TestNoRetryOfClientErrors(t *testing.T) {
+	for _, code := range []int{http.StatusNotFound, http.StatusConflict, http.StatusNotImplemented} {
+		handler := &flakyHandler{failures: 1, code: code, t: t}
+		server := httptest.NewServer(handler)
+		var delays []time.Duration
+		c := newRetryingClient(server, 3, &delays)
+		if err := c.Get().Path("pods").Do().Error(); err == nil {
+			t.Errorf("%d: expected error", code)
+		}
+		if handler.count() != 1 {
+			t.Errorf("%d: expected 1 request, got %d", code, handler.count())
+		}
+		server.Close()
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2014, 7, 1, 12, 0, 0, 0, time.UTC)
+	table := map[string]time.Duration{
+		"":                              -1,
+		"3":                             3 * time.Second,
+		"-3":                            -1,
+		"soon":                          -1,
+		"Tue, 01 Jul 2014 12:00:30 GMT": 30 * time.Second,
+		"Tue, 01 Jul 2014 11:00:00 GMT": 0,
+	}
+	for value, expected := range table {
+		if d := parseRetryAfter(value, now); d != expected {
+			t.Errorf("%q: expected %v, got %v", value, expected, d)
+		}
+	}
+}
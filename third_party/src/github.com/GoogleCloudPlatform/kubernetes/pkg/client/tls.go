@@ -0,0 +1,51 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+)
+
+// TLSConfig builds the TLS configuration described by the certificate settings of a.
+func (a *AuthInfo) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: a.Insecure}
+	if len(a.CertFile) > 0 || len(a.KeyFile) > 0 {
+		if len(a.CertFile) == 0 || len(a.KeyFile) == 0 {
+			return nil, fmt.Errorf("a client certificate requires both a certificate file and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate %s with key %s: %v", a.CertFile, a.KeyFile, err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	if len(a.CAFile) > 0 {
+		data, err := ioutil.ReadFile(a.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read certificate authority %s: %v", a.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("unable to load certificate authority %s: no PEM encoded certificates found", a.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeServerCert writes the certificate and key of a TLS test server to dir.
+func writeServerCert(t *testing.T, server *httptest.Server, dir string) (certFile, keyFile string) {
+	cert := server.TLS.Certificates[0]
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return
+}
+
+func TestNewTLSClientCertificate(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if len(req.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"kind": "PodList"}`))
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeServerCert(t, server, dir)
+
+	// The test server's certificate doubles as the client certificate and the CA.
+	c, err := NewTLS(server.URL, &AuthInfo{CertFile: certFile, KeyFile: keyFile, CAFile: certFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get().Path("pods").Do().Error(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	c, err = NewTLS(server.URL, &AuthInfo{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get().Path("pods").Do().Error(); err == nil {
+		t.Errorf("expected an unknown certificate authority to be rejected")
+	}
+
+	c, err = NewTLS(server.URL, &AuthInfo{CertFile: certFile, KeyFile: keyFile, Insecure: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get().Path("pods").Do().Error(); err != nil {
+		t.Errorf("unexpected error with verification disabled: %v", err)
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := ioutil.WriteFile(notPEM, []byte("not a certificate"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	missing := filepath.Join(dir, "missing.pem")
+
+	table := []struct {
+		auth AuthInfo
+		file string
+	}{
+		{AuthInfo{CertFile: missing, KeyFile: missing}, missing},
+		{AuthInfo{CAFile: missing}, missing},
+		{AuthInfo{CAFile: notPEM}, notPEM},
+		{AuthInfo{CertFile: missing}, "key file"},
+	}
+	for _, item := range table {
+		_, err := item.auth.TLSConfig()
+		if err == nil {
+			t.Errorf("expected error for %#v", item.auth)
+			continue
+		}
+		if !strings.Contains(err.Error(), item.file) {
+			t.Errorf("expected error to mention %s, got %v", item.file, err)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// TraceBodyLimit is the number of bytes of a body a TraceTransport prints unless it prints
+// full bodies.
+const TraceBodyLimit = 512
+
+// redacted replaces the credentials a TraceTransport doesn't print.
+const redacted = "<redacted>"
+
+// redactedHeaders are the headers whose values a TraceTransport doesn't print.
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// passwordField matches the password fields of JSON bodies, with their name as the first
+// group.
+var passwordField = regexp.MustCompile(`("(?i:password|passwd)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// TraceTransport is an http.RoundTripper that prints each request it passes to Transport and
+// the response to it: the request line or status, the headers and the body, truncated to
+// TraceBodyLimit bytes unless FullBodies is set. Credentials are redacted: the values of the
+// Authorization and cookie headers, and the password fields of bodies.
+type TraceTransport struct {
+	Transport  http.RoundTripper
+	Out        io.Writer
+	FullBodies bool
+
+	// lock keeps the traces of concurrent requests from interleaving.
+	lock sync.Mutex
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		body = data
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	}
+	trace := &bytes.Buffer{}
+	fmt.Fprintf(trace, "> %s %s %s\n", req.Method, req.URL, req.Proto)
+	t.writeHeader(trace, ">", req.Header)
+	t.writeBody(trace, ">", body, false)
+	t.print(trace.Bytes())
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		t.print([]byte(fmt.Sprintf("< %v\n", err)))
+		return nil, err
+	}
+	trace = &bytes.Buffer{}
+	fmt.Fprintf(trace, "< %s %s\n", resp.Proto, resp.Status)
+	t.writeHeader(trace, "<", resp.Header)
+	t.print(trace.Bytes())
+	// The response body is printed as it is read, once it is closed, so that watches and
+	// other streams reach the caller as they arrive.
+	resp.Body = &tracedBody{ReadCloser: resp.Body, t: t}
+	return resp, nil
+}
+
+// writeHeader writes header to w, sorted by name, with each line prefixed by prefix.
+func (t *TraceTransport) writeHeader(w io.Writer, prefix string, header http.Header) {
+	names := []string{}
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			if redactedHeaders[name] {
+				value = redacted
+			}
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, name, value)
+		}
+	}
+}
+
+// writeBody writes body to w, redacted and truncated unless t prints full bodies. truncated
+// is true if body is already missing some of its end.
+func (t *TraceTransport) writeBody(w io.Writer, prefix string, body []byte, truncated bool) {
+	if len(body) == 0 {
+		return
+	}
+	body = passwordField.ReplaceAll(body, []byte(`$1"`+redacted+`"`))
+	if !t.FullBodies && len(body) > TraceBodyLimit {
+		body, truncated = body[:TraceBodyLimit], true
+	}
+	fmt.Fprintf(w, "%s\n%s\n", prefix, body)
+	if truncated {
+		fmt.Fprintf(w, "%s (truncated)\n", prefix)
+	}
+}
+
+func (t *TraceTransport) print(trace []byte) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.Out.Write(trace)
+}
+
+// tracedBody is the body of a response of a TraceTransport, which keeps what is read of it
+// to print it when it is closed.
+type tracedBody struct {
+	io.ReadCloser
+	t         *TraceTransport
+	read      bytes.Buffer
+	truncated bool
+	closed    bool
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	keep := n
+	// Keep enough to redact a password field across the limit before truncating.
+	if limit := 2 * TraceBodyLimit; !b.t.FullBodies && b.read.Len()+keep > limit {
+		keep = limit - b.read.Len()
+		if keep < 0 {
+			keep = 0
+		}
+		b.truncated = true
+	}
+	b.read.Write(p[:keep])
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	if !b.closed {
+		b.closed = true
+		trace := &bytes.Buffer{}
+		b.t.writeBody(trace, "<", b.read.Bytes(), b.truncated)
+		b.t.print(trace.Bytes())
+	}
+	return b.ReadCloser.Close()
+}
+
+// Trace makes c print each request it sends and the response to it to out, with full bodies
+// if fullBodies is set, as TraceTransport does.
+func (c *Client) Trace(out io.Writer, fullBodies bool) {
+	transport := c.httpClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	c.httpClient.Transport = &TraceTransport{Transport: transport, Out: out, FullBodies: fullBodies}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceRedactsCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Write([]byte(`{"kind": "Status", "status": "success", "password" : "p\"w"}`))
+	}))
+	defer server.Close()
+	c := New(server.URL, &AuthInfo{User: "user", Password: "hunter2"})
+	out := &bytes.Buffer{}
+	c.Trace(out, false)
+
+	if _, err := c.Post().Path("minions").Body([]byte(`{"id": "m1", "Password": "hunter2"}`)).Do().Raw(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	trace := out.String()
+	for _, expected := range []string{
+		"> POST " + server.URL + "/api/v1beta1/minions",
+		"> Authorization: <redacted>\n",
+		`{"id": "m1", "Password": "<redacted>"}`,
+		"< HTTP/1.1 200 OK\n",
+		"< Set-Cookie: <redacted>\n",
+		`"password" : "<redacted>"}`,
+	} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("expected %q in the trace, got:\n%s", expected, trace)
+		}
+	}
+	for _, secret := range []string{"hunter2", "secret", `p\"w`, "dXNlcjpodW50ZXIy"} {
+		if strings.Contains(trace, secret) {
+			t.Errorf("expected %q to be redacted, got:\n%s", secret, trace)
+		}
+	}
+}
+
+func TestTraceTruncatesBodies(t *testing.T) {
+	body := strings.Repeat("x", 3*TraceBodyLimit)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	for _, full := range []bool{false, true} {
+		c := New(server.URL, nil)
+		out := &bytes.Buffer{}
+		c.Trace(out, full)
+		data, err := c.Get().Path("pods").Do().Raw()
+		if err != nil || string(data) != body {
+			t.Fatalf("expected the body to reach the caller whole, got %d bytes: %v", len(data), err)
+		}
+		trace := out.String()
+		if truncated := strings.Contains(trace, "< (truncated)\n"); truncated == full {
+			t.Errorf("full=%v: unexpected trace:\n%s", full, trace)
+		}
+		if full && !strings.Contains(trace, body) {
+			t.Errorf("expected the full body in the trace, got:\n%s", trace)
+		}
+		if !full && !strings.Contains(trace, "<\n"+body[:TraceBodyLimit]+"\n") {
+			t.Errorf("expected the start of the body in the trace, got:\n%s", trace)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// Watcher delivers the events of a watch opened by Client.Watch as typed objects. It
+// implements watch.Interface. The result channel is closed when the server ends the stream,
+// when the stream fails or when Stop is called; Err then tells the cases apart. A Watcher
+// does not reconnect; callers that want to resume should open a new watch from
+// ResourceVersion()+1.
+type Watcher struct {
+	stream  io.ReadCloser
+	decoder *json.Decoder
+	result  chan watch.Event
+	done    chan struct{}
+
+	lock            sync.Mutex
+	stopped         bool
+	err             error
+	resourceVersion uint64
+}
+
+// Watch watches resource for objects matching selector, starting after resourceVersion, or
+// with the current state of the resource if it is 0.
+func (c *Client) Watch(resource string, selector labels.Selector, resourceVersion uint64) (*Watcher, error) {
+	stream, err := c.Get().
+		Path("watch").
+		Path(resource).
+		UintParam("resourceVersion", resourceVersion).
+		SelectorParam("labels", selector).
+		Stream()
+	if err != nil {
+		return nil, err
+	}
+	return newWatcher(stream, resourceVersion), nil
+}
+
+// newWatcher starts decoding the api.WatchEvents of stream.
+func newWatcher(stream io.ReadCloser, resourceVersion uint64) *Watcher {
+	w := &Watcher{
+		stream:          stream,
+		decoder:         json.NewDecoder(stream),
+		result:          make(chan watch.Event),
+		done:            make(chan struct{}),
+		resourceVersion: resourceVersion,
+	}
+	go w.receive()
+	return w
+}
+
+// ResultChan implements watch.Interface.
+func (w *Watcher) ResultChan() <-chan watch.Event {
+	return w.result
+}
+
+// Stop implements watch.Interface. It closes the stream, after which Err returns nil.
+func (w *Watcher) Stop() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.stopped {
+		w.stopped = true
+		close(w.done)
+		w.stream.Close()
+	}
+}
+
+// Err returns the error that ended the watch, once the result channel is closed. It is nil
+// if the server ended the stream cleanly or Stop was called. An error frame sent by the
+// server is returned as a *StatusErr.
+func (w *Watcher) Err() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.err
+}
+
+// ResourceVersion returns the resource version of the last object received, or the version
+// the watch was started from if none has been.
+func (w *Watcher) ResourceVersion() uint64 {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.resourceVersion
+}
+
+// receive decodes events from the stream and sends them down the result channel until the
+// stream ends or fails.
+func (w *Watcher) receive() {
+	defer close(w.result)
+	defer w.Stop()
+	defer util.HandleCrash()
+	for {
+		event, err := w.decode()
+		if err != nil {
+			w.fail(err)
+			return
+		}
+		select {
+		case w.result <- event:
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// decode reads the next event from the stream, recording its resource version.
+func (w *Watcher) decode() (watch.Event, error) {
+	var got api.WatchEvent
+	if err := w.decoder.Decode(&got); err != nil {
+		return watch.Event{}, err
+	}
+	switch got.Type {
+	case watch.Added, watch.Modified, watch.Deleted:
+	case watch.Error:
+		if status, ok := got.Object.Object.(*api.Status); ok {
+			return watch.Event{}, &StatusErr{*status}
+		}
+		return watch.Event{}, fmt.Errorf("watch failed: %#v", got.Object.Object)
+	default:
+		return watch.Event{}, fmt.Errorf("got invalid watch event type: %v", got.Type)
+	}
+	version := got.ResourceVersion
+	if version == 0 {
+		// Servers that don't send the version of events have it in the object.
+		version, _ = api.ResourceVersioner.ResourceVersion(got.Object.Object)
+	}
+	if version != 0 {
+		w.lock.Lock()
+		w.resourceVersion = version
+		w.lock.Unlock()
+	}
+	return watch.Event{Type: got.Type, Object: got.Object.Object}, nil
+}
+
+// fail records err as the reason the watch ended, unless it was stopped or the stream ended.
+func (w *Watcher) fail(err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if !w.stopped && err != io.EOF {
+		w.err = err
+	}
+}
@@ -0,0 +1,174 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// watchServer returns a server that streams events as api.WatchEvents and then ends the
+// response, recording the request it received.
+func watchServer(t *testing.T, events []api.WatchEvent, received **http.Request) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = r
+		flusher := w.(http.Flusher)
+		w.Header().Set("Transfer-Encoding", "chunked")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		encoder := json.NewEncoder(w)
+		for i := range events {
+			if err := encoder.Encode(&events[i]); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			flusher.Flush()
+		}
+	}))
+}
+
+func TestClientWatch(t *testing.T) {
+	events := []api.WatchEvent{
+		{Type: watch.Added, Object: api.APIObject{&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 3}}}},
+		{Type: watch.Modified, Object: api.APIObject{&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 5}}}},
+		{Type: watch.Deleted, Object: api.APIObject{&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 6}}}},
+	}
+	var received *http.Request
+	server := watchServer(t, events, &received)
+	defer server.Close()
+
+	c := New(server.URL, nil)
+	w, err := c.Watch("pods", labels.Set{"name": "foo"}.AsSelector(), 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, expected := range events {
+		got, ok := <-w.ResultChan()
+		if !ok {
+			t.Fatalf("unexpected close")
+		}
+		if got.Type != expected.Type || !reflect.DeepEqual(got.Object, expected.Object.Object) {
+			t.Errorf("expected %#v, got %#v", expected, got)
+		}
+	}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Errorf("expected the result channel to be closed")
+	}
+	if err := w.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if e, a := uint64(6), w.ResourceVersion(); e != a {
+		t.Errorf("expected resource version %d, got %d", e, a)
+	}
+
+	if e, a := "/api/v1beta1/watch/pods", received.URL.Path; e != a {
+		t.Errorf("expected path %s, got %s", e, a)
+	}
+	query := received.URL.Query()
+	if query.Get("labels") != "name=foo" || query.Get("resourceVersion") != "2" {
+		t.Errorf("unexpected query: %v", query)
+	}
+}
+
+func TestClientWatchErrorFrame(t *testing.T) {
+	events := []api.WatchEvent{
+		{Type: watch.Added, Object: api.APIObject{&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 3}}}},
+		{Type: watch.Error, Object: api.APIObject{&api.Status{Status: api.StatusFailure, Message: "etcd went away"}}},
+		{Type: watch.Added, Object: api.APIObject{&api.Pod{JSONBase: api.JSONBase{ID: "bar", ResourceVersion: 4}}}},
+	}
+	var received *http.Request
+	server := watchServer(t, events, &received)
+	defer server.Close()
+
+	w, err := New(server.URL, nil).Watch("pods", labels.Everything(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []watch.Event
+	for event := range w.ResultChan() {
+		got = append(got, event)
+	}
+	if len(got) != 1 {
+		t.Errorf("expected only the event before the error frame, got %#v", got)
+	}
+	statusErr, ok := w.Err().(*StatusErr)
+	if !ok || statusErr.Status.Message != "etcd went away" {
+		t.Errorf("expected the status of the error frame, got %#v", w.Err())
+	}
+	if e, a := uint64(3), w.ResourceVersion(); e != a {
+		t.Errorf("expected resource version %d, got %d", e, a)
+	}
+}
+
+func TestClientWatchMalformedFrame(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"type":"ADDED","object":`))
+	}))
+	defer server.Close()
+
+	w, err := New(server.URL, nil).Watch("pods", labels.Everything(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Errorf("expected the result channel to be closed")
+	}
+	if w.Err() == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestClientWatchStop(t *testing.T) {
+	block := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-block
+	}))
+	defer server.Close()
+	// Release the handler before the server waits for it to finish.
+	defer close(block)
+
+	w, err := New(server.URL, nil).Watch("pods", labels.Everything(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w.Stop()
+	if _, ok := <-w.ResultChan(); ok {
+		t.Errorf("expected the result channel to be closed")
+	}
+	if err := w.Err(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClientWatchRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	if _, err := New(server.URL, nil).Watch("unknown", labels.Everything(), 0); err == nil {
+		t.Errorf("expected an error")
+	}
+}
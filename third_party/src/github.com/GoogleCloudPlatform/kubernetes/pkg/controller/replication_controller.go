@@ -89,6 +89,8 @@ func (rm *ReplicationManager) Run(period time.Duration) {
 	rm.syncTime = time.Tick(period)
 	resourceVersion := uint64(0)
 	go util.Forever(func() { rm.watchControllers(&resourceVersion) }, period)
+	podResourceVersion := uint64(0)
+	go util.Forever(func() { rm.watchPods(&podResourceVersion) }, period)
 }
 
 // resourceVersion is a pointer to the resource version to use/update.
@@ -127,6 +129,63 @@ func (rm *ReplicationManager) watchControllers(resourceVersion *uint64) {
 	}
 }
 
+// watchPods recounts the replicas of the controllers selecting a pod whenever it changes.
+// resourceVersion is a pointer to the resource version to use/update.
+func (rm *ReplicationManager) watchPods(resourceVersion *uint64) {
+	watching, err := rm.kubeClient.WatchPods(labels.Everything(), labels.Everything(), *resourceVersion)
+	if err != nil {
+		glog.Errorf("Unexpected failure to watch pods: %v", err)
+		time.Sleep(5 * time.Second)
+		return
+	}
+	for event := range watching.ResultChan() {
+		pod, ok := event.Object.(*api.Pod)
+		if !ok {
+			glog.Errorf("unexpected object: %#v", event.Object)
+			continue
+		}
+		// If we get disconnected, start where we left off.
+		*resourceVersion = pod.ResourceVersion + 1
+		if err := rm.podChanged(pod); err != nil {
+			glog.Errorf("Error counting the replicas of pod %s: %v", pod.ID, err)
+		}
+	}
+}
+
+// podChanged updates the current state of the controllers whose selector matches pod.
+func (rm *ReplicationManager) podChanged(pod *api.Pod) error {
+	list, err := rm.kubeClient.ListReplicationControllers(labels.Everything())
+	if err != nil {
+		return err
+	}
+	for _, controller := range list.Items {
+		if len(controller.DesiredState.ReplicaSelector) == 0 {
+			continue
+		}
+		if !labels.Set(controller.DesiredState.ReplicaSelector).AsSelector().Matches(labels.Set(pod.Labels)) {
+			continue
+		}
+		podList, err := rm.kubeClient.ListPods(labels.Set(controller.DesiredState.ReplicaSelector).AsSelector())
+		if err != nil {
+			return err
+		}
+		rm.recordReplicas(controller, len(rm.filterActivePods(podList.Items)))
+	}
+	return nil
+}
+
+// recordReplicas writes replicas to the current state of the controller through its status
+// subresource, unless the controller already has that many.
+func (rm *ReplicationManager) recordReplicas(controller api.ReplicationController, replicas int) {
+	if controller.CurrentState.Replicas == replicas || len(controller.ID) == 0 {
+		return
+	}
+	controller.CurrentState = api.ReplicationControllerState{Replicas: replicas}
+	if _, err := rm.kubeClient.UpdateReplicationControllerStatus(controller); err != nil {
+		glog.Errorf("Error recording the replicas of %s: %v", controller.ID, err)
+	}
+}
+
 func (rm *ReplicationManager) filterActivePods(pods []api.Pod) []api.Pod {
 	var result []api.Pod
 	for _, value := range pods {
@@ -144,6 +203,7 @@ func (rm *ReplicationManager) syncReplicationController(controllerSpec api.Repli
 		return err
 	}
 	filteredList := rm.filterActivePods(podList.Items)
+	rm.recordReplicas(controllerSpec, len(filteredList))
 	diff := len(filteredList) - controllerSpec.DesiredState.Replicas
 	if diff < 0 {
 		diff *= -1
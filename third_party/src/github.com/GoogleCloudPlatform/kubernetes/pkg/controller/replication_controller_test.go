@@ -352,3 +352,116 @@ func TestWatchControllers(t *testing.T) {
 		t.Errorf("Expected 1 call but got 0")
 	}
 }
+
+// statusClient serves pods and controllers, and records the status updates of controllers.
+type statusClient struct {
+	*client.FakeClient
+	pods        api.PodList
+	controllers api.ReplicationControllerList
+	podWatch    *watch.FakeWatcher
+
+	lock     sync.Mutex
+	statuses []api.ReplicationController
+	recorded chan struct{}
+}
+
+func (c *statusClient) ListPods(selector labels.Selector) (api.PodList, error) {
+	list := api.PodList{}
+	for _, pod := range c.pods.Items {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			list.Items = append(list.Items, pod)
+		}
+	}
+	return list, nil
+}
+
+func (c *statusClient) ListReplicationControllers(selector labels.Selector) (api.ReplicationControllerList, error) {
+	return c.controllers, nil
+}
+
+func (c *statusClient) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return c.podWatch, nil
+}
+
+func (c *statusClient) UpdateReplicationControllerStatus(controller api.ReplicationController) (api.ReplicationController, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.statuses = append(c.statuses, controller)
+	if c.recorded != nil {
+		c.recorded <- struct{}{}
+	}
+	return controller, nil
+}
+
+func labeledPod(id string, status api.PodStatus, labels map[string]string) api.Pod {
+	return api.Pod{JSONBase: api.JSONBase{ID: id}, Labels: labels, CurrentState: api.PodState{Status: status}}
+}
+
+func TestSyncReplicationControllerRecordsReplicas(t *testing.T) {
+	selector := map[string]string{"name": "foo"}
+	fakeClient := &statusClient{
+		FakeClient: &client.FakeClient{},
+		pods: api.PodList{Items: []api.Pod{
+			labeledPod("a", api.PodRunning, selector),
+			labeledPod("b", api.PodWaiting, selector),
+			labeledPod("c", api.PodTerminated, selector),
+			labeledPod("d", api.PodRunning, map[string]string{"name": "bar"}),
+		}},
+	}
+	manager := MakeReplicationManager(fakeClient)
+	manager.podControl = &FakePodControl{}
+
+	controller := makeReplicationController(2)
+	controller.ID = "foo"
+	controller.DesiredState.ReplicaSelector = selector
+	manager.syncReplicationController(controller)
+	if len(fakeClient.statuses) != 1 || fakeClient.statuses[0].CurrentState.Replicas != 2 || fakeClient.statuses[0].ID != "foo" {
+		t.Fatalf("expected the active pods to be recorded, got %#v", fakeClient.statuses)
+	}
+
+	// A controller that already has the count isn't written again.
+	controller.CurrentState.Replicas = 2
+	manager.syncReplicationController(controller)
+	if len(fakeClient.statuses) != 1 {
+		t.Errorf("unexpected status updates %#v", fakeClient.statuses[1:])
+	}
+}
+
+func TestWatchPodsRecordsReplicas(t *testing.T) {
+	selector := map[string]string{"name": "foo"}
+	foo := makeReplicationController(3)
+	foo.ID = "foo"
+	foo.DesiredState.ReplicaSelector = selector
+	bar := makeReplicationController(1)
+	bar.ID = "bar"
+	bar.DesiredState.ReplicaSelector = map[string]string{"name": "bar"}
+	fakeClient := &statusClient{
+		FakeClient:  &client.FakeClient{},
+		pods:        api.PodList{Items: []api.Pod{labeledPod("a", api.PodRunning, selector)}},
+		controllers: api.ReplicationControllerList{Items: []api.ReplicationController{foo, bar}},
+		podWatch:    watch.NewFake(),
+		recorded:    make(chan struct{}, 1),
+	}
+	manager := MakeReplicationManager(fakeClient)
+	resourceVersion := uint64(0)
+	go manager.watchPods(&resourceVersion)
+
+	pod := labeledPod("a", api.PodRunning, selector)
+	pod.ResourceVersion = 7
+	fakeClient.podWatch.Add(&pod)
+	select {
+	case <-fakeClient.recorded:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the replicas to be recorded")
+	}
+	fakeClient.podWatch.Stop()
+
+	fakeClient.lock.Lock()
+	defer fakeClient.lock.Unlock()
+	if len(fakeClient.statuses) != 1 || fakeClient.statuses[0].ID != "foo" || fakeClient.statuses[0].CurrentState.Replicas != 1 {
+		t.Errorf("expected only the controller selecting the pod to be updated, got %#v", fakeClient.statuses)
+	}
+	if resourceVersion != 8 {
+		t.Errorf("expected the watch to resume after the pod, got %d", resourceVersion)
+	}
+}
@@ -19,6 +19,7 @@ package conversion
 import (
 	"fmt"
 	"reflect"
+	"strings"
 )
 
 type typePair struct {
@@ -83,6 +84,31 @@ func (c *Converter) Register(conversionFunc interface{}) error {
 	return nil
 }
 
+// FieldError is returned by Convert when a field of an object can't be converted.
+type FieldError struct {
+	// Path names the field, from the converted object down, e.g. "DesiredState.Manifest.Env[0]".
+	Path string
+	Err  error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// NewFieldError returns err as the error of converting field, the name of a field of an object
+// or an index like "[0]". If err is a FieldError of a field within field, field is prepended to
+// its path. Conversion funcs may use it to name the fields they fail to convert.
+func NewFieldError(field string, err error) error {
+	if fieldErr, ok := err.(*FieldError); ok {
+		path := fieldErr.Path
+		if !strings.HasPrefix(path, "[") {
+			path = "." + path
+		}
+		return &FieldError{field + path, fieldErr.Err}
+	}
+	return &FieldError{field, err}
+}
+
 // FieldMatchingType contains a list of ways in which struct fields could be
 // copied. These constants may be | combined.
 type FieldMatchingFlags int
@@ -112,7 +138,8 @@ func (f FieldMatchingFlags) IsSet(flag FieldMatchingFlags) bool {
 
 // Convert will translate src to dest if it knows how. Both must be pointers.
 // If no conversion func is registered and the default copying mechanism
-// doesn't work on this type pair, an error will be returned.
+// doesn't work on this type pair, an error will be returned. Errors converting
+// a field of src are FieldErrors naming the field.
 // Not safe for objects with cyclic references!
 func (c *Converter) Convert(src, dest interface{}, flags FieldMatchingFlags) error {
 	dv, sv := reflect.ValueOf(dest), reflect.ValueOf(src)
@@ -180,14 +207,14 @@ func (c *Converter) convert(sv, dv reflect.Value, flags FieldMatchingFlags) erro
 				case flags.IsSet(IgnoreMissingFields):
 					// No error.
 				case flags.IsSet(SourceToDest):
-					return fmt.Errorf("%v not present in dest (%v to %v)", f.Name, st, dt)
+					return NewFieldError(f.Name, fmt.Errorf("not present in dest (%v to %v)", st, dt))
 				default:
-					return fmt.Errorf("%v not present in src (%v to %v)", f.Name, st, dt)
+					return NewFieldError(f.Name, fmt.Errorf("not present in src (%v to %v)", st, dt))
 				}
 				continue
 			}
 			if err := c.convert(sf, df, flags); err != nil {
-				return err
+				return NewFieldError(f.Name, err)
 			}
 		}
 	case reflect.Slice:
@@ -199,7 +226,7 @@ func (c *Converter) convert(sv, dv reflect.Value, flags FieldMatchingFlags) erro
 		dv.Set(reflect.MakeSlice(dt, sv.Len(), sv.Cap()))
 		for i := 0; i < sv.Len(); i++ {
 			if err := c.convert(sv.Index(i), dv.Index(i), flags); err != nil {
-				return err
+				return NewFieldError(fmt.Sprintf("[%d]", i), err)
 			}
 		}
 	case reflect.Ptr:
@@ -220,11 +247,14 @@ func (c *Converter) convert(sv, dv reflect.Value, flags FieldMatchingFlags) erro
 		for _, sk := range sv.MapKeys() {
 			dk := reflect.New(dt.Key()).Elem()
 			if err := c.convert(sk, dk, flags); err != nil {
-				return err
+				return NewFieldError(fmt.Sprintf("[%v]", sk.Interface()), err)
 			}
+			// Map values aren't addressable, which conversion funcs need them to be.
+			skv := reflect.New(st.Elem()).Elem()
+			skv.Set(sv.MapIndex(sk))
 			dkv := reflect.New(dt.Elem()).Elem()
-			if err := c.convert(sv.MapIndex(sk), dkv, flags); err != nil {
-				return err
+			if err := c.convert(skv, dkv, flags); err != nil {
+				return NewFieldError(fmt.Sprintf("[%v]", sk.Interface()), err)
 			}
 			dv.SetMapIndex(dk, dkv)
 		}
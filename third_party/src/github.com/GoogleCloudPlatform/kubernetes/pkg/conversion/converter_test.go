@@ -83,6 +83,68 @@ func TestConverter_CallsRegisteredFunctions(t *testing.T) {
 	}
 }
 
+func TestConverter_ErrorsNameTheField(t *testing.T) {
+	type Inner struct {
+		Value string
+	}
+	type Outer struct {
+		Items  []Inner
+		ByName map[string]Inner
+	}
+	type OtherInner struct {
+		Value string
+	}
+	c := NewConverter()
+	err := c.Register(func(in *Inner, out *OtherInner) error {
+		if in.Value == "bad" {
+			return fmt.Errorf("can't convert %q", in.Value)
+		}
+		out.Value = in.Value
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	type OtherOuter struct {
+		Items  []OtherInner
+		ByName map[string]OtherInner
+	}
+
+	table := []struct {
+		in   Outer
+		path string
+	}{
+		{Outer{Items: []Inner{{"good"}, {"bad"}}}, "Items[1]"},
+		{Outer{ByName: map[string]Inner{"foo": {"bad"}}}, "ByName[foo]"},
+	}
+	for _, item := range table {
+		err := c.Convert(&item.in, &OtherOuter{}, AllowDifferentFieldTypeNames)
+		fieldErr, ok := err.(*FieldError)
+		if !ok {
+			t.Errorf("%#v: expected a FieldError, got %v", item.in, err)
+			continue
+		}
+		if fieldErr.Path != item.path || fieldErr.Error() != item.path+`: can't convert "bad"` {
+			t.Errorf("expected the error to name %s, got %v", item.path, err)
+		}
+	}
+
+	type Missing struct {
+		Items []struct{ Gone string }
+	}
+	err = c.Convert(&Missing{Items: []struct{ Gone string }{{}}}, &struct{ Items []struct{} }{}, SourceToDest|AllowDifferentFieldTypeNames)
+	if fieldErr, ok := err.(*FieldError); !ok || fieldErr.Path != "Items[0].Gone" {
+		t.Errorf("expected the missing field to be named, got %v", err)
+	}
+}
+
+func TestNewFieldError(t *testing.T) {
+	err := NewFieldError("Spec", NewFieldError("[2]", NewFieldError("Name", fmt.Errorf("bad"))))
+	if err.Error() != "Spec[2].Name: bad" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
 func TestConverter_fuzz(t *testing.T) {
 	newAnonType := func() interface{} {
 		return reflect.New(reflect.TypeOf(externalTypeReturn())).Interface()
@@ -35,13 +35,21 @@ func (s *Scheme) Decode(data []byte) (interface{}, error) {
 	if version == "" {
 		return nil, fmt.Errorf("version not set in '%s'", string(data))
 	}
+	// yaml is a superset of json, so we use it to decode here. That way,
+	// we understand both.
+	return s.decode(version, kind, func(obj interface{}) error {
+		return yaml.Unmarshal(data, obj)
+	})
+}
+
+// decode creates an object of the given version and kind, fills it in with unmarshal, and
+// converts it to s.InternalVersion if needed.
+func (s *Scheme) decode(version, kind string, unmarshal func(obj interface{}) error) (interface{}, error) {
 	obj, err := s.NewObject(version, kind)
 	if err != nil {
 		return nil, err
 	}
-	// yaml is a superset of json, so we use it to decode here. That way,
-	// we understand both.
-	err = yaml.Unmarshal(data, obj)
+	err = unmarshal(obj)
 	if err != nil {
 		return nil, err
 	}
@@ -77,6 +85,17 @@ func (s *Scheme) DecodeInto(data []byte, obj interface{}) error {
 	if err != nil {
 		return err
 	}
+	// yaml is a superset of json, so we use it to decode here. That way,
+	// we understand both.
+	return s.decodeInto(dataVersion, dataKind, obj, func(obj interface{}) error {
+		return yaml.Unmarshal(data, obj)
+	})
+}
+
+// decodeInto fills in obj with unmarshal, which decodes data of the given version and kind,
+// converting the data if its version isn't the one of obj. An empty version or kind is
+// assumed to be the one of obj.
+func (s *Scheme) decodeInto(dataVersion, dataKind string, obj interface{}, unmarshal func(obj interface{}) error) error {
 	objVersion, objKind, err := s.ObjectVersionAndKind(obj)
 	if err != nil {
 		return err
@@ -97,7 +116,7 @@ func (s *Scheme) DecodeInto(data []byte, obj interface{}) error {
 
 	if objVersion == dataVersion {
 		// Easy case!
-		err = yaml.Unmarshal(data, obj)
+		err = unmarshal(obj)
 		if err != nil {
 			return err
 		}
@@ -106,9 +125,7 @@ func (s *Scheme) DecodeInto(data []byte, obj interface{}) error {
 		if err != nil {
 			return fmt.Errorf("Unable to create new object of type ('%s', '%s')", dataVersion, dataKind)
 		}
-		// yaml is a superset of json, so we use it to decode here. That way,
-		// we understand both.
-		err = yaml.Unmarshal(data, external)
+		err = unmarshal(external)
 		if err != nil {
 			return err
 		}
@@ -19,6 +19,7 @@ package conversion
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 )
 
 // EncodeOrDie is a version of Encode which will panic instead of returning an error. For tests.
@@ -64,26 +65,47 @@ func (s *Scheme) Encode(obj interface{}) (data []byte, err error) {
 
 // EncodeToVersion is like Encode, but you may choose the version.
 func (s *Scheme) EncodeToVersion(obj interface{}, destVersion string) (data []byte, err error) {
+	err = s.encodeToVersion(obj, destVersion, func(obj interface{}) error {
+		data, err = json.Marshal(obj)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// EncodeToStream is like Encode, but writes the JSON to w with a streaming encoder instead
+// of returning it. Nothing is written to w if obj can't be encoded.
+func (s *Scheme) EncodeToStream(w io.Writer, obj interface{}) error {
+	return s.encodeToVersion(obj, s.ExternalVersion, func(obj interface{}) error {
+		return json.NewEncoder(w).Encode(obj)
+	})
+}
+
+// encodeToVersion converts obj to destVersion, sets its Version and Kind, and passes it to
+// marshal to be written out.
+func (s *Scheme) encodeToVersion(obj interface{}, destVersion string, marshal func(obj interface{}) error) error {
 	obj = maybeCopy(obj)
 	v, _ := enforcePtr(obj) // maybeCopy guarantees a pointer
 	if _, registered := s.typeToVersion[v.Type()]; !registered {
-		return nil, fmt.Errorf("type %v is not registered and it will be impossible to Decode it, therefore Encode will refuse to encode it.", v.Type())
+		return fmt.Errorf("type %v is not registered and it will be impossible to Decode it, therefore Encode will refuse to encode it.", v.Type())
 	}
 
 	objVersion, objKind, err := s.ObjectVersionAndKind(obj)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// Perform a conversion if necessary.
 	if objVersion != destVersion {
 		objOut, err := s.NewObject(destVersion, objKind)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		err = s.converter.Convert(obj, objOut, 0)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		obj = objOut
 	}
@@ -91,21 +113,15 @@ func (s *Scheme) EncodeToVersion(obj interface{}, destVersion string) (data []by
 	// Version and Kind should be set on the wire.
 	err = s.SetVersionAndKind(destVersion, objKind, obj)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// To add metadata, do some simple surgery on the JSON.
-	data, err = json.Marshal(obj)
-	if err != nil {
-		return nil, err
+	if err := marshal(obj); err != nil {
+		return err
 	}
 
 	// Version and Kind should be blank in memory. Reset them, since it's
 	// possible that we modified a user object and not a copy above.
-	err = s.SetVersionAndKind("", "", obj)
-	if err != nil {
-		return nil, err
-	}
-
-	return data, nil
+	return s.SetVersionAndKind("", "", obj)
 }
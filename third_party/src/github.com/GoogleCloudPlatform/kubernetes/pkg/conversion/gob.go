@@ -0,0 +1,97 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package conversion
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+)
+
+// GobCodec encodes and decodes the objects of a Scheme with encoding/gob rather than JSON,
+// which is more compact and much cheaper to produce and parse. Objects are converted exactly
+// as by Encode and Decode; only the wire format differs. Each encoding is a gobHeader naming
+// the version and kind of the object, followed by the object in that version.
+type GobCodec struct {
+	scheme *Scheme
+}
+
+// gobHeader precedes each object encoded by a GobCodec, since gob encodings don't name the
+// type they were made from.
+type gobHeader struct {
+	Version string
+	Kind    string
+}
+
+// GobCodec returns a GobCodec for the objects of s.
+func (s *Scheme) GobCodec() *GobCodec {
+	return &GobCodec{s}
+}
+
+// Encode converts obj to the external version of the scheme, like Scheme.Encode, and
+// encodes it with encoding/gob.
+func (c *GobCodec) Encode(obj interface{}) (data []byte, err error) {
+	buf := &bytes.Buffer{}
+	err = c.scheme.encodeToVersion(obj, c.scheme.ExternalVersion, func(obj interface{}) error {
+		version, kind, err := c.scheme.ObjectVersionAndKind(obj)
+		if err != nil {
+			return err
+		}
+		encoder := gob.NewEncoder(buf)
+		if err := encoder.Encode(gobHeader{version, kind}); err != nil {
+			return err
+		}
+		return encoder.Encode(obj)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode decodes data, which Encode produced, and converts the object to the internal
+// version of the scheme.
+func (c *GobCodec) Decode(data []byte) (interface{}, error) {
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	header, err := decodeGobHeader(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if header.Version == "" {
+		return nil, fmt.Errorf("version not set in gob encoded %s", header.Kind)
+	}
+	return c.scheme.decode(header.Version, header.Kind, decoder.Decode)
+}
+
+// DecodeInto decodes data, which Encode produced, into obj, converting it if its version
+// isn't the one of obj. Returns an error if data holds an object of another kind.
+func (c *GobCodec) DecodeInto(data []byte, obj interface{}) error {
+	decoder := gob.NewDecoder(bytes.NewReader(data))
+	header, err := decodeGobHeader(decoder)
+	if err != nil {
+		return err
+	}
+	return c.scheme.decodeInto(header.Version, header.Kind, obj, decoder.Decode)
+}
+
+func decodeGobHeader(decoder *gob.Decoder) (gobHeader, error) {
+	var header gobHeader
+	if err := decoder.Decode(&header); err != nil {
+		return gobHeader{}, fmt.Errorf("couldn't get version/kind: %v", err)
+	}
+	return header, nil
+}
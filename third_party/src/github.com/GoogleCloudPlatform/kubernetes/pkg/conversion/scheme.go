@@ -103,6 +103,16 @@ func (s *Scheme) AddKnownTypes(version string, types ...interface{}) {
 	}
 }
 
+// KnownTypes returns the types registered for version with AddKnownTypes, by the kind they
+// are encoded with. The map is a copy, which the caller may change.
+func (s *Scheme) KnownTypes(version string) map[string]reflect.Type {
+	types := map[string]reflect.Type{}
+	for kind, t := range s.versionMap[version] {
+		types[kind] = t
+	}
+	return types
+}
+
 // NewObject returns a new object of the given version and name,
 // or an error if it hasn't been registered.
 func (s *Scheme) NewObject(versionName, typeName string) (interface{}, error) {
@@ -17,6 +17,7 @@ limitations under the License.
 package conversion
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -218,6 +219,25 @@ func TestTypes(t *testing.T) {
 	}
 }
 
+func TestKnownTypes(t *testing.T) {
+	s := GetTestScheme()
+	types := s.KnownTypes("v1")
+	expected := map[string]reflect.Type{
+		"TestType1":            reflect.TypeOf(externalTypeReturn()),
+		"ExternalInternalSame": reflect.TypeOf(ExternalInternalSame{}),
+	}
+	if !reflect.DeepEqual(types, expected) {
+		t.Errorf("expected %v, got %v", expected, types)
+	}
+	delete(types, "TestType1")
+	if _, ok := s.KnownTypes("v1")["TestType1"]; !ok {
+		t.Errorf("expected changes to the returned map not to reach the scheme")
+	}
+	if types := s.KnownTypes("v2"); len(types) != 0 {
+		t.Errorf("expected no types for an unknown version, got %v", types)
+	}
+}
+
 func TestEncode_NonPtr(t *testing.T) {
 	s := GetTestScheme()
 	tt := TestType1{A: "I'm not a pointer object"}
@@ -252,6 +272,33 @@ func TestEncode_Ptr(t *testing.T) {
 	}
 }
 
+func TestEncodeToStream(t *testing.T) {
+	s := GetTestScheme()
+	tt := &TestType1{A: "I am streamed"}
+	expected, err := s.Encode(tt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	if err := s.EncodeToStream(buf, tt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e, a := string(expected)+"\n", buf.String(); e != a {
+		t.Errorf("Expected:\n %s,\n Got:\n %s", e, a)
+	}
+	if tt.MyWeirdCustomEmbeddedVersionKindField.APIVersion != "" {
+		t.Errorf("expected the version to be reset in memory: %#v", tt)
+	}
+
+	buf.Reset()
+	if err := s.EncodeToStream(buf, &struct{}{}); err == nil {
+		t.Errorf("expected an error encoding an unregistered type")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected nothing to be written on error, got %q", buf.String())
+	}
+}
+
 func TestBadJSONRejection(t *testing.T) {
 	s := GetTestScheme()
 	badJSONs := [][]byte{
@@ -17,4 +17,6 @@ limitations under the License.
 // Package healthz implements basic http server health checking.
 // Usage:
 //   import _ "healthz" registers a handler on the path '/healthz', that serves 200s
+// Servers with dependencies install a handler running Checks of them with InstallHandler,
+// and can Run the same checks once, e.g. to validate their configuration before serving.
 package healthz
@@ -17,20 +17,99 @@ limitations under the License.
 package healthz
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"os"
 )
 
 func init() {
-	http.HandleFunc("/healthz", handleHealthz)
+	http.Handle("/healthz", NewHandler())
 }
 
-func handleHealthz(w http.ResponseWriter, r *http.Request) {
-	// TODO Support user supplied health functions too.
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+// Check checks a dependency of a server, such as its storage, each time the health of the
+// server is checked.
+type Check struct {
+	// Name names the dependency in reports.
+	Name string
+	// Check returns an error if the dependency is unhealthy.
+	Check func() error
 }
 
-// InstallHandler registers a handler for health checking on the path "/healthz" to mux.
-func InstallHandler(mux *http.ServeMux) {
-	mux.HandleFunc("/healthz", handleHealthz)
+// Result is the outcome of a Check.
+type Result struct {
+	Name string
+	Err  error
+}
+
+// Run runs each of checks once, in order.
+func Run(checks []Check) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		results = append(results, Result{check.Name, check.Check()})
+	}
+	return results
+}
+
+// Healthy returns true if none of results is an error.
+func Healthy(results []Result) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteReport writes a line for each of results to w, "ok <name>" or
+// "FAILED <name>: <error>".
+func WriteReport(w io.Writer, results []Result) error {
+	for _, result := range results {
+		var err error
+		if result.Err != nil {
+			_, err = fmt.Fprintf(w, "FAILED %s: %v\n", result.Name, result.Err)
+		} else {
+			_, err = fmt.Fprintf(w, "ok %s\n", result.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewHandler returns a handler that runs checks on every request. It answers "ok" if they
+// all pass, and otherwise 500 with the report of WriteReport.
+func NewHandler(checks ...Check) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		results := Run(checks)
+		if Healthy(results) {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("ok"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusInternalServerError)
+		WriteReport(w, results)
+	})
+}
+
+// InstallHandler registers a handler for health checking on the path "/healthz" to mux,
+// which runs checks on every request.
+func InstallHandler(mux *http.ServeMux, checks ...Check) {
+	mux.Handle("/healthz", NewHandler(checks...))
+}
+
+// WritableDir returns a check that dir exists and files can be created in it, e.g. for the
+// logs of a server.
+func WritableDir(dir string) func() error {
+	return func() error {
+		file, err := ioutil.TempFile(dir, ".healthz")
+		if err != nil {
+			return err
+		}
+		file.Close()
+		return os.Remove(file.Name())
+	}
 }
@@ -17,8 +17,12 @@ limitations under the License.
 package healthz
 
 import (
+	"errors"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -38,3 +42,47 @@ func TestInstallHandler(t *testing.T) {
 		t.Errorf("Expected %v, got %v", "ok", w.Body.String())
 	}
 }
+
+func TestInstallHandlerChecks(t *testing.T) {
+	healthy := true
+	mux := http.NewServeMux()
+	InstallHandler(mux,
+		Check{"storage", func() error { return nil }},
+		Check{"minions", func() error {
+			if !healthy {
+				return errors.New("no minions")
+			}
+			return nil
+		}},
+	)
+	for _, healthy = range []bool{true, false} {
+		req, _ := http.NewRequest("GET", "http://example.com/healthz", nil)
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		expectedCode, expectedBody := http.StatusOK, "ok"
+		if !healthy {
+			expectedCode, expectedBody = http.StatusInternalServerError, "ok storage\nFAILED minions: no minions\n"
+		}
+		if w.Code != expectedCode || w.Body.String() != expectedBody {
+			t.Errorf("healthy=%t: expected %d %q, got %d %q", healthy, expectedCode, expectedBody, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestWritableDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "healthz")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	results := Run([]Check{
+		{"logs", WritableDir(dir)},
+		{"missing", WritableDir(filepath.Join(dir, "missing"))},
+	})
+	if len(results) != 2 || results[0].Err != nil || results[1].Err == nil || Healthy(results) {
+		t.Errorf("expected only the missing directory to fail, got %#v", results)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("expected the probe file to be removed, got %v", files)
+	}
+}
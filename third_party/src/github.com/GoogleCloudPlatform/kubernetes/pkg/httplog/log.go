@@ -145,6 +145,14 @@ func (rl *respLogger) Write(b []byte) (int, error) {
 	return rl.w.Write(b)
 }
 
+// Implement http.Flusher, so that streaming handlers can be logged. Does nothing if the
+// wrapped ResponseWriter can't flush.
+func (rl *respLogger) Flush() {
+	if flusher, ok := rl.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // Implement http.ResponseWriter
 func (rl *respLogger) WriteHeader(status int) {
 	rl.status = status
@@ -39,7 +39,7 @@ func NewImageRepositoryRegistryStorage(registry ImageRepositoryRegistry, imageRe
 }
 
 // List obtains a list of ImageRepositorys that match selector.
-func (s *ImageRepositoryRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (s *ImageRepositoryRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	result := imageapi.ImageRepositoryList{}
 	images, err := s.registry.ListImageRepositories(selector)
 	if err == nil {
@@ -49,7 +49,7 @@ func (s *ImageRepositoryRegistryStorage) List(selector labels.Selector) (interfa
 }
 
 // Get obtains the ImageRepository specified by its id.
-func (s *ImageRepositoryRegistryStorage) Get(id string) (interface{}, error) {
+func (s *ImageRepositoryRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
 	image, err := s.registry.GetImageRepository(id)
 	if err != nil {
 		return nil, err
@@ -58,7 +58,7 @@ func (s *ImageRepositoryRegistryStorage) Get(id string) (interface{}, error) {
 }
 
 // Delete asynchronously deletes the ImageRepository specified by its id.
-func (s *ImageRepositoryRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (s *ImageRepositoryRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		return api.Status{Status: api.StatusSuccess}, s.registry.DeleteImageRepository(id)
 	}), nil
@@ -70,7 +70,7 @@ func (s *ImageRepositoryRegistryStorage) New() interface{} {
 }
 
 // Create registers a given new ImageRepository instance to the registry.
-func (s *ImageRepositoryRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (s *ImageRepositoryRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	repository, ok := obj.(*imageapi.ImageRepository)
 	if !ok {
 		return nil, fmt.Errorf("not an image repository: %#v", obj)
@@ -97,7 +97,7 @@ func (s *ImageRepositoryRegistryStorage) Create(obj interface{}) (<-chan interfa
 }
 
 // Update replaces a given ImageRepository instance with an existing instance in the registry.
-func (s *ImageRepositoryRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (s *ImageRepositoryRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	repository, ok := obj.(*imageapi.ImageRepository)
 	if !ok {
 		return nil, fmt.Errorf("not an image repository: %#v", obj)
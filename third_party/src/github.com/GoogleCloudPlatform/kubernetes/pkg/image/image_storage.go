@@ -38,7 +38,7 @@ func NewImageRegistryStorage(registry ImageRegistry) apiserver.RESTStorage {
 }
 
 // List obtains a list of Images that match selector.
-func (s *ImageRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (s *ImageRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	result := imageapi.ImageList{}
 	images, err := s.registry.ListImages(selector)
 	if err == nil {
@@ -48,7 +48,7 @@ func (s *ImageRegistryStorage) List(selector labels.Selector) (interface{}, erro
 }
 
 // Get obtains the Image specified by its id.
-func (s *ImageRegistryStorage) Get(id string) (interface{}, error) {
+func (s *ImageRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
 	image, err := s.registry.GetImage(id)
 	if err != nil {
 		return nil, err
@@ -57,7 +57,7 @@ func (s *ImageRegistryStorage) Get(id string) (interface{}, error) {
 }
 
 // Delete asynchronously deletes the Image specified by its id.
-func (s *ImageRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (s *ImageRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		return api.Status{Status: api.StatusSuccess}, s.registry.DeleteImage(id)
 	}), nil
@@ -69,7 +69,7 @@ func (s *ImageRegistryStorage) New() interface{} {
 }
 
 // Create registers a given new Image instance to s.registry.
-func (s *ImageRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (s *ImageRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	image, ok := obj.(*imageapi.Image)
 	if !ok {
 		return nil, fmt.Errorf("not an image: %#v", obj)
@@ -88,6 +88,6 @@ func (s *ImageRegistryStorage) Create(obj interface{}) (<-chan interface{}, erro
 }
 
 // Update replaces a given Image instance with an existing instance in s.registry.
-func (s *ImageRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
-	return s.Create(obj)
+func (s *ImageRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	return s.Create(ctx, obj)
 }
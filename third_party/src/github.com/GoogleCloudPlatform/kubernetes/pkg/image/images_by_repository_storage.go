@@ -60,12 +60,12 @@ func mappingToID(mapping *imageapi.ImageRepositoryMapping) string {
 }
 
 // List obtains a list of ImageRepositorys that match selector.
-func (s *ImagesByRepositoryRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (s *ImagesByRepositoryRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	return nil, errors.New("not supported")
 }
 
 // Get returns the Images in the ImageRepository specified by its id.
-func (s *ImagesByRepositoryRegistryStorage) Get(id string) (interface{}, error) {
+func (s *ImagesByRepositoryRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
 	result := imageapi.ImageList{}
 	imageIDs, err := s.registry.ListImagesFromRepository(id, labels.Everything())
 	if err != nil {
@@ -80,7 +80,7 @@ func (s *ImagesByRepositoryRegistryStorage) Get(id string) (interface{}, error)
 }
 
 // Delete asynchronously deletes the ImageRepository specified by its id.
-func (s *ImagesByRepositoryRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (s *ImagesByRepositoryRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	mapping, err := mappingFromID(id)
 	if err != nil {
 		return nil, err
@@ -96,7 +96,7 @@ func (s *ImagesByRepositoryRegistryStorage) New() interface{} {
 }
 
 // Create binds a new or existing image to an image repository
-func (s *ImagesByRepositoryRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (s *ImagesByRepositoryRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	mapping, ok := obj.(*imageapi.ImageRepositoryMapping)
 	if !ok {
 		return nil, fmt.Errorf("not an image repository mapping: %#v", obj)
@@ -124,6 +124,6 @@ func (s *ImagesByRepositoryRegistryStorage) Create(obj interface{}) (<-chan inte
 }
 
 // Update replaces a given ImageRepository instance with an existing instance in the registry.
-func (s *ImagesByRepositoryRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
-	return s.Create(obj)
+func (s *ImagesByRepositoryRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	return s.Create(ctx, obj)
 }
@@ -0,0 +1,76 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"io"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+// CancelBuild marks a build as cancelled through its status; the build controller then
+// deletes its pod. The server rejects cancelling a build that has already finished with a
+// conflict.
+func CancelBuild(id string, client client.Interface) (buildapi.Build, error) {
+	build, err := client.GetBuild(id)
+	if err != nil {
+		return buildapi.Build{}, err
+	}
+	build.Status = buildapi.BuildCancelled
+	build.Reason = buildapi.BuildReasonCancelled
+	build.Message = "The build was cancelled"
+	build.CompletionTimestamp = time.Now().Format(time.UnixDate)
+	return client.UpdateBuildStatus(build)
+}
+
+// CloneBuild creates a new build with the config, source revision and output of the build
+// 'id', recording 'id' as its parent. The returned bool is true if the original build has not
+// finished, in which case both builds may run at the same time.
+func CloneBuild(id string, client client.Interface) (buildapi.Build, bool, error) {
+	build, err := client.GetBuild(id)
+	if err != nil {
+		return buildapi.Build{}, false, err
+	}
+	running := build.Status == buildapi.BuildNew || build.Status == buildapi.BuildPending || build.Status == buildapi.BuildRunning
+	clone := buildapi.Build{
+		Labels:   build.Labels,
+		Config:   build.Config,
+		Revision: build.Revision,
+		ParentID: build.ID,
+		Output:   build.Output,
+	}
+	clone, err = client.CreateBuild(clone)
+	return clone, running, err
+}
+
+// BuildLogs copies the log of a build's pod to w. If follow is true, it keeps copying until
+// the build's container exits.
+func BuildLogs(id string, c *client.Client, follow bool, w io.Writer) error {
+	r := c.Get().Path("builds").Path(id).Path("log")
+	if follow {
+		r = r.Param("follow", "true")
+	}
+	body, err := r.Stream()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	_, err = io.Copy(w, body)
+	return err
+}
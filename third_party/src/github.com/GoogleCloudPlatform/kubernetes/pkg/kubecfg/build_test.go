@@ -0,0 +1,147 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+func TestCancelBuild(t *testing.T) {
+	fakeClient := FakeKubeClient{
+		builds: buildapi.BuildList{
+			Items: []buildapi.Build{
+				{JSONBase: api.JSONBase{ID: "foo"}, Status: buildapi.BuildRunning, PodID: "build-foo"},
+			},
+		},
+	}
+	build, err := CancelBuild("foo", &fakeClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if build.Status != buildapi.BuildCancelled || build.Reason != buildapi.BuildReasonCancelled || build.PodID != "build-foo" || len(build.CompletionTimestamp) == 0 {
+		t.Errorf("unexpected build: %#v", build)
+	}
+	if len(fakeClient.actions) != 2 {
+		t.Fatalf("unexpected actions: %#v", fakeClient.actions)
+	}
+	validateAction(Action{action: "get-build", value: "foo"}, fakeClient.actions[0], t)
+	validateAction(Action{action: "update-build-status", value: "foo"}, fakeClient.actions[1], t)
+}
+
+func TestPrintCancelledBuild(t *testing.T) {
+	buf := &bytes.Buffer{}
+	build := &buildapi.Build{JSONBase: api.JSONBase{ID: "foo"}, Status: buildapi.BuildCancelled, PodID: "build-foo"}
+	if err := (&HumanReadablePrinter{}).PrintObj(build, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if fields := strings.Fields(lines[len(lines)-1]); strings.Join(fields, " ") != "foo cancelled build-foo" {
+		t.Errorf("unexpected output: %q", buf.String())
+	}
+}
+
+func TestBuildLogs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1beta1/builds/foo/log" {
+			t.Errorf("unexpected path: %s", req.URL.Path)
+		}
+		fmt.Fprintf(w, "follow=%s\n", req.URL.Query().Get("follow"))
+	}))
+	defer server.Close()
+	c := client.New(server.URL, nil)
+
+	for _, follow := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if err := BuildLogs("foo", c, follow, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := "follow=\n"
+		if follow {
+			expected = "follow=true\n"
+		}
+		if buf.String() != expected {
+			t.Errorf("expected %q, got %q", expected, buf.String())
+		}
+	}
+}
+
+func TestCloneBuild(t *testing.T) {
+	revision := &buildapi.SourceRevision{Commit: "abc123", Ref: "refs/heads/master"}
+	fakeClient := FakeKubeClient{
+		builds: buildapi.BuildList{
+			Items: []buildapi.Build{
+				{
+					JSONBase: api.JSONBase{ID: "failed", CreationTimestamp: "yesterday"},
+					Config:   buildconfigapi.BuildConfig{Type: "docker", SourceURI: "git://example.com/app", ImageTag: "app"},
+					Status:   buildapi.BuildFailed,
+					PodID:    "build-failed",
+					Revision: revision,
+				},
+				{JSONBase: api.JSONBase{ID: "running"}, Status: buildapi.BuildRunning},
+			},
+		},
+	}
+	clone, running, err := CloneBuild("failed", &fakeClient)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if running {
+		t.Errorf("expected a failed build not to be flagged as running")
+	}
+	expected := buildapi.Build{
+		JSONBase: api.JSONBase{ID: "clone"},
+		Config:   buildconfigapi.BuildConfig{Type: "docker", SourceURI: "git://example.com/app", ImageTag: "app"},
+		Status:   buildapi.BuildNew,
+		Revision: revision,
+		ParentID: "failed",
+	}
+	if !reflect.DeepEqual(expected, clone) {
+		t.Errorf("expected %#v, got %#v", expected, clone)
+	}
+	validateAction(Action{action: "create-build", value: "failed"}, fakeClient.actions[1], t)
+
+	if _, running, err := CloneBuild("running", &fakeClient); err != nil || !running {
+		t.Errorf("expected a running build to be flagged, got %v %v", running, err)
+	}
+}
+
+func TestPrintBuildWide(t *testing.T) {
+	build := &buildapi.Build{JSONBase: api.JSONBase{ID: "foo"}, Status: buildapi.BuildComplete, PodID: "build-foo", ParentID: "bar", OutputImage: "registry/foo:1"}
+	for _, wide := range []bool{false, true} {
+		buf := &bytes.Buffer{}
+		if err := (&HumanReadablePrinter{Wide: wide}).PrintObj(build, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hasParent := strings.Contains(buf.String(), "Parent ID") && strings.Contains(buf.String(), "bar"); hasParent != wide {
+			t.Errorf("wide=%v: unexpected output %q", wide, buf.String())
+		}
+		if hasImage := strings.Contains(buf.String(), "Output Image") && strings.Contains(buf.String(), "registry/foo:1"); hasImage != wide {
+			t.Errorf("wide=%v: unexpected output %q", wide, buf.String())
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
+)
+
+// Feature is something kubecfg asks of the apiserver that not every version of it supports.
+type Feature string
+
+const (
+	// FeatureFieldSelectors is filtering lists by fields, with the fields parameter.
+	FeatureFieldSelectors Feature = "field selectors"
+	// FeatureCreateIfMissing is creating an object by updating it, with createIfMissing.
+	FeatureCreateIfMissing Feature = "createIfMissing"
+	// FeatureDryRun is checking an object without storing it, with dryRun.
+	FeatureDryRun Feature = "dry runs"
+	// FeatureForce is storing an object despite the checks that may be overridden, with force.
+	FeatureForce Feature = "force"
+)
+
+// minorVersion is a version of the apiserver, by its major and minor numbers.
+type minorVersion struct {
+	major, minor int
+}
+
+func (v minorVersion) String() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+func (v minorVersion) before(other minorVersion) bool {
+	return v.major < other.major || v.major == other.major && v.minor < other.minor
+}
+
+// featureVersions maps each Feature to the first version of the apiserver that supports it.
+var featureVersions = map[Feature]minorVersion{
+	FeatureFieldSelectors:  {0, 1},
+	FeatureCreateIfMissing: {0, 1},
+	FeatureDryRun:          {0, 1},
+	FeatureForce:           {0, 1},
+}
+
+// parseVersion returns the major and minor numbers of info. Only the leading digits of each
+// count, so that development builds such as "1+" parse as the release they follow. ok is
+// false if either has none.
+func parseVersion(info *version.Info) (v minorVersion, ok bool) {
+	if info == nil {
+		return v, false
+	}
+	var majorOK, minorOK bool
+	v.major, majorOK = leadingNumber(info.Major)
+	v.minor, minorOK = leadingNumber(info.Minor)
+	return v, majorOK && minorOK
+}
+
+func leadingNumber(s string) (int, bool) {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	n, err := strconv.Atoi(s[:end])
+	return n, err == nil
+}
+
+// Compat tells which Features the apiserver kubecfg talks to supports, from the version of
+// the server. A server whose version is unknown or can't be parsed is assumed to support
+// them all, as is every server when Force is set.
+type Compat struct {
+	Client version.Info
+	// Server is the version the server reported, or nil if it didn't.
+	Server *version.Info
+	// Force assumes the server supports every Feature, for testing against servers whose
+	// version isn't accurate.
+	Force bool
+}
+
+// NewCompat returns the Compat of this kubecfg with a server of version server, which may be
+// nil if it is unknown.
+func NewCompat(server *version.Info, force bool) *Compat {
+	return &Compat{Client: version.Get(), Server: server, Force: force}
+}
+
+// Supports returns true unless the server is known to predate feature.
+func (c *Compat) Supports(feature Feature) bool {
+	return c.Check(feature) == nil
+}
+
+// Check returns an UnsupportedFeatureError if the server is known to predate feature, and
+// nil otherwise.
+func (c *Compat) Check(feature Feature) error {
+	if c.Force {
+		return nil
+	}
+	server, ok := parseVersion(c.Server)
+	needed, known := featureVersions[feature]
+	if !ok || !known || !server.before(needed) {
+		return nil
+	}
+	return &UnsupportedFeatureError{Feature: feature, Server: server.String(), Needed: needed.String()}
+}
+
+// Warning returns a one line warning if the minor versions of the client and the server
+// differ, and "" if they match or the version of the server is unknown.
+func (c *Compat) Warning() string {
+	server, ok := parseVersion(c.Server)
+	client, clientOK := parseVersion(&c.Client)
+	if !ok || !clientOK || server == client {
+		return ""
+	}
+	if server.before(client) {
+		return fmt.Sprintf("Warning: the server is older than kubecfg (version %s, kubecfg %s); features it doesn't support are disabled", server, client)
+	}
+	return fmt.Sprintf("Warning: the server is newer than kubecfg (version %s, kubecfg %s)", server, client)
+}
+
+// UnsupportedFeatureError is returned for a Feature that the server is too old to support.
+type UnsupportedFeatureError struct {
+	Feature Feature
+	// Server is the version of the server, and Needed the first that supports Feature.
+	Server string
+	Needed string
+}
+
+func (e *UnsupportedFeatureError) Error() string {
+	return fmt.Sprintf("the server (version %s) doesn't support %s, which needs version %s", e.Server, e.Feature, e.Needed)
+}
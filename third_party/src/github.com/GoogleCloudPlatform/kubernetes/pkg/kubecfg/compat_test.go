@@ -0,0 +1,69 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
+)
+
+func TestCompatSupports(t *testing.T) {
+	table := []struct {
+		server    *version.Info
+		force     bool
+		supported bool
+		warning   string
+	}{
+		{server: nil, supported: true},
+		{server: &version.Info{}, supported: true},
+		{server: &version.Info{Major: "0", Minor: "x"}, supported: true},
+		{server: &version.Info{Major: "0", Minor: "1"}, supported: true},
+		{server: &version.Info{Major: "0", Minor: "1+"}, supported: true},
+		{server: &version.Info{Major: "0", Minor: "0"}, supported: false, warning: "older"},
+		{server: &version.Info{Major: "0", Minor: "0"}, force: true, supported: true, warning: "older"},
+		{server: &version.Info{Major: "0", Minor: "2"}, supported: true, warning: "newer"},
+		{server: &version.Info{Major: "1", Minor: "0"}, supported: true, warning: "newer"},
+	}
+	for i, item := range table {
+		compat := &Compat{Client: version.Info{Major: "0", Minor: "1"}, Server: item.server, Force: item.force}
+		for feature := range featureVersions {
+			if compat.Supports(feature) != item.supported {
+				t.Errorf("%d: expected %s to be supported: %t", i, feature, item.supported)
+			}
+		}
+		warning := compat.Warning()
+		if len(item.warning) == 0 && len(warning) > 0 || !strings.Contains(warning, item.warning) {
+			t.Errorf("%d: unexpected warning %q", i, warning)
+		}
+	}
+}
+
+func TestCompatCheck(t *testing.T) {
+	compat := &Compat{Client: version.Info{Major: "0", Minor: "1"}, Server: &version.Info{Major: "0", Minor: "0"}}
+	err := compat.Check(FeatureDryRun)
+	if _, ok := err.(*UnsupportedFeatureError); !ok {
+		t.Fatalf("expected an unsupported feature error, got %v", err)
+	}
+	if expected := "the server (version 0.0) doesn't support dry runs, which needs version 0.1"; err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+	if err := compat.Check(Feature("unknown")); err != nil {
+		t.Errorf("unexpected error for a feature missing from the table: %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"gopkg.in/v1/yaml"
+)
+
+// ConfigObject is a single object read from a config file, along with enough
+// information to report errors against its origin.
+type ConfigObject struct {
+	// Source is the name of the file the object was read from, or "stdin".
+	Source string
+	// Index is the position of the object within Source, starting at 0.
+	Index int
+	// Data is the raw JSON or YAML for this object.
+	Data []byte
+	// Err is set instead of Data if the file or the document holding the object could not
+	// be read or parsed.
+	Err error
+}
+
+// String returns a description of where the object came from, suitable for error messages.
+func (o ConfigObject) String() string {
+	return fmt.Sprintf("%s[%d]", o.Source, o.Index)
+}
+
+// Storage returns the storage the object should be sent to, inferred from its Kind.
+// If the object does not declare a Kind, defaultStorage is returned.
+func (o ConfigObject) Storage(defaultStorage string) (string, error) {
+	_, kind, err := api.VersionAndKind(o.Data)
+	if err != nil {
+		return "", err
+	}
+	if kind == "" {
+		if defaultStorage == "" {
+			return "", fmt.Errorf("object has no kind and no storage was specified")
+		}
+		return defaultStorage, nil
+	}
+	storage, err := StorageForKind(kind)
+	if err != nil {
+		return "", err
+	}
+	if defaultStorage != "" && storage != defaultStorage {
+		return "", fmt.Errorf("object of kind %s cannot be sent to %s", kind, defaultStorage)
+	}
+	return storage, nil
+}
+
+// configExtensions are the file extensions considered when reading a directory of config files.
+var configExtensions = map[string]bool{
+	".json": true,
+	".yaml": true,
+	".yml":  true,
+}
+
+// yamlDocumentSeparator matches the line separating documents in a YAML stream.
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// LoadConfigObjects reads every object from path. If path is a directory, each file in it
+// with a .json, .yaml or .yml extension is read in name order. A file may contain a single
+// object, a JSON or YAML list of objects, or multiple YAML documents. A file or document
+// that can't be read or parsed is returned as an object with Err set, in its place, so that
+// the objects around it can still be used. An error is returned only if path can't be read.
+func LoadConfigObjects(path string) ([]ConfigObject, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = []string{}
+		for _, entry := range entries {
+			if entry.IsDir() || !configExtensions[strings.ToLower(filepath.Ext(entry.Name()))] {
+				continue
+			}
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+
+	objects := []ConfigObject{}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			objects = append(objects, ConfigObject{Source: file, Err: err})
+			continue
+		}
+		objects = append(objects, ParseConfigObjects(file, data)...)
+	}
+	return objects, nil
+}
+
+// ParseConfigObjects returns every object in data, read from source, as LoadConfigObjects
+// does for a single file. A document that can't be parsed is returned as an object with
+// Err set.
+func ParseConfigObjects(source string, data []byte) []ConfigObject {
+	objects := []ConfigObject{}
+	index := 0
+	for _, doc := range yamlDocumentSeparator.Split(string(data), -1) {
+		items, err := splitDocument([]byte(doc))
+		if err != nil {
+			objects = append(objects, ConfigObject{Source: source, Index: index, Err: fmt.Errorf("unable to parse: %v", err)})
+			index++
+			continue
+		}
+		for _, item := range items {
+			objects = append(objects, ConfigObject{Source: source, Index: index, Data: item})
+			index++
+		}
+	}
+	return objects
+}
+
+// SplitConfigData breaks data into the individual objects it contains. Data may hold
+// a single object, a JSON or YAML list of objects, or a stream of YAML documents.
+func SplitConfigData(data []byte) ([][]byte, error) {
+	result := [][]byte{}
+	for _, doc := range yamlDocumentSeparator.Split(string(data), -1) {
+		items, err := splitDocument([]byte(doc))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, items...)
+	}
+	return result, nil
+}
+
+// splitDocument breaks a single YAML document, which may also be JSON, into the objects it
+// contains.
+func splitDocument(doc []byte) ([][]byte, error) {
+	trimmed := bytes.TrimSpace(doc)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	switch trimmed[0] {
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+		result := [][]byte{}
+		for _, item := range items {
+			result = append(result, []byte(item))
+		}
+		return result, nil
+	case '-':
+		var items []interface{}
+		if err := yaml.Unmarshal(trimmed, &items); err != nil {
+			return nil, err
+		}
+		result := [][]byte{}
+		for _, item := range items {
+			out, err := yaml.Marshal(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, out)
+		}
+		return result, nil
+	}
+	return [][]byte{trimmed}, nil
+}
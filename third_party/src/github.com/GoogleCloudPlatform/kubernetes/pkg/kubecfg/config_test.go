@@ -0,0 +1,177 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitConfigData(t *testing.T) {
+	table := []struct {
+		data  string
+		count int
+	}{
+		{`{"kind": "Pod", "id": "foo"}`, 1},
+		{`[{"kind": "Pod", "id": "foo"}, {"kind": "Service", "id": "bar"}]`, 2},
+		{"kind: Pod\nid: foo\n---\nkind: Service\nid: bar\n", 2},
+		{"---\nkind: Pod\nid: foo\n---\n", 1},
+		{"- kind: Pod\n  id: foo\n- kind: Service\n  id: bar\n", 2},
+		{"", 0},
+	}
+	for _, item := range table {
+		objects, err := SplitConfigData([]byte(item.data))
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", item.data, err)
+			continue
+		}
+		if len(objects) != item.count {
+			t.Errorf("expected %d objects for %q, got %d: %q", item.count, item.data, len(objects), objects)
+		}
+	}
+}
+
+func TestSplitConfigDataInvalidList(t *testing.T) {
+	if _, err := SplitConfigData([]byte(`[{"kind": "Pod"`)); err == nil {
+		t.Errorf("expected error for malformed list")
+	}
+}
+
+func TestConfigObjectStorage(t *testing.T) {
+	table := []struct {
+		data           string
+		defaultStorage string
+		storage        string
+		err            bool
+	}{
+		{`{"kind": "Pod"}`, "", "pods", false},
+		{`{"kind": "ReplicationController"}`, "", "replicationControllers", false},
+		{`{"kind": "Build"}`, "builds", "builds", false},
+		{`{"id": "foo"}`, "services", "services", false},
+		{`{"id": "foo"}`, "", "", true},
+		{`{"kind": "Unknown"}`, "", "", true},
+		{`{"kind": "Pod"}`, "services", "", true},
+	}
+	for _, item := range table {
+		storage, err := ConfigObject{Data: []byte(item.data)}.Storage(item.defaultStorage)
+		if item.err {
+			if err == nil {
+				t.Errorf("expected error for %s", item.data)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", item.data, err)
+		}
+		if storage != item.storage {
+			t.Errorf("expected %s, got %s", item.storage, storage)
+		}
+	}
+}
+
+func TestLoadConfigObjectsFromDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubecfg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.json":     `{"kind": "Pod", "id": "foo"}`,
+		"b.yaml":     "kind: Service\nid: bar\n---\nkind: Service\nid: baz\n",
+		"ignore.txt": "not a config",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	objects, err := LoadConfigObjects(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects, got %#v", objects)
+	}
+	expected := []string{
+		filepath.Join(dir, "a.json") + "[0]",
+		filepath.Join(dir, "b.yaml") + "[0]",
+		filepath.Join(dir, "b.yaml") + "[1]",
+	}
+	for i := range expected {
+		if objects[i].String() != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], objects[i])
+		}
+	}
+}
+
+func TestLoadConfigObjectsParseErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubecfg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"a.json": `[{"kind": "Pod", "id": "foo"`,
+		"b.yaml": "kind: Service\nid: bar\n---\n- [unterminated\n---\nkind: Service\nid: baz\n",
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	objects, err := LoadConfigObjects(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []struct {
+		name  string
+		index int
+		err   bool
+	}{
+		{"a.json", 0, true},
+		{"b.yaml", 0, false},
+		{"b.yaml", 1, true},
+		{"b.yaml", 2, false},
+	}
+	if len(objects) != len(expected) {
+		t.Fatalf("expected %d objects, got %#v", len(expected), objects)
+	}
+	for i, e := range expected {
+		object := objects[i]
+		if object.Source != filepath.Join(dir, e.name) || object.Index != e.index || (object.Err != nil) != e.err {
+			t.Errorf("expected %s[%d] with error %t, got %s with error %v", e.name, e.index, e.err, object, object.Err)
+		}
+	}
+}
+
+func TestParseConfigObjects(t *testing.T) {
+	objects := ParseConfigObjects("stdin", []byte("kind: Pod\nid: foo\n---\n[{\"kind\": \"Service\", \"id\": \"bar\"}, {\"kind\": \"Service\", \"id\": \"baz\"}]\n"))
+	if len(objects) != 3 {
+		t.Fatalf("expected 3 objects, got %#v", objects)
+	}
+	for i, object := range objects {
+		if object.Source != "stdin" || object.Index != i || object.Err != nil {
+			t.Errorf("unexpected object %s: %v", object, object.Err)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+// maxDescribedEvents is the number of most recent events a description lists.
+const maxDescribedEvents = 10
+
+// Describer is like ResourcePrinter, but prints a detailed, multi-line description of a
+// single object, including what it can find out about the objects related to it.
+type Describer interface {
+	// Describe prints a description of obj, a pointer to an API object, to w.
+	Describe(obj interface{}, w io.Writer) error
+}
+
+// DescriberFor returns the Describer for obj, which fetches the related objects it
+// describes through c. Objects of kinds without a Describer are printed as YAML.
+func DescriberFor(obj interface{}, c *client.Client) Describer {
+	switch obj.(type) {
+	case *api.Pod:
+		return &PodDescriber{c}
+	case *api.Service:
+		return &ServiceDescriber{c}
+	case *api.ReplicationController:
+		return &ReplicationControllerDescriber{c}
+	case *api.Minion:
+		return &MinionDescriber{c}
+	case *buildapi.Build:
+		return &BuildDescriber{c}
+	}
+	return &yamlDescriber{}
+}
+
+// PodDescriber describes a pod: its containers, where it runs, and its recent events.
+type PodDescriber struct {
+	Client *client.Client
+}
+
+// Describe implements Describer.
+func (d *PodDescriber) Describe(obj interface{}, w io.Writer) error {
+	pod, ok := obj.(*api.Pod)
+	if !ok {
+		return fmt.Errorf("expected a pod, got %#v", obj)
+	}
+	events, err := listEvents(d.Client, pod.Namespace, "pod", pod.ID)
+	if err != nil {
+		return err
+	}
+	out := newDescription(w)
+	describeJSONBase(out, pod.JSONBase)
+	out.field("Labels", formatLabels(pod.Labels))
+	out.field("Host", describeHost(pod.CurrentState.Host, pod.CurrentState.HostIP))
+	out.field("Pod IP", pod.CurrentState.PodIP)
+	out.field("Status", string(pod.CurrentState.Status))
+	describeContainers(out, pod.DesiredState.Manifest.Containers)
+	describeEvents(out, events)
+	return out.flush()
+}
+
+// ServiceDescriber describes a service: its port and the pods it routes traffic to.
+type ServiceDescriber struct {
+	Client *client.Client
+}
+
+// Describe implements Describer.
+func (d *ServiceDescriber) Describe(obj interface{}, w io.Writer) error {
+	service, ok := obj.(*api.Service)
+	if !ok {
+		return fmt.Errorf("expected a service, got %#v", obj)
+	}
+	pods, err := listServicePods(d.Client, service)
+	if err != nil {
+		return err
+	}
+	events, err := listEvents(d.Client, service.Namespace, "service", service.ID)
+	if err != nil {
+		return err
+	}
+	out := newDescription(w)
+	describeJSONBase(out, service.JSONBase)
+	out.field("Labels", formatLabels(service.Labels))
+	out.field("Selector", formatLabels(service.Selector))
+	out.field("Port", fmt.Sprintf("%d", service.Port))
+	switch port := service.ContainerPort; {
+	case port.Kind == util.IntstrString:
+		out.field("Container Port", port.StrVal)
+	case port.IntVal != 0:
+		out.field("Container Port", fmt.Sprintf("%d", port.IntVal))
+	}
+	if service.CreateExternalLoadBalancer {
+		out.field("External Load Balancer", "yes")
+	}
+	describePods(out, pods)
+	describeEvents(out, events)
+	return out.flush()
+}
+
+// ReplicationControllerDescriber describes a replication controller: the pods it keeps
+// running, how many of them are running, and its recent events.
+type ReplicationControllerDescriber struct {
+	Client *client.Client
+}
+
+// Describe implements Describer.
+func (d *ReplicationControllerDescriber) Describe(obj interface{}, w io.Writer) error {
+	controller, ok := obj.(*api.ReplicationController)
+	if !ok {
+		return fmt.Errorf("expected a replication controller, got %#v", obj)
+	}
+	state := controller.DesiredState
+	pods, err := listPods(d.Client, controller.Namespace, state.ReplicaSelector)
+	if err != nil {
+		return err
+	}
+	events, err := listEvents(d.Client, controller.Namespace, "replicationController", controller.ID)
+	if err != nil {
+		return err
+	}
+	out := newDescription(w)
+	describeJSONBase(out, controller.JSONBase)
+	out.field("Labels", formatLabels(controller.Labels))
+	out.field("Selector", formatLabels(state.ReplicaSelector))
+	out.field("Replicas", fmt.Sprintf("%d desired, %d current", state.Replicas, len(pods.Items)))
+	out.field("Pod Status", describePodStatuses(pods))
+	describeContainers(out, state.PodTemplate.DesiredState.Manifest.Containers)
+	describePods(out, pods)
+	describeEvents(out, events)
+	return out.flush()
+}
+
+// MinionDescriber describes a minion: the pods running on it and its recent events.
+type MinionDescriber struct {
+	Client *client.Client
+}
+
+// Describe implements Describer.
+func (d *MinionDescriber) Describe(obj interface{}, w io.Writer) error {
+	minion, ok := obj.(*api.Minion)
+	if !ok {
+		return fmt.Errorf("expected a minion, got %#v", obj)
+	}
+	all, err := listPods(d.Client, api.NamespaceAll, nil)
+	if err != nil {
+		return err
+	}
+	pods := api.PodList{}
+	for _, pod := range all.Items {
+		if pod.CurrentState.Host == minion.ID {
+			pods.Items = append(pods.Items, pod)
+		}
+	}
+	events, err := listEvents(d.Client, minion.Namespace, "minion", minion.ID)
+	if err != nil {
+		return err
+	}
+	out := newDescription(w)
+	describeJSONBase(out, minion.JSONBase)
+	out.field("Host IP", minion.HostIP)
+	out.field("Status", string(minion.Status))
+	out.field("Addresses", strings.Join(minion.Addresses, ", "))
+	if usage := minion.Usage; usage != nil {
+		out.field("Pod Count", fmt.Sprintf("%d (%d unbounded)", usage.Pods, usage.UnboundedPods))
+		out.field("Requested CPU", strconv.Itoa(usage.CPU))
+		out.field("Requested Memory", strconv.Itoa(usage.Memory))
+	}
+	describePods(out, pods)
+	describeEvents(out, events)
+	return out.flush()
+}
+
+// BuildDescriber describes a build: what it builds, the pod it runs in, and its recent
+// events.
+type BuildDescriber struct {
+	Client *client.Client
+}
+
+// Describe implements Describer.
+func (d *BuildDescriber) Describe(obj interface{}, w io.Writer) error {
+	build, ok := obj.(*buildapi.Build)
+	if !ok {
+		return fmt.Errorf("expected a build, got %#v", obj)
+	}
+	var pod *api.Pod
+	if len(build.PodID) != 0 {
+		pod = &api.Pod{}
+		err := d.Client.Get().Namespace(build.Namespace).Path("pods").Path(build.PodID).Do().Into(pod)
+		if ExitCode(err) == ExitNotFound {
+			pod = nil
+		} else if err != nil {
+			return err
+		}
+	}
+	events, err := listEvents(d.Client, build.Namespace, "build", build.ID)
+	if err != nil {
+		return err
+	}
+	out := newDescription(w)
+	describeJSONBase(out, build.JSONBase)
+	out.field("Labels", formatLabels(build.Labels))
+	out.field("Status", string(build.Status))
+	out.field("Reason", string(build.Reason))
+	out.field("Type", string(build.Config.Type))
+	out.field("Source", build.Config.SourceURI)
+	out.field("Source Ref", build.Config.SourceRef)
+	out.field("Builder Image", build.Config.BuilderImage)
+	out.field("Image Tag", build.Config.ImageTag)
+	if build.Output != nil {
+		out.field("Output Registry", build.Output.Registry)
+		out.field("Output Image Name", build.Output.ImageName)
+		out.field("Output Tag Template", build.Output.TagTemplate)
+	}
+	out.field("Output Image", build.OutputImage)
+	if build.Revision != nil {
+		out.field("Commit", build.Revision.Commit)
+		out.field("Author", build.Revision.Author)
+	}
+	out.field("Parent", build.ParentID)
+	switch {
+	case pod != nil:
+		out.field("Pod", fmt.Sprintf("%s (%s on %s)", pod.ID, pod.CurrentState.Status, describeHost(pod.CurrentState.Host, pod.CurrentState.HostIP)))
+	case len(build.PodID) != 0:
+		out.field("Pod", build.PodID+" (not found)")
+	}
+	describeEvents(out, events)
+	return out.flush()
+}
+
+// yamlDescriber describes objects of kinds without a Describer by printing them as YAML.
+type yamlDescriber struct {
+	YAMLPrinter
+}
+
+// Describe implements Describer.
+func (d *yamlDescriber) Describe(obj interface{}, w io.Writer) error {
+	return d.PrintObj(obj, w)
+}
+
+// description writes the "Name:  value" lines and the indented sections of a description,
+// aligning values in a column. The lines of each section are aligned on their own.
+type description struct {
+	w         *tabwriter.Writer
+	inSection bool
+}
+
+func newDescription(w io.Writer) *description {
+	return &description{w: tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)}
+}
+
+// field writes a line naming value. Fields without a value are left out.
+func (d *description) field(name, value string) {
+	if len(value) == 0 {
+		return
+	}
+	if d.inSection {
+		d.w.Flush()
+		d.inSection = false
+	}
+	fmt.Fprintf(d.w, "%s:\t%s\n", name, value)
+}
+
+// section starts a section, whose lines follow it indented.
+func (d *description) section(name string) {
+	d.w.Flush()
+	d.inSection = true
+	fmt.Fprintf(d.w, "%s:\n", name)
+}
+
+// line writes a line of a section, its cells separated by tabs.
+func (d *description) line(indent int, cells ...string) {
+	fmt.Fprintf(d.w, "%s%s\n", strings.Repeat("  ", indent), strings.Join(cells, "\t"))
+}
+
+func (d *description) flush() error {
+	return d.w.Flush()
+}
+
+func describeJSONBase(out *description, base api.JSONBase) {
+	out.field("Name", base.ID)
+	namespace := base.Namespace
+	if len(namespace) == 0 {
+		namespace = api.NamespaceDefault
+	}
+	out.field("Namespace", namespace)
+	out.field("Created", base.CreationTimestamp)
+	out.field("Modified", base.LastModified)
+}
+
+func describeHost(host, hostIP string) string {
+	if len(hostIP) == 0 || hostIP == host {
+		return host
+	}
+	return host + "/" + hostIP
+}
+
+func describeContainers(out *description, containers []api.Container) {
+	if len(containers) == 0 {
+		return
+	}
+	out.section("Containers")
+	for _, container := range containers {
+		out.line(1, container.Name+":")
+		out.line(2, "Image:", container.Image)
+		if len(container.Command) != 0 {
+			out.line(2, "Command:", strings.Join(container.Command, " "))
+		}
+		if len(container.Ports) != 0 {
+			ports := []string{}
+			for _, port := range container.Ports {
+				protocol := port.Protocol
+				if len(protocol) == 0 {
+					protocol = "TCP"
+				}
+				spec := fmt.Sprintf("%d/%s", port.ContainerPort, protocol)
+				if port.HostPort != 0 {
+					spec += fmt.Sprintf(" (host %d)", port.HostPort)
+				}
+				ports = append(ports, spec)
+			}
+			out.line(2, "Ports:", strings.Join(ports, ", "))
+		}
+		for i, env := range container.Env {
+			name := ""
+			if i == 0 {
+				name = "Environment:"
+			}
+			out.line(2, name, env.Name+"="+env.Value)
+		}
+	}
+}
+
+// describePods lists pods, by name, with the host each runs on and its status.
+func describePods(out *description, pods api.PodList) {
+	if len(pods.Items) == 0 {
+		out.field("Pods", "<none>")
+		return
+	}
+	out.section("Pods")
+	items := append([]api.Pod{}, pods.Items...)
+	sort.Sort(podsByID(items))
+	for _, pod := range items {
+		out.line(1, pod.ID, describeHost(pod.CurrentState.Host, pod.CurrentState.HostIP), string(pod.CurrentState.Status))
+	}
+}
+
+// describePodStatuses counts pods by their status, e.g. "2 Running, 1 Waiting".
+func describePodStatuses(pods api.PodList) string {
+	counts := map[string]int{}
+	for _, pod := range pods.Items {
+		counts[string(pod.CurrentState.Status)]++
+	}
+	statuses := []string{}
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	parts := []string{}
+	for _, status := range statuses {
+		name := status
+		if len(name) == 0 {
+			name = "Unknown"
+		}
+		parts = append(parts, fmt.Sprintf("%d %s", counts[status], name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeEvents lists the most recent of events, oldest first.
+func describeEvents(out *description, events api.EventList) {
+	if len(events.Items) == 0 {
+		out.field("Events", "<none>")
+		return
+	}
+	items := append([]api.Event{}, events.Items...)
+	sort.Sort(eventsByTimestamp(items))
+	if len(items) > maxDescribedEvents {
+		items = items[len(items)-maxDescribedEvents:]
+	}
+	out.section("Events")
+	for _, event := range items {
+		out.line(1, event.Timestamp, event.Reason, event.Source, event.Message)
+	}
+}
+
+// listPods lists the pods of namespace that selector, a label set, selects. A nil
+// selector selects every pod.
+func listPods(c *client.Client, namespace string, selector map[string]string) (api.PodList, error) {
+	pods := api.PodList{}
+	if selector != nil && len(selector) == 0 {
+		return pods, nil
+	}
+	err := c.Get().Namespace(namespace).Path("pods").SelectorParam("labels", labels.Set(selector).AsSelector()).Do().Into(&pods)
+	return pods, err
+}
+
+// listServicePods lists the pods that the selector of service selects, which the server
+// serves as the pods of the service. A service with an empty selector has no pods.
+func listServicePods(c *client.Client, service *api.Service) (api.PodList, error) {
+	obj, err := c.Get().Namespace(service.Namespace).Path("services").Path(service.ID).Path("pods").Do().Get()
+	if err != nil {
+		return api.PodList{}, err
+	}
+	switch t := obj.(type) {
+	case *api.PodList:
+		return *t, nil
+	case *api.Status:
+		return api.PodList{}, nil
+	}
+	return api.PodList{}, fmt.Errorf("expected the pods of service %s, got %#v", service.ID, obj)
+}
+
+// listEvents lists the events of namespace about the object of kind called id.
+func listEvents(c *client.Client, namespace, kind, id string) (api.EventList, error) {
+	events := api.EventList{}
+	fields := labels.Set{"involvedObject.kind": kind, "involvedObject.id": id}
+	err := c.Get().Namespace(namespace).Path("events").SelectorParam("fields", fields.AsSelector()).Do().Into(&events)
+	return events, err
+}
+
+type podsByID []api.Pod
+
+func (p podsByID) Len() int           { return len(p) }
+func (p podsByID) Less(i, j int) bool { return p[i].ID < p[j].ID }
+func (p podsByID) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+
+type eventsByTimestamp []api.Event
+
+func (e eventsByTimestamp) Len() int           { return len(e) }
+func (e eventsByTimestamp) Less(i, j int) bool { return e[i].Timestamp < e[j].Timestamp }
+func (e eventsByTimestamp) Swap(i, j int)      { e[i], e[j] = e[j], e[i] }
@@ -0,0 +1,251 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig/buildconfigapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+)
+
+var updateGolden = flag.Bool("update", false, "If true, rewrite the golden descriptions in testdata")
+
+// describeServer answers GETs of the paths in objects with the object, and any other
+// request with 404.
+func describeServer(t *testing.T, objects map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		obj, ok := objects[req.URL.Path]
+		if !ok {
+			t.Logf("unexpected request %s", req.URL)
+			w.WriteHeader(http.StatusNotFound)
+			data, _ := api.Encode(&api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound})
+			w.Write(data)
+			return
+		}
+		data, err := api.Encode(obj)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(data)
+	}))
+}
+
+var describedPods = api.PodList{
+	Items: []api.Pod{
+		{
+			JSONBase: api.JSONBase{ID: "frontend-2", Namespace: "default"},
+			Labels:   map[string]string{"name": "frontend"},
+			CurrentState: api.PodState{
+				Host:   "minion-1",
+				HostIP: "10.0.0.1",
+				Status: api.PodRunning,
+			},
+		},
+		{
+			JSONBase: api.JSONBase{ID: "frontend-1", Namespace: "default"},
+			Labels:   map[string]string{"name": "frontend"},
+			CurrentState: api.PodState{
+				Host:   "minion-2",
+				Status: api.PodWaiting,
+			},
+		},
+	},
+}
+
+func describedEvents(kind, id string) api.EventList {
+	return api.EventList{
+		Items: []api.Event{
+			{
+				InvolvedObject: api.ObjectReference{Kind: kind, ID: id},
+				Reason:         "failedUpdate",
+				Source:         "apiserver",
+				Message:        "resource version conflict",
+				Timestamp:      "2014-07-01T12:05:00Z",
+			},
+			{
+				InvolvedObject: api.ObjectReference{Kind: kind, ID: id},
+				Reason:         "created",
+				Source:         "apiserver",
+				Message:        "created",
+				Timestamp:      "2014-07-01T12:00:00Z",
+			},
+		},
+	}
+}
+
+var describedContainers = []api.Container{
+	{
+		Name:    "php-redis",
+		Image:   "brendanburns/php-redis",
+		Command: []string{"/run.sh", "--verbose"},
+		Ports:   []api.Port{{ContainerPort: 80, HostPort: 8000}, {ContainerPort: 53, Protocol: "UDP"}},
+		Env:     []api.EnvVar{{Name: "REDIS_HOST", Value: "redis-master"}, {Name: "DEBUG", Value: "1"}},
+	},
+	{
+		Name:  "sidecar",
+		Image: "busybox",
+	},
+}
+
+func TestDescribeGolden(t *testing.T) {
+	events := "/api/v1beta1/ns/default/events"
+	pods := "/api/v1beta1/ns/default/pods"
+	table := []struct {
+		golden  string
+		obj     interface{}
+		objects map[string]interface{}
+	}{
+		{
+			golden: "pod.txt",
+			obj: &api.Pod{
+				JSONBase: api.JSONBase{ID: "frontend-1", Namespace: "default", CreationTimestamp: "2014-07-01T11:59:00Z", LastModified: "2014-07-01T12:00:00Z"},
+				Labels:   map[string]string{"name": "frontend", "env": "prod"},
+				DesiredState: api.PodState{
+					Manifest: api.ContainerManifest{Containers: describedContainers},
+				},
+				CurrentState: api.PodState{
+					Host:   "minion-1",
+					HostIP: "10.0.0.1",
+					PodIP:  "172.17.0.4",
+					Status: api.PodRunning,
+				},
+			},
+			objects: map[string]interface{}{events: describedEvents("pod", "frontend-1")},
+		},
+		{
+			golden: "service.txt",
+			obj: &api.Service{
+				JSONBase:      api.JSONBase{ID: "frontend", Namespace: "default"},
+				Port:          9998,
+				Labels:        map[string]string{"name": "frontend"},
+				Selector:      map[string]string{"name": "frontend"},
+				ContainerPort: util.MakeIntOrStringFromInt(80),
+			},
+			objects: map[string]interface{}{
+				"/api/v1beta1/ns/default/services/frontend/pods": describedPods,
+				events: api.EventList{},
+			},
+		},
+		{
+			golden: "controller.txt",
+			obj: &api.ReplicationController{
+				JSONBase: api.JSONBase{ID: "frontend-controller", Namespace: "default"},
+				DesiredState: api.ReplicationControllerState{
+					Replicas:        3,
+					ReplicaSelector: map[string]string{"name": "frontend"},
+					PodTemplate: api.PodTemplate{
+						DesiredState: api.PodState{Manifest: api.ContainerManifest{Containers: describedContainers[:1]}},
+					},
+				},
+			},
+			objects: map[string]interface{}{
+				pods:   describedPods,
+				events: describedEvents("replicationController", "frontend-controller"),
+			},
+		},
+		{
+			golden: "minion.txt",
+			obj: &api.Minion{
+				JSONBase:  api.JSONBase{ID: "minion-2", Namespace: "default"},
+				HostIP:    "10.0.0.2",
+				Status:    api.MinionReachable,
+				Addresses: []string{"10.0.0.2", "192.168.0.2"},
+				Usage:     &api.MinionUsage{Pods: 2, Memory: 1536, CPU: 500, UnboundedPods: 1},
+			},
+			objects: map[string]interface{}{
+				"/api/v1beta1/pods": describedPods,
+				events:              api.EventList{},
+			},
+		},
+		{
+			golden: "build.txt",
+			obj: &buildapi.Build{
+				JSONBase: api.JSONBase{ID: "build-1", Namespace: "default"},
+				Status:   buildapi.BuildRunning,
+				PodID:    "build-pod-1",
+				Config: buildconfigapi.BuildConfig{
+					Type:      buildconfigapi.DockerBuildType,
+					SourceURI: "git://github.com/openshift/ruby-hello-world.git",
+					ImageTag:  "openshift/ruby-hello-world",
+				},
+				Revision: &buildapi.SourceRevision{Commit: "4a8f0b2", Author: "Jane Doe <jane@example.com>"},
+				Output:   &buildapi.BuildOutput{Registry: "registry:5000", TagTemplate: "${COMMIT}"},
+			},
+			objects: map[string]interface{}{
+				"/api/v1beta1/ns/default/pods/build-pod-1": api.Pod{
+					JSONBase:     api.JSONBase{ID: "build-pod-1"},
+					CurrentState: api.PodState{Host: "minion-1", Status: api.PodRunning},
+				},
+				events: describedEvents("build", "build-1"),
+			},
+		},
+		{
+			golden: "event.txt",
+			obj: &api.Event{
+				JSONBase:       api.JSONBase{ID: "event-1"},
+				InvolvedObject: api.ObjectReference{Kind: "pod", ID: "frontend-1"},
+				Reason:         "created",
+			},
+		},
+	}
+	for _, item := range table {
+		server := describeServer(t, item.objects)
+		buf := &bytes.Buffer{}
+		if err := DescriberFor(item.obj, client.New(server.URL, nil)).Describe(item.obj, buf); err != nil {
+			t.Errorf("%s: unexpected error: %v", item.golden, err)
+		}
+		server.Close()
+
+		golden := filepath.Join("testdata", "describe", item.golden)
+		if *updateGolden {
+			if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			continue
+		}
+		expected, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(buf.Bytes(), expected) {
+			t.Errorf("description differs from %s, run the test with -update if the change is intended:\n%s", golden, buf.String())
+		}
+	}
+}
+
+func TestDescribeBuildWithoutPod(t *testing.T) {
+	server := describeServer(t, map[string]interface{}{"/api/v1beta1/events": api.EventList{}})
+	defer server.Close()
+	build := &buildapi.Build{JSONBase: api.JSONBase{ID: "build-1"}, Status: buildapi.BuildFailed, PodID: "gone"}
+	buf := &bytes.Buffer{}
+	if err := DescriberFor(build, client.New(server.URL, nil)).Describe(build, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("gone (not found)")) {
+		t.Errorf("expected a deleted pod to be described as not found, got:\n%s", buf.String())
+	}
+}
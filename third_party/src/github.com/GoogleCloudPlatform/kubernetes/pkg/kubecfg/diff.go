@@ -0,0 +1,205 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"gopkg.in/v1/yaml"
+)
+
+// DiffIgnoredFields are the fields the server manages, by kind, which DiffObjects leaves
+// out of both objects. Each field is a path of JSON keys separated by dots. The fields
+// under "" are left out of objects of every kind.
+var DiffIgnoredFields = map[string][]string{
+	"":       {"creationTimestamp", "resourceVersion", "selfLink"},
+	"Pod":    {"currentState", "desiredState.host"},
+	"Build":  {"status", "podID", "revision", "reason"},
+	"Minion": {"status", "addresses", "usage"},
+}
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// DiffObjects returns a unified diff of the YAML of local, as read from a config file, and
+// live, as returned by the server, or "" if they are the same. Both objects are encoded
+// through the API codec first, so that fields defaulted on the way don't show as changes,
+// and the fields of DiffIgnoredFields are left out.
+func DiffObjects(local, live interface{}) (string, error) {
+	from, err := diffYAML(local)
+	if err != nil {
+		return "", err
+	}
+	to, err := diffYAML(live)
+	if err != nil {
+		return "", err
+	}
+	return UnifiedDiff("local", "live", from, to), nil
+}
+
+// diffYAML returns the lines of the normalized YAML of obj.
+func diffYAML(obj interface{}) ([]string, error) {
+	data, err := api.Encode(obj)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	kind, _ := fields["kind"].(string)
+	for _, field := range append(DiffIgnoredFields[""], DiffIgnoredFields[kind]...) {
+		deleteField(fields, strings.Split(field, "."))
+	}
+	data, err = yaml.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), nil
+}
+
+// deleteField removes the field at path from fields, if it is there.
+func deleteField(fields map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(fields, path[0])
+		return
+	}
+	if nested, ok := fields[path[0]].(map[string]interface{}); ok {
+		deleteField(nested, path[1:])
+	}
+}
+
+// UnifiedDiff returns the changes from the lines 'from' to the lines 'to' in the unified
+// format of diff -u, labelling them fromName and toName, or "" if the lines are the same.
+func UnifiedDiff(fromName, toName string, from, to []string) string {
+	// common[i][j] is the length of the longest common subsequence of from[i:] and to[j:].
+	common := make([][]int, len(from)+1)
+	for i := range common {
+		common[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	// Each line of the diff is a line of from, to, or both, prefixed by '-', '+' or ' '.
+	type diffLine struct {
+		op   byte
+		text string
+	}
+	lines := []diffLine{}
+	changed := false
+	i, j := 0, 0
+	for i < len(from) || j < len(to) {
+		switch {
+		case i < len(from) && j < len(to) && from[i] == to[j]:
+			lines = append(lines, diffLine{' ', from[i]})
+			i++
+			j++
+		case j == len(to) || (i < len(from) && common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, diffLine{'-', from[i]})
+			changed = true
+			i++
+		default:
+			lines = append(lines, diffLine{'+', to[j]})
+			changed = true
+			j++
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "--- %s\n+++ %s\n", fromName, toName)
+	// fromLine and toLine are the line numbers, from 1, of lines[k] in from and to.
+	fromLine, toLine := 1, 1
+	for k := 0; k < len(lines); {
+		if lines[k].op == ' ' {
+			k++
+			fromLine++
+			toLine++
+			continue
+		}
+		// A hunk starts diffContext lines before a change, and ends diffContext lines after
+		// the last change that is no more than 2*diffContext lines from the previous one.
+		start := k
+		for start > 0 && k-start < diffContext && lines[start-1].op == ' ' {
+			start--
+		}
+		end := k
+		for unchanged := 0; end < len(lines) && unchanged <= 2*diffContext; end++ {
+			if lines[end].op == ' ' {
+				unchanged++
+			} else {
+				unchanged = 0
+			}
+		}
+		for end > k && lines[end-1].op == ' ' {
+			end--
+		}
+		for after := 0; end < len(lines) && after < diffContext; after++ {
+			end++
+		}
+		hunkFrom, hunkTo := fromLine-(k-start), toLine-(k-start)
+		fromCount, toCount := 0, 0
+		for _, line := range lines[start:end] {
+			if line.op != '+' {
+				fromCount++
+			}
+			if line.op != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(buf, "@@ -%s +%s @@\n", hunkRange(hunkFrom, fromCount), hunkRange(hunkTo, toCount))
+		for _, line := range lines[start:end] {
+			fmt.Fprintf(buf, "%c%s\n", line.op, line.text)
+		}
+		for _, line := range lines[k:end] {
+			if line.op != '+' {
+				fromLine++
+			}
+			if line.op != '-' {
+				toLine++
+			}
+		}
+		k = end
+	}
+	return buf.String()
+}
+
+// hunkRange formats the range of count lines starting at line start as diff -u does.
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
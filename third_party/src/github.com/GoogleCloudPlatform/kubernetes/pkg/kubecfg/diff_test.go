@@ -0,0 +1,78 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	table := []struct {
+		from, to string
+		expected string
+	}{
+		{"a b c", "a b c", ""},
+		{"a b c", "a x c", "--- from\n+++ to\n@@ -1,3 +1,3 @@\n a\n-b\n+x\n c\n"},
+		{"", "a", "--- from\n+++ to\n@@ -0,0 +1 @@\n+a\n"},
+		{"a", "", "--- from\n+++ to\n@@ -1 +0,0 @@\n-a\n"},
+		// Changes further apart than twice the context are in separate hunks.
+		{"1 2 3 4 5 6 7 8 9 10 11 12", "x 2 3 4 5 6 7 8 9 10 11 y",
+			"--- from\n+++ to\n@@ -1,4 +1,4 @@\n-1\n+x\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+y\n"},
+		// Closer changes share a hunk.
+		{"1 2 3 4 5 6 7 8", "x 2 3 4 5 6 7 y",
+			"--- from\n+++ to\n@@ -1,8 +1,8 @@\n-1\n+x\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+y\n"},
+	}
+	for _, item := range table {
+		if actual := UnifiedDiff("from", "to", strings.Fields(item.from), strings.Fields(item.to)); actual != item.expected {
+			t.Errorf("%q to %q: expected\n%s\ngot\n%s", item.from, item.to, item.expected, actual)
+		}
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	local := &api.Pod{
+		JSONBase: api.JSONBase{ID: "web"},
+		Labels:   map[string]string{"name": "web"},
+	}
+	live := &api.Pod{
+		JSONBase: api.JSONBase{ID: "web", ResourceVersion: 4, SelfLink: "/api/v1beta1/pods/web"},
+		Labels:   map[string]string{"name": "web"},
+		DesiredState: api.PodState{
+			Host: "minion1",
+		},
+		CurrentState: api.PodState{Status: api.PodRunning, Host: "minion1"},
+	}
+	diff, err := DiffObjects(local, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff != "" {
+		t.Errorf("expected the fields the server manages to be ignored, got\n%s", diff)
+	}
+
+	live.Labels["name"] = "api"
+	diff, err = DiffObjects(local, live)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(diff, "-  name: web\n+  name: api\n") {
+		t.Errorf("unexpected diff\n%s", diff)
+	}
+}
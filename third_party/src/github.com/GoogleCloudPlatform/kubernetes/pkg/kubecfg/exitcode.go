@@ -0,0 +1,106 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+// Exit codes returned by kubecfg.
+const (
+	// ExitSuccess means the operation completed, or was accepted by the server and not waited for.
+	ExitSuccess = 0
+	// ExitError is returned for any failure without a more specific code.
+	ExitError = 1
+	// ExitUsage means the command line was invalid.
+	ExitUsage = 2
+	// ExitNotFound means the requested object does not exist.
+	ExitNotFound = 3
+	// ExitConflict means the object already exists or was modified concurrently.
+	ExitConflict = 4
+	// ExitTimeout means the server accepted the request but it did not complete in time, or
+	// the condition waited for did not hold in time.
+	ExitTimeout = 5
+	// ExitInvalid means the server rejected the object as invalid.
+	ExitInvalid = 6
+)
+
+// ExitCode returns the exit code that describes the outcome of a request that returned err.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitSuccess
+	}
+	switch e := err.(type) {
+	case *client.StatusErr:
+		return ExitCodeForStatus(e.Status)
+	case *OperationTimeoutError, *WaitTimeoutError:
+		return ExitTimeout
+	case api.InvalidIDError:
+		return ExitInvalid
+	}
+	return ExitError
+}
+
+// ExitCodeForStatus returns the exit code that describes status, using its reason if the
+// server gave one and its HTTP code otherwise.
+func ExitCodeForStatus(status api.Status) int {
+	switch status.Status {
+	case api.StatusSuccess, api.StatusWorking:
+		return ExitSuccess
+	}
+	switch status.Reason {
+	case api.ReasonTypeNotFound:
+		return ExitNotFound
+	case api.ReasonTypeAlreadyExists, api.ReasonTypeConflict:
+		return ExitConflict
+	case api.ReasonTypeInvalid:
+		return ExitInvalid
+	}
+	switch status.Code {
+	case http.StatusNotFound:
+		return ExitNotFound
+	case http.StatusConflict:
+		return ExitConflict
+	}
+	return ExitError
+}
+
+// InvalidFields returns a line for each invalid field of an object the server rejected
+// with err, naming the field and what is wrong with it, or the other object it conflicts
+// with. It returns nil if err is neither an invalid object nor a conflict error.
+func InvalidFields(err error) []string {
+	statusErr, ok := err.(*client.StatusErr)
+	if !ok || statusErr.Status.Details == nil {
+		return nil
+	}
+	if reason := statusErr.Status.Reason; reason != api.ReasonTypeInvalid && reason != api.ReasonTypeConflict {
+		return nil
+	}
+	lines := []string{}
+	for _, cause := range statusErr.Status.Details.Causes {
+		if len(cause.Field) == 0 {
+			lines = append(lines, cause.Message)
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s", cause.Field, cause.Message))
+		}
+	}
+	return lines
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+// statusServer responds to every request with 'code' and 'status', except for requests
+// for the operation "op1", which receive 'final'.
+func statusServer(t *testing.T, code int, status api.Status, final *api.Status) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if final != nil && req.URL.Path == "/api/v1beta1/operations/op1" {
+			code, status = final.Code, *final
+		}
+		data, err := api.Encode(status)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.WriteHeader(code)
+		w.Write(data)
+	}))
+}
+
+func TestExitCodeFromServer(t *testing.T) {
+	working := api.Status{Status: api.StatusWorking, Code: http.StatusAccepted, Details: &api.StatusDetails{ID: "op1"}}
+	table := []struct {
+		name   string
+		code   int
+		status api.Status
+		final  *api.Status
+		wait   bool
+		exit   int
+	}{
+		{"success", http.StatusOK, api.Status{Status: api.StatusSuccess}, nil, false, ExitSuccess},
+		{"not found", http.StatusNotFound, api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound}, nil, false, ExitNotFound},
+		{"not found without reason", http.StatusNotFound, api.Status{Status: api.StatusFailure, Code: http.StatusNotFound}, nil, false, ExitNotFound},
+		{"already exists", http.StatusConflict, api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeAlreadyExists}, nil, false, ExitConflict},
+		{"conflict", http.StatusConflict, api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeConflict}, nil, false, ExitConflict},
+		{"invalid", 422, api.Status{Status: api.StatusFailure, Code: 422, Reason: api.ReasonTypeInvalid}, nil, false, ExitInvalid},
+		{"server error", http.StatusInternalServerError, api.Status{Status: api.StatusFailure, Code: http.StatusInternalServerError}, nil, false, ExitError},
+		{"accepted, not waiting", http.StatusAccepted, working, nil, false, ExitSuccess},
+		{"accepted, waiting for success", http.StatusAccepted, working, &api.Status{Status: api.StatusSuccess, Code: http.StatusOK}, true, ExitSuccess},
+		{"accepted, waiting for failure", http.StatusAccepted, working, &api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeAlreadyExists}, true, ExitConflict},
+	}
+	for _, item := range table {
+		server := statusServer(t, item.code, item.status, item.final)
+		c := client.New(server.URL, nil)
+		pollPeriod := time.Duration(0)
+		if item.wait {
+			pollPeriod = time.Millisecond
+		}
+		err := c.Post().Path("pods").Body([]byte("{}")).PollPeriod(pollPeriod).Do().Error()
+		if code := ExitCode(err); code != item.exit {
+			t.Errorf("%s: expected exit code %d, got %d (%v)", item.name, item.exit, code, err)
+		}
+		server.Close()
+	}
+}
+
+func TestExitCodeGenericError(t *testing.T) {
+	if code := ExitCode(errors.New("connection refused")); code != ExitError {
+		t.Errorf("expected %d, got %d", ExitError, code)
+	}
+	if code := ExitCode(nil); code != ExitSuccess {
+		t.Errorf("expected %d, got %d", ExitSuccess, code)
+	}
+}
+
+func TestInvalidFields(t *testing.T) {
+	status := api.Status{
+		Status: api.StatusFailure,
+		Code:   422,
+		Reason: api.ReasonTypeInvalid,
+		Details: &api.StatusDetails{
+			Kind: "pod",
+			ID:   "foo",
+			Causes: []api.StatusCause{
+				{Type: api.CauseTypeFieldValueInvalid, Field: "desiredState.manifest.containers[0].ports[0].containerPort", Message: "invalid value '-1'"},
+				{Message: "something else"},
+			},
+		},
+	}
+	server := statusServer(t, 422, status, nil)
+	defer server.Close()
+	err := client.New(server.URL, nil).Post().Path("pods").Body([]byte("{}")).Do().Error()
+	expected := []string{
+		"desiredState.manifest.containers[0].ports[0].containerPort: invalid value '-1'",
+		"something else",
+	}
+	if lines := InvalidFields(err); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %#v, got %#v", expected, lines)
+	}
+	if lines := InvalidFields(errors.New("connection refused")); lines != nil {
+		t.Errorf("expected no lines for other errors, got %#v", lines)
+	}
+
+	conflict := api.Status{
+		Status: api.StatusFailure,
+		Code:   409,
+		Reason: api.ReasonTypeConflict,
+		Details: &api.StatusDetails{
+			Kind: "replicationController",
+			ID:   "front",
+			Causes: []api.StatusCause{
+				{Type: api.CauseTypeFieldValueConflict, Field: "desiredState.replicaSelector", Message: `conflicts with replicationController "web"`},
+			},
+		},
+	}
+	conflictServer := statusServer(t, 409, conflict, nil)
+	defer conflictServer.Close()
+	err = client.New(conflictServer.URL, nil).Post().Path("replicationControllers").Body([]byte("{}")).Do().Error()
+	expected = []string{`desiredState.replicaSelector: conflicts with replicationController "web"`}
+	if lines := InvalidFields(err); !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %#v, got %#v", expected, lines)
+	}
+}
+
+// operationServer reports operation "op1" as working for the first 'polls' requests, asking
+// to poll again after retryAfter if it isn't empty, and then returns a pod.
+func operationServer(polls int, retryAfter string) (*httptest.Server, *int) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1beta1/operations/op1" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		count++
+		if count <= polls {
+			data, _ := api.Encode(api.Status{Status: api.StatusWorking, Code: http.StatusAccepted, Details: &api.StatusDetails{ID: "op1"}})
+			if len(retryAfter) != 0 {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			w.Write(data)
+			return
+		}
+		data, _ := api.Encode(api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+		w.Write(data)
+	}))
+	return server, &count
+}
+
+func TestWaitForOperation(t *testing.T) {
+	server, count := operationServer(2, "")
+	defer server.Close()
+	obj, err := WaitForOperation(client.New(server.URL, nil), "op1", 0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pod, ok := obj.(*api.Pod); !ok || pod.ID != "foo" {
+		t.Errorf("unexpected result: %#v", obj)
+	}
+	if *count != 3 {
+		t.Errorf("expected 3 polls, got %d", *count)
+	}
+}
+
+func TestWaitForOperationTimeout(t *testing.T) {
+	server, _ := operationServer(1000, "")
+	defer server.Close()
+	_, err := WaitForOperation(client.New(server.URL, nil), "op1", 250*time.Millisecond, nil)
+	if _, ok := err.(*OperationTimeoutError); !ok {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if code := ExitCode(err); code != ExitTimeout {
+		t.Errorf("expected exit code %d, got %d", ExitTimeout, code)
+	}
+}
+
+func TestWaitForOperationHonorsRetryAfter(t *testing.T) {
+	server, count := operationServer(1000, "1")
+	defer server.Close()
+	_, err := WaitForOperation(client.New(server.URL, nil), "op1", 500*time.Millisecond, nil)
+	if _, ok := err.(*OperationTimeoutError); !ok {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	// Waiting a second to poll again would pass the deadline.
+	if *count != 1 {
+		t.Errorf("expected a single poll, got %d", *count)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+// PortPair is a local port forwarded to a port of a pod.
+type PortPair struct {
+	// Local is the local port to listen on; 0 picks any free port.
+	Local int
+	Pod   int
+}
+
+// ParsePortPairs parses specs of the form <local port>:<pod port>, or <port> to use the same
+// port on both ends.
+func ParsePortPairs(specs []string) ([]PortPair, error) {
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no ports to forward")
+	}
+	pairs := []PortPair{}
+	for _, spec := range specs {
+		parts := strings.Split(spec, ":")
+		if len(parts) == 1 {
+			parts = append(parts, parts[0])
+		}
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q should be <local port>:<pod port>", spec)
+		}
+		local, err := strconv.Atoi(parts[0])
+		if err != nil || local < 0 || local > 65535 {
+			return nil, fmt.Errorf("invalid local port in %q", spec)
+		}
+		pod, err := strconv.Atoi(parts[1])
+		if err != nil || pod <= 0 || pod > 65535 {
+			return nil, fmt.Errorf("invalid pod port in %q", spec)
+		}
+		pairs = append(pairs, PortPair{Local: local, Pod: pod})
+	}
+	return pairs, nil
+}
+
+// PortForwarder relays the TCP connections made to local ports to the ports of a pod. The
+// pod is looked up through the API server for each connection, so that a pod that moves or
+// restarts is followed, and the connection is made directly to its IP, which must be
+// reachable from where kubecfg runs.
+type PortForwarder struct {
+	Client    *client.Client
+	Namespace string
+	PodID     string
+	Pairs     []PortPair
+	// Out is where the bound addresses are printed, and Err where the connections that
+	// couldn't be forwarded are reported.
+	Out io.Writer
+	Err io.Writer
+	// DialTimeout bounds how long a connection to the pod may take to open.
+	DialTimeout time.Duration
+
+	listeners []net.Listener
+}
+
+// Listen binds a local listener on 127.0.0.1 for each pair, and prints the address it is
+// bound to. Nothing is bound if any of the ports can't be.
+func (f *PortForwarder) Listen() error {
+	for _, pair := range f.Pairs {
+		listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(pair.Local)))
+		if err != nil {
+			f.Close()
+			return err
+		}
+		f.listeners = append(f.listeners, listener)
+		fmt.Fprintf(f.Out, "Forwarding from %s -> %d\n", listener.Addr(), pair.Pod)
+	}
+	return nil
+}
+
+// Addrs returns the addresses the listeners are bound to, in the order of the pairs.
+func (f *PortForwarder) Addrs() []net.Addr {
+	addrs := []net.Addr{}
+	for _, listener := range f.listeners {
+		addrs = append(addrs, listener.Addr())
+	}
+	return addrs
+}
+
+// Close closes the listeners. Connections already forwarded are left to finish.
+func (f *PortForwarder) Close() {
+	for _, listener := range f.listeners {
+		listener.Close()
+	}
+}
+
+// Serve forwards the connections accepted by the listeners until stop is closed. A
+// connection that can't be forwarded is reported and closed, and the listener keeps
+// accepting others.
+func (f *PortForwarder) Serve(stop <-chan struct{}) {
+	var wg sync.WaitGroup
+	for i := range f.listeners {
+		wg.Add(1)
+		go func(listener net.Listener, pair PortPair) {
+			defer wg.Done()
+			for {
+				conn, err := listener.Accept()
+				if err != nil {
+					select {
+					case <-stop:
+					default:
+						fmt.Fprintf(f.Err, "Error accepting connections on %s: %v\n", listener.Addr(), err)
+					}
+					return
+				}
+				go f.forward(conn, pair)
+			}
+		}(f.listeners[i], f.Pairs[i])
+	}
+	<-stop
+	f.Close()
+	wg.Wait()
+}
+
+// forward relays the bytes of conn to and from the pod port of pair, until either end closes.
+func (f *PortForwarder) forward(conn net.Conn, pair PortPair) {
+	defer conn.Close()
+	podConn, err := f.dial(pair.Pod)
+	if err != nil {
+		fmt.Fprintf(f.Err, "Error forwarding a connection from %s to port %d of pod %s: %v\n", conn.RemoteAddr(), pair.Pod, f.PodID, err)
+		return
+	}
+	defer podConn.Close()
+
+	done := make(chan struct{}, 2)
+	relay := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		// Let the other end know nothing more is coming, without dropping what it still sends.
+		if tcp, ok := dst.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+		done <- struct{}{}
+	}
+	go relay(podConn, conn)
+	go relay(conn, podConn)
+	<-done
+	<-done
+}
+
+// dial opens a connection to port of the pod, at the IP the API server reports for it.
+func (f *PortForwarder) dial(port int) (net.Conn, error) {
+	obj, err := f.Client.Get().Namespace(f.Namespace).Path("pods").Name(f.PodID).Do().Get()
+	if err != nil {
+		return nil, err
+	}
+	pod, ok := obj.(*api.Pod)
+	if !ok {
+		return nil, fmt.Errorf("expected a pod, got %T", obj)
+	}
+	if len(pod.CurrentState.PodIP) == 0 {
+		return nil, fmt.Errorf("the pod has no IP, it may not be running yet (status %s)", pod.CurrentState.Status)
+	}
+	timeout := f.DialTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	return net.DialTimeout("tcp", net.JoinHostPort(pod.CurrentState.PodIP, strconv.Itoa(port)), timeout)
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+func TestParsePortPairs(t *testing.T) {
+	pairs, err := ParsePortPairs([]string{"8080:80", "0:443", "5000"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []PortPair{{8080, 80}, {0, 443}, {5000, 5000}}
+	if !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("expected %v, got %v", expected, pairs)
+	}
+
+	for _, specs := range [][]string{{}, {"a:80"}, {"80:b"}, {"80:0"}, {"1:2:3"}, {"-1:80"}, {"80:70000"}} {
+		if _, err := ParsePortPairs(specs); err == nil {
+			t.Errorf("%v: expected an error", specs)
+		}
+	}
+}
+
+// echoListener echoes what is written to the connections it accepts, prefixed with prefix.
+func echoListener(t *testing.T, prefix string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte(prefix))
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return listener
+}
+
+func listenerPort(t *testing.T, listener net.Listener) int {
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return n
+}
+
+// podServer serves pods/foo with the IP in ip.
+type podServer struct {
+	lock sync.Mutex
+	ip   string
+}
+
+func (s *podServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	data, _ := api.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo"}, CurrentState: api.PodState{Status: api.PodWaiting, PodIP: s.ip}})
+	w.Write(data)
+}
+
+func (s *podServer) setIP(ip string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.ip = ip
+}
+
+// roundTrip writes data to addr and returns what is read back once the write side is closed.
+func roundTrip(t *testing.T, addr net.Addr, data string) string {
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte(data))
+	conn.(*net.TCPConn).CloseWrite()
+	read, _ := ioutil.ReadAll(conn)
+	return string(read)
+}
+
+func TestPortForwarder(t *testing.T) {
+	web, admin := echoListener(t, "web:"), echoListener(t, "admin:")
+	defer web.Close()
+	defer admin.Close()
+	pods := &podServer{}
+	server := httptest.NewServer(pods)
+	defer server.Close()
+
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	f := &PortForwarder{
+		Client: client.New(server.URL, nil),
+		PodID:  "foo",
+		Pairs:  []PortPair{{0, listenerPort(t, web)}, {0, listenerPort(t, admin)}},
+		Out:    out,
+		Err:    errOut,
+	}
+	if err := f.Listen(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addrs := f.Addrs()
+	if len(addrs) != 2 || !strings.Contains(out.String(), "Forwarding from "+addrs[0].String()) {
+		t.Errorf("expected the bound addresses to be printed, got %q", out.String())
+	}
+	stop := make(chan struct{})
+	served := make(chan struct{})
+	go func() {
+		f.Serve(stop)
+		close(served)
+	}()
+
+	// The pod has no IP yet: the connection is closed and reported, and the listener stays.
+	if got := roundTrip(t, addrs[0], "hello"); got != "" {
+		t.Errorf("expected nothing to be read, got %q", got)
+	}
+	if !strings.Contains(errOut.String(), "pod foo") {
+		t.Errorf("expected the connection error to be reported, got %q", errOut.String())
+	}
+
+	pods.setIP("127.0.0.1")
+	if got := roundTrip(t, addrs[0], "hello"); got != "web:hello" {
+		t.Errorf("unexpected reply %q", got)
+	}
+	if got := roundTrip(t, addrs[1], "there"); got != "admin:there" {
+		t.Errorf("unexpected reply %q", got)
+	}
+
+	close(stop)
+	<-served
+	if _, err := net.Dial("tcp", addrs[0].String()); err == nil {
+		t.Errorf("expected the listeners to be closed")
+	}
+}
+
+func TestPortForwarderListenFails(t *testing.T) {
+	taken := echoListener(t, "")
+	defer taken.Close()
+	f := &PortForwarder{
+		Pairs: []PortPair{{0, 80}, {listenerPort(t, taken), 80}},
+		Out:   &bytes.Buffer{},
+	}
+	if err := f.Listen(); err == nil {
+		t.Fatalf("expected an error")
+	}
+	if _, err := net.Dial("tcp", f.listeners[0].Addr().String()); err == nil {
+		t.Errorf("expected the listeners already bound to be closed")
+	}
+}
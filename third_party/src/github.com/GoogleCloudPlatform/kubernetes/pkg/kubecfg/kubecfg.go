@@ -22,6 +22,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strconv"
 	"strings"
 	"time"
@@ -29,6 +30,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/version"
 	"github.com/golang/glog"
 	"gopkg.in/v1/yaml"
@@ -47,6 +49,17 @@ func GetServerVersion(client *client.Client) (*version.Info, error) {
 	return &info, nil
 }
 
+// LogServerConfig logs the limits and defaults of the apiserver client talks to, which help
+// to explain how it answered. Servers that don't describe them are logged as such.
+func LogServerConfig(client *client.Client) {
+	config, err := client.ServerConfig()
+	if err != nil {
+		glog.Infof("Unable to read the server config: %v", err)
+		return
+	}
+	glog.Infof("Server config: %s", util.MakeJSONString(config))
+}
+
 func promptForString(field string, r io.Reader) string {
 	fmt.Printf("Please enter %s: ", field)
 	var result string
@@ -54,6 +67,92 @@ func promptForString(field string, r io.Reader) string {
 	return result
 }
 
+// Confirm writes 'message' to w and reads an answer from r, returning true if the
+// answer was "y" or "yes".
+func Confirm(message string, r io.Reader, w io.Writer) bool {
+	fmt.Fprintf(w, "%s [y/N]: ", message)
+	var answer string
+	fmt.Fscanln(r, &answer)
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// ItemIDs returns the IDs of the objects in 'list', which must be an api list type
+// with an Items field, such as *api.PodList.
+func ItemIDs(list interface{}) ([]string, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a list, but got %#v", list)
+	}
+	items := v.FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected a list, but got %#v", list)
+	}
+	ids := []string{}
+	for i := 0; i < items.Len(); i++ {
+		jsonBase, err := api.FindJSONBaseRO(items.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, jsonBase.ID)
+	}
+	return ids, nil
+}
+
+// AppendItems appends the items of 'more' to those of 'list', which must both be pointers to
+// the same api list type, such as *api.PodList, e.g. to join the pages of a list the server
+// answered in parts.
+func AppendItems(list, more interface{}) error {
+	v, m := reflect.ValueOf(list), reflect.ValueOf(more)
+	if v.Kind() != reflect.Ptr || v.Type() != m.Type() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("expected two lists of the same type, but got %#v and %#v", list, more)
+	}
+	items := v.Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice {
+		return fmt.Errorf("expected a list, but got %#v", list)
+	}
+	items.Set(reflect.AppendSlice(items, m.Elem().FieldByName("Items")))
+	return nil
+}
+
+// ListOf returns 'objects', pointers to objects of 'storage' such as *api.Pod for pods, as
+// the list type of that storage, such as *api.PodList, e.g. to print objects read one by one
+// as a single table.
+func ListOf(storage string, objects []interface{}) (interface{}, error) {
+	t, found := storageToType[storage]
+	if !found {
+		return nil, fmt.Errorf("unknown storage type: %v", storage)
+	}
+	return listOfKind(t.Name()+"List", objects)
+}
+
+// listOfKind returns 'objects', pointers to the items of lists of 'kind' such as "PodList",
+// as a list of that kind.
+func listOfKind(kind string, objects []interface{}) (interface{}, error) {
+	list, err := api.New("", kind)
+	if err != nil {
+		return nil, err
+	}
+	items := reflect.ValueOf(list).Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected a list, but got %#v", list)
+	}
+	for _, obj := range objects {
+		v := reflect.ValueOf(obj)
+		if v.Kind() != reflect.Ptr || v.Elem().Type() != items.Type().Elem() {
+			return nil, fmt.Errorf("expected an item of a %s, but got %#v", kind, obj)
+		}
+		items.Set(reflect.Append(items, v.Elem()))
+	}
+	return list, nil
+}
+
 // LoadAuthInfo parses an AuthInfo object from a file path. It prompts user and creates file if it doesn't exist.
 func LoadAuthInfo(path string, r io.Reader) (*client.AuthInfo, error) {
 	var auth client.AuthInfo
@@ -78,6 +177,28 @@ func LoadAuthInfo(path string, r io.Reader) (*client.AuthInfo, error) {
 	return &auth, err
 }
 
+// MergeAuthInfo overlays the non-empty certificate settings of overrides, typically taken
+// from command line flags, onto auth. A nil auth is treated as empty.
+func MergeAuthInfo(auth *client.AuthInfo, overrides client.AuthInfo) *client.AuthInfo {
+	merged := client.AuthInfo{}
+	if auth != nil {
+		merged = *auth
+	}
+	if len(overrides.CertFile) > 0 {
+		merged.CertFile = overrides.CertFile
+	}
+	if len(overrides.KeyFile) > 0 {
+		merged.KeyFile = overrides.KeyFile
+	}
+	if len(overrides.CAFile) > 0 {
+		merged.CAFile = overrides.CAFile
+	}
+	if overrides.Insecure {
+		merged.Insecure = true
+	}
+	return &merged
+}
+
 // Update performs a rolling update of a collection of pods.
 // 'name' points to a replication controller.
 // 'client' is used for updating pods.
@@ -105,6 +226,97 @@ func Update(name string, client client.Interface, updatePeriod time.Duration) er
 	return nil
 }
 
+// RollingUpdateOptions controls the behavior of RollingUpdate.
+type RollingUpdateOptions struct {
+	// Template, if set, replaces the controller's pod template before any pods are replaced.
+	Template *api.PodTemplate
+	// UpdatePeriod is the pause between replacing pods.
+	UpdatePeriod time.Duration
+	// PollInterval is how often pods are listed while waiting for a replacement. Defaults to one second.
+	PollInterval time.Duration
+	// Timeout bounds the whole update. Zero means no limit.
+	Timeout time.Duration
+	// Progress, if set, is called with each step of the update.
+	Progress ProgressFunc
+}
+
+// RollingUpdate replaces the pods of the replication controller 'name' one at a time.
+// Each old pod is deleted and the update waits until its replacement, created by the
+// controller, is running before pausing for UpdatePeriod and moving on to the next pod.
+// If the timeout expires the update stops, and the returned error reports how many pods
+// were replaced; the controller itself is left with the new template.
+func RollingUpdate(name string, client client.Interface, options RollingUpdateOptions) error {
+	controller, err := client.GetReplicationController(name)
+	if err != nil {
+		return err
+	}
+	if options.Template != nil {
+		controller.DesiredState.PodTemplate = *options.Template
+		if _, err := client.UpdateReplicationController(controller); err != nil {
+			return err
+		}
+		options.Progress.report(PhaseUpdated, "replicationControllers/"+name, "updated the pod template")
+	}
+	if options.PollInterval == 0 {
+		options.PollInterval = time.Second
+	}
+	var deadline time.Time
+	if options.Timeout != 0 {
+		deadline = time.Now().Add(options.Timeout)
+	}
+
+	s := labels.Set(controller.DesiredState.ReplicaSelector).AsSelector()
+	podList, err := client.ListPods(s)
+	if err != nil {
+		return err
+	}
+	oldPods := map[string]bool{}
+	for _, pod := range podList.Items {
+		oldPods[pod.ID] = true
+	}
+
+	for i, pod := range podList.Items {
+		if i > 0 {
+			time.Sleep(options.UpdatePeriod)
+		}
+		if err := client.DeletePod(pod.ID); err != nil {
+			return fmt.Errorf("replaced %d of %d pods: %v", i, len(podList.Items), err)
+		}
+		options.Progress.report(PhaseReplacing, "pods/"+pod.ID, "replacing pod %d of %d", i+1, len(podList.Items))
+		for {
+			replaced, err := countReplacementPods(client, s, oldPods)
+			if err != nil {
+				return fmt.Errorf("replaced %d of %d pods: %v", i, len(podList.Items), err)
+			}
+			if replaced > i {
+				break
+			}
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				return fmt.Errorf("timed out after replacing %d of %d pods of %s", i, len(podList.Items), name)
+			}
+			time.Sleep(options.PollInterval)
+		}
+		glog.Infof("Replaced pod %s (%d of %d)", pod.ID, i+1, len(podList.Items))
+		options.Progress.report(PhaseReplaced, "pods/"+pod.ID, "replaced pod %d of %d", i+1, len(podList.Items))
+	}
+	return nil
+}
+
+// countReplacementPods returns the number of running pods matching 's' that are not in 'oldPods'.
+func countReplacementPods(client client.Interface, s labels.Selector, oldPods map[string]bool) (int, error) {
+	podList, err := client.ListPods(s)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, pod := range podList.Items {
+		if !oldPods[pod.ID] && pod.CurrentState.Status == api.PodRunning {
+			count++
+		}
+	}
+	return count, nil
+}
+
 // StopController stops a controller named 'name' by setting replicas to zero
 func StopController(name string, client client.Interface) error {
 	controller, err := client.GetReplicationController(name)
@@ -126,23 +338,116 @@ func StopController(name string, client client.Interface) error {
 
 // ResizeController resizes a controller named 'name' by setting replicas to 'replicas'
 func ResizeController(name string, replicas int, client client.Interface) error {
+	controllerOut, err := Resize(name, replicas, client, 0)
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(controllerOut)
+	if err != nil {
+		return err
+	}
+	fmt.Print(string(data))
+	return nil
+}
+
+// Resize sets the desired replica count of the controller 'name' to 'replicas'. The controller
+// is read and updated again up to 'retries' more times if the update conflicts with a
+// concurrent change.
+func Resize(name string, replicas int, kubeClient client.Interface, retries int) (api.ReplicationController, error) {
+	for attempt := 0; ; attempt++ {
+		controller, err := kubeClient.GetReplicationController(name)
+		if err != nil {
+			return api.ReplicationController{}, err
+		}
+		controller.DesiredState.Replicas = replicas
+		controllerOut, err := kubeClient.UpdateReplicationController(controller)
+		if err == nil || !client.IsConflict(err) || attempt >= retries {
+			return controllerOut, err
+		}
+		glog.Infof("Update of %s conflicted, retrying", name)
+	}
+}
+
+// WaitForReplicas blocks until the number of pods matching the selector of the controller
+// 'name' equals its desired replica count, checking every 'pollInterval'. A zero timeout
+// waits forever. 'progress', if set, is called each time the pods are counted short.
+func WaitForReplicas(name string, client client.Interface, pollInterval, timeout time.Duration, progress ProgressFunc) error {
 	controller, err := client.GetReplicationController(name)
 	if err != nil {
 		return err
 	}
-	controller.DesiredState.Replicas = replicas
-	controllerOut, err := client.UpdateReplicationController(controller)
+	s := labels.Set(controller.DesiredState.ReplicaSelector).AsSelector()
+	podList, err := waitForPodCount(client, s, controller.DesiredState.Replicas, pollInterval, timeout, "replicationControllers/"+name, progress)
+	if err != nil {
+		return fmt.Errorf("waiting for %s to have %d pods, %d observed: %v", name, controller.DesiredState.Replicas, len(podList.Items), err)
+	}
+	return nil
+}
+
+// StopAndDeleteController scales the controller 'name' to zero, waits up to 'gracePeriod'
+// for the pods matching its selector to terminate, and then deletes the controller. If any
+// pods remain when the grace period expires the controller is left in place and the error
+// names the remaining pods. A zero grace period waits forever. 'progress', if set, is called
+// with each step.
+func StopAndDeleteController(name string, client client.Interface, pollInterval, gracePeriod time.Duration, progress ProgressFunc) error {
+	controller, err := Resize(name, 0, client, 3)
 	if err != nil {
 		return err
 	}
-	data, err := yaml.Marshal(controllerOut)
+	object := "replicationControllers/" + name
+	progress.report(PhaseUpdated, object, "resized to 0 replicas")
+	s := labels.Set(controller.DesiredState.ReplicaSelector).AsSelector()
+	podList, err := waitForPodCount(client, s, 0, pollInterval, gracePeriod, object, progress)
 	if err != nil {
+		ids := []string{}
+		for _, pod := range podList.Items {
+			ids = append(ids, pod.ID)
+		}
+		return fmt.Errorf("pods of %s did not terminate, controller not deleted: %v: %s", name, err, strings.Join(ids, ", "))
+	}
+	if err := client.DeleteReplicationController(name); err != nil {
 		return err
 	}
-	fmt.Print(string(data))
+	progress.report(PhaseDeleted, object, "deleted")
 	return nil
 }
 
+// waitForPodCount lists the pods matching 's' every 'pollInterval' until exactly 'count'
+// are found or 'timeout' expires, and returns the last list observed. A zero timeout
+// waits forever. Each count short of 'count' is reported to 'progress' against 'object'.
+func waitForPodCount(client client.Interface, s labels.Selector, count int, pollInterval, timeout time.Duration, object string, progress ProgressFunc) (api.PodList, error) {
+	var deadline time.Time
+	if timeout != 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		podList, err := client.ListPods(s)
+		if err != nil {
+			return podList, err
+		}
+		if len(podList.Items) == count {
+			return podList, nil
+		}
+		progress.report(PhaseWaiting, object, "%d pods, waiting for %d", len(podList.Items), count)
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return podList, fmt.Errorf("timed out after %v", timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// ParseReplicas parses a replica count given on the command line.
+func ParseReplicas(value string) (int, error) {
+	replicas, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("replicas must be a non-negative integer, got %q", value)
+	}
+	if replicas < 0 {
+		return 0, fmt.Errorf("replicas must be a non-negative integer, got %d", replicas)
+	}
+	return replicas, nil
+}
+
 func makePorts(spec string) []api.Port {
 	parts := strings.Split(spec, ",")
 	var result []api.Port
@@ -167,9 +472,84 @@ func makePorts(spec string) []api.Port {
 	return result
 }
 
-// RunController creates a new replication controller named 'name' which creates 'replicas' pods running 'image'
-func RunController(image, name string, replicas int, client client.Interface, portSpec string, servicePort int) error {
-	controller := api.ReplicationController{
+// PodTemplateOptions are the settings of the container of a controller made by MakeController
+// beyond its image and ports, as given on the command line.
+type PodTemplateOptions struct {
+	// Env are the environment variables of the container, each KEY=VALUE.
+	Env []string
+	// RestartPolicy is "always", "onFailure" or "never", or empty for the default.
+	RestartPolicy string
+	// Memory is the memory limit of the container, in bytes, or 0 for no limit.
+	Memory int
+	// CPU is the CPU limit of the container, in millicores, or 0 for no limit.
+	CPU int
+	// Volumes are the host directories mounted in the container, each hostpath:containerpath.
+	Volumes []string
+}
+
+// restartPolicies are the restart policies PodTemplateOptions accepts, by lower case name.
+var restartPolicies = map[string]api.RestartPolicyType{
+	"always":    api.RestartAlways,
+	"onfailure": api.RestartOnFailure,
+	"never":     api.RestartNever,
+}
+
+// MakeController returns a replication controller named 'name' which creates 'replicas' pods
+// running 'image' with the given ports and options. Malformed or conflicting options are
+// returned as an error.
+func MakeController(image, name string, replicas int, portSpec string, options PodTemplateOptions) (api.ReplicationController, error) {
+	if options.Memory < 0 || options.CPU < 0 {
+		return api.ReplicationController{}, fmt.Errorf("memory and cpu limits can't be negative")
+	}
+	container := api.Container{
+		Name:   strings.ToLower(name),
+		Image:  image,
+		Ports:  makePorts(portSpec),
+		Memory: options.Memory,
+		CPU:    options.CPU,
+	}
+	names := util.NewStringSet()
+	for _, env := range options.Env {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) != 2 || !util.IsCIdentifier(parts[0]) {
+			return api.ReplicationController{}, fmt.Errorf("environment variable %q should be KEY=VALUE, with KEY a C identifier", env)
+		}
+		if names.Has(parts[0]) {
+			return api.ReplicationController{}, fmt.Errorf("environment variable %s is set more than once", parts[0])
+		}
+		names.Insert(parts[0])
+		container.Env = append(container.Env, api.EnvVar{Name: parts[0], Value: parts[1]})
+	}
+	podState := api.PodState{
+		Manifest: api.ContainerManifest{Version: "v1beta2"},
+	}
+	if len(options.RestartPolicy) > 0 {
+		policy, ok := restartPolicies[strings.ToLower(options.RestartPolicy)]
+		if !ok {
+			return api.ReplicationController{}, fmt.Errorf("restart policy %q should be always, onFailure or never", options.RestartPolicy)
+		}
+		podState.RestartPolicy.Type = policy
+	}
+	mountPaths := util.NewStringSet()
+	for i, volume := range options.Volumes {
+		parts := strings.Split(volume, ":")
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "/") || !strings.HasPrefix(parts[1], "/") {
+			return api.ReplicationController{}, fmt.Errorf("volume %q should be hostpath:containerpath, with both paths absolute", volume)
+		}
+		if mountPaths.Has(parts[1]) {
+			return api.ReplicationController{}, fmt.Errorf("more than one volume is mounted at %s", parts[1])
+		}
+		mountPaths.Insert(parts[1])
+		volumeName := fmt.Sprintf("volume%d", i)
+		podState.Manifest.Volumes = append(podState.Manifest.Volumes, api.Volume{
+			Name:   volumeName,
+			Source: &api.VolumeSource{HostDirectory: &api.HostDirectory{Path: parts[0]}},
+		})
+		container.VolumeMounts = append(container.VolumeMounts, api.VolumeMount{Name: volumeName, MountPath: parts[1]})
+	}
+	podState.Manifest.Containers = []api.Container{container}
+
+	return api.ReplicationController{
 		JSONBase: api.JSONBase{
 			ID: name,
 		},
@@ -179,18 +559,7 @@ func RunController(image, name string, replicas int, client client.Interface, po
 				"name": name,
 			},
 			PodTemplate: api.PodTemplate{
-				DesiredState: api.PodState{
-					Manifest: api.ContainerManifest{
-						Version: "v1beta2",
-						Containers: []api.Container{
-							{
-								Name:  strings.ToLower(name),
-								Image: image,
-								Ports: makePorts(portSpec),
-							},
-						},
-					},
-				},
+				DesiredState: podState,
 				Labels: map[string]string{
 					"name": name,
 				},
@@ -199,8 +568,21 @@ func RunController(image, name string, replicas int, client client.Interface, po
 		Labels: map[string]string{
 			"name": name,
 		},
+	}, nil
+}
+
+// RunController creates a new replication controller named 'name' which creates 'replicas' pods running 'image'
+func RunController(image, name string, replicas int, client client.Interface, portSpec string, servicePort int) error {
+	controller, err := MakeController(image, name, replicas, portSpec, PodTemplateOptions{})
+	if err != nil {
+		return err
 	}
+	return CreateController(controller, client, servicePort)
+}
 
+// CreateController creates controller, and a service selecting its pods on servicePort if it
+// is positive, and prints them as YAML.
+func CreateController(controller api.ReplicationController, client client.Interface, servicePort int) error {
 	controllerOut, err := client.CreateReplicationController(controller)
 	if err != nil {
 		return err
@@ -212,7 +594,7 @@ func RunController(image, name string, replicas int, client client.Interface, po
 	fmt.Print(string(data))
 
 	if servicePort > 0 {
-		svc, err := createService(name, servicePort, client)
+		svc, err := createService(controller.ID, servicePort, client)
 		if err != nil {
 			return err
 		}
@@ -252,3 +634,49 @@ func DeleteController(name string, client client.Interface) error {
 	}
 	return client.DeleteReplicationController(name)
 }
+
+// OperationTimeoutError is returned by WaitForOperation if the operation did not complete in time.
+type OperationTimeoutError struct {
+	ID string
+}
+
+func (e *OperationTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for operation %s", e.ID)
+}
+
+// maxOperationPollInterval caps the backoff between polls in WaitForOperation.
+const maxOperationPollInterval = 2 * time.Second
+
+// WaitForOperation polls the operation 'id' until it completes and returns its result, which
+// is either the object it produced or a Status. The interval between polls starts short and
+// doubles up to a couple of seconds, unless the server asks to wait for a given time with a
+// Retry-After header. A zero timeout waits forever; otherwise an
+// *OperationTimeoutError is returned when it expires. 'progress', if set, is called each
+// time the operation is found still running.
+func WaitForOperation(c *client.Client, id string, timeout time.Duration, progress ProgressFunc) (interface{}, error) {
+	var deadline time.Time
+	if timeout != 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	interval := 100 * time.Millisecond
+	for {
+		result := c.PollFor(id).Do()
+		obj, err := result.Get()
+		statusErr, ok := err.(*client.StatusErr)
+		if !ok || statusErr.Status.Status != api.StatusWorking {
+			return obj, err
+		}
+		wait := interval
+		if retryAfter, ok := result.RetryAfter(); ok {
+			wait = retryAfter
+		}
+		progress.report(PhaseWaiting, "operations/"+id, "still running, polling again in %v", wait)
+		if !deadline.IsZero() && time.Now().Add(wait).After(deadline) {
+			return nil, &OperationTimeoutError{ID: id}
+		}
+		time.Sleep(wait)
+		if interval *= 2; interval > maxOperationPollInterval {
+			interval = maxOperationPollInterval
+		}
+	}
+}
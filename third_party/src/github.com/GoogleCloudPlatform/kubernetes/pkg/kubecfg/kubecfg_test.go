@@ -22,7 +22,9 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
@@ -68,6 +70,11 @@ func (client *FakeKubeClient) UpdatePod(pod api.Pod) (api.Pod, error) {
 	return api.Pod{}, nil
 }
 
+func (client *FakeKubeClient) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	client.actions = append(client.actions, Action{action: "watch-pods"})
+	return watch.NewFake(), nil
+}
+
 func (client *FakeKubeClient) ListReplicationControllers(selector labels.Selector) (api.ReplicationControllerList, error) {
 	client.actions = append(client.actions, Action{action: "list-controllers"})
 	return api.ReplicationControllerList{}, nil
@@ -88,6 +95,11 @@ func (client *FakeKubeClient) UpdateReplicationController(controller api.Replica
 	return api.ReplicationController{}, nil
 }
 
+func (client *FakeKubeClient) UpdateReplicationControllerStatus(controller api.ReplicationController) (api.ReplicationController, error) {
+	client.actions = append(client.actions, Action{action: "update-controller-status", value: controller})
+	return api.ReplicationController{}, nil
+}
+
 func (client *FakeKubeClient) DeleteReplicationController(controller string) error {
 	client.actions = append(client.actions, Action{action: "delete-controller", value: controller})
 	return nil
@@ -98,6 +110,16 @@ func (client *FakeKubeClient) WatchReplicationControllers(label, field labels.Se
 	return watch.NewFake(), nil
 }
 
+func (client *FakeKubeClient) WatchBuilds(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	client.actions = append(client.actions, Action{action: "watch-builds"})
+	return watch.NewFake(), nil
+}
+
+func (client *FakeKubeClient) ListServices(selector labels.Selector) (api.ServiceList, error) {
+	client.actions = append(client.actions, Action{action: "list-services"})
+	return api.ServiceList{}, nil
+}
+
 func (client *FakeKubeClient) GetService(name string) (api.Service, error) {
 	client.actions = append(client.actions, Action{action: "get-service", value: name})
 	return api.Service{}, nil
@@ -118,14 +140,61 @@ func (client *FakeKubeClient) DeleteService(service string) error {
 	return nil
 }
 
-func (client *FakeKubeClient) ListBuilds() (buildapi.BuildList, error) {
+func (client *FakeKubeClient) ListMinions() (api.MinionList, error) {
+	client.actions = append(client.actions, Action{action: "list-minions"})
+	return api.MinionList{}, nil
+}
+
+func (client *FakeKubeClient) GetMinion(name string) (api.Minion, error) {
+	client.actions = append(client.actions, Action{action: "get-minion", value: name})
+	return api.Minion{}, nil
+}
+
+func (client *FakeKubeClient) CreateMinion(minion api.Minion) (api.Minion, error) {
+	client.actions = append(client.actions, Action{action: "create-minion", value: minion.ID})
+	return minion, nil
+}
+
+func (client *FakeKubeClient) DeleteMinion(name string) error {
+	client.actions = append(client.actions, Action{action: "delete-minion", value: name})
+	return nil
+}
+
+func (client *FakeKubeClient) ListBuilds(selector labels.Selector) (buildapi.BuildList, error) {
 	client.actions = append(client.actions, Action{action: "list-builds"})
 	return client.builds, nil
 }
 
+func (client *FakeKubeClient) GetBuild(name string) (buildapi.Build, error) {
+	client.actions = append(client.actions, Action{action: "get-build", value: name})
+	for _, build := range client.builds.Items {
+		if build.ID == name {
+			return build, nil
+		}
+	}
+	return buildapi.Build{}, nil
+}
+
+func (client *FakeKubeClient) CreateBuild(build buildapi.Build) (buildapi.Build, error) {
+	client.actions = append(client.actions, Action{action: "create-build", value: build.ParentID})
+	build.ID = "clone"
+	build.Status = buildapi.BuildNew
+	return build, nil
+}
+
 func (client *FakeKubeClient) UpdateBuild(build buildapi.Build) (buildapi.Build, error) {
 	client.actions = append(client.actions, Action{action: "update-build", value: build.ID})
-	return buildapi.Build{}, nil
+	return build, nil
+}
+
+func (client *FakeKubeClient) UpdateBuildStatus(build buildapi.Build) (buildapi.Build, error) {
+	client.actions = append(client.actions, Action{action: "update-build-status", value: build.ID})
+	return build, nil
+}
+
+func (client *FakeKubeClient) DeleteBuild(name string) error {
+	client.actions = append(client.actions, Action{action: "delete-build", value: name})
+	return nil
 }
 
 func validateAction(expectedAction, actualAction Action, t *testing.T) {
@@ -200,6 +269,52 @@ func TestRunControllerWithService(t *testing.T) {
 	}
 }
 
+func TestMakeController(t *testing.T) {
+	options := PodTemplateOptions{
+		Env:           []string{"FOO=bar", "URL=http://a/?b=c"},
+		RestartPolicy: "onFailure",
+		Memory:        1 << 20,
+		CPU:           500,
+		Volumes:       []string{"/var/log:/logs"},
+	}
+	controller, err := MakeController("foo/bar", "Web", 2, "8080:80", options)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podState := controller.DesiredState.PodTemplate.DesiredState
+	container := podState.Manifest.Containers[0]
+	if container.Name != "web" || container.Memory != 1<<20 || container.CPU != 500 || len(container.Ports) != 1 {
+		t.Errorf("unexpected container %#v", container)
+	}
+	if !reflect.DeepEqual(container.Env, []api.EnvVar{{Name: "FOO", Value: "bar"}, {Name: "URL", Value: "http://a/?b=c"}}) {
+		t.Errorf("unexpected env %#v", container.Env)
+	}
+	if podState.RestartPolicy.Type != api.RestartOnFailure {
+		t.Errorf("unexpected restart policy %#v", podState.RestartPolicy)
+	}
+	if len(podState.Manifest.Volumes) != 1 || podState.Manifest.Volumes[0].Source.HostDirectory.Path != "/var/log" ||
+		!reflect.DeepEqual(container.VolumeMounts, []api.VolumeMount{{Name: podState.Manifest.Volumes[0].Name, MountPath: "/logs"}}) {
+		t.Errorf("unexpected volumes %#v mounted as %#v", podState.Manifest.Volumes, container.VolumeMounts)
+	}
+
+	invalid := []PodTemplateOptions{
+		{Env: []string{"FOO"}},
+		{Env: []string{"1FOO=bar"}},
+		{Env: []string{"FOO=bar", "FOO=baz"}},
+		{RestartPolicy: "sometimes"},
+		{Memory: -1},
+		{CPU: -1},
+		{Volumes: []string{"/var/log"}},
+		{Volumes: []string{"logs:/logs"}},
+		{Volumes: []string{"/var/log:/logs", "/tmp:/logs"}},
+	}
+	for _, options := range invalid {
+		if _, err := MakeController("foo/bar", "web", 1, "", options); err == nil {
+			t.Errorf("expected %#v to be invalid", options)
+		}
+	}
+}
+
 func TestStopController(t *testing.T) {
 	fakeClient := FakeKubeClient{}
 	name := "name"
@@ -290,6 +405,11 @@ func TestLoadAuthInfo(t *testing.T) {
 			&client.AuthInfo{User: "user", Password: "pass"},
 			nil,
 		},
+		{
+			`{"user": "user", "password": "pass", "certFile": "cert.pem", "keyFile": "key.pem", "caFile": "ca.pem"}`,
+			&client.AuthInfo{User: "user", Password: "pass", CertFile: "cert.pem", KeyFile: "key.pem", CAFile: "ca.pem"},
+			nil,
+		},
 		{
 			"", nil, nil,
 		},
@@ -332,6 +452,32 @@ func TestLoadAuthInfo(t *testing.T) {
 	}
 }
 
+func TestMergeAuthInfo(t *testing.T) {
+	fromFile := &client.AuthInfo{User: "user", Password: "pass", CertFile: "file-cert.pem", KeyFile: "file-key.pem", CAFile: "file-ca.pem"}
+	table := []struct {
+		auth      *client.AuthInfo
+		overrides client.AuthInfo
+		expected  client.AuthInfo
+	}{
+		{fromFile, client.AuthInfo{}, *fromFile},
+		{
+			fromFile,
+			client.AuthInfo{CertFile: "flag-cert.pem", KeyFile: "flag-key.pem", Insecure: true},
+			client.AuthInfo{User: "user", Password: "pass", CertFile: "flag-cert.pem", KeyFile: "flag-key.pem", CAFile: "file-ca.pem", Insecure: true},
+		},
+		{nil, client.AuthInfo{CAFile: "flag-ca.pem"}, client.AuthInfo{CAFile: "flag-ca.pem"}},
+	}
+	for _, item := range table {
+		merged := MergeAuthInfo(item.auth, item.overrides)
+		if !reflect.DeepEqual(*merged, item.expected) {
+			t.Errorf("expected %#v, got %#v", item.expected, *merged)
+		}
+	}
+	if fromFile.CertFile != "file-cert.pem" {
+		t.Errorf("MergeAuthInfo modified its input: %#v", fromFile)
+	}
+}
+
 func TestMakePorts(t *testing.T) {
 	var makePortsTests = []struct {
 		spec  string
@@ -353,3 +499,285 @@ func TestMakePorts(t *testing.T) {
 		}
 	}
 }
+
+func TestConfirm(t *testing.T) {
+	table := map[string]bool{
+		"y\n":   true,
+		"Yes\n": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+	}
+	for answer, expected := range table {
+		out := &bytes.Buffer{}
+		if got := Confirm("Delete?", bytes.NewBufferString(answer), out); got != expected {
+			t.Errorf("%q: expected %v, got %v", answer, expected, got)
+		}
+		if out.String() != "Delete? [y/N]: " {
+			t.Errorf("unexpected prompt %q", out.String())
+		}
+	}
+}
+
+func TestItemIDs(t *testing.T) {
+	ids, err := ItemIDs(&api.PodList{
+		Items: []api.Pod{
+			{JSONBase: api.JSONBase{ID: "foo"}},
+			{JSONBase: api.JSONBase{ID: "bar"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []string{"foo", "bar"}) {
+		t.Errorf("unexpected ids: %v", ids)
+	}
+	ids, err = ItemIDs(&api.MinionList{Items: []api.Minion{{JSONBase: api.JSONBase{ID: "m1"}}}})
+	if err != nil || !reflect.DeepEqual(ids, []string{"m1"}) {
+		t.Errorf("unexpected result: %v %v", ids, err)
+	}
+	if _, err := ItemIDs(&api.Pod{}); err == nil {
+		t.Errorf("expected error for non-list object")
+	}
+}
+
+func TestAppendItems(t *testing.T) {
+	list := &api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "foo"}}}}
+	if err := AppendItems(list, &api.PodList{Items: []api.Pod{{JSONBase: api.JSONBase{ID: "bar"}}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids, _ := ItemIDs(list); !reflect.DeepEqual(ids, []string{"foo", "bar"}) {
+		t.Errorf("unexpected ids: %v", ids)
+	}
+	if err := AppendItems(list, &api.MinionList{}); err == nil {
+		t.Errorf("expected an error for lists of different types")
+	}
+	if err := AppendItems(&api.Pod{}, &api.Pod{}); err == nil {
+		t.Errorf("expected an error for non-list objects")
+	}
+}
+
+func TestListOf(t *testing.T) {
+	list, err := ListOf("pods", []interface{}{&api.Pod{JSONBase: api.JSONBase{ID: "foo"}}, &api.Pod{JSONBase: api.JSONBase{ID: "bar"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := list.(*api.PodList); !ok {
+		t.Fatalf("expected a pod list, got %#v", list)
+	}
+	if ids, _ := ItemIDs(list); !reflect.DeepEqual(ids, []string{"foo", "bar"}) {
+		t.Errorf("unexpected ids: %v", ids)
+	}
+	if list, err := ListOf("services", nil); err != nil || len(list.(*api.ServiceList).Items) != 0 {
+		t.Errorf("expected an empty service list, got %#v, %v", list, err)
+	}
+	if _, err := ListOf("pods", []interface{}{&api.Service{}}); err == nil {
+		t.Errorf("expected an error for an object of another storage")
+	}
+	if _, err := ListOf("widgets", nil); err == nil {
+		t.Errorf("expected an error for an unknown storage")
+	}
+}
+
+// sequenceKubeClient returns successive pod lists from ListPods, repeating the last one.
+type sequenceKubeClient struct {
+	FakeKubeClient
+	podLists []api.PodList
+}
+
+func (client *sequenceKubeClient) ListPods(selector labels.Selector) (api.PodList, error) {
+	client.actions = append(client.actions, Action{action: "list-pods"})
+	list := client.podLists[0]
+	if len(client.podLists) > 1 {
+		client.podLists = client.podLists[1:]
+	}
+	return list, nil
+}
+
+func runningPod(id string) api.Pod {
+	return api.Pod{
+		JSONBase:     api.JSONBase{ID: id},
+		CurrentState: api.PodState{Status: api.PodRunning},
+	}
+}
+
+func TestRollingUpdate(t *testing.T) {
+	client := &sequenceKubeClient{
+		podLists: []api.PodList{
+			{Items: []api.Pod{runningPod("pod-1"), runningPod("pod-2")}},
+			{Items: []api.Pod{runningPod("pod-2")}},
+			{Items: []api.Pod{runningPod("pod-2"), {JSONBase: api.JSONBase{ID: "pod-3"}}}},
+			{Items: []api.Pod{runningPod("pod-2"), runningPod("pod-3")}},
+			{Items: []api.Pod{runningPod("pod-3"), runningPod("pod-4")}},
+		},
+	}
+	template := &api.PodTemplate{Labels: map[string]string{"version": "2"}}
+	err := RollingUpdate("foo", client, RollingUpdateOptions{Template: template, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Action{
+		{action: "get-controller", value: "foo"},
+		{action: "update-controller"},
+		{action: "list-pods"},
+		{action: "delete-pod", value: "pod-1"},
+		{action: "list-pods"},
+		{action: "list-pods"},
+		{action: "list-pods"},
+		{action: "delete-pod", value: "pod-2"},
+		{action: "list-pods"},
+	}
+	if len(client.actions) != len(expected) {
+		t.Fatalf("unexpected actions: %#v", client.actions)
+	}
+	for i := range expected {
+		if expected[i].action == "update-controller" {
+			controller := client.actions[i].value.(api.ReplicationController)
+			if !reflect.DeepEqual(controller.DesiredState.PodTemplate, *template) {
+				t.Errorf("unexpected template: %#v", controller.DesiredState.PodTemplate)
+			}
+			continue
+		}
+		validateAction(expected[i], client.actions[i], t)
+	}
+}
+
+func TestRollingUpdateTimeout(t *testing.T) {
+	client := &sequenceKubeClient{
+		podLists: []api.PodList{
+			{Items: []api.Pod{runningPod("pod-1"), runningPod("pod-2")}},
+			{Items: []api.Pod{runningPod("pod-2")}},
+		},
+	}
+	err := RollingUpdate("foo", client, RollingUpdateOptions{PollInterval: time.Millisecond, Timeout: 10 * time.Millisecond})
+	if err == nil {
+		t.Fatalf("expected timeout error")
+	}
+	if !strings.Contains(err.Error(), "0 of 2") {
+		t.Errorf("expected progress in error, got %v", err)
+	}
+	for _, action := range client.actions {
+		if action.action == "delete-pod" && action.value != "pod-1" {
+			t.Errorf("unexpected delete after timeout: %#v", action)
+		}
+	}
+}
+
+// conflictKubeClient fails the first 'conflicts' controller updates with a conflict.
+type conflictKubeClient struct {
+	FakeKubeClient
+	conflicts int
+}
+
+func (c *conflictKubeClient) UpdateReplicationController(controller api.ReplicationController) (api.ReplicationController, error) {
+	if c.conflicts > 0 {
+		c.conflicts--
+		c.actions = append(c.actions, Action{action: "update-controller-conflict", value: controller})
+		return api.ReplicationController{}, &client.StatusErr{Status: api.Status{Status: api.StatusFailure, Reason: api.ReasonTypeConflict}}
+	}
+	return c.FakeKubeClient.UpdateReplicationController(controller)
+}
+
+func TestResizeRetriesOnConflict(t *testing.T) {
+	fakeClient := &conflictKubeClient{conflicts: 2}
+	if _, err := Resize("foo", 3, fakeClient, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"get-controller", "update-controller-conflict", "get-controller", "update-controller-conflict", "get-controller", "update-controller"}
+	if len(fakeClient.actions) != len(expected) {
+		t.Fatalf("unexpected actions: %#v", fakeClient.actions)
+	}
+	for i := range expected {
+		if fakeClient.actions[i].action != expected[i] {
+			t.Errorf("expected %s, got %#v", expected[i], fakeClient.actions[i])
+		}
+	}
+	if controller := fakeClient.actions[5].value.(api.ReplicationController); controller.DesiredState.Replicas != 3 {
+		t.Errorf("unexpected replicas: %#v", controller)
+	}
+
+	fakeClient = &conflictKubeClient{conflicts: 2}
+	if _, err := Resize("foo", 3, fakeClient, 1); err == nil {
+		t.Errorf("expected conflict error after retries are exhausted")
+	}
+}
+
+func TestWaitForReplicas(t *testing.T) {
+	fakeClient := &sequenceKubeClient{
+		FakeKubeClient: FakeKubeClient{ctrl: api.ReplicationController{DesiredState: api.ReplicationControllerState{Replicas: 2}}},
+		podLists: []api.PodList{
+			{Items: []api.Pod{runningPod("pod-1")}},
+			{Items: []api.Pod{runningPod("pod-1"), runningPod("pod-2")}},
+		},
+	}
+	if err := WaitForReplicas("foo", fakeClient, time.Millisecond, 0, nil); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	fakeClient.podLists = []api.PodList{{Items: []api.Pod{runningPod("pod-1")}}}
+	if err := WaitForReplicas("foo", fakeClient, time.Millisecond, 10*time.Millisecond, nil); err == nil {
+		t.Errorf("expected timeout error")
+	}
+}
+
+func TestParseReplicas(t *testing.T) {
+	table := map[string]bool{
+		"0":   true,
+		"12":  true,
+		"-1":  false,
+		"1.5": false,
+		"two": false,
+		"":    false,
+	}
+	for value, valid := range table {
+		_, err := ParseReplicas(value)
+		if valid && err != nil {
+			t.Errorf("unexpected error for %q: %v", value, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("expected error for %q", value)
+		}
+	}
+}
+
+func TestStopAndDeleteController(t *testing.T) {
+	fakeClient := &sequenceKubeClient{
+		podLists: []api.PodList{
+			{Items: []api.Pod{runningPod("pod-1")}},
+			{},
+		},
+	}
+	if err := StopAndDeleteController("foo", fakeClient, time.Millisecond, 0, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"get-controller", "update-controller", "list-pods", "list-pods", "delete-controller"}
+	if len(fakeClient.actions) != len(expected) {
+		t.Fatalf("unexpected actions: %#v", fakeClient.actions)
+	}
+	for i := range expected {
+		if fakeClient.actions[i].action != expected[i] {
+			t.Errorf("expected %s, got %#v", expected[i], fakeClient.actions[i])
+		}
+	}
+	if controller := fakeClient.actions[1].value.(api.ReplicationController); controller.DesiredState.Replicas != 0 {
+		t.Errorf("expected controller to be scaled to zero: %#v", controller)
+	}
+}
+
+func TestStopAndDeleteControllerGracePeriod(t *testing.T) {
+	fakeClient := &sequenceKubeClient{
+		podLists: []api.PodList{{Items: []api.Pod{runningPod("pod-1"), runningPod("pod-2")}}},
+	}
+	err := StopAndDeleteController("foo", fakeClient, time.Millisecond, 10*time.Millisecond, nil)
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if !strings.Contains(err.Error(), "pod-1, pod-2") {
+		t.Errorf("expected remaining pods in error, got %v", err)
+	}
+	for _, action := range fakeClient.actions {
+		if action.action == "delete-controller" {
+			t.Errorf("controller should not be deleted")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// LabelChanges are the changes to the labels of an object given on the command line.
+type LabelChanges struct {
+	// Set are the labels to add or change.
+	Set map[string]string
+	// Remove are the keys of the labels to remove.
+	Remove []string
+}
+
+// ParseLabelChanges parses args, each either key=value to set a label or key- to remove
+// one. A key may only be changed once.
+func ParseLabelChanges(args []string) (LabelChanges, error) {
+	changes := LabelChanges{Set: map[string]string{}}
+	keys := util.NewStringSet()
+	for _, arg := range args {
+		var key string
+		if parts := strings.SplitN(arg, "=", 2); len(parts) == 2 {
+			key = parts[0]
+			changes.Set[key] = parts[1]
+		} else if strings.HasSuffix(arg, "-") {
+			key = strings.TrimSuffix(arg, "-")
+			changes.Remove = append(changes.Remove, key)
+		} else {
+			return LabelChanges{}, fmt.Errorf("%q should be key=value to set a label, or key- to remove it", arg)
+		}
+		if len(key) == 0 {
+			return LabelChanges{}, fmt.Errorf("%q has no label key", arg)
+		}
+		if keys.Has(key) {
+			return LabelChanges{}, fmt.Errorf("label %s is changed more than once", key)
+		}
+		keys.Insert(key)
+	}
+	if len(keys) == 0 {
+		return LabelChanges{}, fmt.Errorf("no label changes given")
+	}
+	return changes, nil
+}
+
+// Apply makes the changes to the labels of obj, a pointer to an object with a Labels map.
+// Unless overwrite is true, setting a label that the object already has with another value
+// is an error.
+func (c LabelChanges) Apply(obj interface{}, overwrite bool) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	var field reflect.Value
+	if v.Kind() == reflect.Struct {
+		field = v.FieldByName("Labels")
+	}
+	if !field.IsValid() || field.Type() != reflect.TypeOf(map[string]string{}) {
+		return fmt.Errorf("objects of type %v have no labels", v.Type())
+	}
+	labels := field.Interface().(map[string]string)
+	for key, value := range c.Set {
+		if existing, ok := labels[key]; ok && existing != value && !overwrite {
+			return fmt.Errorf("label %s is already set to %q, use --overwrite to change it", key, existing)
+		}
+	}
+	if labels == nil {
+		labels = map[string]string{}
+		field.Set(reflect.ValueOf(labels))
+	}
+	for key, value := range c.Set {
+		labels[key] = value
+	}
+	for _, key := range c.Remove {
+		delete(labels, key)
+	}
+	return nil
+}
+
+// UpdateLabels makes changes to the labels of the object at path and returns the updated
+// object. Only the labels are changed: the object is read and written back again up to
+// 'retries' more times if the update conflicts with a concurrent change.
+func UpdateLabels(c *client.Client, namespace, path string, changes LabelChanges, overwrite bool, retries int) (interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		obj, err := c.Get().Namespace(namespace).Path(path).Do().Get()
+		if err != nil {
+			return nil, err
+		}
+		if err := changes.Apply(obj, overwrite); err != nil {
+			return nil, err
+		}
+		updated, err := c.Put().Namespace(namespace).Path(path).Body(obj).Do().Get()
+		if err == nil || !client.IsConflict(err) || attempt >= retries {
+			return updated, err
+		}
+		glog.Infof("Update of the labels of %s conflicted, retrying", path)
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+func TestParseLabelChanges(t *testing.T) {
+	changes, err := ParseLabelChanges([]string{"tier=frontend", "env=", "old-"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := LabelChanges{Set: map[string]string{"tier": "frontend", "env": ""}, Remove: []string{"old"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %#v, got %#v", expected, changes)
+	}
+
+	for _, args := range [][]string{
+		{},
+		{"tier"},
+		{"=frontend"},
+		{"-"},
+		{"tier=a", "tier=b"},
+		{"tier=a", "tier-"},
+	} {
+		if _, err := ParseLabelChanges(args); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+}
+
+func TestApplyLabelChanges(t *testing.T) {
+	changes := LabelChanges{Set: map[string]string{"tier": "backend", "env": "prod"}, Remove: []string{"old"}}
+	pod := &api.Pod{Labels: map[string]string{"tier": "frontend", "old": "x"}}
+	if err := changes.Apply(pod, false); err == nil {
+		t.Errorf("expected an existing label not to be overwritten")
+	}
+	if !reflect.DeepEqual(pod.Labels, map[string]string{"tier": "frontend", "old": "x"}) {
+		t.Errorf("expected a refused change to leave the labels alone, got %v", pod.Labels)
+	}
+	if err := changes.Apply(pod, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(pod.Labels, map[string]string{"tier": "backend", "env": "prod"}) {
+		t.Errorf("unexpected labels %v", pod.Labels)
+	}
+
+	// Setting a label to the value it has is not an overwrite.
+	service := &api.Service{}
+	if err := changes.Apply(service, false); err != nil || service.Labels["env"] != "prod" {
+		t.Errorf("unexpected labels %v: %v", service.Labels, err)
+	}
+	if err := changes.Apply(service, false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := changes.Apply(&api.Minion{}, true); err == nil {
+		t.Errorf("expected an object without labels to be refused")
+	}
+}
+
+func TestUpdateLabelsRetriesOnConflict(t *testing.T) {
+	conflicts := 1
+	var updated api.Pod
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1beta1/pods/foo" {
+			t.Errorf("unexpected request %s %s", req.Method, req.URL)
+		}
+		if req.Method == "GET" {
+			data, _ := api.Encode(&api.Pod{JSONBase: api.JSONBase{ID: "foo", ResourceVersion: 2}, Labels: map[string]string{"name": "foo"}})
+			w.Write(data)
+			return
+		}
+		if conflicts > 0 {
+			conflicts--
+			w.WriteHeader(http.StatusConflict)
+			data, _ := api.Encode(&api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeConflict})
+			w.Write(data)
+			return
+		}
+		body, _ := ioutil.ReadAll(req.Body)
+		if err := api.DecodeInto(body, &updated); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	c := client.New(server.URL, nil)
+	changes := LabelChanges{Set: map[string]string{"tier": "frontend"}}
+	obj, err := UpdateLabels(c, "", "pods/foo", changes, false, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{"name": "foo", "tier": "frontend"}
+	if pod, ok := obj.(*api.Pod); !ok || !reflect.DeepEqual(pod.Labels, expected) {
+		t.Errorf("unexpected object %#v", obj)
+	}
+	if updated.ResourceVersion != 2 || !reflect.DeepEqual(updated.Labels, expected) {
+		t.Errorf("expected only the labels to be changed, got %#v", updated)
+	}
+
+	conflicts = 2
+	if _, err := UpdateLabels(c, "", "pods/foo", changes, false, 1); !client.IsConflict(err) {
+		t.Errorf("expected a conflict once retries are exhausted, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// ListPrinter may be implemented by ResourcePrinters that can print a list as its items are
+// decoded, so that a huge list needn't be held in memory to be printed. PrintListStart is
+// called with the kind of the list, such as "PodList", then PrintItem with a pointer to each
+// of its items, then PrintListEnd. Lists are printed with PrintObj by printers that don't
+// implement it.
+type ListPrinter interface {
+	PrintListStart(kind string, w io.Writer) error
+	PrintItem(obj interface{}, w io.Writer) error
+	PrintListEnd(w io.Writer) error
+}
+
+// DecodeListItems decodes the list encoded as JSON in r one item at a time, so that the
+// list is never held in memory as a whole. It calls start with the kind of the list, then
+// item with each of its items as it is decoded, stopping at the first error. The kind and
+// version of the list must come before its items, as they do in the lists the apiserver
+// writes.
+func DecodeListItems(r io.Reader, start func(kind string) error, item func(obj interface{}) error) error {
+	decoder := json.NewDecoder(r)
+	if err := expectDelim(decoder, '{'); err != nil {
+		return err
+	}
+	var kind, version string
+	started := false
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case "kind":
+			err = decoder.Decode(&kind)
+		case "apiVersion":
+			err = decoder.Decode(&version)
+		case "items":
+			if len(kind) == 0 || len(version) == 0 {
+				return fmt.Errorf("the kind and version of the list must come before its items")
+			}
+			if err := start(kind); err != nil {
+				return err
+			}
+			started = true
+			err = decodeItems(decoder, kind, version, item)
+		default:
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := expectDelim(decoder, '}'); err != nil {
+		return err
+	}
+	if started {
+		return nil
+	}
+	// Lists without items may leave them out.
+	if len(kind) == 0 {
+		return fmt.Errorf("the list has no kind")
+	}
+	return start(kind)
+}
+
+// decodeItems decodes the items of a list of kind and version with decoder, which is at the
+// start of the items, passing each to item.
+func decodeItems(decoder *json.Decoder, kind, version string, item func(obj interface{}) error) error {
+	token, err := decoder.Token()
+	if err != nil || token == nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("expected the items of the list, got %v", token)
+	}
+	for decoder.More() {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			return err
+		}
+		obj, err := decodeItem(kind, version, raw)
+		if err != nil {
+			return err
+		}
+		if err := item(obj); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, ']')
+}
+
+// decodeItem decodes data, an item of a list of kind and version, as a list of that one item,
+// since items don't carry their kind, and returns a pointer to the item.
+func decodeItem(kind, version string, data []byte) (interface{}, error) {
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, `{"kind":%q,"apiVersion":%q,"items":[`, kind, version)
+	buf.Write(data)
+	buf.WriteString("]}")
+	list, err := api.Decode(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	items := reflect.ValueOf(list).Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice || items.Len() != 1 {
+		return nil, fmt.Errorf("expected a list, but got %#v", list)
+	}
+	return items.Index(0).Addr().Interface(), nil
+}
+
+// expectDelim reads the next token of decoder, which must be delim.
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// streamList prints list, encoded as the apiserver does, with printer as its items are
+// decoded.
+func streamList(t *testing.T, printer ListPrinter, list interface{}) string {
+	data, err := api.Encode(list)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf := &bytes.Buffer{}
+	err = DecodeListItems(bytes.NewReader(data), func(kind string) error {
+		return printer.PrintListStart(kind, buf)
+	}, func(obj interface{}) error {
+		return printer.PrintItem(obj, buf)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := printer.PrintListEnd(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.String()
+}
+
+func TestDecodeListItems(t *testing.T) {
+	pods := &api.PodList{Items: []api.Pod{
+		{JSONBase: api.JSONBase{ID: "a", Namespace: "ns"}, Labels: map[string]string{"name": "a"}},
+		{JSONBase: api.JSONBase{ID: "b"}, CurrentState: api.PodState{Status: api.PodRunning}},
+	}}
+	data, err := api.Encode(pods)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	kinds := []string{}
+	items := []interface{}{}
+	err = DecodeListItems(bytes.NewReader(data), func(kind string) error {
+		kinds = append(kinds, kind)
+		return nil
+	}, func(obj interface{}) error {
+		items = append(items, obj)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(kinds, []string{"PodList"}) || len(items) != 2 {
+		t.Fatalf("unexpected list %v: %#v", kinds, items)
+	}
+	for i := range items {
+		if !reflect.DeepEqual(items[i], &pods.Items[i]) {
+			t.Errorf("expected %#v, got %#v", &pods.Items[i], items[i])
+		}
+	}
+
+	for _, data := range []string{`{"kind":"PodList","apiVersion":"v1beta1"}`, `{"kind":"PodList","apiVersion":"v1beta1","items":null}`} {
+		started := ""
+		err := DecodeListItems(strings.NewReader(data), func(kind string) error {
+			started = kind
+			return nil
+		}, func(obj interface{}) error {
+			t.Errorf("%s: unexpected item %#v", data, obj)
+			return nil
+		})
+		if err != nil || started != "PodList" {
+			t.Errorf("%s: expected an empty list, got %q: %v", data, started, err)
+		}
+	}
+
+	for _, data := range []string{`[]`, `{"items":[{}],"kind":"PodList","apiVersion":"v1beta1"}`, `{"kind":"PodList","apiVersion":"v1beta1","items":[{"id":`, `{}`} {
+		err := DecodeListItems(strings.NewReader(data), func(string) error { return nil }, func(interface{}) error { return nil })
+		if err == nil {
+			t.Errorf("%s: expected an error", data)
+		}
+	}
+}
+
+func TestListPrinters(t *testing.T) {
+	pods := &api.PodList{Items: []api.Pod{
+		{JSONBase: api.JSONBase{ID: "a", Namespace: "ns"}, CurrentState: api.PodState{Status: api.PodWaiting}},
+		{JSONBase: api.JSONBase{ID: "b", Namespace: "other"}, CurrentState: api.PodState{Status: api.PodRunning}},
+	}}
+	services := &api.ServiceList{Items: []api.Service{{JSONBase: api.JSONBase{ID: "web"}, Port: 80}}}
+	for _, list := range []interface{}{pods, services, &api.ServiceList{}} {
+		for _, printer := range []*HumanReadablePrinter{{}, {Summary: true, Namespaces: true}, {Wide: true}} {
+			buf := &bytes.Buffer{}
+			if err := printer.PrintObj(list, buf); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if streamed := streamList(t, printer, list); streamed != buf.String() {
+				t.Errorf("expected the streamed table %q to be %q", streamed, buf.String())
+			}
+		}
+
+		streamed := streamList(t, &JSONPrinter{}, list)
+		if !strings.HasPrefix(streamed, "{\n  \"kind\": ") {
+			t.Errorf("expected indented JSON, got %q", streamed)
+		}
+		obj, err := api.Decode([]byte(streamed))
+		if err != nil || !reflect.DeepEqual(obj, list) {
+			t.Errorf("expected %#v, got %#v: %v", list, obj, err)
+		}
+	}
+
+	if err := (&HumanReadablePrinter{}).PrintListStart("WidgetList", &bytes.Buffer{}); err == nil {
+		t.Errorf("expected an error for an unknown list")
+	}
+}
+
+func TestHumanReadablePrinterStreamsRows(t *testing.T) {
+	printer := &HumanReadablePrinter{}
+	buf := &bytes.Buffer{}
+	if err := printer.PrintListStart("PodList", buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < listStreamRows+1; i++ {
+		if err := printer.PrintItem(&api.Pod{JSONBase: api.JSONBase{ID: fmt.Sprintf("pod%d", i)}}, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != listStreamRows+2 {
+		t.Errorf("expected the header and a batch of rows before the end of the list, got %d lines", lines)
+	}
+	if err := printer.PrintListEnd(buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != listStreamRows+3 {
+		t.Errorf("expected every row at the end of the list, got %d lines", lines)
+	}
+	if err := printer.PrintItem(&api.Pod{}, buf); err == nil {
+		t.Errorf("expected an error for an item after the end of the list")
+	}
+}
@@ -17,11 +17,15 @@ limitations under the License.
 package kubecfg
 
 import (
+	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"reflect"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"gopkg.in/v1/yaml"
 )
 
 var storageToType = map[string]reflect.Type{
@@ -30,6 +34,8 @@ var storageToType = map[string]reflect.Type{
 	"replicationControllers": reflect.TypeOf(api.ReplicationController{}),
 	"minions":                reflect.TypeOf(api.Minion{}),
 	"builds":                 reflect.TypeOf(buildapi.Build{}),
+	"events":                 reflect.TypeOf(api.Event{}),
+	"endpoints":              reflect.TypeOf(api.Endpoints{}),
 }
 
 // ToWireFormat takes input 'data' as either json or yaml, checks that it parses as the
@@ -48,6 +54,94 @@ func ToWireFormat(data []byte, storage string) ([]byte, error) {
 	return api.Encode(obj)
 }
 
+// ValidateID checks the ID of the object data encodes, as returned by ToWireFormat, against
+// the rules the apiserver enforces when objects are created, so that an object it would
+// reject with an invalid ID fails before it is sent. The error returned is an
+// api.InvalidIDError naming the rule broken, unless data can't be decoded.
+func ValidateID(data []byte) error {
+	obj, err := api.Decode(data)
+	if err != nil {
+		return err
+	}
+	if errs := api.ValidateObjectID(obj); len(errs) != 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// IsYAML returns true if the config read from 'name' should be treated as YAML, either
+// because of its extension or because 'data' does not parse as JSON.
+func IsYAML(name string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+		return true
+	case ".json":
+		return false
+	}
+	var obj interface{}
+	return json.Unmarshal(data, &obj) != nil
+}
+
+// ToJSON converts the config 'data' read from 'name' to JSON. YAML input is detected
+// with IsYAML; errors parsing it include the line number reported by the YAML parser.
+func ToJSON(name string, data []byte) ([]byte, error) {
+	if !IsYAML(name, data) {
+		return data, nil
+	}
+	var obj interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return nil, fmt.Errorf("unable to parse %s as YAML: %v", name, err)
+	}
+	obj, err := yamlToJSONValue(obj)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert %s to JSON: %v", name, err)
+	}
+	return json.Marshal(obj)
+}
+
+// yamlToJSONValue converts the generic maps produced by the YAML parser, which are
+// keyed by interface{}, into maps that encoding/json can marshal.
+func yamlToJSONValue(in interface{}) (interface{}, error) {
+	switch t := in.(type) {
+	case map[interface{}]interface{}:
+		out := map[string]interface{}{}
+		for key, value := range t {
+			keyString, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a string", key)
+			}
+			converted, err := yamlToJSONValue(value)
+			if err != nil {
+				return nil, err
+			}
+			out[keyString] = converted
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, value := range t {
+			converted, err := yamlToJSONValue(value)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+	default:
+		return in, nil
+	}
+}
+
+// StorageForKind returns the storage that holds objects of the given kind.
+func StorageForKind(kind string) (string, error) {
+	for storage, t := range storageToType {
+		if t.Name() == kind {
+			return storage, nil
+		}
+	}
+	return "", fmt.Errorf("unknown kind: %v", kind)
+}
+
 func SupportedWireStorage() []string {
 	types := []string{}
 	for k := range storageToType {
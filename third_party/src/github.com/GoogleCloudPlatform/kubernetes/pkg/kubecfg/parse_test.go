@@ -17,6 +17,8 @@ limitations under the License.
 package kubecfg
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
@@ -105,3 +107,106 @@ func TestParseController(t *testing.T) {
 		},
 	})
 }
+
+func TestIsYAML(t *testing.T) {
+	table := []struct {
+		name string
+		data string
+		yaml bool
+	}{
+		{"pod.yaml", `{"id": "foo"}`, true},
+		{"pod.yml", "id: foo", true},
+		{"pod.json", "id: foo", false},
+		{"pod", `{"id": "foo"}`, false},
+		{"-", "id: foo", true},
+	}
+	for _, item := range table {
+		if got := IsYAML(item.name, []byte(item.data)); got != item.yaml {
+			t.Errorf("%s %q: expected %v, got %v", item.name, item.data, item.yaml, got)
+		}
+	}
+}
+
+func TestYAMLPodMatchesJSON(t *testing.T) {
+	jsonData := []byte(`{
+  "id": "frontend",
+  "kind": "Pod",
+  "apiVersion": "v1beta1",
+  "labels": {"name": "frontend"},
+  "desiredState": {
+    "manifest": {
+      "version": "v1beta1",
+      "id": "frontend",
+      "containers": [{
+        "name": "web",
+        "image": "dockerfile/nginx",
+        "ports": [{"containerPort": 80, "hostPort": 8080}],
+        "env": [{"name": "MODE", "value": "prod"}]
+      }]
+    }
+  }
+}`)
+	yamlData := []byte(`id: frontend
+kind: Pod
+apiVersion: v1beta1
+labels:
+  name: frontend
+desiredState:
+  manifest:
+    version: v1beta1
+    id: frontend
+    containers:
+      - name: web
+        image: dockerfile/nginx
+        ports:
+          - containerPort: 80
+            hostPort: 8080
+        env:
+          - name: MODE
+            value: prod
+`)
+	objects := []interface{}{}
+	for name, data := range map[string][]byte{"pod.json": jsonData, "pod.yaml": yamlData} {
+		converted, err := ToJSON(name, data)
+		if err != nil {
+			t.Fatalf("unexpected error converting %s: %v", name, err)
+		}
+		wire, err := ToWireFormat(converted, "pods")
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %v", name, err)
+		}
+		obj, err := api.Decode(wire)
+		if err != nil {
+			t.Fatalf("unexpected error decoding %s: %v", name, err)
+		}
+		objects = append(objects, obj)
+	}
+	if !reflect.DeepEqual(objects[0], objects[1]) {
+		t.Errorf("expected YAML and JSON manifests to produce the same pod:\n%#v\n%#v", objects[0], objects[1])
+	}
+}
+
+func TestToJSONMalformedYAML(t *testing.T) {
+	_, err := ToJSON("pod.yaml", []byte("id: foo\ndesiredState:\n  manifest: [\n"))
+	if err == nil {
+		t.Fatalf("expected error for malformed YAML")
+	}
+	if !strings.Contains(err.Error(), "line") {
+		t.Errorf("expected error to include a line number, got %v", err)
+	}
+}
+
+func TestValidateID(t *testing.T) {
+	valid, _ := api.Encode(api.Pod{JSONBase: api.JSONBase{ID: "frontend"}})
+	if err := ValidateID(valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	invalid, _ := api.Encode(api.Pod{JSONBase: api.JSONBase{ID: "proxy"}})
+	err := ValidateID(invalid)
+	if _, ok := err.(api.InvalidIDError); !ok {
+		t.Fatalf("expected an invalid ID error, got %v", err)
+	}
+	if code := ExitCode(err); code != ExitInvalid {
+		t.Errorf("expected %d, got %d", ExitInvalid, code)
+	}
+}
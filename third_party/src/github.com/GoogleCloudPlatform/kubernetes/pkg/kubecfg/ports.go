@@ -0,0 +1,81 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+// IsPortConflict returns true if err is the server refusing a service because another
+// service already uses its port.
+func IsPortConflict(err error) bool {
+	statusErr, ok := err.(*client.StatusErr)
+	if !ok || statusErr.Status.Reason != api.ReasonTypeConflict || statusErr.Status.Details == nil {
+		return false
+	}
+	for _, cause := range statusErr.Status.Details.Causes {
+		if cause.Type == api.CauseTypeFieldValueConflict && cause.Field == "port" {
+			return true
+		}
+	}
+	return false
+}
+
+// FreeServicePort returns the first port after port that no service uses with protocol,
+// according to used, the ports of the usedPorts view of services. It returns 0 if every
+// port after port is used.
+func FreeServicePort(used map[string]string, port int, protocol string) int {
+	protocol = strings.ToUpper(protocol)
+	if len(protocol) == 0 {
+		protocol = "TCP"
+	}
+	for free := port + 1; free <= 65535; free++ {
+		if _, ok := used[fmt.Sprintf("%d/%s", free, protocol)]; !ok {
+			return free
+		}
+	}
+	return 0
+}
+
+// SuggestServicePort returns a line suggesting a free port for service, which the server
+// refused with err, if err is a port conflict. It returns "" if err is not, or if the used
+// ports can't be listed.
+func SuggestServicePort(c *client.Client, service *api.Service, err error) string {
+	if !IsPortConflict(err) {
+		return ""
+	}
+	data, err := c.Get().Path("services").Param("usedPorts", "true").Do().Raw()
+	if err != nil {
+		return ""
+	}
+	var used struct {
+		Ports map[string]string `json:"ports"`
+	}
+	if err := json.Unmarshal(data, &used); err != nil {
+		return ""
+	}
+	port := FreeServicePort(used.Ports, service.Port, service.Protocol)
+	if port == 0 {
+		return ""
+	}
+	return fmt.Sprintf("port %d is free", port)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+func TestFreeServicePort(t *testing.T) {
+	used := map[string]string{"80/TCP": "web", "81/TCP": "admin", "81/UDP": "dns", "65535/TCP": "last"}
+	table := []struct {
+		port     int
+		protocol string
+		free     int
+	}{
+		{80, "", 82},
+		{80, "TCP", 82},
+		{80, "udp", 82},
+		{80, "UDP", 82},
+		{81, "UDP", 82},
+		{79, "UDP", 80},
+		{65534, "TCP", 0},
+	}
+	for _, item := range table {
+		if free := FreeServicePort(used, item.port, item.protocol); free != item.free {
+			t.Errorf("%d/%s: expected %d, got %d", item.port, item.protocol, item.free, free)
+		}
+	}
+}
+
+func TestSuggestServicePort(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1beta1/services" || req.URL.Query().Get("usedPorts") != "true" {
+			t.Errorf("unexpected request: %s", req.URL)
+		}
+		w.Write([]byte(`{"kind": "UsedPorts", "ports": {"80/TCP": "web", "81/TCP": "admin"}}`))
+	}))
+	defer server.Close()
+	c := client.New(server.URL, nil)
+	service := &api.Service{JSONBase: api.JSONBase{ID: "other"}, Port: 80}
+
+	conflict := &client.StatusErr{Status: api.Status{
+		Status:  api.StatusFailure,
+		Code:    http.StatusConflict,
+		Reason:  api.ReasonTypeConflict,
+		Details: &api.StatusDetails{Kind: "service", ID: "other", Causes: []api.StatusCause{{Type: api.CauseTypeFieldValueConflict, Field: "port"}}},
+	}}
+	if line := SuggestServicePort(c, service, conflict); line != "port 82 is free" {
+		t.Errorf("unexpected suggestion: %q", line)
+	}
+	exists := &client.StatusErr{Status: api.Status{Status: api.StatusFailure, Code: http.StatusConflict, Reason: api.ReasonTypeAlreadyExists}}
+	if line := SuggestServicePort(c, service, exists); len(line) != 0 {
+		t.Errorf("unexpected suggestion for an existing service: %q", line)
+	}
+}
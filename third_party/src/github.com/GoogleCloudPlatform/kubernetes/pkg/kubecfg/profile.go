@@ -0,0 +1,187 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"gopkg.in/v1/yaml"
+)
+
+// Profile holds the connection settings for one named server.
+type Profile struct {
+	// Host is the server to connect to.
+	Host string `yaml:"host,omitempty"`
+	// Auth is the path of the auth info file.
+	Auth string `yaml:"auth,omitempty"`
+	// Labels is the default label selector.
+	Labels string `yaml:"labels,omitempty"`
+	// Namespace is the namespace objects are operated on in by default.
+	Namespace string `yaml:"namespace,omitempty"`
+	// Insecure disables verification of the server's TLS certificate.
+	Insecure bool `yaml:"insecure,omitempty"`
+}
+
+// ProfileConfig is the contents of a profile file: a set of named profiles and the
+// name of the one used when none is selected.
+type ProfileConfig struct {
+	Default  string             `yaml:"default,omitempty"`
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+}
+
+// LoadProfileConfig reads the profile file at path. A missing file yields an empty config.
+func LoadProfileConfig(path string) (*ProfileConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &ProfileConfig{Profiles: map[string]Profile{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config, err := ParseProfileConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// ParseProfileConfig parses the YAML or JSON contents of a profile file. Unknown keys and
+// values of the wrong type are reported by their full key, e.g. "profiles.dev.insecure".
+func ParseProfileConfig(data []byte) (*ProfileConfig, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	config := &ProfileConfig{Profiles: map[string]Profile{}}
+	if raw == nil {
+		return config, nil
+	}
+	top, ok := raw.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected a map of settings, got %v", raw)
+	}
+	for k, v := range top {
+		key := fmt.Sprintf("%v", k)
+		switch key {
+		case "default":
+			s, err := profileString(key, v)
+			if err != nil {
+				return nil, err
+			}
+			config.Default = s
+		case "profiles":
+			profiles, ok := v.(map[interface{}]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s: expected a map of profiles, got %v", key, v)
+			}
+			for name, value := range profiles {
+				profile, err := parseProfile(fmt.Sprintf("%s.%v", key, name), value)
+				if err != nil {
+					return nil, err
+				}
+				config.Profiles[fmt.Sprintf("%v", name)] = profile
+			}
+		default:
+			return nil, fmt.Errorf("%s: unknown key", key)
+		}
+	}
+	return config, nil
+}
+
+func parseProfile(prefix string, value interface{}) (Profile, error) {
+	profile := Profile{}
+	if value == nil {
+		return profile, nil
+	}
+	fields, ok := value.(map[interface{}]interface{})
+	if !ok {
+		return profile, fmt.Errorf("%s: expected a map of settings, got %v", prefix, value)
+	}
+	for k, v := range fields {
+		key := fmt.Sprintf("%s.%v", prefix, k)
+		var err error
+		switch fmt.Sprintf("%v", k) {
+		case "host":
+			profile.Host, err = profileString(key, v)
+		case "auth":
+			profile.Auth, err = profileString(key, v)
+		case "labels":
+			profile.Labels, err = profileString(key, v)
+		case "namespace":
+			profile.Namespace, err = profileString(key, v)
+		case "insecure":
+			b, ok := v.(bool)
+			if !ok {
+				err = fmt.Errorf("%s: expected true or false, got %v", key, v)
+			}
+			profile.Insecure = b
+		default:
+			err = fmt.Errorf("%s: unknown key", key)
+		}
+		if err != nil {
+			return profile, err
+		}
+	}
+	return profile, nil
+}
+
+func profileString(key string, value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s: expected a string, got %v", key, value)
+	}
+	return s, nil
+}
+
+// Save writes the config to path. The file is only readable by its owner, since profiles
+// refer to credentials.
+func (c *ProfileConfig) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Profile returns the profile called name, or the default profile if name is empty. The
+// second return value is false if no profile was named and there is no default.
+func (c *ProfileConfig) Profile(name string) (Profile, bool, error) {
+	if len(name) == 0 {
+		name = c.Default
+		if len(name) == 0 {
+			return Profile{}, false, nil
+		}
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, false, fmt.Errorf("no profile named %q", name)
+	}
+	return profile, true, nil
+}
+
+// Names returns the names of all profiles in sorted order.
+func (c *ProfileConfig) Names() []string {
+	names := []string{}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
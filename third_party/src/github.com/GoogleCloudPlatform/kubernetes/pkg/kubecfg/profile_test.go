@@ -0,0 +1,126 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseProfileConfig(t *testing.T) {
+	data := `
+default: local
+profiles:
+  local:
+    host: http://localhost:8080
+  dev:
+    host: https://dev.example.com
+    auth: /home/me/.dev_auth
+    labels: team=web
+    namespace: web
+    insecure: true
+`
+	config, err := ParseProfileConfig([]byte(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &ProfileConfig{
+		Default: "local",
+		Profiles: map[string]Profile{
+			"local": {Host: "http://localhost:8080"},
+			"dev":   {Host: "https://dev.example.com", Auth: "/home/me/.dev_auth", Labels: "team=web", Namespace: "web", Insecure: true},
+		},
+	}
+	if !reflect.DeepEqual(expected, config) {
+		t.Errorf("expected %#v, got %#v", expected, config)
+	}
+	if names := config.Names(); !reflect.DeepEqual(names, []string{"dev", "local"}) {
+		t.Errorf("unexpected names: %v", names)
+	}
+}
+
+func TestParseProfileConfigErrors(t *testing.T) {
+	table := map[string]string{
+		"defualt: local":                          "defualt",
+		"profiles:\n  dev:\n    hots: foo":        "profiles.dev.hots",
+		"profiles:\n  dev:\n    insecure: maybe":  "profiles.dev.insecure",
+		"profiles:\n  dev:\n    host: [a, b]":     "profiles.dev.host",
+		"profiles: [dev]":                         "profiles",
+		"default: {name: local}":                  "default",
+		"profiles:\n  dev: http://localhost:8080": "profiles.dev",
+	}
+	for data, key := range table {
+		_, err := ParseProfileConfig([]byte(data))
+		if err == nil {
+			t.Errorf("expected error for %q", data)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), key+":") {
+			t.Errorf("expected error naming %s, got %v", key, err)
+		}
+	}
+}
+
+func TestProfileSelection(t *testing.T) {
+	config := &ProfileConfig{
+		Default:  "local",
+		Profiles: map[string]Profile{"local": {Host: "a"}, "dev": {Host: "b"}},
+	}
+	if profile, ok, err := config.Profile(""); err != nil || !ok || profile.Host != "a" {
+		t.Errorf("unexpected default profile: %#v %v %v", profile, ok, err)
+	}
+	if profile, ok, err := config.Profile("dev"); err != nil || !ok || profile.Host != "b" {
+		t.Errorf("unexpected named profile: %#v %v %v", profile, ok, err)
+	}
+	if _, _, err := config.Profile("missing"); err == nil {
+		t.Errorf("expected error for missing profile")
+	}
+	config.Default = ""
+	if _, ok, err := config.Profile(""); err != nil || ok {
+		t.Errorf("expected no profile, got %v %v", ok, err)
+	}
+}
+
+func TestProfileConfigSaveAndLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kubecfg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, ".kubecfg")
+
+	config, err := LoadProfileConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading missing file: %v", err)
+	}
+	config.Profiles["dev"] = Profile{Host: "https://dev.example.com", Insecure: true}
+	config.Default = "dev"
+	if err := config.Save(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := LoadProfileConfig(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(config, loaded) {
+		t.Errorf("expected %#v, got %#v", config, loaded)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// Phases of the steps of long kubecfg actions, reported in ProgressEvents.
+const (
+	// PhaseAccepted means the server accepted a request as an operation to wait for.
+	PhaseAccepted = "accepted"
+	// PhaseWaiting means an operation, or pods, were found not done yet.
+	PhaseWaiting = "waiting"
+	// PhaseUpdated means a controller was changed, such as given a new template or resized.
+	PhaseUpdated = "updated"
+	// PhaseReplacing means a pod was deleted, for its controller to replace it.
+	PhaseReplacing = "replacing"
+	// PhaseReplaced means the replacement of a pod is running.
+	PhaseReplaced = "replaced"
+	// PhaseCreated means an object was created.
+	PhaseCreated = "created"
+	// PhaseDeleted means an object was deleted.
+	PhaseDeleted = "deleted"
+	// PhaseFailed means a step failed; the action may go on with the next.
+	PhaseFailed = "failed"
+	// PhaseResult is the phase of the last event of an action, which has its Result.
+	PhaseResult = "result"
+)
+
+// ProgressEvent is a step of a long kubecfg action, such as the replacement of a pod by a
+// rolling update. With --json, kubecfg writes the steps of rollingupdate, stop and of
+// --wait as a stream of events, one JSON object per line, ending with a PhaseResult event.
+type ProgressEvent struct {
+	Phase string `json:"phase"`
+	// Object is the object the step is about, such as pods/foo.
+	Object    string    `json:"object,omitempty"`
+	Message   string    `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Result is set on the PhaseResult event only.
+	Result *ProgressResult `json:"result,omitempty"`
+}
+
+// ProgressResult is the outcome of a long kubecfg action.
+type ProgressResult struct {
+	Succeeded bool `json:"succeeded"`
+	// ExitCode is the code kubecfg exits with.
+	ExitCode int `json:"exitCode"`
+	// Output is the object the action produced, if any, such as the result of an operation.
+	Output json.RawMessage `json:"output,omitempty"`
+}
+
+// ProgressFunc is called with each step of a long action. A nil ProgressFunc ignores them.
+type ProgressFunc func(phase, object, message string)
+
+// report calls f, if it is set, with a message made from format and args.
+func (f ProgressFunc) report(phase, object, format string, args ...interface{}) {
+	if f != nil {
+		f(phase, object, fmt.Sprintf(format, args...))
+	}
+}
+
+// ProgressWriter writes the steps of a long action to a writer as newline delimited JSON
+// ProgressEvents. It is safe for concurrent use.
+type ProgressWriter struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewProgressWriter returns a ProgressWriter that writes events to w.
+func NewProgressWriter(w io.Writer) *ProgressWriter {
+	return &ProgressWriter{encoder: json.NewEncoder(w)}
+}
+
+// Progress writes an event for a step. It is a ProgressFunc.
+func (p *ProgressWriter) Progress(phase, object, message string) {
+	p.write(ProgressEvent{Phase: phase, Object: object, Message: message})
+}
+
+// Result writes the last event of an action, which failed with err unless err is nil.
+// output, the object the action produced, is encoded into it if it is not nil.
+func (p *ProgressWriter) Result(err error, output interface{}) {
+	message := ""
+	if err != nil {
+		message = err.Error()
+	}
+	p.ResultCode(ExitCode(err), message, output)
+}
+
+// ResultCode writes the last event of an action that exits with code, for actions whose
+// outcome isn't a single error, such as one that failed for several objects.
+func (p *ProgressWriter) ResultCode(code int, message string, output interface{}) {
+	event := ProgressEvent{Phase: PhaseResult, Message: message, Result: &ProgressResult{Succeeded: code == ExitSuccess, ExitCode: code}}
+	if output != nil {
+		data, err := api.Encode(output)
+		if err != nil {
+			event.Result.Succeeded = false
+			event.Result.ExitCode = ExitError
+			event.Message = fmt.Sprintf("unable to encode the result: %v", err)
+		}
+		event.Result.Output = data
+	}
+	p.write(event)
+}
+
+func (p *ProgressWriter) write(event ProgressEvent) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	event.Timestamp = time.Now().UTC()
+	// Events are encoded one per line: the encoder ends each with a newline, and the
+	// encoding of an event never has one.
+	p.encoder.Encode(&event)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+)
+
+// decodeProgress decodes every line of data as a ProgressEvent.
+func decodeProgress(t *testing.T, data string) []ProgressEvent {
+	events := []ProgressEvent{}
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\n"), "\n") {
+		var event ProgressEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		if event.Timestamp.IsZero() {
+			t.Errorf("event without a timestamp: %q", line)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestProgressWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewProgressWriter(buf)
+	w.Progress(PhaseReplacing, "pods/foo", "replacing pod 1 of 2")
+	w.Progress(PhaseFailed, "pods/bar", "a message\nover two lines")
+	w.Result(&client.StatusErr{Status: api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound, Message: "not found"}}, nil)
+	w.Result(nil, &api.Pod{JSONBase: api.JSONBase{ID: "foo"}})
+
+	events := decodeProgress(t, buf.String())
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events, got %q", buf.String())
+	}
+	if events[0].Phase != PhaseReplacing || events[0].Object != "pods/foo" || events[0].Message != "replacing pod 1 of 2" || events[0].Result != nil {
+		t.Errorf("unexpected event: %#v", events[0])
+	}
+	if events[1].Message != "a message\nover two lines" {
+		t.Errorf("unexpected event: %#v", events[1])
+	}
+	failed := events[2]
+	if failed.Phase != PhaseResult || failed.Result == nil || failed.Result.Succeeded || failed.Result.ExitCode != ExitNotFound || !strings.Contains(failed.Message, "not found") {
+		t.Errorf("unexpected result: %#v", failed)
+	}
+	succeeded := events[3]
+	if succeeded.Result == nil || !succeeded.Result.Succeeded || succeeded.Result.ExitCode != ExitSuccess {
+		t.Fatalf("unexpected result: %#v", succeeded)
+	}
+	var pod api.Pod
+	if err := api.DecodeInto(succeeded.Result.Output, &pod); err != nil || pod.ID != "foo" {
+		t.Errorf("unexpected output %s: %v", succeeded.Result.Output, err)
+	}
+}
+
+// recordProgress returns a ProgressFunc that appends the steps it is called with to steps.
+func recordProgress(steps *[]string) ProgressFunc {
+	return func(phase, object, message string) {
+		*steps = append(*steps, fmt.Sprintf("%s %s: %s", phase, object, message))
+	}
+}
+
+func TestRollingUpdateProgress(t *testing.T) {
+	client := &sequenceKubeClient{
+		podLists: []api.PodList{
+			{Items: []api.Pod{runningPod("pod-1"), runningPod("pod-2")}},
+			{Items: []api.Pod{runningPod("pod-2"), runningPod("pod-3")}},
+			{Items: []api.Pod{runningPod("pod-3"), runningPod("pod-4")}},
+		},
+	}
+	steps := []string{}
+	options := RollingUpdateOptions{Template: &api.PodTemplate{}, PollInterval: time.Millisecond, Progress: recordProgress(&steps)}
+	if err := RollingUpdate("foo", client, options); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"updated replicationControllers/foo: updated the pod template",
+		"replacing pods/pod-1: replacing pod 1 of 2",
+		"replaced pods/pod-1: replaced pod 1 of 2",
+		"replacing pods/pod-2: replacing pod 2 of 2",
+		"replaced pods/pod-2: replaced pod 2 of 2",
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected %v, got %v", expected, steps)
+	}
+}
+
+func TestStopAndDeleteControllerProgress(t *testing.T) {
+	fakeClient := &sequenceKubeClient{
+		podLists: []api.PodList{
+			{Items: []api.Pod{runningPod("pod-1")}},
+			{},
+		},
+	}
+	steps := []string{}
+	if err := StopAndDeleteController("foo", fakeClient, time.Millisecond, 0, recordProgress(&steps)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"updated replicationControllers/foo: resized to 0 replicas",
+		"waiting replicationControllers/foo: 1 pods, waiting for 0",
+		"deleted replicationControllers/foo: deleted",
+	}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Errorf("expected %v, got %v", expected, steps)
+	}
+}
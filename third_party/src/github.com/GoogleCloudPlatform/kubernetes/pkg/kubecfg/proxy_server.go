@@ -17,40 +17,82 @@ limitations under the License.
 package kubecfg
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 )
 
 // ProxyServer is a http.Handler which proxies Kubenetes APIs to remote API server.
+// Requests under APIPrefix are forwarded to the server's /api/ path with the credentials
+// in Auth attached; requests outside APIPrefix and the static file root receive a 404.
 type ProxyServer struct {
-	Host   string
-	Auth   *client.AuthInfo
-	Client *client.Client
+	Host      string
+	Auth      *client.AuthInfo
+	Client    *client.Client
+	APIPrefix string
+	// CertFile and KeyFile, if set, make the proxy serve HTTPS with the given certificate.
+	CertFile string
+	KeyFile  string
+
+	mux *http.ServeMux
 }
 
 func makeFileHandler(prefix, base string) http.Handler {
 	return http.StripPrefix(prefix, http.FileServer(http.Dir(base)))
 }
 
-// NewProxyServer creates and installs a new ProxyServer.
-// It automatically registers the created ProxyServer to http.DefaultServeMux.
+// NewProxyServer creates a new ProxyServer that serves the API under /api/ and the
+// files in filebase under /static/.
 func NewProxyServer(filebase, host string, auth *client.AuthInfo) *ProxyServer {
+	return NewPrefixedProxyServer(filebase, "/api/", host, auth)
+}
+
+// NewPrefixedProxyServer creates a new ProxyServer that serves the API under apiPrefix,
+// which replaces the /api/ prefix of the remote server, and the files in filebase under
+// /static/. If filebase is empty no static files are served.
+func NewPrefixedProxyServer(filebase, apiPrefix, host string, auth *client.AuthInfo) *ProxyServer {
+	if !strings.HasSuffix(apiPrefix, "/") {
+		apiPrefix += "/"
+	}
 	server := &ProxyServer{
-		Host:   host,
-		Auth:   auth,
-		Client: client.New(host, auth),
+		Host:      host,
+		Auth:      auth,
+		Client:    client.New(host, auth),
+		APIPrefix: apiPrefix,
+		mux:       http.NewServeMux(),
+	}
+	server.mux.Handle(apiPrefix, server)
+	if len(filebase) > 0 {
+		server.mux.Handle("/static/", makeFileHandler("/static/", filebase))
 	}
-	http.Handle("/api/", server)
-	http.Handle("/static/", makeFileHandler("/static/", filebase))
 	return server
 }
 
-// Serve starts the server (http.DefaultServeMux) on TCP port 8001, loops forever.
+// Serve starts the server on TCP port 8001, loops forever.
 func (s *ProxyServer) Serve() error {
-	return http.ListenAndServe(":8001", nil)
+	return s.ListenAndServe("tcp", ":8001")
+}
+
+// ListenAndServe starts the server on the given network ("tcp" or "unix") and address, loops forever.
+func (s *ProxyServer) ListenAndServe(network, address string) error {
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return err
+	}
+	if len(s.CertFile) > 0 || len(s.KeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(s.CertFile, s.KeyFile)
+		if err != nil {
+			l.Close()
+			return err
+		}
+		l = tls.NewListener(l, &tls.Config{Certificates: []tls.Certificate{cert}})
+	}
+	return http.Serve(l, s.mux)
 }
 
 func (s *ProxyServer) doError(w http.ResponseWriter, err error) {
@@ -64,7 +106,12 @@ func (s *ProxyServer) doError(w http.ResponseWriter, err error) {
 }
 
 func (s *ProxyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	result := s.Client.Verb(r.Method).AbsPath(r.URL.Path).Body(r.Body).Do()
+	if !strings.HasPrefix(r.URL.Path, s.APIPrefix) {
+		http.NotFound(w, r)
+		return
+	}
+	path := "/api/" + strings.TrimPrefix(r.URL.Path, s.APIPrefix)
+	result := s.Client.Verb(r.Method).AbsPath(path).Body(r.Body).Do()
 	if result.Error() != nil {
 		s.doError(w, result.Error())
 		return
@@ -20,7 +20,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 )
 
 func TestFileServing(t *testing.T) {
@@ -57,3 +60,49 @@ func TestFileServing(t *testing.T) {
 		t.Errorf("Data doesn't match: %s vs %s", string(b), data)
 	}
 }
+
+func TestProxyRewritesPrefixAndInjectsAuth(t *testing.T) {
+	var gotPath, gotUser, gotPassword string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		gotPath = req.URL.Path
+		gotUser, gotPassword, _ = req.BasicAuth()
+		w.Write([]byte(`{"kind": "PodList"}`))
+	}))
+	defer backend.Close()
+
+	dir, err := ioutil.TempDir("", "data")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(dir+"/test.txt", []byte("data"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	proxy := NewPrefixedProxyServer(dir, "/k8s", backend.URL, &client.AuthInfo{User: "user", Password: "pass"})
+	server := httptest.NewServer(proxy.mux)
+	defer server.Close()
+
+	table := map[string]int{
+		"/k8s/v1beta1/pods": http.StatusOK,
+		"/static/test.txt":  http.StatusOK,
+		"/api/v1beta1/pods": http.StatusNotFound,
+		"/other":            http.StatusNotFound,
+	}
+	for path, status := range table {
+		res, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != status {
+			t.Errorf("Expected %d for %s, got %d", status, path, res.StatusCode)
+		}
+	}
+	if gotPath != "/api/v1beta1/pods" {
+		t.Errorf("Unexpected forwarded path: %s", gotPath)
+	}
+	if gotUser != "user" || gotPassword != "pass" {
+		t.Errorf("Expected credentials to be forwarded, got %s:%s", gotUser, gotPassword)
+	}
+}
@@ -17,16 +17,19 @@ limitations under the License.
 package kubecfg
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
 	"text/template"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"gopkg.in/v1/yaml"
 )
 
@@ -55,6 +58,97 @@ func (i *IdentityPrinter) PrintObj(obj interface{}, output io.Writer) error {
 	return i.Print(data, output)
 }
 
+// JSONPrinter is an implementation of ResourcePrinter which prints JSON indented, however
+// compact the server sent it. It is a ListPrinter, which prints the items of a list as they
+// come.
+type JSONPrinter struct {
+	// kind is the kind of the list being printed with PrintItem, and items the number of its
+	// items printed so far.
+	kind  string
+	items int
+}
+
+// Print indents the JSON data and prints it. Data that isn't JSON is printed as it is.
+func (j *JSONPrinter) Print(data []byte, w io.Writer) error {
+	buf := &bytes.Buffer{}
+	if err := json.Indent(buf, data, "", "  "); err != nil {
+		_, err := w.Write(data)
+		return err
+	}
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// PrintObj encodes obj and prints it indented.
+func (j *JSONPrinter) PrintObj(obj interface{}, w io.Writer) error {
+	data, err := api.Encode(obj)
+	if err != nil {
+		return err
+	}
+	return j.Print(data, w)
+}
+
+// PrintListStart starts an indented list of kind, whose items PrintItem prints.
+func (j *JSONPrinter) PrintListStart(kind string, w io.Writer) error {
+	list, err := api.New("", kind)
+	if err != nil {
+		return err
+	}
+	data, err := api.Encode(list)
+	if err != nil {
+		return err
+	}
+	version, _, err := api.VersionAndKind(data)
+	if err != nil {
+		return err
+	}
+	j.kind, j.items = kind, 0
+	_, err = fmt.Fprintf(w, "{\n  \"kind\": %q,\n  \"apiVersion\": %q,\n  \"items\": [", kind, version)
+	return err
+}
+
+// PrintItem encodes obj and prints it indented as the next item of the list. Items are
+// encoded as they are in a list, without their kind.
+func (j *JSONPrinter) PrintItem(obj interface{}, w io.Writer) error {
+	list, err := listOfKind(j.kind, []interface{}{obj})
+	if err != nil {
+		return err
+	}
+	data, err := api.Encode(list)
+	if err != nil {
+		return err
+	}
+	var encoded struct {
+		Items []json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil || len(encoded.Items) != 1 {
+		return fmt.Errorf("unable to encode %#v as an item of a %s: %v", obj, j.kind, err)
+	}
+	data = encoded.Items[0]
+	buf := &bytes.Buffer{}
+	if j.items > 0 {
+		buf.WriteByte(',')
+	}
+	buf.WriteString("\n    ")
+	if err := json.Indent(buf, data, "    ", "  "); err != nil {
+		return err
+	}
+	j.items++
+	_, err = buf.WriteTo(w)
+	return err
+}
+
+// PrintListEnd ends the list.
+func (j *JSONPrinter) PrintListEnd(w io.Writer) error {
+	end := "]\n}\n"
+	if j.items > 0 {
+		end = "\n  " + end
+	}
+	_, err := fmt.Fprint(w, end)
+	return err
+}
+
 // YAMLPrinter is an implementation of ResourcePrinter which parsess JSON, and re-formats as YAML
 type YAMLPrinter struct{}
 
@@ -82,22 +176,105 @@ func (y *YAMLPrinter) PrintObj(obj interface{}, w io.Writer) error {
 	return err
 }
 
+// DefaultMaxColumnWidth is the width HumanReadablePrinter truncates cells to unless told
+// otherwise.
+const DefaultMaxColumnWidth = 50
+
 // HumanReadablePrinter is an implementation of ResourcePrinter which attempts to provide more elegant output.
-type HumanReadablePrinter struct{}
+type HumanReadablePrinter struct {
+	// Wide adds columns that are too detailed for the default output, and prints every cell
+	// in full.
+	Wide bool
+	// MaxColumnWidth is the number of characters cells are truncated to, ending with "...",
+	// so that long values such as label sets don't push the other columns off the screen.
+	// Zero means DefaultMaxColumnWidth, and a negative width means no limit.
+	MaxColumnWidth int
+	// Endpoints are the endpoints of services, by the qualified ID of the service (see
+	// api.QualifiedID), which Wide service tables count. If nil, the counts are unknown.
+	Endpoints map[string]api.Endpoints
+	// NoHeaders leaves out the column names above tables.
+	NoHeaders bool
+	// Summary prints a ListSummary line after the tables of pods, builds and replication
+	// controllers, unless NoHeaders is set.
+	Summary bool
+	// Replicas are the current replicas of replication controllers, by the qualified ID of
+	// the controller, which summaries of controllers compare to the desired replicas. If
+	// nil, the current replicas are unknown.
+	Replicas map[string]int
+	// Namespaces adds a Namespace column to the tables of lists, for lists that span
+	// namespaces.
+	Namespaces bool
+
+	// stream is the list being printed with PrintItem, if any.
+	stream *humanListStream
+}
+
+// listStreamRows is the number of rows of a list printed with PrintItem that are aligned
+// together: the columns of each batch of rows are as wide as the widest of its cells, so
+// that rows needn't be held until the end of the list.
+const listStreamRows = 100
+
+// humanListStream is a list being printed by a HumanReadablePrinter as its items come.
+type humanListStream struct {
+	kind string
+	w    *tabwriter.Writer
+	rows int
+	// summary tallies the items printed so far, if summarized is true.
+	summary    ListSummary
+	summarized bool
+}
+
+// EndpointsByService returns the endpoints in list by the qualified ID of their service, as
+// HumanReadablePrinter.Endpoints expects them.
+func EndpointsByService(list *api.EndpointsList) map[string]api.Endpoints {
+	byService := map[string]api.Endpoints{}
+	for _, endpoints := range list.Items {
+		byService[api.QualifiedID(endpoints.Namespace, endpoints.ID)] = endpoints
+	}
+	return byService
+}
 
-var podColumns = []string{"Name", "Image(s)", "Host", "Labels"}
-var replicationControllerColumns = []string{"Name", "Image(s)", "Selector", "Replicas"}
-var serviceColumns = []string{"Name", "Labels", "Selector", "Port"}
-var minionColumns = []string{"Minion identifier"}
+var podColumns = []string{"Name", "Image(s)", "Host", "Labels", "Age"}
+var replicationControllerColumns = []string{"Name", "Image(s)", "Selector", "Replicas (current/desired)", "Age"}
+var serviceColumns = []string{"Name", "Labels", "Selector", "Port", "Age"}
+var wideServiceColumns = []string{"Name", "Labels", "Selector", "Port", "Endpoints", "Age"}
+var endpointsColumns = []string{"Name", "Endpoints"}
+var minionColumns = []string{"Minion identifier", "Status", "Addresses"}
+var wideMinionColumns = []string{"Minion identifier", "Status", "Addresses", "Pods", "Requested CPU", "Requested Memory"}
 var statusColumns = []string{"Status"}
-var buildColumns = []string{"ID", "Status", "Pod ID"}
+var buildColumns = []string{"ID", "Status", "Pod ID", "Created", "Duration"}
+var wideBuildColumns = []string{"ID", "Status", "Pod ID", "Created", "Duration", "Parent ID", "Output Image"}
+var eventColumns = []string{"Time", "Object", "Reason", "Message"}
+var podSummaryStatusColumns = []string{"Status", "Pods"}
+var podSummaryHostColumns = []string{"Host", "Pods"}
 
 func (h *HumanReadablePrinter) unknown(data []byte, w io.Writer) error {
 	_, err := fmt.Fprintf(w, "Unknown object: %s", string(data))
 	return err
 }
 
+// listColumns returns the columns of the table of a list of objects with the given
+// columns, led by the namespace if h prints it.
+func (h *HumanReadablePrinter) listColumns(columnNames []string) []string {
+	if !h.Namespaces {
+		return columnNames
+	}
+	return append([]string{"Namespace"}, columnNames...)
+}
+
+// printNamespace starts the row of an object of a list with its namespace, if h prints it.
+func (h *HumanReadablePrinter) printNamespace(namespace string, w io.Writer) error {
+	if !h.Namespaces {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%s\t", h.cell(namespace))
+	return err
+}
+
 func (h *HumanReadablePrinter) printHeader(columnNames []string, w io.Writer) error {
+	if h.NoHeaders {
+		return nil
+	}
 	if _, err := fmt.Fprintf(w, "%s\n", strings.Join(columnNames, "\t")); err != nil {
 		return err
 	}
@@ -109,6 +286,48 @@ func (h *HumanReadablePrinter) printHeader(columnNames []string, w io.Writer) er
 	return err
 }
 
+// cell returns value as it should be printed in a column: truncated to the maximum width
+// unless h is Wide.
+func (h *HumanReadablePrinter) cell(value string) string {
+	max := h.MaxColumnWidth
+	if max == 0 {
+		max = DefaultMaxColumnWidth
+	}
+	if h.Wide || max < 0 {
+		return value
+	}
+	return truncate(value, max)
+}
+
+// truncate shortens value to at most max characters, ending it with "..." if anything had
+// to be cut.
+func truncate(value string, max int) string {
+	const ellipsis = "..."
+	runes := []rune(value)
+	if len(runes) <= max {
+		return value
+	}
+	if max <= len(ellipsis) {
+		return string(runes[:max])
+	}
+	return string(runes[:max-len(ellipsis)]) + ellipsis
+}
+
+// formatLabels renders a label set as key=value pairs sorted by key, so that the same set
+// always prints identically.
+func formatLabels(set map[string]string) string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+set[key])
+	}
+	return strings.Join(pairs, ",")
+}
+
 func (h *HumanReadablePrinter) makeImageList(manifest api.ContainerManifest) string {
 	var images []string
 	for _, container := range manifest.Containers {
@@ -118,13 +337,16 @@ func (h *HumanReadablePrinter) makeImageList(manifest api.ContainerManifest) str
 }
 
 func (h *HumanReadablePrinter) printPod(pod *api.Pod, w io.Writer) error {
-	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
-		pod.ID, h.makeImageList(pod.DesiredState.Manifest), pod.CurrentState.Host+"/"+pod.CurrentState.HostIP, labels.Set(pod.Labels))
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+		h.cell(pod.ID), h.cell(h.makeImageList(pod.DesiredState.Manifest)), h.cell(pod.CurrentState.Host+"/"+pod.CurrentState.HostIP), h.cell(formatLabels(pod.Labels)), objectAge(pod.CreationTimestamp, time.Now()))
 	return err
 }
 
 func (h *HumanReadablePrinter) printPodList(podList *api.PodList, w io.Writer) error {
 	for _, pod := range podList.Items {
+		if err := h.printNamespace(pod.Namespace, w); err != nil {
+			return err
+		}
 		if err := h.printPod(&pod, w); err != nil {
 			return err
 		}
@@ -133,12 +355,62 @@ func (h *HumanReadablePrinter) printPodList(podList *api.PodList, w io.Writer) e
 }
 
 func (h *HumanReadablePrinter) printBuild(build *buildapi.Build, w io.Writer) error {
-	_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", build.ID, build.Status, build.PodID)
+	duration := buildDuration(build, time.Now())
+	if h.Wide {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", build.ID, build.Status, build.PodID, build.CreationTimestamp, duration, build.ParentID, build.OutputImage)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", h.cell(build.ID), build.Status, h.cell(build.PodID), build.CreationTimestamp, duration)
 	return err
 }
 
+// buildDuration returns how long build ran, to the second: from its start until it finished,
+// or until now if it is still running. It is empty if the build hasn't started, or its
+// timestamps can't be read.
+func buildDuration(build *buildapi.Build, now time.Time) string {
+	start, err := time.Parse(time.UnixDate, build.StartTimestamp)
+	if err != nil {
+		return ""
+	}
+	end := now
+	if len(build.CompletionTimestamp) != 0 {
+		if end, err = time.Parse(time.UnixDate, build.CompletionTimestamp); err != nil {
+			return ""
+		}
+	}
+	duration := end.Sub(start)
+	if duration < 0 {
+		duration = 0
+	}
+	return (duration - duration%time.Second).String()
+}
+
+// objectAge returns how long before now an object created at timestamp, in RFC 3339 format,
+// was created, to the second, or "<unknown>" if the timestamp can't be read.
+func objectAge(timestamp string, now time.Time) string {
+	created, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return "<unknown>"
+	}
+	age := now.Sub(created)
+	if age < 0 {
+		age = 0
+	}
+	return (age - age%time.Second).String()
+}
+
+func (h *HumanReadablePrinter) buildColumns() []string {
+	if h.Wide {
+		return wideBuildColumns
+	}
+	return buildColumns
+}
+
 func (h *HumanReadablePrinter) printBuildList(buildList *buildapi.BuildList, w io.Writer) error {
 	for _, build := range buildList.Items {
+		if err := h.printNamespace(build.Namespace, w); err != nil {
+			return err
+		}
 		if err := h.printBuild(&build, w); err != nil {
 			return err
 		}
@@ -146,14 +418,30 @@ func (h *HumanReadablePrinter) printBuildList(buildList *buildapi.BuildList, w i
 	return nil
 }
 
+// printSummary prints the summary of list after its table, if h prints summaries.
+func (h *HumanReadablePrinter) printSummary(list interface{}, w io.Writer) error {
+	if !h.Summary || h.NoHeaders {
+		return nil
+	}
+	summary, ok := SummarizeList(list, h.Replicas)
+	if !ok {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "%s\n", summary)
+	return err
+}
+
 func (h *HumanReadablePrinter) printReplicationController(ctrl *api.ReplicationController, w io.Writer) error {
-	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d\n",
-		ctrl.ID, h.makeImageList(ctrl.DesiredState.PodTemplate.DesiredState.Manifest), labels.Set(ctrl.DesiredState.ReplicaSelector), ctrl.DesiredState.Replicas)
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%s\n",
+		h.cell(ctrl.ID), h.cell(h.makeImageList(ctrl.DesiredState.PodTemplate.DesiredState.Manifest)), h.cell(formatLabels(ctrl.DesiredState.ReplicaSelector)), ctrl.CurrentState.Replicas, ctrl.DesiredState.Replicas, objectAge(ctrl.CreationTimestamp, time.Now()))
 	return err
 }
 
 func (h *HumanReadablePrinter) printReplicationControllerList(list *api.ReplicationControllerList, w io.Writer) error {
 	for _, ctrl := range list.Items {
+		if err := h.printNamespace(ctrl.Namespace, w); err != nil {
+			return err
+		}
 		if err := h.printReplicationController(&ctrl, w); err != nil {
 			return err
 		}
@@ -162,12 +450,35 @@ func (h *HumanReadablePrinter) printReplicationControllerList(list *api.Replicat
 }
 
 func (h *HumanReadablePrinter) printService(svc *api.Service, w io.Writer) error {
-	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", svc.ID, labels.Set(svc.Labels), labels.Set(svc.Selector), svc.Port)
+	if h.Wide {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", svc.ID, formatLabels(svc.Labels), formatLabels(svc.Selector), svc.Port, h.endpointCount(svc), objectAge(svc.CreationTimestamp, time.Now()))
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", h.cell(svc.ID), h.cell(formatLabels(svc.Labels)), h.cell(formatLabels(svc.Selector)), svc.Port, objectAge(svc.CreationTimestamp, time.Now()))
 	return err
 }
 
+func (h *HumanReadablePrinter) serviceColumns() []string {
+	if h.Wide {
+		return wideServiceColumns
+	}
+	return serviceColumns
+}
+
+// endpointCount returns the number of endpoints of svc, or "<unknown>" if h wasn't given
+// the endpoints of services.
+func (h *HumanReadablePrinter) endpointCount(svc *api.Service) string {
+	if h.Endpoints == nil {
+		return "<unknown>"
+	}
+	return strconv.Itoa(len(h.Endpoints[api.QualifiedID(svc.Namespace, svc.ID)].Endpoints))
+}
+
 func (h *HumanReadablePrinter) printServiceList(list *api.ServiceList, w io.Writer) error {
 	for _, svc := range list.Items {
+		if err := h.printNamespace(svc.Namespace, w); err != nil {
+			return err
+		}
 		if err := h.printService(&svc, w); err != nil {
 			return err
 		}
@@ -175,11 +486,47 @@ func (h *HumanReadablePrinter) printServiceList(list *api.ServiceList, w io.Writ
 	return nil
 }
 
+func (h *HumanReadablePrinter) printEndpoints(endpoints *api.Endpoints, w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s\t%s\n", h.cell(endpoints.ID), h.cell(strings.Join(endpoints.Endpoints, ",")))
+	return err
+}
+
+func (h *HumanReadablePrinter) printEndpointsList(list *api.EndpointsList, w io.Writer) error {
+	for _, endpoints := range list.Items {
+		if err := h.printNamespace(endpoints.Namespace, w); err != nil {
+			return err
+		}
+		if err := h.printEndpoints(&endpoints, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *HumanReadablePrinter) printMinion(minion *api.Minion, w io.Writer) error {
-	_, err := fmt.Fprintf(w, "%s\n", minion.ID)
+	if h.Wide {
+		pods, cpu, memory := "<unknown>", "<unknown>", "<unknown>"
+		if usage := minion.Usage; usage != nil {
+			pods = strconv.Itoa(usage.Pods)
+			if usage.UnboundedPods > 0 {
+				pods += fmt.Sprintf(" (%d unbounded)", usage.UnboundedPods)
+			}
+			cpu, memory = strconv.Itoa(usage.CPU), strconv.Itoa(usage.Memory)
+		}
+		_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", minion.ID, minion.Status, strings.Join(minion.Addresses, ","), pods, cpu, memory)
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\n", h.cell(minion.ID), minion.Status, h.cell(strings.Join(minion.Addresses, ",")))
 	return err
 }
 
+func (h *HumanReadablePrinter) minionColumns() []string {
+	if h.Wide {
+		return wideMinionColumns
+	}
+	return minionColumns
+}
+
 func (h *HumanReadablePrinter) printMinionList(list *api.MinionList, w io.Writer) error {
 	for _, minion := range list.Items {
 		if err := h.printMinion(&minion, w); err != nil {
@@ -189,6 +536,65 @@ func (h *HumanReadablePrinter) printMinionList(list *api.MinionList, w io.Writer
 	return nil
 }
 
+func (h *HumanReadablePrinter) printEvent(event *api.Event, w io.Writer) error {
+	object := event.InvolvedObject.Kind + "/" + event.InvolvedObject.ID
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", event.Timestamp, h.cell(object), h.cell(event.Reason), h.cell(event.Message))
+	return err
+}
+
+func (h *HumanReadablePrinter) printEventList(list *api.EventList, w io.Writer) error {
+	for _, event := range list.Items {
+		if err := h.printNamespace(event.Namespace, w); err != nil {
+			return err
+		}
+		if err := h.printEvent(&event, w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPodSummary prints the pods of summary by status, then by host, the most frequent
+// first, and the total.
+func (h *HumanReadablePrinter) printPodSummary(summary *api.PodSummary, w io.Writer) error {
+	h.printHeader(podSummaryStatusColumns, w)
+	if err := printCounts(summary.ByStatus, w); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	h.printHeader(podSummaryHostColumns, w)
+	if err := printCounts(summary.ByHost, w); err != nil {
+		return err
+	}
+	if summary.Unassigned > 0 {
+		if _, err := fmt.Fprintf(w, "<unassigned>\t%d\n", summary.Unassigned); err != nil {
+			return err
+		}
+	}
+	if h.NoHeaders {
+		return nil
+	}
+	_, err := fmt.Fprintf(w, "\n%d pods\n", summary.Total)
+	return err
+}
+
+// printCounts prints a line for each key of counts and its count, the largest count first.
+func printCounts(counts map[string]int, w io.Writer) error {
+	keys := []string{}
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Sort(byCount{keys, counts})
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s\t%d\n", key, counts[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *HumanReadablePrinter) printStatus(status *api.Status, w io.Writer) error {
 	err := h.printHeader(statusColumns, w)
 	if err != nil {
@@ -225,55 +631,269 @@ func (h *HumanReadablePrinter) PrintObj(obj interface{}, output io.Writer) error
 		h.printHeader(podColumns, w)
 		return h.printPod(o, w)
 	case *api.PodList:
-		h.printHeader(podColumns, w)
-		return h.printPodList(o, w)
+		h.printHeader(h.listColumns(podColumns), w)
+		if err := h.printPodList(o, w); err != nil {
+			return err
+		}
+		return h.printSummary(o, w)
+	case *api.PodSummary:
+		return h.printPodSummary(o, w)
 	case *api.ReplicationController:
 		h.printHeader(replicationControllerColumns, w)
 		return h.printReplicationController(o, w)
 	case *api.ReplicationControllerList:
-		h.printHeader(replicationControllerColumns, w)
-		return h.printReplicationControllerList(o, w)
+		h.printHeader(h.listColumns(replicationControllerColumns), w)
+		if err := h.printReplicationControllerList(o, w); err != nil {
+			return err
+		}
+		return h.printSummary(o, w)
 	case *api.Service:
-		h.printHeader(serviceColumns, w)
+		h.printHeader(h.serviceColumns(), w)
 		return h.printService(o, w)
 	case *api.ServiceList:
-		h.printHeader(serviceColumns, w)
+		h.printHeader(h.listColumns(h.serviceColumns()), w)
 		return h.printServiceList(o, w)
+	case *api.Endpoints:
+		h.printHeader(endpointsColumns, w)
+		return h.printEndpoints(o, w)
+	case *api.EndpointsList:
+		h.printHeader(h.listColumns(endpointsColumns), w)
+		return h.printEndpointsList(o, w)
 	case *api.Minion:
-		h.printHeader(minionColumns, w)
+		h.printHeader(h.minionColumns(), w)
 		return h.printMinion(o, w)
 	case *api.MinionList:
-		h.printHeader(minionColumns, w)
+		h.printHeader(h.minionColumns(), w)
 		return h.printMinionList(o, w)
+	case *api.Event:
+		h.printHeader(eventColumns, w)
+		return h.printEvent(o, w)
+	case *api.EventList:
+		h.printHeader(h.listColumns(eventColumns), w)
+		return h.printEventList(o, w)
 	case *api.Status:
 		return h.printStatus(o, w)
 	case *buildapi.Build:
-		h.printHeader(buildColumns, w)
+		h.printHeader(h.buildColumns(), w)
 		return h.printBuild(o, w)
 	case *buildapi.BuildList:
-		h.printHeader(buildColumns, w)
-		return h.printBuildList(o, w)
+		h.printHeader(h.listColumns(h.buildColumns()), w)
+		if err := h.printBuildList(o, w); err != nil {
+			return err
+		}
+		return h.printSummary(o, w)
 	default:
 		_, err := fmt.Fprintf(w, "Error: unknown type %#v", obj)
 		return err
 	}
 }
 
+// listKindColumns returns the columns of the table of lists of kind.
+func (h *HumanReadablePrinter) listKindColumns(kind string) ([]string, bool) {
+	switch kind {
+	case "PodList":
+		return h.listColumns(podColumns), true
+	case "ReplicationControllerList":
+		return h.listColumns(replicationControllerColumns), true
+	case "ServiceList":
+		return h.listColumns(h.serviceColumns()), true
+	case "EndpointsList":
+		return h.listColumns(endpointsColumns), true
+	case "MinionList":
+		return h.minionColumns(), true
+	case "EventList":
+		return h.listColumns(eventColumns), true
+	case "BuildList":
+		return h.listColumns(h.buildColumns()), true
+	}
+	return nil, false
+}
+
+// PrintListStart prints the header of the table of a list of kind, whose rows PrintItem
+// prints.
+func (h *HumanReadablePrinter) PrintListStart(kind string, w io.Writer) error {
+	columns, ok := h.listKindColumns(kind)
+	if !ok {
+		return fmt.Errorf("unknown list kind %q", kind)
+	}
+	h.stream = &humanListStream{kind: kind, w: tabwriter.NewWriter(w, 20, 5, 3, ' ', 0)}
+	if h.Summary && !h.NoHeaders {
+		list, err := listOfKind(kind, nil)
+		if err != nil {
+			return err
+		}
+		h.stream.summary, h.stream.summarized = SummarizeList(list, h.Replicas)
+	}
+	return h.printHeader(columns, h.stream.w)
+}
+
+// PrintItem prints the row of obj, the next item of the list, and the rows before it once a
+// batch of listStreamRows rows is complete.
+func (h *HumanReadablePrinter) PrintItem(obj interface{}, w io.Writer) error {
+	s := h.stream
+	if s == nil {
+		return fmt.Errorf("PrintItem called before PrintListStart")
+	}
+	list, err := listOfKind(s.kind, []interface{}{obj})
+	if err != nil {
+		return err
+	}
+	switch o := list.(type) {
+	case *api.PodList:
+		err = h.printPodList(o, s.w)
+	case *api.ReplicationControllerList:
+		err = h.printReplicationControllerList(o, s.w)
+	case *api.ServiceList:
+		err = h.printServiceList(o, s.w)
+	case *api.EndpointsList:
+		err = h.printEndpointsList(o, s.w)
+	case *api.MinionList:
+		err = h.printMinionList(o, s.w)
+	case *api.EventList:
+		err = h.printEventList(o, s.w)
+	case *buildapi.BuildList:
+		err = h.printBuildList(o, s.w)
+	}
+	if err != nil {
+		return err
+	}
+	if s.summarized {
+		summary, _ := SummarizeList(list, h.Replicas)
+		s.summary.merge(summary)
+	}
+	if s.rows++; s.rows%listStreamRows == 0 {
+		return s.w.Flush()
+	}
+	return nil
+}
+
+// PrintListEnd prints the rows left and the summary of the list.
+func (h *HumanReadablePrinter) PrintListEnd(w io.Writer) error {
+	s := h.stream
+	if s == nil {
+		return fmt.Errorf("PrintListEnd called before PrintListStart")
+	}
+	h.stream = nil
+	if s.summarized {
+		if _, err := fmt.Fprintf(s.w, "%s\n", s.summary); err != nil {
+			return err
+		}
+	}
+	return s.w.Flush()
+}
+
 // TemplatePrinter is an implementation of ResourcePrinter which formats data with a Go Template.
 type TemplatePrinter struct {
 	Template *template.Template
+	// Raw executes the template with a TemplateData instead of the object, so that it can
+	// reach fields the API types don't have.
+	Raw bool
+}
+
+// TemplateData is what a TemplatePrinter with Raw set executes its template with.
+type TemplateData struct {
+	// Object is the decoded object, e.g. {{.Object.ID}}.
+	Object interface{}
+	// Raw is the JSON of the object as a generic map, e.g. {{.Raw.annotations}}.
+	Raw map[string]interface{}
 }
 
-// Print parses the data as JSON, and re-formats it with the Go Template.
+// Print parses the data as JSON, and re-formats it with the Go Template. With Raw set, the
+// fields of data are available to the template as they were received.
 func (t *TemplatePrinter) Print(data []byte, w io.Writer) error {
 	obj, err := api.Decode(data)
 	if err != nil {
 		return err
 	}
-	return t.PrintObj(obj, w)
+	if !t.Raw {
+		return t.Template.Execute(w, obj)
+	}
+	return t.executeRaw(obj, data, w)
 }
 
-// PrintObj formats the obj with the Go Template.
+// PrintObj formats the obj with the Go Template. With Raw set, the fields of obj are
+// available to the template as they are encoded.
 func (t *TemplatePrinter) PrintObj(obj interface{}, w io.Writer) error {
-	return t.Template.Execute(w, obj)
+	if !t.Raw {
+		return t.Template.Execute(w, obj)
+	}
+	data, err := api.Encode(obj)
+	if err != nil {
+		return err
+	}
+	return t.executeRaw(obj, data, w)
+}
+
+// executeRaw executes the template with obj and data, the JSON obj was decoded from.
+func (t *TemplatePrinter) executeRaw(obj interface{}, data []byte, w io.Writer) error {
+	raw, err := decodeRaw(data)
+	if err != nil {
+		return err
+	}
+	return t.Template.Execute(w, TemplateData{Object: obj, Raw: raw})
+}
+
+// decodeRaw decodes the JSON of an object into a generic map, keeping the fields as they
+// were received.
+func decodeRaw(data []byte) (map[string]interface{}, error) {
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// TeeSink is a ResourcePrinter bound to the writer it prints to.
+type TeeSink struct {
+	Printer ResourcePrinter
+	// Out is the writer the sink prints to, or nil for the one the TeePrinter is given.
+	Out io.Writer
+	// Optional sinks don't fail the print of a TeePrinter when they fail, their errors are
+	// passed to its OnError instead.
+	Optional bool
+}
+
+// TeePrinter is an implementation of ResourcePrinter which prints each object with every one
+// of its sinks, so that an object can be shown as a table and archived as JSON at once. Every
+// sink prints, even after one fails; the first error of a sink that isn't optional is
+// returned.
+type TeePrinter struct {
+	Sinks []TeeSink
+	// OnError, if set, is called with the errors of optional sinks.
+	OnError func(sink TeeSink, err error)
+}
+
+// Print prints data with each sink.
+func (t *TeePrinter) Print(data []byte, w io.Writer) error {
+	return t.each(w, func(printer ResourcePrinter, out io.Writer) error {
+		return printer.Print(data, out)
+	})
+}
+
+// PrintObj prints obj with each sink.
+func (t *TeePrinter) PrintObj(obj interface{}, w io.Writer) error {
+	return t.each(w, func(printer ResourcePrinter, out io.Writer) error {
+		return printer.PrintObj(obj, out)
+	})
+}
+
+func (t *TeePrinter) each(w io.Writer, print func(ResourcePrinter, io.Writer) error) error {
+	var result error
+	for _, sink := range t.Sinks {
+		out := sink.Out
+		if out == nil {
+			out = w
+		}
+		err := print(sink.Printer, out)
+		switch {
+		case err == nil:
+		case sink.Optional:
+			if t.OnError != nil {
+				t.OnError(sink, err)
+			}
+		case result == nil:
+			result = err
+		}
+	}
+	return result
 }
@@ -19,8 +19,12 @@ package kubecfg
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"gopkg.in/v1/yaml"
@@ -79,6 +83,36 @@ func TestYAMLPrinterPrint(t *testing.T) {
 	}
 }
 
+func TestJSONPrinter(t *testing.T) {
+	printer := &JSONPrinter{}
+	buff := bytes.NewBuffer([]byte{})
+	if err := printer.Print([]byte(`{"kind":"Pod","id":"foo","labels":{"name":"foo"}}`), buff); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "{\n  \"kind\": \"Pod\",\n  \"id\": \"foo\",\n  \"labels\": {\n    \"name\": \"foo\"\n  }\n}\n"
+	if buff.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buff.String())
+	}
+
+	buff.Reset()
+	if err := printer.Print([]byte("not json"), buff); err != nil || buff.String() != "not json" {
+		t.Errorf("expected data that isn't JSON as it is, got %q: %v", buff.String(), err)
+	}
+
+	obj := api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
+	buff.Reset()
+	if err := printer.PrintObj(obj, buff); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buff.String(), "\n  \"id\": \"foo\"") {
+		t.Errorf("expected indented JSON, got %q", buff.String())
+	}
+	objOut, err := api.Decode(buff.Bytes())
+	if err != nil || !reflect.DeepEqual(&obj, objOut) {
+		t.Errorf("expected %#v, got %#v: %v", obj, objOut, err)
+	}
+}
+
 func TestIdentityPrinter(t *testing.T) {
 	printer := &IdentityPrinter{}
 	buff := bytes.NewBuffer([]byte{})
@@ -101,3 +135,473 @@ func TestIdentityPrinter(t *testing.T) {
 		t.Errorf("Unexpected inequality: %#v vs %#v", obj, objOut)
 	}
 }
+
+func TestHumanReadablePrinterEvents(t *testing.T) {
+	printer := &HumanReadablePrinter{}
+	buf := bytes.NewBuffer([]byte{})
+	list := &api.EventList{
+		Items: []api.Event{
+			{
+				InvolvedObject: api.ObjectReference{Kind: "pod", ID: "foo"},
+				Reason:         "failedCreate",
+				Message:        "no room",
+				Timestamp:      "2014-07-01T12:00:00Z",
+			},
+		},
+	}
+	if err := printer.PrintObj(list, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and one event, got %q", buf.String())
+	}
+	for _, column := range []string{"Time", "Object", "Reason", "Message"} {
+		if !strings.Contains(lines[0], column) {
+			t.Errorf("expected column %s in %q", column, lines[0])
+		}
+	}
+	for _, value := range []string{"2014-07-01T12:00:00Z", "pod/foo", "failedCreate", "no room"} {
+		if !strings.Contains(lines[2], value) {
+			t.Errorf("expected %s in %q", value, lines[2])
+		}
+	}
+}
+
+func TestHumanReadablePrinterTruncatesLongCells(t *testing.T) {
+	image := strings.Repeat("i", 500)
+	pod := &api.Pod{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Labels:   map[string]string{"name": "foo"},
+		DesiredState: api.PodState{
+			Manifest: api.ContainerManifest{Containers: []api.Container{{Image: image}, {Image: image}}},
+		},
+	}
+	printPod := func(printer *HumanReadablePrinter) []string {
+		buf := bytes.NewBuffer([]byte{})
+		if err := printer.PrintObj(pod, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+
+	lines := printPod(&HumanReadablePrinter{})
+	truncated := strings.Repeat("i", DefaultMaxColumnWidth-3) + "..."
+	if fields := strings.Fields(lines[2]); len(fields) != 5 || fields[1] != truncated {
+		t.Errorf("expected the images to be truncated to %d characters, got %q", DefaultMaxColumnWidth, lines[2])
+	}
+	if len(lines[2]) > 5*(DefaultMaxColumnWidth+3) {
+		t.Errorf("expected a line of bounded width, got %d characters", len(lines[2]))
+	}
+
+	lines = printPod(&HumanReadablePrinter{MaxColumnWidth: 10})
+	if fields := strings.Fields(lines[2]); fields[1] != "iiiiiii..." {
+		t.Errorf("expected the images to be truncated to 10 characters, got %q", lines[2])
+	}
+
+	for _, printer := range []*HumanReadablePrinter{{Wide: true}, {MaxColumnWidth: -1}} {
+		lines = printPod(printer)
+		if fields := strings.Fields(lines[2]); fields[1] != image+","+image {
+			t.Errorf("%#v: expected the images in full, got %q", printer, lines[2])
+		}
+	}
+}
+
+func TestHumanReadablePrinterSortsLabels(t *testing.T) {
+	service := &api.Service{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Labels:   map[string]string{"b": "1", "a-b": "2", "a": "3", "c": "4"},
+	}
+	for i := 0; i < 10; i++ {
+		buf := bytes.NewBuffer([]byte{})
+		if err := (&HumanReadablePrinter{}).PrintObj(service, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if fields := strings.Fields(lines[2]); fields[1] != "a=3,a-b=2,b=1,c=4" {
+			t.Fatalf("expected labels sorted by key, got %q", lines[2])
+		}
+	}
+}
+
+func TestHumanReadablePrinterMinionColumns(t *testing.T) {
+	list := &api.MinionList{
+		Items: []api.Minion{
+			{JSONBase: api.JSONBase{ID: "minion-1"}, Status: api.MinionReachable, Addresses: []string{"10.0.0.1", "192.168.0.1"}},
+			{JSONBase: api.JSONBase{ID: "minion-2"}, Status: api.MinionUnreachable},
+		},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	if err := (&HumanReadablePrinter{}).PrintObj(list, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	expected := [][]string{
+		{"Minion", "identifier", "Status", "Addresses"},
+		nil, // the separator under the header
+		{"minion-1", "Reachable", "10.0.0.1,192.168.0.1"},
+		{"minion-2", "Unreachable"},
+	}
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	for i, line := range lines {
+		if expected[i] == nil {
+			continue
+		}
+		if fields := strings.Fields(line); !reflect.DeepEqual(fields, expected[i]) {
+			t.Errorf("expected line %d to be %v, got %q", i, expected[i], line)
+		}
+	}
+}
+
+func TestHumanReadablePrinterControllerReplicas(t *testing.T) {
+	ctrl := &api.ReplicationController{
+		JSONBase:     api.JSONBase{ID: "web"},
+		DesiredState: api.ReplicationControllerState{Replicas: 5},
+		CurrentState: api.ReplicationControllerState{Replicas: 3},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	if err := (&HumanReadablePrinter{}).PrintObj(ctrl, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "Replicas (current/desired)") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	if fields := strings.Fields(lines[2]); fields[len(fields)-2] != "3/5" {
+		t.Errorf("expected the current and desired replicas, got %q", lines[2])
+	}
+}
+
+func TestHumanReadablePrinterMinionUsage(t *testing.T) {
+	minions := &api.MinionList{
+		Items: []api.Minion{
+			{JSONBase: api.JSONBase{ID: "minion-1"}, Usage: &api.MinionUsage{Pods: 3, Memory: 1024, CPU: 500, UnboundedPods: 1}},
+			{JSONBase: api.JSONBase{ID: "minion-2"}},
+		},
+	}
+	buf := bytes.NewBuffer([]byte{})
+	if err := (&HumanReadablePrinter{Wide: true}).PrintObj(minions, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[0], "Requested CPU") {
+		t.Fatalf("unexpected output:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[2], "3 (1 unbounded)") || !strings.Contains(lines[2], "500") || !strings.Contains(lines[2], "1024") {
+		t.Errorf("expected the usage of the minion, got %q", lines[2])
+	}
+	if strings.Count(lines[3], "<unknown>") != 3 {
+		t.Errorf("expected an unknown usage, got %q", lines[3])
+	}
+
+	buf.Reset()
+	if err := (&HumanReadablePrinter{}).PrintObj(minions, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "Pods") {
+		t.Errorf("expected the usage only in wide output, got\n%s", buf.String())
+	}
+}
+
+func TestHumanReadablePrinterServiceEndpoints(t *testing.T) {
+	services := &api.ServiceList{
+		Items: []api.Service{
+			{JSONBase: api.JSONBase{ID: "frontend", Namespace: "default"}, Port: 80},
+			{JSONBase: api.JSONBase{ID: "frontend", Namespace: "other"}, Port: 80},
+		},
+	}
+	endpoints := &api.EndpointsList{
+		Items: []api.Endpoints{
+			{JSONBase: api.JSONBase{ID: "frontend", Namespace: "default"}, Endpoints: []string{"10.0.0.1:80", "10.0.0.2:80"}},
+		},
+	}
+	table := []struct {
+		printer *HumanReadablePrinter
+		counts  []string
+	}{
+		{&HumanReadablePrinter{Wide: true, Endpoints: EndpointsByService(endpoints)}, []string{"2", "0"}},
+		{&HumanReadablePrinter{Wide: true}, []string{"<unknown>", "<unknown>"}},
+	}
+	for _, item := range table {
+		buf := bytes.NewBuffer([]byte{})
+		if err := item.printer.PrintObj(services, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if header := strings.Fields(lines[0]); header[len(header)-2] != "Endpoints" {
+			t.Errorf("expected an Endpoints column, got %q", lines[0])
+		}
+		for i, count := range item.counts {
+			if fields := strings.Fields(lines[i+2]); fields[len(fields)-2] != count {
+				t.Errorf("expected %s endpoints, got %q", count, lines[i+2])
+			}
+		}
+	}
+
+	buf := bytes.NewBuffer([]byte{})
+	if err := (&HumanReadablePrinter{}).PrintObj(endpoints, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "10.0.0.1:80,10.0.0.2:80") {
+		t.Errorf("expected the endpoints to be listed, got:\n%s", buf.String())
+	}
+}
+
+func TestHumanReadablePrinterNamespaceColumn(t *testing.T) {
+	pods := &api.PodList{Items: []api.Pod{
+		{JSONBase: api.JSONBase{ID: "web", Namespace: "default"}, CurrentState: api.PodState{Host: "minion-1"}},
+		{JSONBase: api.JSONBase{ID: "web", Namespace: "team1"}, CurrentState: api.PodState{Host: "minion-2"}},
+	}}
+	table := []struct {
+		printer  *HumanReadablePrinter
+		obj      interface{}
+		expected [][]string
+	}{
+		{&HumanReadablePrinter{}, pods, [][]string{
+			{"Name", "Image(s)", "Host", "Labels", "Age"},
+			nil,
+			{"web", "minion-1/", "<unknown>"},
+			{"web", "minion-2/", "<unknown>"},
+		}},
+		{&HumanReadablePrinter{Namespaces: true}, pods, [][]string{
+			{"Namespace", "Name", "Image(s)", "Host", "Labels", "Age"},
+			nil,
+			{"default", "web", "minion-1/", "<unknown>"},
+			{"team1", "web", "minion-2/", "<unknown>"},
+		}},
+		// Single objects have no namespace column.
+		{&HumanReadablePrinter{Namespaces: true}, &pods.Items[1], [][]string{
+			{"Name", "Image(s)", "Host", "Labels", "Age"},
+			nil,
+			{"web", "minion-2/", "<unknown>"},
+		}},
+	}
+	for i, item := range table {
+		buf := bytes.NewBuffer([]byte{})
+		if err := item.printer.PrintObj(item.obj, buf); err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != len(item.expected) {
+			t.Fatalf("%d: unexpected output:\n%s", i, buf.String())
+		}
+		for j, line := range lines {
+			if item.expected[j] == nil {
+				continue
+			}
+			if fields := strings.Fields(line); !reflect.DeepEqual(fields, item.expected[j]) {
+				t.Errorf("%d: expected line %d to be %v, got %q", i, j, item.expected[j], line)
+			}
+		}
+	}
+}
+
+func TestObjectAge(t *testing.T) {
+	now := time.Date(2014, 7, 1, 12, 0, 0, 0, time.UTC)
+	table := []struct {
+		timestamp string
+		expected  string
+	}{
+		{"2014-07-01T11:58:30Z", "1m30s"},
+		{"2014-06-30T12:00:00Z", "24h0m0s"},
+		{"2014-07-01T12:00:05Z", "0s"},
+		{"Tue Jul  1 11:00:00 UTC 2014", "<unknown>"},
+		{"", "<unknown>"},
+	}
+	for _, item := range table {
+		if age := objectAge(item.timestamp, now); age != item.expected {
+			t.Errorf("%q: expected %q, got %q", item.timestamp, item.expected, age)
+		}
+	}
+
+	pod := &api.Pod{JSONBase: api.JSONBase{ID: "web", CreationTimestamp: time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)}}
+	buf := bytes.NewBuffer([]byte{})
+	if err := (&HumanReadablePrinter{}).PrintObj(pod, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if fields := strings.Fields(lines[2]); !strings.HasPrefix(fields[len(fields)-1], "1h0m") {
+		t.Errorf("expected the pod to be an hour old, got %q", lines[2])
+	}
+}
+
+func TestHumanReadablePrinterSummary(t *testing.T) {
+	pods := &api.PodList{Items: []api.Pod{
+		{JSONBase: api.JSONBase{ID: "a"}, CurrentState: api.PodState{Status: api.PodRunning}},
+		{JSONBase: api.JSONBase{ID: "b"}, CurrentState: api.PodState{Status: api.PodWaiting}},
+		{JSONBase: api.JSONBase{ID: "c"}, CurrentState: api.PodState{Status: api.PodRunning}},
+	}}
+	print := func(printer *HumanReadablePrinter, obj interface{}) []string {
+		buf := &bytes.Buffer{}
+		if err := printer.PrintObj(obj, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+
+	lines := print(&HumanReadablePrinter{Summary: true}, pods)
+	if len(lines) != 6 || lines[5] != "3 pods: 2 running, 1 waiting" {
+		t.Errorf("expected the summary after the table, got %q", lines)
+	}
+	if lines := print(&HumanReadablePrinter{}, pods); len(lines) != 5 {
+		t.Errorf("expected no summary unless asked for, got %q", lines)
+	}
+	if lines := print(&HumanReadablePrinter{Summary: true, NoHeaders: true}, pods); len(lines) != 3 {
+		t.Errorf("expected only the rows without headers, got %q", lines)
+	}
+	if lines := print(&HumanReadablePrinter{Summary: true}, &pods.Items[0]); len(lines) != 3 {
+		t.Errorf("expected no summary for a single object, got %q", lines)
+	}
+
+	controllers := &api.ReplicationControllerList{Items: []api.ReplicationController{
+		{JSONBase: api.JSONBase{ID: "web"}, DesiredState: api.ReplicationControllerState{Replicas: 3}},
+		{JSONBase: api.JSONBase{ID: "db"}, DesiredState: api.ReplicationControllerState{Replicas: 2}},
+	}}
+	lines = print(&HumanReadablePrinter{Summary: true}, controllers)
+	if last := lines[len(lines)-1]; last != "2 replicationControllers: 5 replicas desired" {
+		t.Errorf("unexpected summary %q", last)
+	}
+	lines = print(&HumanReadablePrinter{Summary: true, Replicas: map[string]int{"web": 3, "db": 1}}, controllers)
+	if last := lines[len(lines)-1]; last != "2 replicationControllers: 1 at size, 1 resizing; 4 of 5 replicas" {
+		t.Errorf("unexpected summary %q", last)
+	}
+}
+
+func TestHumanReadablePrinterPodSummary(t *testing.T) {
+	summary := &api.PodSummary{
+		Total:      6,
+		ByStatus:   map[string]int{"Running": 4, "Waiting": 1, "Terminated": 1},
+		ByHost:     map[string]int{"foo": 2, "bar": 3},
+		Unassigned: 1,
+	}
+	buf := &bytes.Buffer{}
+	if err := (&HumanReadablePrinter{}).PrintObj(summary, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{
+		"Status Pods",
+		"---------- ----------",
+		"Running 4",
+		"Terminated 1",
+		"Waiting 1",
+		"",
+		"Host Pods",
+		"---------- ----------",
+		"bar 3",
+		"foo 2",
+		"<unassigned> 1",
+		"",
+		"6 pods",
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i := range lines {
+		lines[i] = strings.Join(strings.Fields(lines[i]), " ")
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+
+	buf.Reset()
+	if err := (&HumanReadablePrinter{NoHeaders: true}).PrintObj(summary, buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(buf.String(), "Status") || strings.Contains(buf.String(), "6 pods") {
+		t.Errorf("expected only the counts without headers, got %q", buf.String())
+	}
+}
+
+func TestTemplatePrinterRaw(t *testing.T) {
+	data := []byte(`{"kind": "Pod", "apiVersion": "v1beta1", "id": "foo", "annotations": {"team": "web"}}`)
+	execute := func(printer *TemplatePrinter, print func(*TemplatePrinter, *bytes.Buffer) error) string {
+		buf := &bytes.Buffer{}
+		if err := print(printer, buf); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return buf.String()
+	}
+	fromData := func(printer *TemplatePrinter, buf *bytes.Buffer) error { return printer.Print(data, buf) }
+
+	printer := &TemplatePrinter{Template: template.Must(template.New("output").Parse("{{.ID}}"))}
+	if output := execute(printer, fromData); output != "foo" {
+		t.Errorf("expected templates to reach the object by default, got %q", output)
+	}
+
+	printer = &TemplatePrinter{Template: template.Must(template.New("output").Parse("{{.Object.ID}} {{.Raw.annotations.team}}")), Raw: true}
+	if output := execute(printer, fromData); output != "foo web" {
+		t.Errorf("expected a field only in the JSON to be reachable, got %q", output)
+	}
+
+	printer = &TemplatePrinter{Template: template.Must(template.New("output").Parse("{{.Object.ID}} {{.Raw.apiVersion}}")), Raw: true}
+	fromObj := func(printer *TemplatePrinter, buf *bytes.Buffer) error {
+		return printer.PrintObj(&api.Pod{JSONBase: api.JSONBase{ID: "bar"}}, buf)
+	}
+	if output := execute(printer, fromObj); output != "bar v1beta1" {
+		t.Errorf("expected the encoded object to be reachable, got %q", output)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestTeePrinter(t *testing.T) {
+	terminal := &bytes.Buffer{}
+	file := &bytes.Buffer{}
+	printer := &TeePrinter{Sinks: []TeeSink{
+		{Printer: &HumanReadablePrinter{}},
+		{Printer: &JSONPrinter{}, Out: file},
+	}}
+	pod := &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
+	if err := printer.PrintObj(pod, terminal); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(terminal.String(), "Name") || strings.Contains(terminal.String(), "{") {
+		t.Errorf("expected only the table on the terminal, got %q", terminal.String())
+	}
+	obj, err := api.Decode(file.Bytes())
+	if err != nil || !reflect.DeepEqual(pod, obj) {
+		t.Errorf("expected only the JSON of %#v in the file, got %q: %v", pod, file.String(), err)
+	}
+
+	terminal.Reset()
+	file.Reset()
+	data := []byte(`{"kind":"Pod","apiVersion":"v1beta1","id":"bar"}`)
+	if err := printer.Print(data, terminal); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(terminal.String(), "bar") || file.String() != "{\n  \"kind\": \"Pod\",\n  \"apiVersion\": \"v1beta1\",\n  \"id\": \"bar\"\n}\n" {
+		t.Errorf("unexpected output %q and %q", terminal.String(), file.String())
+	}
+}
+
+func TestTeePrinterErrors(t *testing.T) {
+	terminal := &bytes.Buffer{}
+	failures := []error{}
+	printer := &TeePrinter{
+		Sinks: []TeeSink{
+			{Printer: &JSONPrinter{}, Out: failingWriter{}, Optional: true},
+			{Printer: &IdentityPrinter{}},
+		},
+		OnError: func(sink TeeSink, err error) { failures = append(failures, err) },
+	}
+	if err := printer.Print([]byte("data"), terminal); err != nil {
+		t.Errorf("expected the optional sink not to fail the print, got %v", err)
+	}
+	if terminal.String() != "data" || len(failures) != 1 {
+		t.Errorf("expected the data on the terminal and a failure, got %q and %v", terminal.String(), failures)
+	}
+
+	terminal.Reset()
+	printer.Sinks[0].Optional = false
+	if err := printer.Print([]byte("data"), terminal); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected the error of the sink, got %v", err)
+	}
+	if terminal.String() != "data" {
+		t.Errorf("expected the other sinks to print after a failure, got %q", terminal.String())
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// SelectorSyntaxError is returned by ValidateSelector for a selector it can't parse. Offset
+// is the index of the offending character in Selector.
+type SelectorSyntaxError struct {
+	Selector string
+	Offset   int
+	Reason   string
+}
+
+// Error names the offending character and points at it under the selector.
+func (e *SelectorSyntaxError) Error() string {
+	return fmt.Sprintf("invalid selector %q: %s at character %d\n  %s\n  %s^", e.Selector, e.Reason, e.Offset+1, e.Selector, strings.Repeat(" ", e.Offset))
+}
+
+// ValidateSelector returns a *SelectorSyntaxError if selector, a comma separated list of
+// <key>=<value>, <key>==<value> and <key>!=<value> requirements, is malformed. It is stricter
+// than labels.ParseSelector, which takes spaces and stray operators as part of keys and values,
+// so that typos are caught before they are sent to the server.
+func ValidateSelector(selector string) error {
+	start := 0
+	for _, part := range strings.Split(selector, ",") {
+		if err := validateRequirement(selector, part, start); err != nil {
+			return err
+		}
+		start += len(part) + 1
+	}
+	return nil
+}
+
+// validateRequirement validates part, the requirement of selector starting at start.
+func validateRequirement(selector, part string, start int) error {
+	if len(part) == 0 {
+		return nil
+	}
+	fail := func(offset int, reason string) error {
+		return &SelectorSyntaxError{Selector: selector, Offset: start + offset, Reason: reason}
+	}
+	op := strings.IndexAny(part, "!=")
+	if op == -1 {
+		return fail(len(part), "expected =, == or !=")
+	}
+	if i := strings.IndexAny(part[:op], " \t"); i != -1 {
+		return fail(i, "unexpected space in key")
+	}
+	if op == 0 {
+		return fail(op, "expected a key")
+	}
+	value := op + 1
+	switch {
+	case strings.HasPrefix(part[op:], "!="), strings.HasPrefix(part[op:], "=="):
+		value = op + 2
+	case part[op] == '!':
+		return fail(op, "expected !=")
+	}
+	if i := strings.IndexAny(part[value:], "!="); i != -1 {
+		return fail(value+i, fmt.Sprintf("unexpected %c in value", part[value+i]))
+	}
+	return nil
+}
+
+// objectFields presents the fields of an object, decoded from its JSON into a generic map,
+// as labels, so that field selectors can match them. The keys of selectors are field paths,
+// e.g. currentState.status.
+type objectFields map[string]interface{}
+
+func (f objectFields) Get(key string) string {
+	value, _ := FieldValue(f, strings.Split(key, "."))
+	return value
+}
+
+// FilterFields returns a copy of 'list', which must be a pointer to an api list type with an
+// Items field, such as *api.PodList, holding only the items whose fields match 'field'. It
+// does on the client what servers that predate field selectors can't, matching the keys of
+// 'field' as field paths of the JSON of each item.
+func FilterFields(list interface{}, field labels.Selector) (interface{}, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a list, but got %#v", list)
+	}
+	filtered := reflect.New(v.Elem().Type())
+	filtered.Elem().Set(v.Elem())
+	items := filtered.Elem().FieldByName("Items")
+	if !items.IsValid() || items.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("expected a list, but got %#v", list)
+	}
+	matching := reflect.MakeSlice(items.Type(), 0, items.Len())
+	for i := 0; i < items.Len(); i++ {
+		data, err := json.Marshal(items.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+		fields := objectFields{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		if field.Matches(fields) {
+			matching = reflect.Append(matching, items.Index(i))
+		}
+	}
+	items.Set(matching)
+	return filtered.Interface(), nil
+}
+
+// SupportsFieldSelectors returns whether the server with 'config' filters lists of 'resource'
+// by fields. known is false if config doesn't say, as for servers that predate field
+// selectors or don't serve their config.
+func SupportsFieldSelectors(config *apiserver.ServerConfig, resource string) (supported, known bool) {
+	if config == nil || config.FieldSelectors == nil {
+		return false, false
+	}
+	for _, r := range config.FieldSelectors {
+		if r == resource {
+			return true, true
+		}
+	}
+	return false, true
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+func TestValidateSelector(t *testing.T) {
+	for _, selector := range []string{"", "status=failed", "a==b,c!=d", "involvedObject.id=foo,", "a=", "source=kubelet on host"} {
+		if err := ValidateSelector(selector); err != nil {
+			t.Errorf("%q: unexpected error: %v", selector, err)
+		}
+	}
+
+	table := map[string]int{
+		"status":             6,
+		"status=failed,reas": 18,
+		"=failed":            0,
+		"stat us=failed":     4,
+		"a=b=c":              3,
+		"a==b,c!d":           6,
+		"a===b":              3,
+		"a=b,c=!d":           6,
+	}
+	for selector, offset := range table {
+		err := ValidateSelector(selector)
+		syntaxErr, ok := err.(*SelectorSyntaxError)
+		if !ok {
+			t.Errorf("%q: expected a syntax error, got %v", selector, err)
+			continue
+		}
+		if syntaxErr.Offset != offset {
+			t.Errorf("%q: expected the error at %d, got %d", selector, offset, syntaxErr.Offset)
+		}
+	}
+
+	message := ValidateSelector("status=failed,reas").Error()
+	if !strings.HasSuffix(message, "\n  status=failed,reas\n                    ^") {
+		t.Errorf("expected the offending character to be pointed at, got %q", message)
+	}
+}
+
+func TestFilterFields(t *testing.T) {
+	list := &api.PodList{Items: []api.Pod{
+		{JSONBase: api.JSONBase{ID: "a"}, CurrentState: api.PodState{Status: api.PodRunning, Host: "m1"}},
+		{JSONBase: api.JSONBase{ID: "b"}, CurrentState: api.PodState{Status: api.PodWaiting, Host: "m1"}},
+		{JSONBase: api.JSONBase{ID: "c"}, CurrentState: api.PodState{Status: api.PodRunning, Host: "m2"}},
+	}}
+	table := map[string][]string{
+		"":                            {"a", "b", "c"},
+		"currentState.status=Running": {"a", "c"},
+		"currentState.status=Running,currentState.host!=m2": {"a"},
+		"currentState.missing=x":                            {},
+	}
+	for selector, expected := range table {
+		field, err := labels.ParseSelector(selector)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", selector, err)
+		}
+		filtered, err := FilterFields(list, field)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", selector, err)
+		}
+		ids, _ := ItemIDs(filtered)
+		if !reflect.DeepEqual(ids, expected) {
+			t.Errorf("%q: expected %v, got %v", selector, expected, ids)
+		}
+	}
+	if len(list.Items) != 3 {
+		t.Errorf("expected the list to be left alone, got %#v", list)
+	}
+
+	if _, err := FilterFields(api.PodList{}, labels.Everything()); err == nil {
+		t.Errorf("expected an error for a list that isn't a pointer")
+	}
+}
+
+func TestSupportsFieldSelectors(t *testing.T) {
+	config := &apiserver.ServerConfig{FieldSelectors: []string{"builds", "events"}}
+	if supported, known := SupportsFieldSelectors(config, "events"); !supported || !known {
+		t.Errorf("expected events to be filtered by the server")
+	}
+	if supported, known := SupportsFieldSelectors(config, "pods"); supported || !known {
+		t.Errorf("expected pods not to be filtered by the server")
+	}
+	for _, config := range []*apiserver.ServerConfig{nil, {}} {
+		if _, known := SupportsFieldSelectors(config, "events"); known {
+			t.Errorf("%#v: expected support to be unknown", config)
+		}
+	}
+}
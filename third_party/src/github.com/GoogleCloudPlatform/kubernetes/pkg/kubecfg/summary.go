@@ -0,0 +1,174 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// ListSummary is the roll-up of a list of objects, such as "200 pods: 180 running,
+// 15 pending, 5 failed", that HumanReadablePrinter prints after the table of the list.
+type ListSummary struct {
+	// Resource is the plural name of the objects in the list, e.g. "pods".
+	Resource string
+	// Total is the number of objects in the list.
+	Total int
+	// Counts are the number of objects in each state.
+	Counts map[string]int
+	// Desired is the number of replicas the replication controllers of the list want.
+	Desired int
+	// Current is the number of replicas they have, or -1 if that is unknown.
+	Current int
+}
+
+// Controller states counted by SummarizeList.
+const (
+	controllerAtSize   = "at size"
+	controllerResizing = "resizing"
+)
+
+// SummarizeList tallies the objects of list, a PodList, BuildList or
+// ReplicationControllerList, by state. Pods and builds are counted by status. Controllers
+// are counted by whether they have the replicas they want, according to replicas, the
+// current replicas by qualified controller ID (see ReplicasByController); if replicas is
+// nil, only the desired replicas are known. False is returned for any other object.
+func SummarizeList(list interface{}, replicas map[string]int) (ListSummary, bool) {
+	summary := ListSummary{Counts: map[string]int{}, Current: -1}
+	switch o := list.(type) {
+	case *api.PodList:
+		summary.Resource, summary.Total = "pods", len(o.Items)
+		for _, pod := range o.Items {
+			summary.Counts[stateName(string(pod.CurrentState.Status))]++
+		}
+	case *buildapi.BuildList:
+		summary.Resource, summary.Total = "builds", len(o.Items)
+		for _, build := range o.Items {
+			summary.Counts[stateName(string(build.Status))]++
+		}
+	case *api.ReplicationControllerList:
+		summary.Resource, summary.Total = "replicationControllers", len(o.Items)
+		if replicas != nil {
+			summary.Current = 0
+		}
+		for _, ctrl := range o.Items {
+			summary.Desired += ctrl.DesiredState.Replicas
+			if replicas == nil {
+				continue
+			}
+			current := replicas[api.QualifiedID(ctrl.Namespace, ctrl.ID)]
+			summary.Current += current
+			if current == ctrl.DesiredState.Replicas {
+				summary.Counts[controllerAtSize]++
+			} else {
+				summary.Counts[controllerResizing]++
+			}
+		}
+	default:
+		return ListSummary{}, false
+	}
+	return summary, true
+}
+
+// merge adds the objects counted in other, a summary of more objects of the same resource,
+// to s.
+func (s *ListSummary) merge(other ListSummary) {
+	s.Total += other.Total
+	for state, count := range other.Counts {
+		s.Counts[state] += count
+	}
+	s.Desired += other.Desired
+	if other.Current >= 0 {
+		if s.Current < 0 {
+			s.Current = 0
+		}
+		s.Current += other.Current
+	}
+}
+
+// stateName returns the name a status is counted under in a summary.
+func stateName(status string) string {
+	if len(status) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(status)
+}
+
+// ReplicasByController returns the number of pods in pods that each controller in
+// controllers selects, by the qualified ID of the controller, as SummarizeList expects them.
+func ReplicasByController(controllers *api.ReplicationControllerList, pods *api.PodList) map[string]int {
+	replicas := map[string]int{}
+	for _, ctrl := range controllers.Items {
+		selector := labels.SelectorFromSet(labels.Set(ctrl.DesiredState.ReplicaSelector))
+		count := 0
+		for _, pod := range pods.Items {
+			if pod.Namespace == ctrl.Namespace && selector.Matches(labels.Set(pod.Labels)) {
+				count++
+			}
+		}
+		replicas[api.QualifiedID(ctrl.Namespace, ctrl.ID)] = count
+	}
+	return replicas
+}
+
+// String formats s as a single line, with the most frequent states first.
+func (s ListSummary) String() string {
+	states := []string{}
+	for state := range s.Counts {
+		states = append(states, state)
+	}
+	sort.Sort(byCount{states, s.Counts})
+	parts := []string{}
+	for _, state := range states {
+		parts = append(parts, fmt.Sprintf("%d %s", s.Counts[state], state))
+	}
+	line := fmt.Sprintf("%d %s", s.Total, s.Resource)
+	if len(parts) > 0 {
+		line += ": " + strings.Join(parts, ", ")
+	}
+	if s.Resource != "replicationControllers" {
+		return line
+	}
+	separator := ": "
+	if len(parts) > 0 {
+		separator = "; "
+	}
+	if s.Current < 0 {
+		return fmt.Sprintf("%s%s%d replicas desired", line, separator, s.Desired)
+	}
+	return fmt.Sprintf("%s%s%d of %d replicas", line, separator, s.Current, s.Desired)
+}
+
+// byCount sorts states by decreasing count, then by name.
+type byCount struct {
+	states []string
+	counts map[string]int
+}
+
+func (b byCount) Len() int      { return len(b.states) }
+func (b byCount) Swap(i, j int) { b.states[i], b.states[j] = b.states[j], b.states[i] }
+func (b byCount) Less(i, j int) bool {
+	if b.counts[b.states[i]] != b.counts[b.states[j]] {
+		return b.counts[b.states[i]] > b.counts[b.states[j]]
+	}
+	return b.states[i] < b.states[j]
+}
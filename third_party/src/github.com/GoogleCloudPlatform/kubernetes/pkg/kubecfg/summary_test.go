@@ -0,0 +1,80 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/buildapi"
+)
+
+func TestSummarizeList(t *testing.T) {
+	pods := &api.PodList{Items: []api.Pod{
+		{CurrentState: api.PodState{Status: api.PodRunning}},
+		{CurrentState: api.PodState{Status: api.PodRunning}},
+		{CurrentState: api.PodState{Status: api.PodWaiting}},
+		{},
+	}}
+	builds := &buildapi.BuildList{Items: []buildapi.Build{
+		{Status: buildapi.BuildComplete},
+		{Status: buildapi.BuildFailed},
+	}}
+	controllers := &api.ReplicationControllerList{Items: []api.ReplicationController{
+		{JSONBase: api.JSONBase{ID: "web"}, DesiredState: api.ReplicationControllerState{Replicas: 3}},
+		{JSONBase: api.JSONBase{ID: "db"}, DesiredState: api.ReplicationControllerState{Replicas: 2}},
+	}}
+
+	table := []struct {
+		list     interface{}
+		replicas map[string]int
+		expected ListSummary
+	}{
+		{pods, nil, ListSummary{Resource: "pods", Total: 4, Counts: map[string]int{"running": 2, "waiting": 1, "unknown": 1}, Current: -1}},
+		{builds, nil, ListSummary{Resource: "builds", Total: 2, Counts: map[string]int{"complete": 1, "failed": 1}, Current: -1}},
+		{controllers, nil, ListSummary{Resource: "replicationControllers", Total: 2, Counts: map[string]int{}, Desired: 5, Current: -1}},
+		{controllers, map[string]int{"web": 3, "db": 1}, ListSummary{Resource: "replicationControllers", Total: 2, Counts: map[string]int{"at size": 1, "resizing": 1}, Desired: 5, Current: 4}},
+	}
+	for _, item := range table {
+		summary, ok := SummarizeList(item.list, item.replicas)
+		if !ok || !reflect.DeepEqual(summary, item.expected) {
+			t.Errorf("expected %#v, got %#v", item.expected, summary)
+		}
+	}
+
+	if _, ok := SummarizeList(&api.Pod{}, nil); ok {
+		t.Errorf("expected a single object not to be summarized")
+	}
+}
+
+func TestReplicasByController(t *testing.T) {
+	controllers := &api.ReplicationControllerList{Items: []api.ReplicationController{
+		{JSONBase: api.JSONBase{ID: "web"}, DesiredState: api.ReplicationControllerState{ReplicaSelector: map[string]string{"name": "web"}}},
+		{JSONBase: api.JSONBase{ID: "web", Namespace: "other"}, DesiredState: api.ReplicationControllerState{ReplicaSelector: map[string]string{"name": "web"}}},
+	}}
+	pods := &api.PodList{Items: []api.Pod{
+		{Labels: map[string]string{"name": "web"}},
+		{Labels: map[string]string{"name": "web", "tier": "frontend"}},
+		{Labels: map[string]string{"name": "db"}},
+		{JSONBase: api.JSONBase{Namespace: "other"}, Labels: map[string]string{"name": "web"}},
+	}}
+	expected := map[string]int{"web": 2, api.QualifiedID("other", "web"): 1}
+	if replicas := ReplicasByController(controllers, pods); !reflect.DeepEqual(replicas, expected) {
+		t.Errorf("expected %v, got %v", expected, replicas)
+	}
+}
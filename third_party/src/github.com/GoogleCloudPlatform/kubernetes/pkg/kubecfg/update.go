@@ -0,0 +1,207 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FieldChange sets a field of an object, as given to 'kubecfg update' with --patch.
+type FieldChange struct {
+	// Path are the keys leading to the field in the JSON of the object, as in a WaitCondition.
+	// Keys that are numbers index into lists.
+	Path []string
+	// Value is the new value of the field, as it is decoded from JSON.
+	Value interface{}
+}
+
+// ParseFieldChanges parses args, each <field path>=<value>, e.g. desiredState.replicas=3. The
+// value is read as JSON if it is valid JSON, e.g. a number, true, or a quoted string, and as a
+// string otherwise.
+func ParseFieldChanges(args []string) ([]FieldChange, error) {
+	changes := []FieldChange{}
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q should be <field path>=<value>", arg)
+		}
+		keys, err := parseFieldPath(arg, parts[0])
+		if err != nil {
+			return nil, err
+		}
+		value, err := decodeJSON([]byte(parts[1]))
+		if err != nil {
+			value = parts[1]
+		}
+		changes = append(changes, FieldChange{keys, value})
+	}
+	return changes, nil
+}
+
+// MergeObject returns the JSON of live, the JSON of an object, with the fields set in config,
+// the JSON of a partial object, merged over it, and then changes made. Objects are merged
+// key by key; other values, and lists unless mergeLists is true, replace the live ones. With
+// mergeLists, the items of a list in config that are objects with the name or id of an item
+// of the live list are merged into it, and the others are added to the list unless it already
+// has them. config may be nil. The merged object keeps the resource version of live, so that
+// writing it fails if live was changed since it was read.
+func MergeObject(live, config []byte, changes []FieldChange, mergeLists bool) ([]byte, error) {
+	liveValue, err := decodeJSON(live)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the live object: %v", err)
+	}
+	object, ok := liveValue.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the live object is not a JSON object")
+	}
+	resourceVersion, hasResourceVersion := object["resourceVersion"]
+	if len(config) > 0 {
+		configValue, err := decodeJSON(config)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse the config: %v", err)
+		}
+		configObject, ok := configValue.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("the config is not a JSON object")
+		}
+		if id, ok := configObject["id"]; ok && !reflect.DeepEqual(id, object["id"]) {
+			return nil, fmt.Errorf("the config is for %v, not %v", id, object["id"])
+		}
+		object = mergeJSON(object, configObject, mergeLists).(map[string]interface{})
+	}
+	for _, change := range changes {
+		if err := setField(object, change.Path, change.Value); err != nil {
+			return nil, err
+		}
+	}
+	delete(object, "resourceVersion")
+	if hasResourceVersion {
+		object["resourceVersion"] = resourceVersion
+	}
+	return json.Marshal(object)
+}
+
+// decodeJSON decodes data into generic maps and lists, keeping numbers as they are written.
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	var extra interface{}
+	if err := decoder.Decode(&extra); err == nil {
+		return nil, fmt.Errorf("unexpected data after the value")
+	}
+	return value, nil
+}
+
+// mergeJSON returns patch merged over live, as MergeObject describes.
+func mergeJSON(live, patch interface{}, mergeLists bool) interface{} {
+	switch p := patch.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return patch
+		}
+		for key, value := range p {
+			l[key] = mergeJSON(l[key], value, mergeLists)
+		}
+		return l
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || !mergeLists {
+			return patch
+		}
+		for _, item := range p {
+			if i := findListItem(l, item); i != -1 {
+				l[i] = mergeJSON(l[i], item, mergeLists)
+			} else {
+				l = append(l, item)
+			}
+		}
+		return l
+	}
+	return patch
+}
+
+// findListItem returns the index of the item of list that item should be merged into: the
+// object with the same name or id, or an item equal to item. It returns -1 if there is none.
+func findListItem(list []interface{}, item interface{}) int {
+	for _, key := range []string{"name", "id"} {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			break
+		}
+		value, ok := object[key]
+		if !ok {
+			continue
+		}
+		for i, existing := range list {
+			if existingObject, ok := existing.(map[string]interface{}); ok && reflect.DeepEqual(existingObject[key], value) {
+				return i
+			}
+		}
+		return -1
+	}
+	for i, existing := range list {
+		if reflect.DeepEqual(existing, item) {
+			return i
+		}
+	}
+	return -1
+}
+
+// setField sets the field at path in object to value, adding the objects leading to it that
+// are missing. Keys that are numbers index into existing lists.
+func setField(object map[string]interface{}, path []string, value interface{}) error {
+	var current interface{} = object
+	for i, key := range path {
+		last := i == len(path)-1
+		switch c := current.(type) {
+		case map[string]interface{}:
+			if last {
+				c[key] = value
+				return nil
+			}
+			next, ok := c[key]
+			if !ok || next == nil {
+				next = map[string]interface{}{}
+				c[key] = next
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(c) {
+				return fmt.Errorf("%s: %s is not an index of a list of %d items", strings.Join(path, "."), key, len(c))
+			}
+			if last {
+				c[index] = value
+				return nil
+			}
+			current = c[index]
+		default:
+			return fmt.Errorf("%s: %s is not an object or a list", strings.Join(path, "."), strings.Join(path[:i], "."))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFieldChanges(t *testing.T) {
+	changes, err := ParseFieldChanges([]string{"desiredState.replicas=3", "labels.name=web", `{.labels.quoted}="4"`, "labels.empty="})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []FieldChange{
+		{[]string{"desiredState", "replicas"}, json.Number("3")},
+		{[]string{"labels", "name"}, "web"},
+		{[]string{"labels", "quoted"}, "4"},
+		{[]string{"labels", "empty"}, ""},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("expected %#v, got %#v", expected, changes)
+	}
+
+	for _, arg := range []string{"replicas", "=3", "desiredState..replicas=3"} {
+		if _, err := ParseFieldChanges([]string{arg}); err == nil {
+			t.Errorf("%s: expected an error", arg)
+		}
+	}
+}
+
+// decodeMerged returns the object MergeObject merged, decoded.
+func decodeMerged(t *testing.T, live, config string, changes []FieldChange, mergeLists bool) map[string]interface{} {
+	var configData []byte
+	if len(config) > 0 {
+		configData = []byte(config)
+	}
+	data, err := MergeObject([]byte(live), configData, changes, mergeLists)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	merged := map[string]interface{}{}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return merged
+}
+
+func decodeObject(t *testing.T, data string) map[string]interface{} {
+	object := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(data), &object); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return object
+}
+
+func TestMergeObject(t *testing.T) {
+	live := `{"id": "web", "resourceVersion": 9007199254740993, "labels": {"name": "web", "tier": "frontend"},
+		"desiredState": {"replicas": 2, "podTemplate": {"desiredState": {"manifest": {"containers": [{"name": "web", "image": "web:1"}, {"name": "log", "image": "log:1"}]}}}},
+		"currentState": {"replicas": 2}}`
+
+	table := []struct {
+		config     string
+		changes    []FieldChange
+		mergeLists bool
+		expected   string
+	}{
+		// Scalars are replaced and objects merged, keeping the live fields the config leaves out.
+		{
+			config: `{"resourceVersion": 1, "desiredState": {"replicas": 3}, "labels": {"tier": "backend"}}`,
+			expected: `{"id": "web", "resourceVersion": 9007199254740993, "labels": {"name": "web", "tier": "backend"},
+				"desiredState": {"replicas": 3, "podTemplate": {"desiredState": {"manifest": {"containers": [{"name": "web", "image": "web:1"}, {"name": "log", "image": "log:1"}]}}}},
+				"currentState": {"replicas": 2}}`,
+		},
+		// Lists are replaced.
+		{
+			config: `{"desiredState": {"podTemplate": {"desiredState": {"manifest": {"containers": [{"name": "web", "image": "web:2"}]}}}}}`,
+			expected: `{"id": "web", "resourceVersion": 9007199254740993, "labels": {"name": "web", "tier": "frontend"},
+				"desiredState": {"replicas": 2, "podTemplate": {"desiredState": {"manifest": {"containers": [{"name": "web", "image": "web:2"}]}}}},
+				"currentState": {"replicas": 2}}`,
+		},
+		// Unless they are merged, by name.
+		{
+			config:     `{"desiredState": {"podTemplate": {"desiredState": {"manifest": {"containers": [{"name": "web", "image": "web:2"}, {"name": "cache", "image": "cache:1"}]}}}}}`,
+			mergeLists: true,
+			expected: `{"id": "web", "resourceVersion": 9007199254740993, "labels": {"name": "web", "tier": "frontend"},
+				"desiredState": {"replicas": 2, "podTemplate": {"desiredState": {"manifest": {"containers": [{"name": "web", "image": "web:2"}, {"name": "log", "image": "log:1"}, {"name": "cache", "image": "cache:1"}]}}}},
+				"currentState": {"replicas": 2}}`,
+		},
+		// Changes are made after the config is merged, and may index into lists.
+		{
+			config: `{"desiredState": {"replicas": 3}}`,
+			changes: []FieldChange{
+				{[]string{"desiredState", "replicas"}, json.Number("4")},
+				{[]string{"desiredState", "podTemplate", "desiredState", "manifest", "containers", "1", "image"}, "log:2"},
+				{[]string{"annotations", "owner"}, "ops"},
+				{[]string{"resourceVersion"}, json.Number("1")},
+			},
+			expected: `{"id": "web", "resourceVersion": 9007199254740993, "labels": {"name": "web", "tier": "frontend"}, "annotations": {"owner": "ops"},
+				"desiredState": {"replicas": 4, "podTemplate": {"desiredState": {"manifest": {"containers": [{"name": "web", "image": "web:1"}, {"name": "log", "image": "log:2"}]}}}},
+				"currentState": {"replicas": 2}}`,
+		},
+	}
+	for i, item := range table {
+		merged := decodeMerged(t, live, item.config, item.changes, item.mergeLists)
+		if expected := decodeObject(t, item.expected); !reflect.DeepEqual(merged, expected) {
+			t.Errorf("%d: expected %v, got %v", i, expected, merged)
+		}
+	}
+
+	data, err := MergeObject([]byte(live), nil, nil, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(decodeObject(t, string(data)), decodeObject(t, live)) {
+		t.Errorf("expected the live object unchanged, got %s", data)
+	}
+	if !strings.Contains(string(data), `"resourceVersion":9007199254740993`) {
+		t.Errorf("expected the resource version to be kept exactly, got %s", data)
+	}
+}
+
+func TestMergeObjectErrors(t *testing.T) {
+	live := `{"id": "web", "desiredState": {"replicas": 2, "containers": [{"name": "web"}]}}`
+	table := []struct {
+		config  string
+		changes []FieldChange
+	}{
+		{config: `{"id": "db"}`},
+		{config: `[]`},
+		{config: `{"id": `},
+		{changes: []FieldChange{{[]string{"desiredState", "replicas", "count"}, "3"}}},
+		{changes: []FieldChange{{[]string{"desiredState", "containers", "1", "name"}, "log"}}},
+		{changes: []FieldChange{{[]string{"desiredState", "containers", "name"}, "log"}}},
+	}
+	for i, item := range table {
+		var config []byte
+		if len(item.config) > 0 {
+			config = []byte(item.config)
+		}
+		if _, err := MergeObject([]byte(live), config, item.changes, false); err == nil {
+			t.Errorf("%d: expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+)
+
+// ValidateConfigObject checks object as the apiserver would when it is created, without a
+// server: it must decode strictly, with no field its kind doesn't have, into the type of the
+// storage it is for, and pass the validation of its ID and of its type. The storage is
+// inferred from the kind of the object unless defaultStorage is provided. Every problem
+// found is returned, each naming the field at fault if there is one; nil means the object
+// is valid.
+func ValidateConfigObject(object ConfigObject, defaultStorage string) []error {
+	if object.Err != nil {
+		return []error{object.Err}
+	}
+	storage, err := object.Storage(defaultStorage)
+	if err != nil {
+		return []error{err}
+	}
+	data, err := ToJSON(object.Source, object.Data)
+	if err != nil {
+		return []error{err}
+	}
+	version, kind, err := api.VersionAndKind(data)
+	if err != nil {
+		return []error{err}
+	}
+	if len(kind) == 0 {
+		kind = storageToType[storage].Name()
+	}
+	prototype, err := api.New(version, kind)
+	if err != nil {
+		return []error{fmt.Errorf("unknown kind %q of API version %q", kind, version)}
+	}
+	errs := []error{}
+	fields, err := UnknownFields(data, reflect.TypeOf(prototype))
+	if err != nil {
+		return []error{err}
+	}
+	for _, field := range fields {
+		errs = append(errs, fmt.Errorf("%s: unknown field", field))
+	}
+	wire, err := ToWireFormat(data, storage)
+	if err != nil {
+		return append(errs, fmt.Errorf("error parsing as an object for %v: %v", storage, err))
+	}
+	obj, err := api.Decode(wire)
+	if err != nil {
+		return append(errs, err)
+	}
+	for _, err := range api.ValidateObjectID(obj) {
+		errs = append(errs, err)
+	}
+	for _, err := range api.Validate(obj) {
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// ConvertConfigObject returns object as JSON of the given API version. The storage, and so
+// the type, of the object is inferred from its kind unless defaultStorage is provided.
+func ConvertConfigObject(object ConfigObject, defaultStorage, version string) ([]byte, error) {
+	if object.Err != nil {
+		return nil, object.Err
+	}
+	storage, err := object.Storage(defaultStorage)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ToJSON(object.Source, object.Data)
+	if err != nil {
+		return nil, err
+	}
+	obj := reflect.New(storageToType[storage]).Interface()
+	if err := api.DecodeInto(data, obj); err != nil {
+		return nil, fmt.Errorf("error parsing as an object for %v: %v", storage, err)
+	}
+	if _, err := api.New(version, reflect.TypeOf(obj).Elem().Name()); err != nil {
+		return nil, fmt.Errorf("unknown API version %q", version)
+	}
+	return api.EncodeToVersion(obj, version)
+}
+
+// UnknownFields returns the fields of the JSON object data that t, the type data decodes
+// into, doesn't have, and which decoding would silently drop. Fields are named by their path
+// from the top of the object, such as desiredState.manifest.containers[0].imag, and sorted.
+// Values decoded by their own unmarshalling, and maps, may hold any field.
+func UnknownFields(data []byte, t reflect.Type) ([]string, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	fields := []string{}
+	unknownFields(value, t, "", &fields)
+	sort.Strings(fields)
+	return fields, nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	yamlSetterType      = reflect.TypeOf((*interface {
+		SetYAML(tag string, value interface{}) bool
+	})(nil)).Elem()
+)
+
+// unknownFields appends the fields of value, found at path, that t doesn't have to fields.
+func unknownFields(value interface{}, t reflect.Type, path string, fields *[]string) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) || reflect.PtrTo(t).Implements(yamlSetterType) {
+		return
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		switch t.Kind() {
+		case reflect.Struct:
+			known := map[string]reflect.Type{}
+			structFields(t, known)
+			for key, child := range value {
+				field, ok := known[key]
+				if !ok {
+					*fields = append(*fields, joinField(path, key))
+					continue
+				}
+				unknownFields(child, field, joinField(path, key), fields)
+			}
+		case reflect.Map:
+			for key, child := range value {
+				unknownFields(child, t.Elem(), joinField(path, key), fields)
+			}
+		}
+	case []interface{}:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, child := range value {
+				unknownFields(child, t.Elem(), fmt.Sprintf("%s[%d]", path, i), fields)
+			}
+		}
+	}
+}
+
+// structFields adds the fields of the struct type t to known, by the name they are decoded
+// from: the name in their yaml tag, which is what objects are decoded with, or their own name
+// in lower case if they have none. Inlined structs add their own fields.
+func structFields(t reflect.Type, known map[string]reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if len(field.PkgPath) != 0 {
+			continue
+		}
+		tag := strings.Split(field.Tag.Get("yaml"), ",")
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		inline := false
+		for _, option := range tag[1:] {
+			inline = inline || option == "inline"
+		}
+		if inline && field.Type.Kind() == reflect.Struct {
+			structFields(field.Type, known)
+			continue
+		}
+		if len(name) == 0 {
+			name = strings.ToLower(field.Name)
+		}
+		known[name] = field.Type
+	}
+}
+
+// joinField returns the path of the field name of the object at path.
+func joinField(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api/v1beta1"
+)
+
+const validPod = `{"kind": "Pod", "apiVersion": "v1beta1", "id": "foo", "desiredState": {"manifest": {"version": "v1beta1", "id": "foo", "containers": [{"name": "web", "image": "nginx"}]}}}`
+
+func TestValidateConfigObject(t *testing.T) {
+	table := []struct {
+		data           string
+		defaultStorage string
+		errors         []string
+	}{
+		{validPod, "", nil},
+		{"kind: Pod\nid: foo\ndesiredState:\n  manifest:\n    version: v1beta1\n    id: foo\n", "", nil},
+		{`{"id": "foo", "desiredState": {"manifest": {"version": "v1beta1", "id": "foo"}}}`, "pods", nil},
+		{`{"kind": "Service", "id": "foo", "port": 80, "selector": {"name": "foo"}, "labels": {"any": "thing"}}`, "", nil},
+		{
+			`{"kind": "Pod", "id": "foo", "replica": 2, "desiredState": {"manifest": {"version": "v1beta1", "id": "foo", "containers": [{"name": "web", "imag": "nginx"}]}}}`,
+			"",
+			[]string{"desiredState.manifest.containers[0].imag: unknown field", "replica: unknown field", "desiredState.manifest.containers[0].image"},
+		},
+		{`{"kind": "Pod", "id": "Foo", "desiredState": {"manifest": {"version": "v1beta1", "id": "foo"}}}`, "", []string{"id"}},
+		{`{"kind": "Pod", "apiVersion": "v1beta2", "id": "foo"}`, "", []string{"unknown kind"}},
+		{`{"id": "foo"}`, "", []string{"no kind"}},
+	}
+	for _, item := range table {
+		errs := ValidateConfigObject(ConfigObject{Source: "test", Data: []byte(item.data)}, item.defaultStorage)
+		if len(errs) != len(item.errors) {
+			t.Errorf("%s: expected %v, got %v", item.data, item.errors, errs)
+			continue
+		}
+		for i := range errs {
+			if !strings.Contains(errs[i].Error(), item.errors[i]) {
+				t.Errorf("%s: expected an error about %q, got %v", item.data, item.errors[i], errs[i])
+			}
+		}
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	data := `{"kind": "Pod", "id": "foo", "labels": {"a": "b"}, "x": 1, "desiredState": {"manifest": {"containers": [{"ports": [{"containerPort": 80, "hostport": 1}]}]}}}`
+	fields, err := UnknownFields([]byte(data), reflect.TypeOf(&v1beta1.Pod{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"desiredState.manifest.containers[0].ports[0].hostport", "x"}
+	if !reflect.DeepEqual(fields, expected) {
+		t.Errorf("expected %v, got %v", expected, fields)
+	}
+	if _, err := UnknownFields([]byte("{"), reflect.TypeOf(&v1beta1.Pod{})); err == nil {
+		t.Errorf("expected an error for invalid JSON")
+	}
+}
+
+func TestConvertConfigObject(t *testing.T) {
+	object := ConfigObject{Source: "test.yaml", Data: []byte("kind: Pod\nid: foo\nlabels:\n  name: foo\n")}
+	data, err := ConvertConfigObject(object, "", "v1beta1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var pod v1beta1.Pod
+	if err := api.DecodeInto(data, &pod); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"apiVersion":"v1beta1"`) || pod.ID != "foo" || pod.Labels["name"] != "foo" {
+		t.Errorf("unexpected conversion: %s", data)
+	}
+	if _, err := ConvertConfigObject(object, "", "v1beta2"); err == nil || !strings.Contains(err.Error(), "v1beta2") {
+		t.Errorf("expected an unknown version error, got %v", err)
+	}
+	if _, err := ConvertConfigObject(ConfigObject{Source: "test.json", Data: []byte(`{"id": "foo"}`)}, "", "v1beta1"); err == nil {
+		t.Errorf("expected an error for an object without a kind")
+	}
+}
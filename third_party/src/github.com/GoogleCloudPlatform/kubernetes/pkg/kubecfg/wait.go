@@ -0,0 +1,295 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+	"github.com/golang/glog"
+)
+
+// WaitCondition is what 'kubecfg wait' waits for: a field of an object to have a value, or
+// the object to be deleted.
+type WaitCondition struct {
+	// Path are the keys leading to the field in the JSON of the object, as a TemplatePrinter
+	// with Raw set sees it. Keys that are numbers index into lists.
+	Path []string
+	// Value is the value the field should have, as printed by FieldValue.
+	Value string
+	// Delete waits for the object to be deleted instead.
+	Delete bool
+}
+
+// ParseWaitCondition parses spec, either "delete" or <field path>=<value>. The field path
+// names the keys leading to the field separated by dots, e.g. currentState.status, and may
+// be written as in a template, e.g. {.currentState.status}.
+func ParseWaitCondition(spec string) (WaitCondition, error) {
+	if spec == "delete" {
+		return WaitCondition{Delete: true}, nil
+	}
+	parts := strings.SplitN(spec, "=", 2)
+	if len(parts) != 2 {
+		return WaitCondition{}, fmt.Errorf("%q should be <field path>=<value> or delete", spec)
+	}
+	keys, err := parseFieldPath(spec, parts[0])
+	if err != nil {
+		return WaitCondition{}, err
+	}
+	return WaitCondition{Path: keys, Value: parts[1]}, nil
+}
+
+// parseFieldPath returns the keys of path, the field path of spec, which names the keys
+// leading to a field separated by dots and may be written as in a template.
+func parseFieldPath(spec, path string) ([]string, error) {
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	path = strings.TrimPrefix(path, ".")
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%q has no field path", spec)
+	}
+	keys := strings.Split(path, ".")
+	for _, key := range keys {
+		if len(key) == 0 {
+			return nil, fmt.Errorf("%q has an empty key in its field path", spec)
+		}
+	}
+	return keys, nil
+}
+
+func (c WaitCondition) String() string {
+	if c.Delete {
+		return "deletion"
+	}
+	return fmt.Sprintf(".%s=%s", strings.Join(c.Path, "."), c.Value)
+}
+
+// FieldValue returns the value of the field at path in raw, the JSON of an object decoded
+// into a generic map. Strings are returned as they are, numbers and booleans formatted, and
+// lists and maps encoded as JSON. ok is false if the object has no such field.
+func FieldValue(raw map[string]interface{}, path []string) (value string, ok bool) {
+	var field interface{} = raw
+	for _, key := range path {
+		switch current := field.(type) {
+		case map[string]interface{}:
+			if field, ok = current[key]; !ok {
+				return "", false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(current) {
+				return "", false
+			}
+			field = current[i]
+		default:
+			return "", false
+		}
+	}
+	switch v := field.(type) {
+	case nil:
+		return "", true
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	}
+	data, err := json.Marshal(field)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// WaitTimeoutError is returned by Wait if the condition did not hold in time. Observed is
+// the last value of the field that was seen.
+type WaitTimeoutError struct {
+	Resource  string
+	ID        string
+	Condition WaitCondition
+	Observed  string
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("timed out waiting for %s/%s to reach %s, last observed: %s", e.Resource, e.ID, e.Condition, e.Observed)
+}
+
+// Observations of an object that don't show the value of its field.
+const (
+	observedNothing = "nothing"
+	observedDeleted = "deleted"
+	observedMissing = "field missing"
+)
+
+// waiter follows the object resource/id until cond holds.
+type waiter struct {
+	c         *client.Client
+	namespace string
+	resource  string
+	id        string
+	cond      WaitCondition
+	observed  string
+}
+
+// Wait waits until cond holds for the object resource/id in namespace. The object is
+// watched for changes, and polled every pollInterval if the watch can't be opened or ends.
+// The condition is met by an object that doesn't exist yet once it is created. A zero
+// timeout waits forever; otherwise a *WaitTimeoutError is returned when it expires.
+func Wait(c *client.Client, namespace, resource, id string, cond WaitCondition, timeout, pollInterval time.Duration) error {
+	w := &waiter{c: c, namespace: namespace, resource: resource, id: id, cond: cond, observed: observedNothing}
+	var expired <-chan time.Time
+	if timeout != 0 {
+		expired = time.After(timeout)
+	}
+
+	done, resourceVersion, err := w.get()
+	if done || err != nil {
+		return err
+	}
+	watching, err := c.Get().
+		Path("watch").
+		Namespace(namespace).
+		Path(resource).
+		UintParam("resourceVersion", resourceVersion).
+		Watch()
+	if err != nil {
+		glog.V(2).Infof("Unable to watch %s, polling instead: %v", resource, err)
+	} else {
+		done, err := w.watch(watching, expired)
+		if done || err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-expired:
+			return w.timeout()
+		case <-ticker.C:
+		}
+		if done, _, err := w.get(); done || err != nil {
+			return err
+		}
+	}
+}
+
+// get fetches the object and checks the condition. It returns the resource version to
+// watch from, or 0 if the object doesn't exist.
+func (w *waiter) get() (done bool, resourceVersion uint64, err error) {
+	obj, err := w.c.Get().Namespace(w.namespace).Path(w.resource).Name(w.id).Do().Get()
+	if client.IsNotFound(err) {
+		w.observed = observedDeleted
+		return w.cond.Delete, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+	done, err = w.check(obj)
+	if err != nil {
+		return false, 0, err
+	}
+	jsonBase, err := api.FindJSONBaseRO(obj)
+	if err != nil || jsonBase.ResourceVersion == 0 {
+		return done, 0, nil
+	}
+	return done, jsonBase.ResourceVersion + 1, nil
+}
+
+// watch checks the condition against each change of the object until it holds, the watch
+// ends or the timeout expires. It returns false without an error if the watch ended.
+func (w *waiter) watch(watching watch.Interface, expired <-chan time.Time) (bool, error) {
+	defer watching.Stop()
+	for {
+		var event watch.Event
+		var ok bool
+		select {
+		case <-expired:
+			return false, w.timeout()
+		case event, ok = <-watching.ResultChan():
+		}
+		if !ok {
+			glog.V(2).Infof("The watch of %s ended, polling instead", w.resource)
+			return false, nil
+		}
+		if event.Type == watch.Error {
+			glog.V(2).Infof("The watch of %s failed, polling instead: %#v", w.resource, event.Object)
+			return false, nil
+		}
+		jsonBase, err := api.FindJSONBaseRO(event.Object)
+		if err != nil || !w.matches(jsonBase) {
+			continue
+		}
+		if event.Type == watch.Deleted {
+			w.observed = observedDeleted
+			if w.cond.Delete {
+				return true, nil
+			}
+			continue
+		}
+		if done, err := w.check(event.Object); done || err != nil {
+			return done, err
+		}
+	}
+}
+
+// matches returns true if jsonBase is that of the object waited for. Watches in the default
+// namespace see the objects of all namespaces.
+func (w *waiter) matches(jsonBase api.JSONBase) bool {
+	if jsonBase.ID != w.id {
+		return false
+	}
+	if len(w.namespace) == 0 {
+		return len(jsonBase.Namespace) == 0 || jsonBase.Namespace == api.NamespaceDefault
+	}
+	return jsonBase.Namespace == w.namespace
+}
+
+// check records the value of the field of obj, and returns true if it is the value waited
+// for.
+func (w *waiter) check(obj interface{}) (bool, error) {
+	if w.cond.Delete {
+		w.observed = "exists"
+		return false, nil
+	}
+	data, err := api.Encode(obj)
+	if err != nil {
+		return false, err
+	}
+	raw, err := decodeRaw(data)
+	if err != nil {
+		return false, err
+	}
+	value, ok := FieldValue(raw, w.cond.Path)
+	if !ok {
+		w.observed = observedMissing
+		return false, nil
+	}
+	w.observed = strconv.Quote(value)
+	return value == w.cond.Value, nil
+}
+
+func (w *waiter) timeout() error {
+	return &WaitTimeoutError{Resource: w.resource, ID: w.id, Condition: w.cond, Observed: w.observed}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubecfg
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+func TestParseWaitCondition(t *testing.T) {
+	table := map[string]WaitCondition{
+		"delete":                        {Delete: true},
+		"currentState.status=Running":   {Path: []string{"currentState", "status"}, Value: "Running"},
+		".currentState.status=Running":  {Path: []string{"currentState", "status"}, Value: "Running"},
+		"{.currentState.status}=a=b":    {Path: []string{"currentState", "status"}, Value: "a=b"},
+		"desiredState.replicas=":        {Path: []string{"desiredState", "replicas"}, Value: ""},
+		"currentState.info.net.state=1": {Path: []string{"currentState", "info", "net", "state"}, Value: "1"},
+	}
+	for spec, expected := range table {
+		cond, err := ParseWaitCondition(spec)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(cond, expected) {
+			t.Errorf("%s: expected %#v, got %#v", spec, expected, cond)
+		}
+	}
+
+	for _, spec := range []string{"", "deleted", "currentState.status", "=Running", "{}=a", "currentState..status=a"} {
+		if _, err := ParseWaitCondition(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestFieldValue(t *testing.T) {
+	var raw map[string]interface{}
+	data := `{"id":"foo","replicas":2,"ready":true,"none":null,"ports":[{"port":80}],"labels":{"a":"b"}}`
+	if err := json.Unmarshal([]byte(data), &raw); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table := []struct {
+		path  []string
+		value string
+		ok    bool
+	}{
+		{[]string{"id"}, "foo", true},
+		{[]string{"replicas"}, "2", true},
+		{[]string{"ready"}, "true", true},
+		{[]string{"none"}, "", true},
+		{[]string{"ports", "0", "port"}, "80", true},
+		{[]string{"labels"}, `{"a":"b"}`, true},
+		{[]string{"missing"}, "", false},
+		{[]string{"id", "more"}, "", false},
+		{[]string{"ports", "1"}, "", false},
+		{[]string{"ports", "port"}, "", false},
+	}
+	for _, item := range table {
+		value, ok := FieldValue(raw, item.path)
+		if value != item.value || ok != item.ok {
+			t.Errorf("%v: expected %q, %v, got %q, %v", item.path, item.value, item.ok, value, ok)
+		}
+	}
+}
+
+// waitServer serves pods/foo as the current pod, and the events of events to watches, or
+// 404 to watches if events is nil. Each pod served by GET is read from pods in turn, the
+// last one being served again; a nil pod is served as not found.
+type waitServer struct {
+	t      *testing.T
+	lock   sync.Mutex
+	pods   []*api.Pod
+	gets   int
+	events []watch.Event
+
+	watchedFrom string
+}
+
+func (s *waitServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	switch req.URL.Path {
+	case "/api/v1beta1/pods/foo":
+		pod := s.pods[s.gets]
+		if s.gets < len(s.pods)-1 {
+			s.gets++
+		}
+		if pod == nil {
+			w.WriteHeader(http.StatusNotFound)
+			data, _ := api.Encode(&api.Status{Status: api.StatusFailure, Code: http.StatusNotFound, Reason: api.ReasonTypeNotFound})
+			w.Write(data)
+			return
+		}
+		data, _ := api.Encode(pod)
+		w.Write(data)
+	case "/api/v1beta1/watch/pods":
+		if s.events == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		s.watchedFrom = req.URL.Query().Get("resourceVersion")
+		encoder := json.NewEncoder(w)
+		for _, event := range s.events {
+			encoder.Encode(&api.WatchEvent{Type: event.Type, Object: api.APIObject{event.Object}})
+		}
+	default:
+		s.t.Errorf("unexpected request %s %s", req.Method, req.URL)
+	}
+}
+
+func podWithStatus(id string, status api.PodStatus) *api.Pod {
+	return &api.Pod{JSONBase: api.JSONBase{ID: id, ResourceVersion: 5}, CurrentState: api.PodState{Status: status}}
+}
+
+func TestWaitWatchesObject(t *testing.T) {
+	s := &waitServer{
+		t:    t,
+		pods: []*api.Pod{podWithStatus("foo", api.PodWaiting)},
+		events: []watch.Event{
+			{watch.Modified, podWithStatus("bar", api.PodRunning)},
+			{watch.Modified, podWithStatus("foo", api.PodRunning)},
+		},
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	cond, _ := ParseWaitCondition("currentState.status=Running")
+	if err := Wait(client.New(server.URL, nil), "", "pods", "foo", cond, 5*time.Second, time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.watchedFrom != "6" {
+		t.Errorf("expected the watch to start after the version of the pod, got %q", s.watchedFrom)
+	}
+}
+
+func TestWaitPollsWithoutWatch(t *testing.T) {
+	s := &waitServer{
+		t:    t,
+		pods: []*api.Pod{nil, podWithStatus("foo", api.PodWaiting), podWithStatus("foo", api.PodRunning)},
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	cond, _ := ParseWaitCondition("{.currentState.status}=Running")
+	if err := Wait(client.New(server.URL, nil), "", "pods", "foo", cond, 5*time.Second, 10*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.gets != 2 {
+		t.Errorf("expected the pod to be polled until it runs, got %d gets", s.gets)
+	}
+}
+
+func TestWaitForDeletion(t *testing.T) {
+	s := &waitServer{
+		t:      t,
+		pods:   []*api.Pod{podWithStatus("foo", api.PodRunning)},
+		events: []watch.Event{{watch.Deleted, podWithStatus("foo", api.PodRunning)}},
+	}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	c := client.New(server.URL, nil)
+	cond, _ := ParseWaitCondition("delete")
+	if err := Wait(c, "", "pods", "foo", cond, 5*time.Second, time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// An object that doesn't exist is deleted.
+	s = &waitServer{t: t, pods: []*api.Pod{nil}}
+	server = httptest.NewServer(s)
+	defer server.Close()
+	if err := Wait(client.New(server.URL, nil), "", "pods", "foo", cond, 5*time.Second, time.Hour); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	s := &waitServer{t: t, pods: []*api.Pod{podWithStatus("foo", api.PodWaiting)}}
+	server := httptest.NewServer(s)
+	defer server.Close()
+	cond, _ := ParseWaitCondition("currentState.status=Running")
+	err := Wait(client.New(server.URL, nil), "", "pods", "foo", cond, 100*time.Millisecond, 10*time.Millisecond)
+	timeout, ok := err.(*WaitTimeoutError)
+	if !ok {
+		t.Fatalf("expected a timeout error, got %v", err)
+	}
+	if timeout.Observed != `"Waiting"` {
+		t.Errorf("expected the last observed value to be reported, got %s", timeout.Observed)
+	}
+	if code := ExitCode(err); code != ExitTimeout {
+		t.Errorf("expected exit code %d, got %d", ExitTimeout, code)
+	}
+}
@@ -103,6 +103,19 @@ func (f *FakeDockerClient) RemoveContainer(opts docker.RemoveContainerOptions) e
 	return nil
 }
 
+// Logs is a test-spy implementation of DockerInterface.Logs.
+// It adds an entry "logs" to the internal method call record, and writes a line naming the container.
+func (f *FakeDockerClient) Logs(opts docker.LogsOptions) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.called = append(f.called, "logs")
+	if f.err != nil {
+		return f.err
+	}
+	fmt.Fprintf(opts.OutputStream, "logs of %s\n", opts.Container)
+	return nil
+}
+
 // PullImage is a test-spy implementation of DockerInterface.StopContainer.
 // It adds an entry "pull" to the internal method call record.
 func (f *FakeDockerClient) PullImage(opts docker.PullImageOptions, auth docker.AuthConfiguration) error {
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"strconv"
@@ -167,7 +168,7 @@ func (self *podWorkers) Run(podFullName string, action func()) {
 }
 
 // LogEvent logs an event to the etcd backend.
-func (kl *Kubelet) LogEvent(event *api.Event) error {
+func (kl *Kubelet) LogEvent(event *api.ContainerEvent) error {
 	if kl.etcdClient == nil {
 		return fmt.Errorf("no etcd client connection")
 	}
@@ -325,7 +326,7 @@ func (kl *Kubelet) killContainer(dockerContainer *docker.APIContainers) error {
 	err := kl.dockerClient.StopContainer(dockerContainer.ID, 10)
 	kl.dockerClient.RemoveContainer(docker.RemoveContainerOptions{ID: dockerContainer.ID, Force: true})
 	podFullName, containerName, _ := parseDockerName(dockerContainer.Names[0])
-	kl.LogEvent(&api.Event{
+	kl.LogEvent(&api.ContainerEvent{
 		Event: "STOP",
 		Manifest: &api.ContainerManifest{
 			//TODO: This should be reported using either the apiserver schema or the kubelet schema
@@ -708,6 +709,27 @@ func (kl *Kubelet) GetContainerInfo(podFullName, containerName string, req *info
 	return kl.statsFromContainerPath(fmt.Sprintf("/docker/%s", dockerContainer.ID), req)
 }
 
+// GetContainerLogs writes the stdout and stderr of a container to the given writers. If
+// follow is true, it keeps writing until the container exits.
+func (kl *Kubelet) GetContainerLogs(podFullName, containerName string, follow bool, stdout, stderr io.Writer) error {
+	dockerContainers, err := getKubeletDockerContainers(kl.dockerClient)
+	if err != nil {
+		return err
+	}
+	dockerContainer, found, _ := dockerContainers.FindPodContainer(podFullName, containerName)
+	if !found {
+		return ErrNoContainersInPod
+	}
+	return kl.dockerClient.Logs(docker.LogsOptions{
+		Container:    dockerContainer.ID,
+		OutputStream: stdout,
+		ErrorStream:  stderr,
+		Follow:       follow,
+		Stdout:       true,
+		Stderr:       true,
+	})
+}
+
 // GetRootInfo returns stats (from Cadvisor) of current machine (root container).
 func (kl *Kubelet) GetRootInfo(req *info.ContainerInfoRequest) (*info.ContainerInfo, error) {
 	return kl.statsFromContainerPath("/", req)
@@ -17,6 +17,7 @@ limitations under the License.
 package kubelet
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"hash/adler32"
@@ -586,7 +587,7 @@ func TestSyncPodUnhealthy(t *testing.T) {
 
 func TestEventWriting(t *testing.T) {
 	kubelet, fakeEtcd, _ := makeTestKubelet(t)
-	expectedEvent := api.Event{
+	expectedEvent := api.ContainerEvent{
 		Event: "test",
 		Container: &api.Container{
 			Name: "foo",
@@ -605,7 +606,7 @@ func TestEventWriting(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	var event api.Event
+	var event api.ContainerEvent
 	err = json.Unmarshal([]byte(response.Node.Value), &event)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -620,7 +621,7 @@ func TestEventWriting(t *testing.T) {
 func TestEventWritingError(t *testing.T) {
 	kubelet, fakeEtcd, _ := makeTestKubelet(t)
 	fakeEtcd.Err = fmt.Errorf("test error")
-	err := kubelet.LogEvent(&api.Event{
+	err := kubelet.LogEvent(&api.ContainerEvent{
 		Event: "test",
 		Container: &api.Container{
 			Name: "foo",
@@ -1154,3 +1155,28 @@ func TestParseImageName(t *testing.T) {
 		}
 	}
 }
+
+func TestGetContainerLogs(t *testing.T) {
+	kubelet, _, fakeDocker := makeTestKubelet(t)
+	fakeDocker.containerList = []docker.APIContainers{
+		{
+			ID: "ab2cdf",
+			// pod id: qux
+			// container id: foo
+			Names: []string{"/k8s--foo--qux--1234"},
+		},
+	}
+
+	out := &bytes.Buffer{}
+	if err := kubelet.GetContainerLogs("qux", "foo", false, out, out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "logs of ab2cdf\n" {
+		t.Errorf("unexpected logs: %q", out.String())
+	}
+	verifyCalls(t, fakeDocker, []string{"list", "logs"})
+
+	if err := kubelet.GetContainerLogs("qux", "bar", false, out, out); err != ErrNoContainersInPod {
+		t.Errorf("expected ErrNoContainersInPod, got %v", err)
+	}
+}
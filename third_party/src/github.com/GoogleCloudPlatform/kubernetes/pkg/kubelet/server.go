@@ -68,6 +68,7 @@ type HostInterface interface {
 	GetRootInfo(req *info.ContainerInfoRequest) (*info.ContainerInfo, error)
 	GetMachineInfo() (*info.MachineInfo, error)
 	GetPodInfo(name string) (api.PodInfo, error)
+	GetContainerLogs(podFullName, containerName string, follow bool, stdout, stderr io.Writer) error
 	ServeLogs(w http.ResponseWriter, req *http.Request)
 }
 
@@ -148,6 +149,8 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Header().Add("Content-type", "application/json")
 		w.Write(data)
+	case strings.HasPrefix(u.Path, "/containerLogs/"):
+		s.serveContainerLogs(w, req)
 	case strings.HasPrefix(u.Path, "/stats"):
 		s.serveStats(w, req)
 	case strings.HasPrefix(u.Path, "/spec"):
@@ -172,6 +175,55 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// serveContainerLogs writes the output of a container. Its path is
+// /containerLogs/<podID>/<containerName>, and follow=true keeps the response open until
+// the container exits.
+func (s *Server) serveContainerLogs(w http.ResponseWriter, req *http.Request) {
+	components := strings.Split(strings.TrimPrefix(path.Clean(req.URL.Path), "/"), "/")
+	if len(components) != 3 {
+		http.Error(w, "Expected /containerLogs/<podID>/<containerName>", http.StatusNotFound)
+		return
+	}
+	// TODO: backwards compatibility with existing API, needs API change
+	podFullName := GetPodFullName(&Pod{Name: components[1], Namespace: "etcd"})
+	follow := req.URL.Query().Get("follow") == "true"
+
+	w.Header().Set("Content-Type", "text/plain")
+	out := &flushWriter{w: w}
+	if flusher, ok := w.(http.Flusher); ok && follow {
+		out.flusher = flusher
+	}
+	err := s.host.GetContainerLogs(podFullName, components[2], follow, out, out)
+	if err == ErrNoContainersInPod {
+		http.Error(w, "Container does not exist", http.StatusNotFound)
+		return
+	}
+	if err != nil && !out.written {
+		s.error(w, err)
+		return
+	}
+	if err != nil {
+		glog.Errorf("Error streaming logs of %s/%s: %v", podFullName, components[2], err)
+	}
+}
+
+// flushWriter flushes after every write, so that followed logs reach the client as they
+// are produced.
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+	written bool
+}
+
+func (f *flushWriter) Write(data []byte) (int, error) {
+	n, err := f.w.Write(data)
+	f.written = true
+	if f.flusher != nil {
+		f.flusher.Flush()
+	}
+	return n, err
+}
+
 func (s *Server) serveStats(w http.ResponseWriter, req *http.Request) {
 	// /stats/<podfullname>/<containerName>
 	components := strings.Split(strings.TrimPrefix(path.Clean(req.URL.Path), "/"), "/")
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
@@ -40,6 +41,7 @@ type fakeKubelet struct {
 	rootInfoFunc      func(query *info.ContainerInfoRequest) (*info.ContainerInfo, error)
 	machineInfoFunc   func() (*info.MachineInfo, error)
 	logFunc           func(w http.ResponseWriter, req *http.Request)
+	containerLogsFunc func(podFullName, containerName string, follow bool, stdout, stderr io.Writer) error
 }
 
 func (fk *fakeKubelet) GetPodInfo(name string) (api.PodInfo, error) {
@@ -58,6 +60,10 @@ func (fk *fakeKubelet) GetMachineInfo() (*info.MachineInfo, error) {
 	return fk.machineInfoFunc()
 }
 
+func (fk *fakeKubelet) GetContainerLogs(podFullName, containerName string, follow bool, stdout, stderr io.Writer) error {
+	return fk.containerLogsFunc(podFullName, containerName, follow, stdout, stderr)
+}
+
 func (fk *fakeKubelet) ServeLogs(w http.ResponseWriter, req *http.Request) {
 	fk.logFunc(w, req)
 }
@@ -290,3 +296,47 @@ func TestServeLogs(t *testing.T) {
 		t.Errorf("Received wrong data: %s", result)
 	}
 }
+
+func TestContainerLogs(t *testing.T) {
+	fw := makeServerTest()
+	var gotPod, gotContainer string
+	var gotFollow bool
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, follow bool, stdout, stderr io.Writer) error {
+		gotPod, gotContainer, gotFollow = podFullName, containerName, follow
+		fmt.Fprint(stdout, "building...\n")
+		fmt.Fprint(stderr, "done\n")
+		return nil
+	}
+
+	resp, err := http.Get(fw.testHTTPServer.URL + "/containerLogs/build-foo/sti-build?follow=true")
+	if err != nil {
+		t.Fatalf("Got error GETing: %v", err)
+	}
+	body, err := readResp(resp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || body != "building...\ndone\n" {
+		t.Errorf("Unexpected response %d: %q", resp.StatusCode, body)
+	}
+	if gotPod != "build-foo.etcd" || gotContainer != "sti-build" || !gotFollow {
+		t.Errorf("Unexpected arguments: %s %s %v", gotPod, gotContainer, gotFollow)
+	}
+}
+
+func TestContainerLogsNotFound(t *testing.T) {
+	fw := makeServerTest()
+	fw.fakeKubelet.containerLogsFunc = func(podFullName, containerName string, follow bool, stdout, stderr io.Writer) error {
+		return ErrNoContainersInPod
+	}
+	for _, path := range []string{"/containerLogs/build-foo/sti-build", "/containerLogs/build-foo"} {
+		resp, err := http.Get(fw.testHTTPServer.URL + path)
+		if err != nil {
+			t.Fatalf("Got error GETing: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, resp.StatusCode)
+		}
+	}
+}
@@ -17,19 +17,26 @@ limitations under the License.
 package master
 
 import (
+	"fmt"
+	"io"
 	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/build"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/build/webhook"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/buildconfig"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/healthz"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/image"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/registry"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/scheduler"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/coreos/go-etcd/etcd"
 	"github.com/golang/glog"
@@ -45,6 +52,58 @@ type Config struct {
 	MinionCacheTTL     time.Duration
 	MinionRegexp       string
 	PodInfoGetter      client.PodInfoGetter
+	// If positive, the results of asynchronous operations are kept in etcd for this long,
+	// so that clients can still poll them after the apiserver restarts.
+	OperationTTL time.Duration
+	// EventTTL is how long events are kept in etcd before they expire.
+	EventTTL time.Duration
+	// If positive, responses to list requests are cached for this long, or until the listed
+	// resource changes.
+	ListCacheTTL time.Duration
+	// AsyncOpWait is how long requests that create, update or delete objects wait for their
+	// operation to complete before they are answered with its ID. If zero,
+	// apiserver.DefaultAsyncOpWait is used; if negative, they are answered at once.
+	AsyncOpWait time.Duration
+	// MaxAsyncOpWait caps the wait requests may ask for. If not positive,
+	// apiserver.DefaultMaxAsyncOpWait is used.
+	MaxAsyncOpWait time.Duration
+	// SlowRequestThreshold is how long a request may take before the steps it took are
+	// logged. If zero, apiserver.DefaultSlowRequestThreshold is used; if negative, nothing
+	// is logged.
+	SlowRequestThreshold time.Duration
+	// BodyReadTimeout is how long clients have to send the bodies of requests. If zero,
+	// apiserver.DefaultBodyReadTimeout is used; if negative, bodies may take forever.
+	BodyReadTimeout time.Duration
+	// ListWorkers is the number of goroutines that match pod lists against selectors. If not
+	// positive, one per CPU is used.
+	ListWorkers int
+	// Admission is the chain of hooks that objects clients create or update pass through.
+	Admission []apiserver.Admission
+	// LegacyIDs names, as resource/id, objects that may be created even though their IDs
+	// break the rules of api.ValidateObjectID, e.g. "pods/myPod".
+	LegacyIDs []string
+	// LenientParams serves requests with query parameters the apiserver doesn't know rather
+	// than rejecting them, unless they pass strictParams=true.
+	LenientParams bool
+	// InvalidBodyLogLimit, if positive, logs up to this many bytes of the bodies of requests
+	// that can't be decoded or are invalid, with passwords and tokens redacted.
+	InvalidBodyLogLimit int
+	// WatchBufferSize is the number of events each watch buffers for a slow client. If not
+	// positive, apiserver.DefaultWatchBufferSize is used.
+	WatchBufferSize int
+	// WatchBufferPolicy is what a watch does when its buffer is full. If empty,
+	// apiserver.WatchBufferDrop is used.
+	WatchBufferPolicy apiserver.WatchBufferPolicy
+	// WatchReplaySize is the number of recent events of each resource kept to start watches
+	// from a recent resource version without watching etcd for them. If not positive,
+	// tools.DefaultWatchReplaySize is used.
+	WatchReplaySize int
+	// Handlers selects the handlers served besides the API, such as /logs/ and the minion
+	// proxy. If nil, apiserver.DefaultConfig is used.
+	Handlers *apiserver.Config
+	// HealthChecks are checked with the storage and minions of the master, on /healthz and
+	// by Validate, e.g. that the directory of the logs is writable.
+	HealthChecks []healthz.Check
 }
 
 // Master contains state for a Kubernetes cluster master/api server.
@@ -52,75 +111,135 @@ type Master struct {
 	podRegistry             registry.PodRegistry
 	controllerRegistry      registry.ControllerRegistry
 	serviceRegistry         registry.ServiceRegistry
+	endpointsRegistry       registry.EndpointsRegistry
 	minionRegistry          registry.MinionRegistry
 	buildRegistry           build.BuildRegistry
 	buildConfigRegistry     buildconfig.BuildConfigRegistry
 	imageRegistry           image.ImageRegistry
 	imageRepositoryRegistry image.ImageRepositoryRegistry
+	eventRegistry           registry.EventRegistry
+	listCacheTTL            time.Duration
+	asyncOpWait             time.Duration
+	maxAsyncOpWait          time.Duration
+	slowRequestThreshold    time.Duration
+	bodyReadTimeout         time.Duration
 	storage                 map[string]apiserver.RESTStorage
 	client                  *client.Client
+	ops                     *apiserver.Operations
+	admission               []apiserver.Admission
+	legacyIDs               []string
+	lenientParams           bool
+	invalidBodyLogLimit     int
+	watchBufferSize         int
+	watchBufferPolicy       apiserver.WatchBufferPolicy
+	handlers                apiserver.Config
+	healthChecks            []healthz.Check
 }
 
 // NewMemoryServer returns a new instance of Master backed with memory (not etcd).
 func NewMemoryServer(c *Config) *Master {
+	serviceRegistry := registry.MakeMemoryRegistry()
 	m := &Master{
 		podRegistry:             registry.MakeMemoryRegistry(),
 		controllerRegistry:      registry.MakeMemoryRegistry(),
-		serviceRegistry:         registry.MakeMemoryRegistry(),
+		serviceRegistry:         serviceRegistry,
+		endpointsRegistry:       serviceRegistry,
 		minionRegistry:          registry.MakeMinionRegistry(c.Minions),
 		imageRegistry:           image.MakeMemoryRegistry(),
 		imageRepositoryRegistry: image.MakeMemoryRegistry(),
 		buildRegistry:           build.MakeMemoryRegistry(),
 		buildConfigRegistry:     buildconfig.MakeMemoryRegistry(),
+		eventRegistry:           registry.MakeMemoryRegistry(),
+		listCacheTTL:            c.ListCacheTTL,
+		asyncOpWait:             asyncOpWait(c),
+		maxAsyncOpWait:          maxAsyncOpWait(c),
+		slowRequestThreshold:    slowRequestThreshold(c),
+		bodyReadTimeout:         bodyReadTimeout(c),
 		client:                  c.Client,
+		ops:                     apiserver.NewOperations(),
+		admission:               c.Admission,
+		legacyIDs:               c.LegacyIDs,
+		lenientParams:           c.LenientParams,
+		invalidBodyLogLimit:     c.InvalidBodyLogLimit,
+		watchBufferSize:         c.WatchBufferSize,
+		watchBufferPolicy:       c.WatchBufferPolicy,
+		handlers:                handlers(c),
 	}
+	m.healthChecks = append(m.baseHealthChecks(), c.HealthChecks...)
 	m.init(c.Cloud, c.PodInfoGetter)
 	return m
 }
 
 // New returns a new instance of Master connected to the given etcdServer.
 func New(c *Config) *Master {
-	etcdClient := etcd.NewClient(c.EtcdServers)
+	rawEtcdClient := etcd.NewClient(c.EtcdServers)
+	// The storages call etcd through an instrumented client, whose breaker the handlers
+	// configure. The health check probes etcd itself.
+	etcdClient := tools.NewInstrumentedEtcdClient(rawEtcdClient)
 	minionRegistry := minionRegistryMaker(c)
+	podRegistry := registry.MakeEtcdRegistry(etcdClient, minionRegistry)
+	podRegistry.SetListWorkers(c.ListWorkers)
+	podRegistry.SetWatchReplaySize(c.WatchReplaySize)
+	controllerRegistry := registry.MakeEtcdRegistry(etcdClient, minionRegistry)
+	controllerRegistry.SetWatchReplaySize(c.WatchReplaySize)
+	serviceRegistry := registry.MakeEtcdRegistry(etcdClient, minionRegistry)
+	serviceRegistry.SetWatchReplaySize(c.WatchReplaySize)
 	m := &Master{
-		podRegistry:             registry.MakeEtcdRegistry(etcdClient, minionRegistry),
-		controllerRegistry:      registry.MakeEtcdRegistry(etcdClient, minionRegistry),
-		serviceRegistry:         registry.MakeEtcdRegistry(etcdClient, minionRegistry),
+		podRegistry:             podRegistry,
+		controllerRegistry:      controllerRegistry,
+		serviceRegistry:         serviceRegistry,
+		endpointsRegistry:       serviceRegistry,
 		minionRegistry:          minionRegistry,
 		buildRegistry:           build.MakeEtcdRegistry(etcdClient),
 		buildConfigRegistry:     buildconfig.MakeEtcdRegistry(etcdClient),
 		imageRegistry:           image.MakeMemoryRegistry(),
 		imageRepositoryRegistry: image.MakeMemoryRegistry(),
+		eventRegistry:           registry.MakeEtcdEventRegistry(etcdClient, c.EventTTL),
+		listCacheTTL:            c.ListCacheTTL,
+		asyncOpWait:             asyncOpWait(c),
+		maxAsyncOpWait:          maxAsyncOpWait(c),
+		slowRequestThreshold:    slowRequestThreshold(c),
+		bodyReadTimeout:         bodyReadTimeout(c),
 		client:                  c.Client,
+		ops:                     apiserver.NewOperations(),
+		admission:               c.Admission,
+		legacyIDs:               c.LegacyIDs,
+		lenientParams:           c.LenientParams,
+		invalidBodyLogLimit:     c.InvalidBodyLogLimit,
+		watchBufferSize:         c.WatchBufferSize,
+		watchBufferPolicy:       c.WatchBufferPolicy,
+		handlers:                handlers(c),
 	}
+	if c.OperationTTL > 0 {
+		m.ops = apiserver.NewPersistentOperations(apiserver.NewEtcdOperationStore(etcdClient, api.Codec, c.OperationTTL))
+	}
+	m.handlers.InstrumentedStorage = etcdClient
+	m.healthChecks = append(m.baseHealthChecks(), healthz.Check{Name: "etcd", Check: etcdProbe(rawEtcdClient)})
+	m.healthChecks = append(m.healthChecks, c.HealthChecks...)
 	m.init(c.Cloud, c.PodInfoGetter)
 	return m
 }
 
+// minionHealthTimeout bounds each health check of a minion.
+const minionHealthTimeout = 5 * time.Second
+
+// minionRegistryMaker returns a registry of the minions in c.Minions, and of the cloud
+// instances matching c.MinionRegexp, refreshed every c.MinionCacheTTL.
 func minionRegistryMaker(c *Config) registry.MinionRegistry {
-	var minionRegistry registry.MinionRegistry
+	var nodes registry.CloudNodes
 	if c.Cloud != nil && len(c.MinionRegexp) > 0 {
-		var err error
-		minionRegistry, err = registry.MakeCloudMinionRegistry(c.Cloud, c.MinionRegexp)
-		if err != nil {
-			glog.Errorf("Failed to initalize cloud minion registry reverting to static registry (%#v)", err)
+		instances, ok := c.Cloud.Instances()
+		if ok {
+			nodes = instances
+		} else {
+			glog.Errorf("Cloud doesn't support instances, using the static minions only")
 		}
 	}
-	if minionRegistry == nil {
-		minionRegistry = registry.MakeMinionRegistry(c.Minions)
-	}
+	var probe registry.MinionProber
 	if c.HealthCheckMinions {
-		minionRegistry = registry.NewHealthyMinionRegistry(minionRegistry, &http.Client{})
+		probe = registry.NewHTTPMinionProber(&http.Client{Timeout: minionHealthTimeout})
 	}
-	if c.MinionCacheTTL > 0 {
-		cachingMinionRegistry, err := registry.NewCachingMinionRegistry(minionRegistry, c.MinionCacheTTL)
-		if err != nil {
-			glog.Errorf("Failed to initialize caching layer, ignoring cache.")
-		} else {
-			minionRegistry = cachingMinionRegistry
-		}
-	}
-	return minionRegistry
+	return registry.NewRefreshingMinionRegistry(c.Minions, nodes, c.MinionRegexp, probe, c.MinionCacheTTL)
 }
 
 func (m *Master) init(cloud cloudprovider.Interface, podInfoGetter client.PodInfoGetter) {
@@ -132,35 +251,233 @@ func (m *Master) init(cloud cloudprovider.Interface, podInfoGetter client.PodInf
 
 	random := rand.New(rand.NewSource(int64(time.Now().Nanosecond())))
 	s := scheduler.NewRandomFitScheduler(m.podRegistry, random)
+	pods := registry.MakePodRegistryStorage(m.podRegistry, podInfoGetter, s, m.minionRegistry, cloud, podCache)
 	m.storage = map[string]apiserver.RESTStorage{
-		"pods": registry.MakePodRegistryStorage(m.podRegistry, podInfoGetter, s, m.minionRegistry, cloud, podCache),
+		"pods":                   pods,
+		"podSummary":             registry.NewPodSummaryStorage(pods.(apiserver.Lister)),
 		"replicationControllers": registry.NewControllerRegistryStorage(m.controllerRegistry, m.podRegistry),
-		"services":               registry.MakeServiceRegistryStorage(m.serviceRegistry, cloud, m.minionRegistry),
-		"minions":                registry.MakeMinionRegistryStorage(m.minionRegistry),
+		"services":               registry.MakeServiceRegistryStorage(m.serviceRegistry, cloud, m.minionRegistry, pods.(apiserver.Lister)),
+		"endpoints":              registry.NewEndpointsRegistryStorage(m.endpointsRegistry),
+		"minions":                registry.MakeMinionRegistryStorage(m.minionRegistry, pods.(apiserver.Lister)),
 		"bindings":               registry.MakeBindingStorage(m.podRegistry),
 		"images":                 image.NewImageRegistryStorage(m.imageRegistry),
 		"imageRepositories":      image.NewImageRepositoryRegistryStorage(m.imageRepositoryRegistry, m.imageRegistry),
 		"imagesByRepository":     image.NewImagesByRepositoryRegistryStorage(m.imageRepositoryRegistry, m.imageRegistry),
 		"builds":                 build.NewBuildRegistryStorage(m.buildRegistry),
 		"buildConfigs":           buildconfig.NewBuildConfigRegistryStorage(m.buildConfigRegistry),
+		"events":                 registry.NewEventRegistryStorage(m.eventRegistry),
 	}
 }
 
 // Run begins serving the Kubernetes API. It never returns.
 func (m *Master) Run(myAddress, apiPrefix string) error {
-	s := &http.Server{
-		Addr:           myAddress,
-		Handler:        m.ConstructHandler(apiPrefix),
+	return m.Serve(&ServingOptions{Address: myAddress}, apiPrefix)
+}
+
+// ServingOptions configures the ports Master.Serve serves the API on.
+type ServingOptions struct {
+	// Address is the address of the plain HTTP port. If empty, the API is only served over
+	// HTTPS.
+	Address string
+	// TLSAddress is the address of the HTTPS port, which presents the certificate in
+	// CertFile with the key in KeyFile. If empty, the API is only served over plain HTTP.
+	TLSAddress string
+	CertFile   string
+	KeyFile    string
+	// If set, clients of the HTTPS port must present a certificate signed by one of the
+	// certificate authorities in ClientCAFile.
+	ClientCAFile string
+	// CertReloadPeriod is how often the certificate files are checked for changes. If not
+	// positive, the certificate is loaded once.
+	CertReloadPeriod time.Duration
+	// If RedirectToTLS is set, the plain HTTP port redirects requests to the HTTPS port
+	// instead of serving them.
+	RedirectToTLS bool
+}
+
+// Serve serves the API on the ports of o, the plain HTTP and HTTPS ports side by side if
+// both are set, until one of them fails.
+func (m *Master) Serve(o *ServingOptions, apiPrefix string) error {
+	if len(o.Address) == 0 && len(o.TLSAddress) == 0 {
+		return fmt.Errorf("no address to serve the API on")
+	}
+	handler := m.ConstructHandler(apiPrefix)
+	errs := make(chan error, 2)
+	if len(o.TLSAddress) > 0 {
+		certs, err := apiserver.NewCertificateReloader(o.CertFile, o.KeyFile)
+		if err != nil {
+			return err
+		}
+		config, err := apiserver.NewServerTLSConfig(certs, o.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		if o.CertReloadPeriod > 0 {
+			go certs.Watch(o.CertReloadPeriod)
+		}
+		s := newHTTPServer(o.TLSAddress, handler)
+		s.TLSConfig = config
+		go func() {
+			// The certificate comes from the TLS configuration.
+			errs <- s.ListenAndServeTLS("", "")
+		}()
+	}
+	if len(o.Address) > 0 {
+		plain := handler
+		if o.RedirectToTLS && len(o.TLSAddress) > 0 {
+			redirect, err := apiserver.RedirectToTLS(o.TLSAddress)
+			if err != nil {
+				return err
+			}
+			plain = redirect
+		}
+		go func() {
+			errs <- newHTTPServer(o.Address, plain).ListenAndServe()
+		}()
+	}
+	return <-errs
+}
+
+// newHTTPServer returns a server of handler on address.
+func newHTTPServer(address string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           address,
+		Handler:        handler,
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
 	}
-	return s.ListenAndServe()
+}
+
+// asyncOpWait returns how long requests configured by c wait for their operations.
+func asyncOpWait(c *Config) time.Duration {
+	switch {
+	case c.AsyncOpWait == 0:
+		return apiserver.DefaultAsyncOpWait
+	case c.AsyncOpWait < 0:
+		return 0
+	}
+	return c.AsyncOpWait
+}
+
+// maxAsyncOpWait returns the longest requests configured by c may wait for their operations.
+func maxAsyncOpWait(c *Config) time.Duration {
+	if c.MaxAsyncOpWait <= 0 {
+		return apiserver.DefaultMaxAsyncOpWait
+	}
+	return c.MaxAsyncOpWait
+}
+
+// slowRequestThreshold returns how long requests configured by c may take before they are
+// logged.
+func slowRequestThreshold(c *Config) time.Duration {
+	if c.SlowRequestThreshold == 0 {
+		return apiserver.DefaultSlowRequestThreshold
+	}
+	return c.SlowRequestThreshold
+}
+
+// bodyReadTimeout returns how long clients of the master configured by c have to send the
+// bodies of requests.
+func bodyReadTimeout(c *Config) time.Duration {
+	if c.BodyReadTimeout == 0 {
+		return apiserver.DefaultBodyReadTimeout
+	}
+	return c.BodyReadTimeout
+}
+
+// handlers returns the apiserver.Config of the handlers the master configured by c serves.
+func handlers(c *Config) apiserver.Config {
+	if c.Handlers == nil {
+		return apiserver.DefaultConfig()
+	}
+	return *c.Handlers
+}
+
+// etcdProbeKey is the key etcdProbe writes, and etcdProbeTTL the seconds a probe outlives a
+// check that fails before deleting it.
+const (
+	etcdProbeKey = "/registry/healthz/probe"
+	etcdProbeTTL = 60
+)
+
+// etcdProbe returns a check that a value written to etcd through client can be read back.
+func etcdProbe(client tools.EtcdGetSet) func() error {
+	return func() error {
+		value := strconv.FormatInt(time.Now().UnixNano(), 10)
+		if _, err := client.Set(etcdProbeKey, value, etcdProbeTTL); err != nil {
+			return err
+		}
+		response, err := client.Get(etcdProbeKey, false, false)
+		if err != nil {
+			return err
+		}
+		if response.Node == nil || response.Node.Value != value {
+			return fmt.Errorf("read back a different probe than was written to %s", etcdProbeKey)
+		}
+		_, err = client.Delete(etcdProbeKey, false)
+		return err
+	}
+}
+
+// baseHealthChecks returns the checks of the dependencies every master has.
+func (m *Master) baseHealthChecks() []healthz.Check {
+	return []healthz.Check{
+		{Name: "minions", Check: func() error {
+			_, err := m.minionRegistry.List()
+			return err
+		}},
+	}
+}
+
+// HealthChecks returns the checks of the dependencies of m, which /healthz runs.
+func (m *Master) HealthChecks() []healthz.Check {
+	return m.healthChecks
+}
+
+// Validate constructs the handler of the API under apiPrefix without serving it, runs the
+// HealthChecks of m once, and writes their report to w. It returns true if they all pass.
+func (m *Master) Validate(apiPrefix string, w io.Writer) bool {
+	m.ConstructHandler(apiPrefix)
+	results := healthz.Run(m.healthChecks)
+	healthz.WriteReport(w, results)
+	return healthz.Healthy(results)
 }
 
 // ConstructHandler returns an http.Handler which serves the Kubernetes API.
 // Instead of calling Run, you can call this function to get a handler for your own server.
 // It is intended for testing. Only call once.
 func (m *Master) ConstructHandler(apiPrefix string) http.Handler {
-	return apiserver.New(m.storage, api.Codec, apiPrefix)
+	handlers := m.handlers
+	handlers.Operations = m.ops
+	handlers.HealthChecks = append(append([]healthz.Check{}, handlers.HealthChecks...), m.healthChecks...)
+	s := apiserver.NewWithConfig(m.storage, api.Codec, apiPrefix, handlers, m.admission...)
+	s.SetEventRecorder(apiserver.NewEventRecorder(m.eventRegistry, "apiserver"))
+	s.AddCodec(api.GobMediaType, api.GobCodec)
+	s.SetListCacheTTL(m.listCacheTTL)
+	s.SetAsyncOpWait(m.asyncOpWait, m.maxAsyncOpWait)
+	s.SetSlowRequestThreshold(m.slowRequestThreshold)
+	s.SetBodyReadTimeout(m.bodyReadTimeout)
+	s.SetStrictParams(!m.lenientParams)
+	s.SetInvalidBodyLogLimit(m.invalidBodyLogLimit)
+	policy := m.watchBufferPolicy
+	if len(policy) == 0 {
+		policy = apiserver.WatchBufferDrop
+	}
+	if err := s.SetWatchBuffer(m.watchBufferSize, policy); err != nil {
+		glog.Errorf("Ignoring the watch buffer policy: %v", err)
+	}
+	s.SetListCacheDependency("pods", "podSummary")
+	s.SetListCacheDependency("bindings", "pods", "podSummary")
+	for _, legacy := range m.legacyIDs {
+		parts := strings.SplitN(legacy, "/", 2)
+		if len(parts) != 2 {
+			glog.Errorf("Ignoring legacy ID %q, which is not of the form resource/id", legacy)
+			continue
+		}
+		s.AllowLegacyID(parts[0], parts[1])
+	}
+	var handler http.Handler = s
+	handler = build.NewLogHandler(apiPrefix, handler, m.buildRegistry, m.podRegistry)
+	return webhook.NewHandler(apiPrefix, handler, m.buildConfigRegistry, m.storage["builds"].(apiserver.Creater))
 }
@@ -0,0 +1,61 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package master
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/healthz"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+)
+
+func TestEtcdProbe(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	probe := etcdProbe(fakeClient)
+	if err := probe(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(fakeClient.DeletedKeys) != 1 || fakeClient.DeletedKeys[0] != etcdProbeKey {
+		t.Errorf("expected the probe to be deleted, got %v", fakeClient.DeletedKeys)
+	}
+	fakeClient.Err = errors.New("unreachable")
+	if err := probe(); err == nil {
+		t.Errorf("expected an unreachable etcd to fail the probe")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m := NewMemoryServer(&Config{
+		Minions:      []string{"minion-1"},
+		HealthChecks: []healthz.Check{{Name: "logs", Check: func() error { return errors.New("not writable") }}},
+	})
+	out := &bytes.Buffer{}
+	if m.Validate("/api/v1beta1", out) {
+		t.Errorf("expected a failing check to fail validation")
+	}
+	if expected := "ok minions\nFAILED logs: not writable\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	out.Reset()
+	if !NewMemoryServer(&Config{Minions: []string{"minion-1"}}).Validate("/api/v1beta1", out) || !strings.HasPrefix(out.String(), "ok minions\n") {
+		t.Errorf("expected validation to pass, got %q", out.String())
+	}
+}
@@ -80,7 +80,7 @@ func (p *PodCache) UpdateAllContainers() {
 		return
 	}
 	for _, pod := range pods {
-		err := p.updatePodInfo(pod.CurrentState.Host, pod.ID)
+		err := p.updatePodInfo(pod.CurrentState.Host, registry.PodManifestID(pod.Namespace, pod.ID))
 		if err != nil && err != client.ErrPodInfoNotAvailable {
 			glog.Errorf("Error synchronizing container: %v", err)
 		}
@@ -133,6 +133,7 @@ func (s ConfigSourceEtcd) GetServices() ([]api.Service, []api.Endpoints, error)
 				glog.Errorf("Failed to load Service: %s (%#v)", node.Value, err)
 				continue
 			}
+			svc.ID = api.QualifiedID(svc.Namespace, svc.ID)
 			retServices[i] = svc
 			endpoints, err := s.GetEndpoints(svc.ID)
 			if err != nil {
@@ -157,10 +158,13 @@ func (s ConfigSourceEtcd) GetEndpoints(service string) (api.Endpoints, error) {
 	// Parse all the endpoint specifications in this value.
 	var e api.Endpoints
 	err = api.DecodeInto([]byte(response.Node.Value), &e)
+	e.ID = api.QualifiedID(e.Namespace, e.ID)
 	return e, err
 }
 
 // etcdResponseToService takes an etcd response and pulls it apart to find service.
+// The service ID is qualified with its namespace, so it matches the etcd key the
+// proxy sees when the service is deleted.
 func etcdResponseToService(response *etcd.Response) (*api.Service, error) {
 	if response.Node == nil {
 		return nil, fmt.Errorf("invalid response from etcd: %#v", response)
@@ -170,6 +174,7 @@ func etcdResponseToService(response *etcd.Response) (*api.Service, error) {
 	if err != nil {
 		return nil, err
 	}
+	svc.ID = api.QualifiedID(svc.Namespace, svc.ID)
 	return &svc, err
 }
 
@@ -222,6 +227,7 @@ func (s ConfigSourceEtcd) ProcessEndpointResponse(response *etcd.Response) {
 		glog.Errorf("Failed to parse service out of etcd key: %v : %+v", response.Node.Value, err)
 		return
 	}
+	endpoints.ID = api.QualifiedID(endpoints.Namespace, endpoints.ID)
 	endpointsUpdate := EndpointsUpdate{Op: ADD, Endpoints: []api.Endpoints{endpoints}}
 	s.endpointsChannel <- endpointsUpdate
 }
@@ -20,13 +20,11 @@ import (
 	"fmt"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
-	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 )
 
-// BindingStorage implements the RESTStorage interface. When bindings are written, it
-// changes the location of the affected pods. This information is eventually reflected
-// in the pod's CurrentState.Host field.
+// BindingStorage implements the RESTStorage and Creater interfaces. When bindings are
+// written, it changes the location of the affected pods. This information is eventually
+// reflected in the pod's CurrentState.Host field. Bindings are write-only objects.
 type BindingStorage struct {
 	podRegistry PodRegistry
 }
@@ -38,28 +36,13 @@ func MakeBindingStorage(podRegistry PodRegistry) *BindingStorage {
 	}
 }
 
-// List returns an error because bindings are write-only objects.
-func (*BindingStorage) List(selector labels.Selector) (interface{}, error) {
-	return nil, apiserver.NewNotFoundErr("binding", "list")
-}
-
-// Get returns an error because bindings are write-only objects.
-func (*BindingStorage) Get(id string) (interface{}, error) {
-	return nil, apiserver.NewNotFoundErr("binding", id)
-}
-
-// Delete returns an error because bindings are write-only objects.
-func (*BindingStorage) Delete(id string) (<-chan interface{}, error) {
-	return nil, apiserver.NewNotFoundErr("binding", id)
-}
-
 // New returns a new binding object fit for having data unmarshalled into it.
 func (*BindingStorage) New() interface{} {
 	return &api.Binding{}
 }
 
 // Create attempts to make the assignment indicated by the binding it recieves.
-func (b *BindingStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (b *BindingStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	binding, ok := obj.(*api.Binding)
 	if !ok {
 		return nil, fmt.Errorf("incorrect type: %#v", obj)
@@ -67,8 +50,3 @@ func (b *BindingStorage) Create(obj interface{}) (<-chan interface{}, error) {
 	_ = binding
 	return nil, fmt.Errorf("Implementation coming in the future. Storage layer can't easily support this yet.")
 }
-
-// Update returns an error-- this object may not be updated.
-func (b *BindingStorage) Update(obj interface{}) (<-chan interface{}, error) {
-	return nil, fmt.Errorf("Bindings may not be changed.")
-}
@@ -18,6 +18,7 @@ package registry
 
 import (
 	"fmt"
+	"reflect"
 	"time"
 
 	"code.google.com/p/go-uuid/uuid"
@@ -33,18 +34,21 @@ type ControllerRegistryStorage struct {
 	podRegistry PodRegistry
 	// Period in between polls when waiting for a controller to complete
 	pollPeriod time.Duration
+	// objectLabels serves the labels of controllers, if registry is a LabelRegistry.
+	objectLabels *labelsSubresource
 }
 
 func NewControllerRegistryStorage(registry ControllerRegistry, podRegistry PodRegistry) apiserver.RESTStorage {
 	return &ControllerRegistryStorage{
-		registry:    registry,
-		podRegistry: podRegistry,
-		pollPeriod:  time.Second * 10,
+		registry:     registry,
+		podRegistry:  podRegistry,
+		pollPeriod:   time.Second * 10,
+		objectLabels: makeLabelsSubresource("replicationController", registry),
 	}
 }
 
 // List obtains a list of ReplicationControllers that match selector.
-func (storage *ControllerRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (storage *ControllerRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	result := api.ReplicationControllerList{}
 	controllers, err := storage.registry.ListControllers()
 	if err == nil {
@@ -58,8 +62,8 @@ func (storage *ControllerRegistryStorage) List(selector labels.Selector) (interf
 }
 
 // Get obtains the ReplicationController specified by its id.
-func (storage *ControllerRegistryStorage) Get(id string) (interface{}, error) {
-	controller, err := storage.registry.GetController(id)
+func (storage *ControllerRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	controller, err := storage.registry.GetController(api.QualifiedID(ctx.Namespace, id))
 	if err != nil {
 		return nil, err
 	}
@@ -67,9 +71,9 @@ func (storage *ControllerRegistryStorage) Get(id string) (interface{}, error) {
 }
 
 // Delete asynchronously deletes the ReplicationController specified by its id.
-func (storage *ControllerRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (storage *ControllerRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	return apiserver.MakeAsync(func() (interface{}, error) {
-		return &api.Status{Status: api.StatusSuccess}, storage.registry.DeleteController(id)
+		return &api.Status{Status: api.StatusSuccess}, storage.registry.DeleteController(api.QualifiedID(ctx.Namespace, id))
 	}), nil
 }
 
@@ -78,22 +82,29 @@ func (storage *ControllerRegistryStorage) New() interface{} {
 	return &api.ReplicationController{}
 }
 
-// Create registers a given new ReplicationController instance to storage.registry.
-func (storage *ControllerRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+// Default implements apiserver.Defaulter. A controller without an ID is given a new UUID.
+func (storage *ControllerRegistryStorage) Default(ctx api.Context, obj interface{}) {
 	controller, ok := obj.(*api.ReplicationController)
 	if !ok {
-		return nil, fmt.Errorf("not a replication controller: %#v", obj)
+		return
 	}
 	if len(controller.ID) == 0 {
 		controller.ID = uuid.NewUUID().String()
 	}
 	// Pod Manifest ID should be assigned by the pod API
 	controller.DesiredState.PodTemplate.DesiredState.Manifest.ID = ""
+}
 
-	if errs := api.ValidateReplicationController(controller); len(errs) > 0 {
-		return nil, fmt.Errorf("Validation errors: %v", errs)
+// Create registers a given new ReplicationController instance to storage.registry.
+func (storage *ControllerRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	controller, ok := obj.(*api.ReplicationController)
+	if !ok {
+		return nil, fmt.Errorf("not a replication controller: %#v", obj)
+	}
+	storage.Default(ctx, controller)
+	if err := storage.checkSelectorConflicts(ctx, controller); err != nil {
+		return nil, err
 	}
-
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		err := storage.registry.CreateController(*controller)
 		if err != nil {
@@ -104,13 +115,13 @@ func (storage *ControllerRegistryStorage) Create(obj interface{}) (<-chan interf
 }
 
 // Update replaces a given ReplicationController instance with an existing instance in storage.registry.
-func (storage *ControllerRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (storage *ControllerRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	controller, ok := obj.(*api.ReplicationController)
 	if !ok {
 		return nil, fmt.Errorf("not a replication controller: %#v", obj)
 	}
-	if errs := api.ValidateReplicationController(controller); len(errs) > 0 {
-		return nil, fmt.Errorf("Validation errors: %v", errs)
+	if err := storage.checkSelectorConflicts(ctx, controller); err != nil {
+		return nil, err
 	}
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		err := storage.registry.UpdateController(*controller)
@@ -121,6 +132,54 @@ func (storage *ControllerRegistryStorage) Update(obj interface{}) (<-chan interf
 	}), nil
 }
 
+// checkSelectorConflicts returns a conflict error naming another controller in the namespace
+// of controller whose replica selector selects some of the same pods, since the two would
+// fight over those pods, each deleting the ones the other creates. Overlapping selectors are
+// allowed if ctx.Force is set, but identical ones never are.
+func (storage *ControllerRegistryStorage) checkSelectorConflicts(ctx api.Context, controller *api.ReplicationController) error {
+	controllers, err := storage.registry.ListControllers()
+	if err != nil {
+		return err
+	}
+	selector := labels.Set(controller.DesiredState.ReplicaSelector)
+	namespace := namespaceOf(controller.JSONBase)
+	for _, other := range controllers {
+		if other.ID == controller.ID || namespaceOf(other.JSONBase) != namespace {
+			continue
+		}
+		otherSelector := labels.Set(other.DesiredState.ReplicaSelector)
+		if reflect.DeepEqual(selector, otherSelector) {
+			return apiserver.NewFieldConflictErr("replicationController", controller.ID, "desiredState.replicaSelector", other.ID,
+				fmt.Errorf("both have the replica selector %q", selector))
+		}
+		if !ctx.Force && selectorsOverlap(selector, otherSelector) {
+			return apiserver.NewFieldConflictErr("replicationController", controller.ID, "desiredState.replicaSelector", other.ID,
+				fmt.Errorf("the replica selectors %q and %q select the same pods; pass force=true to allow it", selector, otherSelector))
+		}
+	}
+	return nil
+}
+
+// selectorsOverlap returns true if some pod's labels match both a and b, which is the case
+// unless they require different values of the same label.
+func selectorsOverlap(a, b labels.Set) bool {
+	for label, value := range a {
+		if otherValue, ok := b[label]; ok && otherValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// namespaceOf returns the namespace of the object with jsonBase, which is the default
+// namespace if it has none.
+func namespaceOf(jsonBase api.JSONBase) string {
+	if len(jsonBase.Namespace) == 0 {
+		return api.NamespaceDefault
+	}
+	return jsonBase.Namespace
+}
+
 func (storage *ControllerRegistryStorage) waitForController(ctrl api.ReplicationController) (interface{}, error) {
 	for {
 		pods, err := storage.podRegistry.ListPods(labels.Set(ctrl.DesiredState.ReplicaSelector).AsSelector())
@@ -137,6 +196,62 @@ func (storage *ControllerRegistryStorage) waitForController(ctrl api.Replication
 
 // WatchAll returns ReplicationController events via a watch.Interface, implementing
 // apiserver.ResourceWatcher.
-func (storage *ControllerRegistryStorage) Watch(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+func (storage *ControllerRegistryStorage) Watch(ctx api.Context, label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
 	return storage.registry.WatchControllers(label, field, resourceVersion)
 }
+
+// Subresources implements apiserver.SubresourceStorage. The status of a controller is its
+// current state, which the replication manager records without touching its desired state.
+// The labels of a controller can be changed without the rest of it.
+func (storage *ControllerRegistryStorage) Subresources() []string {
+	if storage.objectLabels == nil {
+		return []string{"status"}
+	}
+	return []string{"status", "labels"}
+}
+
+func (storage *ControllerRegistryStorage) NewSubresource(subresource string) interface{} {
+	if subresource == "labels" {
+		return &api.ObjectLabels{}
+	}
+	return &api.ReplicationController{}
+}
+
+func (storage *ControllerRegistryStorage) GetSubresource(ctx api.Context, id, subresource string) (interface{}, error) {
+	if subresource == "labels" {
+		return storage.objectLabels.get(ctx, id)
+	}
+	return storage.Get(ctx, id)
+}
+
+// UpdateSubresource copies the current state of the controller it receives onto the
+// controller with the given id. The desired state of the existing controller is kept,
+// whatever the received controller says, even if it is changed meanwhile.
+func (storage *ControllerRegistryStorage) UpdateSubresource(ctx api.Context, id, subresource string, obj interface{}) (<-chan interface{}, error) {
+	if subresource == "labels" {
+		return storage.objectLabels.update(ctx, id, obj)
+	}
+	status, ok := obj.(*api.ReplicationController)
+	if !ok {
+		return nil, fmt.Errorf("not a replication controller: %#v", obj)
+	}
+	if status.CurrentState.Replicas < 0 {
+		return nil, apiserver.NewInvalidErr("replicationController", id, api.ValidationErrorList{
+			api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "currentState.replicas", BadValue: status.CurrentState.Replicas},
+		})
+	}
+	return apiserver.MakeAsync(func() (interface{}, error) {
+		return storage.registry.UpdateControllerStatus(api.QualifiedID(ctx.Namespace, id), func(api.ReplicationController) (api.ReplicationControllerState, error) {
+			return api.ReplicationControllerState{Replicas: status.CurrentState.Replicas}, nil
+		})
+	}), nil
+}
+
+// PatchSubresource implements apiserver.SubresourcePatcher. Only the labels of a controller
+// can be patched.
+func (storage *ControllerRegistryStorage) PatchSubresource(ctx api.Context, id, subresource string, patch apiserver.MergePatch) (<-chan interface{}, error) {
+	if subresource != "labels" {
+		return nil, apiserver.NewMethodNotSupported("replicationController", "patch "+subresource)
+	}
+	return storage.objectLabels.patch(ctx, id, patch)
+}
@@ -20,11 +20,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
@@ -51,6 +54,10 @@ func (registry *MockControllerRegistry) UpdateController(controller api.Replicat
 	return registry.err
 }
 
+func (registry *MockControllerRegistry) UpdateControllerStatus(ID string, update ControllerStatusUpdateFunc) (*api.ReplicationController, error) {
+	return &api.ReplicationController{}, registry.err
+}
+
 func (registry *MockControllerRegistry) DeleteController(ID string) error {
 	return registry.err
 }
@@ -66,7 +73,7 @@ func TestListControllersError(t *testing.T) {
 	storage := ControllerRegistryStorage{
 		registry: &mockRegistry,
 	}
-	controllersObj, err := storage.List(nil)
+	controllersObj, err := storage.List(api.NewContext(), nil)
 	controllers := controllersObj.(api.ReplicationControllerList)
 	if err != mockRegistry.err {
 		t.Errorf("Expected %#v, Got %#v", mockRegistry.err, err)
@@ -81,7 +88,7 @@ func TestListEmptyControllerList(t *testing.T) {
 	storage := ControllerRegistryStorage{
 		registry: &mockRegistry,
 	}
-	controllers, err := storage.List(labels.Everything())
+	controllers, err := storage.List(api.NewContext(), labels.Everything())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -109,7 +116,7 @@ func TestListControllerList(t *testing.T) {
 	storage := ControllerRegistryStorage{
 		registry: &mockRegistry,
 	}
-	controllersObj, err := storage.List(labels.Everything())
+	controllersObj, err := storage.List(api.NewContext(), labels.Everything())
 	controllers := controllersObj.(api.ReplicationControllerList)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -260,7 +267,7 @@ func TestCreateController(t *testing.T) {
 			PodTemplate:     validPodTemplate,
 		},
 	}
-	channel, err := storage.Create(controller)
+	channel, err := storage.Create(api.NewContext(), controller)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
 	}
@@ -298,38 +305,43 @@ func TestCreateController(t *testing.T) {
 
 func TestControllerStorageValidatesCreate(t *testing.T) {
 	mockRegistry := MockControllerRegistry{}
-	storage := ControllerRegistryStorage{
+	storage := &ControllerRegistryStorage{
 		registry:    &mockRegistry,
 		podRegistry: nil,
 		pollPeriod:  time.Millisecond * 1,
 	}
 
 	failureCases := map[string]api.ReplicationController{
-		"empty ID": {
-			JSONBase: api.JSONBase{ID: ""},
-			DesiredState: api.ReplicationControllerState{
-				ReplicaSelector: map[string]string{"bar": "baz"},
-			},
-		},
 		"empty selector": {
 			JSONBase:     api.JSONBase{ID: "abc"},
 			DesiredState: api.ReplicationControllerState{},
 		},
 	}
-	for _, failureCase := range failureCases {
-		c, err := storage.Create(&failureCase)
-		if c != nil {
-			t.Errorf("Expected nil channel")
-		}
-		if err == nil {
-			t.Errorf("Expected to get an error")
+	for name, failureCase := range failureCases {
+		if code := serveStorage(t, "replicationControllers", storage, "POST", "replicationControllers?dryRun=true", &failureCase); code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected %d, got %d", name, http.StatusUnprocessableEntity, code)
 		}
 	}
+
+	// Controllers without an ID are given one before they are validated.
+	controller := api.ReplicationController{
+		DesiredState: api.ReplicationControllerState{
+			ReplicaSelector: map[string]string{"bar": "baz"},
+			PodTemplate: api.PodTemplate{
+				DesiredState: api.PodState{
+					Manifest: api.ContainerManifest{Version: "v1beta1"},
+				},
+			},
+		},
+	}
+	if code := serveStorage(t, "replicationControllers", storage, "POST", "replicationControllers?dryRun=true", &controller); code != http.StatusOK {
+		t.Errorf("empty ID: expected %d, got %d", http.StatusOK, code)
+	}
 }
 
 func TestControllerStorageValidatesUpdate(t *testing.T) {
 	mockRegistry := MockControllerRegistry{}
-	storage := ControllerRegistryStorage{
+	storage := &ControllerRegistryStorage{
 		registry:    &mockRegistry,
 		podRegistry: nil,
 		pollPeriod:  time.Millisecond * 1,
@@ -347,13 +359,103 @@ func TestControllerStorageValidatesUpdate(t *testing.T) {
 			DesiredState: api.ReplicationControllerState{},
 		},
 	}
-	for _, failureCase := range failureCases {
-		c, err := storage.Update(&failureCase)
-		if c != nil {
-			t.Errorf("Expected nil channel")
+	for name, failureCase := range failureCases {
+		if code := serveStorage(t, "replicationControllers", storage, "PUT", "replicationControllers/abc?dryRun=true", &failureCase); code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected %d, got %d", name, http.StatusUnprocessableEntity, code)
+		}
+	}
+}
+
+func TestControllerSelectorConflicts(t *testing.T) {
+	mockRegistry := MockControllerRegistry{
+		controllers: []api.ReplicationController{
+			{
+				JSONBase:     api.JSONBase{ID: "web"},
+				DesiredState: api.ReplicationControllerState{ReplicaSelector: map[string]string{"name": "web"}},
+			},
+			{
+				JSONBase:     api.JSONBase{ID: "api", Namespace: "other"},
+				DesiredState: api.ReplicationControllerState{ReplicaSelector: map[string]string{"name": "api"}},
+			},
+		},
+	}
+	storage := &ControllerRegistryStorage{
+		registry:    &mockRegistry,
+		podRegistry: &MockPodRegistry{},
+		pollPeriod:  time.Millisecond * 1,
+	}
+	table := []struct {
+		method   string
+		path     string
+		id       string
+		selector map[string]string
+		code     int
+	}{
+		{"POST", "replicationControllers", "copy", map[string]string{"name": "web"}, http.StatusConflict},
+		{"POST", "replicationControllers?force=true", "copy", map[string]string{"name": "web"}, http.StatusConflict},
+		{"POST", "replicationControllers", "front", map[string]string{"name": "web", "tier": "front"}, http.StatusConflict},
+		{"POST", "replicationControllers", "all", map[string]string{"tier": "front"}, http.StatusConflict},
+		{"POST", "replicationControllers?force=true", "front", map[string]string{"name": "web", "tier": "front"}, http.StatusCreated},
+		{"POST", "replicationControllers", "db", map[string]string{"name": "db"}, http.StatusCreated},
+		{"POST", "replicationControllers", "api", map[string]string{"name": "api"}, http.StatusCreated},
+		{"PUT", "replicationControllers/web", "web", map[string]string{"name": "web"}, http.StatusOK},
+		{"PUT", "replicationControllers/db", "db", map[string]string{"name": "web", "tier": "db"}, http.StatusConflict},
+	}
+	for _, item := range table {
+		controller := api.ReplicationController{
+			JSONBase: api.JSONBase{ID: item.id},
+			DesiredState: api.ReplicationControllerState{
+				ReplicaSelector: item.selector,
+				PodTemplate:     validPodTemplate,
+			},
+		}
+		path := item.path + "?sync=true"
+		if strings.Contains(item.path, "?") {
+			path = item.path + "&sync=true"
 		}
-		if err == nil {
-			t.Errorf("Expected to get an error")
+		if code := serveStorage(t, "replicationControllers", storage, item.method, path, &controller); code != item.code {
+			t.Errorf("%s %s %v: expected %d, got %d", item.method, item.path, item.selector, item.code, code)
 		}
 	}
+
+	controller := &api.ReplicationController{
+		JSONBase:     api.JSONBase{ID: "front"},
+		DesiredState: api.ReplicationControllerState{ReplicaSelector: map[string]string{"name": "web", "tier": "front"}},
+	}
+	_, err := storage.Create(api.NewDefaultContext(), controller)
+	if !apiserver.IsConflict(err) || !strings.Contains(err.Error(), `"web"`) {
+		t.Errorf("expected a conflict naming the controller, got %v", err)
+	}
+}
+
+func TestControllerStatusSubresource(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateController(api.ReplicationController{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		DesiredState: api.ReplicationControllerState{Replicas: 5, ReplicaSelector: map[string]string{"a": "b"}},
+	})
+	storage := NewControllerRegistryStorage(registry, nil).(*ControllerRegistryStorage)
+
+	channel, err := storage.UpdateSubresource(api.NewContext(), "foo", "status", &api.ReplicationController{
+		DesiredState: api.ReplicationControllerState{Replicas: 1},
+		CurrentState: api.ReplicationControllerState{Replicas: 3, ReplicaSelector: map[string]string{"c": "d"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-channel
+	controller, _ := registry.GetController("foo")
+	if controller.CurrentState.Replicas != 3 || controller.CurrentState.ReplicaSelector != nil {
+		t.Errorf("expected the replicas to be recorded, got %#v", controller.CurrentState)
+	}
+	if controller.DesiredState.Replicas != 5 || controller.DesiredState.ReplicaSelector["a"] != "b" {
+		t.Errorf("expected the desired state to be kept, got %#v", controller.DesiredState)
+	}
+
+	if _, err := storage.UpdateSubresource(api.NewContext(), "foo", "status", &api.ReplicationController{CurrentState: api.ReplicationControllerState{Replicas: -1}}); !apiserver.IsInvalid(err) {
+		t.Errorf("expected negative replicas to be invalid, got %v", err)
+	}
+	if _, err := storage.PatchSubresource(api.NewContext(), "foo", "status", apiserver.MergePatch{}); err == nil {
+		t.Errorf("expected the status not to be patchable")
+	}
 }
@@ -23,6 +23,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/cache"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/golang/glog"
@@ -38,6 +39,30 @@ func MakeEndpointController(serviceRegistry ServiceRegistry, client *client.Clie
 type EndpointController struct {
 	serviceRegistry ServiceRegistry
 	client          *client.Client
+	// podStore holds the pods when they are cached, or is nil if they are listed at each sync.
+	podStore cache.Store
+}
+
+// UsePodStore makes e find the pods of services among those of store, which a
+// cache.Reflector keeps current, instead of listing them from the apiserver at each sync.
+func (e *EndpointController) UsePodStore(store cache.Store) {
+	e.podStore = store
+}
+
+// listPods returns the pods that match selector.
+func (e *EndpointController) listPods(selector labels.Selector) ([]api.Pod, error) {
+	if e.podStore == nil {
+		pods, err := e.client.ListPods(selector)
+		if err != nil {
+			return nil, err
+		}
+		return pods.Items, nil
+	}
+	pods := []api.Pod{}
+	for _, obj := range cache.ListSelected(e.podStore, selector) {
+		pods = append(pods, *obj.(*api.Pod))
+	}
+	return pods, nil
 }
 
 func findPort(manifest *api.ContainerManifest, portName util.IntOrString) (int, error) {
@@ -68,14 +93,14 @@ func (e *EndpointController) SyncServiceEndpoints() error {
 	}
 	var resultErr error
 	for _, service := range services.Items {
-		pods, err := e.client.ListPods(labels.Set(service.Selector).AsSelector())
+		pods, err := e.listPods(labels.Set(service.Selector).AsSelector())
 		if err != nil {
 			glog.Errorf("Error syncing service: %#v, skipping.", service)
 			resultErr = err
 			continue
 		}
-		endpoints := make([]string, len(pods.Items))
-		for ix, pod := range pods.Items {
+		endpoints := make([]string, len(pods))
+		for ix, pod := range pods {
 			port, err := findPort(&pod.DesiredState.Manifest, service.ContainerPort)
 			if err != nil {
 				glog.Errorf("Failed to find port for service: %v, %v", service, err)
@@ -88,7 +113,7 @@ func (e *EndpointController) SyncServiceEndpoints() error {
 			endpoints[ix] = net.JoinHostPort(pod.CurrentState.PodIP, strconv.Itoa(port))
 		}
 		err = e.serviceRegistry.UpdateEndpoints(api.Endpoints{
-			JSONBase:  api.JSONBase{ID: service.ID},
+			JSONBase:  api.JSONBase{ID: service.ID, Namespace: service.Namespace},
 			Endpoints: endpoints,
 		})
 		if err != nil {
@@ -24,6 +24,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/client"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/client/cache"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
@@ -195,6 +196,38 @@ func TestSyncEndpointsItems(t *testing.T) {
 	}
 }
 
+func TestSyncEndpointsPodStore(t *testing.T) {
+	serviceRegistry := MockServiceRegistry{
+		list: api.ServiceList{
+			Items: []api.Service{
+				{
+					Selector: map[string]string{
+						"foo": "bar",
+					},
+				},
+			},
+		},
+	}
+	store := cache.NewStore()
+	for i, pod := range makePodList(2).Items {
+		pod := pod
+		if i == 0 {
+			pod.Labels = map[string]string{"foo": "bar"}
+		}
+		store.Add(pod.ID, &pod)
+	}
+
+	// The pods are only found in the store, so the client is never used.
+	endpoints := MakeEndpointController(&serviceRegistry, nil)
+	endpoints.UsePodStore(store)
+	if err := endpoints.SyncServiceEndpoints(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(serviceRegistry.endpoints.Endpoints) != 1 {
+		t.Errorf("Unexpected endpoints update: %#v", serviceRegistry.endpoints)
+	}
+}
+
 func TestSyncEndpointsPodError(t *testing.T) {
 	fakeHandler := util.FakeHandler{
 		StatusCode: 500,
@@ -0,0 +1,73 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+)
+
+// EndpointsRegistryStorage implements the RESTStorage interface, backed by an
+// EndpointsRegistry. Endpoints are written by the EndpointController, so through the API
+// they can only be read and watched.
+type EndpointsRegistryStorage struct {
+	registry EndpointsRegistry
+}
+
+func NewEndpointsRegistryStorage(registry EndpointsRegistry) apiserver.RESTStorage {
+	return &EndpointsRegistryStorage{
+		registry: registry,
+	}
+}
+
+// List obtains the Endpoints of every Service. Endpoints have no labels, so only an empty
+// selector matches them.
+func (storage *EndpointsRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	result := api.EndpointsList{}
+	list, err := storage.registry.ListEndpoints()
+	if err != nil {
+		return nil, err
+	}
+	for _, endpoints := range list.Items {
+		if selector.Matches(labels.Set{}) {
+			result.Items = append(result.Items, endpoints)
+		}
+	}
+	return result, nil
+}
+
+// Get obtains the Endpoints of the Service specified by its id.
+func (storage *EndpointsRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	endpoints, err := storage.registry.GetEndpoints(api.QualifiedID(ctx.Namespace, id))
+	if err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+// Watch begins watching for changes to the Endpoints of Services, implementing
+// apiserver.ResourceWatcher.
+func (storage *EndpointsRegistryStorage) Watch(ctx api.Context, label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return storage.registry.WatchEndpoints(label, field, resourceVersion)
+}
+
+// New creates a new Endpoints object.
+func (storage *EndpointsRegistryStorage) New() interface{} {
+	return &api.Endpoints{}
+}
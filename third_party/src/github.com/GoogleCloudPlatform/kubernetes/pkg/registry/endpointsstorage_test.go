@@ -0,0 +1,63 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+func TestEndpointsRegistryStorage(t *testing.T) {
+	memory := MakeMemoryRegistry()
+	memory.UpdateEndpoints(api.Endpoints{
+		JSONBase:  api.JSONBase{ID: "foo", Namespace: "default"},
+		Endpoints: []string{"10.0.0.1:80", "10.0.0.2:80"},
+	})
+	storage := NewEndpointsRegistryStorage(memory).(*EndpointsRegistryStorage)
+	ctx := api.NewDefaultContext()
+
+	obj, err := storage.Get(ctx, "foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if endpoints := obj.(*api.Endpoints); !reflect.DeepEqual(endpoints.Endpoints, []string{"10.0.0.1:80", "10.0.0.2:80"}) {
+		t.Errorf("unexpected endpoints %#v", endpoints)
+	}
+	if _, err := storage.Get(ctx, "bar"); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+
+	list, err := storage.List(ctx, labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items := list.(api.EndpointsList).Items; len(items) != 1 || items[0].ID != "foo" {
+		t.Errorf("unexpected list %#v", list)
+	}
+	list, err = storage.List(ctx, labels.SelectorFromSet(labels.Set{"name": "foo"}))
+	if err != nil || len(list.(api.EndpointsList).Items) != 0 {
+		t.Errorf("expected endpoints, which have no labels, not to match a selector, got %#v, %v", list, err)
+	}
+
+	if _, ok := interface{}(storage).(apiserver.Creater); ok {
+		t.Errorf("expected endpoints not to be writable through the API")
+	}
+}
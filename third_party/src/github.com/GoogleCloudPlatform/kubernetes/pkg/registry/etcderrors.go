@@ -0,0 +1,39 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+)
+
+// etcdError translates err, an error of etcd about the object of kind called id, into the
+// error the apiserver answers it with: not found if etcd has no such key, already exists
+// if the key is taken, and a conflict if the object changed since it was read. The error of
+// etcd is kept in the message. Other errors, and nil, are returned unchanged, and answered
+// with 500.
+func etcdError(err error, kind, id string) error {
+	switch {
+	case tools.IsEtcdNotFound(err):
+		return apiserver.WithCause(apiserver.NewNotFoundErr(kind, id), err)
+	case tools.IsEtcdNodeExist(err):
+		return apiserver.WithCause(apiserver.NewAlreadyExistsErr(kind, id), err)
+	case tools.IsEtcdTestFailed(err):
+		return apiserver.NewConflictErr(kind, id, err)
+	}
+	return err
+}
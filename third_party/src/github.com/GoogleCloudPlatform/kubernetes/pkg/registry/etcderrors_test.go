@@ -0,0 +1,67 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestEtcdErrorsAreTranslated(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	notFound := &etcd.EtcdError{ErrorCode: tools.EtcdErrorCodeNotFound, Message: "Key not found", Cause: "/registry/pods/foo"}
+	fakeClient.Data["/registry/pods/foo"] = tools.EtcdResponseWithError{R: &etcd.Response{}, E: notFound}
+	resp, _ := fakeClient.Set("/registry/services/specs/web", api.EncodeOrDie(api.Service{JSONBase: api.JSONBase{ID: "web"}}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+
+	_, getErr := registry.GetPod("foo")
+	createErr := registry.CreateService(api.Service{JSONBase: api.JSONBase{ID: "web"}})
+	updateErr := registry.UpdateService(api.Service{JSONBase: api.JSONBase{ID: "web", ResourceVersion: resp.Node.ModifiedIndex + 1}})
+	table := []struct {
+		err  error
+		is   func(error) bool
+		etcd error
+	}{
+		{getErr, apiserver.IsNotFound, notFound},
+		{createErr, apiserver.IsAlreadyExists, tools.EtcdErrorNodeExist},
+		{updateErr, apiserver.IsConflict, tools.EtcdErrorTestFailed},
+	}
+	for i, item := range table {
+		if !item.is(item.err) {
+			t.Errorf("%d: unexpected error %#v", i, item.err)
+		}
+		// The error of etcd is kept for debugging.
+		if !strings.Contains(item.err.Error(), item.etcd.Error()) {
+			t.Errorf("%d: expected %q to contain %q", i, item.err.Error(), item.etcd.Error())
+		}
+	}
+
+	other := errors.New("connection refused")
+	if err := etcdError(other, "pod", "foo"); err != other {
+		t.Errorf("expected other errors to be unchanged, got %#v", err)
+	}
+	if err := etcdError(nil, "pod", "foo"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
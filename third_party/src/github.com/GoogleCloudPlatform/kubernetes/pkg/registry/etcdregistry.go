@@ -23,6 +23,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 	"github.com/golang/glog"
 )
@@ -34,6 +35,11 @@ import (
 type EtcdRegistry struct {
 	helper          tools.EtcdHelper
 	manifestFactory ManifestFactory
+	// The number of goroutines that match lists against selectors. If not positive,
+	// util.DefaultWorkers() are used.
+	listWorkers int
+	// Each resource is watched in etcd once, however many clients watch it.
+	pods, controllers, endpoints *tools.WatchBroadcaster
 }
 
 // MakeEtcdRegistry creates an etcd registry.
@@ -47,31 +53,65 @@ func MakeEtcdRegistry(client tools.EtcdClient, machines MinionRegistry) *EtcdReg
 	registry.manifestFactory = &BasicManifestFactory{
 		serviceRegistry: registry,
 	}
+	registry.SetWatchReplaySize(0)
 	return registry
 }
 
+// The keys of objects are made from their ID qualified with their namespace, see
+// api.QualifiedID. Methods that look objects up are passed the qualified ID.
+
 func makePodKey(podID string) string {
 	return "/registry/pods/" + podID
 }
 
-// ListPods obtains a list of pods that match selector.
+// SetListWorkers sets the number of goroutines that match lists against selectors.
+// If workers is not positive, one per CPU is used.
+func (registry *EtcdRegistry) SetListWorkers(workers int) {
+	registry.listWorkers = workers
+}
+
+// SetWatchReplaySize sets the number of recent events of each resource kept to replay to
+// watches that start from a recent resource version. If size is not positive,
+// tools.DefaultWatchReplaySize is used. It must be called before anything is watched.
+func (registry *EtcdRegistry) SetWatchReplaySize(size int) {
+	registry.pods = tools.NewWatchBroadcaster(registry.helper, "/registry/pods", size)
+	registry.controllers = tools.NewWatchBroadcaster(registry.helper, "/registry/controllers", size)
+	registry.endpoints = tools.NewWatchBroadcaster(registry.helper, "/registry/services/endpoints", size)
+}
+
+// ListPods obtains a list of pods that match selector, in the order etcd lists them.
 func (registry *EtcdRegistry) ListPods(selector labels.Selector) ([]api.Pod, error) {
 	allPods := []api.Pod{}
-	filteredPods := []api.Pod{}
 	err := registry.helper.ExtractList("/registry/pods", &allPods)
 	if err != nil {
 		return nil, err
 	}
-	for _, pod := range allPods {
-		if selector.Matches(labels.Set(pod.Labels)) {
-			// TODO: Currently nothing sets CurrentState.Host. We need a feedback loop that sets
-			// the CurrentState.Host and Status fields. Here we pretend that reality perfectly
-			// matches our desires.
-			pod.CurrentState.Host = pod.DesiredState.Host
-			filteredPods = append(filteredPods, pod)
+	matches := allPods
+	if !selector.Empty() {
+		matches = []api.Pod{}
+		for _, i := range util.ParallelFilter(len(allPods), registry.listWorkers, func(i int) bool {
+			return selector.Matches(labels.Set(allPods[i].Labels))
+		}) {
+			matches = append(matches, allPods[i])
 		}
 	}
-	return filteredPods, nil
+	for i := range matches {
+		// TODO: Currently nothing sets CurrentState.Host. We need a feedback loop that sets
+		// the CurrentState.Host and Status fields. Here we pretend that reality perfectly
+		// matches our desires.
+		matches[i].CurrentState.Host = matches[i].DesiredState.Host
+	}
+	return matches, nil
+}
+
+// WatchPods begins watching for new, changed, or deleted pods.
+func (registry *EtcdRegistry) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	if !field.Empty() {
+		return nil, fmt.Errorf("no field selector implemented for pods")
+	}
+	return registry.pods.Watch(resourceVersion, func(obj interface{}) bool {
+		return label.Matches(labels.Set(obj.(*api.Pod).Labels))
+	})
 }
 
 // GetPod gets a specific pod specified by its ID.
@@ -79,7 +119,7 @@ func (registry *EtcdRegistry) GetPod(podID string) (*api.Pod, error) {
 	var pod api.Pod
 	err := registry.helper.ExtractObj(makePodKey(podID), &pod, false)
 	if err != nil {
-		return nil, err
+		return nil, etcdError(err, "pod", podID)
 	}
 	// TODO: Currently nothing sets CurrentState.Host. We need a feedback loop that sets
 	// the CurrentState.Host and Status fields. Here we pretend that reality perfectly
@@ -102,20 +142,23 @@ func (registry *EtcdRegistry) CreatePod(machine string, pod api.Pod) error {
 	pod.DesiredState.Status = api.PodRunning
 	pod.DesiredState.Host = ""
 
-	err := registry.helper.CreateObj(makePodKey(pod.ID), &pod)
+	podID := api.QualifiedID(pod.Namespace, pod.ID)
+	err := registry.helper.CreateObj(makePodKey(podID), &pod)
 	if err != nil {
-		return err
+		return etcdError(err, "pod", pod.ID)
 	}
 
 	// TODO: Until scheduler separation is completed, just assign here.
-	return registry.AssignPod(pod.ID, machine)
+	return registry.AssignPod(podID, machine)
 }
 
-// AssignPod assigns the given pod to the given machine.
-// TODO: hook this up via apiserver, not by calling it from CreatePod().
+// AssignPod assigns the given pod to the given machine. Assigning a pod to the machine it
+// is already on does nothing; assigning it to another machine is a conflict.
+// TODO: stop calling it from CreatePod(), now that bindings reach it via apiserver.
 func (registry *EtcdRegistry) AssignPod(podID string, machine string) error {
 	podKey := makePodKey(podID)
 	var finalPod *api.Pod
+	assigned := false
 	err := registry.helper.AtomicUpdate(
 		podKey,
 		&api.Pod{},
@@ -124,6 +167,10 @@ func (registry *EtcdRegistry) AssignPod(podID string, machine string) error {
 			if !ok {
 				return nil, fmt.Errorf("unexpected object: %#v", obj)
 			}
+			if err := checkAssignable(pod, machine); err != nil {
+				return nil, err
+			}
+			assigned = pod.DesiredState.Host == machine
 			pod.DesiredState.Host = machine
 			finalPod = pod
 			return pod, nil
@@ -132,6 +179,10 @@ func (registry *EtcdRegistry) AssignPod(podID string, machine string) error {
 	if err != nil {
 		return err
 	}
+	if assigned {
+		// The manifest is already on the machine.
+		return nil
+	}
 
 	// TODO: move this to a watch/rectification loop.
 	manifest, err := registry.manifestFactory.MakeManifest(machine, *finalPod)
@@ -169,21 +220,15 @@ func (registry *EtcdRegistry) DeletePod(podID string) error {
 	var pod api.Pod
 	podKey := makePodKey(podID)
 	err := registry.helper.ExtractObj(podKey, &pod, false)
-	if tools.IsEtcdNotFound(err) {
-		return apiserver.NewNotFoundErr("pod", podID)
-	}
 	if err != nil {
-		return err
+		return etcdError(err, "pod", podID)
 	}
 
 	// First delete the pod, so a scheduler doesn't notice it getting removed from the
 	// machine and attempt to put it somewhere.
 	err = registry.helper.Delete(podKey, true)
-	if tools.IsEtcdNotFound(err) {
-		return apiserver.NewNotFoundErr("pod", podID)
-	}
 	if err != nil {
-		return err
+		return etcdError(err, "pod", podID)
 	}
 
 	machine := pod.DesiredState.Host
@@ -198,8 +243,9 @@ func (registry *EtcdRegistry) DeletePod(podID string) error {
 		manifests := in.(*api.ContainerManifestList)
 		newManifests := make([]api.ContainerManifest, 0, len(manifests.Items))
 		found := false
+		manifestID := PodManifestID(pod.Namespace, pod.ID)
 		for _, manifest := range manifests.Items {
-			if manifest.ID != podID {
+			if manifest.ID != manifestID {
 				newManifests = append(newManifests, manifest)
 			} else {
 				found = true
@@ -228,7 +274,7 @@ func (registry *EtcdRegistry) WatchControllers(label, field labels.Selector, res
 	if !field.Empty() {
 		return nil, fmt.Errorf("no field selector implemented for controllers")
 	}
-	return registry.helper.WatchList("/registry/controllers", resourceVersion, func(obj interface{}) bool {
+	return registry.controllers.Watch(resourceVersion, func(obj interface{}) bool {
 		return label.Matches(labels.Set(obj.(*api.ReplicationController).Labels))
 	})
 }
@@ -242,37 +288,53 @@ func (registry *EtcdRegistry) GetController(controllerID string) (*api.Replicati
 	var controller api.ReplicationController
 	key := makeControllerKey(controllerID)
 	err := registry.helper.ExtractObj(key, &controller, false)
-	if tools.IsEtcdNotFound(err) {
-		return nil, apiserver.NewNotFoundErr("replicationController", controllerID)
-	}
 	if err != nil {
-		return nil, err
+		return nil, etcdError(err, "replicationController", controllerID)
 	}
 	return &controller, nil
 }
 
 // CreateController creates a new ReplicationController.
 func (registry *EtcdRegistry) CreateController(controller api.ReplicationController) error {
-	err := registry.helper.CreateObj(makeControllerKey(controller.ID), controller)
-	if tools.IsEtcdNodeExist(err) {
-		return apiserver.NewAlreadyExistsErr("replicationController", controller.ID)
-	}
-	return err
+	err := registry.helper.CreateObj(makeControllerKey(api.QualifiedID(controller.Namespace, controller.ID)), controller)
+	return etcdError(err, "replicationController", controller.ID)
 }
 
 // UpdateController replaces an existing ReplicationController.
 func (registry *EtcdRegistry) UpdateController(controller api.ReplicationController) error {
-	return registry.helper.SetObj(makeControllerKey(controller.ID), controller)
+	err := registry.helper.SetObj(makeControllerKey(api.QualifiedID(controller.Namespace, controller.ID)), controller)
+	return etcdError(err, "replicationController", controller.ID)
+}
+
+// UpdateControllerStatus changes the current state of a ReplicationController, atomically
+// with respect to any other update of it.
+func (registry *EtcdRegistry) UpdateControllerStatus(controllerID string, update ControllerStatusUpdateFunc) (*api.ReplicationController, error) {
+	var updated *api.ReplicationController
+	err := registry.helper.AtomicUpdate(makeControllerKey(controllerID), &api.ReplicationController{}, func(obj interface{}) (interface{}, error) {
+		controller := obj.(*api.ReplicationController)
+		// AtomicUpdate passes an object without a resource version if there is none.
+		if controller.ResourceVersion == 0 {
+			return nil, apiserver.NewNotFoundErr("replicationController", controllerID)
+		}
+		state, err := update(*controller)
+		if err != nil {
+			return nil, err
+		}
+		controller.CurrentState = state
+		updated = controller
+		return controller, nil
+	})
+	if err != nil {
+		return nil, etcdError(err, "replicationController", controllerID)
+	}
+	return updated, nil
 }
 
 // DeleteController deletes a ReplicationController specified by its ID.
 func (registry *EtcdRegistry) DeleteController(controllerID string) error {
 	key := makeControllerKey(controllerID)
 	err := registry.helper.Delete(key, false)
-	if tools.IsEtcdNotFound(err) {
-		return apiserver.NewNotFoundErr("replicationController", controllerID)
-	}
-	return err
+	return etcdError(err, "replicationController", controllerID)
 }
 
 func makeServiceKey(name string) string {
@@ -288,11 +350,8 @@ func (registry *EtcdRegistry) ListServices() (api.ServiceList, error) {
 
 // CreateService creates a new Service.
 func (registry *EtcdRegistry) CreateService(svc api.Service) error {
-	err := registry.helper.CreateObj(makeServiceKey(svc.ID), svc)
-	if tools.IsEtcdNodeExist(err) {
-		return apiserver.NewAlreadyExistsErr("service", svc.ID)
-	}
-	return err
+	err := registry.helper.CreateObj(makeServiceKey(api.QualifiedID(svc.Namespace, svc.ID)), svc)
+	return etcdError(err, "service", svc.ID)
 }
 
 // GetService obtains a Service specified by its name.
@@ -300,11 +359,8 @@ func (registry *EtcdRegistry) GetService(name string) (*api.Service, error) {
 	key := makeServiceKey(name)
 	var svc api.Service
 	err := registry.helper.ExtractObj(key, &svc, false)
-	if tools.IsEtcdNotFound(err) {
-		return nil, apiserver.NewNotFoundErr("service", name)
-	}
 	if err != nil {
-		return nil, err
+		return nil, etcdError(err, "service", name)
 	}
 	return &svc, nil
 }
@@ -317,11 +373,8 @@ func makeServiceEndpointsKey(name string) string {
 func (registry *EtcdRegistry) DeleteService(name string) error {
 	key := makeServiceKey(name)
 	err := registry.helper.Delete(key, true)
-	if tools.IsEtcdNotFound(err) {
-		return apiserver.NewNotFoundErr("service", name)
-	}
 	if err != nil {
-		return err
+		return etcdError(err, "service", name)
 	}
 	key = makeServiceEndpointsKey(name)
 	err = registry.helper.Delete(key, true)
@@ -333,11 +386,93 @@ func (registry *EtcdRegistry) DeleteService(name string) error {
 
 // UpdateService replaces an existing Service.
 func (registry *EtcdRegistry) UpdateService(svc api.Service) error {
-	return registry.helper.SetObj(makeServiceKey(svc.ID), svc)
+	err := registry.helper.SetObj(makeServiceKey(api.QualifiedID(svc.Namespace, svc.ID)), svc)
+	return etcdError(err, "service", svc.ID)
+}
+
+// ListEndpoints obtains the Endpoints of every Service.
+func (registry *EtcdRegistry) ListEndpoints() (api.EndpointsList, error) {
+	var list api.EndpointsList
+	err := registry.helper.ExtractList("/registry/services/endpoints", &list.Items)
+	return list, err
+}
+
+// GetEndpoints obtains the Endpoints of the Service specified by its name.
+func (registry *EtcdRegistry) GetEndpoints(name string) (*api.Endpoints, error) {
+	var endpoints api.Endpoints
+	err := registry.helper.ExtractObj(makeServiceEndpointsKey(name), &endpoints, false)
+	if err != nil {
+		return nil, etcdError(err, "endpoints", name)
+	}
+	return &endpoints, nil
+}
+
+// WatchEndpoints begins watching for new, changed, or deleted Endpoints. Endpoints have no
+// labels, so only an empty label selector matches them.
+func (registry *EtcdRegistry) WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	if !field.Empty() {
+		return nil, fmt.Errorf("no field selector implemented for endpoints")
+	}
+	return registry.endpoints.Watch(resourceVersion, func(obj interface{}) bool {
+		return label.Matches(labels.Set{})
+	})
 }
 
 // UpdateEndpoints update Endpoints of a Service.
 func (registry *EtcdRegistry) UpdateEndpoints(e api.Endpoints) error {
 	updateFunc := func(interface{}) (interface{}, error) { return e, nil }
-	return registry.helper.AtomicUpdate(makeServiceEndpointsKey(e.ID), &api.Endpoints{}, updateFunc)
+	return registry.helper.AtomicUpdate(makeServiceEndpointsKey(api.QualifiedID(e.Namespace, e.ID)), &api.Endpoints{}, updateFunc)
+}
+
+// labeledObjectKey returns the key of the pod, replication controller or service with the
+// given id, and an empty object of its kind to extract it into.
+func labeledObjectKey(kind, id string) (string, interface{}, error) {
+	switch kind {
+	case "pod":
+		return makePodKey(id), &api.Pod{}, nil
+	case "replicationController":
+		return makeControllerKey(id), &api.ReplicationController{}, nil
+	case "service":
+		return makeServiceKey(id), &api.Service{}, nil
+	}
+	return "", nil, fmt.Errorf("objects of kind %q have no labels", kind)
+}
+
+// GetLabels obtains the labels of a pod, replication controller or service.
+func (registry *EtcdRegistry) GetLabels(kind, id string) (*api.ObjectLabels, error) {
+	key, obj, err := labeledObjectKey(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	err = registry.helper.ExtractObj(key, obj, false)
+	if err != nil {
+		return nil, etcdError(err, kind, id)
+	}
+	return makeObjectLabels(obj), nil
+}
+
+// UpdateLabels changes the labels of a pod, replication controller or service, atomically
+// with respect to any other update of it.
+func (registry *EtcdRegistry) UpdateLabels(kind, id string, update LabelUpdateFunc) (*api.ObjectLabels, error) {
+	key, obj, err := labeledObjectKey(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	err = registry.helper.AtomicUpdate(key, obj, func(obj interface{}) (interface{}, error) {
+		labels, jsonBase := labeledFields(obj)
+		// AtomicUpdate passes an object without a resource version if there is none.
+		if jsonBase.ResourceVersion == 0 {
+			return nil, apiserver.NewNotFoundErr(kind, id)
+		}
+		updated, err := update(*labels, jsonBase.ResourceVersion)
+		if err != nil {
+			return nil, err
+		}
+		*labels = updated
+		return obj, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return registry.GetLabels(kind, id)
 }
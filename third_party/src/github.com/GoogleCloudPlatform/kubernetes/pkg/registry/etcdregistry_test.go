@@ -17,8 +17,10 @@ limitations under the License.
 package registry
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
@@ -59,8 +61,8 @@ func TestEtcdGetPodNotFound(t *testing.T) {
 	}
 	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
 	_, err := registry.GetPod("foo")
-	if err == nil {
-		t.Errorf("Unexpected non-error.")
+	if !apiserver.IsNotFound(err) {
+		t.Errorf("expected not found err, got %#v", err)
 	}
 }
 
@@ -118,6 +120,45 @@ func TestEtcdCreatePod(t *testing.T) {
 	}
 }
 
+func TestEtcdAssignPodAlreadyAssigned(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	fakeClient.Set("/registry/pods/foo", api.EncodeOrDie(&api.Pod{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		DesiredState: api.PodState{Host: "machine"},
+	}), 1)
+	fakeClient.Set("/registry/hosts/machine/kubelet", api.EncodeOrDie(&api.ContainerManifestList{
+		Items: []api.ContainerManifest{{ID: "foo"}},
+	}), 1)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine", "other"})
+
+	if err := registry.AssignPod("foo", "machine"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	var manifests api.ContainerManifestList
+	resp, err := fakeClient.Get("/registry/hosts/machine/kubelet", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	api.DecodeInto([]byte(resp.Node.Value), &manifests)
+	if len(manifests.Items) != 1 {
+		t.Errorf("expected the manifest not to be added twice, got %#v", manifests)
+	}
+
+	if err := registry.AssignPod("foo", "other"); !apiserver.IsConflict(err) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+	resp, err = fakeClient.Get("/registry/pods/foo", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var pod api.Pod
+	api.DecodeInto([]byte(resp.Node.Value), &pod)
+	if pod.DesiredState.Host != "machine" {
+		t.Errorf("expected the pod to stay on its machine, got %#v", pod)
+	}
+}
+
 func TestEtcdCreatePodAlreadyExisting(t *testing.T) {
 	fakeClient := tools.MakeFakeEtcdClient(t)
 	fakeClient.Data["/registry/pods/foo"] = tools.EtcdResponseWithError{
@@ -134,8 +175,8 @@ func TestEtcdCreatePodAlreadyExisting(t *testing.T) {
 			ID: "foo",
 		},
 	})
-	if err == nil {
-		t.Error("Unexpected non-error")
+	if !apiserver.IsAlreadyExists(err) {
+		t.Errorf("expected already exists err, got %#v", err)
 	}
 }
 
@@ -447,6 +488,86 @@ func TestEtcdListPods(t *testing.T) {
 	}
 }
 
+func makeEtcdPodList(n int) *etcd.Node {
+	node := &etcd.Node{}
+	for i := 0; i < n; i++ {
+		name := "frontend"
+		if i%2 == 1 {
+			name = "backend"
+		}
+		node.Nodes = append(node.Nodes, &etcd.Node{
+			Value: api.EncodeOrDie(api.Pod{
+				JSONBase:     api.JSONBase{ID: fmt.Sprintf("pod-%d", i)},
+				Labels:       map[string]string{"name": name},
+				DesiredState: api.PodState{Host: "machine"},
+			}),
+		})
+	}
+	return node
+}
+
+func TestEtcdListPodsParallelOrdering(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.Data["/registry/pods"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{Node: makeEtcdPodList(1001)},
+	}
+	selector := labels.Set{"name": "backend"}.AsSelector()
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+
+	registry.SetListWorkers(1)
+	serial, err := registry.ListPods(selector)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registry.SetListWorkers(8)
+	parallel, err := registry.ListPods(selector)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(serial) != 500 {
+		t.Errorf("expected 500 pods, got %d", len(serial))
+	}
+	if !reflect.DeepEqual(serial, parallel) {
+		t.Errorf("parallel list differs from serial list")
+	}
+	for _, pod := range parallel {
+		if pod.CurrentState.Host != "machine" {
+			t.Errorf("Failed to populate host name for %s", pod.ID)
+		}
+	}
+}
+
+func benchmarkListPods(b *testing.B, n int, selector labels.Selector) {
+	fakeClient := tools.MakeFakeEtcdClient(b)
+	fakeClient.Data["/registry/pods"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{Node: makeEtcdPodList(n)},
+	}
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := registry.ListPods(selector); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func BenchmarkListPods1k(b *testing.B) {
+	benchmarkListPods(b, 1000, labels.Everything())
+}
+
+func BenchmarkListPods1kSelector(b *testing.B) {
+	benchmarkListPods(b, 1000, labels.Set{"name": "backend"}.AsSelector())
+}
+
+func BenchmarkListPods10k(b *testing.B) {
+	benchmarkListPods(b, 10000, labels.Everything())
+}
+
+func BenchmarkListPods10kSelector(b *testing.B) {
+	benchmarkListPods(b, 10000, labels.Set{"name": "backend"}.AsSelector())
+}
+
 func TestEtcdListControllersNotFound(t *testing.T) {
 	fakeClient := tools.MakeFakeEtcdClient(t)
 	key := "/registry/controllers"
@@ -625,6 +746,103 @@ func TestEtcdUpdateController(t *testing.T) {
 	}
 }
 
+func TestEtcdUpdateControllerConflict(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+
+	resp, _ := fakeClient.Set("/registry/controllers/foo", api.EncodeOrDie(api.ReplicationController{JSONBase: api.JSONBase{ID: "foo"}}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	err := registry.UpdateController(api.ReplicationController{
+		JSONBase: api.JSONBase{ID: "foo", ResourceVersion: resp.Node.ModifiedIndex + 1},
+	})
+	if !apiserver.IsConflict(err) {
+		t.Errorf("expected conflict err, got %#v", err)
+	}
+}
+
+func TestEtcdUpdateControllerStatusRacingSpecUpdate(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	resp, _ := fakeClient.Set("/registry/controllers/foo", api.EncodeOrDie(api.ReplicationController{
+		JSONBase:     api.JSONBase{ID: "foo"},
+		DesiredState: api.ReplicationControllerState{Replicas: 2},
+	}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+
+	attempts := 0
+	controller, err := registry.UpdateControllerStatus("foo", func(current api.ReplicationController) (api.ReplicationControllerState, error) {
+		attempts++
+		if attempts == 1 {
+			// A user resizes the controller after the status update read it.
+			err := registry.UpdateController(api.ReplicationController{
+				JSONBase:     api.JSONBase{ID: "foo", ResourceVersion: resp.Node.ModifiedIndex},
+				DesiredState: api.ReplicationControllerState{Replicas: 5},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		return api.ReplicationControllerState{Replicas: 3}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected the status update to be retried once, got %d attempts", attempts)
+	}
+	stored, _ := registry.GetController("foo")
+	for _, c := range []*api.ReplicationController{controller, stored} {
+		if c.DesiredState.Replicas != 5 || c.CurrentState.Replicas != 3 {
+			t.Errorf("expected both the spec and the status updates to be kept, got %#v", c)
+		}
+	}
+
+	// The user's update raced with the status update, and is a conflict if it comes last.
+	err = registry.UpdateController(api.ReplicationController{
+		JSONBase:     api.JSONBase{ID: "foo", ResourceVersion: resp.Node.ModifiedIndex},
+		DesiredState: api.ReplicationControllerState{Replicas: 1},
+	})
+	if !apiserver.IsConflict(err) {
+		t.Errorf("expected a conflict, got %v", err)
+	}
+
+	fakeClient.ExpectNotFoundGet("/registry/controllers/bar")
+	if _, err := registry.UpdateControllerStatus("bar", func(api.ReplicationController) (api.ReplicationControllerState, error) {
+		return api.ReplicationControllerState{}, nil
+	}); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestEtcdWatchPods(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.ExpectNotFoundGet("/registry/pods")
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	watching, err := registry.WatchPods(labels.Everything(), labels.Everything(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClient.WaitForWatchCompletion()
+
+	fakeClient.WatchResponse <- &etcd.Response{
+		Action: "set",
+		Node:   &etcd.Node{Key: "/registry/pods/foo", Value: api.EncodeOrDie(api.Pod{JSONBase: api.JSONBase{ID: "foo"}}), ModifiedIndex: 1},
+	}
+	select {
+	case event := <-watching.ResultChan():
+		if got, ok := event.Object.(*api.Pod); !ok || got.ID != "foo" {
+			t.Errorf("unexpected event %#v", event)
+		}
+	case <-time.After(10 * time.Second):
+		t.Errorf("expected an event")
+	}
+	watching.Stop()
+
+	if _, err := registry.WatchPods(labels.Everything(), labels.SelectorFromSet(labels.Set{"id": "foo"}), 1); err == nil {
+		t.Errorf("expected field selectors to be rejected")
+	}
+}
+
 func TestEtcdListServices(t *testing.T) {
 	fakeClient := tools.MakeFakeEtcdClient(t)
 	key := "/registry/services/specs"
@@ -775,6 +993,53 @@ func TestEtcdUpdateService(t *testing.T) {
 	}
 }
 
+func TestEtcdUpdateLabels(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.TestIndex = true
+	fakeClient.Set("/registry/services/specs/foo", api.EncodeOrDie(api.Service{
+		JSONBase: api.JSONBase{ID: "foo"},
+		Labels:   map[string]string{"name": "foo"},
+		Selector: map[string]string{"name": "web"},
+	}), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+
+	current, err := registry.GetLabels("service", "foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	labels, err := registry.UpdateLabels("service", "foo", func(labels map[string]string, resourceVersion uint64) (map[string]string, error) {
+		if resourceVersion != current.ResourceVersion || labels["name"] != "foo" {
+			t.Errorf("unexpected labels %v at %d", labels, resourceVersion)
+		}
+		return map[string]string{"name": "foo", "env": "prod"}, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels.ResourceVersion <= current.ResourceVersion || labels.Labels["env"] != "prod" {
+		t.Errorf("unexpected labels %#v", labels)
+	}
+	svc, err := registry.GetService("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if svc.Selector["name"] != "web" || !reflect.DeepEqual(svc.Labels, labels.Labels) {
+		t.Errorf("expected only the labels of the service to change, got %#v", svc)
+	}
+
+	update := func(labels map[string]string, resourceVersion uint64) (map[string]string, error) {
+		t.Errorf("unexpected update of a missing object")
+		return labels, nil
+	}
+	fakeClient.ExpectNotFoundGet("/registry/services/specs/bar")
+	if _, err := registry.UpdateLabels("service", "bar", update); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a missing service not to be found, got %v", err)
+	}
+	if _, err := fakeClient.Get("/registry/services/specs/bar", false, false); !tools.IsEtcdNotFound(err) {
+		t.Errorf("expected no service to be created, got %v", err)
+	}
+}
+
 func TestEtcdUpdateEndpoints(t *testing.T) {
 	fakeClient := tools.MakeFakeEtcdClient(t)
 	fakeClient.TestIndex = true
@@ -802,6 +1067,93 @@ func TestEtcdUpdateEndpoints(t *testing.T) {
 	}
 }
 
+func TestEtcdListEndpoints(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.Data["/registry/services/endpoints"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{
+						Value: api.EncodeOrDie(api.Endpoints{JSONBase: api.JSONBase{ID: "foo"}, Endpoints: []string{"127.0.0.1:8345"}}),
+					},
+					{
+						Value: api.EncodeOrDie(api.Endpoints{JSONBase: api.JSONBase{ID: "bar"}}),
+					},
+				},
+			},
+		},
+		E: nil,
+	}
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	endpoints, err := registry.ListEndpoints()
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if len(endpoints.Items) != 2 || endpoints.Items[0].ID != "foo" || endpoints.Items[1].ID != "bar" {
+		t.Errorf("Unexpected endpoints list: %#v", endpoints)
+	}
+}
+
+func TestEtcdGetEndpoints(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	endpoints := api.Endpoints{
+		JSONBase:  api.JSONBase{ID: "foo"},
+		Endpoints: []string{"127.0.0.1:8345"},
+	}
+	fakeClient.Set("/registry/services/endpoints/foo", api.EncodeOrDie(endpoints), 0)
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	got, err := registry.GetEndpoints("foo")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(endpoints.Endpoints, got.Endpoints) {
+		t.Errorf("Unexpected endpoints: %#v, expected %#v", got, endpoints)
+	}
+
+	fakeClient.Data["/registry/services/endpoints/bar"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{Node: nil},
+		E: tools.EtcdErrorNotFound,
+	}
+	if _, err := registry.GetEndpoints("bar"); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+}
+
+func TestEtcdWatchEndpoints(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.ExpectNotFoundGet("/registry/services/endpoints")
+	registry := MakeTestEtcdRegistry(fakeClient, []string{"machine"})
+	watching, err := registry.WatchEndpoints(labels.Everything(), labels.Everything(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fakeClient.WaitForWatchCompletion()
+
+	endpoints := api.Endpoints{JSONBase: api.JSONBase{ID: "foo"}, Endpoints: []string{"127.0.0.1:8345"}}
+	fakeClient.WatchResponse <- &etcd.Response{
+		Action: "set",
+		Node: &etcd.Node{
+			Key:           "/registry/services/endpoints/foo",
+			Value:         api.EncodeOrDie(endpoints),
+			ModifiedIndex: 1,
+		},
+	}
+	select {
+	case event := <-watching.ResultChan():
+		if got, ok := event.Object.(*api.Endpoints); !ok || got.ID != "foo" {
+			t.Errorf("unexpected event %#v", event)
+		}
+	case <-time.After(10 * time.Second):
+		t.Errorf("expected an event")
+	}
+	watching.Stop()
+
+	if _, err := registry.WatchEndpoints(labels.Everything(), labels.SelectorFromSet(labels.Set{"id": "foo"}), 1); err == nil {
+		t.Errorf("expected field selectors to be rejected")
+	}
+}
+
 // TODO We need a test for the compare and swap behavior.  This basically requires two things:
 //   1) Add a per-operation synchronization channel to the fake etcd client, such that any operation waits on that
 //      channel, this will enable us to orchestrate the flow of etcd requests in the test.
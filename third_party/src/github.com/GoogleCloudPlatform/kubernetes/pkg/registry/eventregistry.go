@@ -0,0 +1,75 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+)
+
+// EtcdEventRegistry implements EventRegistry backed by etcd. Events expire from etcd
+// after a fixed TTL, so that they don't accumulate forever.
+type EtcdEventRegistry struct {
+	helper tools.EtcdHelper
+	ttl    time.Duration
+}
+
+// MakeEtcdEventRegistry creates an etcd event registry.
+// 'client' is the connection to etcd
+// 'ttl' is how long events are kept for
+func MakeEtcdEventRegistry(client tools.EtcdClient, ttl time.Duration) *EtcdEventRegistry {
+	return &EtcdEventRegistry{
+		helper: tools.EtcdHelper{client, api.Codec, api.ResourceVersioner},
+		ttl:    ttl,
+	}
+}
+
+// makeEventKey returns the key of the event with id, qualified with its namespace.
+func makeEventKey(id string) string {
+	return "/registry/events/" + id
+}
+
+// ListEvents obtains a list of the Events that haven't expired.
+func (registry *EtcdEventRegistry) ListEvents() (api.EventList, error) {
+	var list api.EventList
+	err := registry.helper.ExtractList("/registry/events", &list.Items)
+	return list, err
+}
+
+// GetEvent gets a specific Event specified by its ID.
+func (registry *EtcdEventRegistry) GetEvent(eventID string) (*api.Event, error) {
+	var event api.Event
+	err := registry.helper.ExtractObj(makeEventKey(eventID), &event, false)
+	if err != nil {
+		return nil, etcdError(err, "event", eventID)
+	}
+	return &event, nil
+}
+
+// CreateEvent creates a new Event, which expires after the TTL of the registry.
+func (registry *EtcdEventRegistry) CreateEvent(event api.Event) error {
+	err := registry.helper.CreateObjWithTTL(makeEventKey(api.QualifiedID(event.Namespace, event.ID)), event, uint64(registry.ttl.Seconds()))
+	return etcdError(err, "event", event.ID)
+}
+
+// DeleteEvent deletes an Event specified by its ID.
+func (registry *EtcdEventRegistry) DeleteEvent(eventID string) error {
+	err := registry.helper.Delete(makeEventKey(eventID), false)
+	return etcdError(err, "event", eventID)
+}
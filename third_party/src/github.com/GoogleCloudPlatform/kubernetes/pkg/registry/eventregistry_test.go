@@ -0,0 +1,96 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/tools"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+func TestEtcdCreateEventExpires(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.ExpectNotFoundGet("/registry/events/foo")
+	registry := MakeEtcdEventRegistry(fakeClient, time.Hour)
+	err := registry.CreateEvent(api.Event{JSONBase: api.JSONBase{ID: "foo"}, Reason: "failedCreate"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node := fakeClient.Data["/registry/events/foo"].R.Node
+	if node.TTL != 3600 {
+		t.Errorf("expected the event to expire after an hour, got TTL %d", node.TTL)
+	}
+	event, err := registry.GetEvent("foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.Reason != "failedCreate" {
+		t.Errorf("unexpected event: %#v", event)
+	}
+}
+
+func TestEtcdGetEventNotFound(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.Data["/registry/events/foo"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: nil,
+		},
+		E: tools.EtcdErrorNotFound,
+	}
+	registry := MakeEtcdEventRegistry(fakeClient, time.Hour)
+	_, err := registry.GetEvent("foo")
+	if !apiserver.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestEtcdListEvents(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.Data["/registry/events"] = tools.EtcdResponseWithError{
+		R: &etcd.Response{
+			Node: &etcd.Node{
+				Nodes: []*etcd.Node{
+					{Value: api.EncodeOrDie(api.Event{JSONBase: api.JSONBase{ID: "foo"}})},
+					{Value: api.EncodeOrDie(api.Event{JSONBase: api.JSONBase{ID: "bar"}})},
+				},
+			},
+		},
+		E: nil,
+	}
+	registry := MakeEtcdEventRegistry(fakeClient, time.Hour)
+	list, err := registry.ListEvents()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Items) != 2 || list.Items[0].ID != "foo" || list.Items[1].ID != "bar" {
+		t.Errorf("unexpected event list: %#v", list)
+	}
+}
+
+func TestEtcdDeleteEventNotFound(t *testing.T) {
+	fakeClient := tools.MakeFakeEtcdClient(t)
+	fakeClient.Err = tools.EtcdErrorNotFound
+	registry := MakeEtcdEventRegistry(fakeClient, time.Hour)
+	err := registry.DeleteEvent("foo")
+	if !apiserver.IsNotFound(err) {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
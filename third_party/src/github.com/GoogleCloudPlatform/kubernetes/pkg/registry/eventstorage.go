@@ -0,0 +1,119 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"time"
+
+	"code.google.com/p/go-uuid/uuid"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// EventRegistryStorage implements the RESTStorage interface, backed by an EventRegistry.
+// Events can be created and deleted, but not updated.
+type EventRegistryStorage struct {
+	registry EventRegistry
+}
+
+func NewEventRegistryStorage(registry EventRegistry) apiserver.RESTStorage {
+	return &EventRegistryStorage{
+		registry: registry,
+	}
+}
+
+// List obtains a list of Events. Events have no labels, so only an empty selector matches them.
+func (storage *EventRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	return storage.ListFields(ctx, selector, labels.Everything())
+}
+
+// ListFields obtains a list of Events whose fields match field, e.g. "involvedObject.id=foo".
+func (storage *EventRegistryStorage) ListFields(ctx api.Context, label, field labels.Selector) (interface{}, error) {
+	result := api.EventList{}
+	events, err := storage.registry.ListEvents()
+	if err == nil {
+		for _, event := range events.Items {
+			if label.Matches(labels.Set{}) && field.Matches(eventFields(&event)) {
+				result.Items = append(result.Items, event)
+			}
+		}
+	}
+	return result, err
+}
+
+// eventFields returns the fields of event that ListFields can select on.
+func eventFields(event *api.Event) labels.Set {
+	return labels.Set{
+		"involvedObject.kind": event.InvolvedObject.Kind,
+		"involvedObject.id":   event.InvolvedObject.ID,
+		"reason":              event.Reason,
+		"source":              event.Source,
+	}
+}
+
+// Get obtains the Event specified by its id.
+func (storage *EventRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	event, err := storage.registry.GetEvent(api.QualifiedID(ctx.Namespace, id))
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Delete asynchronously deletes the Event specified by its id.
+func (storage *EventRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
+	return apiserver.MakeAsync(func() (interface{}, error) {
+		return &api.Status{Status: api.StatusSuccess}, storage.registry.DeleteEvent(api.QualifiedID(ctx.Namespace, id))
+	}), nil
+}
+
+// New creates a new Event for use with Create.
+func (storage *EventRegistryStorage) New() interface{} {
+	return &api.Event{}
+}
+
+// Default implements apiserver.Defaulter, filling in the ID and timestamp of an Event if
+// they are not set.
+func (storage *EventRegistryStorage) Default(ctx api.Context, obj interface{}) {
+	event, ok := obj.(*api.Event)
+	if !ok {
+		return
+	}
+	if len(event.ID) == 0 {
+		event.ID = uuid.NewUUID().String()
+	}
+	if len(event.Timestamp) == 0 {
+		event.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	}
+}
+
+// Create records a new Event, filling in its ID and timestamp if they are not set.
+func (storage *EventRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	event, ok := obj.(*api.Event)
+	if !ok {
+		return nil, fmt.Errorf("not an event: %#v", obj)
+	}
+	storage.Default(ctx, event)
+	return apiserver.MakeAsync(func() (interface{}, error) {
+		if err := storage.registry.CreateEvent(*event); err != nil {
+			return nil, err
+		}
+		return event, nil
+	}), nil
+}
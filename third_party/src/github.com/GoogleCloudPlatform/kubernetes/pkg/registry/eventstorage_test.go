@@ -0,0 +1,91 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+func TestEventStorageCreateFillsIDAndTimestamp(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	storage := NewEventRegistryStorage(registry).(*EventRegistryStorage)
+	channel, err := storage.Create(api.NewDefaultContext(), &api.Event{Reason: "failedCreate"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	created := (<-channel).(*api.Event)
+	if len(created.ID) == 0 || len(created.Timestamp) == 0 {
+		t.Errorf("expected an ID and a timestamp, got %#v", created)
+	}
+	if _, err := registry.GetEvent(created.ID); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRecordedEventsAreFoundInTheirNamespace(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	storage := NewEventRegistryStorage(registry).(*EventRegistryStorage)
+	recorder := apiserver.NewEventRecorder(registry, "apiserver")
+	recorder.Eventf(api.ObjectReference{Kind: "pod", Namespace: "team1", ID: "foo"}, "failedCreate", "no room")
+
+	obj, err := storage.List(api.Context{Namespace: "team1"}, labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list := obj.(api.EventList)
+	if len(list.Items) != 1 {
+		t.Fatalf("unexpected events: %#v", list)
+	}
+	event := list.Items[0]
+	if event.Namespace != "team1" {
+		t.Errorf("expected the event in the namespace of its object, got %#v", event)
+	}
+	if _, err := storage.Get(api.Context{Namespace: "team1"}, event.ID); err != nil {
+		t.Errorf("expected the event to be found by its ID, got %v", err)
+	}
+	if _, err := registry.GetEvent(api.QualifiedID("team1", event.ID)); err != nil {
+		t.Errorf("expected the event to be keyed by its ID qualified once, got %v", err)
+	}
+}
+
+func TestEventStorageListFields(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateEvent(api.Event{JSONBase: api.JSONBase{ID: "a"}, InvolvedObject: api.ObjectReference{Kind: "pod", ID: "foo"}, Reason: "failedCreate"})
+	registry.CreateEvent(api.Event{JSONBase: api.JSONBase{ID: "b"}, InvolvedObject: api.ObjectReference{Kind: "pod", ID: "bar"}, Reason: "failedDelete"})
+	storage := NewEventRegistryStorage(registry).(*EventRegistryStorage)
+
+	obj, err := storage.ListFields(api.NewContext(), labels.Everything(), labels.Set{"involvedObject.id": "foo"}.AsSelector())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	list := obj.(api.EventList)
+	if len(list.Items) != 1 || list.Items[0].ID != "a" {
+		t.Errorf("unexpected events: %#v", list)
+	}
+
+	obj, err = storage.List(api.NewContext(), labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if list := obj.(api.EventList); len(list.Items) != 2 {
+		t.Errorf("unexpected events: %#v", list)
+	}
+}
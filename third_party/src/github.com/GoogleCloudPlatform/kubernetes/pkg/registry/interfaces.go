@@ -23,6 +23,8 @@ import (
 )
 
 // PodRegistry is an interface implemented by things that know how to store Pod objects.
+// Like the other registries, it stores objects under their ID qualified with their
+// namespace, and is passed qualified IDs to look them up; see api.QualifiedID.
 type PodRegistry interface {
 	// ListPods obtains a list of pods that match selector.
 	ListPods(selector labels.Selector) ([]api.Pod, error)
@@ -32,8 +34,12 @@ type PodRegistry interface {
 	CreatePod(machine string, pod api.Pod) error
 	// Update an existing pod
 	UpdatePod(pod api.Pod) error
+	// Assign an existing pod to a specific machine.
+	AssignPod(podID string, machine string) error
 	// Delete an existing pod
 	DeletePod(podID string) error
+	// Watch for new, changed or deleted pods.
+	WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
 }
 
 // ControllerRegistry is an interface for things that know how to store ReplicationControllers.
@@ -43,9 +49,17 @@ type ControllerRegistry interface {
 	GetController(controllerID string) (*api.ReplicationController, error)
 	CreateController(controller api.ReplicationController) error
 	UpdateController(controller api.ReplicationController) error
+	// UpdateControllerStatus replaces the current state of a controller with what update
+	// returns for the controller as it is stored, leaving the rest of it as it is. update is
+	// called again if the controller changes meanwhile.
+	UpdateControllerStatus(controllerID string, update ControllerStatusUpdateFunc) (*api.ReplicationController, error)
 	DeleteController(controllerID string) error
 }
 
+// ControllerStatusUpdateFunc returns the new current state of a replication controller given
+// the controller as it is stored.
+type ControllerStatusUpdateFunc func(controller api.ReplicationController) (api.ReplicationControllerState, error)
+
 // ServiceRegistry is an interface for things that know how to store services.
 type ServiceRegistry interface {
 	ListServices() (api.ServiceList, error)
@@ -55,3 +69,35 @@ type ServiceRegistry interface {
 	UpdateService(svc api.Service) error
 	UpdateEndpoints(e api.Endpoints) error
 }
+
+// EndpointsRegistry is an interface for things that know how to read the Endpoints of
+// services, which the EndpointController writes through a ServiceRegistry.
+type EndpointsRegistry interface {
+	ListEndpoints() (api.EndpointsList, error)
+	GetEndpoints(name string) (*api.Endpoints, error)
+	WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error)
+}
+
+// EventRegistry is an interface for things that know how to store Events.
+type EventRegistry interface {
+	ListEvents() (api.EventList, error)
+	GetEvent(eventID string) (*api.Event, error)
+	CreateEvent(event api.Event) error
+	DeleteEvent(eventID string) error
+}
+
+// LabelRegistry is an interface for registries that can change the labels of the pods,
+// replication controllers and services they store without replacing the rest of them, so
+// that label edits and other updates of an object don't overwrite each other. Objects are
+// named by their kind, "pod", "replicationController" or "service", and qualified ID.
+type LabelRegistry interface {
+	// GetLabels returns the labels of an object along with its resource version.
+	GetLabels(kind, id string) (*api.ObjectLabels, error)
+	// UpdateLabels replaces the labels of an object with what update returns for its current
+	// labels and resource version. update is called again if the object changes meanwhile.
+	UpdateLabels(kind, id string, update LabelUpdateFunc) (*api.ObjectLabels, error)
+}
+
+// LabelUpdateFunc returns the new labels of an object given its current labels, which it
+// must not modify, and its resource version.
+type LabelUpdateFunc func(labels map[string]string, resourceVersion uint64) (map[string]string, error)
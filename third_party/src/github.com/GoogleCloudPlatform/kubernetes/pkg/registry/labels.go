@@ -0,0 +1,177 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+)
+
+// labeledFields returns pointers to the labels and the JSONBase of obj, a pointer to a pod,
+// replication controller or service, or nils for any other object.
+func labeledFields(obj interface{}) (*map[string]string, *api.JSONBase) {
+	switch obj := obj.(type) {
+	case *api.Pod:
+		return &obj.Labels, &obj.JSONBase
+	case *api.ReplicationController:
+		return &obj.Labels, &obj.JSONBase
+	case *api.Service:
+		return &obj.Labels, &obj.JSONBase
+	}
+	return nil, nil
+}
+
+// makeObjectLabels returns the labels of obj, a pointer to a pod, replication controller or
+// service.
+func makeObjectLabels(obj interface{}) *api.ObjectLabels {
+	labels, jsonBase := labeledFields(obj)
+	return &api.ObjectLabels{
+		JSONBase: api.JSONBase{
+			ID:              jsonBase.ID,
+			Namespace:       jsonBase.Namespace,
+			ResourceVersion: jsonBase.ResourceVersion,
+		},
+		Labels: *labels,
+	}
+}
+
+// labelsSubresource serves the labels of the objects of a kind in a LabelRegistry as the
+// labels subresource of those objects.
+type labelsSubresource struct {
+	kind     string
+	registry LabelRegistry
+}
+
+// makeLabelsSubresource returns the labels subresource of the objects of kind in registry,
+// or nil if registry isn't a LabelRegistry.
+func makeLabelsSubresource(kind string, registry interface{}) *labelsSubresource {
+	labelRegistry, ok := registry.(LabelRegistry)
+	if !ok {
+		return nil
+	}
+	return &labelsSubresource{kind, labelRegistry}
+}
+
+func (s *labelsSubresource) get(ctx api.Context, id string) (interface{}, error) {
+	return s.registry.GetLabels(s.kind, api.QualifiedID(ctx.Namespace, id))
+}
+
+// update replaces the labels of the object with the given id by those of obj. If obj has a
+// resource version, the labels are only replaced while the object still has that version.
+func (s *labelsSubresource) update(ctx api.Context, id string, obj interface{}) (<-chan interface{}, error) {
+	labels, ok := obj.(*api.ObjectLabels)
+	if !ok {
+		return nil, fmt.Errorf("not labels: %#v", obj)
+	}
+	if errs := api.ValidateObjectLabels(labels); len(errs) > 0 {
+		return nil, apiserver.NewInvalidErr("labels", id, errs)
+	}
+	return apiserver.MakeAsync(func() (interface{}, error) {
+		return s.registry.UpdateLabels(s.kind, api.QualifiedID(ctx.Namespace, id), func(current map[string]string, resourceVersion uint64) (map[string]string, error) {
+			if err := s.checkResourceVersion(id, labels.ResourceVersion, resourceVersion); err != nil {
+				return nil, err
+			}
+			return labels.Labels, nil
+		})
+	}), nil
+}
+
+// patch merges the labels of patch into those of the object with the given id: labels set
+// to null are removed, the others are set. If patch has a resourceVersion, it is only merged
+// while the object still has that version.
+func (s *labelsSubresource) patch(ctx api.Context, id string, patch apiserver.MergePatch) (<-chan interface{}, error) {
+	changes, expectedVersion, err := parseLabelsPatch(id, patch)
+	if err != nil {
+		return nil, err
+	}
+	return apiserver.MakeAsync(func() (interface{}, error) {
+		return s.registry.UpdateLabels(s.kind, api.QualifiedID(ctx.Namespace, id), func(current map[string]string, resourceVersion uint64) (map[string]string, error) {
+			if err := s.checkResourceVersion(id, expectedVersion, resourceVersion); err != nil {
+				return nil, err
+			}
+			labels := map[string]string{}
+			for key, value := range current {
+				labels[key] = value
+			}
+			for key, value := range changes {
+				if value == nil {
+					delete(labels, key)
+				} else {
+					labels[key] = *value
+				}
+			}
+			return labels, nil
+		})
+	}), nil
+}
+
+// checkResourceVersion returns a conflict if the object with the given id was expected to
+// have another resource version than it has. An expected version of 0 matches any version.
+func (s *labelsSubresource) checkResourceVersion(id string, expected, actual uint64) error {
+	if expected == 0 || expected == actual {
+		return nil
+	}
+	return apiserver.NewConflictErr(s.kind, id, fmt.Errorf("the labels were changed from resource version %d, but the %s has resource version %d", expected, s.kind, actual))
+}
+
+// parseLabelsPatch returns the label changes of patch, a nil value removing a label, and
+// the resource version it expects the object to have, 0 if it doesn't expect any.
+func parseLabelsPatch(id string, patch apiserver.MergePatch) (map[string]*string, uint64, error) {
+	errs := api.ValidationErrorList{}
+	changes := map[string]*string{}
+	set := &api.ObjectLabels{Labels: map[string]string{}}
+	var resourceVersion uint64
+	for field, value := range patch {
+		switch field {
+		case "kind", "apiVersion", "id", "namespace":
+			// Identify the object, which the path of the request already does.
+		case "labels":
+			labels, ok := value.(map[string]interface{})
+			if !ok {
+				errs = append(errs, api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "labels", BadValue: value})
+				continue
+			}
+			for key, value := range labels {
+				switch value := value.(type) {
+				case nil:
+					changes[key] = nil
+				case string:
+					changes[key] = &value
+					set.Labels[key] = value
+				default:
+					errs = append(errs, api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "labels." + key, BadValue: value})
+				}
+			}
+		case "resourceVersion":
+			version, ok := value.(float64)
+			if !ok || version < 0 || version != float64(uint64(version)) {
+				errs = append(errs, api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "resourceVersion", BadValue: value})
+				continue
+			}
+			resourceVersion = uint64(version)
+		default:
+			errs = append(errs, api.ValidationError{ErrorType: api.ErrTypeNotSupported, ErrorField: field, BadValue: value})
+		}
+	}
+	errs = append(errs, api.ValidateObjectLabels(set)...)
+	if len(errs) > 0 {
+		return nil, 0, apiserver.NewInvalidErr("labels", id, errs)
+	}
+	return changes, resourceVersion, nil
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+)
+
+func TestLabelsSubresource(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateController(api.ReplicationController{
+		JSONBase:     api.JSONBase{ID: "foo", ResourceVersion: 1},
+		DesiredState: api.ReplicationControllerState{Replicas: 2},
+		Labels:       map[string]string{"name": "foo", "tier": "frontend"},
+	})
+	storage := NewControllerRegistryStorage(registry, registry).(*ControllerRegistryStorage)
+	ctx := api.NewContext()
+
+	obj, err := storage.GetSubresource(ctx, "foo", "labels")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	labels := obj.(*api.ObjectLabels)
+	if labels.ID != "foo" || !reflect.DeepEqual(labels.Labels, map[string]string{"name": "foo", "tier": "frontend"}) {
+		t.Errorf("unexpected labels %#v", labels)
+	}
+
+	channel, err := storage.UpdateSubresource(ctx, "foo", "labels", &api.ObjectLabels{
+		JSONBase: api.JSONBase{ResourceVersion: labels.ResourceVersion},
+		Labels:   map[string]string{"name": "foo"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels, ok := (<-channel).(*api.ObjectLabels); !ok || !reflect.DeepEqual(labels.Labels, map[string]string{"name": "foo"}) {
+		t.Errorf("unexpected result %#v", labels)
+	}
+	controller, _ := registry.GetController("foo")
+	if controller.DesiredState.Replicas != 2 || !reflect.DeepEqual(controller.Labels, map[string]string{"name": "foo"}) {
+		t.Errorf("expected only the labels of the controller to change, got %#v", controller)
+	}
+
+	// The labels were read before the update, which changed the resource version.
+	channel, err = storage.UpdateSubresource(ctx, "foo", "labels", labels)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status, ok := (<-channel).(*api.Status); !ok || status.Code != http.StatusConflict {
+		t.Errorf("expected a conflict, got %#v", status)
+	}
+
+	channel, err = storage.PatchSubresource(ctx, "foo", "labels", apiserver.MergePatch{
+		"labels": map[string]interface{}{"name": nil, "env": "prod"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-channel
+	if controller, _ := registry.GetController("foo"); !reflect.DeepEqual(controller.Labels, map[string]string{"env": "prod"}) {
+		t.Errorf("unexpected labels %v", controller.Labels)
+	}
+	channel, err = storage.PatchSubresource(ctx, "foo", "labels", apiserver.MergePatch{
+		"labels":          map[string]interface{}{"env": "test"},
+		"resourceVersion": float64(1),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status, ok := (<-channel).(*api.Status); !ok || status.Code != http.StatusConflict {
+		t.Errorf("expected a conflict, got %#v", status)
+	}
+
+	if _, err := storage.GetSubresource(ctx, "bar", "labels"); !apiserver.IsNotFound(err) {
+		t.Errorf("expected the labels of a missing controller to be not found, got %v", err)
+	}
+	for _, patch := range []apiserver.MergePatch{
+		{"labels": "env=prod"},
+		{"labels": map[string]interface{}{"env": 1}},
+		{"labels": map[string]interface{}{"bad key": "prod"}},
+		{"resourceVersion": "1"},
+		{"replicas": float64(3)},
+	} {
+		if _, err := storage.PatchSubresource(ctx, "foo", "labels", patch); !apiserver.IsInvalid(err) {
+			t.Errorf("%v: expected an invalid error, got %v", patch, err)
+		}
+	}
+}
+
+func TestPatchLabelsInvalid(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreateService(api.Service{JSONBase: api.JSONBase{ID: "foo"}})
+	storage := MakeServiceRegistryStorage(registry, nil, nil, nil)
+	server := httptest.NewServer(apiserver.New(map[string]apiserver.RESTStorage{"services": storage}, api.Codec, "/prefix"))
+	defer server.Close()
+
+	body := []byte(`{"labels": {"env": "prod", "-bad": "x", "tier": "not valid"}}`)
+	req, _ := http.NewRequest("PATCH", server.URL+"/prefix/services/foo/labels", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", apiserver.MergePatchMediaType)
+	response, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("expected %d, got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+	data, _ := ioutil.ReadAll(response.Body)
+	var status api.Status
+	if err := json.Unmarshal(data, &status); err != nil || status.Details == nil {
+		t.Fatalf("unexpected status %s: %v", data, err)
+	}
+	fields := map[string]bool{}
+	for _, cause := range status.Details.Causes {
+		fields[cause.Field] = true
+	}
+	if !reflect.DeepEqual(fields, map[string]bool{"labels.-bad": true, "labels.tier": true}) {
+		t.Errorf("unexpected causes %#v", status.Details.Causes)
+	}
+	if service, _ := registry.GetService("foo"); len(service.Labels) != 0 {
+		t.Errorf("expected invalid labels not to be stored, got %v", service.Labels)
+	}
+}
@@ -18,6 +18,8 @@ package registry
 
 import (
 	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
@@ -25,12 +27,17 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
-// An implementation of PodRegistry and ControllerRegistry that is backed by memory
-// Mainly used for testing.
+// An implementation of PodRegistry, ControllerRegistry, ServiceRegistry, EndpointsRegistry, EventRegistry
+// and LabelRegistry that is backed by memory. Mainly used for testing. Like EtcdRegistry, it keeps objects
+// under their ID qualified with their namespace.
 type MemoryRegistry struct {
 	podData        map[string]api.Pod
 	controllerData map[string]api.ReplicationController
 	serviceData    map[string]api.Service
+	endpointsData  map[string]api.Endpoints
+	// Events are recorded from operations running in the background, so they are locked.
+	eventLock sync.Mutex
+	eventData map[string]api.Event
 }
 
 func MakeMemoryRegistry() *MemoryRegistry {
@@ -38,6 +45,8 @@ func MakeMemoryRegistry() *MemoryRegistry {
 		podData:        map[string]api.Pod{},
 		controllerData: map[string]api.ReplicationController{},
 		serviceData:    map[string]api.Service{},
+		endpointsData:  map[string]api.Endpoints{},
+		eventData:      map[string]api.Event{},
 	}
 }
 
@@ -61,7 +70,7 @@ func (registry *MemoryRegistry) GetPod(podID string) (*api.Pod, error) {
 }
 
 func (registry *MemoryRegistry) CreatePod(machine string, pod api.Pod) error {
-	registry.podData[pod.ID] = pod
+	registry.podData[api.QualifiedID(pod.Namespace, pod.ID)] = pod
 	return nil
 }
 
@@ -74,13 +83,31 @@ func (registry *MemoryRegistry) DeletePod(podID string) error {
 }
 
 func (registry *MemoryRegistry) UpdatePod(pod api.Pod) error {
-	if _, ok := registry.podData[pod.ID]; !ok {
+	podID := api.QualifiedID(pod.Namespace, pod.ID)
+	if _, ok := registry.podData[podID]; !ok {
 		return apiserver.NewNotFoundErr("pod", pod.ID)
 	}
-	registry.podData[pod.ID] = pod
+	registry.podData[podID] = pod
+	return nil
+}
+
+func (registry *MemoryRegistry) AssignPod(podID string, machine string) error {
+	pod, ok := registry.podData[podID]
+	if !ok {
+		return apiserver.NewNotFoundErr("pod", podID)
+	}
+	if err := checkAssignable(&pod, machine); err != nil {
+		return err
+	}
+	pod.DesiredState.Host = machine
+	registry.podData[podID] = pod
 	return nil
 }
 
+func (registry *MemoryRegistry) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return nil, errors.New("unimplemented")
+}
+
 func (registry *MemoryRegistry) ListControllers() ([]api.ReplicationController, error) {
 	result := []api.ReplicationController{}
 	for _, value := range registry.controllerData {
@@ -103,7 +130,7 @@ func (registry *MemoryRegistry) GetController(controllerID string) (*api.Replica
 }
 
 func (registry *MemoryRegistry) CreateController(controller api.ReplicationController) error {
-	registry.controllerData[controller.ID] = controller
+	registry.controllerData[api.QualifiedID(controller.Namespace, controller.ID)] = controller
 	return nil
 }
 
@@ -116,13 +143,31 @@ func (registry *MemoryRegistry) DeleteController(controllerID string) error {
 }
 
 func (registry *MemoryRegistry) UpdateController(controller api.ReplicationController) error {
-	if _, ok := registry.controllerData[controller.ID]; !ok {
+	controllerID := api.QualifiedID(controller.Namespace, controller.ID)
+	if _, ok := registry.controllerData[controllerID]; !ok {
 		return apiserver.NewNotFoundErr("replicationController", controller.ID)
 	}
-	registry.controllerData[controller.ID] = controller
+	registry.controllerData[controllerID] = controller
 	return nil
 }
 
+// UpdateControllerStatus changes the current state of a controller and increments its
+// resource version.
+func (registry *MemoryRegistry) UpdateControllerStatus(controllerID string, update ControllerStatusUpdateFunc) (*api.ReplicationController, error) {
+	controller, ok := registry.controllerData[controllerID]
+	if !ok {
+		return nil, apiserver.NewNotFoundErr("replicationController", controllerID)
+	}
+	state, err := update(controller)
+	if err != nil {
+		return nil, err
+	}
+	controller.CurrentState = state
+	controller.ResourceVersion++
+	registry.controllerData[controllerID] = controller
+	return &controller, nil
+}
+
 func (registry *MemoryRegistry) ListServices() (api.ServiceList, error) {
 	var list []api.Service
 	for _, value := range registry.serviceData {
@@ -132,7 +177,7 @@ func (registry *MemoryRegistry) ListServices() (api.ServiceList, error) {
 }
 
 func (registry *MemoryRegistry) CreateService(svc api.Service) error {
-	registry.serviceData[svc.ID] = svc
+	registry.serviceData[api.QualifiedID(svc.Namespace, svc.ID)] = svc
 	return nil
 }
 
@@ -154,12 +199,124 @@ func (registry *MemoryRegistry) DeleteService(name string) error {
 }
 
 func (registry *MemoryRegistry) UpdateService(svc api.Service) error {
-	if _, ok := registry.serviceData[svc.ID]; !ok {
+	if _, ok := registry.serviceData[api.QualifiedID(svc.Namespace, svc.ID)]; !ok {
 		return apiserver.NewNotFoundErr("service", svc.ID)
 	}
 	return registry.CreateService(svc)
 }
 
 func (registry *MemoryRegistry) UpdateEndpoints(e api.Endpoints) error {
+	registry.endpointsData[api.QualifiedID(e.Namespace, e.ID)] = e
+	return nil
+}
+
+func (registry *MemoryRegistry) ListEndpoints() (api.EndpointsList, error) {
+	var list []api.Endpoints
+	for _, value := range registry.endpointsData {
+		list = append(list, value)
+	}
+	return api.EndpointsList{Items: list}, nil
+}
+
+func (registry *MemoryRegistry) GetEndpoints(name string) (*api.Endpoints, error) {
+	endpoints, found := registry.endpointsData[name]
+	if !found {
+		return nil, apiserver.NewNotFoundErr("endpoints", name)
+	}
+	return &endpoints, nil
+}
+
+func (registry *MemoryRegistry) WatchEndpoints(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return nil, errors.New("unimplemented")
+}
+
+func (registry *MemoryRegistry) ListEvents() (api.EventList, error) {
+	registry.eventLock.Lock()
+	defer registry.eventLock.Unlock()
+	var list []api.Event
+	for _, value := range registry.eventData {
+		list = append(list, value)
+	}
+	return api.EventList{Items: list}, nil
+}
+
+func (registry *MemoryRegistry) GetEvent(eventID string) (*api.Event, error) {
+	registry.eventLock.Lock()
+	defer registry.eventLock.Unlock()
+	event, found := registry.eventData[eventID]
+	if !found {
+		return nil, apiserver.NewNotFoundErr("event", eventID)
+	}
+	return &event, nil
+}
+
+func (registry *MemoryRegistry) CreateEvent(event api.Event) error {
+	registry.eventLock.Lock()
+	defer registry.eventLock.Unlock()
+	registry.eventData[api.QualifiedID(event.Namespace, event.ID)] = event
 	return nil
 }
+
+func (registry *MemoryRegistry) DeleteEvent(eventID string) error {
+	registry.eventLock.Lock()
+	defer registry.eventLock.Unlock()
+	if _, ok := registry.eventData[eventID]; !ok {
+		return apiserver.NewNotFoundErr("event", eventID)
+	}
+	delete(registry.eventData, eventID)
+	return nil
+}
+
+// labeledObject returns a copy of the pod, replication controller or service with the given id.
+func (registry *MemoryRegistry) labeledObject(kind, id string) (interface{}, error) {
+	switch kind {
+	case "pod":
+		if pod, ok := registry.podData[id]; ok {
+			return &pod, nil
+		}
+	case "replicationController":
+		if controller, ok := registry.controllerData[id]; ok {
+			return &controller, nil
+		}
+	case "service":
+		if svc, ok := registry.serviceData[id]; ok {
+			return &svc, nil
+		}
+	default:
+		return nil, fmt.Errorf("objects of kind %q have no labels", kind)
+	}
+	return nil, apiserver.NewNotFoundErr(kind, id)
+}
+
+func (registry *MemoryRegistry) GetLabels(kind, id string) (*api.ObjectLabels, error) {
+	obj, err := registry.labeledObject(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	return makeObjectLabels(obj), nil
+}
+
+// UpdateLabels changes the labels of an object and increments its resource version, so that
+// concurrent changes can be told apart.
+func (registry *MemoryRegistry) UpdateLabels(kind, id string, update LabelUpdateFunc) (*api.ObjectLabels, error) {
+	obj, err := registry.labeledObject(kind, id)
+	if err != nil {
+		return nil, err
+	}
+	labels, jsonBase := labeledFields(obj)
+	updated, err := update(*labels, jsonBase.ResourceVersion)
+	if err != nil {
+		return nil, err
+	}
+	*labels = updated
+	jsonBase.ResourceVersion++
+	switch obj := obj.(type) {
+	case *api.Pod:
+		registry.podData[id] = *obj
+	case *api.ReplicationController:
+		registry.controllerData[id] = *obj
+	case *api.Service:
+		registry.serviceData[id] = *obj
+	}
+	return makeObjectLabels(obj), nil
+}
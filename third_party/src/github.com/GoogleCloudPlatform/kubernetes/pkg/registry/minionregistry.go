@@ -21,6 +21,7 @@ import (
 	"sort"
 	"sync"
 
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
@@ -34,6 +35,16 @@ type MinionRegistry interface {
 	Contains(minion string) (bool, error)
 }
 
+// MinionInfoRegistry is a MinionRegistry which also knows the status and addresses of its
+// minions.
+type MinionInfoRegistry interface {
+	MinionRegistry
+	// Minions returns every minion, including those List leaves out.
+	Minions() ([]api.Minion, error)
+	// Minion returns the minion 'name', and false if there is none.
+	Minion(name string) (api.Minion, bool, error)
+}
+
 // Initialize a minion registry with a list of minions.
 func MakeMinionRegistry(minions []string) MinionRegistry {
 	m := &minionList{
@@ -25,13 +25,19 @@ import (
 )
 
 // MinionRegistryStorage implements the RESTStorage interface, backed by a MinionRegistry.
+// Minions can be created and deleted, but not updated.
 type MinionRegistryStorage struct {
 	registry MinionRegistry
+	pods     apiserver.Lister
 }
 
-func MakeMinionRegistryStorage(m MinionRegistry) apiserver.RESTStorage {
+// MakeMinionRegistryStorage makes a new MinionRegistryStorage. If pods isn't nil, the pods
+// running on a minion are listed from it, as the pods subresource of the minion, and the
+// minions returned carry the Usage of their pods.
+func MakeMinionRegistryStorage(m MinionRegistry, pods apiserver.Lister) apiserver.RESTStorage {
 	return &MinionRegistryStorage{
 		registry: m,
+		pods:     pods,
 	}
 }
 
@@ -39,11 +45,35 @@ func (storage *MinionRegistryStorage) toApiMinion(name string) api.Minion {
 	return api.Minion{JSONBase: api.JSONBase{ID: name}}
 }
 
-func (storage *MinionRegistryStorage) List(selector labels.Selector) (interface{}, error) {
-	nameList, err := storage.registry.List()
+func (storage *MinionRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	list, err := storage.list()
 	if err != nil {
 		return nil, err
 	}
+	if storage.pods != nil && len(list.Items) > 0 {
+		pods, err := storage.allPods()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			list.Items[i].Usage = minionUsage(podsOn(list.Items[i].ID, pods))
+		}
+	}
+	return list, nil
+}
+
+func (storage *MinionRegistryStorage) list() (api.MinionList, error) {
+	if info, ok := storage.registry.(MinionInfoRegistry); ok {
+		minions, err := info.Minions()
+		if err != nil {
+			return api.MinionList{}, err
+		}
+		return api.MinionList{Items: minions}, nil
+	}
+	nameList, err := storage.registry.List()
+	if err != nil {
+		return api.MinionList{}, err
+	}
 	var list api.MinionList
 	for _, name := range nameList {
 		list.Items = append(list.Items, storage.toApiMinion(name))
@@ -51,19 +81,47 @@ func (storage *MinionRegistryStorage) List(selector labels.Selector) (interface{
 	return list, nil
 }
 
-func (storage *MinionRegistryStorage) Get(id string) (interface{}, error) {
+func (storage *MinionRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	minion, err := storage.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if storage.pods != nil {
+		pods, err := storage.allPods()
+		if err != nil {
+			return nil, err
+		}
+		minion.Usage = minionUsage(podsOn(id, pods))
+	}
+	return minion, nil
+}
+
+func (storage *MinionRegistryStorage) get(id string) (api.Minion, error) {
+	if info, ok := storage.registry.(MinionInfoRegistry); ok {
+		minion, exists, err := info.Minion(id)
+		if err != nil {
+			return api.Minion{}, err
+		}
+		if !exists {
+			return api.Minion{}, apiserver.NewNotFoundErr("minion", id)
+		}
+		return minion, nil
+	}
 	exists, err := storage.registry.Contains(id)
+	if err != nil {
+		return api.Minion{}, err
+	}
 	if !exists {
-		return nil, ErrDoesNotExist
+		return api.Minion{}, apiserver.NewNotFoundErr("minion", id)
 	}
-	return storage.toApiMinion(id), err
+	return storage.toApiMinion(id), nil
 }
 
 func (storage *MinionRegistryStorage) New() interface{} {
 	return &api.Minion{}
 }
 
-func (storage *MinionRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (storage *MinionRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	minion, ok := obj.(*api.Minion)
 	if !ok {
 		return nil, fmt.Errorf("not a minion: %#v", obj)
@@ -81,20 +139,16 @@ func (storage *MinionRegistryStorage) Create(obj interface{}) (<-chan interface{
 			return nil, err
 		}
 		if contains {
-			return storage.toApiMinion(minion.ID), nil
+			return storage.Get(ctx, minion.ID)
 		}
 		return nil, fmt.Errorf("unable to add minion %#v", minion)
 	}), nil
 }
 
-func (storage *MinionRegistryStorage) Update(minion interface{}) (<-chan interface{}, error) {
-	return nil, fmt.Errorf("Minions can only be created (inserted) and deleted.")
-}
-
-func (storage *MinionRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (storage *MinionRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	exists, err := storage.registry.Contains(id)
 	if !exists {
-		return nil, ErrDoesNotExist
+		return nil, apiserver.NewNotFoundErr("minion", id)
 	}
 	if err != nil {
 		return nil, err
@@ -103,3 +157,81 @@ func (storage *MinionRegistryStorage) Delete(id string) (<-chan interface{}, err
 		return &api.Status{Status: api.StatusSuccess}, storage.registry.Delete(id)
 	}), nil
 }
+
+// Subresources implements apiserver.SubresourceStorage. The pods of a minion are the pods
+// running on it.
+func (storage *MinionRegistryStorage) Subresources() []string {
+	if storage.pods == nil {
+		return []string{}
+	}
+	return []string{"pods"}
+}
+
+func (storage *MinionRegistryStorage) NewSubresource(subresource string) interface{} {
+	return &api.PodList{}
+}
+
+// GetSubresource lists the pods whose current host is the minion with the given id. Like
+// other lists of pods, they are kept to the namespace of the request, though the Usage of the
+// minion counts the pods of every namespace.
+func (storage *MinionRegistryStorage) GetSubresource(ctx api.Context, id, subresource string) (interface{}, error) {
+	if _, err := storage.get(id); err != nil {
+		return nil, err
+	}
+	pods, err := storage.allPods()
+	if err != nil {
+		return nil, err
+	}
+	return &api.PodList{Items: podsOn(id, pods)}, nil
+}
+
+// UpdateSubresource implements apiserver.SubresourceStorage. The pods of a minion can only be
+// changed through the pods themselves.
+func (storage *MinionRegistryStorage) UpdateSubresource(ctx api.Context, id, subresource string, obj interface{}) (<-chan interface{}, error) {
+	return nil, apiserver.NewMethodNotSupported("minion", "update "+subresource)
+}
+
+// allPods lists the pods of every namespace.
+func (storage *MinionRegistryStorage) allPods() ([]api.Pod, error) {
+	obj, err := storage.pods.List(api.NewContext(), labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	switch list := obj.(type) {
+	case api.PodList:
+		return list.Items, nil
+	case *api.PodList:
+		return list.Items, nil
+	}
+	return nil, fmt.Errorf("not a list of pods: %#v", obj)
+}
+
+// podsOn returns the pods of pods whose current host is host.
+func podsOn(host string, pods []api.Pod) []api.Pod {
+	on := []api.Pod{}
+	for _, pod := range pods {
+		if pod.CurrentState.Host == host {
+			on = append(on, pod)
+		}
+	}
+	return on
+}
+
+// minionUsage sums what the containers of pods request.
+func minionUsage(pods []api.Pod) *api.MinionUsage {
+	usage := &api.MinionUsage{Pods: len(pods)}
+	for _, pod := range pods {
+		unbounded := false
+		for _, container := range pod.DesiredState.Manifest.Containers {
+			usage.Memory += container.Memory
+			usage.CPU += container.CPU
+			if container.Memory == 0 || container.CPU == 0 {
+				unbounded = true
+			}
+		}
+		if unbounded {
+			usage.UnboundedPods++
+		}
+	}
+	return usage
+}
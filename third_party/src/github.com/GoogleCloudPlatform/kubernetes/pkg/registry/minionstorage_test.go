@@ -18,27 +18,30 @@ package registry
 
 import (
 	"reflect"
+	"sort"
+	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 )
 
 func TestMinionRegistryStorage(t *testing.T) {
 	m := MakeMinionRegistry([]string{"foo", "bar"})
-	ms := MakeMinionRegistryStorage(m)
+	ms := MakeMinionRegistryStorage(m, nil).(*MinionRegistryStorage)
 
-	if obj, err := ms.Get("foo"); err != nil || obj.(api.Minion).ID != "foo" {
+	if obj, err := ms.Get(api.NewContext(), "foo"); err != nil || obj.(api.Minion).ID != "foo" {
 		t.Errorf("missing expected object")
 	}
-	if obj, err := ms.Get("bar"); err != nil || obj.(api.Minion).ID != "bar" {
+	if obj, err := ms.Get(api.NewContext(), "bar"); err != nil || obj.(api.Minion).ID != "bar" {
 		t.Errorf("missing expected object")
 	}
-	if _, err := ms.Get("baz"); err != ErrDoesNotExist {
+	if _, err := ms.Get(api.NewContext(), "baz"); !apiserver.IsNotFound(err) {
 		t.Errorf("has unexpected object")
 	}
 
-	c, err := ms.Create(&api.Minion{JSONBase: api.JSONBase{ID: "baz"}})
+	c, err := ms.Create(api.NewContext(), &api.Minion{JSONBase: api.JSONBase{ID: "baz"}})
 	if err != nil {
 		t.Errorf("insert failed")
 	}
@@ -46,11 +49,11 @@ func TestMinionRegistryStorage(t *testing.T) {
 	if m, ok := obj.(api.Minion); !ok || m.ID != "baz" {
 		t.Errorf("insert return value was weird: %#v", obj)
 	}
-	if obj, err := ms.Get("baz"); err != nil || obj.(api.Minion).ID != "baz" {
+	if obj, err := ms.Get(api.NewContext(), "baz"); err != nil || obj.(api.Minion).ID != "baz" {
 		t.Errorf("insert didn't actually insert")
 	}
 
-	c, err = ms.Delete("bar")
+	c, err = ms.Delete(api.NewContext(), "bar")
 	if err != nil {
 		t.Errorf("delete failed")
 	}
@@ -58,16 +61,16 @@ func TestMinionRegistryStorage(t *testing.T) {
 	if s, ok := obj.(*api.Status); !ok || s.Status != api.StatusSuccess {
 		t.Errorf("delete return value was weird: %#v", obj)
 	}
-	if _, err := ms.Get("bar"); err != ErrDoesNotExist {
+	if _, err := ms.Get(api.NewContext(), "bar"); !apiserver.IsNotFound(err) {
 		t.Errorf("delete didn't actually delete")
 	}
 
-	_, err = ms.Delete("bar")
-	if err != ErrDoesNotExist {
+	_, err = ms.Delete(api.NewContext(), "bar")
+	if !apiserver.IsNotFound(err) {
 		t.Errorf("delete returned wrong error")
 	}
 
-	list, err := ms.List(labels.Everything())
+	list, err := ms.List(api.NewContext(), labels.Everything())
 	if err != nil {
 		t.Errorf("got error calling List")
 	}
@@ -82,3 +85,80 @@ func TestMinionRegistryStorage(t *testing.T) {
 		t.Errorf("Unexpected list value: %#v", list)
 	}
 }
+
+func TestMinionRegistryStorageUpdate(t *testing.T) {
+	ms := MakeMinionRegistryStorage(MakeMinionRegistry([]string{"foo"}), nil)
+	if _, ok := ms.(apiserver.Updater); ok {
+		t.Errorf("expected minions not to support update")
+	}
+}
+
+func TestMinionRegistryStoragePods(t *testing.T) {
+	memory := MakeMemoryRegistry()
+	for _, pod := range []api.Pod{
+		{JSONBase: api.JSONBase{ID: "bounded"}, CurrentState: api.PodState{Host: "foo"}, DesiredState: api.PodState{Manifest: api.ContainerManifest{
+			Containers: []api.Container{{Memory: 100, CPU: 10}, {Memory: 50, CPU: 5}},
+		}}},
+		{JSONBase: api.JSONBase{ID: "unbounded", Namespace: "other"}, CurrentState: api.PodState{Host: "foo"}, DesiredState: api.PodState{Manifest: api.ContainerManifest{
+			Containers: []api.Container{{Memory: 10}},
+		}}},
+		{JSONBase: api.JSONBase{ID: "elsewhere"}, CurrentState: api.PodState{Host: "bar"}, DesiredState: api.PodState{Manifest: api.ContainerManifest{
+			Containers: []api.Container{{Memory: 1000, CPU: 1000}},
+		}}},
+	} {
+		memory.CreatePod(pod.CurrentState.Host, pod)
+	}
+	ms := MakeMinionRegistryStorage(MakeMinionRegistry([]string{"foo", "bar", "baz"}), &PodRegistryStorage{registry: memory}).(*MinionRegistryStorage)
+
+	obj, err := ms.Get(api.NewContext(), "foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &api.MinionUsage{Pods: 2, Memory: 160, CPU: 15, UnboundedPods: 1}
+	if usage := obj.(api.Minion).Usage; !reflect.DeepEqual(usage, expected) {
+		t.Errorf("expected %#v, got %#v", expected, usage)
+	}
+
+	list, err := ms.List(api.NewContext(), labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	usages := map[string]api.MinionUsage{}
+	for _, minion := range list.(api.MinionList).Items {
+		usages[minion.ID] = *minion.Usage
+	}
+	expectedUsages := map[string]api.MinionUsage{
+		"foo": *expected,
+		"bar": {Pods: 1, Memory: 1000, CPU: 1000},
+		"baz": {},
+	}
+	if !reflect.DeepEqual(usages, expectedUsages) {
+		t.Errorf("expected %#v, got %#v", expectedUsages, usages)
+	}
+
+	obj, err = ms.GetSubresource(api.NewContext(), "foo", "pods")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ids := []string{}
+	for _, pod := range obj.(*api.PodList).Items {
+		ids = append(ids, pod.ID)
+	}
+	// The pods are listed in the order of the registry, which doesn't keep one.
+	sort.Strings(ids)
+	if strings.Join(ids, ",") != "bounded,unbounded" {
+		t.Errorf("expected the pods running on the minion, got %v", ids)
+	}
+	if _, err := ms.GetSubresource(api.NewContext(), "missing", "pods"); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a missing minion not to be found, got %v", err)
+	}
+	if _, err := ms.UpdateSubresource(api.NewContext(), "foo", "pods", &api.PodList{}); err == nil {
+		t.Errorf("expected the pods of a minion not to be updated")
+	}
+	if subresources := MakeMinionRegistryStorage(MakeMinionRegistry(nil), nil).(*MinionRegistryStorage).Subresources(); len(subresources) != 0 {
+		t.Errorf("expected no pods subresource without pods, got %v", subresources)
+	}
+	if obj, _ := MakeMinionRegistryStorage(MakeMinionRegistry([]string{"foo"}), nil).(*MinionRegistryStorage).Get(api.NewContext(), "foo"); obj.(api.Minion).Usage != nil {
+		t.Errorf("expected no usage without pods, got %#v", obj)
+	}
+}
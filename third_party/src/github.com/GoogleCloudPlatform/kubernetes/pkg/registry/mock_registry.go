@@ -21,6 +21,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 )
 
 type MockPodRegistry struct {
@@ -70,12 +71,27 @@ func (registry *MockPodRegistry) UpdatePod(pod api.Pod) error {
 	return registry.err
 }
 
+func (registry *MockPodRegistry) AssignPod(podId string, machine string) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.pod != nil {
+		registry.pod.DesiredState.Host = machine
+	}
+	return registry.err
+}
+
 func (registry *MockPodRegistry) DeletePod(podId string) error {
 	registry.Lock()
 	defer registry.Unlock()
 	return registry.err
 }
 
+func (registry *MockPodRegistry) WatchPods(label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	registry.Lock()
+	defer registry.Unlock()
+	return nil, registry.err
+}
+
 type MockMinionRegistry struct {
 	err     error
 	minion  string
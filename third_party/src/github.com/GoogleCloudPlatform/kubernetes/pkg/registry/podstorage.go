@@ -29,6 +29,7 @@ import (
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/scheduler"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
 	"github.com/golang/glog"
 )
 
@@ -42,16 +43,19 @@ type PodRegistryStorage struct {
 	cloud         cloudprovider.Interface
 	podPollPeriod time.Duration
 	lock          sync.Mutex
+	// objectLabels serves the labels of pods, if registry is a LabelRegistry.
+	objectLabels *labelsSubresource
 }
 
 // MakePodRegistryStorage makes a RESTStorage object for a pod registry.
 // Parameters:
-//   registry:      The pod registry
-//   podInfoGetter: Source of fresh container info
-//   scheduler:     The scheduler for assigning pods to machines
-//   minionLister:  Object which can list available minions for the scheduler
-//   cloud:         Interface to a cloud provider (may be null)
-//   podCache:      Source of cached container info
+//
+//	registry:      The pod registry
+//	podInfoGetter: Source of fresh container info
+//	scheduler:     The scheduler for assigning pods to machines
+//	minionLister:  Object which can list available minions for the scheduler
+//	cloud:         Interface to a cloud provider (may be null)
+//	podCache:      Source of cached container info
 func MakePodRegistryStorage(registry PodRegistry,
 	podInfoGetter client.PodInfoGetter,
 	scheduler scheduler.Scheduler,
@@ -66,15 +70,20 @@ func MakePodRegistryStorage(registry PodRegistry,
 		cloud:         cloud,
 		podCache:      podCache,
 		podPollPeriod: time.Second * 10,
+		objectLabels:  makeLabelsSubresource("pod", registry),
 	}
 }
 
-func (storage *PodRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (storage *PodRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	var result api.PodList
 	pods, err := storage.registry.ListPods(selector)
 	if err == nil {
 		result.Items = pods
 		for i := range result.Items {
+			// Filling in the info of a pod may ask its minion, so stop once nobody waits for the list.
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
 			storage.fillPodInfo(&result.Items[i])
 		}
 	}
@@ -86,13 +95,14 @@ func (storage *PodRegistryStorage) fillPodInfo(pod *api.Pod) {
 	// Get cached info for the list currently.
 	// TODO: Optionally use fresh info
 	if storage.podCache != nil {
-		info, err := storage.podCache.GetPodInfo(pod.CurrentState.Host, pod.ID)
+		manifestID := PodManifestID(pod.Namespace, pod.ID)
+		info, err := storage.podCache.GetPodInfo(pod.CurrentState.Host, manifestID)
 		if err != nil {
 			if err != client.ErrPodInfoNotAvailable {
 				glog.Errorf("Error getting container info from cache: %#v", err)
 			}
 			if storage.podInfoGetter != nil {
-				info, err = storage.podInfoGetter.GetPodInfo(pod.CurrentState.Host, pod.ID)
+				info, err = storage.podInfoGetter.GetPodInfo(pod.CurrentState.Host, manifestID)
 			}
 			if err != nil {
 				if err != client.ErrPodInfoNotAvailable {
@@ -166,8 +176,8 @@ func getInstanceIP(cloud cloudprovider.Interface, host string) string {
 	return addr.String()
 }
 
-func (storage *PodRegistryStorage) Get(id string) (interface{}, error) {
-	pod, err := storage.registry.GetPod(id)
+func (storage *PodRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	pod, err := storage.registry.GetPod(api.QualifiedID(ctx.Namespace, id))
 	if err != nil {
 		return pod, err
 	}
@@ -183,9 +193,9 @@ func (storage *PodRegistryStorage) Get(id string) (interface{}, error) {
 	return pod, err
 }
 
-func (storage *PodRegistryStorage) Delete(id string) (<-chan interface{}, error) {
+func (storage *PodRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
 	return apiserver.MakeAsync(func() (interface{}, error) {
-		return &api.Status{Status: api.StatusSuccess}, storage.registry.DeletePod(id)
+		return &api.Status{Status: api.StatusSuccess}, storage.registry.DeletePod(api.QualifiedID(ctx.Namespace, id))
 	}), nil
 }
 
@@ -204,43 +214,148 @@ func (storage *PodRegistryStorage) scheduleAndCreatePod(pod api.Pod) error {
 	return storage.registry.CreatePod(machine, pod)
 }
 
-func (storage *PodRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+// Default implements apiserver.Defaulter. A pod without an ID is given a new UUID, and
+// its manifest is named after it.
+func (storage *PodRegistryStorage) Default(ctx api.Context, obj interface{}) {
 	pod := obj.(*api.Pod)
 	if len(pod.ID) == 0 {
 		pod.ID = uuid.NewUUID().String()
 	}
-	pod.DesiredState.Manifest.ID = pod.ID
+	pod.DesiredState.Manifest.ID = PodManifestID(ctx.Namespace, pod.ID)
+}
 
-	if errs := api.ValidatePod(pod); len(errs) > 0 {
-		return nil, fmt.Errorf("Validation errors: %v", errs)
+// PodManifestID returns the name that the pod called id in namespace is known by on its
+// host: its ID, followed by its namespace if it is not the default one. Unlike qualified
+// IDs, these names are valid DNS subdomains, as the kubelet requires.
+func PodManifestID(namespace, id string) string {
+	if namespace == api.NamespaceAll || namespace == api.NamespaceDefault {
+		return id
 	}
+	return id + "." + namespace
+}
 
+func (storage *PodRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
+	pod := obj.(*api.Pod)
+	storage.Default(ctx, pod)
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		err := storage.scheduleAndCreatePod(*pod)
 		if err != nil {
 			return nil, err
 		}
-		return storage.waitForPodRunning(*pod)
+		return storage.waitForPodRunning(ctx, *pod)
 	}), nil
 }
 
-func (storage *PodRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (storage *PodRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	pod := obj.(*api.Pod)
-	if errs := api.ValidatePod(pod); len(errs) > 0 {
-		return nil, fmt.Errorf("Validation errors: %v", errs)
-	}
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		err := storage.registry.UpdatePod(*pod)
 		if err != nil {
 			return nil, err
 		}
-		return storage.waitForPodRunning(*pod)
+		return storage.waitForPodRunning(ctx, *pod)
 	}), nil
 }
 
-func (storage *PodRegistryStorage) waitForPodRunning(pod api.Pod) (interface{}, error) {
+// Watch returns pod events via a watch.Interface, implementing apiserver.ResourceWatcher.
+func (storage *PodRegistryStorage) Watch(ctx api.Context, label, field labels.Selector, resourceVersion uint64) (watch.Interface, error) {
+	return storage.registry.WatchPods(label, field, resourceVersion)
+}
+
+// Subresources implements apiserver.SubresourceStorage. The binding of a pod names the
+// machine it is assigned to. Its labels can be changed without the rest of it.
+func (storage *PodRegistryStorage) Subresources() []string {
+	if storage.objectLabels == nil {
+		return []string{"binding"}
+	}
+	return []string{"binding", "labels"}
+}
+
+func (storage *PodRegistryStorage) NewSubresource(subresource string) interface{} {
+	if subresource == "labels" {
+		return &api.ObjectLabels{}
+	}
+	return &api.Binding{}
+}
+
+func (storage *PodRegistryStorage) GetSubresource(ctx api.Context, id, subresource string) (interface{}, error) {
+	if subresource == "labels" {
+		return storage.objectLabels.get(ctx, id)
+	}
+	pod, err := storage.registry.GetPod(api.QualifiedID(ctx.Namespace, id))
+	if err != nil {
+		return nil, err
+	}
+	if pod == nil {
+		return nil, apiserver.NewNotFoundErr("pod", id)
+	}
+	return makeBinding(pod, pod.DesiredState.Host), nil
+}
+
+// UpdateSubresource assigns the pod with the given id to the host of the binding it receives.
+// The rest of the pod is left as it is. A pod that is already assigned to another host
+// is a conflict; it has to be deleted and created again to move.
+func (storage *PodRegistryStorage) UpdateSubresource(ctx api.Context, id, subresource string, obj interface{}) (<-chan interface{}, error) {
+	if subresource == "labels" {
+		return storage.objectLabels.update(ctx, id, obj)
+	}
+	binding, ok := obj.(*api.Binding)
+	if !ok {
+		return nil, fmt.Errorf("not a binding: %#v", obj)
+	}
+	if len(binding.Host) == 0 {
+		return nil, apiserver.NewInvalidErr("binding", id, api.ValidationErrorList{
+			api.ValidationError{ErrorType: api.ErrTypeInvalid, ErrorField: "host", BadValue: ""},
+		})
+	}
+	podID := api.QualifiedID(ctx.Namespace, id)
+	pod, err := storage.registry.GetPod(podID)
+	if err != nil {
+		return nil, err
+	}
+	if pod == nil {
+		return nil, apiserver.NewNotFoundErr("pod", id)
+	}
+	if err := checkAssignable(pod, binding.Host); err != nil {
+		return nil, err
+	}
+	return apiserver.MakeAsync(func() (interface{}, error) {
+		if err := storage.registry.AssignPod(podID, binding.Host); err != nil {
+			return nil, err
+		}
+		return makeBinding(pod, binding.Host), nil
+	}), nil
+}
+
+// PatchSubresource implements apiserver.SubresourcePatcher. Only the labels of a pod can be
+// patched.
+func (storage *PodRegistryStorage) PatchSubresource(ctx api.Context, id, subresource string, patch apiserver.MergePatch) (<-chan interface{}, error) {
+	if subresource != "labels" {
+		return nil, apiserver.NewMethodNotSupported("pod", "patch "+subresource)
+	}
+	return storage.objectLabels.patch(ctx, id, patch)
+}
+
+// checkAssignable returns a conflict error if pod is already assigned to a host other than host.
+func checkAssignable(pod *api.Pod, host string) error {
+	if len(pod.DesiredState.Host) == 0 || pod.DesiredState.Host == host {
+		return nil
+	}
+	return apiserver.NewConflictErr("pod", pod.ID, fmt.Errorf("pod is already assigned to host %q", pod.DesiredState.Host))
+}
+
+// makeBinding returns the binding of pod to host, named and namespaced like pod.
+func makeBinding(pod *api.Pod, host string) *api.Binding {
+	return &api.Binding{
+		JSONBase: api.JSONBase{ID: pod.ID, Namespace: pod.Namespace},
+		PodID:    pod.ID,
+		Host:     host,
+	}
+}
+
+func (storage *PodRegistryStorage) waitForPodRunning(ctx api.Context, pod api.Pod) (interface{}, error) {
 	for {
-		podObj, err := storage.Get(pod.ID)
+		podObj, err := storage.Get(ctx, pod.ID)
 
 		if err != nil || podObj == nil {
 			return nil, err
@@ -17,12 +17,16 @@ limitations under the License.
 package registry
 
 import (
+	"bytes"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/scheduler"
@@ -65,7 +69,7 @@ func TestCreatePodRegistryError(t *testing.T) {
 		},
 	}
 	pod := &api.Pod{DesiredState: desiredState}
-	ch, err := storage.Create(pod)
+	ch, err := storage.Create(api.NewContext(), pod)
 	if err != nil {
 		t.Errorf("Expected %#v, Got %#v", nil, err)
 	}
@@ -96,7 +100,7 @@ func TestCreatePodSchedulerError(t *testing.T) {
 		},
 	}
 	pod := &api.Pod{DesiredState: desiredState}
-	ch, err := storage.Create(pod)
+	ch, err := storage.Create(api.NewContext(), pod)
 	if err != nil {
 		t.Errorf("Expected %#v, Got %#v", nil, err)
 	}
@@ -128,7 +132,7 @@ func TestCreatePodSetsIds(t *testing.T) {
 		},
 	}
 	pod := &api.Pod{DesiredState: desiredState}
-	ch, err := storage.Create(pod)
+	ch, err := storage.Create(api.NewContext(), pod)
 	if err != nil {
 		t.Errorf("Expected %#v, Got %#v", nil, err)
 	}
@@ -149,7 +153,7 @@ func TestListPodsError(t *testing.T) {
 	storage := PodRegistryStorage{
 		registry: &mockRegistry,
 	}
-	pods, err := storage.List(labels.Everything())
+	pods, err := storage.List(api.NewContext(), labels.Everything())
 	if err != mockRegistry.err {
 		t.Errorf("Expected %#v, Got %#v", mockRegistry.err, err)
 	}
@@ -163,7 +167,7 @@ func TestListEmptyPodList(t *testing.T) {
 	storage := PodRegistryStorage{
 		registry: &mockRegistry,
 	}
-	pods, err := storage.List(labels.Everything())
+	pods, err := storage.List(api.NewContext(), labels.Everything())
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -191,7 +195,7 @@ func TestListPodList(t *testing.T) {
 	storage := PodRegistryStorage{
 		registry: &mockRegistry,
 	}
-	podsObj, err := storage.List(labels.Everything())
+	podsObj, err := storage.List(api.NewContext(), labels.Everything())
 	pods := podsObj.(api.PodList)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -242,7 +246,7 @@ func TestGetPod(t *testing.T) {
 	storage := PodRegistryStorage{
 		registry: &mockRegistry,
 	}
-	obj, err := storage.Get("foo")
+	obj, err := storage.Get(api.NewContext(), "foo")
 	pod := obj.(*api.Pod)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -264,7 +268,7 @@ func TestGetPodCloud(t *testing.T) {
 		registry: &mockRegistry,
 		cloud:    fakeCloud,
 	}
-	obj, err := storage.Get("foo")
+	obj, err := storage.Get(api.NewContext(), "foo")
 	pod := obj.(*api.Pod)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
@@ -372,21 +376,35 @@ func TestMakePodStatus(t *testing.T) {
 	}
 }
 
+// serveStorage sends obj to storage, served by an apiserver as resource, with the
+// given method and path, and returns the status code of the response.
+func serveStorage(t *testing.T, resource string, storage apiserver.RESTStorage, method, path string, obj interface{}) int {
+	var data []byte
+	if obj != nil {
+		var err error
+		if data, err = api.Encode(obj); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	req, err := http.NewRequest(method, "/prefix/"+path, bytes.NewBuffer(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	w := httptest.NewRecorder()
+	apiserver.New(map[string]apiserver.RESTStorage{resource: storage}, api.Codec, "/prefix").ServeHTTP(w, req)
+	return w.Code
+}
+
 func TestPodStorageValidatesCreate(t *testing.T) {
 	mockRegistry := &MockPodStorageRegistry{
 		MockPodRegistry: MockPodRegistry{err: fmt.Errorf("test error")},
 	}
-	storage := PodRegistryStorage{
+	storage := &PodRegistryStorage{
 		scheduler: &MockScheduler{machine: "test"},
 		registry:  mockRegistry,
 	}
-	pod := &api.Pod{}
-	c, err := storage.Create(pod)
-	if c != nil {
-		t.Errorf("Expected nil channel")
-	}
-	if err == nil {
-		t.Errorf("Expected to get an error")
+	if code := serveStorage(t, "pods", storage, "POST", "pods?dryRun=true", &api.Pod{}); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected %d, got %d", http.StatusUnprocessableEntity, code)
 	}
 }
 
@@ -394,17 +412,28 @@ func TestPodStorageValidatesUpdate(t *testing.T) {
 	mockRegistry := &MockPodStorageRegistry{
 		MockPodRegistry: MockPodRegistry{err: fmt.Errorf("test error")},
 	}
-	storage := PodRegistryStorage{
+	storage := &PodRegistryStorage{
 		scheduler: &MockScheduler{machine: "test"},
 		registry:  mockRegistry,
 	}
-	pod := &api.Pod{}
-	c, err := storage.Update(pod)
-	if c != nil {
-		t.Errorf("Expected nil channel")
+	if code := serveStorage(t, "pods", storage, "PUT", "pods/foo?dryRun=true", &api.Pod{}); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected %d, got %d", http.StatusUnprocessableEntity, code)
 	}
-	if err == nil {
-		t.Errorf("Expected to get an error")
+}
+
+func TestPodStorageDefaultsID(t *testing.T) {
+	storage := &PodRegistryStorage{}
+	pod := &api.Pod{
+		DesiredState: api.PodState{
+			Manifest: api.ContainerManifest{Version: "v1beta1"},
+		},
+	}
+	storage.Default(api.NewContext(), pod)
+	if len(pod.ID) == 0 || pod.DesiredState.Manifest.ID != pod.ID {
+		t.Errorf("Expected the pod and its manifest to be given an ID, got %#v", pod)
+	}
+	if errs := api.Validate(pod); len(errs) != 0 {
+		t.Errorf("Expected a defaulted pod to be valid, got %v", errs)
 	}
 }
 
@@ -432,7 +461,7 @@ func TestCreatePod(t *testing.T) {
 		JSONBase:     api.JSONBase{ID: "foo"},
 		DesiredState: desiredState,
 	}
-	channel, err := storage.Create(pod)
+	channel, err := storage.Create(api.NewContext(), pod)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -522,3 +551,47 @@ func TestFillPodInfoNoData(t *testing.T) {
 		t.Errorf("Expected %s, Got %s", expectedIP, pod.CurrentState.PodIP)
 	}
 }
+
+func TestPodBinding(t *testing.T) {
+	registry := MakeMemoryRegistry()
+	registry.CreatePod("", api.Pod{JSONBase: api.JSONBase{ID: "foo"}, Labels: map[string]string{"name": "foo"}})
+	storage := PodRegistryStorage{registry: registry}
+
+	channel, err := storage.UpdateSubresource(api.NewContext(), "foo", "binding", &api.Binding{Host: "machine"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-channel
+	pod, _ := registry.GetPod("foo")
+	if pod.DesiredState.Host != "machine" || pod.Labels["name"] != "foo" {
+		t.Errorf("expected only the host of the pod to change, got %#v", pod)
+	}
+	obj, err := storage.GetSubresource(api.NewContext(), "foo", "binding")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if binding := obj.(*api.Binding); binding.PodID != "foo" || binding.Host != "machine" {
+		t.Errorf("unexpected binding %#v", binding)
+	}
+
+	if _, err := storage.UpdateSubresource(api.NewContext(), "foo", "binding", &api.Binding{}); !apiserver.IsInvalid(err) {
+		t.Errorf("expected a binding without a host to be invalid, got %v", err)
+	}
+	if _, err := storage.UpdateSubresource(api.NewContext(), "bar", "binding", &api.Binding{Host: "machine"}); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a binding of a missing pod to be not found, got %v", err)
+	}
+	if _, err := storage.UpdateSubresource(api.NewContext(), "foo", "binding", &api.Binding{Host: "other"}); !apiserver.IsConflict(err) {
+		t.Errorf("expected a binding to another host to conflict, got %v", err)
+	}
+	if code := serveStorage(t, "pods", &storage, "PUT", "pods/foo/binding", &api.Binding{Host: "other"}); code != http.StatusConflict {
+		t.Errorf("expected %d, got %d", http.StatusConflict, code)
+	}
+	channel, err = storage.UpdateSubresource(api.NewContext(), "foo", "binding", &api.Binding{Host: "machine"})
+	if err != nil {
+		t.Fatalf("expected binding to the same host again to succeed, got %v", err)
+	}
+	<-channel
+	if pod, _ := registry.GetPod("foo"); pod.DesiredState.Host != "machine" {
+		t.Errorf("expected the pod to stay on its host, got %#v", pod)
+	}
+}
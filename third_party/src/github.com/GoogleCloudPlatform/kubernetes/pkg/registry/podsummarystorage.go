@@ -0,0 +1,92 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+// PodSummaryStorage serves the podSummary resource: the pods of the pods resource, counted.
+type PodSummaryStorage struct {
+	pods apiserver.Lister
+}
+
+// NewPodSummaryStorage returns the storage of the summaries of the pods pods lists.
+func NewPodSummaryStorage(pods apiserver.Lister) apiserver.RESTStorage {
+	return &PodSummaryStorage{pods: pods}
+}
+
+// New returns a new api.PodSummary.
+func (storage *PodSummaryStorage) New() interface{} {
+	return &api.PodSummary{}
+}
+
+// List counts the pods of the namespace of ctx that match selector.
+func (storage *PodSummaryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
+	obj, err := storage.pods.List(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	var pods []api.Pod
+	switch list := obj.(type) {
+	case api.PodList:
+		pods = list.Items
+	case *api.PodList:
+		pods = list.Items
+	default:
+		return nil, fmt.Errorf("not a list of pods: %#v", obj)
+	}
+	summary := &api.PodSummary{ByStatus: map[string]int{}, ByHost: map[string]int{}}
+	for i := range pods {
+		pod := &pods[i]
+		if !podInNamespace(pod, ctx.Namespace) {
+			continue
+		}
+		summary.Total++
+		status := string(pod.CurrentState.Status)
+		if len(status) == 0 {
+			status = api.PodSummaryUnknown
+		}
+		summary.ByStatus[status]++
+		host := pod.CurrentState.Host
+		if len(host) == 0 {
+			host = pod.DesiredState.Host
+		}
+		if len(host) == 0 {
+			summary.Unassigned++
+		} else {
+			summary.ByHost[host]++
+		}
+	}
+	return summary, nil
+}
+
+// podInNamespace returns true if pod is in namespace, pods without a namespace being in the
+// default one.
+func podInNamespace(pod *api.Pod, namespace string) bool {
+	if namespace == api.NamespaceAll {
+		return true
+	}
+	if len(pod.Namespace) == 0 {
+		return namespace == api.NamespaceDefault
+	}
+	return pod.Namespace == namespace
+}
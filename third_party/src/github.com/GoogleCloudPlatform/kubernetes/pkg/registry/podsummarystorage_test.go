@@ -0,0 +1,83 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
+)
+
+func TestPodSummaryStorage(t *testing.T) {
+	memory := MakeMemoryRegistry()
+	for _, pod := range []api.Pod{
+		{JSONBase: api.JSONBase{ID: "a"}, Labels: map[string]string{"name": "web"}, CurrentState: api.PodState{Host: "foo", Status: api.PodRunning}},
+		{JSONBase: api.JSONBase{ID: "b"}, Labels: map[string]string{"name": "web"}, CurrentState: api.PodState{Host: "foo", Status: api.PodRunning}},
+		{JSONBase: api.JSONBase{ID: "c"}, Labels: map[string]string{"name": "web"}, CurrentState: api.PodState{Host: "bar", Status: api.PodTerminated}},
+		{JSONBase: api.JSONBase{ID: "d"}, Labels: map[string]string{"name": "db"}},
+		{JSONBase: api.JSONBase{ID: "e", Namespace: "other"}, Labels: map[string]string{"name": "web"}, CurrentState: api.PodState{Host: "foo", Status: api.PodWaiting}},
+	} {
+		memory.CreatePod(pod.CurrentState.Host, pod)
+	}
+	storage := NewPodSummaryStorage(&PodRegistryStorage{registry: memory}).(*PodSummaryStorage)
+
+	table := []struct {
+		ctx      api.Context
+		selector labels.Selector
+		summary  *api.PodSummary
+	}{
+		{
+			api.NewContext(),
+			labels.Everything(),
+			&api.PodSummary{
+				Total:      5,
+				ByStatus:   map[string]int{"Running": 2, "Terminated": 1, "Waiting": 1, api.PodSummaryUnknown: 1},
+				ByHost:     map[string]int{"foo": 3, "bar": 1},
+				Unassigned: 1,
+			},
+		},
+		{
+			api.NewContext(),
+			labels.Set{"name": "web"}.AsSelector(),
+			&api.PodSummary{
+				Total:    4,
+				ByStatus: map[string]int{"Running": 2, "Terminated": 1, "Waiting": 1},
+				ByHost:   map[string]int{"foo": 3, "bar": 1},
+			},
+		},
+		{
+			api.NewDefaultContext(),
+			labels.Set{"name": "web"}.AsSelector(),
+			&api.PodSummary{
+				Total:    3,
+				ByStatus: map[string]int{"Running": 2, "Terminated": 1},
+				ByHost:   map[string]int{"foo": 2, "bar": 1},
+			},
+		},
+	}
+	for i, item := range table {
+		obj, err := storage.List(item.ctx, item.selector)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(obj, item.summary) {
+			t.Errorf("%d: expected %#v, got %#v", i, item.summary, obj)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/health"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/golang/glog"
+)
+
+// CloudNodes lists the instances of a cloud. cloudprovider.Instances is a CloudNodes.
+type CloudNodes interface {
+	// List lists the instances whose names match the regular expression 'filter'.
+	List(filter string) ([]string, error)
+	// IPAddress returns the IP address of the instance 'name'.
+	IPAddress(name string) (net.IP, error)
+}
+
+// MinionProber returns true if the kubelet of minion is reachable.
+type MinionProber func(minion string) bool
+
+// NewHTTPMinionProber returns a MinionProber which GETs the healthz page of the kubelet of a
+// minion, at the address the minion proxy forwards requests for the minion to.
+func NewHTTPMinionProber(client health.HTTPGetInterface) MinionProber {
+	return func(minion string) bool {
+		status, err := health.DoHTTPCheck(fmt.Sprintf("http://%s/healthz", apiserver.MinionHost(minion)), client)
+		if err != nil {
+			glog.V(1).Infof("%s failed health check with error: %v", minion, err)
+			return false
+		}
+		return status == health.Healthy
+	}
+}
+
+// RefreshingMinionRegistry is a MinionRegistry of the minions it is given or has inserted,
+// and of the cloud instances whose names match a regular expression. The cloud instances,
+// and the health of every minion if it has a prober, are refreshed periodically. Minions
+// whose kubelet is unreachable are kept and marked as such, but left out of List, so that
+// nothing is scheduled onto them.
+type RefreshingMinionRegistry struct {
+	nodes   CloudNodes
+	matchRE string
+	probe   MinionProber
+
+	// lock guards the fields below.
+	lock sync.RWMutex
+	// static holds the minions given or inserted, cloud the instances last listed.
+	static  util.StringSet
+	cloud   util.StringSet
+	minions map[string]api.Minion
+}
+
+// NewRefreshingMinionRegistry returns a RefreshingMinionRegistry of the minions in static,
+// and of the instances of nodes matching matchRE if nodes isn't nil, refreshed every period
+// if period is positive. If probe is nil, the health of minions isn't checked.
+func NewRefreshingMinionRegistry(static []string, nodes CloudNodes, matchRE string, probe MinionProber, period time.Duration) *RefreshingMinionRegistry {
+	r := &RefreshingMinionRegistry{
+		nodes:   nodes,
+		matchRE: matchRE,
+		probe:   probe,
+		static:  util.NewStringSet(static...),
+		cloud:   util.StringSet{},
+		minions: map[string]api.Minion{},
+	}
+	r.Refresh()
+	if period > 0 {
+		go func() {
+			time.Sleep(period)
+			util.Forever(r.Refresh, period)
+		}()
+	}
+	return r
+}
+
+// Refresh lists the cloud instances and checks the health of every minion. If the cloud
+// can't be listed, the instances listed before are kept.
+func (r *RefreshingMinionRegistry) Refresh() {
+	cloud, err := r.listCloud()
+	if err != nil {
+		glog.Errorf("Unable to list the minions of the cloud: %v", err)
+	}
+	r.lock.RLock()
+	if cloud == nil {
+		cloud = r.cloud
+	}
+	names := util.NewStringSet(r.static.List()...)
+	r.lock.RUnlock()
+	names.Insert(cloud.List()...)
+
+	minions := make(map[string]api.Minion, len(names))
+	var minionsLock sync.Mutex
+	var wg sync.WaitGroup
+	for name := range names {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			minion := r.describe(name, cloud.Has(name))
+			minionsLock.Lock()
+			defer minionsLock.Unlock()
+			minions[name] = minion
+		}(name)
+	}
+	wg.Wait()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.cloud = cloud
+	for name := range minions {
+		if !r.static.Has(name) && !r.cloud.Has(name) {
+			// Deleted while it was being described.
+			delete(minions, name)
+		}
+	}
+	for name := range r.static {
+		if _, ok := minions[name]; !ok {
+			// Inserted while the others were being described.
+			minions[name] = r.minions[name]
+		}
+	}
+	r.minions = minions
+}
+
+// listCloud returns the names of the cloud instances, or nil if there is no cloud.
+func (r *RefreshingMinionRegistry) listCloud() (util.StringSet, error) {
+	if r.nodes == nil {
+		return nil, nil
+	}
+	names, err := r.nodes.List(r.matchRE)
+	if err != nil {
+		return nil, err
+	}
+	return util.NewStringSet(names...), nil
+}
+
+// describe returns the minion 'name', with its addresses and health. The addresses of a
+// cloud instance come from the cloud; other minions named by an IP address have that one.
+func (r *RefreshingMinionRegistry) describe(name string, inCloud bool) api.Minion {
+	minion := api.Minion{JSONBase: api.JSONBase{ID: name}}
+	if inCloud {
+		if ip, err := r.nodes.IPAddress(name); err == nil {
+			minion.Addresses = []string{ip.String()}
+		} else {
+			glog.Errorf("Unable to find the address of minion %s: %v", name, err)
+		}
+	} else if ip := net.ParseIP(name); ip != nil {
+		minion.Addresses = []string{ip.String()}
+	}
+	if len(minion.Addresses) > 0 {
+		minion.HostIP = minion.Addresses[0]
+	}
+	if r.probe != nil {
+		minion.Status = api.MinionUnreachable
+		if r.probe(name) {
+			minion.Status = api.MinionReachable
+		}
+	}
+	return minion
+}
+
+// List implements MinionRegistry, listing the minions not known to be unreachable.
+func (r *RefreshingMinionRegistry) List() ([]string, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	names := []string{}
+	for name, minion := range r.minions {
+		if minion.Status != api.MinionUnreachable {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Insert implements MinionRegistry. The health of the minion is checked before it is
+// inserted.
+func (r *RefreshingMinionRegistry) Insert(minion string) error {
+	described := r.describe(minion, false)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.static.Insert(minion)
+	if _, ok := r.minions[minion]; !ok {
+		r.minions[minion] = described
+	}
+	return nil
+}
+
+// Delete implements MinionRegistry. Cloud instances can't be deleted.
+func (r *RefreshingMinionRegistry) Delete(minion string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.cloud.Has(minion) {
+		return fmt.Errorf("minion %s is an instance of the cloud, and can't be deleted", minion)
+	}
+	r.static.Delete(minion)
+	delete(r.minions, minion)
+	return nil
+}
+
+// Contains implements MinionRegistry.
+func (r *RefreshingMinionRegistry) Contains(minion string) (bool, error) {
+	_, ok, err := r.Minion(minion)
+	return ok, err
+}
+
+// Minions implements MinionInfoRegistry.
+func (r *RefreshingMinionRegistry) Minions() ([]api.Minion, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	names := []string{}
+	for name := range r.minions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	minions := []api.Minion{}
+	for _, name := range names {
+		minions = append(minions, r.minions[name])
+	}
+	return minions, nil
+}
+
+// Minion implements MinionInfoRegistry. A minion known only as a cloud instance is looked
+// up in the cloud again, so that an instance that just disappeared isn't found.
+func (r *RefreshingMinionRegistry) Minion(name string) (api.Minion, bool, error) {
+	r.lock.RLock()
+	minion, ok := r.minions[name]
+	recheck := ok && r.cloud.Has(name) && !r.static.Has(name)
+	r.lock.RUnlock()
+	if !recheck {
+		return minion, ok, nil
+	}
+	cloud, err := r.listCloud()
+	if err != nil {
+		return api.Minion{}, false, err
+	}
+	if cloud.Has(name) {
+		return minion, true, nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	// Replace the set rather than change it, since a refresh may be reading it.
+	remaining := util.StringSet{}
+	for instance := range r.cloud {
+		if instance != name {
+			remaining.Insert(instance)
+		}
+	}
+	r.cloud = remaining
+	if !r.static.Has(name) {
+		delete(r.minions, name)
+	}
+	return api.Minion{}, false, nil
+}
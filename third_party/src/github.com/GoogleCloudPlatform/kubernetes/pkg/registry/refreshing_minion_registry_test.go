@@ -0,0 +1,128 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
+)
+
+// fakeNodes is a CloudNodes whose instances can change while it is used.
+type fakeNodes struct {
+	lock      sync.Mutex
+	instances []string
+	err       error
+}
+
+func (f *fakeNodes) set(instances []string, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.instances, f.err = instances, err
+}
+
+func (f *fakeNodes) List(filter string) ([]string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	return append([]string{}, f.instances...), nil
+}
+
+func (f *fakeNodes) IPAddress(name string) (net.IP, error) {
+	return net.ParseIP("10.0.0." + name[len(name)-1:]), nil
+}
+
+func TestRefreshingMinionRegistry(t *testing.T) {
+	nodes := &fakeNodes{instances: []string{"cloud-1", "cloud-2"}}
+	probe := func(minion string) bool { return minion != "cloud-2" }
+	registry := NewRefreshingMinionRegistry([]string{"10.1.0.1"}, nodes, ".*", probe, 0)
+
+	minions, err := registry.Minions()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []api.Minion{
+		{JSONBase: api.JSONBase{ID: "10.1.0.1"}, HostIP: "10.1.0.1", Addresses: []string{"10.1.0.1"}, Status: api.MinionReachable},
+		{JSONBase: api.JSONBase{ID: "cloud-1"}, HostIP: "10.0.0.1", Addresses: []string{"10.0.0.1"}, Status: api.MinionReachable},
+		{JSONBase: api.JSONBase{ID: "cloud-2"}, HostIP: "10.0.0.2", Addresses: []string{"10.0.0.2"}, Status: api.MinionUnreachable},
+	}
+	if !reflect.DeepEqual(minions, expected) {
+		t.Errorf("unexpected minions: %#v", minions)
+	}
+	if list, _ := registry.List(); !reflect.DeepEqual(list, []string{"10.1.0.1", "cloud-1"}) {
+		t.Errorf("expected the unreachable minion not to be listed, got %v", list)
+	}
+
+	nodes.set([]string{"cloud-1", "cloud-3"}, nil)
+	registry.Refresh()
+	if list, _ := registry.List(); !reflect.DeepEqual(list, []string{"10.1.0.1", "cloud-1", "cloud-3"}) {
+		t.Errorf("expected the cloud instances to be refreshed, got %v", list)
+	}
+
+	nodes.set(nil, fmt.Errorf("cloud unavailable"))
+	registry.Refresh()
+	if list, _ := registry.List(); !reflect.DeepEqual(list, []string{"10.1.0.1", "cloud-1", "cloud-3"}) {
+		t.Errorf("expected the instances to be kept when the cloud fails, got %v", list)
+	}
+}
+
+func TestRefreshingMinionRegistryInstanceDisappears(t *testing.T) {
+	nodes := &fakeNodes{instances: []string{"cloud-1", "cloud-2"}}
+	registry := NewRefreshingMinionRegistry(nil, nodes, ".*", nil, 0)
+	storage := MakeMinionRegistryStorage(registry, nil).(*MinionRegistryStorage)
+
+	obj, err := storage.Get(api.NewContext(), "cloud-2")
+	if err != nil || obj.(api.Minion).HostIP != "10.0.0.2" {
+		t.Fatalf("unexpected minion %#v: %v", obj, err)
+	}
+	nodes.set([]string{"cloud-1"}, nil)
+	if _, err := storage.Get(api.NewContext(), "cloud-2"); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a vanished instance not to be found before the next refresh, got %v", err)
+	}
+	if list, _ := registry.List(); !reflect.DeepEqual(list, []string{"cloud-1"}) {
+		t.Errorf("expected the vanished instance to be dropped, got %v", list)
+	}
+}
+
+func TestRefreshingMinionRegistryInsertDelete(t *testing.T) {
+	nodes := &fakeNodes{instances: []string{"cloud-1"}}
+	registry := NewRefreshingMinionRegistry(nil, nodes, ".*", nil, 0)
+
+	if err := registry.Insert("static-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	registry.Refresh()
+	if contains, _ := registry.Contains("static-1"); !contains {
+		t.Errorf("expected an inserted minion to survive a refresh")
+	}
+	if err := registry.Delete("static-1"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if contains, _ := registry.Contains("static-1"); contains {
+		t.Errorf("expected the minion to be deleted")
+	}
+	if err := registry.Delete("cloud-1"); err == nil {
+		t.Errorf("expected a cloud instance not to be deletable")
+	}
+}
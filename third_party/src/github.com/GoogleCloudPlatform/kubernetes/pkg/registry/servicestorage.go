@@ -18,6 +18,7 @@ package registry
 
 import (
 	"fmt"
+	"net/http"
 	"strconv"
 	"strings"
 
@@ -33,14 +34,21 @@ type ServiceRegistryStorage struct {
 	registry ServiceRegistry
 	cloud    cloudprovider.Interface
 	machines MinionRegistry
+	pods     apiserver.Lister
+	// objectLabels serves the labels of services, if registry is a LabelRegistry.
+	objectLabels *labelsSubresource
 }
 
-// MakeServiceRegistryStorage makes a new ServiceRegistryStorage.
-func MakeServiceRegistryStorage(registry ServiceRegistry, cloud cloudprovider.Interface, machines MinionRegistry) apiserver.RESTStorage {
+// MakeServiceRegistryStorage makes a new ServiceRegistryStorage. The pods a service selects
+// are listed from pods, if it isn't nil, as the pods subresource of the service.
+func MakeServiceRegistryStorage(registry ServiceRegistry, cloud cloudprovider.Interface, machines MinionRegistry, pods apiserver.Lister) apiserver.RESTStorage {
 	return &ServiceRegistryStorage{
 		registry: registry,
 		cloud:    cloud,
 		machines: machines,
+		pods:     pods,
+
+		objectLabels: makeLabelsSubresource("service", registry),
 	}
 }
 
@@ -95,7 +103,7 @@ func GetServiceEnvironmentVariables(registry ServiceRegistry, machine string) ([
 	return result, nil
 }
 
-func (sr *ServiceRegistryStorage) List(selector labels.Selector) (interface{}, error) {
+func (sr *ServiceRegistryStorage) List(ctx api.Context, selector labels.Selector) (interface{}, error) {
 	list, err := sr.registry.ListServices()
 	if err != nil {
 		return nil, err
@@ -110,8 +118,8 @@ func (sr *ServiceRegistryStorage) List(selector labels.Selector) (interface{}, e
 	return list, err
 }
 
-func (sr *ServiceRegistryStorage) Get(id string) (interface{}, error) {
-	service, err := sr.registry.GetService(id)
+func (sr *ServiceRegistryStorage) Get(ctx api.Context, id string) (interface{}, error) {
+	service, err := sr.registry.GetService(api.QualifiedID(ctx.Namespace, id))
 	if err != nil {
 		return nil, err
 	}
@@ -149,14 +157,17 @@ func (sr *ServiceRegistryStorage) deleteExternalLoadBalancer(service *api.Servic
 	return nil
 }
 
-func (sr *ServiceRegistryStorage) Delete(id string) (<-chan interface{}, error) {
-	service, err := sr.registry.GetService(id)
+func (sr *ServiceRegistryStorage) Delete(ctx api.Context, id string) (<-chan interface{}, error) {
+	service, err := sr.registry.GetService(api.QualifiedID(ctx.Namespace, id))
 	if err != nil {
 		return nil, err
 	}
-	return apiserver.MakeAsync(func() (interface{}, error) {
+	return apiserver.MakeAsyncWithProgress(func(progress apiserver.ProgressFunc) (interface{}, error) {
+		if service.CreateExternalLoadBalancer {
+			progress(apiserver.ProgressStatus(fmt.Sprintf("deleting the external load balancer of service %s", id)))
+		}
 		sr.deleteExternalLoadBalancer(service)
-		return &api.Status{Status: api.StatusSuccess}, sr.registry.DeleteService(id)
+		return &api.Status{Status: api.StatusSuccess}, sr.registry.DeleteService(api.QualifiedID(ctx.Namespace, id))
 	}), nil
 }
 
@@ -164,10 +175,10 @@ func (sr *ServiceRegistryStorage) New() interface{} {
 	return &api.Service{}
 }
 
-func (sr *ServiceRegistryStorage) Create(obj interface{}) (<-chan interface{}, error) {
+func (sr *ServiceRegistryStorage) Create(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	srv := obj.(*api.Service)
-	if errs := api.ValidateService(srv); len(errs) > 0 {
-		return nil, fmt.Errorf("Validation errors: %v", errs)
+	if err := sr.checkPortConflicts(srv); err != nil {
+		return nil, err
 	}
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		// TODO: Consider moving this to a rectification loop, so that we make/remove external load balancers
@@ -202,17 +213,17 @@ func (sr *ServiceRegistryStorage) Create(obj interface{}) (<-chan interface{}, e
 		if err != nil {
 			return nil, err
 		}
-		return sr.registry.GetService(srv.ID)
+		return sr.registry.GetService(api.QualifiedID(srv.Namespace, srv.ID))
 	}), nil
 }
 
-func (sr *ServiceRegistryStorage) Update(obj interface{}) (<-chan interface{}, error) {
+func (sr *ServiceRegistryStorage) Update(ctx api.Context, obj interface{}) (<-chan interface{}, error) {
 	srv := obj.(*api.Service)
 	if srv.ID == "" {
 		return nil, fmt.Errorf("ID should not be empty: %#v", srv)
 	}
-	if errs := api.ValidateService(srv); len(errs) > 0 {
-		return nil, fmt.Errorf("Validation errors: %v", errs)
+	if err := sr.checkPortConflicts(srv); err != nil {
+		return nil, err
 	}
 	return apiserver.MakeAsync(func() (interface{}, error) {
 		// TODO: check to see if external load balancer status changed
@@ -220,6 +231,142 @@ func (sr *ServiceRegistryStorage) Update(obj interface{}) (<-chan interface{}, e
 		if err != nil {
 			return nil, err
 		}
-		return sr.registry.GetService(srv.ID)
+		return sr.registry.GetService(api.QualifiedID(srv.Namespace, srv.ID))
 	}), nil
 }
+
+// servicePort returns how the port of service is named in UsedPorts, e.g. "80/TCP".
+func servicePort(service *api.Service) string {
+	protocol := strings.ToUpper(service.Protocol)
+	if len(protocol) == 0 {
+		protocol = "TCP"
+	}
+	return fmt.Sprintf("%d/%s", service.Port, protocol)
+}
+
+// checkPortConflicts returns a conflict error naming another service with the same port and
+// protocol as service, in any namespace, since the proxy listens on the port of every
+// service. Services without a port don't conflict.
+func (sr *ServiceRegistryStorage) checkPortConflicts(service *api.Service) error {
+	if service.Port == 0 {
+		return nil
+	}
+	list, err := sr.registry.ListServices()
+	if err != nil {
+		return err
+	}
+	port := servicePort(service)
+	for i := range list.Items {
+		other := &list.Items[i]
+		if other.ID == service.ID && namespaceOf(other.JSONBase) == namespaceOf(service.JSONBase) {
+			continue
+		}
+		if other.Port != 0 && servicePort(other) == port {
+			return apiserver.NewFieldConflictErr("service", service.ID, "port", other.ID, fmt.Errorf("both use port %s", port))
+		}
+	}
+	return nil
+}
+
+// UsedPorts is the usedPorts view of services: the ports of the services in every namespace,
+// such as "80/TCP", and the ID of the service using each.
+type UsedPorts struct {
+	// Kind is always "UsedPorts".
+	Kind  string            `json:"kind"`
+	Ports map[string]string `json:"ports"`
+}
+
+// ListViews implements apiserver.ListViewer. The usedPorts view of services is their
+// UsedPorts.
+func (sr *ServiceRegistryStorage) ListViews() []string {
+	return []string{"usedPorts"}
+}
+
+func (sr *ServiceRegistryStorage) ListView(ctx api.Context, name string, selector labels.Selector) (interface{}, error) {
+	list, err := sr.registry.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	used := UsedPorts{Kind: "UsedPorts", Ports: map[string]string{}}
+	for i := range list.Items {
+		service := &list.Items[i]
+		if service.Port != 0 && selector.Matches(labels.Set(service.Labels)) {
+			used.Ports[servicePort(service)] = service.ID
+		}
+	}
+	return used, nil
+}
+
+// Subresources implements apiserver.SubresourceStorage. The pods of a service are the pods
+// its selector selects. Its labels can be changed without the rest of it.
+func (sr *ServiceRegistryStorage) Subresources() []string {
+	subresources := []string{}
+	if sr.pods != nil {
+		subresources = append(subresources, "pods")
+	}
+	if sr.objectLabels != nil {
+		subresources = append(subresources, "labels")
+	}
+	return subresources
+}
+
+func (sr *ServiceRegistryStorage) NewSubresource(subresource string) interface{} {
+	if subresource == "labels" {
+		return &api.ObjectLabels{}
+	}
+	return &api.PodList{}
+}
+
+func (sr *ServiceRegistryStorage) GetSubresource(ctx api.Context, id, subresource string) (interface{}, error) {
+	return sr.GetSubresourceFields(ctx, id, subresource, labels.Everything())
+}
+
+// GetSubresourceFields implements apiserver.SubresourceFieldGetter, listing the pods the
+// service with the given id selects as pods are listed, filtered on field if the pods can be.
+// A service with an empty selector selects no pods, rather than all of them, which is
+// explained by the Status returned.
+func (sr *ServiceRegistryStorage) GetSubresourceFields(ctx api.Context, id, subresource string, field labels.Selector) (interface{}, error) {
+	if subresource == "labels" {
+		if !field.Empty() {
+			return nil, fmt.Errorf("no field selector implemented for labels")
+		}
+		return sr.objectLabels.get(ctx, id)
+	}
+	service, err := sr.registry.GetService(api.QualifiedID(ctx.Namespace, id))
+	if err != nil {
+		return nil, err
+	}
+	if len(service.Selector) == 0 {
+		return &api.Status{
+			Status:  api.StatusSuccess,
+			Code:    http.StatusOK,
+			Message: fmt.Sprintf("service %q has an empty selector, so it selects no pods", id),
+		}, nil
+	}
+	selector := labels.Set(service.Selector).AsSelector()
+	if lister, ok := sr.pods.(apiserver.ResourceFieldLister); ok {
+		return lister.ListFields(ctx, selector, field)
+	}
+	if !field.Empty() {
+		return nil, fmt.Errorf("no field selector implemented for pods")
+	}
+	return sr.pods.List(ctx, selector)
+}
+
+// UpdateSubresource implements apiserver.SubresourceStorage. The pods of a service can only
+// be changed through the pods themselves.
+func (sr *ServiceRegistryStorage) UpdateSubresource(ctx api.Context, id, subresource string, obj interface{}) (<-chan interface{}, error) {
+	if subresource == "labels" {
+		return sr.objectLabels.update(ctx, id, obj)
+	}
+	return nil, apiserver.NewMethodNotSupported("service", "update "+subresource)
+}
+
+// PatchSubresource implements apiserver.SubresourcePatcher. Only the labels of a service can
+// be patched.
+func (sr *ServiceRegistryStorage) PatchSubresource(ctx api.Context, id, subresource string, patch apiserver.MergePatch) (<-chan interface{}, error) {
+	if subresource != "labels" {
+		return nil, apiserver.NewMethodNotSupported("service", "patch "+subresource)
+	}
+	return sr.objectLabels.patch(ctx, id, patch)
+}
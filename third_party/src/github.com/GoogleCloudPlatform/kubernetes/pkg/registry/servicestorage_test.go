@@ -18,11 +18,15 @@ package registry
 
 import (
 	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/apiserver"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/cloudprovider"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/labels"
 	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
 )
 
@@ -31,13 +35,13 @@ func TestServiceRegistry(t *testing.T) {
 	fakeCloud := &cloudprovider.FakeCloud{}
 	machines := []string{"foo", "bar", "baz"}
 
-	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines))
+	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines), nil).(*ServiceRegistryStorage)
 
 	svc := &api.Service{
 		JSONBase: api.JSONBase{ID: "foo"},
 		Selector: map[string]string{"bar": "baz"},
 	}
-	c, _ := storage.Create(svc)
+	c, _ := storage.Create(api.NewContext(), svc)
 	<-c
 
 	if len(fakeCloud.Calls) != 0 {
@@ -55,7 +59,7 @@ func TestServiceRegistry(t *testing.T) {
 
 func TestServiceStorageValidatesCreate(t *testing.T) {
 	memory := MakeMemoryRegistry()
-	storage := MakeServiceRegistryStorage(memory, nil, nil)
+	storage := MakeServiceRegistryStorage(memory, nil, nil, nil).(*ServiceRegistryStorage)
 
 	failureCases := map[string]api.Service{
 		"empty ID": {
@@ -67,13 +71,9 @@ func TestServiceStorageValidatesCreate(t *testing.T) {
 			Selector: map[string]string{},
 		},
 	}
-	for _, failureCase := range failureCases {
-		c, err := storage.Create(&failureCase)
-		if c != nil {
-			t.Errorf("Expected nil channel")
-		}
-		if err == nil {
-			t.Errorf("Expected to get an error")
+	for name, failureCase := range failureCases {
+		if code := serveStorage(t, "services", storage, "POST", "services?dryRun=true", &failureCase); code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected %d, got %d", name, http.StatusUnprocessableEntity, code)
 		}
 	}
 }
@@ -84,7 +84,7 @@ func TestServiceStorageValidatesUpdate(t *testing.T) {
 		JSONBase: api.JSONBase{ID: "foo"},
 		Selector: map[string]string{"bar": "baz"},
 	})
-	storage := MakeServiceRegistryStorage(memory, nil, nil)
+	storage := MakeServiceRegistryStorage(memory, nil, nil, nil).(*ServiceRegistryStorage)
 
 	failureCases := map[string]api.Service{
 		"empty ID": {
@@ -96,15 +96,131 @@ func TestServiceStorageValidatesUpdate(t *testing.T) {
 			Selector: map[string]string{},
 		},
 	}
-	for _, failureCase := range failureCases {
-		c, err := storage.Update(&failureCase)
-		if c != nil {
-			t.Errorf("Expected nil channel")
+	for name, failureCase := range failureCases {
+		if code := serveStorage(t, "services", storage, "PUT", "services/foo?dryRun=true", &failureCase); code != http.StatusUnprocessableEntity {
+			t.Errorf("%s: expected %d, got %d", name, http.StatusUnprocessableEntity, code)
 		}
-		if err == nil {
-			t.Errorf("Expected to get an error")
+	}
+}
+
+func TestServiceRegistryNamespaced(t *testing.T) {
+	memory := MakeMemoryRegistry()
+	storage := MakeServiceRegistryStorage(memory, nil, MakeMinionRegistry(nil), nil).(*ServiceRegistryStorage)
+
+	// The proxy listens on the port of every service, whatever its namespace.
+	for port, path := range []string{"ns/team1/services", "services"} {
+		svc := &api.Service{
+			JSONBase: api.JSONBase{ID: "web"},
+			Port:     80 + port,
+			Selector: map[string]string{"bar": "baz"},
+		}
+		if code := serveStorage(t, "services", storage, "POST", path, svc); code != http.StatusCreated {
+			t.Errorf("%s: expected %d, got %d", path, http.StatusCreated, code)
+		}
+	}
+
+	srv, err := memory.GetService("web_team1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if srv.ID != "web" || srv.Namespace != "team1" {
+		t.Errorf("expected the service to keep its ID and carry its namespace, got %#v", srv)
+	}
+	if srv, err := memory.GetService("web"); err != nil || srv.Namespace != api.NamespaceDefault {
+		t.Errorf("expected a separate service in the default namespace, got %#v %v", srv, err)
+	}
+	if code := serveStorage(t, "services", storage, "GET", "ns/team1/services/web", nil); code != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, code)
+	}
+	if code := serveStorage(t, "services", storage, "GET", "services/web_team1", nil); code != http.StatusNotFound {
+		t.Errorf("expected %d, got %d", http.StatusNotFound, code)
+	}
+}
+
+func TestServicePortConflicts(t *testing.T) {
+	memory := MakeMemoryRegistry()
+	storage := MakeServiceRegistryStorage(memory, nil, MakeMinionRegistry(nil), nil).(*ServiceRegistryStorage)
+	memory.CreateService(api.Service{JSONBase: api.JSONBase{ID: "web"}, Port: 80, Protocol: "TCP", Selector: map[string]string{"name": "web"}})
+	memory.CreateService(api.Service{JSONBase: api.JSONBase{ID: "dns", Namespace: "infra"}, Port: 53, Protocol: "UDP", Selector: map[string]string{"name": "dns"}})
+
+	table := []struct {
+		method  string
+		path    string
+		service api.Service
+		code    int
+	}{
+		{"POST", "services", api.Service{JSONBase: api.JSONBase{ID: "other"}, Port: 80}, http.StatusConflict},
+		{"POST", "ns/team1/services", api.Service{JSONBase: api.JSONBase{ID: "other"}, Port: 80}, http.StatusConflict},
+		{"POST", "services", api.Service{JSONBase: api.JSONBase{ID: "other"}, Port: 53, Protocol: "udp"}, http.StatusConflict},
+		{"POST", "services", api.Service{JSONBase: api.JSONBase{ID: "other"}, Port: 80, Protocol: "UDP"}, http.StatusCreated},
+		{"POST", "services", api.Service{JSONBase: api.JSONBase{ID: "tcpdns"}, Port: 53}, http.StatusCreated},
+		{"POST", "services", api.Service{JSONBase: api.JSONBase{ID: "bad"}, Port: 70000}, http.StatusUnprocessableEntity},
+		{"POST", "services", api.Service{JSONBase: api.JSONBase{ID: "bad"}, Port: 81, Protocol: "SCTP"}, http.StatusUnprocessableEntity},
+		// Updates that keep their port don't conflict with themselves.
+		{"PUT", "services/web", api.Service{JSONBase: api.JSONBase{ID: "web"}, Port: 80}, http.StatusOK},
+		{"PUT", "services/tcpdns", api.Service{JSONBase: api.JSONBase{ID: "tcpdns"}, Port: 80}, http.StatusConflict},
+	}
+	for _, item := range table {
+		service := item.service
+		service.Selector = map[string]string{"name": service.ID}
+		if code := serveStorage(t, "services", storage, item.method, item.path+"?sync=true", &service); code != item.code {
+			t.Errorf("%s %s %d/%s: expected %d, got %d", item.method, item.path, service.Port, service.Protocol, item.code, code)
 		}
 	}
+
+	_, err := storage.Create(api.NewDefaultContext(), &api.Service{JSONBase: api.JSONBase{ID: "again"}, Port: 80, Protocol: "TCP"})
+	if !apiserver.IsConflict(err) || !strings.Contains(err.Error(), `"web"`) {
+		t.Errorf("expected a conflict naming the service, got %v", err)
+	}
+
+	used, err := storage.ListView(api.NewContext(), "usedPorts", labels.Everything())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := UsedPorts{Kind: "UsedPorts", Ports: map[string]string{"80/TCP": "web", "53/UDP": "dns", "80/UDP": "other", "53/TCP": "tcpdns"}}
+	if !reflect.DeepEqual(used, expected) {
+		t.Errorf("expected %#v, got %#v", expected, used)
+	}
+	if code := serveStorage(t, "services", storage, "GET", "services?usedPorts=true", nil); code != http.StatusOK {
+		t.Errorf("expected %d, got %d", http.StatusOK, code)
+	}
+}
+
+func TestServiceRegistryPods(t *testing.T) {
+	memory := MakeMemoryRegistry()
+	for _, pod := range []api.Pod{
+		{JSONBase: api.JSONBase{ID: "web-1"}, Labels: map[string]string{"name": "web"}},
+		{JSONBase: api.JSONBase{ID: "web-2"}, Labels: map[string]string{"name": "web", "env": "test"}},
+		{JSONBase: api.JSONBase{ID: "db-1"}, Labels: map[string]string{"name": "db"}},
+	} {
+		memory.CreatePod("machine", pod)
+	}
+	memory.CreateService(api.Service{JSONBase: api.JSONBase{ID: "web"}, Selector: map[string]string{"name": "web"}})
+	memory.CreateService(api.Service{JSONBase: api.JSONBase{ID: "everything"}})
+	storage := MakeServiceRegistryStorage(memory, nil, nil, &PodRegistryStorage{registry: memory}).(*ServiceRegistryStorage)
+
+	obj, err := storage.GetSubresource(api.NewContext(), "web", "pods")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pods, ok := obj.(api.PodList)
+	if !ok || len(pods.Items) != 2 || pods.Items[0].Labels["name"] != "web" || pods.Items[1].Labels["name"] != "web" {
+		t.Errorf("expected the pods the selector selects, got %#v", obj)
+	}
+
+	obj, err = storage.GetSubresource(api.NewContext(), "everything", "pods")
+	if status, ok := obj.(*api.Status); err != nil || !ok || !strings.Contains(status.Message, "selects no pods") {
+		t.Errorf("expected a status explaining an empty selector selects nothing, got %#v %v", obj, err)
+	}
+	if _, err := storage.GetSubresourceFields(api.NewContext(), "web", "pods", labels.SelectorFromSet(labels.Set{"id": "web-1"})); err == nil {
+		t.Errorf("expected a field selector to be rejected, since pods can't be listed by field")
+	}
+	if _, err := storage.GetSubresource(api.NewContext(), "missing", "pods"); !apiserver.IsNotFound(err) {
+		t.Errorf("expected a missing service not to be found, got %v", err)
+	}
+	if subresources := MakeServiceRegistryStorage(memory, nil, nil, nil).(*ServiceRegistryStorage).Subresources(); !reflect.DeepEqual(subresources, []string{"labels"}) {
+		t.Errorf("expected no pods subresource without pods, got %v", subresources)
+	}
 }
 
 func TestServiceRegistryExternalService(t *testing.T) {
@@ -112,14 +228,14 @@ func TestServiceRegistryExternalService(t *testing.T) {
 	fakeCloud := &cloudprovider.FakeCloud{}
 	machines := []string{"foo", "bar", "baz"}
 
-	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines))
+	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines), nil).(*ServiceRegistryStorage)
 
 	svc := &api.Service{
 		JSONBase:                   api.JSONBase{ID: "foo"},
 		Selector:                   map[string]string{"bar": "baz"},
 		CreateExternalLoadBalancer: true,
 	}
-	c, _ := storage.Create(svc)
+	c, _ := storage.Create(api.NewContext(), svc)
 	<-c
 
 	if len(fakeCloud.Calls) != 2 || fakeCloud.Calls[0] != "get-zone" || fakeCloud.Calls[1] != "create" {
@@ -142,14 +258,14 @@ func TestServiceRegistryExternalServiceError(t *testing.T) {
 	}
 	machines := []string{"foo", "bar", "baz"}
 
-	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines))
+	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines), nil).(*ServiceRegistryStorage)
 
 	svc := &api.Service{
 		JSONBase:                   api.JSONBase{ID: "foo"},
 		Selector:                   map[string]string{"bar": "baz"},
 		CreateExternalLoadBalancer: true,
 	}
-	c, _ := storage.Create(svc)
+	c, _ := storage.Create(api.NewContext(), svc)
 	<-c
 
 	if len(fakeCloud.Calls) != 1 || fakeCloud.Calls[0] != "get-zone" {
@@ -170,7 +286,7 @@ func TestServiceRegistryDelete(t *testing.T) {
 	fakeCloud := &cloudprovider.FakeCloud{}
 	machines := []string{"foo", "bar", "baz"}
 
-	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines))
+	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines), nil).(*ServiceRegistryStorage)
 
 	svc := api.Service{
 		JSONBase: api.JSONBase{ID: "foo"},
@@ -178,7 +294,7 @@ func TestServiceRegistryDelete(t *testing.T) {
 	}
 	memory.CreateService(svc)
 
-	c, _ := storage.Delete(svc.ID)
+	c, _ := storage.Delete(api.NewContext(), svc.ID)
 	<-c
 
 	if len(fakeCloud.Calls) != 0 {
@@ -199,7 +315,7 @@ func TestServiceRegistryDeleteExternal(t *testing.T) {
 	fakeCloud := &cloudprovider.FakeCloud{}
 	machines := []string{"foo", "bar", "baz"}
 
-	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines))
+	storage := MakeServiceRegistryStorage(memory, fakeCloud, MakeMinionRegistry(machines), nil).(*ServiceRegistryStorage)
 
 	svc := api.Service{
 		JSONBase:                   api.JSONBase{ID: "foo"},
@@ -208,8 +324,8 @@ func TestServiceRegistryDeleteExternal(t *testing.T) {
 	}
 	memory.CreateService(svc)
 
-	c, _ := storage.Delete(svc.ID)
-	<-c
+	c, _ := storage.Delete(api.NewContext(), svc.ID)
+	apiserver.FinalResult(c)
 
 	if len(fakeCloud.Calls) != 2 || fakeCloud.Calls[0] != "get-zone" || fakeCloud.Calls[1] != "delete" {
 		t.Errorf("Unexpected call(s): %#v", fakeCloud.Calls)
@@ -34,7 +34,7 @@ func TestDecoder(t *testing.T) {
 
 	expect := &api.Pod{JSONBase: api.JSONBase{ID: "foo"}}
 	go func() {
-		err := encoder.Encode(api.WatchEvent{watch.Added, api.APIObject{expect}})
+		err := encoder.Encode(api.WatchEvent{Type: watch.Added, Object: api.APIObject{expect}})
 		if err != nil {
 			t.Errorf("Unexpected error %v", err)
 		}
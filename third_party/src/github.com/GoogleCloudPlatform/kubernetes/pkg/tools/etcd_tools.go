@@ -196,6 +196,12 @@ func (h *EtcdHelper) bodyAndExtractObj(key string, objPtr interface{}, ignoreNot
 
 // Create adds a new object at a key unless it already exists
 func (h *EtcdHelper) CreateObj(key string, obj interface{}) error {
+	return h.CreateObjWithTTL(key, obj, 0)
+}
+
+// CreateObjWithTTL is like CreateObj, but etcd removes the object after ttl seconds.
+// A ttl of 0 keeps the object until it is deleted.
+func (h *EtcdHelper) CreateObjWithTTL(key string, obj interface{}, ttl uint64) error {
 	data, err := h.Codec.Encode(obj)
 	if err != nil {
 		return err
@@ -206,7 +212,7 @@ func (h *EtcdHelper) CreateObj(key string, obj interface{}) error {
 		}
 	}
 
-	_, err = h.Client.Create(key, string(data), 0)
+	_, err = h.Client.Create(key, string(data), ttl)
 	return err
 }
 
@@ -465,6 +471,37 @@ func (w *etcdWatcher) translate() {
 }
 
 func (w *etcdWatcher) sendResult(res *etcd.Response) {
+	event, err := decodeResult(res, w.encoding, w.versioner)
+	if err == errNotAnEvent {
+		return
+	}
+	if err != nil {
+		// TODO: expose an error through watch.Interface?
+		w.Stop()
+		return
+	}
+
+	// perform any necessary transformation
+	if w.transform != nil {
+		obj, err := w.transform(event.Object)
+		if err != nil {
+			glog.Errorf("failure to transform api object %#v: %v", event.Object, err)
+			// TODO: expose an error through watch.Interface?
+			w.Stop()
+			return
+		}
+		event.Object = obj
+	}
+
+	w.emit(event)
+}
+
+// errNotAnEvent is returned by decodeResult for responses that don't describe a change.
+var errNotAnEvent = errors.New("not a watch event")
+
+// decodeResult returns the event res describes, with its object decoded by encoding and
+// versioned by versioner, if it isn't nil. Responses that can't be decoded are logged.
+func decodeResult(res *etcd.Response, encoding Codec, versioner ResourceVersioner) (watch.Event, error) {
 	var action watch.EventType
 	var data []byte
 	var index uint64
@@ -472,7 +509,7 @@ func (w *etcdWatcher) sendResult(res *etcd.Response) {
 	case "create":
 		if res.Node == nil {
 			glog.Errorf("unexpected nil node: %#v", res)
-			return
+			return watch.Event{}, errNotAnEvent
 		}
 		data = []byte(res.Node.Value)
 		index = res.Node.ModifiedIndex
@@ -480,7 +517,7 @@ func (w *etcdWatcher) sendResult(res *etcd.Response) {
 	case "set", "compareAndSwap", "get":
 		if res.Node == nil {
 			glog.Errorf("unexpected nil node: %#v", res)
-			return
+			return watch.Event{}, errNotAnEvent
 		}
 		data = []byte(res.Node.Value)
 		index = res.Node.ModifiedIndex
@@ -488,46 +525,29 @@ func (w *etcdWatcher) sendResult(res *etcd.Response) {
 	case "delete":
 		if res.PrevNode == nil {
 			glog.Errorf("unexpected nil prev node: %#v", res)
-			return
+			return watch.Event{}, errNotAnEvent
 		}
 		data = []byte(res.PrevNode.Value)
 		index = res.PrevNode.ModifiedIndex
 		action = watch.Deleted
 	default:
 		glog.Errorf("unknown action: %v", res.Action)
-		return
+		return watch.Event{}, errNotAnEvent
 	}
 
-	obj, err := w.encoding.Decode(data)
+	obj, err := encoding.Decode(data)
 	if err != nil {
 		glog.Errorf("failure to decode api object: '%v' from %#v %#v", string(data), res, res.Node)
-		// TODO: expose an error through watch.Interface?
-		w.Stop()
-		return
+		return watch.Event{}, err
 	}
 
 	// ensure resource version is set on the object we load from etcd
-	if w.versioner != nil {
-		if err := w.versioner.SetResourceVersion(obj, index); err != nil {
+	if versioner != nil {
+		if err := versioner.SetResourceVersion(obj, index); err != nil {
 			glog.Errorf("failure to version api object (%d) %#v: %v", index, obj, err)
 		}
 	}
-
-	// perform any necessary transformation
-	if w.transform != nil {
-		obj, err = w.transform(obj)
-		if err != nil {
-			glog.Errorf("failure to transform api object %#v: %v", obj, err)
-			// TODO: expose an error through watch.Interface?
-			w.Stop()
-			return
-		}
-	}
-
-	w.emit(watch.Event{
-		Type:   action,
-		Object: obj,
-	})
+	return watch.Event{Type: action, Object: obj}, nil
 }
 
 // ResultChannel implements watch.Interface.
@@ -0,0 +1,401 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tools
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/golang/glog"
+)
+
+// Defaults of BreakerConfig.
+const (
+	// DefaultBreakerLatencyWindow is the number of calls whose latency is averaged.
+	DefaultBreakerLatencyWindow = 20
+	// DefaultBreakerCooldown is how long a tripped breaker fails calls before letting a
+	// probe through.
+	DefaultBreakerCooldown = 30 * time.Second
+)
+
+// BreakerConfig configures the circuit breaker of an InstrumentedEtcdClient, which fails
+// calls fast, for a cooldown, once etcd looks unavailable. Its zero value never trips.
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive failed calls that trip the breaker. If
+	// not positive, failures don't trip it.
+	FailureThreshold int
+	// LatencyThreshold trips the breaker when the average latency of the last LatencyWindow
+	// calls exceeds it. If not positive, latency doesn't trip it.
+	LatencyThreshold time.Duration
+	// LatencyWindow is the number of calls whose latency is averaged. If not positive,
+	// DefaultBreakerLatencyWindow is used.
+	LatencyWindow int
+	// Cooldown is how long a tripped breaker fails calls. After it, a single call is let
+	// through as a probe: the breaker resets if it succeeds, and trips again if it doesn't.
+	// If not positive, DefaultBreakerCooldown is used.
+	Cooldown time.Duration
+}
+
+// States of the breaker of an InstrumentedEtcdClient.
+const (
+	// BreakerClosed lets every call through.
+	BreakerClosed = "closed"
+	// BreakerOpen fails every call with a StorageUnavailableError.
+	BreakerOpen = "open"
+	// BreakerProbing lets a single call through to find out whether etcd is back, and
+	// fails the others.
+	BreakerProbing = "probing"
+)
+
+// StorageUnavailableError is returned by an InstrumentedEtcdClient whose breaker is tripped,
+// without calling etcd.
+type StorageUnavailableError struct {
+	// RetryAfter is how long until the breaker lets a probe through.
+	RetryAfter time.Duration
+}
+
+func (e *StorageUnavailableError) Error() string {
+	return "storage unavailable"
+}
+
+// IsStorageUnavailable returns true if err is a StorageUnavailableError.
+func IsStorageUnavailable(err error) bool {
+	_, ok := err.(*StorageUnavailableError)
+	return ok
+}
+
+// StorageMetrics are the counters of an InstrumentedEtcdClient.
+type StorageMetrics struct {
+	// Operations has the counters of each etcd call, e.g. "get" and "compareAndSwap".
+	Operations map[string]StorageOperationMetrics `json:"operations"`
+	// Breaker is the state of the breaker, e.g. BreakerClosed.
+	Breaker string `json:"breaker"`
+	// Trips counts the times the breaker tripped, and Rejected the calls it failed.
+	Trips    uint64 `json:"breakerTrips"`
+	Rejected uint64 `json:"breakerRejected"`
+}
+
+// StorageOperationMetrics count the calls of an etcd operation. Calls rejected by the
+// breaker aren't counted.
+type StorageOperationMetrics struct {
+	Calls    uint64 `json:"calls"`
+	Failures uint64 `json:"failures"`
+	// AverageLatencyMilliseconds and MaxLatencyMilliseconds are the average and the
+	// longest latency of the calls.
+	AverageLatencyMilliseconds float64 `json:"averageLatencyMilliseconds"`
+	MaxLatencyMilliseconds     float64 `json:"maxLatencyMilliseconds"`
+}
+
+// storageOperation counts the calls of an etcd operation.
+type storageOperation struct {
+	calls, failures uint64
+	total, max      time.Duration
+}
+
+// InstrumentedEtcdClient is an EtcdClient that records the latency and failures of each call
+// it passes to another, and fails calls fast with a StorageUnavailableError while its
+// circuit breaker is tripped. Etcd answering that a key doesn't exist, already exists or
+// has changed isn't a failure. Watches are counted, but their latency isn't, and they don't
+// trip the breaker.
+type InstrumentedEtcdClient struct {
+	client EtcdClient
+	// now returns the time calls start and end; tests replace it.
+	now func() time.Time
+
+	lock       sync.Mutex
+	config     BreakerConfig
+	operations map[string]*storageOperation
+	state      string
+	// failures counts the consecutive failed calls.
+	failures int
+	// latencies are the latencies of the last config.LatencyWindow calls, next the index
+	// of the oldest one once the window is full.
+	latencies []time.Duration
+	next      int
+	// openUntil is when an open breaker lets a probe through.
+	openUntil time.Time
+	trips     uint64
+	rejected  uint64
+}
+
+// NewInstrumentedEtcdClient returns an InstrumentedEtcdClient of client, whose breaker never
+// trips until it is configured with SetBreaker.
+func NewInstrumentedEtcdClient(client EtcdClient) *InstrumentedEtcdClient {
+	return &InstrumentedEtcdClient{
+		client:     client,
+		now:        time.Now,
+		operations: map[string]*storageOperation{},
+		state:      BreakerClosed,
+	}
+}
+
+// SetBreaker configures the breaker of c, and resets it.
+func (c *InstrumentedEtcdClient) SetBreaker(config BreakerConfig) {
+	if config.LatencyWindow <= 0 {
+		config.LatencyWindow = DefaultBreakerLatencyWindow
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = DefaultBreakerCooldown
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.config = config
+	c.reset()
+}
+
+// BreakerState returns the state of the breaker of c, e.g. BreakerClosed.
+func (c *InstrumentedEtcdClient) BreakerState() string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.state == BreakerOpen && !c.now().Before(c.openUntil) {
+		return BreakerProbing
+	}
+	return c.state
+}
+
+// Metrics returns the counters of c.
+func (c *InstrumentedEtcdClient) Metrics() StorageMetrics {
+	state := c.BreakerState()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	metrics := StorageMetrics{
+		Operations: map[string]StorageOperationMetrics{},
+		Breaker:    state,
+		Trips:      c.trips,
+		Rejected:   c.rejected,
+	}
+	for name, op := range c.operations {
+		m := StorageOperationMetrics{Calls: op.calls, Failures: op.failures, MaxLatencyMilliseconds: milliseconds(op.max)}
+		if op.calls > 0 {
+			m.AverageLatencyMilliseconds = milliseconds(op.total) / float64(op.calls)
+		}
+		metrics.Operations[name] = m
+	}
+	return metrics
+}
+
+// HealthCheck returns an error while the breaker of c is tripped. It is a healthz check.
+func (c *InstrumentedEtcdClient) HealthCheck() error {
+	if state := c.BreakerState(); state != BreakerClosed {
+		return fmt.Errorf("the storage breaker is %s", state)
+	}
+	return nil
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// reset closes the breaker and forgets the failures and latencies it saw. c.lock is held.
+func (c *InstrumentedEtcdClient) reset() {
+	c.state = BreakerClosed
+	c.failures = 0
+	c.latencies = nil
+	c.next = 0
+}
+
+// admit returns an error if the breaker fails a call rather than letting it through, and
+// otherwise when the call starts. A call let through an open breaker after its cooldown is
+// its probe, unless probe is false, for calls whose outcome isn't recorded: those are failed
+// until another call closes the breaker.
+func (c *InstrumentedEtcdClient) admit(probe bool) (time.Time, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	now := c.now()
+	switch c.state {
+	case BreakerOpen:
+		if now.Before(c.openUntil) {
+			c.rejected++
+			return now, &StorageUnavailableError{RetryAfter: c.openUntil.Sub(now)}
+		}
+		if !probe {
+			c.rejected++
+			return now, &StorageUnavailableError{RetryAfter: c.config.Cooldown}
+		}
+		c.state = BreakerProbing
+	case BreakerProbing:
+		c.rejected++
+		return now, &StorageUnavailableError{RetryAfter: c.config.Cooldown}
+	}
+	return now, nil
+}
+
+// isStorageFailure returns true if err means etcd failed to serve a call, rather than
+// answering it with an error such as a key not being found.
+func isStorageFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if etcdErr, ok := err.(*etcd.EtcdError); ok {
+		return etcdErr.ErrorCode >= 300
+	}
+	return true
+}
+
+// record counts a call of operation that started at start and returned err, and trips or
+// resets the breaker accordingly.
+func (c *InstrumentedEtcdClient) record(operation string, start time.Time, err error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	latency := c.now().Sub(start)
+	failed := isStorageFailure(err)
+	op, ok := c.operations[operation]
+	if !ok {
+		op = &storageOperation{}
+		c.operations[operation] = op
+	}
+	op.calls++
+	op.total += latency
+	if latency > op.max {
+		op.max = latency
+	}
+	if failed {
+		op.failures++
+	}
+
+	if c.state == BreakerProbing {
+		if failed {
+			c.trip(fmt.Sprintf("its probe failed: %v", err))
+		} else {
+			glog.Infof("Storage probe succeeded, closing the storage breaker")
+			c.reset()
+		}
+		return
+	}
+	if c.state != BreakerClosed {
+		return
+	}
+	if failed {
+		c.failures++
+	} else {
+		c.failures = 0
+	}
+	if c.config.FailureThreshold > 0 && c.failures >= c.config.FailureThreshold {
+		c.trip(fmt.Sprintf("%d consecutive calls failed, the last with: %v", c.failures, err))
+		return
+	}
+	if c.config.LatencyThreshold <= 0 {
+		return
+	}
+	if len(c.latencies) < c.config.LatencyWindow {
+		c.latencies = append(c.latencies, latency)
+	} else {
+		c.latencies[c.next] = latency
+		c.next = (c.next + 1) % len(c.latencies)
+	}
+	if len(c.latencies) < c.config.LatencyWindow {
+		return
+	}
+	var total time.Duration
+	for _, latency := range c.latencies {
+		total += latency
+	}
+	if average := total / time.Duration(len(c.latencies)); average > c.config.LatencyThreshold {
+		c.trip(fmt.Sprintf("the last %d calls took %v on average", len(c.latencies), average))
+	}
+}
+
+// trip opens the breaker for a cooldown because of reason. c.lock is held.
+func (c *InstrumentedEtcdClient) trip(reason string) {
+	glog.Errorf("Failing storage calls for %v: %s", c.config.Cooldown, reason)
+	c.reset()
+	c.state = BreakerOpen
+	c.openUntil = c.now().Add(c.config.Cooldown)
+	c.trips++
+}
+
+func (c *InstrumentedEtcdClient) AddChild(key, data string, ttl uint64) (*etcd.Response, error) {
+	start, err := c.admit(true)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.AddChild(key, data, ttl)
+	c.record("addChild", start, err)
+	return response, err
+}
+
+func (c *InstrumentedEtcdClient) Get(key string, sort, recursive bool) (*etcd.Response, error) {
+	start, err := c.admit(true)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.Get(key, sort, recursive)
+	c.record("get", start, err)
+	return response, err
+}
+
+func (c *InstrumentedEtcdClient) Set(key, value string, ttl uint64) (*etcd.Response, error) {
+	start, err := c.admit(true)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.Set(key, value, ttl)
+	c.record("set", start, err)
+	return response, err
+}
+
+func (c *InstrumentedEtcdClient) Create(key, value string, ttl uint64) (*etcd.Response, error) {
+	start, err := c.admit(true)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.Create(key, value, ttl)
+	c.record("create", start, err)
+	return response, err
+}
+
+func (c *InstrumentedEtcdClient) CompareAndSwap(key, value string, ttl uint64, prevValue string, prevIndex uint64) (*etcd.Response, error) {
+	start, err := c.admit(true)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.CompareAndSwap(key, value, ttl, prevValue, prevIndex)
+	c.record("compareAndSwap", start, err)
+	return response, err
+}
+
+func (c *InstrumentedEtcdClient) Delete(key string, recursive bool) (*etcd.Response, error) {
+	start, err := c.admit(true)
+	if err != nil {
+		return nil, err
+	}
+	response, err := c.client.Delete(key, recursive)
+	c.record("delete", start, err)
+	return response, err
+}
+
+// Watch is refused while the breaker is tripped, but otherwise passed through as it is: the
+// time a watch lasts isn't the latency of etcd, so a watch is never the probe of the breaker.
+func (c *InstrumentedEtcdClient) Watch(prefix string, waitIndex uint64, recursive bool, receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error) {
+	if _, err := c.admit(false); err != nil {
+		if receiver != nil {
+			close(receiver)
+		}
+		return nil, err
+	}
+	c.lock.Lock()
+	op, ok := c.operations["watch"]
+	if !ok {
+		op = &storageOperation{}
+		c.operations["watch"] = op
+	}
+	op.calls++
+	c.lock.Unlock()
+	return c.client.Watch(prefix, waitIndex, recursive, receiver, stop)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tools
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-etcd/etcd"
+)
+
+// fakeClock is the time of an InstrumentedEtcdClient under test. Each call of now advances it
+// by step, so that every etcd call takes step.
+type fakeClock struct {
+	time time.Time
+	step time.Duration
+}
+
+func (c *fakeClock) now() time.Time {
+	c.time = c.time.Add(c.step)
+	return c.time
+}
+
+func newTestInstrumentedClient(t *testing.T, config BreakerConfig) (*InstrumentedEtcdClient, *FakeEtcdClient, *fakeClock) {
+	fake := MakeFakeEtcdClient(t)
+	fake.Data["/ok"] = EtcdResponseWithError{R: &etcd.Response{Node: &etcd.Node{Value: "ok"}}}
+	fake.Data["/broken"] = EtcdResponseWithError{R: &etcd.Response{}, E: errors.New("connection refused")}
+	fake.ExpectNotFoundGet("/missing")
+	clock := &fakeClock{time: time.Unix(0, 0), step: time.Millisecond}
+	client := NewInstrumentedEtcdClient(fake)
+	client.now = clock.now
+	client.SetBreaker(config)
+	return client, fake, clock
+}
+
+func TestInstrumentedEtcdClientMetrics(t *testing.T) {
+	client, _, clock := newTestInstrumentedClient(t, BreakerConfig{})
+	client.Get("/ok", false, false)
+	clock.step = 3 * time.Millisecond
+	client.Get("/missing", false, false)
+	client.Get("/broken", false, false)
+	for i := 0; i < 10; i++ {
+		if _, err := client.Get("/broken", false, false); IsStorageUnavailable(err) {
+			t.Fatalf("a breaker without thresholds tripped")
+		}
+	}
+
+	metrics := client.Metrics()
+	get := metrics.Operations["get"]
+	if get.Calls != 13 || get.Failures != 11 || get.MaxLatencyMilliseconds != 3 {
+		t.Errorf("unexpected metrics: %#v", get)
+	}
+	if get.AverageLatencyMilliseconds <= 2.8 || get.AverageLatencyMilliseconds >= 3 {
+		t.Errorf("unexpected average latency: %v", get.AverageLatencyMilliseconds)
+	}
+	if metrics.Breaker != BreakerClosed || metrics.Trips != 0 || metrics.Rejected != 0 {
+		t.Errorf("unexpected breaker metrics: %#v", metrics)
+	}
+}
+
+func TestInstrumentedEtcdClientBreakerFailures(t *testing.T) {
+	client, _, clock := newTestInstrumentedClient(t, BreakerConfig{FailureThreshold: 3, Cooldown: time.Second})
+	client.Get("/broken", false, false)
+	client.Get("/broken", false, false)
+	// Etcd answering isn't a failure, and resets the count.
+	client.Get("/missing", false, false)
+	client.Get("/broken", false, false)
+	client.Get("/broken", false, false)
+	if state := client.BreakerState(); state != BreakerClosed {
+		t.Fatalf("expected the breaker to be closed, got %s", state)
+	}
+	client.Get("/broken", false, false)
+	if state := client.BreakerState(); state != BreakerOpen {
+		t.Fatalf("expected the breaker to be open, got %s", state)
+	}
+	if err := client.HealthCheck(); err == nil {
+		t.Errorf("expected the health check to fail")
+	}
+
+	_, err := client.Get("/ok", false, false)
+	unavailable, ok := err.(*StorageUnavailableError)
+	if !ok || unavailable.RetryAfter <= 0 || unavailable.RetryAfter > time.Second {
+		t.Fatalf("expected the call to fail fast, got %#v", err)
+	}
+	if _, err := client.Watch("/ok", 0, false, nil, nil); !IsStorageUnavailable(err) {
+		t.Errorf("expected the watch to fail fast, got %v", err)
+	}
+
+	// After the cooldown, a probe goes through; a failed probe trips the breaker again. A
+	// watch isn't a probe, and is still refused.
+	clock.time = clock.time.Add(time.Second)
+	if state := client.BreakerState(); state != BreakerProbing {
+		t.Fatalf("expected the breaker to let a probe through, got %s", state)
+	}
+	_, err = client.Watch("/ok", 0, false, nil, nil)
+	if unavailable, ok := err.(*StorageUnavailableError); !ok || unavailable.RetryAfter != time.Second {
+		t.Errorf("expected the watch to fail fast until the breaker closes, got %v", err)
+	}
+	if _, err := client.Get("/broken", false, false); IsStorageUnavailable(err) {
+		t.Fatalf("expected the probe to reach etcd")
+	}
+	if _, err := client.Get("/ok", false, false); !IsStorageUnavailable(err) {
+		t.Fatalf("expected the breaker to trip again, got %v", err)
+	}
+	clock.time = clock.time.Add(time.Second)
+	if _, err := client.Get("/ok", false, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if state := client.BreakerState(); state != BreakerClosed {
+		t.Fatalf("expected a successful probe to close the breaker, got %s", state)
+	}
+	if err := client.HealthCheck(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	metrics := client.Metrics()
+	if metrics.Trips != 2 || metrics.Rejected != 4 || metrics.Operations["get"].Calls != 8 {
+		t.Errorf("unexpected metrics: %#v", metrics)
+	}
+}
+
+func TestInstrumentedEtcdClientBreakerLatency(t *testing.T) {
+	client, _, clock := newTestInstrumentedClient(t, BreakerConfig{LatencyThreshold: 6 * time.Millisecond, LatencyWindow: 4})
+	for i := 0; i < 10; i++ {
+		client.Set("/ok", "ok", 0)
+	}
+	clock.step = 10 * time.Millisecond
+	// The average of the window only goes over the threshold with the third slow call.
+	for i := 0; i < 2; i++ {
+		if _, err := client.Set("/ok", "ok", 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if state := client.BreakerState(); state != BreakerClosed {
+		t.Fatalf("expected the breaker to be closed, got %s", state)
+	}
+	client.Set("/ok", "ok", 0)
+	if _, err := client.Set("/ok", "ok", 0); !IsStorageUnavailable(err) {
+		t.Errorf("expected slow calls to trip the breaker, got %v", err)
+	}
+	if client.config.Cooldown != DefaultBreakerCooldown {
+		t.Errorf("expected the default cooldown, got %v", client.config.Cooldown)
+	}
+}
@@ -0,0 +1,398 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tools
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/util"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+	"github.com/coreos/go-etcd/etcd"
+	"github.com/golang/glog"
+)
+
+// DefaultWatchReplaySize is the number of recent events a WatchBroadcaster keeps by default.
+const DefaultWatchReplaySize = 100
+
+// WatchBroadcaster shares a single etcd watch of the items under a key among all the watches
+// of them, so that etcd serves one watch however many clients watch the items. The etcd
+// watch is opened by the first watch and closed when the last one stops. The broadcaster
+// keeps the items as of the latest event, to start watches from the current state, and the
+// most recent events, to replay them to watches starting from a recent resource version.
+// Watches starting from an older resource version get an etcd watch of their own.
+type WatchBroadcaster struct {
+	helper EtcdHelper
+	key    string
+	size   int
+
+	lock sync.Mutex
+	// current is the etcd watch being shared, or nil if nothing watches the items.
+	current *broadcast
+	// upstreams counts the etcd watches opened, shared or not.
+	upstreams int
+}
+
+// broadcast is an etcd watch shared by watchers.
+type broadcast struct {
+	// objects are the items under the key as of the latest event, by etcd key.
+	objects map[string]interface{}
+	// events are the most recent events, a ring starting at first.
+	events []indexedEvent
+	first  int
+	// coveredAfter is the etcd index after which every event is in events.
+	coveredAfter uint64
+	watchers     map[*broadcastWatcher]bool
+	// quit is closed to stop the etcd watch.
+	quit chan struct{}
+}
+
+// indexedEvent is an event and the etcd index of the change it describes.
+type indexedEvent struct {
+	index uint64
+	event watch.Event
+}
+
+// NewWatchBroadcaster returns a WatchBroadcaster of the items under key, read and decoded
+// with helper, which replays up to size recent events. If size isn't positive,
+// DefaultWatchReplaySize is used.
+func NewWatchBroadcaster(helper EtcdHelper, key string, size int) *WatchBroadcaster {
+	if size <= 0 {
+		size = DefaultWatchReplaySize
+	}
+	return &WatchBroadcaster{helper: helper, key: key, size: size}
+}
+
+// Upstreams returns the number of etcd watches b has opened.
+func (b *WatchBroadcaster) Upstreams() int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.upstreams
+}
+
+// Watch is EtcdHelper.WatchList of the key of b, served from the etcd watch b shares if
+// resourceVersion is 0, for the current items and then every change, or recent enough for
+// the events since to be replayed.
+func (b *WatchBroadcaster) Watch(resourceVersion uint64, filter FilterFunc) (watch.Interface, error) {
+	if filter == nil {
+		filter = Everything
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.current == nil {
+		current, err := b.start()
+		if err != nil {
+			return nil, err
+		}
+		b.current = current
+	}
+	c := b.current
+	w := &broadcastWatcher{
+		b:        b,
+		c:        c,
+		filter:   filter,
+		from:     resourceVersion,
+		wake:     make(chan struct{}, 1),
+		outgoing: make(chan watch.Event),
+		stop:     make(chan struct{}),
+	}
+	switch {
+	case resourceVersion == 0:
+		w.push(c.snapshot()...)
+	case resourceVersion > c.coveredAfter:
+		w.push(c.since(resourceVersion)...)
+	default:
+		glog.V(2).Infof("Watch of %s from %d predates the events kept since %d, watching etcd for it", b.key, resourceVersion, c.coveredAfter)
+		if len(c.watchers) == 0 {
+			b.current = nil
+			close(c.quit)
+		}
+		b.upstreams++
+		return b.helper.WatchList(b.key, resourceVersion, filter)
+	}
+	c.watchers[w] = true
+	go w.run()
+	return w, nil
+}
+
+// start reads the items under the key of b and opens the etcd watch of their changes.
+func (b *WatchBroadcaster) start() (*broadcast, error) {
+	c := &broadcast{
+		objects:  map[string]interface{}{},
+		watchers: map[*broadcastWatcher]bool{},
+		quit:     make(chan struct{}),
+	}
+	resp, err := b.helper.Client.Get(b.key, false, true)
+	switch {
+	case err == nil:
+		c.coveredAfter = resp.EtcdIndex
+		if resp.Node != nil {
+			c.load(resp.Node, b.helper)
+		}
+	case IsEtcdNotFound(err):
+		c.coveredAfter, _ = etcdErrorIndex(err)
+	default:
+		return nil, err
+	}
+	b.upstreams++
+
+	incoming := make(chan *etcd.Response)
+	stop := make(chan bool)
+	ended := make(chan struct{})
+	go func() {
+		defer util.HandleCrash()
+		defer close(ended)
+		_, err := b.helper.Client.Watch(b.key, c.coveredAfter+1, true, incoming, stop)
+		if err != etcd.ErrWatchStoppedByUser {
+			glog.Errorf("etcd.Watch stopped unexpectedly: %v (%#v)", err, b.key)
+		}
+	}()
+	go b.relay(c, incoming, stop, ended)
+	return c, nil
+}
+
+// load stores the items of node, a directory or an item.
+func (c *broadcast) load(node *etcd.Node, helper EtcdHelper) {
+	if node.Dir {
+		for _, child := range node.Nodes {
+			c.load(child, helper)
+		}
+		return
+	}
+	event, err := decodeResult(&etcd.Response{Action: "get", Node: node}, helper.Codec, helper.ResourceVersioner)
+	if err == nil {
+		c.objects[node.Key] = event.Object
+	}
+}
+
+// relay passes the changes etcd sends to the watchers of c, until the etcd watch ends or c
+// is stopped.
+func (b *WatchBroadcaster) relay(c *broadcast, incoming chan *etcd.Response, stop chan bool, ended chan struct{}) {
+	defer util.HandleCrash()
+	for {
+		select {
+		case res := <-incoming:
+			if !b.dispatch(c, res) && b.end(c) {
+				close(c.quit)
+			}
+		case <-ended:
+			b.end(c)
+			return
+		case <-c.quit:
+			// etcd may be blocked sending a change, so keep reading until it takes the stop.
+			for {
+				select {
+				case stop <- true:
+					return
+				case <-ended:
+					return
+				case <-incoming:
+				}
+			}
+		}
+	}
+}
+
+// dispatch records the change res describes and passes it to the watchers of c. It returns
+// false if the object of res can't be decoded, in which case the watch is ended, as etcd
+// watches of a single client are.
+func (b *WatchBroadcaster) dispatch(c *broadcast, res *etcd.Response) bool {
+	event, err := decodeResult(res, b.helper.Codec, b.helper.ResourceVersioner)
+	if err == errNotAnEvent {
+		return true
+	}
+	if err != nil {
+		return false
+	}
+	node := res.Node
+	if node == nil {
+		node = res.PrevNode
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.current != c {
+		return true
+	}
+	if event.Type == watch.Deleted {
+		delete(c.objects, node.Key)
+	} else {
+		c.objects[node.Key] = event.Object
+	}
+	c.record(indexedEvent{node.ModifiedIndex, event}, b.size)
+	for w := range c.watchers {
+		if node.ModifiedIndex >= w.from {
+			w.push(event)
+		}
+	}
+	return true
+}
+
+// end closes the watchers of c once they have been sent the events they were passed, and
+// lets the next watch open a new etcd watch. It returns false if c was already stopped.
+func (b *WatchBroadcaster) end(c *broadcast) bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for w := range c.watchers {
+		w.end()
+	}
+	c.watchers = map[*broadcastWatcher]bool{}
+	if b.current != c {
+		return false
+	}
+	b.current = nil
+	return true
+}
+
+// unsubscribe removes w from the watchers of its broadcast, and stops the etcd watch if it
+// was the last one.
+func (b *WatchBroadcaster) unsubscribe(w *broadcastWatcher) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	delete(w.c.watchers, w)
+	if len(w.c.watchers) == 0 && b.current == w.c {
+		b.current = nil
+		close(w.c.quit)
+	}
+}
+
+// record adds e to the recent events of c, dropping the oldest if there are already size.
+func (c *broadcast) record(e indexedEvent, size int) {
+	if len(c.events) < size {
+		c.events = append(c.events, e)
+		return
+	}
+	c.coveredAfter = c.events[c.first].index
+	c.events[c.first] = e
+	c.first = (c.first + 1) % len(c.events)
+}
+
+// since returns the recent events of c for changes at or after resourceVersion, oldest first.
+func (c *broadcast) since(resourceVersion uint64) []watch.Event {
+	events := []watch.Event{}
+	for i := range c.events {
+		e := c.events[(c.first+i)%len(c.events)]
+		if e.index >= resourceVersion {
+			events = append(events, e.event)
+		}
+	}
+	return events
+}
+
+// snapshot returns an event for each of the current items of c, as a watch starting from
+// the current state is sent, sorted by key.
+func (c *broadcast) snapshot() []watch.Event {
+	keys := make([]string, 0, len(c.objects))
+	for key := range c.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	events := make([]watch.Event, 0, len(keys))
+	for _, key := range keys {
+		events = append(events, watch.Event{Type: watch.Modified, Object: c.objects[key]})
+	}
+	return events
+}
+
+// broadcastWatcher is a watch served from a broadcast. Events are queued for it without
+// blocking the broadcast, and sent by a goroutine of its own.
+type broadcastWatcher struct {
+	b      *WatchBroadcaster
+	c      *broadcast
+	filter FilterFunc
+	// from is the resource version the watch starts from; earlier changes aren't sent.
+	from uint64
+
+	lock    sync.Mutex
+	pending []watch.Event
+	ended   bool
+	// wake is signalled when events are queued or the watch ends.
+	wake chan struct{}
+
+	outgoing chan watch.Event
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// push queues events to be sent.
+func (w *broadcastWatcher) push(events ...watch.Event) {
+	w.lock.Lock()
+	w.pending = append(w.pending, events...)
+	w.lock.Unlock()
+	w.signal()
+}
+
+// end closes the result channel of w once the queued events are sent.
+func (w *broadcastWatcher) end() {
+	w.lock.Lock()
+	w.ended = true
+	w.lock.Unlock()
+	w.signal()
+}
+
+func (w *broadcastWatcher) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the queued events that pass the filter of w until it is stopped or ended.
+func (w *broadcastWatcher) run() {
+	defer close(w.outgoing)
+	defer util.HandleCrash()
+	for {
+		w.lock.Lock()
+		events, ended := w.pending, w.ended
+		w.pending = nil
+		w.lock.Unlock()
+		for _, event := range events {
+			if !w.filter(event.Object) {
+				continue
+			}
+			select {
+			case w.outgoing <- event:
+			case <-w.stop:
+				return
+			}
+		}
+		if len(events) > 0 {
+			continue
+		}
+		if ended {
+			return
+		}
+		select {
+		case <-w.wake:
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// ResultChan implements watch.Interface.
+func (w *broadcastWatcher) ResultChan() <-chan watch.Event {
+	return w.outgoing
+}
+
+// Stop implements watch.Interface.
+func (w *broadcastWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		w.b.unsubscribe(w)
+	})
+}
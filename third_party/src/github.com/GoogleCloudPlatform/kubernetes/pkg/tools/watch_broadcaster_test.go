@@ -0,0 +1,225 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tools
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/api"
+	"github.com/GoogleCloudPlatform/kubernetes/pkg/watch"
+	"github.com/coreos/go-etcd/etcd"
+)
+
+// broadcastEtcdClient is a FakeEtcdClient whose watches are handed to the test, which may open
+// any number of them.
+type broadcastEtcdClient struct {
+	*FakeEtcdClient
+	watches chan *fakeEtcdWatch
+}
+
+// fakeEtcdWatch is a watch opened on a broadcastEtcdClient.
+type fakeEtcdWatch struct {
+	waitIndex uint64
+	receiver  chan *etcd.Response
+	stopped   chan struct{}
+}
+
+func newBroadcastEtcdClient(t TestLogger) *broadcastEtcdClient {
+	client := &broadcastEtcdClient{MakeFakeEtcdClient(t), make(chan *fakeEtcdWatch, 10)}
+	client.ExpectNotFoundGet("/pods")
+	return client
+}
+
+func (c *broadcastEtcdClient) Watch(prefix string, waitIndex uint64, recursive bool, receiver chan *etcd.Response, stop chan bool) (*etcd.Response, error) {
+	w := &fakeEtcdWatch{waitIndex, receiver, make(chan struct{})}
+	c.watches <- w
+	<-stop
+	close(w.stopped)
+	return nil, etcd.ErrWatchStoppedByUser
+}
+
+// set sends the change of the pod id at index down w.
+func (w *fakeEtcdWatch) set(id string, index uint64) {
+	pod := &api.Pod{JSONBase: api.JSONBase{ID: id}}
+	w.receiver <- &etcd.Response{
+		Action: "set",
+		Node:   &etcd.Node{Key: "/pods/" + id, Value: api.EncodeOrDie(pod), ModifiedIndex: index},
+	}
+}
+
+// expectPods reads an event from each of watchers for each of ids, in order.
+func expectPods(t *testing.T, watchers []watch.Interface, ids ...string) {
+	for i, w := range watchers {
+		for _, id := range ids {
+			event, ok := <-w.ResultChan()
+			if !ok {
+				t.Fatalf("watcher %d: expected %s, got the end of the watch", i, id)
+			}
+			if pod, ok := event.Object.(*api.Pod); !ok || pod.ID != id {
+				t.Fatalf("watcher %d: expected %s, got %#v", i, id, event.Object)
+			}
+		}
+	}
+}
+
+func TestWatchBroadcasterSharesWatch(t *testing.T) {
+	client := newBroadcastEtcdClient(t)
+	b := NewWatchBroadcaster(EtcdHelper{client, codec, versioner}, "/pods", 3)
+
+	first, err := b.Watch(0, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	upstream := <-client.watches
+	second, err := b.Watch(0, func(obj interface{}) bool { return obj.(*api.Pod).ID != "b" })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	upstream.set("a", 1)
+	upstream.set("b", 2)
+	upstream.set("c", 3)
+	expectPods(t, []watch.Interface{first}, "a", "b", "c")
+	expectPods(t, []watch.Interface{second}, "a", "c")
+
+	// A watch from now starts with the current items, and one from a recent version with
+	// the events since.
+	current, _ := b.Watch(0, nil)
+	expectPods(t, []watch.Interface{current}, "a", "b", "c")
+	recent, _ := b.Watch(2, nil)
+	expectPods(t, []watch.Interface{recent}, "b", "c")
+	upstream.set("d", 4)
+	expectPods(t, []watch.Interface{first, second, current, recent}, "d")
+	if b.Upstreams() != 1 {
+		t.Errorf("expected a single etcd watch, got %d", b.Upstreams())
+	}
+
+	// Only the last 3 events are kept, so older versions are watched in etcd.
+	old, _ := b.Watch(1, nil)
+	own := <-client.watches
+	if own.waitIndex != 1 || b.Upstreams() != 2 {
+		t.Errorf("expected an etcd watch from 1, got %d watches from %d", b.Upstreams(), own.waitIndex)
+	}
+	old.Stop()
+
+	for _, w := range []watch.Interface{first, second, current} {
+		w.Stop()
+	}
+	select {
+	case <-upstream.stopped:
+		t.Fatalf("expected the etcd watch to be kept while it is watched")
+	default:
+	}
+	recent.Stop()
+	<-upstream.stopped
+	for _, w := range []watch.Interface{first, second, current, recent} {
+		if _, ok := <-w.ResultChan(); ok {
+			t.Errorf("expected the stopped watch to be closed")
+		}
+	}
+
+	next, _ := b.Watch(0, nil)
+	if upstream := <-client.watches; upstream.waitIndex != 1 {
+		t.Errorf("expected a new etcd watch from the current index, got %d", upstream.waitIndex)
+	}
+	next.Stop()
+}
+
+func TestWatchBroadcasterEndsWithEtcdWatch(t *testing.T) {
+	client := newBroadcastEtcdClient(t)
+	b := NewWatchBroadcaster(EtcdHelper{client, codec, versioner}, "/pods", 0)
+	w, _ := b.Watch(0, nil)
+	upstream := <-client.watches
+	upstream.receiver <- &etcd.Response{Action: "set", Node: &etcd.Node{Key: "/pods/x", Value: "garbage"}}
+	if _, ok := <-w.ResultChan(); ok {
+		t.Errorf("expected the watch to end with an object that can't be decoded")
+	}
+	<-upstream.stopped
+	w.Stop()
+}
+
+func TestWatchBroadcasterConcurrentWatches(t *testing.T) {
+	client := newBroadcastEtcdClient(t)
+	b := NewWatchBroadcaster(EtcdHelper{client, codec, versioner}, "/pods", 0)
+	keep, _ := b.Watch(0, nil)
+	upstream := <-client.watches
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w, err := b.Watch(0, nil)
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			w.Stop()
+		}()
+	}
+	for i := uint64(1); i <= 20; i++ {
+		upstream.set(fmt.Sprintf("pod%d", i), i)
+	}
+	wg.Wait()
+	for i := 1; i <= 20; i++ {
+		expectPods(t, []watch.Interface{keep}, fmt.Sprintf("pod%d", i))
+	}
+	if b.Upstreams() != 1 {
+		t.Errorf("expected a single etcd watch, got %d", b.Upstreams())
+	}
+	keep.Stop()
+	<-upstream.stopped
+}
+
+// BenchmarkWatchBroadcaster sends events to 100 concurrent watches over one etcd watch.
+func BenchmarkWatchBroadcaster(b *testing.B) {
+	client := newBroadcastEtcdClient(b)
+	broadcaster := NewWatchBroadcaster(EtcdHelper{client, codec, versioner}, "/pods", 0)
+	watchers := []watch.Interface{}
+	for i := 0; i < 100; i++ {
+		w, err := broadcaster.Watch(0, nil)
+		if err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+		watchers = append(watchers, w)
+	}
+	upstream := <-client.watches
+
+	var wg sync.WaitGroup
+	for _, w := range watchers {
+		wg.Add(1)
+		go func(w watch.Interface) {
+			defer wg.Done()
+			for i := 0; i < b.N; i++ {
+				<-w.ResultChan()
+			}
+		}(w)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		upstream.set("foo", uint64(i+1))
+	}
+	wg.Wait()
+	b.StopTimer()
+	if upstreams := broadcaster.Upstreams(); upstreams != 1 {
+		b.Fatalf("expected a single etcd watch for 100 watches, got %d", upstreams)
+	}
+	for _, w := range watchers {
+		w.Stop()
+	}
+}
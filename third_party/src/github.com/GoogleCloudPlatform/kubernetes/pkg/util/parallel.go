@@ -0,0 +1,71 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"runtime"
+	"sync"
+)
+
+// DefaultWorkers is the number of goroutines ParallelFilter runs on when it is not
+// given a positive count: one per CPU Go may use.
+func DefaultWorkers() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// ParallelFilter calls keep with each index in [0, n), split into contiguous ranges over
+// up to workers goroutines, and returns the indices for which keep returned true, in
+// increasing order. keep must be safe to call from several goroutines at once. A workers
+// count that is not positive means DefaultWorkers().
+func ParallelFilter(n, workers int, keep func(i int) bool) []int {
+	if workers <= 0 {
+		workers = DefaultWorkers()
+	}
+	if workers > n {
+		workers = n
+	}
+	kept := make([]bool, n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			kept[i] = keep(i)
+		}
+	} else {
+		var wg sync.WaitGroup
+		size := (n + workers - 1) / workers
+		for start := 0; start < n; start += size {
+			end := start + size
+			if end > n {
+				end = n
+			}
+			wg.Add(1)
+			go func(start, end int) {
+				defer wg.Done()
+				for i := start; i < end; i++ {
+					kept[i] = keep(i)
+				}
+			}(start, end)
+		}
+		wg.Wait()
+	}
+	indices := []int{}
+	for i, ok := range kept {
+		if ok {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
@@ -0,0 +1,42 @@
+/*
+Copyright 2014 Google Inc. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParallelFilterMatchesSerialOrder(t *testing.T) {
+	keep := func(i int) bool { return i%3 == 0 || i%7 == 0 }
+	for _, n := range []int{0, 1, 2, 10, 101, 1000} {
+		serial := ParallelFilter(n, 1, keep)
+		for _, workers := range []int{-1, 0, 2, 3, 8, 2000} {
+			parallel := ParallelFilter(n, workers, keep)
+			if !reflect.DeepEqual(serial, parallel) {
+				t.Errorf("n=%d workers=%d: expected %v, got %v", n, workers, serial, parallel)
+			}
+		}
+	}
+}
+
+func TestParallelFilterKeepsEveryMatch(t *testing.T) {
+	indices := ParallelFilter(5, 2, func(i int) bool { return i != 2 })
+	if expected := []int{0, 1, 3, 4}; !reflect.DeepEqual(expected, indices) {
+		t.Errorf("expected %v, got %v", expected, indices)
+	}
+}
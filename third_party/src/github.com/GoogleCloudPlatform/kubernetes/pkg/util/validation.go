@@ -18,30 +18,33 @@ package util
 
 import (
 	"regexp"
+	"strings"
 )
 
 const dnsLabelFmt string = "[a-z0-9]([-a-z0-9]*[a-z0-9])?"
 
 var dnsLabelRegexp = regexp.MustCompile("^" + dnsLabelFmt + "$")
 
-const dnsLabelMaxLength int = 63
+// DNSLabelMaxLength is the length limit of IsDNSLabel.
+const DNSLabelMaxLength int = 63
 
 // IsDNSLabel tests for a string that conforms to the definition of a label in
 // DNS (RFC 1035/1123).
 func IsDNSLabel(value string) bool {
-	return len(value) <= dnsLabelMaxLength && dnsLabelRegexp.MatchString(value)
+	return len(value) <= DNSLabelMaxLength && dnsLabelRegexp.MatchString(value)
 }
 
 const dnsSubdomainFmt string = dnsLabelFmt + "(\\." + dnsLabelFmt + ")*"
 
 var dnsSubdomainRegexp = regexp.MustCompile("^" + dnsSubdomainFmt + "$")
 
-const dnsSubdomainMaxLength int = 253
+// DNSSubdomainMaxLength is the length limit of IsDNSSubdomain.
+const DNSSubdomainMaxLength int = 253
 
 // IsDNSSubdomain tests for a string that conforms to the definition of a
 // subdomain in DNS (RFC 1035/1123).
 func IsDNSSubdomain(value string) bool {
-	return len(value) <= dnsSubdomainMaxLength && dnsSubdomainRegexp.MatchString(value)
+	return len(value) <= DNSSubdomainMaxLength && dnsSubdomainRegexp.MatchString(value)
 }
 
 const cIdentifierFmt string = "[A-Za-z_][A-Za-z0-9_]*"
@@ -68,3 +71,29 @@ const dns952MaxLength = 24
 func IsDNS952Label(value string) bool {
 	return len(value) <= dns952MaxLength && dns952Regexp.MatchString(value)
 }
+
+const labelKeyNameFmt string = "[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?"
+
+var labelKeyNameRegexp = regexp.MustCompile("^" + labelKeyNameFmt + "$")
+
+const labelKeyNameMaxLength int = 63
+
+// IsLabelKey tests for a string that can be used as the key of a label: a name of
+// at most 63 alphanumerics, '-', '_' and '.', starting and ending with an
+// alphanumeric, optionally preceded by a DNS subdomain and a '/'.
+func IsLabelKey(value string) bool {
+	name := value
+	if i := strings.Index(value, "/"); i != -1 {
+		if !IsDNSSubdomain(value[:i]) {
+			return false
+		}
+		name = value[i+1:]
+	}
+	return len(name) <= labelKeyNameMaxLength && labelKeyNameRegexp.MatchString(name)
+}
+
+// IsLabelValue tests for a string that can be used as the value of a label: empty, or at
+// most 63 alphanumerics, '-', '_' and '.', starting and ending with an alphanumeric.
+func IsLabelValue(value string) bool {
+	return len(value) == 0 || (len(value) <= labelKeyNameMaxLength && labelKeyNameRegexp.MatchString(value))
+}
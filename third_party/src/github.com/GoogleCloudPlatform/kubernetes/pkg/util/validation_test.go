@@ -147,3 +147,41 @@ func TestIsDNS952(t *testing.T) {
 		}
 	}
 }
+
+func TestIsLabelKey(t *testing.T) {
+	goodValues := []string{
+		"a", "name", "replicationController", "a_b", "a.b", "a-b", "1", "A1-b.c_d",
+		"example.com/name", "a.b.c/Name", strings.Repeat("a", 63),
+	}
+	for _, val := range goodValues {
+		if !IsLabelKey(val) {
+			t.Errorf("expected true for '%s'", val)
+		}
+	}
+
+	badValues := []string{
+		"", "-a", "a-", "_a", "a.", "a b", "a=b", "a,b", "a!",
+		"/name", "example.com/", "Example.com/name", "a/b/c", strings.Repeat("a", 64),
+	}
+	for _, val := range badValues {
+		if IsLabelKey(val) {
+			t.Errorf("expected false for '%s'", val)
+		}
+	}
+}
+
+func TestIsLabelValue(t *testing.T) {
+	goodValues := []string{"", "a", "frontend", "v1.2", "A1-b.c_d", strings.Repeat("a", 63)}
+	for _, val := range goodValues {
+		if !IsLabelValue(val) {
+			t.Errorf("expected true for '%s'", val)
+		}
+	}
+
+	badValues := []string{"-a", "a-", "a b", "a=b", "a,b", "a/b", strings.Repeat("a", 64)}
+	for _, val := range badValues {
+		if IsLabelValue(val) {
+			t.Errorf("expected false for '%s'", val)
+		}
+	}
+}
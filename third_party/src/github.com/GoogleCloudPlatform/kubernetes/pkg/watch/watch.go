@@ -39,6 +39,9 @@ const (
 	Added    EventType = "ADDED"
 	Modified EventType = "MODIFIED"
 	Deleted  EventType = "DELETED"
+	// Error is sent by a server in place of an object event when the watch fails. Its
+	// object is an api.Status describing the failure, and no further events follow.
+	Error EventType = "ERROR"
 )
 
 // Event represents a single event to a watched resource.